@@ -0,0 +1,13 @@
+package activation_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestActivation(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Activation Suite")
+}
@@ -0,0 +1,44 @@
+package activation
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/lager"
+)
+
+//go:generate counterfeiter -o ../volumedriverfakes/fake_idler.go . Idler
+type Idler interface {
+	// IdleSince returns when the idler last did work, or false while it is
+	// busy.
+	IdleSince() (time.Time, bool)
+}
+
+// WatchIdle returns a channel that is closed once idler has been idle for
+// timeout, checking every interval, so that a socket activated driver can
+// exit and be started again on the next request. It stops watching when
+// stop is closed.
+func WatchIdle(logger lager.Logger, idler Idler, timeout time.Duration, interval time.Duration, stop <-chan struct{}) <-chan struct{} {
+	logger = logger.Session("watch-idle", lager.Data{"timeout": timeout.String()})
+	idle := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				since, ok := idler.IdleSince()
+				if ok && now.Sub(since) >= timeout {
+					logger.Info("idle", lager.Data{"since": since.String()})
+					close(idle)
+					return
+				}
+			}
+		}
+	}()
+
+	return idle
+}
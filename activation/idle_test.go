@@ -0,0 +1,46 @@
+package activation_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver/activation"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WatchIdle", func() {
+	var (
+		fakeIdler *volumedriverfakes.FakeIdler
+		stop      chan struct{}
+	)
+
+	BeforeEach(func() {
+		fakeIdler = &volumedriverfakes.FakeIdler{}
+		stop = make(chan struct{})
+	})
+
+	AfterEach(func() {
+		close(stop)
+	})
+
+	watch := func() <-chan struct{} {
+		return activation.WatchIdle(lagertest.NewTestLogger("idle"), fakeIdler, time.Minute, 5*time.Millisecond, stop)
+	}
+
+	It("fires once the idler has been idle for the timeout", func() {
+		fakeIdler.IdleSinceReturns(time.Now().Add(-2*time.Minute), true)
+		Eventually(watch()).Should(BeClosed())
+	})
+
+	It("does not fire before the timeout", func() {
+		fakeIdler.IdleSinceReturns(time.Now(), true)
+		Consistently(watch(), 50*time.Millisecond).ShouldNot(BeClosed())
+	})
+
+	It("does not fire while the idler is busy", func() {
+		fakeIdler.IdleSinceReturns(time.Time{}, false)
+		Consistently(watch(), 50*time.Millisecond).ShouldNot(BeClosed())
+	})
+})
@@ -0,0 +1,49 @@
+package activation
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// listenFdsStart is the first file descriptor passed by systemd, see
+// sd_listen_fds(3).
+const listenFdsStart = 3
+
+// Listeners returns the sockets systemd passed to the process with
+// LISTEN_PID and LISTEN_FDS, in the order of the socket unit, or none when
+// the process was not socket activated. The variables are unset so that
+// child processes, such as mount helpers, do not take the sockets for their
+// own.
+func Listeners() ([]net.Listener, error) {
+	defer os.Unsetenv("LISTEN_PID")
+	defer os.Unsetenv("LISTEN_FDS")
+	defer os.Unsetenv("LISTEN_FDNAMES")
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count < 1 {
+		return nil, nil
+	}
+
+	listeners := []net.Listener{}
+	for fd := listenFdsStart; fd < listenFdsStart+count; fd++ {
+		file := os.NewFile(uintptr(fd), fmt.Sprintf("LISTEN_FD_%d", fd))
+		listener, err := net.FileListener(file)
+		file.Close()
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, fmt.Errorf("file descriptor %d is not a listening socket: %s", fd, err.Error())
+		}
+		listeners = append(listeners, listener)
+	}
+
+	return listeners, nil
+}
@@ -0,0 +1,30 @@
+package activation_test
+
+import (
+	"os"
+	"strconv"
+
+	"code.cloudfoundry.org/volumedriver/activation"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Listeners", func() {
+	AfterEach(func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+	})
+
+	It("returns no listeners when the process was not socket activated", func() {
+		Expect(activation.Listeners()).To(BeEmpty())
+	})
+
+	It("ignores sockets passed to another process", func() {
+		os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()+1))
+		os.Setenv("LISTEN_FDS", "1")
+
+		Expect(activation.Listeners()).To(BeEmpty())
+		_, set := os.LookupEnv("LISTEN_FDS")
+		Expect(set).To(BeFalse())
+	})
+})
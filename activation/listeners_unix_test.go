@@ -0,0 +1,66 @@
+// +build linux darwin
+
+package activation_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+
+	"code.cloudfoundry.org/volumedriver/activation"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// TestActivatedProcess is run by the specs below as a socket activated child
+// process, the way systemd would start the driver.
+func TestActivatedProcess(t *testing.T) {
+	if os.Getenv("ACTIVATION_TEST_CHILD") == "" {
+		t.Skip("only run as a child process")
+	}
+
+	// systemd sets the pid after forking
+	os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+
+	listeners, err := activation.Listeners()
+	if err != nil || len(listeners) != 1 {
+		fmt.Fprintf(os.Stderr, "unexpected listeners %v: %v", listeners, err)
+		os.Exit(1)
+	}
+
+	conn, err := listeners[0].Accept()
+	if err != nil {
+		os.Exit(1)
+	}
+	fmt.Fprintf(conn, "activated, LISTEN_FDS=%q", os.Getenv("LISTEN_FDS"))
+	conn.Close()
+	os.Exit(0)
+}
+
+var _ = Describe("Listeners in a socket activated process", func() {
+	It("returns the passed socket", func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		defer listener.Close()
+		file, err := listener.(*net.TCPListener).File()
+		Expect(err).NotTo(HaveOccurred())
+		defer file.Close()
+
+		child := exec.Command(os.Args[0], "-test.run=TestActivatedProcess")
+		child.Env = append(os.Environ(), "ACTIVATION_TEST_CHILD=1", "LISTEN_FDS=1")
+		child.ExtraFiles = []*os.File{file}
+		child.Stderr = GinkgoWriter
+		Expect(child.Start()).To(Succeed())
+		defer child.Wait()
+
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		Expect(err).NotTo(HaveOccurred())
+		defer conn.Close()
+
+		Expect(ioutil.ReadAll(conn)).To(Equal([]byte(`activated, LISTEN_FDS=""`)))
+	})
+})
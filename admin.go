@@ -0,0 +1,287 @@
+package volumedriver
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"github.com/tedsuo/rata"
+)
+
+const (
+	ResetMountErrorRoute    = "reset-mount-error"
+	ExportStateRoute        = "export-state"
+	ImportStateRoute        = "import-state"
+	ListVolumesRoute        = "list-volumes"
+	CloneRoute              = "clone"
+	DescribeVolumeRoute     = "describe-volume"
+	FreezeVolumeRoute       = "freeze-volume"
+	ThawVolumeRoute         = "thaw-volume"
+	ImportMountRoute        = "import-mount"
+	RemountVolumeRoute      = "remount-volume"
+	DiscoverExportsRoute    = "discover-exports"
+	RevokeReferencesRoute   = "revoke-references"
+	DrainRoute              = "drain"
+	RemoveVolumeRoute       = "remove-volume"
+	InFlightOperationsRoute = "in-flight-operations"
+	BindVolumeRoute         = "bind-volume"
+	UnbindVolumeRoute       = "unbind-volume"
+	ListSnapshotsRoute      = "list-snapshots"
+	RestoreSnapshotRoute    = "restore-snapshot"
+)
+
+var AdminRoutes = rata.Routes{
+	{Path: "/Admin.ResetMountError", Method: "POST", Name: ResetMountErrorRoute},
+	{Path: "/Admin.ExportState", Method: "POST", Name: ExportStateRoute},
+	{Path: "/Admin.ImportState", Method: "POST", Name: ImportStateRoute},
+	{Path: "/Admin.ListVolumes", Method: "POST", Name: ListVolumesRoute},
+	{Path: "/Admin.Clone", Method: "POST", Name: CloneRoute},
+	{Path: "/Admin.DescribeVolume", Method: "POST", Name: DescribeVolumeRoute},
+	{Path: "/Admin.FreezeVolume", Method: "POST", Name: FreezeVolumeRoute},
+	{Path: "/Admin.ThawVolume", Method: "POST", Name: ThawVolumeRoute},
+	{Path: "/Admin.ImportMount", Method: "POST", Name: ImportMountRoute},
+	{Path: "/Admin.RemountVolume", Method: "POST", Name: RemountVolumeRoute},
+	{Path: "/Admin.DiscoverExports", Method: "POST", Name: DiscoverExportsRoute},
+	{Path: "/Admin.RevokeReferences", Method: "POST", Name: RevokeReferencesRoute},
+	{Path: "/Admin.Drain", Method: "POST", Name: DrainRoute},
+	{Path: "/Admin.RemoveVolume", Method: "POST", Name: RemoveVolumeRoute},
+	{Path: "/Admin.InFlightOperations", Method: "POST", Name: InFlightOperationsRoute},
+	{Path: "/Admin.BindVolume", Method: "POST", Name: BindVolumeRoute},
+	{Path: "/Admin.UnbindVolume", Method: "POST", Name: UnbindVolumeRoute},
+	{Path: "/Admin.ListSnapshots", Method: "POST", Name: ListSnapshotsRoute},
+	{Path: "/Admin.RestoreSnapshot", Method: "POST", Name: RestoreSnapshotRoute},
+}
+
+type ResetMountErrorRequest struct {
+	Name string
+}
+
+type ExportStateResponse struct {
+	Volumes []ExportedVolume
+	Err     string
+}
+
+// ExportedVolume is a volume as ExportState reports it: the opts it was
+// created with, less any secret opts, and where it is mounted. Mountpoint and
+// MountCount are only informational; ImportState does not restore them.
+type ExportedVolume struct {
+	dockerdriver.VolumeInfo
+	Opts           map[string]interface{} `json:",omitempty"` // see exportedOpts
+	DroppedOpts    []string               `json:",omitempty"` // secret opts left out of Opts
+	MountDirectory string                 `json:",omitempty"`
+	MountRoot      string                 `json:",omitempty"`
+}
+
+// ImportStateRequest merges previously exported volumes into the driver
+// state. Volumes that already exist are left alone unless Overwrite is set.
+// Volumes with opts are created as Create would create them; volumes
+// without, or with DroppedOpts, must be created again before they mount.
+type ImportStateRequest struct {
+	Volumes   []ExportedVolume
+	Overwrite bool
+}
+
+// ListVolumesRequest filters and pages through the volumes. Selector is a
+// comma separated list of label requirements: key=value, key!=value or just
+// key. Token is the NextToken of the previous page; Limit 0 returns all
+// matching volumes.
+// ListVolumesRequest filters the volumes. UnusedFor only lists the volumes
+// that have not been created, mounted, unmounted or accessed for that long,
+// see VolumeUsage.
+type ListVolumesRequest struct {
+	NamePrefix  string
+	Selector    string
+	MountedOnly bool
+	UnusedFor   time.Duration `json:",omitempty"`
+	Limit       int
+	Token       string
+}
+
+// ListVolumesResponse reports the usage of the volumes listed by name.
+type ListVolumesResponse struct {
+	Volumes   []dockerdriver.VolumeInfo
+	Usage     map[string]VolumeUsage `json:",omitempty"`
+	NextToken string
+	Err       string
+}
+
+// CloneRequest creates the volume Name from a copy of the Subdirectory of
+// the volume From. The copy is made next to it, in Target, so that it stays
+// on the same export and the server can clone the data instead of copying
+// it. The new volume inherits the opts of From.
+type CloneRequest struct {
+	Name         string
+	From         string
+	Subdirectory string
+	Target       string
+}
+
+type DescribeVolumeRequest struct {
+	Name string
+}
+
+// VolumeDescription is what Get would report if the volume plugin protocol
+// had room for it. Opts are only known for volumes created since the driver
+// started, and secrets in them are redacted.
+type VolumeDescription struct {
+	Name             string
+	Mountpoint       string
+	MountCount       int
+	Driver           string                 `json:",omitempty"`
+	Opts             map[string]interface{} `json:",omitempty"`
+	Labels           map[string]string      `json:",omitempty"`
+	ExpiresAt        *time.Time             `json:",omitempty"`
+	MountError       string                 `json:",omitempty"`
+	LastMountedAt    *time.Time             `json:",omitempty"`
+	LastUnmountedAt  *time.Time             `json:",omitempty"`
+	LastIOAt         *time.Time             `json:",omitempty"`
+	LastMountError   string                 `json:",omitempty"`
+	LastMountErrorAt *time.Time             `json:",omitempty"`
+	Frozen           bool                   `json:",omitempty"`
+	// References counts the mount references of every known caller. The
+	// rest of MountCount was taken by callers that did not identify.
+	References map[string]int `json:",omitempty"`
+	// Binds are the targets of BindVolume, which each hold a reference.
+	Binds map[string]VolumeBind `json:",omitempty"`
+}
+
+// FreezeVolumeRequest suspends writes to a mounted volume, so that backup
+// tooling can snapshot a consistent export. The volume stays frozen until a
+// ThawVolumeRequest, and cannot be unmounted meanwhile.
+type FreezeVolumeRequest struct {
+	Name string
+}
+
+type ThawVolumeRequest struct {
+	Name string
+}
+
+// ImportMountRequest adopts the existing kernel mount at Mountpoint, which
+// must be directly below the mount path root, as the volume Name. Opts are
+// the opts the volume would have been created with. MountCount is the number
+// of Unmount requests it takes to unmount it and defaults to 1.
+type ImportMountRequest struct {
+	Name       string
+	Mountpoint string
+	Opts       map[string]interface{}
+	MountCount int
+}
+
+// RemountVolumeRequest changes the mount options of a mounted volume in
+// place, for example {"ro": true} or {"actimeo": "30"}.
+type RemountVolumeRequest struct {
+	Name string
+	Opts map[string]interface{}
+}
+
+// DiscoverExportsRequest lists the exports of Server, a host name or an
+// address, with showmount or by browsing its NFSv4 pseudo root.
+type DiscoverExportsRequest struct {
+	Server string
+}
+
+type DiscoverExportsResponse struct {
+	Exports []Export
+	Err     string
+}
+
+// RevokeReferencesRequest releases the mount references of Caller, to the
+// volume Name or to every volume when Name is empty.
+type RevokeReferencesRequest struct {
+	Caller string
+	Name   string
+}
+
+type RevokeReferencesResponse struct {
+	Revoked int
+	Err     string
+}
+
+// RemoveVolumeRequest removes the volume Name. Unlike Remove, which refuses
+// volumes that are still mounted, Force removes them along with their mount
+// references.
+type RemoveVolumeRequest struct {
+	Name  string
+	Force bool
+}
+
+// InFlightOperationsResponse lists the operations oldest first, so that
+// the first running mount of a volume is the one the others wait for.
+type InFlightOperationsResponse struct {
+	Operations []InFlightOperation
+	Err        string
+}
+
+// DrainResponse reports the unmount of every volume that was mounted when
+// Drain started, by name, and the kernel mounts still below the mount path
+// roots once the roots were purged. Err is set when an unmount failed or did
+// not finish in time, or mounts remain, so that drain scripts can tell
+// whether the cell is safe to stop.
+type DrainResponse struct {
+	Volumes         []DrainedVolume
+	RemainingMounts []string `json:",omitempty"`
+	Err             string
+}
+
+// DrainedVolume is the outcome of the unmount of one volume. Err is empty
+// when the volume was unmounted.
+type DrainedVolume struct {
+	Name       string
+	Mountpoint string
+	Err        string `json:",omitempty"`
+}
+
+// ListSnapshotsResponse lists the state snapshots, newest first.
+type ListSnapshotsResponse struct {
+	Snapshots []StateSnapshot
+	Err       string
+}
+
+// RestoreSnapshotRequest restores the state snapshot ID. Force restores it
+// while volumes are mounted.
+type RestoreSnapshotRequest struct {
+	ID    string
+	Force bool
+}
+
+// BindVolumeRequest bind mounts the mounted volume Name into Target, an
+// absolute path outside the mount path roots, read-only with ReadOnly.
+type BindVolumeRequest struct {
+	Name     string
+	Target   string
+	ReadOnly bool
+}
+
+type UnbindVolumeRequest struct {
+	Name   string
+	Target string
+}
+
+type DescribeVolumeResponse struct {
+	Volume VolumeDescription
+	Err    string
+}
+
+//go:generate counterfeiter -o volumedriverfakes/fake_admin.go . Admin
+type Admin interface {
+	ResetMountError(env dockerdriver.Env, resetRequest ResetMountErrorRequest) dockerdriver.ErrorResponse
+	ExportState(env dockerdriver.Env) ExportStateResponse
+	ImportState(env dockerdriver.Env, importRequest ImportStateRequest) dockerdriver.ErrorResponse
+	ListVolumes(env dockerdriver.Env, listRequest ListVolumesRequest) ListVolumesResponse
+	Clone(env dockerdriver.Env, cloneRequest CloneRequest) dockerdriver.ErrorResponse
+	DescribeVolume(env dockerdriver.Env, describeRequest DescribeVolumeRequest) DescribeVolumeResponse
+	FreezeVolume(env dockerdriver.Env, freezeRequest FreezeVolumeRequest) dockerdriver.ErrorResponse
+	ThawVolume(env dockerdriver.Env, thawRequest ThawVolumeRequest) dockerdriver.ErrorResponse
+	ImportMount(env dockerdriver.Env, importRequest ImportMountRequest) dockerdriver.ErrorResponse
+	RemountVolume(env dockerdriver.Env, remountRequest RemountVolumeRequest) dockerdriver.ErrorResponse
+	DiscoverExports(env dockerdriver.Env, discoverRequest DiscoverExportsRequest) DiscoverExportsResponse
+	RevokeReferences(env dockerdriver.Env, revokeRequest RevokeReferencesRequest) RevokeReferencesResponse
+	RemoveVolume(env dockerdriver.Env, removeRequest RemoveVolumeRequest) dockerdriver.ErrorResponse
+	InFlightOperations(env dockerdriver.Env) InFlightOperationsResponse
+	BindVolume(env dockerdriver.Env, bindRequest BindVolumeRequest) dockerdriver.ErrorResponse
+	UnbindVolume(env dockerdriver.Env, unbindRequest UnbindVolumeRequest) dockerdriver.ErrorResponse
+	ListSnapshots(env dockerdriver.Env) ListSnapshotsResponse
+	RestoreSnapshot(env dockerdriver.Env, restoreRequest RestoreSnapshotRequest) dockerdriver.ErrorResponse
+	// Drain unmounts every volume before the cell is stopped. The driver
+	// serves no further mounts afterwards.
+	Drain(env dockerdriver.Env) DrainResponse
+}
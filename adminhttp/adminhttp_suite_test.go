@@ -0,0 +1,13 @@
+package adminhttp_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestAdminHttp(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "AdminHttp Suite")
+}
@@ -0,0 +1,587 @@
+package adminhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	cf_http_handlers "code.cloudfoundry.org/cfhttp/handlers"
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+	"github.com/tedsuo/rata"
+)
+
+// Like the docker plugin API, errors are reported in the response body and
+// the status code is always 200.
+const (
+	StatusInternalServerError = http.StatusOK
+	StatusOK                  = http.StatusOK
+)
+
+func NewHandler(logger lager.Logger, admin volumedriver.Admin) (http.Handler, error) {
+	logger = logger.Session("admin-server")
+	logger.Info("start")
+	defer logger.Info("end")
+
+	var handlers = rata.Handlers{
+		volumedriver.ResetMountErrorRoute:    newResetMountErrorHandler(logger, admin),
+		volumedriver.ExportStateRoute:        newExportStateHandler(logger, admin),
+		volumedriver.ImportStateRoute:        newImportStateHandler(logger, admin),
+		volumedriver.ListVolumesRoute:        newListVolumesHandler(logger, admin),
+		volumedriver.CloneRoute:              newCloneHandler(logger, admin),
+		volumedriver.DescribeVolumeRoute:     newDescribeVolumeHandler(logger, admin),
+		volumedriver.FreezeVolumeRoute:       newFreezeVolumeHandler(logger, admin),
+		volumedriver.ThawVolumeRoute:         newThawVolumeHandler(logger, admin),
+		volumedriver.ImportMountRoute:        newImportMountHandler(logger, admin),
+		volumedriver.RemountVolumeRoute:      newRemountVolumeHandler(logger, admin),
+		volumedriver.DiscoverExportsRoute:    newDiscoverExportsHandler(logger, admin),
+		volumedriver.RevokeReferencesRoute:   newRevokeReferencesHandler(logger, admin),
+		volumedriver.DrainRoute:              newDrainHandler(logger, admin),
+		volumedriver.RemoveVolumeRoute:       newRemoveVolumeHandler(logger, admin),
+		volumedriver.InFlightOperationsRoute: newInFlightOperationsHandler(logger, admin),
+		volumedriver.ListSnapshotsRoute:      newListSnapshotsHandler(logger, admin),
+		volumedriver.RestoreSnapshotRoute:    newRestoreSnapshotHandler(logger, admin),
+		volumedriver.BindVolumeRoute:         newBindVolumeHandler(logger, admin),
+		volumedriver.UnbindVolumeRoute:       newUnbindVolumeHandler(logger, admin),
+	}
+
+	return rata.NewRouter(volumedriver.AdminRoutes, handlers)
+}
+
+func newResetMountErrorHandler(logger lager.Logger, admin volumedriver.Admin) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		logger := logger.Session("handle-reset-mount-error")
+		logger.Info("start")
+		defer logger.Info("end")
+
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			logger.Error("failed-reading-reset-mount-error-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.ErrorResponse{Err: err.Error()})
+			return
+		}
+
+		var resetRequest volumedriver.ResetMountErrorRequest
+		if err = json.Unmarshal(body, &resetRequest); err != nil {
+			logger.Error("failed-unmarshalling-reset-mount-error-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.ErrorResponse{Err: err.Error()})
+			return
+		}
+
+		resetResponse := admin.ResetMountError(driverhttp.EnvWithMonitor(logger, req.Context(), w), resetRequest)
+		if resetResponse.Err != "" {
+			logger.Error("failed-resetting-mount-error", errors.New(resetResponse.Err), lager.Data{"volume": resetRequest.Name})
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, resetResponse)
+			return
+		}
+
+		cf_http_handlers.WriteJSONResponse(w, StatusOK, resetResponse)
+	}
+}
+
+func newExportStateHandler(logger lager.Logger, admin volumedriver.Admin) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		logger := logger.Session("handle-export-state")
+		logger.Info("start")
+		defer logger.Info("end")
+
+		exportResponse := admin.ExportState(driverhttp.EnvWithMonitor(logger, req.Context(), w))
+		if exportResponse.Err != "" {
+			logger.Error("failed-exporting-state", errors.New(exportResponse.Err))
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, exportResponse)
+			return
+		}
+
+		cf_http_handlers.WriteJSONResponse(w, StatusOK, exportResponse)
+	}
+}
+
+func newImportStateHandler(logger lager.Logger, admin volumedriver.Admin) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		logger := logger.Session("handle-import-state")
+		logger.Info("start")
+		defer logger.Info("end")
+
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			logger.Error("failed-reading-import-state-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.ErrorResponse{Err: err.Error()})
+			return
+		}
+
+		var importRequest volumedriver.ImportStateRequest
+		if err = json.Unmarshal(body, &importRequest); err != nil {
+			logger.Error("failed-unmarshalling-import-state-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.ErrorResponse{Err: err.Error()})
+			return
+		}
+
+		importResponse := admin.ImportState(driverhttp.EnvWithMonitor(logger, req.Context(), w), importRequest)
+		if importResponse.Err != "" {
+			logger.Error("failed-importing-state", errors.New(importResponse.Err))
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, importResponse)
+			return
+		}
+
+		cf_http_handlers.WriteJSONResponse(w, StatusOK, importResponse)
+	}
+}
+
+func newListVolumesHandler(logger lager.Logger, admin volumedriver.Admin) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		logger := logger.Session("handle-list-volumes")
+		logger.Info("start")
+		defer logger.Info("end")
+
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			logger.Error("failed-reading-list-volumes-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, volumedriver.ListVolumesResponse{Err: err.Error()})
+			return
+		}
+
+		var listRequest volumedriver.ListVolumesRequest
+		if len(body) > 0 {
+			if err = json.Unmarshal(body, &listRequest); err != nil {
+				logger.Error("failed-unmarshalling-list-volumes-request-body", err)
+				cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, volumedriver.ListVolumesResponse{Err: err.Error()})
+				return
+			}
+		}
+
+		listResponse := admin.ListVolumes(driverhttp.EnvWithMonitor(logger, req.Context(), w), listRequest)
+		if listResponse.Err != "" {
+			logger.Error("failed-listing-volumes", errors.New(listResponse.Err))
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, listResponse)
+			return
+		}
+
+		cf_http_handlers.WriteJSONResponse(w, StatusOK, listResponse)
+	}
+}
+
+func newCloneHandler(logger lager.Logger, admin volumedriver.Admin) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		logger := logger.Session("handle-clone")
+		logger.Info("start")
+		defer logger.Info("end")
+
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			logger.Error("failed-reading-clone-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.ErrorResponse{Err: err.Error()})
+			return
+		}
+
+		var cloneRequest volumedriver.CloneRequest
+		if err = json.Unmarshal(body, &cloneRequest); err != nil {
+			logger.Error("failed-unmarshalling-clone-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.ErrorResponse{Err: err.Error()})
+			return
+		}
+
+		cloneResponse := admin.Clone(driverhttp.EnvWithMonitor(logger, req.Context(), w), cloneRequest)
+		if cloneResponse.Err != "" {
+			logger.Error("failed-cloning-volume", errors.New(cloneResponse.Err), lager.Data{"volume": cloneRequest.Name, "from": cloneRequest.From})
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, cloneResponse)
+			return
+		}
+
+		cf_http_handlers.WriteJSONResponse(w, StatusOK, cloneResponse)
+	}
+}
+
+func newDescribeVolumeHandler(logger lager.Logger, admin volumedriver.Admin) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		logger := logger.Session("handle-describe-volume")
+		logger.Info("start")
+		defer logger.Info("end")
+
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			logger.Error("failed-reading-describe-volume-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, volumedriver.DescribeVolumeResponse{Err: err.Error()})
+			return
+		}
+
+		var describeRequest volumedriver.DescribeVolumeRequest
+		if err = json.Unmarshal(body, &describeRequest); err != nil {
+			logger.Error("failed-unmarshalling-describe-volume-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, volumedriver.DescribeVolumeResponse{Err: err.Error()})
+			return
+		}
+
+		describeResponse := admin.DescribeVolume(driverhttp.EnvWithMonitor(logger, req.Context(), w), describeRequest)
+		if describeResponse.Err != "" {
+			logger.Error("failed-describing-volume", errors.New(describeResponse.Err), lager.Data{"volume": describeRequest.Name})
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, describeResponse)
+			return
+		}
+
+		cf_http_handlers.WriteJSONResponse(w, StatusOK, describeResponse)
+	}
+}
+
+func newFreezeVolumeHandler(logger lager.Logger, admin volumedriver.Admin) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		logger := logger.Session("handle-freeze-volume")
+		logger.Info("start")
+		defer logger.Info("end")
+
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			logger.Error("failed-reading-freeze-volume-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.ErrorResponse{Err: err.Error()})
+			return
+		}
+
+		var freezeRequest volumedriver.FreezeVolumeRequest
+		if err = json.Unmarshal(body, &freezeRequest); err != nil {
+			logger.Error("failed-unmarshalling-freeze-volume-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.ErrorResponse{Err: err.Error()})
+			return
+		}
+
+		freezeResponse := admin.FreezeVolume(driverhttp.EnvWithMonitor(logger, req.Context(), w), freezeRequest)
+		if freezeResponse.Err != "" {
+			logger.Error("failed-freezing-volume", errors.New(freezeResponse.Err), lager.Data{"volume": freezeRequest.Name})
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, freezeResponse)
+			return
+		}
+
+		cf_http_handlers.WriteJSONResponse(w, StatusOK, freezeResponse)
+	}
+}
+
+func newThawVolumeHandler(logger lager.Logger, admin volumedriver.Admin) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		logger := logger.Session("handle-thaw-volume")
+		logger.Info("start")
+		defer logger.Info("end")
+
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			logger.Error("failed-reading-thaw-volume-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.ErrorResponse{Err: err.Error()})
+			return
+		}
+
+		var thawRequest volumedriver.ThawVolumeRequest
+		if err = json.Unmarshal(body, &thawRequest); err != nil {
+			logger.Error("failed-unmarshalling-thaw-volume-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.ErrorResponse{Err: err.Error()})
+			return
+		}
+
+		thawResponse := admin.ThawVolume(driverhttp.EnvWithMonitor(logger, req.Context(), w), thawRequest)
+		if thawResponse.Err != "" {
+			logger.Error("failed-thawing-volume", errors.New(thawResponse.Err), lager.Data{"volume": thawRequest.Name})
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, thawResponse)
+			return
+		}
+
+		cf_http_handlers.WriteJSONResponse(w, StatusOK, thawResponse)
+	}
+}
+
+func newImportMountHandler(logger lager.Logger, admin volumedriver.Admin) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		logger := logger.Session("handle-import-mount")
+		logger.Info("start")
+		defer logger.Info("end")
+
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			logger.Error("failed-reading-import-mount-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.ErrorResponse{Err: err.Error()})
+			return
+		}
+
+		var importRequest volumedriver.ImportMountRequest
+		if err = json.Unmarshal(body, &importRequest); err != nil {
+			logger.Error("failed-unmarshalling-import-mount-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.ErrorResponse{Err: err.Error()})
+			return
+		}
+
+		importResponse := admin.ImportMount(driverhttp.EnvWithMonitor(logger, req.Context(), w), importRequest)
+		if importResponse.Err != "" {
+			logger.Error("failed-importing-mount", errors.New(importResponse.Err), lager.Data{"volume": importRequest.Name})
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, importResponse)
+			return
+		}
+
+		cf_http_handlers.WriteJSONResponse(w, StatusOK, importResponse)
+	}
+}
+
+func newRemountVolumeHandler(logger lager.Logger, admin volumedriver.Admin) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		logger := logger.Session("handle-remount-volume")
+		logger.Info("start")
+		defer logger.Info("end")
+
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			logger.Error("failed-reading-remount-volume-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.ErrorResponse{Err: err.Error()})
+			return
+		}
+
+		var remountRequest volumedriver.RemountVolumeRequest
+		if err = json.Unmarshal(body, &remountRequest); err != nil {
+			logger.Error("failed-unmarshalling-remount-volume-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.ErrorResponse{Err: err.Error()})
+			return
+		}
+
+		remountResponse := admin.RemountVolume(driverhttp.EnvWithMonitor(logger, req.Context(), w), remountRequest)
+		if remountResponse.Err != "" {
+			logger.Error("failed-remounting-volume", errors.New(remountResponse.Err), lager.Data{"volume": remountRequest.Name})
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, remountResponse)
+			return
+		}
+
+		cf_http_handlers.WriteJSONResponse(w, StatusOK, remountResponse)
+	}
+}
+
+func newDiscoverExportsHandler(logger lager.Logger, admin volumedriver.Admin) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		logger := logger.Session("handle-discover-exports")
+		logger.Info("start")
+		defer logger.Info("end")
+
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			logger.Error("failed-reading-discover-exports-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, volumedriver.DiscoverExportsResponse{Err: err.Error()})
+			return
+		}
+
+		var discoverRequest volumedriver.DiscoverExportsRequest
+		if err = json.Unmarshal(body, &discoverRequest); err != nil {
+			logger.Error("failed-unmarshalling-discover-exports-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, volumedriver.DiscoverExportsResponse{Err: err.Error()})
+			return
+		}
+
+		discoverResponse := admin.DiscoverExports(driverhttp.EnvWithMonitor(logger, req.Context(), w), discoverRequest)
+		if discoverResponse.Err != "" {
+			logger.Error("failed-discovering-exports", errors.New(discoverResponse.Err), lager.Data{"server": discoverRequest.Server})
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, discoverResponse)
+			return
+		}
+
+		cf_http_handlers.WriteJSONResponse(w, StatusOK, discoverResponse)
+	}
+}
+
+func newRevokeReferencesHandler(logger lager.Logger, admin volumedriver.Admin) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		logger := logger.Session("handle-revoke-references")
+		logger.Info("start")
+		defer logger.Info("end")
+
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			logger.Error("failed-reading-revoke-references-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, volumedriver.RevokeReferencesResponse{Err: err.Error()})
+			return
+		}
+
+		var revokeRequest volumedriver.RevokeReferencesRequest
+		if err = json.Unmarshal(body, &revokeRequest); err != nil {
+			logger.Error("failed-unmarshalling-revoke-references-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, volumedriver.RevokeReferencesResponse{Err: err.Error()})
+			return
+		}
+
+		revokeResponse := admin.RevokeReferences(driverhttp.EnvWithMonitor(logger, req.Context(), w), revokeRequest)
+		if revokeResponse.Err != "" {
+			logger.Error("failed-revoking-references", errors.New(revokeResponse.Err), lager.Data{"caller": revokeRequest.Caller, "volume": revokeRequest.Name})
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, revokeResponse)
+			return
+		}
+
+		cf_http_handlers.WriteJSONResponse(w, StatusOK, revokeResponse)
+	}
+}
+
+func newDrainHandler(logger lager.Logger, admin volumedriver.Admin) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		logger := logger.Session("handle-drain")
+		logger.Info("start")
+		defer logger.Info("end")
+
+		drainResponse := admin.Drain(driverhttp.EnvWithMonitor(logger, req.Context(), w))
+		if drainResponse.Err != "" {
+			logger.Error("failed-draining", errors.New(drainResponse.Err))
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, drainResponse)
+			return
+		}
+
+		cf_http_handlers.WriteJSONResponse(w, StatusOK, drainResponse)
+	}
+}
+
+func newRemoveVolumeHandler(logger lager.Logger, admin volumedriver.Admin) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		logger := logger.Session("handle-remove-volume")
+		logger.Info("start")
+		defer logger.Info("end")
+
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			logger.Error("failed-reading-remove-volume-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.ErrorResponse{Err: err.Error()})
+			return
+		}
+
+		var removeRequest volumedriver.RemoveVolumeRequest
+		if err = json.Unmarshal(body, &removeRequest); err != nil {
+			logger.Error("failed-unmarshalling-remove-volume-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.ErrorResponse{Err: err.Error()})
+			return
+		}
+
+		removeResponse := admin.RemoveVolume(driverhttp.EnvWithMonitor(logger, req.Context(), w), removeRequest)
+		if removeResponse.Err != "" {
+			logger.Error("failed-removing-volume", errors.New(removeResponse.Err), lager.Data{"volume": removeRequest.Name, "force": removeRequest.Force})
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, removeResponse)
+			return
+		}
+
+		cf_http_handlers.WriteJSONResponse(w, StatusOK, removeResponse)
+	}
+}
+
+func newInFlightOperationsHandler(logger lager.Logger, admin volumedriver.Admin) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		logger := logger.Session("handle-in-flight-operations")
+		logger.Info("start")
+		defer logger.Info("end")
+
+		operationsResponse := admin.InFlightOperations(driverhttp.EnvWithMonitor(logger, req.Context(), w))
+		if operationsResponse.Err != "" {
+			logger.Error("failed-listing-in-flight-operations", errors.New(operationsResponse.Err))
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, operationsResponse)
+			return
+		}
+
+		cf_http_handlers.WriteJSONResponse(w, StatusOK, operationsResponse)
+	}
+}
+
+func newBindVolumeHandler(logger lager.Logger, admin volumedriver.Admin) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		logger := logger.Session("handle-bind-volume")
+		logger.Info("start")
+		defer logger.Info("end")
+
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			logger.Error("failed-reading-bind-volume-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.ErrorResponse{Err: err.Error()})
+			return
+		}
+
+		var bindRequest volumedriver.BindVolumeRequest
+		if err = json.Unmarshal(body, &bindRequest); err != nil {
+			logger.Error("failed-unmarshalling-bind-volume-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.ErrorResponse{Err: err.Error()})
+			return
+		}
+
+		bindResponse := admin.BindVolume(driverhttp.EnvWithMonitor(logger, req.Context(), w), bindRequest)
+		if bindResponse.Err != "" {
+			logger.Error("failed-binding-volume", errors.New(bindResponse.Err), lager.Data{"volume": bindRequest.Name, "target": bindRequest.Target})
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, bindResponse)
+			return
+		}
+
+		cf_http_handlers.WriteJSONResponse(w, StatusOK, bindResponse)
+	}
+}
+
+func newUnbindVolumeHandler(logger lager.Logger, admin volumedriver.Admin) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		logger := logger.Session("handle-unbind-volume")
+		logger.Info("start")
+		defer logger.Info("end")
+
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			logger.Error("failed-reading-unbind-volume-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.ErrorResponse{Err: err.Error()})
+			return
+		}
+
+		var unbindRequest volumedriver.UnbindVolumeRequest
+		if err = json.Unmarshal(body, &unbindRequest); err != nil {
+			logger.Error("failed-unmarshalling-unbind-volume-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.ErrorResponse{Err: err.Error()})
+			return
+		}
+
+		unbindResponse := admin.UnbindVolume(driverhttp.EnvWithMonitor(logger, req.Context(), w), unbindRequest)
+		if unbindResponse.Err != "" {
+			logger.Error("failed-unbinding-volume", errors.New(unbindResponse.Err), lager.Data{"volume": unbindRequest.Name, "target": unbindRequest.Target})
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, unbindResponse)
+			return
+		}
+
+		cf_http_handlers.WriteJSONResponse(w, StatusOK, unbindResponse)
+	}
+}
+
+func newListSnapshotsHandler(logger lager.Logger, admin volumedriver.Admin) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		logger := logger.Session("handle-list-snapshots")
+		logger.Info("start")
+		defer logger.Info("end")
+
+		snapshotsResponse := admin.ListSnapshots(driverhttp.EnvWithMonitor(logger, req.Context(), w))
+		if snapshotsResponse.Err != "" {
+			logger.Error("failed-listing-snapshots", errors.New(snapshotsResponse.Err))
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, snapshotsResponse)
+			return
+		}
+
+		cf_http_handlers.WriteJSONResponse(w, StatusOK, snapshotsResponse)
+	}
+}
+
+func newRestoreSnapshotHandler(logger lager.Logger, admin volumedriver.Admin) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		logger := logger.Session("handle-restore-snapshot")
+		logger.Info("start")
+		defer logger.Info("end")
+
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			logger.Error("failed-reading-restore-snapshot-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.ErrorResponse{Err: err.Error()})
+			return
+		}
+
+		var restoreRequest volumedriver.RestoreSnapshotRequest
+		if err = json.Unmarshal(body, &restoreRequest); err != nil {
+			logger.Error("failed-unmarshalling-restore-snapshot-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, dockerdriver.ErrorResponse{Err: err.Error()})
+			return
+		}
+
+		restoreResponse := admin.RestoreSnapshot(driverhttp.EnvWithMonitor(logger, req.Context(), w), restoreRequest)
+		if restoreResponse.Err != "" {
+			logger.Error("failed-restoring-snapshot", errors.New(restoreResponse.Err), lager.Data{"id": restoreRequest.ID, "force": restoreRequest.Force})
+			cf_http_handlers.WriteJSONResponse(w, StatusInternalServerError, restoreResponse)
+			return
+		}
+
+		cf_http_handlers.WriteJSONResponse(w, StatusOK, restoreResponse)
+	}
+}
@@ -0,0 +1,548 @@
+package adminhttp_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/adminhttp"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/rata"
+)
+
+var _ = Describe("Admin Handlers", func() {
+	var (
+		testLogger *lagertest.TestLogger
+		fakeAdmin  *volumedriverfakes.FakeAdmin
+		handler    http.Handler
+	)
+
+	BeforeEach(func() {
+		testLogger = lagertest.NewTestLogger("adminhttp-test")
+		fakeAdmin = &volumedriverfakes.FakeAdmin{}
+
+		var err error
+		handler, err = adminhttp.NewHandler(testLogger, fakeAdmin)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("ResetMountError", func() {
+		var (
+			body     []byte
+			recorder *httptest.ResponseRecorder
+		)
+
+		BeforeEach(func() {
+			var err error
+			body, err = json.Marshal(volumedriver.ResetMountErrorRequest{Name: "some-volume"})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		JustBeforeEach(func() {
+			path, err := volumedriver.AdminRoutes.CreatePathForRoute(volumedriver.ResetMountErrorRoute, rata.Params{})
+			Expect(err).NotTo(HaveOccurred())
+
+			request, err := http.NewRequest("POST", path, bytes.NewReader(body))
+			Expect(err).NotTo(HaveOccurred())
+
+			recorder = httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+		})
+
+		It("passes the request to the driver", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(fakeAdmin.ResetMountErrorCallCount()).To(Equal(1))
+			_, resetRequest := fakeAdmin.ResetMountErrorArgsForCall(0)
+			Expect(resetRequest.Name).To(Equal("some-volume"))
+		})
+
+		Context("when the driver returns an error", func() {
+			BeforeEach(func() {
+				fakeAdmin.ResetMountErrorReturns(dockerdriver.ErrorResponse{Err: "badness"})
+			})
+
+			It("returns the error in the body", func() {
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				respBody, err := ioutil.ReadAll(recorder.Body)
+				Expect(err).NotTo(HaveOccurred())
+
+				var response dockerdriver.ErrorResponse
+				Expect(json.Unmarshal(respBody, &response)).To(Succeed())
+				Expect(response.Err).To(Equal("badness"))
+			})
+		})
+
+		Context("when the body is not valid json", func() {
+			BeforeEach(func() {
+				body = []byte("not json")
+			})
+
+			It("returns an error and does not call the driver", func() {
+				var response dockerdriver.ErrorResponse
+				Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+				Expect(response.Err).NotTo(BeEmpty())
+				Expect(fakeAdmin.ResetMountErrorCallCount()).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("ExportState", func() {
+		It("returns the exported volumes", func() {
+			fakeAdmin.ExportStateReturns(volumedriver.ExportStateResponse{
+				Volumes: []volumedriver.ExportedVolume{{
+					VolumeInfo: dockerdriver.VolumeInfo{Name: "some-volume", Mountpoint: "/some/path", MountCount: 1},
+					Opts:       map[string]interface{}{"source": "server:/export"},
+				}},
+			})
+
+			recorder := serve(handler, volumedriver.ExportStateRoute, nil)
+
+			var response volumedriver.ExportStateResponse
+			Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+			Expect(response.Volumes).To(ConsistOf(volumedriver.ExportedVolume{
+				VolumeInfo: dockerdriver.VolumeInfo{Name: "some-volume", Mountpoint: "/some/path", MountCount: 1},
+				Opts:       map[string]interface{}{"source": "server:/export"},
+			}))
+		})
+	})
+
+	Describe("ImportState", func() {
+		It("passes the volumes to the driver", func() {
+			body, err := json.Marshal(volumedriver.ImportStateRequest{
+				Volumes:   []volumedriver.ExportedVolume{{VolumeInfo: dockerdriver.VolumeInfo{Name: "some-volume"}}},
+				Overwrite: true,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			recorder := serve(handler, volumedriver.ImportStateRoute, body)
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+
+			Expect(fakeAdmin.ImportStateCallCount()).To(Equal(1))
+			_, importRequest := fakeAdmin.ImportStateArgsForCall(0)
+			Expect(importRequest.Overwrite).To(BeTrue())
+			Expect(importRequest.Volumes).To(ConsistOf(volumedriver.ExportedVolume{VolumeInfo: dockerdriver.VolumeInfo{Name: "some-volume"}}))
+		})
+
+		Context("when the driver returns an error", func() {
+			BeforeEach(func() {
+				fakeAdmin.ImportStateReturns(dockerdriver.ErrorResponse{Err: "badness"})
+			})
+
+			It("returns the error in the body", func() {
+				recorder := serve(handler, volumedriver.ImportStateRoute, []byte("{}"))
+
+				var response dockerdriver.ErrorResponse
+				Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+				Expect(response.Err).To(Equal("badness"))
+			})
+		})
+	})
+
+	Describe("ListVolumes", func() {
+		BeforeEach(func() {
+			fakeAdmin.ListVolumesReturns(volumedriver.ListVolumesResponse{
+				Volumes:   []dockerdriver.VolumeInfo{{Name: "some-volume"}},
+				NextToken: "some-token",
+			})
+		})
+
+		It("passes the filters to the driver and returns the page", func() {
+			body, err := json.Marshal(volumedriver.ListVolumesRequest{NamePrefix: "some-", Limit: 1})
+			Expect(err).NotTo(HaveOccurred())
+
+			recorder := serve(handler, volumedriver.ListVolumesRoute, body)
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+
+			Expect(fakeAdmin.ListVolumesCallCount()).To(Equal(1))
+			_, listRequest := fakeAdmin.ListVolumesArgsForCall(0)
+			Expect(listRequest).To(Equal(volumedriver.ListVolumesRequest{NamePrefix: "some-", Limit: 1}))
+
+			var response volumedriver.ListVolumesResponse
+			Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+			Expect(response.Volumes).To(HaveLen(1))
+			Expect(response.NextToken).To(Equal("some-token"))
+		})
+
+		It("lists everything when the body is empty", func() {
+			serve(handler, volumedriver.ListVolumesRoute, nil)
+			_, listRequest := fakeAdmin.ListVolumesArgsForCall(0)
+			Expect(listRequest).To(Equal(volumedriver.ListVolumesRequest{}))
+		})
+
+		Context("when the body is not valid json", func() {
+			It("returns the error in the body", func() {
+				recorder := serve(handler, volumedriver.ListVolumesRoute, []byte("{"))
+				Expect(fakeAdmin.ListVolumesCallCount()).To(BeZero())
+
+				var response volumedriver.ListVolumesResponse
+				Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+				Expect(response.Err).NotTo(BeEmpty())
+			})
+		})
+	})
+
+	Describe("Clone", func() {
+		var cloneRequest volumedriver.CloneRequest
+
+		BeforeEach(func() {
+			cloneRequest = volumedriver.CloneRequest{Name: "green", From: "blue", Subdirectory: "app/blue", Target: "app/green"}
+		})
+
+		It("passes the request to the driver", func() {
+			body, err := json.Marshal(cloneRequest)
+			Expect(err).NotTo(HaveOccurred())
+
+			recorder := serve(handler, volumedriver.CloneRoute, body)
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(fakeAdmin.CloneCallCount()).To(Equal(1))
+			_, passed := fakeAdmin.CloneArgsForCall(0)
+			Expect(passed).To(Equal(cloneRequest))
+		})
+
+		Context("when the driver returns an error", func() {
+			BeforeEach(func() {
+				fakeAdmin.CloneReturns(dockerdriver.ErrorResponse{Err: "badness"})
+			})
+
+			It("returns the error in the body", func() {
+				body, err := json.Marshal(cloneRequest)
+				Expect(err).NotTo(HaveOccurred())
+
+				recorder := serve(handler, volumedriver.CloneRoute, body)
+				var response dockerdriver.ErrorResponse
+				Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+				Expect(response.Err).To(Equal("badness"))
+			})
+		})
+
+		Context("when the body is not valid json", func() {
+			It("returns an error and does not call the driver", func() {
+				recorder := serve(handler, volumedriver.CloneRoute, []byte("not json"))
+				var response dockerdriver.ErrorResponse
+				Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+				Expect(response.Err).NotTo(BeEmpty())
+				Expect(fakeAdmin.CloneCallCount()).To(BeZero())
+			})
+		})
+	})
+
+	Describe("DescribeVolume", func() {
+		It("returns the description of the volume", func() {
+			fakeAdmin.DescribeVolumeReturns(volumedriver.DescribeVolumeResponse{
+				Volume: volumedriver.VolumeDescription{Name: "some-volume", MountCount: 2, LastMountError: "badness"},
+			})
+
+			recorder := serve(handler, volumedriver.DescribeVolumeRoute, []byte(`{"Name":"some-volume"}`))
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			_, describeRequest := fakeAdmin.DescribeVolumeArgsForCall(0)
+			Expect(describeRequest.Name).To(Equal("some-volume"))
+
+			var response volumedriver.DescribeVolumeResponse
+			Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+			Expect(response.Volume.MountCount).To(Equal(2))
+			Expect(response.Volume.LastMountError).To(Equal("badness"))
+		})
+
+		Context("when the body is not valid json", func() {
+			It("returns an error and does not call the driver", func() {
+				recorder := serve(handler, volumedriver.DescribeVolumeRoute, []byte("{"))
+				var response volumedriver.DescribeVolumeResponse
+				Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+				Expect(response.Err).NotTo(BeEmpty())
+				Expect(fakeAdmin.DescribeVolumeCallCount()).To(BeZero())
+			})
+		})
+	})
+
+	Describe("FreezeVolume", func() {
+		It("passes the request to the driver", func() {
+			recorder := serve(handler, volumedriver.FreezeVolumeRoute, []byte(`{"Name":"volume"}`))
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(fakeAdmin.FreezeVolumeCallCount()).To(Equal(1))
+			_, passed := fakeAdmin.FreezeVolumeArgsForCall(0)
+			Expect(passed).To(Equal(volumedriver.FreezeVolumeRequest{Name: "volume"}))
+		})
+
+		Context("when the driver returns an error", func() {
+			BeforeEach(func() {
+				fakeAdmin.FreezeVolumeReturns(dockerdriver.ErrorResponse{Err: "badness"})
+			})
+
+			It("returns the error in the body", func() {
+				recorder := serve(handler, volumedriver.FreezeVolumeRoute, []byte(`{"Name":"volume"}`))
+				var response dockerdriver.ErrorResponse
+				Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+				Expect(response.Err).To(Equal("badness"))
+			})
+		})
+
+		Context("when the body is not valid json", func() {
+			It("returns an error and does not call the driver", func() {
+				serve(handler, volumedriver.FreezeVolumeRoute, []byte("not json"))
+				Expect(fakeAdmin.FreezeVolumeCallCount()).To(BeZero())
+			})
+		})
+	})
+
+	Describe("ThawVolume", func() {
+		It("passes the request to the driver", func() {
+			recorder := serve(handler, volumedriver.ThawVolumeRoute, []byte(`{"Name":"volume"}`))
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(fakeAdmin.ThawVolumeCallCount()).To(Equal(1))
+			_, passed := fakeAdmin.ThawVolumeArgsForCall(0)
+			Expect(passed).To(Equal(volumedriver.ThawVolumeRequest{Name: "volume"}))
+		})
+
+		Context("when the driver returns an error", func() {
+			BeforeEach(func() {
+				fakeAdmin.ThawVolumeReturns(dockerdriver.ErrorResponse{Err: "badness"})
+			})
+
+			It("returns the error in the body", func() {
+				recorder := serve(handler, volumedriver.ThawVolumeRoute, []byte(`{"Name":"volume"}`))
+				var response dockerdriver.ErrorResponse
+				Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+				Expect(response.Err).To(Equal("badness"))
+			})
+		})
+	})
+
+	Describe("ImportMount", func() {
+		It("passes the request to the driver", func() {
+			recorder := serve(handler, volumedriver.ImportMountRoute, []byte(`{"Name":"volume","Mountpoint":"/mnt/volume","Opts":{"source":"server:/export"},"MountCount":2}`))
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(fakeAdmin.ImportMountCallCount()).To(Equal(1))
+			_, passed := fakeAdmin.ImportMountArgsForCall(0)
+			Expect(passed).To(Equal(volumedriver.ImportMountRequest{
+				Name:       "volume",
+				Mountpoint: "/mnt/volume",
+				Opts:       map[string]interface{}{"source": "server:/export"},
+				MountCount: 2,
+			}))
+		})
+
+		Context("when the driver returns an error", func() {
+			BeforeEach(func() {
+				fakeAdmin.ImportMountReturns(dockerdriver.ErrorResponse{Err: "badness"})
+			})
+
+			It("returns the error in the body", func() {
+				recorder := serve(handler, volumedriver.ImportMountRoute, []byte(`{"Name":"volume"}`))
+				var response dockerdriver.ErrorResponse
+				Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+				Expect(response.Err).To(Equal("badness"))
+			})
+		})
+	})
+
+	Describe("RemountVolume", func() {
+		It("passes the request to the driver", func() {
+			recorder := serve(handler, volumedriver.RemountVolumeRoute, []byte(`{"Name":"volume","Opts":{"ro":true}}`))
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(fakeAdmin.RemountVolumeCallCount()).To(Equal(1))
+			_, passed := fakeAdmin.RemountVolumeArgsForCall(0)
+			Expect(passed).To(Equal(volumedriver.RemountVolumeRequest{Name: "volume", Opts: map[string]interface{}{"ro": true}}))
+		})
+
+		Context("when the driver returns an error", func() {
+			BeforeEach(func() {
+				fakeAdmin.RemountVolumeReturns(dockerdriver.ErrorResponse{Err: "badness"})
+			})
+
+			It("returns the error in the body", func() {
+				recorder := serve(handler, volumedriver.RemountVolumeRoute, []byte(`{"Name":"volume","Opts":{"ro":true}}`))
+				var response dockerdriver.ErrorResponse
+				Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+				Expect(response.Err).To(Equal("badness"))
+			})
+		})
+	})
+
+	Describe("DiscoverExports", func() {
+		It("returns the exports of the server", func() {
+			fakeAdmin.DiscoverExportsReturns(volumedriver.DiscoverExportsResponse{Exports: []volumedriver.Export{{Path: "/export", Source: "filer:/export"}}})
+
+			recorder := serve(handler, volumedriver.DiscoverExportsRoute, []byte(`{"Server":"filer"}`))
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			_, passed := fakeAdmin.DiscoverExportsArgsForCall(0)
+			Expect(passed).To(Equal(volumedriver.DiscoverExportsRequest{Server: "filer"}))
+
+			var response volumedriver.DiscoverExportsResponse
+			Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+			Expect(response.Exports).To(Equal([]volumedriver.Export{{Path: "/export", Source: "filer:/export"}}))
+		})
+
+		Context("when the driver returns an error", func() {
+			BeforeEach(func() {
+				fakeAdmin.DiscoverExportsReturns(volumedriver.DiscoverExportsResponse{Err: "badness"})
+			})
+
+			It("returns the error in the body", func() {
+				recorder := serve(handler, volumedriver.DiscoverExportsRoute, []byte(`{"Server":"filer"}`))
+				var response volumedriver.DiscoverExportsResponse
+				Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+				Expect(response.Err).To(Equal("badness"))
+			})
+		})
+	})
+
+	Describe("RevokeReferences", func() {
+		It("passes the request to the driver and returns the number of revoked references", func() {
+			fakeAdmin.RevokeReferencesReturns(volumedriver.RevokeReferencesResponse{Revoked: 2})
+
+			recorder := serve(handler, volumedriver.RevokeReferencesRoute, []byte(`{"Caller":"container-guid"}`))
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			_, passed := fakeAdmin.RevokeReferencesArgsForCall(0)
+			Expect(passed).To(Equal(volumedriver.RevokeReferencesRequest{Caller: "container-guid"}))
+
+			var response volumedriver.RevokeReferencesResponse
+			Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+			Expect(response.Revoked).To(Equal(2))
+		})
+	})
+
+	Describe("Drain", func() {
+		It("drains the driver", func() {
+			fakeAdmin.DrainReturns(volumedriver.DrainResponse{Volumes: []volumedriver.DrainedVolume{{Name: "volume", Mountpoint: "/mnt/volume"}}})
+
+			recorder := serve(handler, volumedriver.DrainRoute, nil)
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(fakeAdmin.DrainCallCount()).To(Equal(1))
+			Expect(recorder.Body.String()).To(MatchJSON(`{"Volumes": [{"Name": "volume", "Mountpoint": "/mnt/volume"}], "Err": ""}`))
+		})
+
+		It("reports drain errors with the report", func() {
+			fakeAdmin.DrainReturns(volumedriver.DrainResponse{
+				Volumes:         []volumedriver.DrainedVolume{{Name: "volume", Mountpoint: "/mnt/volume", Err: "unmount failed"}},
+				RemainingMounts: []string{"/mnt/volume"},
+				Err:             "drain failed to unmount volume",
+			})
+
+			recorder := serve(handler, volumedriver.DrainRoute, nil)
+			Expect(recorder.Body.String()).To(MatchJSON(`{
+				"Volumes": [{"Name": "volume", "Mountpoint": "/mnt/volume", "Err": "unmount failed"}],
+				"RemainingMounts": ["/mnt/volume"],
+				"Err": "drain failed to unmount volume"
+			}`))
+		})
+	})
+
+	Describe("RemoveVolume", func() {
+		It("passes the request to the driver", func() {
+			recorder := serve(handler, volumedriver.RemoveVolumeRoute, []byte(`{"Name":"volume","Force":true}`))
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(fakeAdmin.RemoveVolumeCallCount()).To(Equal(1))
+			_, passed := fakeAdmin.RemoveVolumeArgsForCall(0)
+			Expect(passed).To(Equal(volumedriver.RemoveVolumeRequest{Name: "volume", Force: true}))
+		})
+
+		It("returns the error in the body", func() {
+			fakeAdmin.RemoveVolumeReturns(dockerdriver.ErrorResponse{Err: "badness"})
+
+			recorder := serve(handler, volumedriver.RemoveVolumeRoute, []byte(`{"Name":"volume"}`))
+			Expect(recorder.Body.String()).To(MatchJSON(`{"Err": "badness"}`))
+		})
+	})
+
+	Describe("InFlightOperations", func() {
+		It("returns the operations of the driver", func() {
+			fakeAdmin.InFlightOperationsReturns(volumedriver.InFlightOperationsResponse{
+				Operations: []volumedriver.InFlightOperation{{Operation: "mount", Volume: "some-volume", Caller: "some-app", State: volumedriver.OperationQueued}},
+			})
+
+			recorder := serve(handler, volumedriver.InFlightOperationsRoute, nil)
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+
+			var response volumedriver.InFlightOperationsResponse
+			Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+			Expect(response.Operations).To(HaveLen(1))
+			Expect(response.Operations[0].Caller).To(Equal("some-app"))
+			Expect(response.Operations[0].State).To(Equal(volumedriver.OperationQueued))
+		})
+	})
+
+	Describe("BindVolume", func() {
+		It("passes the request to the driver", func() {
+			recorder := serve(handler, volumedriver.BindVolumeRoute, []byte(`{"Name":"volume","Target":"/containers/a/volume","ReadOnly":true}`))
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(fakeAdmin.BindVolumeCallCount()).To(Equal(1))
+			_, passed := fakeAdmin.BindVolumeArgsForCall(0)
+			Expect(passed).To(Equal(volumedriver.BindVolumeRequest{Name: "volume", Target: "/containers/a/volume", ReadOnly: true}))
+		})
+
+		It("returns the error in the body", func() {
+			fakeAdmin.BindVolumeReturns(dockerdriver.ErrorResponse{Err: "badness"})
+
+			recorder := serve(handler, volumedriver.BindVolumeRoute, []byte(`{"Name":"volume"}`))
+			Expect(recorder.Body.String()).To(MatchJSON(`{"Err": "badness"}`))
+		})
+	})
+
+	Describe("UnbindVolume", func() {
+		It("passes the request to the driver", func() {
+			recorder := serve(handler, volumedriver.UnbindVolumeRoute, []byte(`{"Name":"volume","Target":"/containers/a/volume"}`))
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(fakeAdmin.UnbindVolumeCallCount()).To(Equal(1))
+			_, passed := fakeAdmin.UnbindVolumeArgsForCall(0)
+			Expect(passed).To(Equal(volumedriver.UnbindVolumeRequest{Name: "volume", Target: "/containers/a/volume"}))
+		})
+	})
+
+	Describe("ListSnapshots", func() {
+		It("returns the snapshots of the driver", func() {
+			fakeAdmin.ListSnapshotsReturns(volumedriver.ListSnapshotsResponse{Snapshots: []volumedriver.StateSnapshot{{ID: "20200101T000000.000Z", Volumes: 2}}})
+
+			recorder := serve(handler, volumedriver.ListSnapshotsRoute, nil)
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(fakeAdmin.ListSnapshotsCallCount()).To(Equal(1))
+
+			var response volumedriver.ListSnapshotsResponse
+			Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+			Expect(response.Snapshots).To(HaveLen(1))
+			Expect(response.Snapshots[0].ID).To(Equal("20200101T000000.000Z"))
+			Expect(response.Snapshots[0].Volumes).To(Equal(2))
+		})
+	})
+
+	Describe("RestoreSnapshot", func() {
+		It("passes the request to the driver", func() {
+			recorder := serve(handler, volumedriver.RestoreSnapshotRoute, []byte(`{"ID":"20200101T000000.000Z","Force":true}`))
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(fakeAdmin.RestoreSnapshotCallCount()).To(Equal(1))
+			_, passed := fakeAdmin.RestoreSnapshotArgsForCall(0)
+			Expect(passed).To(Equal(volumedriver.RestoreSnapshotRequest{ID: "20200101T000000.000Z", Force: true}))
+		})
+
+		Context("when the driver returns an error", func() {
+			BeforeEach(func() {
+				fakeAdmin.RestoreSnapshotReturns(dockerdriver.ErrorResponse{Err: "badness"})
+			})
+
+			It("returns the error in the body", func() {
+				recorder := serve(handler, volumedriver.RestoreSnapshotRoute, []byte(`{"ID":"20200101T000000.000Z"}`))
+				var response dockerdriver.ErrorResponse
+				Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+				Expect(response.Err).To(Equal("badness"))
+			})
+		})
+	})
+})
+
+func serve(handler http.Handler, route string, body []byte) *httptest.ResponseRecorder {
+	path, err := volumedriver.AdminRoutes.CreatePathForRoute(route, rata.Params{})
+	Expect(err).NotTo(HaveOccurred())
+
+	request, err := http.NewRequest("POST", path, bytes.NewReader(body))
+	Expect(err).NotTo(HaveOccurred())
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
@@ -0,0 +1,47 @@
+package admission
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	cf_http_handlers "code.cloudfoundry.org/cfhttp/handlers"
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+)
+
+const TooManyRequestsError = "Too many concurrent requests, try again later"
+
+// TooManyRequestsRetryAfter is how long callers are asked to wait before they
+// retry a request rejected by the concurrency limit.
+const TooManyRequestsRetryAfter = time.Second
+
+// NewConcurrencyLimitHandler rejects requests once maxInFlight requests are
+// being served. Following the docker plugin API the rejection is reported in
+// the response body with a 200 status code, as a safe error with the Busy
+// code and a RetryAfter. A maxInFlight below 1 disables the limit.
+func NewConcurrencyLimitHandler(logger lager.Logger, handler http.Handler, maxInFlight int) http.Handler {
+	if maxInFlight < 1 {
+		return handler
+	}
+
+	logger = logger.Session("concurrency-limit", lager.Data{"max-in-flight": maxInFlight})
+	slots := make(chan struct{}, maxInFlight)
+
+	rejection := TooManyRequestsError
+	if data, err := json.Marshal(safeerrors.NewBusy(TooManyRequestsRetryAfter, TooManyRequestsError)); err == nil {
+		rejection = string(data)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+			handler.ServeHTTP(w, req)
+		default:
+			logger.Info("request-rejected", lager.Data{"path": req.URL.Path})
+			cf_http_handlers.WriteJSONResponse(w, http.StatusOK, dockerdriver.ErrorResponse{Err: rejection})
+		}
+	})
+}
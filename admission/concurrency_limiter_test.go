@@ -0,0 +1,58 @@
+package admission_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver/admission"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ConcurrencyLimitHandler", func() {
+	var (
+		release chan struct{}
+		started chan struct{}
+		handler http.Handler
+	)
+
+	BeforeEach(func() {
+		release = make(chan struct{})
+		started = make(chan struct{}, 2)
+
+		inner := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			started <- struct{}{}
+			<-release
+			w.WriteHeader(http.StatusOK)
+		})
+		handler = admission.NewConcurrencyLimitHandler(lagertest.NewTestLogger("admission"), inner, 1)
+	})
+
+	It("rejects requests beyond the limit", func() {
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/VolumeDriver.Mount", nil))
+			close(done)
+		}()
+		Eventually(started).Should(Receive())
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/VolumeDriver.Mount", nil))
+
+		var response dockerdriver.ErrorResponse
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+		Expect(response.Err).To(MatchJSON(`{"SafeDescription":"Too many concurrent requests, try again later","Code":"busy","RetryAfter":1}`))
+
+		close(release)
+		Eventually(done).Should(BeClosed())
+
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/VolumeDriver.Mount", nil))
+		Expect(recorder.Body.String()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,111 @@
+package admission
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/goshims/timeshim"
+)
+
+// RateLimit describes a token bucket: Rate requests per second are allowed on
+// average, with bursts of up to Burst requests. A zero Rate disables the limit.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l RateLimit) Enabled() bool {
+	return l.Rate > 0
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter keeps one token bucket per key. The empty key can be used for a
+// single global bucket.
+type RateLimiter struct {
+	limit   RateLimit
+	time    timeshim.Time
+	lock    sync.Mutex
+	buckets map[string]*bucket
+}
+
+func NewRateLimiter(time timeshim.Time, limit RateLimit) *RateLimiter {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+
+	return &RateLimiter{
+		limit:   limit,
+		time:    time,
+		buckets: map[string]*bucket{},
+	}
+}
+
+// Allow takes a token from the bucket of key, if one is available.
+func (r *RateLimiter) Allow(key string) bool {
+	if !r.limit.Enabled() {
+		return true
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := r.time.Now()
+
+	b, ok := r.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(r.limit.Burst), last: now}
+		r.buckets[key] = b
+	}
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * r.limit.Rate
+		if b.tokens > float64(r.limit.Burst) {
+			b.tokens = float64(r.limit.Burst)
+		}
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
+
+// RetryAfter returns how long until the bucket of key has a token again, or
+// zero when it has one.
+func (r *RateLimiter) RetryAfter(key string) time.Duration {
+	if !r.limit.Enabled() {
+		return 0
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	b, ok := r.buckets[key]
+	if !ok {
+		return 0
+	}
+
+	tokens := b.tokens
+	if elapsed := r.time.Now().Sub(b.last); elapsed > 0 {
+		tokens += elapsed.Seconds() * r.limit.Rate
+	}
+	if tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - tokens) / r.limit.Rate * float64(time.Second))
+}
+
+// Forget drops the bucket of key, e.g. once a volume has been removed.
+func (r *RateLimiter) Forget(key string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.buckets, key)
+}
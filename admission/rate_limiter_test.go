@@ -0,0 +1,83 @@
+package admission_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/goshims/timeshim/time_fake"
+	"code.cloudfoundry.org/volumedriver/admission"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RateLimiter", func() {
+	var (
+		fakeTime *time_fake.FakeTime
+		now      time.Time
+		limit    admission.RateLimit
+		limiter  *admission.RateLimiter
+	)
+
+	BeforeEach(func() {
+		now = time.Now()
+		fakeTime = &time_fake.FakeTime{}
+		fakeTime.NowStub = func() time.Time { return now }
+		limit = admission.RateLimit{Rate: 1, Burst: 2}
+	})
+
+	JustBeforeEach(func() {
+		limiter = admission.NewRateLimiter(fakeTime, limit)
+	})
+
+	It("allows bursts up to the burst size", func() {
+		Expect(limiter.Allow("a")).To(BeTrue())
+		Expect(limiter.Allow("a")).To(BeTrue())
+		Expect(limiter.Allow("a")).To(BeFalse())
+	})
+
+	It("refills tokens over time", func() {
+		Expect(limiter.Allow("a")).To(BeTrue())
+		Expect(limiter.Allow("a")).To(BeTrue())
+		Expect(limiter.Allow("a")).To(BeFalse())
+
+		now = now.Add(time.Second)
+		Expect(limiter.Allow("a")).To(BeTrue())
+		Expect(limiter.Allow("a")).To(BeFalse())
+	})
+
+	It("tells how long until the next token", func() {
+		Expect(limiter.RetryAfter("a")).To(BeZero())
+		Expect(limiter.Allow("a")).To(BeTrue())
+		Expect(limiter.Allow("a")).To(BeTrue())
+		Expect(limiter.RetryAfter("a")).To(Equal(time.Second))
+
+		now = now.Add(750 * time.Millisecond)
+		Expect(limiter.RetryAfter("a")).To(Equal(250 * time.Millisecond))
+	})
+
+	It("keeps separate buckets per key", func() {
+		Expect(limiter.Allow("a")).To(BeTrue())
+		Expect(limiter.Allow("a")).To(BeTrue())
+		Expect(limiter.Allow("a")).To(BeFalse())
+		Expect(limiter.Allow("b")).To(BeTrue())
+	})
+
+	It("starts with a full bucket once a key is forgotten", func() {
+		Expect(limiter.Allow("a")).To(BeTrue())
+		Expect(limiter.Allow("a")).To(BeTrue())
+		limiter.Forget("a")
+		Expect(limiter.Allow("a")).To(BeTrue())
+	})
+
+	Context("when the limit is disabled", func() {
+		BeforeEach(func() {
+			limit = admission.RateLimit{}
+		})
+
+		It("allows everything without reading the clock", func() {
+			for i := 0; i < 10; i++ {
+				Expect(limiter.Allow("a")).To(BeTrue())
+			}
+			Expect(fakeTime.NowCallCount()).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,73 @@
+package authz
+
+import (
+	"strings"
+
+	"code.cloudfoundry.org/lager"
+)
+
+// Request describes an API call that is about to be executed. Type is the
+// docker plugin method, e.g. VolumeDriver.Mount, and Volume is empty for calls
+// that are not about a single volume.
+type Request struct {
+	Type   string
+	Volume string
+	Caller string
+}
+
+//go:generate counterfeiter -o ../volumedriverfakes/fake_authorizer.go . Authorizer
+type Authorizer interface {
+	Authorize(logger lager.Logger, request Request) (bool, error)
+}
+
+// Rule allows callers to issue requests. Empty fields and "*" match anything;
+// Types lists request types and VolumePrefix restricts the volumes a caller can
+// address.
+type Rule struct {
+	Caller       string   `json:"caller"`
+	Types        []string `json:"types"`
+	VolumePrefix string   `json:"volume_prefix"`
+}
+
+type policyAuthorizer struct {
+	rules []Rule
+}
+
+// NewPolicyAuthorizer returns an Authorizer that evaluates requests against a
+// static list of rules. Requests that match no rule are denied.
+func NewPolicyAuthorizer(rules []Rule) Authorizer {
+	return &policyAuthorizer{rules: rules}
+}
+
+func (p *policyAuthorizer) Authorize(logger lager.Logger, request Request) (bool, error) {
+	for _, rule := range p.rules {
+		if rule.matches(request) {
+			return true, nil
+		}
+	}
+
+	logger.Info("no-matching-rule", lager.Data{"request": request})
+	return false, nil
+}
+
+func (r Rule) matches(request Request) bool {
+	if r.Caller != "" && r.Caller != "*" && r.Caller != request.Caller {
+		return false
+	}
+
+	if !strings.HasPrefix(request.Volume, r.VolumePrefix) {
+		return false
+	}
+
+	if len(r.Types) == 0 {
+		return true
+	}
+
+	for _, t := range r.Types {
+		if t == "*" || t == request.Type {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,97 @@
+package authz_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver/authz"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PolicyAuthorizer", func() {
+	var (
+		logger     *lagertest.TestLogger
+		authorizer authz.Authorizer
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("authz")
+		authorizer = authz.NewPolicyAuthorizer([]authz.Rule{
+			{Caller: "tenant-a", VolumePrefix: "tenant-a-"},
+			{Caller: "*", Types: []string{"VolumeDriver.List", "VolumeDriver.Capabilities"}},
+		})
+	})
+
+	It("allows requests matching a rule", func() {
+		Expect(authorizer.Authorize(logger, authz.Request{Type: "VolumeDriver.Mount", Volume: "tenant-a-data", Caller: "tenant-a"})).To(BeTrue())
+		Expect(authorizer.Authorize(logger, authz.Request{Type: "VolumeDriver.List", Caller: "tenant-b"})).To(BeTrue())
+	})
+
+	It("denies requests matching no rule", func() {
+		Expect(authorizer.Authorize(logger, authz.Request{Type: "VolumeDriver.Mount", Volume: "tenant-b-data", Caller: "tenant-a"})).To(BeFalse())
+		Expect(authorizer.Authorize(logger, authz.Request{Type: "VolumeDriver.Mount", Volume: "tenant-a-data", Caller: "tenant-b"})).To(BeFalse())
+	})
+})
+
+var _ = Describe("HTTPAuthorizer", func() {
+	var (
+		logger     *lagertest.TestLogger
+		server     *httptest.Server
+		received   authz.Request
+		status     int
+		decision   authz.Decision
+		authorizer authz.Authorizer
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("authz")
+		status = http.StatusOK
+		decision = authz.Decision{Allowed: true}
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			body, err := ioutil.ReadAll(req.Body)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(json.Unmarshal(body, &received)).To(Succeed())
+
+			w.WriteHeader(status)
+			Expect(json.NewEncoder(w).Encode(decision)).To(Succeed())
+		}))
+
+		authorizer = authz.NewHTTPAuthorizer(server.URL, http.DefaultClient)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("sends the request to the authorization service", func() {
+		request := authz.Request{Type: "VolumeDriver.Mount", Volume: "some-volume", Caller: "some-caller"}
+		Expect(authorizer.Authorize(logger, request)).To(BeTrue())
+		Expect(received).To(Equal(request))
+	})
+
+	Context("when the service denies the request", func() {
+		BeforeEach(func() {
+			decision = authz.Decision{Allowed: false, Reason: "nope"}
+		})
+
+		It("denies the request", func() {
+			Expect(authorizer.Authorize(logger, authz.Request{})).To(BeFalse())
+		})
+	})
+
+	Context("when the service fails", func() {
+		BeforeEach(func() {
+			status = http.StatusInternalServerError
+		})
+
+		It("returns an error", func() {
+			_, err := authorizer.Authorize(logger, authz.Request{})
+			Expect(err).To(MatchError("authorization service returned status 500"))
+		})
+	})
+})
@@ -0,0 +1,91 @@
+package authz
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	cf_http_handlers "code.cloudfoundry.org/cfhttp/handlers"
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+)
+
+const AuthorizationFailedError = "Authorization failed, try again later"
+
+// IdentityFunc extracts the caller identity from an API request.
+type IdentityFunc func(req *http.Request) string
+
+// TLSClientIdentity identifies callers by the common name of their client
+// certificate.
+func TLSClientIdentity(req *http.Request) string {
+	if req.TLS == nil || len(req.TLS.PeerCertificates) == 0 {
+		return ""
+	}
+	return req.TLS.PeerCertificates[0].Subject.CommonName
+}
+
+// HeaderIdentity identifies callers by a request header set by a trusted
+// proxy in front of the driver.
+func HeaderIdentity(header string) IdentityFunc {
+	return func(req *http.Request) string {
+		return req.Header.Get(header)
+	}
+}
+
+// NewAuthorizingHandler asks the authorizer before serving each request.
+// Denied requests and authorizer failures are reported in the response body
+// with a 200 status code, following the docker plugin API.
+func NewAuthorizingHandler(logger lager.Logger, handler http.Handler, authorizer Authorizer, identify IdentityFunc) http.Handler {
+	logger = logger.Session("authorize")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			logger.Error("failed-reading-body", err)
+			cf_http_handlers.WriteJSONResponse(w, http.StatusOK, dockerdriver.ErrorResponse{Err: err.Error()})
+			return
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		request := Request{
+			Type:   strings.TrimPrefix(req.URL.Path, "/"),
+			Volume: volumeName(body),
+			Caller: identify(req),
+		}
+
+		allowed, err := authorizer.Authorize(logger, request)
+		if err != nil {
+			logger.Error("authorizer-failed", err, lager.Data{"request": request})
+			cf_http_handlers.WriteJSONResponse(w, http.StatusOK, dockerdriver.ErrorResponse{Err: AuthorizationFailedError})
+			return
+		}
+
+		if !allowed {
+			logger.Info("request-denied", lager.Data{"request": request})
+			cf_http_handlers.WriteJSONResponse(w, http.StatusOK, dockerdriver.ErrorResponse{Err: deniedError(request)})
+			return
+		}
+
+		handler.ServeHTTP(w, req)
+	})
+}
+
+func volumeName(body []byte) string {
+	var named struct {
+		Name string
+	}
+	if err := json.Unmarshal(body, &named); err != nil {
+		return ""
+	}
+	return named.Name
+}
+
+func deniedError(request Request) string {
+	if request.Volume == "" {
+		return fmt.Sprintf("Not authorized to call %s", request.Type)
+	}
+	return fmt.Sprintf("Not authorized to call %s on volume '%s'", request.Type, request.Volume)
+}
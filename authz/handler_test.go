@@ -0,0 +1,80 @@
+package authz_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver/authz"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AuthorizingHandler", func() {
+	var (
+		logger         *lagertest.TestLogger
+		fakeAuthorizer *volumedriverfakes.FakeAuthorizer
+		servedBody     []byte
+		served         bool
+		handler        http.Handler
+		recorder       *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("authz")
+		fakeAuthorizer = &volumedriverfakes.FakeAuthorizer{}
+		fakeAuthorizer.AuthorizeReturns(true, nil)
+		served = false
+
+		inner := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			served = true
+			servedBody, _ = ioutil.ReadAll(req.Body)
+		})
+		handler = authz.NewAuthorizingHandler(logger, inner, fakeAuthorizer, authz.HeaderIdentity("X-Caller"))
+	})
+
+	JustBeforeEach(func() {
+		req := httptest.NewRequest("POST", "/VolumeDriver.Mount", bytes.NewBufferString(`{"Name":"some-volume"}`))
+		req.Header.Set("X-Caller", "some-caller")
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+	})
+
+	It("authorizes the request type, volume and caller", func() {
+		Expect(fakeAuthorizer.AuthorizeCallCount()).To(Equal(1))
+		_, request := fakeAuthorizer.AuthorizeArgsForCall(0)
+		Expect(request).To(Equal(authz.Request{Type: "VolumeDriver.Mount", Volume: "some-volume", Caller: "some-caller"}))
+	})
+
+	It("serves the request with its original body", func() {
+		Expect(served).To(BeTrue())
+		Expect(string(servedBody)).To(Equal(`{"Name":"some-volume"}`))
+	})
+
+	Context("when the request is denied", func() {
+		BeforeEach(func() {
+			fakeAuthorizer.AuthorizeReturns(false, nil)
+		})
+
+		It("rejects the request", func() {
+			Expect(served).To(BeFalse())
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(recorder.Body.String()).To(MatchJSON(`{"Err":"Not authorized to call VolumeDriver.Mount on volume 'some-volume'"}`))
+		})
+	})
+
+	Context("when the authorizer fails", func() {
+		BeforeEach(func() {
+			fakeAuthorizer.AuthorizeReturns(false, errors.New("unreachable"))
+		})
+
+		It("rejects the request", func() {
+			Expect(served).To(BeFalse())
+			Expect(recorder.Body.String()).To(MatchJSON(`{"Err":"` + authz.AuthorizationFailedError + `"}`))
+		})
+	})
+})
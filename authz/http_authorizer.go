@@ -0,0 +1,72 @@
+package authz
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"code.cloudfoundry.org/goshims/http_wrap"
+	"code.cloudfoundry.org/lager"
+)
+
+// Decision is the body an authorization service replies with.
+type Decision struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+type httpAuthorizer struct {
+	url    string
+	client http_wrap.Client
+}
+
+// NewHTTPAuthorizer returns an Authorizer that posts every Request as JSON to
+// url and expects a Decision in return.
+func NewHTTPAuthorizer(url string, client http_wrap.Client) Authorizer {
+	return &httpAuthorizer{url: url, client: client}
+}
+
+func (a *httpAuthorizer) Authorize(logger lager.Logger, request Request) (bool, error) {
+	logger = logger.Session("http-authorize", lager.Data{"url": a.url})
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return false, err
+	}
+
+	httpRequest, err := http.NewRequest("POST", a.url, bytes.NewBuffer(payload))
+	if err != nil {
+		return false, err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+
+	httpResponse, err := a.client.Do(httpRequest)
+	if err != nil {
+		logger.Error("request-failed", err)
+		return false, err
+	}
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("authorization service returned status %d", httpResponse.StatusCode)
+	}
+
+	data, err := ioutil.ReadAll(httpResponse.Body)
+	if err != nil {
+		return false, err
+	}
+
+	var decision Decision
+	if err := json.Unmarshal(data, &decision); err != nil {
+		logger.Error("invalid-decision", err)
+		return false, err
+	}
+
+	if !decision.Allowed {
+		logger.Info("denied", lager.Data{"request": request, "reason": decision.Reason})
+	}
+
+	return decision.Allowed, nil
+}
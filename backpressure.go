@@ -0,0 +1,69 @@
+package volumedriver
+
+import (
+	"encoding/json"
+	"time"
+
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+)
+
+// DefaultBusyRetryAfter is how long callers are asked to wait before they
+// retry a mount refused by MaxPendingMounts or MaxPendingMountsPerServer.
+const DefaultBusyRetryAfter = 5 * time.Second
+
+// checkPendingMounts must be called with volumesLock held, before a Mount
+// request starts to mount a volume of source.
+func (d *VolumeDriver) checkPendingMounts(source string) error {
+	retryAfter := d.busyRetryAfter()
+
+	if max := d.options.MaxPendingMounts; max > 0 {
+		pending := 0
+		for _, count := range d.pendingMounts {
+			pending += count
+		}
+		if pending >= max {
+			return safeerrors.NewBusy(retryAfter, "Too many mounts in progress, try again later")
+		}
+	}
+
+	server := sourceHost(source)
+	if max := d.options.MaxPendingMountsPerServer; max > 0 && d.pendingMounts[server] >= max {
+		return safeerrors.NewBusy(retryAfter, "Too many mounts in progress on server '%s', try again later", server)
+	}
+	return nil
+}
+
+// busyRetryAfter is how long callers of refused mounts are asked to wait.
+func (d *VolumeDriver) busyRetryAfter() time.Duration {
+	if d.options.BusyRetryAfter <= 0 {
+		return DefaultBusyRetryAfter
+	}
+	return d.options.BusyRetryAfter
+}
+
+// startPendingMount must be called with volumesLock held. Call the returned
+// function, also with volumesLock held, once the mount is done.
+func (d *VolumeDriver) startPendingMount(source string) (done func()) {
+	server := sourceHost(source)
+	d.pendingMounts[server]++
+	return func() {
+		if d.pendingMounts[server]--; d.pendingMounts[server] <= 0 {
+			delete(d.pendingMounts, server)
+		}
+	}
+}
+
+// errResponse returns the Err of a response for err. Safe errors, such as
+// the Busy errors of overloaded servers, are reported as their JSON so that
+// callers see their Code and RetryAfter.
+func errResponse(err error) string {
+	safe, ok := safeerrors.From(err)
+	if !ok {
+		return err.Error()
+	}
+	data, marshalErr := json.Marshal(safe)
+	if marshalErr != nil {
+		return err.Error()
+	}
+	return string(data)
+}
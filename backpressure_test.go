@@ -0,0 +1,120 @@
+package volumedriver_test
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+	"code.cloudfoundry.org/volumedriver/testhelpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Backpressure", func() {
+	var (
+		env      dockerdriver.Env
+		options  volumedriver.Options
+		driver   *testhelpers.MemoryDriver
+		releases []func()
+	)
+
+	create := func(name, source string) {
+		Expect(driver.Create(env, dockerdriver.CreateRequest{Name: name, Opts: map[string]interface{}{"source": source}}).Err).To(BeEmpty())
+	}
+
+	// mountHeld starts a mount of the volume that stays in progress until
+	// the test ends
+	mountHeld := func(name, source string) {
+		releases = append(releases, driver.Mounter.HoldMounts(source))
+		go func() {
+			defer GinkgoRecover()
+			driver.Mount(env, dockerdriver.MountRequest{Name: name})
+		}()
+		Eventually(func() int { return len(driver.Mounter.MountCalls()) }).Should(Equal(len(releases)))
+	}
+
+	busy := func(err string) safeerrors.Error {
+		var safe safeerrors.Error
+		Expect(json.Unmarshal([]byte(err), &safe)).To(Succeed())
+		Expect(safe.Code).To(Equal(safeerrors.Busy))
+		return safe
+	}
+
+	BeforeEach(func() {
+		releases = nil
+		options = volumedriver.DefaultOptions()
+	})
+
+	JustBeforeEach(func() {
+		logger := lagertest.NewTestLogger("backpressure")
+		env = driverhttp.NewHttpDriverEnv(logger, context.TODO())
+		driver = testhelpers.NewMemoryDriverWithOptions(logger, options)
+
+		create("a1", "server-a:/one")
+		create("a2", "server-a:/two")
+		create("b1", "server-b:/one")
+	})
+
+	AfterEach(func() {
+		for _, release := range releases {
+			release()
+		}
+	})
+
+	Context("with a limit of mounts in progress per server", func() {
+		BeforeEach(func() {
+			options.MaxPendingMountsPerServer = 1
+		})
+
+		It("refuses further mounts from the saturated server with a retriable busy error", func() {
+			mountHeld("a1", "server-a:/one")
+
+			safe := busy(driver.Mount(env, dockerdriver.MountRequest{Name: "a2"}).Err)
+			Expect(safe.SafeDescription).To(Equal("Too many mounts in progress on server 'server-a', try again later"))
+			Expect(safe.RetryAfter).To(Equal(5))
+
+			Expect(driver.Mount(env, dockerdriver.MountRequest{Name: "b1"}).Err).To(BeEmpty())
+			Expect(driver.Get(env, dockerdriver.GetRequest{Name: "a2"}).Volume.MountCount).To(BeZero())
+		})
+
+		It("admits mounts again once the mount in progress is done", func() {
+			mountHeld("a1", "server-a:/one")
+			releases[0]()
+			Eventually(func() string { return driver.Mount(env, dockerdriver.MountRequest{Name: "a2"}).Err }).Should(BeEmpty())
+		})
+
+		It("does not refuse requests that wait for the mount in progress", func() {
+			mountHeld("a1", "server-a:/one")
+
+			response := make(chan dockerdriver.MountResponse, 1)
+			go func() {
+				defer GinkgoRecover()
+				response <- driver.Mount(env, dockerdriver.MountRequest{Name: "a1"})
+			}()
+			Consistently(response).ShouldNot(Receive())
+
+			releases[0]()
+			Eventually(response).Should(Receive(WithTransform(func(r dockerdriver.MountResponse) string { return r.Err }, BeEmpty())))
+		})
+	})
+
+	Context("with a limit of mounts in progress", func() {
+		BeforeEach(func() {
+			options.MaxPendingMounts = 1
+			options.BusyRetryAfter = 30 * time.Second
+		})
+
+		It("refuses further mounts from every server", func() {
+			mountHeld("a1", "server-a:/one")
+
+			safe := busy(driver.Mount(env, dockerdriver.MountRequest{Name: "b1"}).Err)
+			Expect(safe.SafeDescription).To(Equal("Too many mounts in progress, try again later"))
+			Expect(safe.RetryAfter).To(Equal(30))
+		})
+	})
+})
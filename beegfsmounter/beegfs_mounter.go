@@ -0,0 +1,408 @@
+package beegfsmounter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/goshims/ioutilshim"
+	"code.cloudfoundry.org/goshims/osshim"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/invoker"
+	"code.cloudfoundry.org/volumedriver/mountchecker"
+	"code.cloudfoundry.org/volumedriver/mounthelper"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+)
+
+const (
+	// BeeGFS is the source scheme of BeeGFS filesystems, and the name to
+	// register the mounter under in Options.Mounters.
+	BeeGFS = "beegfs"
+
+	// ConnAuthOpt is the shared secret of the filesystem, best given as a
+	// reference to a secret.
+	ConnAuthOpt = "conn_auth"
+
+	// DefaultMgmtdPort is the port of the management service when the
+	// source has none.
+	DefaultMgmtdPort = "8008"
+
+	// DefaultConfDir and DefaultBaseConfig are the defaults of Config.
+	DefaultConfDir    = "/var/vcap/data/beegfs-client"
+	DefaultBaseConfig = "/etc/beegfs/beegfs-client.conf"
+
+	filesystemsFile = "/proc/filesystems"
+)
+
+// clientOpts are the opts that set client config settings, by the name of
+// the setting.
+var clientOpts = map[string]string{
+	"conn_client_port_udp": "connClientPortUDP",
+	"sys_acls_enabled":     "sysACLsEnabled",
+	"sys_xattrs_enabled":   "sysXAttrsEnabled",
+	"tune_file_cache_type": "tuneFileCacheType",
+}
+
+// mountSettings are set by the mounter for every mount, and replace those of
+// the base config.
+var mountSettings = []string{"sysMgmtdHost", "connMgmtdPortTCP", "connMgmtdPortUDP", "connAuthFile", "connDisableAuthentication"}
+
+var validValue = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Config locates the files of the BeeGFS client on the cell.
+type Config struct {
+	// ConfDir holds the client config, and the connAuth file, of every
+	// mount. It defaults to DefaultConfDir.
+	ConfDir string
+	// BaseConfig is the client config that the settings of a mount are
+	// added to. It defaults to DefaultBaseConfig; a missing file is an
+	// empty config.
+	BaseConfig string
+	// ConnAuthFile is the connAuth file of volumes without the conn_auth
+	// opt. Without it such volumes are mounted with authentication
+	// disabled.
+	ConnAuthFile string
+}
+
+type beegfsMounter struct {
+	invoker      invoker.Invoker
+	os           osshim.Os
+	ioutil       ioutilshim.Ioutil
+	mountChecker mountchecker.MountChecker
+	config       Config
+}
+
+// NewBeegfsMounter returns a Mounter for the BeeGFS filesystems of research
+// computing clusters. Sources have the form beegfs://mgmtd[:port][/].
+//
+// The kernel module of the client reads its settings from a config file
+// given at mount, so every mount gets a config of its own in
+// Config.ConfDir, naming the management service of the source. The
+// conn_auth opt is written next to it as the connAuth file of the mount.
+// BeeGFS always mounts a filesystem from its root; use the subdir opt of
+// the driver to give apps a directory of it. The conn_client_port_udp,
+// sys_acls_enabled, sys_xattrs_enabled and tune_file_cache_type opts set the
+// client settings of the same name, and ro and readonly mount read-only.
+func NewBeegfsMounter(invoker invoker.Invoker, os osshim.Os, ioutil ioutilshim.Ioutil, mountChecker mountchecker.MountChecker, config Config) volumedriver.Mounter {
+	if config.ConfDir == "" {
+		config.ConfDir = DefaultConfDir
+	}
+	if config.BaseConfig == "" {
+		config.BaseConfig = DefaultBaseConfig
+	}
+
+	return &beegfsMounter{
+		invoker:      invoker,
+		os:           os,
+		ioutil:       ioutil,
+		mountChecker: mountChecker,
+		config:       config,
+	}
+}
+
+func (m *beegfsMounter) Mount(env dockerdriver.Env, source string, target string, opts map[string]interface{}) error {
+	logger := env.Logger().Session("beegfs-mount", lager.Data{"source": source, "target": target})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	host, port, fsPath, err := parseSource(source)
+	if err != nil {
+		return err
+	}
+	if fsPath != "/" {
+		return safeerrors.New(safeerrors.InvalidSource, "BeeGFS mounts the whole filesystem, use the subdir opt for '%s'", fsPath)
+	}
+
+	parsed, err := parseOpts(opts)
+	if err != nil {
+		logger.Error("invalid-opts", err)
+		return err
+	}
+
+	if err := m.loadModule(env); err != nil {
+		logger.Error("load-module-failed", err)
+		return err
+	}
+
+	if err := m.os.MkdirAll(m.config.ConfDir, 0700); err != nil {
+		logger.Error("mkdir-conf-dir-failed", err)
+		return err
+	}
+
+	confFile, authFile := m.mountFiles(target)
+	settings := append([]string{"sysMgmtdHost = " + host, "connMgmtdPortTCP = " + port, "connMgmtdPortUDP = " + port}, parsed.settings...)
+	switch {
+	case parsed.connAuth != "":
+		if err := m.ioutil.WriteFile(authFile, []byte(parsed.connAuth), 0400); err != nil {
+			logger.Error("write-conn-auth-failed", err)
+			return err
+		}
+		settings = append(settings, "connAuthFile = "+authFile)
+	case m.config.ConnAuthFile != "":
+		settings = append(settings, "connAuthFile = "+m.config.ConnAuthFile)
+	default:
+		settings = append(settings, "connDisableAuthentication = true")
+	}
+
+	config, err := m.clientConfig(settings)
+	if err != nil {
+		logger.Error("read-base-config-failed", err)
+		m.removeMountFiles(logger, target)
+		return err
+	}
+	if err := m.ioutil.WriteFile(confFile, config, 0600); err != nil {
+		logger.Error("write-config-failed", err)
+		m.removeMountFiles(logger, target)
+		return err
+	}
+
+	mode := "rw"
+	if parsed.readOnly {
+		mode = "ro"
+	}
+	mountOpts := append([]string{mode, "relatime", "cfgFile=" + confFile, "_netdev"}, parsed.flags...)
+	args := []string{"-t", BeeGFS, "beegfs_nodev", target, "-o", strings.Join(mountOpts, ",")}
+
+	result := m.invoker.Invoke(env, "mount", args)
+	if err := result.Wait(); err != nil {
+		logger.Error("mount-failed", err, lager.Data{"stderr": result.StdError()})
+		m.removeMountFiles(logger, target)
+		return fmt.Errorf("beegfs mount failed: %s", strings.TrimSpace(result.StdError()))
+	}
+
+	return nil
+}
+
+// Unmount removes the client config and connAuth file of the mount once it
+// is unmounted.
+func (m *beegfsMounter) Unmount(env dockerdriver.Env, target string) error {
+	logger := env.Logger().Session("beegfs-unmount", lager.Data{"target": target})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	result := m.invoker.Invoke(env, "umount", []string{target})
+	if err := result.Wait(); err != nil {
+		logger.Error("unmount-failed", err, lager.Data{"stderr": result.StdError()})
+		return safeerrors.FromMountHelper(err, result.StdError(), "unmount failed")
+	}
+
+	m.removeMountFiles(logger, target)
+	return nil
+}
+
+func (m *beegfsMounter) Check(env dockerdriver.Env, name, mountPoint string, depth volumedriver.CheckDepth) bool {
+	logger := env.Logger().Session("beegfs-check", lager.Data{"name": name, "mount-point": mountPoint, "depth": depth})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	mounted, err := m.mountChecker.Exists(mountPoint)
+	if err != nil {
+		logger.Error("check-mounts-failed", err)
+		return false
+	}
+	if !mounted {
+		logger.Info("not-mounted")
+		return false
+	}
+
+	if err := volumedriver.ProbeMountPoint(m.os, m.ioutil, mountPoint, depth); err != nil {
+		logger.Error("probe-failed", err)
+		return false
+	}
+
+	return true
+}
+
+// Purge lazily unmounts everything below path, and removes the emptied
+// mountpoints and the files of their mounts. Directory contents are never
+// removed.
+func (m *beegfsMounter) Purge(env dockerdriver.Env, path string) {
+	logger := env.Logger().Session("beegfs-purge", lager.Data{"path": path})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	unmount := mounthelper.Unmount(env, m.invoker, "umount", "-l")
+	mounthelper.Purge(logger, path, m.mountChecker, m.os, func(mountPoint string) error {
+		if err := unmount(mountPoint); err != nil {
+			return err
+		}
+		m.removeMountFiles(logger, mountPoint)
+		return nil
+	})
+}
+
+// loadModule loads the kernel module of the client unless the kernel
+// already knows the beegfs filesystem.
+func (m *beegfsMounter) loadModule(env dockerdriver.Env) error {
+	filesystems, err := m.ioutil.ReadFile(filesystemsFile)
+	if err == nil && hasFilesystem(string(filesystems), BeeGFS) {
+		return nil
+	}
+
+	result := m.invoker.Invoke(env, "modprobe", []string{BeeGFS})
+	if err := result.Wait(); err != nil {
+		return safeerrors.New(safeerrors.Unsupported, "the beegfs client kernel module is not available on this cell: %s", strings.TrimSpace(result.StdError()))
+	}
+	return nil
+}
+
+// clientConfig returns the base config with settings in place of its own,
+// and without the settings of the mount that settings leave out.
+func (m *beegfsMounter) clientConfig(settings []string) ([]byte, error) {
+	replaced := map[string]bool{}
+	for _, key := range mountSettings {
+		replaced[key] = true
+	}
+	for _, setting := range settings {
+		replaced[settingKey(setting)] = true
+	}
+
+	lines := []string{}
+	base, err := m.ioutil.ReadFile(m.config.BaseConfig)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(base), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || replaced[settingKey(trimmed)] {
+			continue
+		}
+		lines = append(lines, trimmed)
+	}
+
+	lines = append(lines, settings...)
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
+
+// mountFiles returns the paths of the client config and connAuth file of the
+// mount at target.
+func (m *beegfsMounter) mountFiles(target string) (string, string) {
+	sum := sha256.Sum256([]byte(filepath.Clean(target)))
+	name := hex.EncodeToString(sum[:8])
+	return filepath.Join(m.config.ConfDir, name+".conf"), filepath.Join(m.config.ConfDir, name+".auth")
+}
+
+func (m *beegfsMounter) removeMountFiles(logger lager.Logger, target string) {
+	confFile, authFile := m.mountFiles(target)
+	for _, file := range []string{confFile, authFile} {
+		if err := m.os.Remove(file); err != nil && !os.IsNotExist(err) {
+			logger.Error("remove-mount-file-failed", err, lager.Data{"file": file})
+		}
+	}
+}
+
+// parseSource returns the host and port of the management service, with the
+// default port filled in, and the cleaned path of a beegfs:// source.
+func parseSource(source string) (string, string, string, error) {
+	invalid := safeerrors.New(safeerrors.InvalidSource, "invalid beegfs source '%s', expected beegfs://mgmtd:port", source)
+
+	parsed, err := url.Parse(source)
+	if err != nil || strings.ToLower(parsed.Scheme) != BeeGFS || parsed.Host == "" {
+		return "", "", "", invalid
+	}
+	if parsed.User != nil || parsed.RawQuery != "" || parsed.Fragment != "" {
+		return "", "", "", invalid
+	}
+
+	host := parsed.Hostname()
+	if net.ParseIP(host) == nil && !validValue.MatchString(host) {
+		return "", "", "", invalid
+	}
+	port := parsed.Port()
+	if port == "" {
+		port = DefaultMgmtdPort
+	}
+
+	return host, port, path.Clean("/" + parsed.Path), nil
+}
+
+type beegfsOpts struct {
+	settings []string
+	connAuth string
+	readOnly bool
+	// flags are the hardening opts of the driver, passed on as mount flags
+	flags []string
+}
+
+func parseOpts(opts map[string]interface{}) (beegfsOpts, error) {
+	opts, flags := volumedriver.HardeningFlags(opts)
+	keys := make([]string, 0, len(opts))
+	for key := range opts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parsed := beegfsOpts{settings: []string{}, flags: flags}
+	for _, key := range keys {
+		value := opts[key]
+
+		switch key {
+		case ConnAuthOpt:
+			secret, ok := value.(string)
+			if !ok || secret == "" {
+				return beegfsOpts{}, safeerrors.New(safeerrors.InvalidOption, "Invalid value for option '%s'", key)
+			}
+			parsed.connAuth = secret
+			continue
+		case "ro", "readonly":
+			set, err := boolOpt(value)
+			if err != nil {
+				return beegfsOpts{}, safeerrors.New(safeerrors.InvalidOption, "Invalid value for option '%s': %v", key, value)
+			}
+			parsed.readOnly = parsed.readOnly || set
+			continue
+		}
+
+		setting, ok := clientOpts[key]
+		if !ok {
+			return beegfsOpts{}, safeerrors.New(safeerrors.InvalidOption, "Not allowed options: %s", key)
+		}
+		formatted := fmt.Sprintf("%v", value)
+		if !validValue.MatchString(formatted) {
+			return beegfsOpts{}, safeerrors.New(safeerrors.InvalidOption, "Invalid value for option '%s': %v", key, value)
+		}
+		parsed.settings = append(parsed.settings, setting+" = "+formatted)
+	}
+
+	return parsed, nil
+}
+
+func settingKey(line string) string {
+	return strings.TrimSpace(strings.SplitN(line, "=", 2)[0])
+}
+
+func hasFilesystem(filesystems string, name string) bool {
+	for _, line := range strings.Split(filesystems, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && fields[len(fields)-1] == name {
+			return true
+		}
+	}
+	return false
+}
+
+func boolOpt(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		switch strings.ToLower(v) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+
+	return false, fmt.Errorf("not a boolean: %v", value)
+}
@@ -0,0 +1,300 @@
+package beegfsmounter_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"regexp"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/goshims/ioutilshim/ioutil_fake"
+	"code.cloudfoundry.org/goshims/osshim/os_fake"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/beegfsmounter"
+	"code.cloudfoundry.org/volumedriver/invokerfakes"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BeegfsMounter", func() {
+	var (
+		logger           *lagertest.TestLogger
+		env              dockerdriver.Env
+		fakeInvoker      *invokerfakes.FakeInvoker
+		fakeInvokeResult *invokerfakes.FakeInvokeResult
+		fakeOs           *os_fake.FakeOs
+		fakeIoutil       *ioutil_fake.FakeIoutil
+		fakeMountChecker *volumedriverfakes.FakeMountChecker
+		config           beegfsmounter.Config
+		files            map[string]string
+		written          map[string]string
+		mounter          volumedriver.Mounter
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("beegfsmounter")
+		env = driverhttp.NewHttpDriverEnv(logger, context.TODO())
+		fakeInvoker = &invokerfakes.FakeInvoker{}
+		fakeInvokeResult = &invokerfakes.FakeInvokeResult{}
+		fakeInvoker.InvokeReturns(fakeInvokeResult)
+		fakeOs = &os_fake.FakeOs{}
+		fakeIoutil = &ioutil_fake.FakeIoutil{}
+		fakeMountChecker = &volumedriverfakes.FakeMountChecker{}
+		config = beegfsmounter.Config{ConfDir: "/var/vcap/data/beegfs-client"}
+
+		files = map[string]string{
+			"/proc/filesystems": "nodev\tsysfs\nnodev\tbeegfs\n",
+		}
+		fakeIoutil.ReadFileStub = func(filename string) ([]byte, error) {
+			content, ok := files[filename]
+			if !ok {
+				return nil, &os.PathError{Op: "open", Path: filename, Err: os.ErrNotExist}
+			}
+			return []byte(content), nil
+		}
+		written = map[string]string{}
+		fakeIoutil.WriteFileStub = func(filename string, data []byte, perm os.FileMode) error {
+			written[filename] = string(data)
+			return nil
+		}
+	})
+
+	JustBeforeEach(func() {
+		mounter = beegfsmounter.NewBeegfsMounter(fakeInvoker, fakeOs, fakeIoutil, fakeMountChecker, config)
+	})
+
+	Describe("Mount", func() {
+		var (
+			source string
+			opts   map[string]interface{}
+			err    error
+		)
+
+		BeforeEach(func() {
+			source = "beegfs://mgmtd.example.com"
+			opts = map[string]interface{}{}
+		})
+
+		JustBeforeEach(func() {
+			err = mounter.Mount(env, source, "/mnt/target", opts)
+		})
+
+		It("mounts the filesystem with a client config of the mount", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeInvoker.InvokeCallCount()).To(Equal(1))
+			_, cmd, args, _ := fakeInvoker.InvokeArgsForCall(0)
+			Expect(cmd).To(Equal("mount"))
+			Expect(args).To(HaveLen(6))
+			Expect(args[:4]).To(Equal([]string{"-t", "beegfs", "beegfs_nodev", "/mnt/target"}))
+			Expect(args[5]).To(MatchRegexp(`^rw,relatime,cfgFile=/var/vcap/data/beegfs-client/[0-9a-f]{16}\.conf,_netdev$`))
+
+			confFile := regexp.MustCompile(`cfgFile=([^,]+)`).FindStringSubmatch(args[5])[1]
+			Expect(written).To(HaveKeyWithValue(confFile, "sysMgmtdHost = mgmtd.example.com\nconnMgmtdPortTCP = 8008\nconnMgmtdPortUDP = 8008\nconnDisableAuthentication = true\n"))
+
+			path, perm := fakeOs.MkdirAllArgsForCall(0)
+			Expect(path).To(Equal("/var/vcap/data/beegfs-client"))
+			Expect(perm).To(Equal(os.FileMode(0700)))
+		})
+
+		Context("when the base config has settings", func() {
+			BeforeEach(func() {
+				config.BaseConfig = "/etc/beegfs/beegfs-client.conf"
+				files[config.BaseConfig] = "# client settings\nsysMgmtdHost = other.example.com\n\nconnAuthFile = /etc/beegfs/connauthfile\ntuneFileCacheType = buffered\n"
+				opts["tune_file_cache_type"] = "native"
+			})
+
+			It("keeps those the mount does not set", func() {
+				Expect(written).To(ContainElement("sysMgmtdHost = mgmtd.example.com\nconnMgmtdPortTCP = 8008\nconnMgmtdPortUDP = 8008\ntuneFileCacheType = native\nconnDisableAuthentication = true\n"))
+			})
+		})
+
+		Context("when the volume has a conn_auth secret", func() {
+			BeforeEach(func() {
+				source = "beegfs://10.0.0.5:9008/"
+				opts = map[string]interface{}{"conn_auth": "s3cret", "readonly": "true", "sys_acls_enabled": true}
+			})
+
+			It("writes it as the connAuth file of the mount", func() {
+				Expect(err).NotTo(HaveOccurred())
+
+				var authFile string
+				for filename, content := range written {
+					if content == "s3cret" {
+						authFile = filename
+					}
+				}
+				Expect(authFile).To(MatchRegexp(`^/var/vcap/data/beegfs-client/[0-9a-f]{16}\.auth$`))
+				Expect(written).To(ContainElement("sysMgmtdHost = 10.0.0.5\nconnMgmtdPortTCP = 9008\nconnMgmtdPortUDP = 9008\nsysACLsEnabled = true\nconnAuthFile = " + authFile + "\n"))
+
+				for i := 0; i < fakeIoutil.WriteFileCallCount(); i++ {
+					filename, _, perm := fakeIoutil.WriteFileArgsForCall(i)
+					if filename == authFile {
+						Expect(perm).To(Equal(os.FileMode(0400)))
+					}
+				}
+			})
+
+			It("mounts read-only", func() {
+				_, _, args, _ := fakeInvoker.InvokeArgsForCall(0)
+				Expect(args[5]).To(HavePrefix("ro,"))
+			})
+		})
+
+		Context("when the cell has a connAuth file", func() {
+			BeforeEach(func() {
+				config.ConnAuthFile = "/etc/beegfs/connauthfile"
+			})
+
+			It("uses it", func() {
+				Expect(written).To(ContainElement(ContainSubstring("connAuthFile = /etc/beegfs/connauthfile\n")))
+			})
+		})
+
+		Context("when the kernel module is not loaded", func() {
+			BeforeEach(func() {
+				files["/proc/filesystems"] = "nodev\tsysfs\n"
+			})
+
+			It("loads it", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeInvoker.InvokeCallCount()).To(Equal(2))
+				_, cmd, args, _ := fakeInvoker.InvokeArgsForCall(0)
+				Expect(cmd).To(Equal("modprobe"))
+				Expect(args).To(Equal([]string{"beegfs"}))
+			})
+
+			Context("when it cannot be loaded", func() {
+				BeforeEach(func() {
+					fakeInvokeResult.WaitReturns(errors.New("exit status 1"))
+					fakeInvokeResult.StdErrorReturns("modprobe: FATAL: Module beegfs not found\n")
+				})
+
+				It("rejects the mount", func() {
+					Expect(err).To(Equal(safeerrors.New(safeerrors.Unsupported, "the beegfs client kernel module is not available on this cell: modprobe: FATAL: Module beegfs not found")))
+					Expect(fakeInvoker.InvokeCallCount()).To(Equal(1))
+				})
+			})
+		})
+
+		Context("when the source has a path", func() {
+			BeforeEach(func() {
+				source = "beegfs://mgmtd.example.com/projects"
+			})
+
+			It("rejects the mount", func() {
+				Expect(err).To(Equal(safeerrors.New(safeerrors.InvalidSource, "BeeGFS mounts the whole filesystem, use the subdir opt for '/projects'")))
+				Expect(fakeInvoker.InvokeCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when the source is not a beegfs url", func() {
+			BeforeEach(func() {
+				source = "mgmtd.example.com:/"
+			})
+
+			It("rejects the mount", func() {
+				Expect(err).To(Equal(safeerrors.New(safeerrors.InvalidSource, "invalid beegfs source 'mgmtd.example.com:/', expected beegfs://mgmtd:port")))
+			})
+		})
+
+		Context("when the driver hardens the mount", func() {
+			BeforeEach(func() {
+				opts["nosuid"] = true
+				opts["nodev"] = true
+				opts["noexec"] = true
+			})
+
+			It("passes the hardening opts on as mount flags", func() {
+				Expect(err).NotTo(HaveOccurred())
+				_, cmd, args, _ := fakeInvoker.InvokeArgsForCall(fakeInvoker.InvokeCallCount() - 1)
+				Expect(cmd).To(Equal("mount"))
+				Expect(args).To(ContainElement(HaveSuffix(",_netdev,nosuid,nodev,noexec")))
+			})
+		})
+
+		Context("when an option is not supported", func() {
+			BeforeEach(func() {
+				opts["uid"] = "1000"
+			})
+
+			It("rejects the mount", func() {
+				Expect(err).To(Equal(safeerrors.New(safeerrors.InvalidOption, "Not allowed options: uid")))
+			})
+		})
+
+		Context("when a setting would inject other settings", func() {
+			BeforeEach(func() {
+				opts["tune_file_cache_type"] = "native\nconnAuthFile = /etc/shadow"
+			})
+
+			It("rejects the mount", func() {
+				Expect(err).To(MatchError(ContainSubstring("Invalid value for option 'tune_file_cache_type'")))
+				Expect(written).To(BeEmpty())
+			})
+		})
+
+		Context("when the mount command fails", func() {
+			BeforeEach(func() {
+				fakeInvokeResult.WaitReturns(errors.New("exit status 32"))
+				fakeInvokeResult.StdErrorReturns("mount: wrong fs type, bad option\n")
+			})
+
+			It("returns the command error and removes the files of the mount", func() {
+				Expect(err).To(MatchError("beegfs mount failed: mount: wrong fs type, bad option"))
+				Expect(fakeOs.RemoveCallCount()).To(Equal(2))
+			})
+		})
+	})
+
+	Describe("Unmount", func() {
+		It("unmounts the target and removes the files of the mount", func() {
+			Expect(mounter.Unmount(env, "/mnt/target")).To(Succeed())
+			_, cmd, args, _ := fakeInvoker.InvokeArgsForCall(0)
+			Expect(cmd).To(Equal("umount"))
+			Expect(args).To(Equal([]string{"/mnt/target"}))
+
+			Expect(fakeOs.RemoveCallCount()).To(Equal(2))
+			Expect(fakeOs.RemoveArgsForCall(0)).To(MatchRegexp(`^/var/vcap/data/beegfs-client/[0-9a-f]{16}\.conf$`))
+			Expect(fakeOs.RemoveArgsForCall(1)).To(MatchRegexp(`^/var/vcap/data/beegfs-client/[0-9a-f]{16}\.auth$`))
+		})
+
+		Context("when umount fails", func() {
+			BeforeEach(func() {
+				fakeInvokeResult.WaitReturns(errors.New("exit status 32"))
+				fakeInvokeResult.StdErrorReturns("umount: /mnt/target: target is busy\n")
+			})
+
+			It("returns the error and keeps the files", func() {
+				Expect(mounter.Unmount(env, "/mnt/target")).To(MatchError("unmount failed: the mount point is busy; stop the processes that use the volume"))
+				Expect(fakeOs.RemoveCallCount()).To(BeZero())
+			})
+		})
+	})
+
+	Describe("Check", func() {
+		It("fails when the mountpoint is not mounted", func() {
+			fakeMountChecker.ExistsReturns(false, nil)
+			Expect(mounter.Check(env, "volume", "/mnt/target", volumedriver.CheckStat)).To(BeFalse())
+		})
+	})
+
+	Describe("Purge", func() {
+		It("lazily unmounts the mounts below the path", func() {
+			fakeMountChecker.ListReturns([]string{"/var/vcap/data/volumes/a", "/var/vcap/data/volumes/b"}, nil)
+
+			mounter.Purge(env, "/var/vcap/data/volumes")
+
+			pattern := fakeMountChecker.ListArgsForCall(0)
+			Expect(pattern).To(Equal(regexp.MustCompile("^/var/vcap/data/volumes/")))
+			Expect(fakeInvoker.InvokeCallCount()).To(Equal(2))
+			_, cmd, args, _ := fakeInvoker.InvokeArgsForCall(0)
+			Expect(cmd).To(Equal("umount"))
+			Expect(args).To(Equal([]string{"-l", "/var/vcap/data/volumes/b"}))
+			Expect(fakeOs.RemoveArgsForCall(2)).To(Equal("/var/vcap/data/volumes/b"))
+		})
+	})
+})
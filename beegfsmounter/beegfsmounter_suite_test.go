@@ -0,0 +1,13 @@
+package beegfsmounter_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestBeegfsMounter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "BeegfsMounter Suite")
+}
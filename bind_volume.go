@@ -0,0 +1,211 @@
+package volumedriver
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager"
+)
+
+// bindReadOnlyOpt is bindmounter.ReadOnlyOpt, which cannot be imported here.
+const bindReadOnlyOpt = "readonly"
+
+// bindCallerPrefix attributes the mount reference a bind holds on its
+// volume, so that DescribeVolume tells binds from containers.
+const bindCallerPrefix = "bind:"
+
+// VolumeBind is a bind mount of a mounted volume into a target path outside
+// the mount path roots. Count is the number of BindVolume requests for the
+// target that have not been undone by UnbindVolume.
+type VolumeBind struct {
+	Count    int
+	ReadOnly bool `json:",omitempty"`
+}
+
+func bindCaller(target string) string {
+	return bindCallerPrefix + target
+}
+
+func copyBinds(binds map[string]*VolumeBind) map[string]VolumeBind {
+	if len(binds) == 0 {
+		return nil
+	}
+	copied := map[string]VolumeBind{}
+	for target, bind := range binds {
+		copied[target] = *bind
+	}
+	return copied
+}
+
+// BindVolume bind mounts a mounted volume into the target of the request,
+// such as a path in a container rootfs prepared by the runtime. Every bind
+// holds a mount reference on the volume, so that the volume stays mounted
+// until its last bind is undone; binding a target again only counts it.
+func (d *VolumeDriver) BindVolume(env dockerdriver.Env, bindRequest BindVolumeRequest) dockerdriver.ErrorResponse {
+	logger := env.Logger().Session("bind-volume", lager.Data{"volume": bindRequest.Name, "target": bindRequest.Target})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	if bindRequest.Name == "" {
+		return dockerdriver.ErrorResponse{Err: "Missing mandatory 'volume_name'"}
+	}
+	if d.options.BindMounter == nil {
+		return dockerdriver.ErrorResponse{Err: "BindVolume is not supported by this driver"}
+	}
+	target, err := d.bindTarget(bindRequest.Target)
+	if err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+	defer d.inFlight.start(env, "bind-volume", bindRequest.Name).done()
+
+	d.volumesLock.Lock()
+	defer d.volumesLock.Unlock()
+
+	volume, ok := d.volumes[bindRequest.Name]
+	if !ok {
+		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("Volume '%s' not found", bindRequest.Name)}
+	}
+	if volume.Mountpoint == "" || volume.MountCount < 1 {
+		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("Volume '%s' must be mounted before it is bound", bindRequest.Name)}
+	}
+	for name, other := range d.volumes {
+		if _, ok := other.Binds[target]; ok && name != bindRequest.Name {
+			return dockerdriver.ErrorResponse{Err: fmt.Sprintf("target '%s' is bound to volume '%s'", target, name)}
+		}
+	}
+
+	if bind, ok := volume.Binds[target]; ok {
+		if bind.ReadOnly != bindRequest.ReadOnly {
+			return dockerdriver.ErrorResponse{Err: fmt.Sprintf("target '%s' is bound with readonly %t", target, bind.ReadOnly)}
+		}
+		bind.Count++
+	} else {
+		if err := d.os.MkdirAll(target, os.ModePerm); err != nil {
+			logger.Error("create-target-failed", err)
+			return dockerdriver.ErrorResponse{Err: fmt.Sprintf("Error creating bind target '%s': %s", target, err.Error())}
+		}
+
+		opts := map[string]interface{}{}
+		if bindRequest.ReadOnly {
+			opts[bindReadOnlyOpt] = true
+		}
+		if err := d.options.BindMounter.Mount(driverhttp.EnvWithLogger(logger, env), volume.Mountpoint, target, opts); err != nil {
+			logger.Error("bind-mount-failed", err)
+			return dockerdriver.ErrorResponse{Err: fmt.Sprintf("Error binding volume '%s': %s", bindRequest.Name, err.Error())}
+		}
+
+		if volume.Binds == nil {
+			volume.Binds = map[string]*VolumeBind{}
+		}
+		volume.Binds[target] = &VolumeBind{Count: 1, ReadOnly: bindRequest.ReadOnly}
+	}
+
+	volume.MountCount++
+	addReference(volume, bindCaller(target))
+	logger.Info("volume-bound", lager.Data{"count": volume.Binds[target].Count, "mount-count": volume.MountCount})
+
+	if err := d.persistVolumeOrQueue(driverhttp.EnvWithLogger(logger, env), bindRequest.Name); err != nil {
+		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("failed to persist state when binding: %s", err.Error())}
+	}
+	return dockerdriver.ErrorResponse{}
+}
+
+// UnbindVolume undoes a BindVolume. The bind mount is unmounted with the
+// last bind of its target, and the volume with its last mount reference.
+func (d *VolumeDriver) UnbindVolume(env dockerdriver.Env, unbindRequest UnbindVolumeRequest) dockerdriver.ErrorResponse {
+	logger := env.Logger().Session("unbind-volume", lager.Data{"volume": unbindRequest.Name, "target": unbindRequest.Target})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	if unbindRequest.Name == "" {
+		return dockerdriver.ErrorResponse{Err: "Missing mandatory 'volume_name'"}
+	}
+	if d.options.BindMounter == nil {
+		return dockerdriver.ErrorResponse{Err: "BindVolume is not supported by this driver"}
+	}
+	defer d.inFlight.start(env, "unbind-volume", unbindRequest.Name).done()
+
+	d.volumesLock.Lock()
+	defer d.volumesLock.Unlock()
+
+	volume, ok := d.volumes[unbindRequest.Name]
+	if !ok {
+		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("Volume '%s' not found", unbindRequest.Name)}
+	}
+	target := filepath.Clean(unbindRequest.Target)
+	bind, ok := volume.Binds[target]
+	if !ok {
+		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("Volume '%s' is not bound to '%s'", unbindRequest.Name, target)}
+	}
+
+	if bind.Count == 1 {
+		if err := d.options.BindMounter.Unmount(driverhttp.EnvWithLogger(logger, env), target); err != nil {
+			logger.Error("bind-unmount-failed", err)
+			return dockerdriver.ErrorResponse{Err: fmt.Sprintf("Error unbinding volume '%s': %s", unbindRequest.Name, err.Error())}
+		}
+		delete(volume.Binds, target)
+		if len(volume.Binds) == 0 {
+			volume.Binds = nil
+		}
+	} else {
+		bind.Count--
+	}
+
+	if err := d.releaseMount(driverhttp.EnvWithLogger(logger, env), unbindRequest.Name, bindCaller(target)); err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+	return dockerdriver.ErrorResponse{}
+}
+
+// bindTarget returns the cleaned target of a bind. Targets must be absolute
+// and outside the mount path roots, which belong to the driver.
+func (d *VolumeDriver) bindTarget(target string) (string, error) {
+	if !filepath.IsAbs(target) {
+		return "", fmt.Errorf("invalid bind target '%s', must be an absolute path", target)
+	}
+	target = filepath.Clean(target)
+	if target == string(filepath.Separator) {
+		return "", errors.New("invalid bind target '/'")
+	}
+
+	for _, root := range d.roots() {
+		root, err := d.filepath.Abs(root)
+		if err != nil {
+			return "", err
+		}
+		if target == root || strings.HasPrefix(target, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator)) {
+			return "", fmt.Errorf("invalid bind target '%s', must not be below the mount path root '%s'", target, root)
+		}
+	}
+	return target, nil
+}
+
+// unbindAll must be called with volumesLock held. It unmounts the binds of
+// a volume that is unmounted regardless of its references; the references
+// of the binds go with the volume.
+func (d *VolumeDriver) unbindAll(env dockerdriver.Env, volume *NfsVolumeInfo) {
+	logger := env.Logger().Session("unbind-all", lager.Data{"volume": volume.Name})
+
+	if d.options.BindMounter == nil {
+		return
+	}
+
+	targets := []string{}
+	for target := range volume.Binds {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	for _, target := range targets {
+		if err := d.options.BindMounter.Unmount(env, target); err != nil {
+			logger.Error("bind-unmount-failed", err, lager.Data{"target": target})
+		}
+	}
+	volume.Binds = nil
+}
@@ -0,0 +1,158 @@
+package bindmounter
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/goshims/ioutilshim"
+	"code.cloudfoundry.org/goshims/osshim"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/invoker"
+	"code.cloudfoundry.org/volumedriver/mountchecker"
+	"code.cloudfoundry.org/volumedriver/mounthelper"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+)
+
+// ReadOnlyOpt makes the bind mount read-only. Any other option is rejected.
+const ReadOnlyOpt = "readonly"
+
+type bindMounter struct {
+	invoker      invoker.Invoker
+	os           osshim.Os
+	ioutil       ioutilshim.Ioutil
+	mountChecker mountchecker.MountChecker
+}
+
+// NewBindMounter returns a Mounter that exposes existing host directories by
+// bind mounting them. Volume sources are absolute host paths, which makes it
+// useful for development and for cell-local scratch space.
+func NewBindMounter(invoker invoker.Invoker, os osshim.Os, ioutil ioutilshim.Ioutil, mountChecker mountchecker.MountChecker) volumedriver.Mounter {
+	return &bindMounter{
+		invoker:      invoker,
+		os:           os,
+		ioutil:       ioutil,
+		mountChecker: mountChecker,
+	}
+}
+
+func (m *bindMounter) Mount(env dockerdriver.Env, source string, target string, opts map[string]interface{}) error {
+	logger := env.Logger().Session("bind-mount", lager.Data{"source": source, "target": target})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	mountOpts, err := bindMountOpts(opts)
+	if err != nil {
+		logger.Error("invalid-opts", err)
+		return err
+	}
+
+	if !filepath.IsAbs(source) {
+		return safeerrors.New(safeerrors.InvalidSource, "source '%s' must be an absolute host path", source)
+	}
+
+	info, err := m.os.Stat(source)
+	if err != nil {
+		logger.Error("stat-source-failed", err)
+		return safeerrors.Wrap(err, safeerrors.NotFound, "source '%s' does not exist", source)
+	}
+	if !info.IsDir() {
+		return safeerrors.New(safeerrors.InvalidSource, "source '%s' is not a directory", source)
+	}
+
+	result := m.invoker.Invoke(env, "mount", []string{"-o", mountOpts, source, target})
+	if err := result.Wait(); err != nil {
+		logger.Error("mount-failed", err, lager.Data{"stderr": result.StdError()})
+		return safeerrors.FromMountHelper(err, result.StdError(), "bind mount failed")
+	}
+
+	return nil
+}
+
+func (m *bindMounter) Unmount(env dockerdriver.Env, target string) error {
+	logger := env.Logger().Session("bind-unmount", lager.Data{"target": target})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	result := m.invoker.Invoke(env, "umount", []string{target})
+	if err := result.Wait(); err != nil {
+		logger.Error("unmount-failed", err, lager.Data{"stderr": result.StdError()})
+		return safeerrors.FromMountHelper(err, result.StdError(), "unmount failed")
+	}
+
+	return nil
+}
+
+func (m *bindMounter) Check(env dockerdriver.Env, name, mountPoint string, depth volumedriver.CheckDepth) bool {
+	logger := env.Logger().Session("bind-check", lager.Data{"name": name, "mount-point": mountPoint, "depth": depth})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	mounted, err := m.mountChecker.Exists(mountPoint)
+	if err != nil {
+		logger.Error("check-mounts-failed", err)
+		return false
+	}
+	if !mounted {
+		logger.Info("not-mounted")
+		return false
+	}
+
+	if err := volumedriver.ProbeMountPoint(m.os, m.ioutil, mountPoint, depth); err != nil {
+		logger.Error("probe-failed", err)
+		return false
+	}
+
+	return true
+}
+
+// Purge unmounts everything below path and removes the emptied mountpoints.
+// It never removes directory contents, since a mountpoint that failed to
+// unmount still exposes the host directory behind it.
+func (m *bindMounter) Purge(env dockerdriver.Env, path string) {
+	logger := env.Logger().Session("bind-purge", lager.Data{"path": path})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	mounthelper.Purge(logger, path, m.mountChecker, m.os, mounthelper.Unmount(env, m.invoker, "umount", "-l"))
+}
+
+// bindMountOpts passes the hardening opts of the driver on as mount flags.
+func bindMountOpts(opts map[string]interface{}) (string, error) {
+	opts, flags := volumedriver.HardeningFlags(opts)
+	mountOpts := strings.Join(append([]string{"bind"}, flags...), ",")
+
+	for key, value := range opts {
+		if key != ReadOnlyOpt {
+			return "", safeerrors.New(safeerrors.InvalidOption, "Not allowed options: %s", key)
+		}
+
+		readOnly, err := boolOpt(value)
+		if err != nil {
+			return "", safeerrors.New(safeerrors.InvalidOption, "Invalid value for option '%s': %v", key, value)
+		}
+		if readOnly {
+			mountOpts += ",ro"
+		}
+	}
+
+	return mountOpts, nil
+}
+
+func boolOpt(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		switch strings.ToLower(v) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+
+	return false, fmt.Errorf("not a boolean: %v", value)
+}
@@ -0,0 +1,251 @@
+package bindmounter_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"regexp"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/goshims/ioutilshim/ioutil_fake"
+	"code.cloudfoundry.org/goshims/osshim/os_fake"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/bindmounter"
+	"code.cloudfoundry.org/volumedriver/invokerfakes"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BindMounter", func() {
+	var (
+		logger           *lagertest.TestLogger
+		env              dockerdriver.Env
+		fakeInvoker      *invokerfakes.FakeInvoker
+		fakeInvokeResult *invokerfakes.FakeInvokeResult
+		fakeOs           *os_fake.FakeOs
+		fakeIoutil       *ioutil_fake.FakeIoutil
+		fakeMountChecker *volumedriverfakes.FakeMountChecker
+		mounter          volumedriver.Mounter
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("bindmounter")
+		env = driverhttp.NewHttpDriverEnv(logger, context.TODO())
+		fakeInvoker = &invokerfakes.FakeInvoker{}
+		fakeInvokeResult = &invokerfakes.FakeInvokeResult{}
+		fakeInvoker.InvokeReturns(fakeInvokeResult)
+		fakeOs = &os_fake.FakeOs{}
+		fakeIoutil = &ioutil_fake.FakeIoutil{}
+		fakeMountChecker = &volumedriverfakes.FakeMountChecker{}
+
+		dirInfo := &ioutil_fake.FakeFileInfo{}
+		dirInfo.IsDirReturns(true)
+		fakeOs.StatReturns(dirInfo, nil)
+
+		mounter = bindmounter.NewBindMounter(fakeInvoker, fakeOs, fakeIoutil, fakeMountChecker)
+	})
+
+	Describe("Mount", func() {
+		var (
+			source string
+			opts   map[string]interface{}
+			err    error
+		)
+
+		BeforeEach(func() {
+			source = "/var/vcap/data/scratch"
+			opts = map[string]interface{}{}
+		})
+
+		JustBeforeEach(func() {
+			err = mounter.Mount(env, source, "/mnt/target", opts)
+		})
+
+		It("bind mounts the host directory", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeInvoker.InvokeCallCount()).To(Equal(1))
+			_, cmd, args, _ := fakeInvoker.InvokeArgsForCall(0)
+			Expect(cmd).To(Equal("mount"))
+			Expect(args).To(Equal([]string{"-o", "bind", "/var/vcap/data/scratch", "/mnt/target"}))
+		})
+
+		Context("when the volume is read-only", func() {
+			BeforeEach(func() {
+				opts[bindmounter.ReadOnlyOpt] = "true"
+			})
+
+			It("bind mounts read-only", func() {
+				_, _, args, _ := fakeInvoker.InvokeArgsForCall(0)
+				Expect(args).To(Equal([]string{"-o", "bind,ro", "/var/vcap/data/scratch", "/mnt/target"}))
+			})
+		})
+
+		Context("when the driver hardens the mount", func() {
+			BeforeEach(func() {
+				opts["nosuid"] = true
+				opts["nodev"] = true
+				opts["noexec"] = true
+			})
+
+			It("passes the hardening opts on as mount flags", func() {
+				Expect(err).NotTo(HaveOccurred())
+				_, _, args, _ := fakeInvoker.InvokeArgsForCall(0)
+				Expect(args).To(Equal([]string{"-o", "bind,nosuid,nodev,noexec", "/var/vcap/data/scratch", "/mnt/target"}))
+			})
+		})
+
+		Context("when an unknown option is given", func() {
+			BeforeEach(func() {
+				opts["uid"] = "1000"
+			})
+
+			It("rejects the mount", func() {
+				Expect(err).To(Equal(safeerrors.New(safeerrors.InvalidOption, "Not allowed options: uid")))
+				Expect(fakeInvoker.InvokeCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when the read-only option is not a boolean", func() {
+			BeforeEach(func() {
+				opts[bindmounter.ReadOnlyOpt] = "sometimes"
+			})
+
+			It("rejects the mount", func() {
+				Expect(err).To(Equal(safeerrors.New(safeerrors.InvalidOption, "Invalid value for option 'readonly': sometimes")))
+			})
+		})
+
+		Context("when the source is not an absolute path", func() {
+			BeforeEach(func() {
+				source = "server:/export"
+			})
+
+			It("rejects the mount", func() {
+				Expect(err).To(Equal(safeerrors.New(safeerrors.InvalidSource, "source 'server:/export' must be an absolute host path")))
+				Expect(fakeInvoker.InvokeCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when the source does not exist", func() {
+			BeforeEach(func() {
+				fakeOs.StatReturns(nil, os.ErrNotExist)
+			})
+
+			It("rejects the mount", func() {
+				Expect(err).To(MatchError("source '/var/vcap/data/scratch' does not exist"))
+				safe, _ := safeerrors.From(err)
+				Expect(safe.Code).To(Equal(safeerrors.NotFound))
+			})
+		})
+
+		Context("when the source is not a directory", func() {
+			BeforeEach(func() {
+				fakeOs.StatReturns(&ioutil_fake.FakeFileInfo{}, nil)
+			})
+
+			It("rejects the mount", func() {
+				Expect(err).To(Equal(safeerrors.New(safeerrors.InvalidSource, "source '/var/vcap/data/scratch' is not a directory")))
+			})
+		})
+
+		Context("when the mount command fails", func() {
+			BeforeEach(func() {
+				fakeInvokeResult.WaitReturns(errors.New("exit status 32"))
+				fakeInvokeResult.StdErrorReturns("mount: permission denied\n")
+			})
+
+			It("returns the command error", func() {
+				Expect(err).To(MatchError("bind mount failed: permission denied; check the credentials of the volume and that the driver runs as root"))
+			})
+		})
+	})
+
+	Describe("Unmount", func() {
+		It("unmounts the target", func() {
+			Expect(mounter.Unmount(env, "/mnt/target")).To(Succeed())
+			_, cmd, args, _ := fakeInvoker.InvokeArgsForCall(0)
+			Expect(cmd).To(Equal("umount"))
+			Expect(args).To(Equal([]string{"/mnt/target"}))
+		})
+
+		Context("when the unmount command fails", func() {
+			BeforeEach(func() {
+				fakeInvokeResult.WaitReturns(errors.New("exit status 32"))
+				fakeInvokeResult.StdErrorReturns("umount: target is busy")
+			})
+
+			It("returns the command error", func() {
+				Expect(mounter.Unmount(env, "/mnt/target")).To(MatchError("unmount failed: the mount point is busy; stop the processes that use the volume"))
+			})
+		})
+	})
+
+	Describe("Check", func() {
+		BeforeEach(func() {
+			fakeMountChecker.ExistsReturns(true, nil)
+		})
+
+		It("reports a healthy mount", func() {
+			Expect(mounter.Check(env, "some-volume", "/mnt/target", volumedriver.CheckRead)).To(BeTrue())
+			Expect(fakeMountChecker.ExistsArgsForCall(0)).To(Equal("/mnt/target"))
+			Expect(fakeIoutil.ReadDirCallCount()).To(Equal(1))
+		})
+
+		Context("when the mountpoint is not mounted", func() {
+			BeforeEach(func() {
+				fakeMountChecker.ExistsReturns(false, nil)
+			})
+
+			It("reports an unhealthy mount", func() {
+				Expect(mounter.Check(env, "some-volume", "/mnt/target", volumedriver.CheckStat)).To(BeFalse())
+			})
+		})
+
+		Context("when the probe fails", func() {
+			BeforeEach(func() {
+				fakeIoutil.ReadDirReturns(nil, errors.New("stale file handle"))
+			})
+
+			It("reports an unhealthy mount", func() {
+				Expect(mounter.Check(env, "some-volume", "/mnt/target", volumedriver.CheckRead)).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("Purge", func() {
+		BeforeEach(func() {
+			fakeMountChecker.ListReturns([]string{"/mnt/root/a", "/mnt/root/a/nested"}, nil)
+		})
+
+		It("unmounts and removes every mountpoint below the path, deepest first", func() {
+			mounter.Purge(env, "/mnt/root")
+
+			pattern := fakeMountChecker.ListArgsForCall(0)
+			Expect(pattern).To(Equal(regexp.MustCompile("^/mnt/root/")))
+
+			Expect(fakeInvoker.InvokeCallCount()).To(Equal(2))
+			_, _, args, _ := fakeInvoker.InvokeArgsForCall(0)
+			Expect(args).To(Equal([]string{"-l", "/mnt/root/a/nested"}))
+			_, _, args, _ = fakeInvoker.InvokeArgsForCall(1)
+			Expect(args).To(Equal([]string{"-l", "/mnt/root/a"}))
+
+			Expect(fakeOs.RemoveCallCount()).To(Equal(2))
+			Expect(fakeOs.RemoveAllCallCount()).To(BeZero())
+		})
+
+		Context("when an unmount fails", func() {
+			BeforeEach(func() {
+				fakeInvokeResult.WaitReturns(errors.New("exit status 32"))
+			})
+
+			It("leaves the mountpoint in place", func() {
+				mounter.Purge(env, "/mnt/root")
+				Expect(fakeOs.RemoveCallCount()).To(BeZero())
+			})
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package bindmounter_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestBindMounter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "BindMounter Suite")
+}
@@ -0,0 +1,13 @@
+package callerhttp_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestCallerHTTP(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Caller HTTP Suite")
+}
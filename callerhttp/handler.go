@@ -0,0 +1,60 @@
+// Package callerhttp attributes the requests of the volume plugin API to
+// their callers, so that the driver can track who holds mount references.
+package callerhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"code.cloudfoundry.org/volumedriver"
+)
+
+// IdentityFunc extracts the caller of a request, or returns the empty string
+// when it cannot tell. body is the request body, which has already been
+// read.
+type IdentityFunc func(req *http.Request, body []byte) string
+
+// RequestID identifies callers by the ID field that docker sends with Mount
+// and Unmount requests, which is unique per container.
+func RequestID(req *http.Request, body []byte) string {
+	var identified struct {
+		ID string
+	}
+	if err := json.Unmarshal(body, &identified); err != nil {
+		return ""
+	}
+	return identified.ID
+}
+
+// Header identifies callers by a request header, such as a container GUID
+// or a request ID set by the orchestrator.
+func Header(header string) IdentityFunc {
+	return func(req *http.Request, _ []byte) string {
+		return req.Header.Get(header)
+	}
+}
+
+// NewHandler passes the caller named by the first of identities that knows
+// it to the driver, through volumedriver.WithCaller.
+func NewHandler(handler http.Handler, identities ...IdentityFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			// leave it to the driver handler to report the broken body
+			handler.ServeHTTP(w, req)
+			return
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		for _, identify := range identities {
+			if caller := identify(req, body); caller != "" {
+				req = req.WithContext(volumedriver.WithCaller(req.Context(), caller))
+				break
+			}
+		}
+
+		handler.ServeHTTP(w, req)
+	})
+}
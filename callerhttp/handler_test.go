@@ -0,0 +1,53 @@
+package callerhttp_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/callerhttp"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Handler", func() {
+	var (
+		caller  string
+		body    string
+		handler http.Handler
+	)
+
+	BeforeEach(func() {
+		inner := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			caller = volumedriver.CallerFrom(req.Context())
+			data, err := ioutil.ReadAll(req.Body)
+			Expect(err).NotTo(HaveOccurred())
+			body = string(data)
+		})
+		handler = callerhttp.NewHandler(inner, callerhttp.Header("X-Container-Guid"), callerhttp.RequestID)
+	})
+
+	serve := func(requestBody string, header string) {
+		request := httptest.NewRequest("POST", "/VolumeDriver.Mount", strings.NewReader(requestBody))
+		if header != "" {
+			request.Header.Set("X-Container-Guid", header)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), request)
+	}
+
+	It("identifies the caller by the first identity that knows it", func() {
+		serve(`{"Name":"volume","ID":"container-id"}`, "container-guid")
+		Expect(caller).To(Equal("container-guid"))
+
+		serve(`{"Name":"volume","ID":"container-id"}`, "")
+		Expect(caller).To(Equal("container-id"))
+	})
+
+	It("passes the body on", func() {
+		serve(`{"Name":"volume"}`, "")
+		Expect(caller).To(BeEmpty())
+		Expect(body).To(Equal(`{"Name":"volume"}`))
+	})
+})
@@ -0,0 +1,227 @@
+package volumedriver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager"
+)
+
+type callerKey struct{}
+
+// WithCaller returns a context that attributes the Mount and Unmount
+// requests it is passed with to caller, such as a container GUID or a
+// request ID. See callerhttp for setting it from the plugin API.
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFrom returns the caller set by WithCaller, or the empty string for
+// requests of unknown callers.
+func CallerFrom(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	caller, _ := ctx.Value(callerKey{}).(string)
+	return caller
+}
+
+// addReference must be called with volumesLock held.
+func addReference(volume *NfsVolumeInfo, caller string) {
+	if caller == "" {
+		return
+	}
+	if volume.References == nil {
+		volume.References = map[string]int{}
+	}
+	volume.References[caller]++
+}
+
+// referenceOwner must be called with volumesLock held. It returns whose
+// reference an unmount by caller releases: the caller's own, otherwise an
+// unattributed one, which is returned as the empty string, otherwise the
+// first other caller's so that the counts stay consistent.
+func referenceOwner(volume *NfsVolumeInfo, caller string) string {
+	if volume.References[caller] > 0 {
+		return caller
+	}
+
+	attributed := 0
+	owners := []string{}
+	for owner, count := range volume.References {
+		attributed += count
+		owners = append(owners, owner)
+	}
+	if attributed < volume.MountCount || len(owners) == 0 {
+		return ""
+	}
+
+	sort.Strings(owners)
+	return owners[0]
+}
+
+// releaseReference must be called with volumesLock held.
+func releaseReference(volume *NfsVolumeInfo, owner string) {
+	if owner == "" {
+		return
+	}
+	volume.References[owner]--
+	if volume.References[owner] < 1 {
+		delete(volume.References, owner)
+	}
+	if len(volume.References) == 0 {
+		volume.References = nil
+	}
+}
+
+// abortMount must be called with volumesLock held. It takes back the mount
+// count and the reference that a Mount request took before it failed, since
+// docker sends no Unmount for a failed Mount. A volume that is left without
+// mounts and whose mountpoint is not mounted forgets the mountpoint, so that
+// Remove does not try to unmount it.
+func (d *VolumeDriver) abortMount(env dockerdriver.Env, volume *NfsVolumeInfo, caller string) {
+	logger := env.Logger().Session("abort-mount", lager.Data{"volume": volume.Name, "caller": caller})
+
+	if caller != "" && volume.References[caller] > 0 {
+		releaseReference(volume, caller)
+	}
+	if volume.MountCount > 0 {
+		volume.MountCount--
+	}
+	if volume.MountCount < 1 && volume.Mountpoint != "" {
+		if mounted, err := d.mountChecker.Exists(volume.Mountpoint); err == nil && !mounted {
+			volume.Mountpoint = ""
+		}
+	}
+	logger.Info("volume-ref-count-decremented", lager.Data{"count": volume.MountCount})
+	d.emitVolumeGauges()
+
+	if err := d.persistVolumeLater(env, volume.Name); err != nil {
+		logger.Error("persist-state-failed", err)
+	}
+}
+
+func copyReferences(references map[string]int) map[string]int {
+	if len(references) == 0 {
+		return nil
+	}
+	copied := map[string]int{}
+	for caller, count := range references {
+		copied[caller] = count
+	}
+	return copied
+}
+
+// RevokeReferences releases the mount references a caller holds, because it
+// is gone without unmounting, such as a crashed container. Volumes whose
+// last reference is revoked are unmounted. With Name set only the
+// references to that volume are revoked.
+func (d *VolumeDriver) RevokeReferences(env dockerdriver.Env, revokeRequest RevokeReferencesRequest) RevokeReferencesResponse {
+	logger := env.Logger().Session("revoke-references", lager.Data{"caller": revokeRequest.Caller, "volume": revokeRequest.Name})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	if revokeRequest.Caller == "" {
+		return RevokeReferencesResponse{Err: "Missing mandatory 'Caller'"}
+	}
+	if strings.HasPrefix(revokeRequest.Caller, bindCallerPrefix) {
+		// the bind mount would outlive the reference
+		return RevokeReferencesResponse{Err: fmt.Sprintf("'%s' is a bind, unbind it with UnbindVolume", revokeRequest.Caller)}
+	}
+
+	d.volumesLock.Lock()
+	defer d.volumesLock.Unlock()
+
+	names := []string{}
+	for name, volume := range d.volumes {
+		if revokeRequest.Name != "" && name != revokeRequest.Name {
+			continue
+		}
+		if volume.References[revokeRequest.Caller] > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	revoked := 0
+	for _, name := range names {
+		for d.volumes[name] != nil && d.volumes[name].References[revokeRequest.Caller] > 0 {
+			if err := d.releaseMount(driverhttp.EnvWithLogger(logger, env), name, revokeRequest.Caller); err != nil {
+				logger.Error("revoke-failed", err, lager.Data{"volume": name})
+				return RevokeReferencesResponse{Revoked: revoked, Err: fmt.Sprintf("Error revoking the references of '%s' to '%s': %s", revokeRequest.Caller, name, err.Error())}
+			}
+			revoked++
+		}
+	}
+
+	logger.Info("references-revoked", lager.Data{"revoked": revoked})
+	return RevokeReferencesResponse{Revoked: revoked}
+}
+
+// releaseMount must be called with volumesLock held. It releases one mount
+// reference of the volume, on behalf of caller, and unmounts and forgets the
+// volume with its last reference.
+func (d *VolumeDriver) releaseMount(env dockerdriver.Env, volumeName string, caller string) error {
+	logger := env.Logger().Session("release-mount", lager.Data{"volume": volumeName, "caller": caller})
+
+	volume, ok := d.volumes[volumeName]
+	if !ok {
+		logger.Error("failed-no-such-volume-found", fmt.Errorf("could not find volume %s", volumeName))
+		return fmt.Errorf("Volume '%s' not found", volumeName)
+	}
+
+	if volume.Mountpoint == "" {
+		errText := "Volume not previously mounted"
+		logger.Error("failed-mountpoint-not-assigned", errors.New(errText))
+		return errors.New(errText)
+	}
+
+	owner := referenceOwner(volume, caller)
+	if owner != caller {
+		logger.Info("releasing-reference-of-other-caller", lager.Data{"owner": owner})
+	}
+
+	if volume.MountCount == 1 {
+		if volume.Frozen {
+			return errors.New(frozenError(volumeName))
+		}
+
+		phase := volume.mount.phase
+		volume.mount.begin(phaseUnmounting)
+		if err := d.unmount(env, d.volumeMounter(volume), volumeName, volume.Mountpoint); err != nil {
+			volume.mount.finish(phase)
+			return err
+		}
+		volume.mount.finish(phaseUnmounted)
+		d.releaseExport(env, volumeName, sourceOpt(volume.Opts))
+		d.publish(EventUnmounted, volumeName, nil)
+	}
+
+	unmountedAt := d.time.Now()
+	volume.LastUnmountedAt = &unmountedAt
+	volume.MountCount--
+	releaseReference(volume, owner)
+	logger.Info("volume-ref-count-decremented", lager.Data{"name": volume.Name, "count": volume.MountCount})
+
+	var err error
+	if volume.MountCount < 1 {
+		delete(d.volumes, volumeName)
+		d.volumeLimiter.Forget(volumeName)
+		d.publish(EventRemoved, volumeName, nil)
+		err = d.persistVolumeOrQueue(env, volumeName)
+	} else {
+		err = d.persistVolumeLater(env, volumeName)
+	}
+	d.emitVolumeGauges()
+
+	if err != nil {
+		return fmt.Errorf("failed to persist state when unmounting: %s", err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package cgroupthrottler
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/goshims/ioutilshim"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+)
+
+// IOMaxFile is the cgroup v2 io controller interface file that holds the
+// limits of a cgroup, one line per device.
+const IOMaxFile = "io.max"
+
+type cgroupThrottler struct {
+	devices    DeviceResolver
+	ioutil     ioutilshim.Ioutil
+	cgroupPath string
+
+	lock sync.Mutex
+	// limited holds the device of every limited mountpoint
+	limited map[string]string
+}
+
+// NewCgroupThrottler returns an IOThrottler that writes the limits of a
+// volume to the io.max file of the cgroup v2 at cgroupPath, for the device
+// that backs the mount. Point it at the parent cgroup of the containers
+// that use the volumes, which needs the io controller enabled in its own
+// parent.
+//
+// The io controller only throttles block devices: network filesystems such
+// as NFS, which are mounted on anonymous devices, are refused. Limits are
+// per device, so volumes on the same device share the limits of whichever
+// was mounted last, until all of them are unmounted.
+func NewCgroupThrottler(devices DeviceResolver, ioutil ioutilshim.Ioutil, cgroupPath string) volumedriver.IOThrottler {
+	return &cgroupThrottler{
+		devices:    devices,
+		ioutil:     ioutil,
+		cgroupPath: cgroupPath,
+		limited:    map[string]string{},
+	}
+}
+
+func (t *cgroupThrottler) Limit(env dockerdriver.Env, mountPoint string, limits volumedriver.IOLimits) error {
+	logger := env.Logger().Session("cgroup-limit", lager.Data{"mount-point": mountPoint, "limits": limits})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	major, minor, err := t.devices.Device(mountPoint)
+	if err != nil {
+		logger.Error("device-lookup-failed", err)
+		return err
+	}
+	if major == 0 {
+		return safeerrors.New(safeerrors.Unsupported, "io limits need a block device, the volume is on device %d:%d", major, minor)
+	}
+	device := fmt.Sprintf("%d:%d", major, minor)
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	line := fmt.Sprintf("%s rbps=%s wbps=%s riops=%s wiops=%s", device, limit(limits.ReadBPS), limit(limits.WriteBPS), limit(limits.ReadIOPS), limit(limits.WriteIOPS))
+	if err := t.write(line); err != nil {
+		logger.Error("write-io-max-failed", err, lager.Data{"line": line})
+		return fmt.Errorf("failed to limit io: %s", err.Error())
+	}
+
+	t.limited[mountPoint] = device
+	return nil
+}
+
+func (t *cgroupThrottler) Unlimit(env dockerdriver.Env, mountPoint string) error {
+	logger := env.Logger().Session("cgroup-unlimit", lager.Data{"mount-point": mountPoint})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	device, ok := t.limited[mountPoint]
+	if !ok {
+		// limited before a restart; look the device up while it is mounted
+		major, minor, err := t.devices.Device(mountPoint)
+		if err != nil {
+			logger.Error("device-lookup-failed", err)
+			return err
+		}
+		device = fmt.Sprintf("%d:%d", major, minor)
+	}
+	delete(t.limited, mountPoint)
+
+	for _, other := range t.limited {
+		if other == device {
+			logger.Info("device-still-limited", lager.Data{"device": device})
+			return nil
+		}
+	}
+
+	line := fmt.Sprintf("%s rbps=max wbps=max riops=max wiops=max", device)
+	if err := t.write(line); err != nil {
+		logger.Error("write-io-max-failed", err, lager.Data{"line": line})
+		return fmt.Errorf("failed to remove io limits: %s", err.Error())
+	}
+	return nil
+}
+
+// write must be called with lock held. The kernel applies every line written
+// to io.max on its own, so there is nothing to read back and merge.
+func (t *cgroupThrottler) write(line string) error {
+	return t.ioutil.WriteFile(filepath.Join(t.cgroupPath, IOMaxFile), []byte(line+"\n"), 0644)
+}
+
+func limit(value uint64) string {
+	if value == 0 {
+		return "max"
+	}
+	return strconv.FormatUint(value, 10)
+}
@@ -0,0 +1,98 @@
+package cgroupthrottler_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/goshims/ioutilshim/ioutil_fake"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/cgroupthrottler"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CgroupThrottler", func() {
+	var (
+		env         dockerdriver.Env
+		fakeDevices *volumedriverfakes.FakeDeviceResolver
+		fakeIoutil  *ioutil_fake.FakeIoutil
+		throttler   volumedriver.IOThrottler
+	)
+
+	written := func(i int) (string, string) {
+		path, data, _ := fakeIoutil.WriteFileArgsForCall(i)
+		return path, string(data)
+	}
+
+	BeforeEach(func() {
+		env = driverhttp.NewHttpDriverEnv(lagertest.NewTestLogger("cgroup-throttler"), context.TODO())
+		fakeDevices = &volumedriverfakes.FakeDeviceResolver{}
+		fakeDevices.DeviceReturns(8, 16, nil)
+		fakeIoutil = &ioutil_fake.FakeIoutil{}
+		throttler = cgroupthrottler.NewCgroupThrottler(fakeDevices, fakeIoutil, "/sys/fs/cgroup/garden")
+	})
+
+	Describe("Limit", func() {
+		It("writes the limits of the device to io.max", func() {
+			err := throttler.Limit(env, "/mnt/volume", volumedriver.IOLimits{ReadBPS: 1048576, WriteIOPS: 100})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeDevices.DeviceArgsForCall(0)).To(Equal("/mnt/volume"))
+			path, data := written(0)
+			Expect(path).To(Equal(filepath.Join("/sys/fs/cgroup/garden", "io.max")))
+			Expect(data).To(Equal("8:16 rbps=1048576 wbps=max riops=max wiops=100\n"))
+		})
+
+		It("refuses mounts that are not on a block device", func() {
+			fakeDevices.DeviceReturns(0, 52, nil)
+
+			err := throttler.Limit(env, "/mnt/volume", volumedriver.IOLimits{ReadBPS: 1048576})
+			Expect(err).To(MatchError("io limits need a block device, the volume is on device 0:52"))
+			Expect(fakeIoutil.WriteFileCallCount()).To(BeZero())
+		})
+
+		It("fails when the device cannot be looked up", func() {
+			fakeDevices.DeviceReturns(0, 0, errors.New("no such file or directory"))
+			Expect(throttler.Limit(env, "/mnt/volume", volumedriver.IOLimits{ReadBPS: 1})).To(MatchError("no such file or directory"))
+		})
+
+		It("fails when io.max cannot be written", func() {
+			fakeIoutil.WriteFileReturns(errors.New("invalid argument"))
+			Expect(throttler.Limit(env, "/mnt/volume", volumedriver.IOLimits{ReadBPS: 1})).To(MatchError("failed to limit io: invalid argument"))
+		})
+	})
+
+	Describe("Unlimit", func() {
+		It("resets the limits of the device", func() {
+			Expect(throttler.Limit(env, "/mnt/volume", volumedriver.IOLimits{ReadBPS: 1})).To(Succeed())
+			Expect(throttler.Unlimit(env, "/mnt/volume")).To(Succeed())
+
+			_, data := written(1)
+			Expect(data).To(Equal("8:16 rbps=max wbps=max riops=max wiops=max\n"))
+		})
+
+		It("keeps the limits while another volume on the device is mounted", func() {
+			Expect(throttler.Limit(env, "/mnt/a", volumedriver.IOLimits{ReadBPS: 1})).To(Succeed())
+			Expect(throttler.Limit(env, "/mnt/b", volumedriver.IOLimits{ReadBPS: 1})).To(Succeed())
+
+			Expect(throttler.Unlimit(env, "/mnt/a")).To(Succeed())
+			Expect(fakeIoutil.WriteFileCallCount()).To(Equal(2))
+
+			Expect(throttler.Unlimit(env, "/mnt/b")).To(Succeed())
+			Expect(fakeIoutil.WriteFileCallCount()).To(Equal(3))
+		})
+
+		It("looks up the device of mounts limited before a restart", func() {
+			Expect(throttler.Unlimit(env, "/mnt/volume")).To(Succeed())
+
+			Expect(fakeDevices.DeviceCallCount()).To(Equal(1))
+			_, data := written(0)
+			Expect(data).To(Equal("8:16 rbps=max wbps=max riops=max wiops=max\n"))
+		})
+	})
+})
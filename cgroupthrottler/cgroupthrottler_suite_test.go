@@ -0,0 +1,13 @@
+package cgroupthrottler_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestCgroupThrottler(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Cgroup Throttler Suite")
+}
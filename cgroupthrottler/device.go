@@ -0,0 +1,8 @@
+package cgroupthrottler
+
+//go:generate counterfeiter -o ../volumedriverfakes/fake_device_resolver.go . DeviceResolver
+type DeviceResolver interface {
+	// Device returns the major and minor number of the device that holds
+	// path.
+	Device(path string) (major uint32, minor uint32, err error)
+}
@@ -0,0 +1,24 @@
+// +build linux
+
+package cgroupthrottler
+
+import "syscall"
+
+type deviceResolver struct{}
+
+// NewDeviceResolver returns a DeviceResolver backed by stat(2).
+func NewDeviceResolver() DeviceResolver {
+	return &deviceResolver{}
+}
+
+func (*deviceResolver) Device(path string) (uint32, uint32, error) {
+	var stat syscall.Stat_t
+	if err := syscall.Stat(path, &stat); err != nil {
+		return 0, 0, err
+	}
+
+	dev := uint64(stat.Dev)
+	major := uint32((dev>>8)&0xfff) | uint32((dev>>32)&^0xfff)
+	minor := uint32(dev&0xff) | uint32((dev>>12)&^0xff)
+	return major, minor, nil
+}
@@ -0,0 +1,17 @@
+// +build !linux
+
+package cgroupthrottler
+
+import "errors"
+
+type deviceResolver struct{}
+
+// NewDeviceResolver returns a DeviceResolver that always fails, cgroups only
+// exist on linux.
+func NewDeviceResolver() DeviceResolver {
+	return &deviceResolver{}
+}
+
+func (*deviceResolver) Device(path string) (uint32, uint32, error) {
+	return 0, 0, errors.New("io limits are only supported on linux")
+}
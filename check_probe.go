@@ -0,0 +1,66 @@
+package volumedriver
+
+import (
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/goshims/ioutilshim"
+	"code.cloudfoundry.org/goshims/osshim"
+)
+
+const checkProbePrefix = ".volumedriver-probe-"
+
+func ParseCheckDepth(depth string) (CheckDepth, error) {
+	switch CheckDepth(strings.ToLower(depth)) {
+	case CheckStat:
+		return CheckStat, nil
+	case CheckRead:
+		return CheckRead, nil
+	case CheckWrite:
+		return CheckWrite, nil
+	}
+
+	return "", fmt.Errorf("invalid check depth '%s', must be one of %s, %s or %s", depth, CheckStat, CheckRead, CheckWrite)
+}
+
+// ProbeMountPoint exercises a mounted filesystem up to the given depth. Mounter
+// implementations can use it in Check once they have verified that the
+// mountpoint is mounted.
+func ProbeMountPoint(os osshim.Os, ioutil ioutilshim.Ioutil, mountPoint string, depth CheckDepth) error {
+	info, err := os.Stat(mountPoint)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("mountpoint %s is not a directory", mountPoint)
+	}
+
+	if depth == CheckStat {
+		return nil
+	}
+
+	if _, err := ioutil.ReadDir(mountPoint); err != nil {
+		return err
+	}
+
+	if depth == CheckRead {
+		return nil
+	}
+
+	probe, err := ioutil.TempFile(mountPoint, checkProbePrefix)
+	if err != nil {
+		return err
+	}
+
+	_, writeErr := probe.WriteString("probe")
+	closeErr := probe.Close()
+	removeErr := os.Remove(probe.Name())
+
+	for _, err := range []error{writeErr, closeErr, removeErr} {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
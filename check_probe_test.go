@@ -0,0 +1,121 @@
+package volumedriver_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/goshims/ioutilshim/ioutil_fake"
+	"code.cloudfoundry.org/goshims/osshim/os_fake"
+	"code.cloudfoundry.org/volumedriver"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ProbeMountPoint", func() {
+	var (
+		fakeOs      *os_fake.FakeOs
+		fakeIoutil  *ioutil_fake.FakeIoutil
+		fakeDirInfo *ioutil_fake.FakeFileInfo
+		fakeProbe   *os_fake.FakeFile
+		depth       volumedriver.CheckDepth
+		err         error
+	)
+
+	BeforeEach(func() {
+		fakeOs = &os_fake.FakeOs{}
+		fakeIoutil = &ioutil_fake.FakeIoutil{}
+
+		fakeDirInfo = &ioutil_fake.FakeFileInfo{}
+		fakeDirInfo.IsDirReturns(true)
+		fakeOs.StatReturns(fakeDirInfo, nil)
+
+		fakeProbe = &os_fake.FakeFile{}
+		fakeProbe.NameReturns("/mnt/volume/.volumedriver-probe-123")
+		fakeIoutil.TempFileReturns(fakeProbe, nil)
+	})
+
+	JustBeforeEach(func() {
+		err = volumedriver.ProbeMountPoint(fakeOs, fakeIoutil, "/mnt/volume", depth)
+	})
+
+	Context("with stat depth", func() {
+		BeforeEach(func() {
+			depth = volumedriver.CheckStat
+		})
+
+		It("only stats the mountpoint", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeOs.StatArgsForCall(0)).To(Equal("/mnt/volume"))
+			Expect(fakeIoutil.ReadDirCallCount()).To(Equal(0))
+			Expect(fakeIoutil.TempFileCallCount()).To(Equal(0))
+		})
+
+		Context("when the mountpoint is not a directory", func() {
+			BeforeEach(func() {
+				fakeDirInfo.IsDirReturns(false)
+			})
+
+			It("fails", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Context("with read depth", func() {
+		BeforeEach(func() {
+			depth = volumedriver.CheckRead
+		})
+
+		It("lists the mountpoint without writing", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeIoutil.ReadDirArgsForCall(0)).To(Equal("/mnt/volume"))
+			Expect(fakeIoutil.TempFileCallCount()).To(Equal(0))
+		})
+
+		Context("when the listing fails", func() {
+			BeforeEach(func() {
+				fakeIoutil.ReadDirReturns(nil, errors.New("stale file handle"))
+			})
+
+			It("fails", func() {
+				Expect(err).To(MatchError("stale file handle"))
+			})
+		})
+	})
+
+	Context("with write depth", func() {
+		BeforeEach(func() {
+			depth = volumedriver.CheckWrite
+		})
+
+		It("writes and removes a probe file", func() {
+			Expect(err).NotTo(HaveOccurred())
+			dir, prefix := fakeIoutil.TempFileArgsForCall(0)
+			Expect(dir).To(Equal("/mnt/volume"))
+			Expect(prefix).To(Equal(".volumedriver-probe-"))
+			Expect(fakeProbe.WriteStringCallCount()).To(Equal(1))
+			Expect(fakeProbe.CloseCallCount()).To(Equal(1))
+			Expect(fakeOs.RemoveArgsForCall(0)).To(Equal("/mnt/volume/.volumedriver-probe-123"))
+		})
+
+		Context("when the export is read-only", func() {
+			BeforeEach(func() {
+				fakeIoutil.TempFileReturns(nil, errors.New("read-only file system"))
+			})
+
+			It("fails", func() {
+				Expect(err).To(MatchError("read-only file system"))
+			})
+		})
+	})
+})
+
+var _ = Describe("ParseCheckDepth", func() {
+	It("accepts the known depths regardless of case", func() {
+		Expect(volumedriver.ParseCheckDepth("Write")).To(Equal(volumedriver.CheckWrite))
+	})
+
+	It("rejects unknown depths", func() {
+		_, err := volumedriver.ParseCheckDepth("deep")
+		Expect(err).To(MatchError("invalid check depth 'deep', must be one of stat, read or write"))
+	})
+})
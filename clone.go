@@ -0,0 +1,106 @@
+package volumedriver
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager"
+)
+
+//go:generate counterfeiter -o volumedriverfakes/fake_cloner.go . Cloner
+
+// Cloner copies the directory from to the directory to, both relative to the
+// root of source. opts are the mount opts of the volume being cloned.
+type Cloner interface {
+	Clone(env dockerdriver.Env, source string, opts map[string]interface{}, from string, to string) error
+}
+
+func (d *VolumeDriver) Clone(env dockerdriver.Env, cloneRequest CloneRequest) dockerdriver.ErrorResponse {
+	logger := env.Logger().Session("clone", lager.Data{"request": cloneRequest})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	if cloneRequest.Name == "" || cloneRequest.From == "" {
+		return dockerdriver.ErrorResponse{Err: "Missing mandatory 'volume_name'"}
+	}
+	if err := ValidateVolumeName(cloneRequest.Name); err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+
+	if d.options.Cloner == nil {
+		return dockerdriver.ErrorResponse{Err: "Clone is not supported by this driver"}
+	}
+
+	subdirectory, err := cloneDirectory("subdirectory", cloneRequest.Subdirectory)
+	if err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+	target, err := cloneDirectory("target", cloneRequest.Target)
+	if err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+	if nested(subdirectory, target) {
+		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("target '%s' and subdirectory '%s' must not contain each other", target, subdirectory)}
+	}
+
+	opts, errResponse := d.cloneOpts(cloneRequest)
+	if errResponse.Err != "" {
+		return errResponse
+	}
+	source := sourceOpt(opts)
+
+	cloneOpts, _ := d.hardenOpts(mounterOpts(opts))
+	cloneOpts, secrets, err := d.resolveSecrets(driverhttp.EnvWithLogger(logger, env), cloneOpts)
+	if err == nil {
+		err = redactSecrets(d.options.Cloner.Clone(driverhttp.EnvWithLogger(logger, env), source, cloneOpts, subdirectory, target), secrets)
+	}
+	if err != nil {
+		logger.Error("clone-failed", err)
+		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("Error cloning volume '%s': %s", cloneRequest.From, err.Error())}
+	}
+
+	opts["source"] = strings.TrimSuffix(source, "/") + "/" + target
+
+	return d.Create(driverhttp.EnvWithLogger(logger, env), dockerdriver.CreateRequest{Name: cloneRequest.Name, Opts: opts})
+}
+
+// cloneOpts returns a copy of the opts of the volume being cloned, after
+// checking that the clone does not exist yet.
+func (d *VolumeDriver) cloneOpts(cloneRequest CloneRequest) (map[string]interface{}, dockerdriver.ErrorResponse) {
+	d.volumesLock.RLock()
+	defer d.volumesLock.RUnlock()
+
+	if _, ok := d.volumes[cloneRequest.Name]; ok {
+		return nil, dockerdriver.ErrorResponse{Err: fmt.Sprintf("Volume '%s' already exists", cloneRequest.Name)}
+	}
+
+	from, ok := d.volumes[cloneRequest.From]
+	if !ok {
+		return nil, dockerdriver.ErrorResponse{Err: fmt.Sprintf("Volume '%s' not found", cloneRequest.From)}
+	}
+
+	// volumes restored without their opts, see restoredOpts, have none
+	if sourceOpt(from.Opts) == "" {
+		return nil, dockerdriver.ErrorResponse{Err: fmt.Sprintf("Volume '%s' cannot be cloned until it is created again", cloneRequest.From)}
+	}
+
+	// the clone lives in the export of the volume, it is not provisioned
+	// again
+	return withoutProvisionOpts(from.Opts), dockerdriver.ErrorResponse{}
+}
+
+// cloneDirectory checks that dir is a directory below the root of a source.
+func cloneDirectory(name string, dir string) (string, error) {
+	cleaned := path.Clean("/" + strings.Replace(dir, `\`, "/", -1))
+	if dir == "" || cleaned == "/" || cleaned != "/"+strings.Trim(dir, "/") {
+		return "", fmt.Errorf("invalid %s '%s', must be a relative path below the volume source", name, dir)
+	}
+	return strings.TrimPrefix(cleaned, "/"), nil
+}
+
+func nested(a, b string) bool {
+	return a == b || strings.HasPrefix(a, b+"/") || strings.HasPrefix(b, a+"/")
+}
@@ -0,0 +1,95 @@
+package cloner
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/goshims/ioutilshim"
+	"code.cloudfoundry.org/goshims/osshim"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/invoker"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+)
+
+type mountCloner struct {
+	mounter     volumedriver.Mounter
+	invoker     invoker.Invoker
+	os          osshim.Os
+	ioutil      ioutilshim.Ioutil
+	scratchRoot string
+}
+
+// NewMountCloner returns a Cloner that mounts the source in a scratch
+// directory below scratchRoot and copies the data with cp --reflink=auto.
+// Both directories are on the same export, so servers that support it
+// (NFS 4.2 and most copy-on-write filesystems) clone the files server side
+// instead of streaming them through the cell.
+func NewMountCloner(mounter volumedriver.Mounter, invoker invoker.Invoker, os osshim.Os, ioutil ioutilshim.Ioutil, scratchRoot string) volumedriver.Cloner {
+	return &mountCloner{
+		mounter:     mounter,
+		invoker:     invoker,
+		os:          os,
+		ioutil:      ioutil,
+		scratchRoot: scratchRoot,
+	}
+}
+
+func (c *mountCloner) Clone(env dockerdriver.Env, source string, opts map[string]interface{}, from string, to string) error {
+	logger := env.Logger().Session("mount-clone", lager.Data{"source": source, "from": from, "to": to})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	if err := c.os.MkdirAll(c.scratchRoot, 0755); err != nil {
+		logger.Error("create-scratch-root-failed", err)
+		return err
+	}
+
+	scratch, err := c.ioutil.TempDir(c.scratchRoot, "clone-")
+	if err != nil {
+		logger.Error("create-scratch-dir-failed", err)
+		return err
+	}
+	defer func() {
+		if err := c.os.Remove(scratch); err != nil {
+			logger.Error("remove-scratch-dir-failed", err, lager.Data{"scratch": scratch})
+		}
+	}()
+
+	if err := c.mounter.Mount(env, source, scratch, opts); err != nil {
+		logger.Error("mount-failed", err)
+		return err
+	}
+	defer func() {
+		if err := c.mounter.Unmount(env, scratch); err != nil {
+			logger.Error("unmount-failed", err, lager.Data{"scratch": scratch})
+		}
+	}()
+
+	fromPath := filepath.Join(scratch, filepath.FromSlash(from))
+	toPath := filepath.Join(scratch, filepath.FromSlash(to))
+
+	info, err := c.os.Stat(fromPath)
+	if err != nil || !info.IsDir() {
+		return safeerrors.New(safeerrors.NotFound, "subdirectory '%s' does not exist", from)
+	}
+
+	if _, err := c.os.Stat(toPath); err == nil {
+		return safeerrors.New(safeerrors.AlreadyExists, "target '%s' already exists", to)
+	}
+
+	if err := c.os.MkdirAll(filepath.Dir(toPath), 0755); err != nil {
+		logger.Error("create-target-parent-failed", err)
+		return err
+	}
+
+	result := c.invoker.Invoke(env, "cp", []string{"-a", "--reflink=auto", fromPath, toPath})
+	if err := result.Wait(); err != nil {
+		logger.Error("copy-failed", err, lager.Data{"stderr": result.StdError()})
+		return fmt.Errorf("copy failed: %s", strings.TrimSpace(result.StdError()))
+	}
+
+	return nil
+}
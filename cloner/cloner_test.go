@@ -0,0 +1,141 @@
+package cloner_test
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/goshims/ioutilshim/ioutil_fake"
+	"code.cloudfoundry.org/goshims/osshim/os_fake"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/cloner"
+	"code.cloudfoundry.org/volumedriver/invokerfakes"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MountCloner", func() {
+	var (
+		logger           *lagertest.TestLogger
+		env              dockerdriver.Env
+		fakeMounter      *volumedriverfakes.FakeMounter
+		fakeInvoker      *invokerfakes.FakeInvoker
+		fakeInvokeResult *invokerfakes.FakeInvokeResult
+		fakeOs           *os_fake.FakeOs
+		fakeIoutil       *ioutil_fake.FakeIoutil
+		c                volumedriver.Cloner
+		opts             map[string]interface{}
+		err              error
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("cloner")
+		env = driverhttp.NewHttpDriverEnv(logger, context.TODO())
+		fakeMounter = &volumedriverfakes.FakeMounter{}
+		fakeInvoker = &invokerfakes.FakeInvoker{}
+		fakeInvokeResult = &invokerfakes.FakeInvokeResult{}
+		fakeInvoker.InvokeReturns(fakeInvokeResult)
+		fakeOs = &os_fake.FakeOs{}
+		fakeIoutil = &ioutil_fake.FakeIoutil{}
+		fakeIoutil.TempDirReturns("/var/vcap/data/clones/clone-123", nil)
+		opts = map[string]interface{}{"vers": "4.2"}
+
+		dirInfo := &ioutil_fake.FakeFileInfo{}
+		dirInfo.IsDirReturns(true)
+		fakeOs.StatStub = func(path string) (os.FileInfo, error) {
+			if path == "/var/vcap/data/clones/clone-123/app/blue" {
+				return dirInfo, nil
+			}
+			return nil, os.ErrNotExist
+		}
+
+		c = cloner.NewMountCloner(fakeMounter, fakeInvoker, fakeOs, fakeIoutil, "/var/vcap/data/clones")
+	})
+
+	JustBeforeEach(func() {
+		err = c.Clone(env, "server:/export", opts, "app/blue", "app/green")
+	})
+
+	It("mounts the source in a scratch directory", func() {
+		Expect(err).NotTo(HaveOccurred())
+		dir, prefix := fakeIoutil.TempDirArgsForCall(0)
+		Expect(dir).To(Equal("/var/vcap/data/clones"))
+		Expect(prefix).To(Equal("clone-"))
+
+		Expect(fakeMounter.MountCallCount()).To(Equal(1))
+		_, source, target, mountOpts := fakeMounter.MountArgsForCall(0)
+		Expect(source).To(Equal("server:/export"))
+		Expect(target).To(Equal("/var/vcap/data/clones/clone-123"))
+		Expect(mountOpts).To(Equal(opts))
+	})
+
+	It("copies the subdirectory with reflinks", func() {
+		Expect(fakeInvoker.InvokeCallCount()).To(Equal(1))
+		_, cmd, args, _ := fakeInvoker.InvokeArgsForCall(0)
+		Expect(cmd).To(Equal("cp"))
+		Expect(args).To(Equal([]string{"-a", "--reflink=auto", "/var/vcap/data/clones/clone-123/app/blue", "/var/vcap/data/clones/clone-123/app/green"}))
+	})
+
+	It("unmounts and removes the scratch directory", func() {
+		Expect(fakeMounter.UnmountCallCount()).To(Equal(1))
+		_, target := fakeMounter.UnmountArgsForCall(0)
+		Expect(target).To(Equal("/var/vcap/data/clones/clone-123"))
+		Expect(fakeOs.RemoveCallCount()).To(Equal(1))
+		Expect(fakeOs.RemoveArgsForCall(0)).To(Equal("/var/vcap/data/clones/clone-123"))
+	})
+
+	Context("when the subdirectory does not exist", func() {
+		BeforeEach(func() {
+			fakeOs.StatReturns(nil, os.ErrNotExist)
+			fakeOs.StatStub = nil
+		})
+
+		It("fails without copying", func() {
+			Expect(err).To(MatchError("subdirectory 'app/blue' does not exist"))
+			Expect(fakeInvoker.InvokeCallCount()).To(Equal(0))
+			Expect(fakeMounter.UnmountCallCount()).To(Equal(1))
+		})
+	})
+
+	Context("when the target already exists", func() {
+		BeforeEach(func() {
+			dirInfo := &ioutil_fake.FakeFileInfo{}
+			dirInfo.IsDirReturns(true)
+			fakeOs.StatStub = nil
+			fakeOs.StatReturns(dirInfo, nil)
+		})
+
+		It("does not overwrite it", func() {
+			Expect(err).To(MatchError("target 'app/green' already exists"))
+			Expect(fakeInvoker.InvokeCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the mount fails", func() {
+		BeforeEach(func() {
+			fakeMounter.MountReturns(errors.New("badness"))
+		})
+
+		It("removes the scratch directory", func() {
+			Expect(err).To(MatchError("badness"))
+			Expect(fakeMounter.UnmountCallCount()).To(Equal(0))
+			Expect(fakeOs.RemoveCallCount()).To(Equal(1))
+		})
+	})
+
+	Context("when the copy fails", func() {
+		BeforeEach(func() {
+			fakeInvokeResult.WaitReturns(errors.New("exit status 1"))
+			fakeInvokeResult.StdErrorReturns("cp: No space left on device\n")
+		})
+
+		It("returns the copy error", func() {
+			Expect(err).To(MatchError("copy failed: cp: No space left on device"))
+			Expect(fakeMounter.UnmountCallCount()).To(Equal(1))
+		})
+	})
+})
@@ -0,0 +1,27 @@
+// Command volumedriver-mount-helper mounts and unmounts nfs exports for a
+// driver that does not run as root. It is installed setuid root and only does
+// what the policy at mounthelper.DefaultPolicyPath allows:
+//
+//	volumedriver-mount-helper mount SOURCE TARGET OPTS
+//	volumedriver-mount-helper unmount TARGET
+//
+// Refused and failed requests exit with status 1 and the error on stderr.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"code.cloudfoundry.org/volumedriver/mounthelper"
+)
+
+func main() {
+	policy, err := mounthelper.LoadPolicy(mounthelper.DefaultPolicyPath)
+	if err == nil {
+		err = mounthelper.NewHelper(policy).Run(os.Args[1:])
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/volumedriver/driverclient"
+)
+
+// requestTimeout bounds a request. Drain waits for unmounts, so it is
+// generous.
+const requestTimeout = 5 * time.Minute
+
+// endpoint returns the base URL of addr and an http client that reaches it.
+func endpoint(addr string) (string, *http.Client, error) {
+	if addr == "" {
+		return "", nil, fmt.Errorf("no address given for this command, see -help")
+	}
+	return driverclient.Endpoint(addr, requestTimeout)
+}
+
+// call posts request to path and prints the response, which is decoded into
+// response to tell whether it carries an error.
+func (c *ctl) call(addr, path string, request interface{}, response interface{}) error {
+	body := []byte("{}")
+	if request != nil {
+		var err error
+		if body, err = json.Marshal(request); err != nil {
+			return err
+		}
+	}
+
+	data, err := c.do(addr, "POST", path, body)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, response); err != nil {
+		return fmt.Errorf("invalid response from %s: %s", path, err)
+	}
+	if err := c.print(response); err != nil {
+		return err
+	}
+
+	if errText := reflect.ValueOf(response).Elem().FieldByName("Err"); errText.IsValid() && errText.String() != "" {
+		return errFailed
+	}
+	return nil
+}
+
+// get prints the JSON served at path as it is.
+func (c *ctl) get(addr, path string) error {
+	data, err := c.do(addr, "GET", path, nil)
+	if err != nil {
+		return err
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, data, "", "  "); err != nil {
+		return fmt.Errorf("invalid response from %s: %s", path, err)
+	}
+	indented.WriteString("\n")
+	_, err = c.stdout.Write(indented.Bytes())
+	return err
+}
+
+func (c *ctl) do(addr, method, path string, body []byte) ([]byte, error) {
+	base, client, err := endpoint(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequest(method, base+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s: %s: %s", method, path, response.Status, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
+
+func (c *ctl) print(response interface{}) error {
+	data, err := json.MarshalIndent(response, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(c.stdout, string(data))
+	return err
+}
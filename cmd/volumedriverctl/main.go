@@ -0,0 +1,247 @@
+// Command volumedriverctl administers a running driver through its plugin
+// API, its admin endpoints and its debug endpoints, over a unix socket or
+// tcp.
+//
+//	volumedriverctl [-driver ADDR] [-admin ADDR] [-debug ADDR] COMMAND [ARGS]
+//
+// Addresses are unix:///path/to/driver.sock, tcp://host:port or
+// http://host:port. Responses are printed as JSON; commands whose response
+// carries an error exit with status 1.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/volumedriver"
+)
+
+// Env names the environment variables that default the address flags.
+const (
+	DriverAddrEnv = "VOLUMEDRIVERCTL_DRIVER"
+	AdminAddrEnv  = "VOLUMEDRIVERCTL_ADMIN"
+	DebugAddrEnv  = "VOLUMEDRIVERCTL_DEBUG"
+)
+
+const defaultDriverAddr = "tcp://127.0.0.1:7589"
+
+type command struct {
+	usage    string
+	args     int // required arguments
+	optional int
+	run      func(c *ctl, args []string) error
+}
+
+var commands = map[string]command{
+	"list": {"list", 0, 0, func(c *ctl, args []string) error {
+		return c.call(c.driver, "/VolumeDriver.List", nil, &dockerdriver.ListResponse{})
+	}},
+	"get": {"get NAME", 1, 0, func(c *ctl, args []string) error {
+		return c.call(c.driver, "/VolumeDriver.Get", dockerdriver.GetRequest{Name: args[0]}, &dockerdriver.GetResponse{})
+	}},
+	"mount": {"mount NAME", 1, 0, func(c *ctl, args []string) error {
+		return c.call(c.driver, "/VolumeDriver.Mount", dockerdriver.MountRequest{Name: args[0]}, &dockerdriver.MountResponse{})
+	}},
+	"unmount": {"unmount NAME", 1, 0, func(c *ctl, args []string) error {
+		return c.call(c.driver, "/VolumeDriver.Unmount", dockerdriver.UnmountRequest{Name: args[0]}, &dockerdriver.ErrorResponse{})
+	}},
+	"validate": {"validate NAME OPTS-JSON [test-mount]", 2, 1, func(c *ctl, args []string) error {
+		validateRequest := volumedriver.ValidateRequest{Name: args[0]}
+		if err := json.Unmarshal([]byte(args[1]), &validateRequest.Opts); err != nil {
+			return fmt.Errorf("invalid opts '%s': %s", args[1], err)
+		}
+		if len(args) > 2 {
+			if args[2] != "test-mount" {
+				return fmt.Errorf("usage: volumedriverctl validate NAME OPTS-JSON [test-mount]")
+			}
+			validateRequest.TestMount = true
+		}
+
+		var validateResponse volumedriver.ValidateResponse
+		if err := c.call(c.driver, "/VolumeDriver.Validate", validateRequest, &validateResponse); err != nil {
+			return err
+		}
+		if !validateResponse.Valid {
+			return errFailed
+		}
+		return nil
+	}},
+	"remove": {"remove NAME [force]", 1, 1, func(c *ctl, args []string) error {
+		if len(args) == 1 {
+			return c.call(c.driver, "/VolumeDriver.Remove", dockerdriver.RemoveRequest{Name: args[0]}, &dockerdriver.ErrorResponse{})
+		}
+		if args[1] != "force" {
+			return fmt.Errorf("usage: volumedriverctl remove NAME [force]")
+		}
+		// only the admin API removes volumes that are still mounted
+		return c.call(c.admin, "/Admin.RemoveVolume", volumedriver.RemoveVolumeRequest{Name: args[0], Force: true}, &dockerdriver.ErrorResponse{})
+	}},
+	"bind": {"bind NAME TARGET [readonly]", 2, 1, func(c *ctl, args []string) error {
+		bindRequest := volumedriver.BindVolumeRequest{Name: args[0], Target: args[1]}
+		if len(args) > 2 {
+			if args[2] != "readonly" {
+				return fmt.Errorf("usage: volumedriverctl bind NAME TARGET [readonly]")
+			}
+			bindRequest.ReadOnly = true
+		}
+		return c.call(c.admin, "/Admin.BindVolume", bindRequest, &dockerdriver.ErrorResponse{})
+	}},
+	"unbind": {"unbind NAME TARGET", 2, 0, func(c *ctl, args []string) error {
+		return c.call(c.admin, "/Admin.UnbindVolume", volumedriver.UnbindVolumeRequest{Name: args[0], Target: args[1]}, &dockerdriver.ErrorResponse{})
+	}},
+	"unused": {"unused DURATION", 1, 0, func(c *ctl, args []string) error {
+		unusedFor, err := time.ParseDuration(args[0])
+		if err != nil || unusedFor <= 0 {
+			return fmt.Errorf("invalid duration '%s', expected for example 720h", args[0])
+		}
+		return c.call(c.admin, "/Admin.ListVolumes", volumedriver.ListVolumesRequest{UnusedFor: unusedFor}, &volumedriver.ListVolumesResponse{})
+	}},
+	"drain": {"drain", 0, 0, func(c *ctl, args []string) error {
+		return c.call(c.admin, "/Admin.Drain", nil, &volumedriver.DrainResponse{})
+	}},
+	"operations": {"operations", 0, 0, func(c *ctl, args []string) error {
+		return c.call(c.admin, "/Admin.InFlightOperations", nil, &volumedriver.InFlightOperationsResponse{})
+	}},
+	"export": {"export", 0, 0, func(c *ctl, args []string) error {
+		return c.call(c.admin, "/Admin.ExportState", nil, &volumedriver.ExportStateResponse{})
+	}},
+	"import": {"import FILE|- [overwrite]", 1, 1, func(c *ctl, args []string) error {
+		importRequest, err := c.readImport(args[0])
+		if err != nil {
+			return err
+		}
+		if len(args) > 1 {
+			if args[1] != "overwrite" {
+				return fmt.Errorf("usage: volumedriverctl import FILE|- [overwrite]")
+			}
+			importRequest.Overwrite = true
+		}
+		return c.call(c.admin, "/Admin.ImportState", importRequest, &dockerdriver.ErrorResponse{})
+	}},
+	"snapshots": {"snapshots", 0, 0, func(c *ctl, args []string) error {
+		return c.call(c.admin, "/Admin.ListSnapshots", nil, &volumedriver.ListSnapshotsResponse{})
+	}},
+	"restore-snapshot": {"restore-snapshot ID [force]", 1, 1, func(c *ctl, args []string) error {
+		restoreRequest := volumedriver.RestoreSnapshotRequest{ID: args[0]}
+		if len(args) > 1 {
+			if args[1] != "force" {
+				return fmt.Errorf("usage: volumedriverctl restore-snapshot ID [force]")
+			}
+			restoreRequest.Force = true
+		}
+		return c.call(c.admin, "/Admin.RestoreSnapshot", restoreRequest, &dockerdriver.ErrorResponse{})
+	}},
+	"debug-dump": {"debug-dump", 0, 0, func(c *ctl, args []string) error {
+		return c.get(c.debug, "/debug/state")
+	}},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr, os.LookupEnv))
+}
+
+type ctl struct {
+	driver string
+	admin  string
+	debug  string
+	stdin  io.Reader
+	stdout io.Writer
+}
+
+// errFailed is returned for responses that carry an error. The response has
+// been printed already.
+var errFailed = fmt.Errorf("the driver reported an error")
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer, lookupEnv func(string) (string, bool)) int {
+	c := &ctl{stdin: stdin, stdout: stdout}
+
+	fs := flag.NewFlagSet("volumedriverctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&c.driver, "driver", envOr(lookupEnv, DriverAddrEnv, defaultDriverAddr), "address of the plugin API (env "+DriverAddrEnv+")")
+	fs.StringVar(&c.admin, "admin", envOr(lookupEnv, AdminAddrEnv, ""), "address of the admin endpoints (env "+AdminAddrEnv+")")
+	fs.StringVar(&c.debug, "debug", envOr(lookupEnv, DebugAddrEnv, ""), "address of the debug endpoints (env "+DebugAddrEnv+")")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: volumedriverctl [flags] COMMAND [ARGS]")
+		fmt.Fprintln(stderr, "\ncommands:")
+		for _, name := range commandNames() {
+			fmt.Fprintln(stderr, "  "+commands[name].usage)
+		}
+		fmt.Fprintln(stderr, "\nflags:")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command '%s'\n", fs.Arg(0))
+		fs.Usage()
+		return 2
+	}
+	cmdArgs := fs.Args()[1:]
+	if len(cmdArgs) < cmd.args || len(cmdArgs) > cmd.args+cmd.optional {
+		fmt.Fprintf(stderr, "usage: volumedriverctl %s\n", cmd.usage)
+		return 2
+	}
+
+	if err := cmd.run(c, cmdArgs); err != nil {
+		if err != errFailed {
+			fmt.Fprintln(stderr, err.Error())
+		}
+		return 1
+	}
+	return 0
+}
+
+func (c *ctl) readImport(path string) (volumedriver.ImportStateRequest, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = ioutil.ReadAll(c.stdin)
+	} else {
+		data, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return volumedriver.ImportStateRequest{}, err
+	}
+
+	// accept the output of export as well as a bare list of volumes
+	var exported volumedriver.ExportStateResponse
+	if err := json.Unmarshal(data, &exported); err == nil && exported.Volumes != nil {
+		return volumedriver.ImportStateRequest{Volumes: exported.Volumes}, nil
+	}
+	var volumes []volumedriver.ExportedVolume
+	if err := json.Unmarshal(data, &volumes); err != nil {
+		return volumedriver.ImportStateRequest{}, fmt.Errorf("invalid import '%s', expected the output of export or a list of volumes: %s", path, err)
+	}
+	return volumedriver.ImportStateRequest{Volumes: volumes}, nil
+}
+
+func envOr(lookupEnv func(string) (string, bool), name, fallback string) string {
+	if value, ok := lookupEnv(name); ok {
+		return value
+	}
+	return fallback
+}
+
+func commandNames() []string {
+	names := []string{}
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("volumedriverctl", func() {
+	var (
+		server    *httptest.Server
+		requests  map[string]string
+		responses map[string]string
+		env       map[string]string
+		stdin     *bytes.Buffer
+		stdout    *bytes.Buffer
+		stderr    *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		requests = map[string]string{}
+		responses = map[string]string{}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			body, _ := ioutil.ReadAll(req.Body)
+			requests[req.Method+" "+req.URL.Path] = string(body)
+
+			response, ok := responses[req.URL.Path]
+			if !ok {
+				http.NotFound(w, req)
+				return
+			}
+			w.Write([]byte(response))
+		}))
+
+		env = map[string]string{DriverAddrEnv: server.URL, AdminAddrEnv: server.URL, DebugAddrEnv: server.URL}
+		stdin = &bytes.Buffer{}
+		stdout = &bytes.Buffer{}
+		stderr = &bytes.Buffer{}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	ctl := func(args ...string) int {
+		return run(args, stdin, stdout, stderr, func(name string) (string, bool) {
+			value, ok := env[name]
+			return value, ok
+		})
+	}
+
+	It("lists the volumes", func() {
+		responses["/VolumeDriver.List"] = `{"Volumes": [{"Name": "volume", "Mountpoint": "/var/vcap/data/volumes/volume"}]}`
+
+		Expect(ctl("list")).To(Equal(0))
+		Expect(requests).To(HaveKeyWithValue("POST /VolumeDriver.List", "{}"))
+		Expect(stdout.String()).To(ContainSubstring(`"Name": "volume"`))
+	})
+
+	It("mounts a volume", func() {
+		responses["/VolumeDriver.Mount"] = `{"Mountpoint": "/var/vcap/data/volumes/volume"}`
+
+		Expect(ctl("mount", "volume")).To(Equal(0))
+		Expect(requests["POST /VolumeDriver.Mount"]).To(MatchJSON(`{"Name": "volume"}`))
+		Expect(stdout.String()).To(ContainSubstring(`"Mountpoint": "/var/vcap/data/volumes/volume"`))
+	})
+
+	It("exits with 1 when the driver reports an error", func() {
+		responses["/VolumeDriver.Unmount"] = `{"Err": "Volume 'volume' not found"}`
+
+		Expect(ctl("unmount", "volume")).To(Equal(1))
+		Expect(stdout.String()).To(ContainSubstring(`"Err": "Volume 'volume' not found"`))
+	})
+
+	It("validates opts and exits with 1 when they are invalid", func() {
+		responses["/VolumeDriver.Validate"] = `{"Valid": false, "Diagnostics": [{"Check": "source", "Passed": false, "Message": "source not allowed"}], "Err": ""}`
+
+		Expect(ctl("validate", "volume", `{"source": "server:/export"}`, "test-mount")).To(Equal(1))
+		Expect(requests["POST /VolumeDriver.Validate"]).To(MatchJSON(`{"Name": "volume", "Opts": {"source": "server:/export"}, "TestMount": true}`))
+		Expect(stdout.String()).To(ContainSubstring(`"Message": "source not allowed"`))
+	})
+
+	It("force-removes volumes through the admin address", func() {
+		responses["/Admin.RemoveVolume"] = `{"Err": ""}`
+
+		Expect(ctl("remove", "volume", "force")).To(Equal(0))
+		Expect(requests["POST /Admin.RemoveVolume"]).To(MatchJSON(`{"Name": "volume", "Force": true}`))
+	})
+
+	It("binds volumes through the admin address", func() {
+		responses["/Admin.BindVolume"] = `{"Err": ""}`
+
+		Expect(ctl("bind", "volume", "/containers/a/volume", "readonly")).To(Equal(0))
+		Expect(requests["POST /Admin.BindVolume"]).To(MatchJSON(`{"Name": "volume", "Target": "/containers/a/volume", "ReadOnly": true}`))
+	})
+
+	It("lists the unused volumes through the admin address", func() {
+		responses["/Admin.ListVolumes"] = `{"Volumes": [{"Name": "volume"}], "Usage": {"volume": {"LastUsedAt": "2020-01-01T00:00:00Z"}}}`
+
+		Expect(ctl("unused", "720h")).To(Equal(0))
+		Expect(requests["POST /Admin.ListVolumes"]).To(ContainSubstring(`"UnusedFor":2592000000000000`))
+		Expect(stdout.String()).To(ContainSubstring(`"LastUsedAt": "2020-01-01T00:00:00Z"`))
+		Expect(ctl("unused", "a month")).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring("invalid duration 'a month'"))
+	})
+
+	It("drains through the admin address", func() {
+		responses["/Admin.Drain"] = `{"Err": ""}`
+
+		Expect(ctl("drain")).To(Equal(0))
+		Expect(requests).To(HaveKey("POST /Admin.Drain"))
+	})
+
+	It("lists the in-flight operations through the admin address", func() {
+		responses["/Admin.InFlightOperations"] = `{"Operations": [{"Operation": "mount", "Volume": "volume", "Caller": "some-app", "State": "running", "StartedAt": "2020-01-01T00:00:00Z"}], "Err": ""}`
+
+		Expect(ctl("operations")).To(Equal(0))
+		Expect(requests).To(HaveKey("POST /Admin.InFlightOperations"))
+		Expect(stdout.String()).To(ContainSubstring(`"Caller": "some-app"`))
+	})
+
+	It("imports the output of export", func() {
+		responses["/Admin.ImportState"] = `{"Err": ""}`
+		stdin.WriteString(`{"Volumes": [{"Name": "volume", "Mountpoint": "/mnt/volume", "MountCount": 1, "Opts": {"source": "server:/export"}, "MountDirectory": "volume"}], "Err": ""}`)
+
+		Expect(ctl("import", "-", "overwrite")).To(Equal(0))
+		Expect(requests["POST /Admin.ImportState"]).To(MatchJSON(`{
+			"Volumes": [{"Name": "volume", "Mountpoint": "/mnt/volume", "MountCount": 1, "Opts": {"source": "server:/export"}, "MountDirectory": "volume"}],
+			"Overwrite": true
+		}`))
+	})
+
+	It("dumps the debug state", func() {
+		responses["/debug/state"] = `{"Volumes":[]}`
+
+		Expect(ctl("debug-dump")).To(Equal(0))
+		Expect(requests).To(HaveKey("GET /debug/state"))
+		Expect(stdout.String()).To(Equal("{\n  \"Volumes\": []\n}\n"))
+	})
+
+	It("refuses commands whose address is not set", func() {
+		delete(env, DebugAddrEnv)
+
+		Expect(ctl("debug-dump")).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring("no address given for this command"))
+	})
+
+	It("refuses unknown commands and wrong arguments", func() {
+		Expect(ctl("frobnicate")).To(Equal(2))
+		Expect(stderr.String()).To(ContainSubstring("unknown command 'frobnicate'"))
+
+		Expect(ctl("get")).To(Equal(2))
+		Expect(stderr.String()).To(ContainSubstring("usage: volumedriverctl get NAME"))
+	})
+
+	It("reports http errors", func() {
+		Expect(ctl("get", "volume")).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring("POST /VolumeDriver.Get: 404 Not Found"))
+	})
+
+	Context("when the driver listens on a unix socket", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "volumedriverctl")
+			Expect(err).NotTo(HaveOccurred())
+
+			socket := filepath.Join(dir, "driver.sock")
+			listener, err := net.Listen("unix", socket)
+			Expect(err).NotTo(HaveOccurred())
+
+			server.Close()
+			server = httptest.NewUnstartedServer(server.Config.Handler)
+			server.Listener = listener
+			server.Start()
+
+			env[DriverAddrEnv] = "unix://" + socket
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("talks to the socket", func() {
+			responses["/VolumeDriver.Get"] = `{"Volume": {"Name": "volume"}}`
+
+			Expect(ctl("-driver", env[DriverAddrEnv], "get", "volume")).To(Equal(0))
+			Expect(requests["POST /VolumeDriver.Get"]).To(MatchJSON(`{"Name": "volume"}`))
+		})
+	})
+})
@@ -0,0 +1,138 @@
+package volumedriver
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Opts that every backend interprets the same way. Create parses them into
+// CommonOpts and stores their values in a canonical form: booleans for
+// ReadOnlyOpt, its alias ReadOnlyAliasOpt and AutoCacheOpt, and decimal
+// strings for UIDOpt and GIDOpt. They are passed on to the Mounter, which
+// refuses the ones its backend does not support.
+const (
+	ReadOnlyOpt      = "readonly"
+	ReadOnlyAliasOpt = "ro"
+	UIDOpt           = "uid"
+	GIDOpt           = "gid"
+	// AutoCacheOpt lets fuse backends keep the page cache of files that did
+	// not change on the server since they were last opened.
+	AutoCacheOpt = "auto_cache"
+)
+
+// maxID is the largest uid or gid; (uid_t)-1 means no change to the kernel.
+const maxID = math.MaxUint32 - 1
+
+// CommonOpts are the typed values of the common opts of a volume, with the
+// defaults of the opts that are not set.
+type CommonOpts struct {
+	ReadOnly bool
+	// UID and GID are nil when the opts are not set.
+	UID       *uint32
+	GID       *uint32
+	AutoCache bool
+}
+
+// ParseCommonOpts parses the common opts of opts. Booleans may be given as
+// JSON booleans, as "true" or "false", or as the empty string for true, as
+// in a mount option without a value; ids as numbers or decimal strings.
+func ParseCommonOpts(opts map[string]interface{}) (CommonOpts, error) {
+	var common CommonOpts
+
+	readOnly := map[string]bool{}
+	for _, key := range []string{ReadOnlyOpt, ReadOnlyAliasOpt} {
+		value, ok := opts[key]
+		if !ok {
+			continue
+		}
+		set, err := parseBoolOpt(key, value)
+		if err != nil {
+			return CommonOpts{}, err
+		}
+		readOnly[key] = set
+	}
+	if len(readOnly) == 2 && readOnly[ReadOnlyOpt] != readOnly[ReadOnlyAliasOpt] {
+		return CommonOpts{}, fmt.Errorf("the %s and %s opts contradict each other", ReadOnlyOpt, ReadOnlyAliasOpt)
+	}
+	common.ReadOnly = readOnly[ReadOnlyOpt] || readOnly[ReadOnlyAliasOpt]
+
+	for _, id := range []struct {
+		key   string
+		value **uint32
+	}{{UIDOpt, &common.UID}, {GIDOpt, &common.GID}} {
+		value, ok := opts[id.key]
+		if !ok {
+			continue
+		}
+		parsed, err := parseIDOpt(id.key, value)
+		if err != nil {
+			return CommonOpts{}, err
+		}
+		*id.value = &parsed
+	}
+
+	if value, ok := opts[AutoCacheOpt]; ok {
+		set, err := parseBoolOpt(AutoCacheOpt, value)
+		if err != nil {
+			return CommonOpts{}, err
+		}
+		common.AutoCache = set
+	}
+
+	return common, nil
+}
+
+// normalizeCommonOpts returns a copy of opts with the values of the common
+// opts in their canonical form, so that every Mounter sees the same types.
+func normalizeCommonOpts(opts map[string]interface{}) (map[string]interface{}, error) {
+	common, err := ParseCommonOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	normalized := map[string]interface{}{}
+	for k, v := range opts {
+		normalized[k] = v
+	}
+	for _, key := range []string{ReadOnlyOpt, ReadOnlyAliasOpt} {
+		if _, ok := opts[key]; ok {
+			normalized[key] = common.ReadOnly
+		}
+	}
+	if common.UID != nil {
+		normalized[UIDOpt] = strconv.FormatUint(uint64(*common.UID), 10)
+	}
+	if common.GID != nil {
+		normalized[GIDOpt] = strconv.FormatUint(uint64(*common.GID), 10)
+	}
+	if _, ok := opts[AutoCacheOpt]; ok {
+		normalized[AutoCacheOpt] = common.AutoCache
+	}
+
+	return normalized, nil
+}
+
+func parseBoolOpt(key string, value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		switch strings.ToLower(v) {
+		case "true", "":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("invalid %s '%v', must be true or false", key, value)
+}
+
+func parseIDOpt(key string, value interface{}) (uint32, error) {
+	id, err := parseLimit(value)
+	if err != nil || id > maxID {
+		return 0, fmt.Errorf("invalid %s '%v', must be a number from 0 to %d", key, value, uint64(maxID))
+	}
+	return uint32(id), nil
+}
@@ -0,0 +1,182 @@
+package volumedriver
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+)
+
+// parseCompositeOpt returns the sources of a composite volume by the
+// directory below the mountpoint they are mounted to, or nil for other
+// volumes. The opt is either an object or a dir=source,... string.
+func parseCompositeOpt(opts map[string]interface{}) (map[string]string, error) {
+	value, ok := opts[CompositeOpt]
+	if !ok {
+		return nil, nil
+	}
+
+	members := map[string]string{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for dir, source := range v {
+			members[dir], _ = source.(string)
+		}
+	case string:
+		for _, pair := range strings.Split(v, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("invalid composite '%s', must be of the form dir=source,...", v)
+			}
+			members[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	default:
+		return nil, fmt.Errorf("invalid composite '%v', must be an object or dir=source,...", value)
+	}
+
+	if len(members) == 0 {
+		return nil, fmt.Errorf("invalid composite '%v', must list at least one source", value)
+	}
+	for dir, source := range members {
+		cleaned := path.Clean("/" + dir)
+		if dir == "" || cleaned == "/" || cleaned != "/"+strings.Trim(dir, "/") {
+			return nil, fmt.Errorf("invalid composite directory '%s', must be a relative path below the mountpoint", dir)
+		}
+		if source == "" {
+			return nil, fmt.Errorf("invalid composite source for directory '%s', must not be empty", dir)
+		}
+	}
+
+	if _, ok := opts[SubdirOpt]; ok {
+		return nil, fmt.Errorf("the %s opt cannot be combined with the %s opt", CompositeOpt, SubdirOpt)
+	}
+
+	normalized := map[string]string{}
+	for dir, source := range members {
+		cleaned := strings.Trim(path.Clean("/"+dir), "/")
+		if _, duplicate := normalized[cleaned]; duplicate {
+			return nil, fmt.Errorf("invalid composite '%v', directory '%s' is listed twice", value, cleaned)
+		}
+		normalized[cleaned] = source
+	}
+	return normalized, nil
+}
+
+// checkCompositeSourcesAllowed applies the source policy to the sources of
+// a composite volume, which have already been validated.
+func (d *VolumeDriver) checkCompositeSourcesAllowed(opts map[string]interface{}) error {
+	members, _ := parseCompositeOpt(opts)
+	for _, dir := range compositeDirs(members) {
+		if err := d.checkSourceAllowed(members[dir]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compositeDirs returns the directories of members in the order they are
+// mounted, parents before the directories below them.
+func compositeDirs(members map[string]string) []string {
+	dirs := []string{}
+	for dir := range members {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// compositeMounter mounts the source of a volume at its mountpoint, and
+// the sources of its members into directories below it, all with the
+// Mounter and the opts of the volume. The directories are created on the
+// source when they are missing. Apps get one volume for exports that they
+// would otherwise need several bindings for.
+type compositeMounter struct {
+	driver  *VolumeDriver
+	mounter Mounter
+	members map[string]string
+}
+
+func (m *compositeMounter) Mount(env dockerdriver.Env, source string, target string, opts map[string]interface{}) error {
+	logger := env.Logger().Session("composite-mount", lager.Data{"source": source, "target": target, "members": m.members})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	if err := m.mounter.Mount(env, source, target, opts); err != nil {
+		logger.Error("mount-failed", err)
+		return err
+	}
+
+	mounted := []string{target}
+	for _, dir := range compositeDirs(m.members) {
+		memberTarget := filepath.Join(target, filepath.FromSlash(dir))
+
+		err := m.driver.os.MkdirAll(memberTarget, os.ModePerm)
+		if err == nil {
+			err = m.mounter.Mount(env, m.members[dir], memberTarget, opts)
+		}
+		if err != nil {
+			logger.Error("mount-member-failed", err, lager.Data{"dir": dir})
+			m.release(env, mounted)
+			return memberError(dir, err)
+		}
+		mounted = append(mounted, memberTarget)
+	}
+
+	return nil
+}
+
+// Unmount unmounts the members before the mount they are below.
+func (m *compositeMounter) Unmount(env dockerdriver.Env, target string) error {
+	dirs := compositeDirs(m.members)
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := m.mounter.Unmount(env, filepath.Join(target, filepath.FromSlash(dirs[i]))); err != nil {
+			return err
+		}
+	}
+	return m.mounter.Unmount(env, target)
+}
+
+func (m *compositeMounter) Check(env dockerdriver.Env, name, mountPoint string, depth CheckDepth) bool {
+	if !m.mounter.Check(env, name, mountPoint, depth) {
+		return false
+	}
+	for _, dir := range compositeDirs(m.members) {
+		if !m.mounter.Check(env, name, filepath.Join(mountPoint, filepath.FromSlash(dir)), depth) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *compositeMounter) Purge(env dockerdriver.Env, path string) {
+	m.mounter.Purge(env, path)
+}
+
+// memberError names the directory of the member that failed to mount,
+// keeping the code of safe errors.
+func memberError(dir string, err error) error {
+	if safe, ok := safeerrors.From(err); ok {
+		return safeerrors.Wrap(err, safe.Code, "composite directory '%s': %s", dir, safe.SafeDescription)
+	}
+	return fmt.Errorf("composite directory '%s': %w", dir, err)
+}
+
+// release unmounts targets in reverse, after a member failed to mount.
+func (m *compositeMounter) release(env dockerdriver.Env, targets []string) {
+	logger := env.Logger().Session("release-composite")
+
+	for i := len(targets) - 1; i >= 0; i-- {
+		if err := m.mounter.Unmount(env, targets[i]); err != nil {
+			logger.Error("unmount-failed", err, lager.Data{"target": targets[i]})
+		}
+	}
+}
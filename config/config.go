@@ -0,0 +1,525 @@
+// Package config loads the settings of a driver process from a JSON file,
+// environment variables and command line flags, in increasing order of
+// precedence, and turns them into volumedriver.Options.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/admission"
+	"code.cloudfoundry.org/volumedriver/invoker"
+	"code.cloudfoundry.org/volumedriver/logformat"
+	"code.cloudfoundry.org/volumedriver/pluginspec"
+)
+
+// EnvPrefix is the prefix of the environment variables that override the
+// file, such as VOLUMEDRIVER_DRAIN_TIMEOUT for the drain-timeout flag.
+const EnvPrefix = "VOLUMEDRIVER_"
+
+// ConfigEnv names the config file when the config flag is not given.
+const ConfigEnv = EnvPrefix + "CONFIG"
+
+// DefaultName is the plugin name of a driver whose configuration does not
+// set one.
+const DefaultName = "volumedriver"
+
+// Duration is a time.Duration written as "30s" or "2m" in the file.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("invalid duration %s, expected a string such as \"30s\"", data)
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// Listener is where the driver serves the docker volume plugin API.
+type Listener struct {
+	// Network is tcp or unix.
+	Network string
+	Address string
+	// AdminAddress serves the admin endpoints when it is set.
+	AdminAddress string
+	// SpecDir is where WriteSpec publishes the driver to docker. No spec
+	// file is written when it is empty.
+	SpecDir string
+	// TLS is how docker connects to a tcp listener that serves TLS. It is
+	// written to the spec file.
+	TLS *pluginspec.TLSConfig `json:",omitempty"`
+}
+
+// SourceDefaults are the default opts of volumes whose source host matches
+// Pattern, with the opts written as a template such as "vers=4.1,timeo=600".
+type SourceDefaults struct {
+	Pattern string
+	Opts    string
+}
+
+type Config struct {
+	// Name is the plugin name the driver is registered with. Instances on
+	// one host need distinct names.
+	Name string
+
+	Listen Listener
+
+	MountPathRoot  string
+	MountPathRoots []string
+	RootPolicy     string
+
+	// Backend names the default mounter. The driver process constructs
+	// the mounter, the config only selects it.
+	Backend string
+	// Rootless refuses the volumes and opts that need the driver to run as
+	// root, see volumedriver.Options.Rootless.
+	Rootless bool
+
+	LogLevel   string
+	CheckDepth string
+
+	// LogFormat is json or human, see package logformat. CommandLog is a
+	// file that takes the logs and output of the commands the driver runs,
+	// such as mount, out of the driver log.
+	LogFormat  string
+	CommandLog string
+
+	// DefaultOpts are merged under the opts of every volume, before the
+	// SourceDefaults of its source.
+	DefaultOpts    string
+	SourceDefaults []SourceDefaults
+	AllowedSources []volumedriver.SourceRule
+
+	// HardeningExemptions are the hardening opts, nosuid, nodev or noexec,
+	// that are not forced onto every mount.
+	HardeningExemptions []string
+
+	// HelperBinaries are the absolute paths of the helper binaries by the
+	// name the mounters invoke them with, such as mount or umount.
+	// HelperPath replaces the PATH the other helpers are looked up in.
+	HelperBinaries map[string]string `json:",omitempty"`
+	HelperPath     string
+
+	MountErrorTTL          Duration
+	MaxMountErrorTTL       Duration
+	SlowMountThreshold     Duration
+	CriticalMountThreshold Duration
+	MountHeartbeatInterval Duration
+	DrainTimeout           Duration
+	SkipPurge              bool
+	LockRoot               bool
+	RemountOnStart         bool
+	ExpiryInterval         Duration
+	SnapshotInterval       Duration
+	SnapshotRetention      int
+	OrphanInterval         Duration
+	MountStatsInterval     Duration
+	PersistDebounce        Duration
+	PersistRetryInterval   Duration
+
+	GlobalRateLimit admission.RateLimit
+	VolumeRateLimit admission.RateLimit
+
+	// MaxInFlight bounds the requests that the handler of LimitConcurrency
+	// serves at once; 0 disables the limit.
+	MaxInFlight int
+
+	MaxPendingMounts          int
+	MaxPendingMountsPerServer int
+	BusyRetryAfter            Duration
+
+	// StatsdAddress is the host:port of the statsd agent that receives the
+	// metrics of the driver, which are dropped when it is empty. StatsdPrefix
+	// is prepended to the metric names.
+	StatsdAddress string
+	StatsdPrefix  string
+
+	Quotas      volumedriver.Quotas
+	StateLimits volumedriver.StateLimits
+}
+
+// Default returns the configuration used for settings that are neither in
+// the file, the environment nor the flags.
+func Default() Config {
+	options := volumedriver.DefaultOptions()
+
+	return Config{
+		Name:                   DefaultName,
+		Listen:                 Listener{Network: "tcp", Address: "127.0.0.1:7589", SpecDir: pluginspec.Dir},
+		RootPolicy:             string(volumedriver.RootMostFree),
+		LogLevel:               "info",
+		LogFormat:              logformat.JSON,
+		CheckDepth:             string(options.CheckDepth),
+		MountErrorTTL:          Duration(options.MountErrorTTL),
+		MaxMountErrorTTL:       Duration(options.MaxMountErrorTTL),
+		SlowMountThreshold:     Duration(options.SlowMountThreshold),
+		CriticalMountThreshold: Duration(options.CriticalMountThreshold),
+		MountHeartbeatInterval: Duration(options.MountHeartbeatInterval),
+		DrainTimeout:           Duration(options.DrainTimeout),
+		BusyRetryAfter:         Duration(options.BusyRetryAfter),
+	}
+}
+
+// file is the layout of the config file. Instances are decoded over the
+// rest of the file by LoadInstances.
+type file struct {
+	Config
+	Instances []json.RawMessage
+}
+
+// Load returns the defaults overridden by the config file, the environment
+// and the flags. The file is named by the config flag or ConfigEnv, and is
+// optional. flags can be nil, lookupEnv is usually os.LookupEnv. Files that
+// define Instances are loaded with LoadInstances.
+func Load(flags *Flags, lookupEnv func(string) (string, bool)) (Config, error) {
+	config, instances, err := load(flags, lookupEnv)
+	if err != nil {
+		return Config{}, err
+	}
+	if len(instances) > 0 {
+		return Config{}, fmt.Errorf("the config file defines instances, load them with LoadInstances")
+	}
+
+	if err := config.Validate(); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// LoadInstances returns one configuration per entry of the Instances of the
+// config file, so that several named drivers, for example with a new
+// backend next to the old one, run on one host. Each instance is the
+// configuration Load would return, overridden by the settings of its entry.
+// Instances must not share their name, mount path roots or addresses. A file
+// without Instances yields the single configuration of Load.
+func LoadInstances(flags *Flags, lookupEnv func(string) (string, bool)) ([]Config, error) {
+	base, instances, err := load(flags, lookupEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(instances) == 0 {
+		if err := base.Validate(); err != nil {
+			return nil, err
+		}
+		return []Config{base}, nil
+	}
+
+	configs := []Config{}
+	for i, data := range instances {
+		instance, err := base.withInstance(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid instance %d: %s", i, err)
+		}
+		if err := instance.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid instance '%s': %s", instance.Name, err)
+		}
+		configs = append(configs, instance)
+	}
+
+	if err := validateInstances(configs); err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
+
+// LoadInstance returns the configuration of the named instance, for
+// reloading it with Reload.
+func LoadInstance(flags *Flags, lookupEnv func(string) (string, bool), name string) (Config, error) {
+	configs, err := LoadInstances(flags, lookupEnv)
+	if err != nil {
+		return Config{}, err
+	}
+	for _, config := range configs {
+		if config.Name == name {
+			return config, nil
+		}
+	}
+	return Config{}, fmt.Errorf("no instance named '%s'", name)
+}
+
+func load(flags *Flags, lookupEnv func(string) (string, bool)) (Config, []json.RawMessage, error) {
+	config := Default()
+	if flags == nil {
+		flags = &Flags{}
+	}
+
+	path := flags.path
+	if path == "" {
+		path, _ = lookupEnv(ConfigEnv)
+	}
+
+	var instances []json.RawMessage
+	if path != "" {
+		var err error
+		if instances, err = config.loadFile(path); err != nil {
+			return Config{}, nil, err
+		}
+	}
+
+	if err := config.applyEnv(lookupEnv); err != nil {
+		return Config{}, nil, err
+	}
+	if err := flags.apply(&config); err != nil {
+		return Config{}, nil, err
+	}
+
+	return config, instances, nil
+}
+
+func (c *Config) loadFile(path string) ([]json.RawMessage, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %s", err)
+	}
+
+	contents := file{Config: *c}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&contents); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %s", path, err)
+	}
+
+	*c = contents.Config
+	return contents.Instances, nil
+}
+
+// withInstance returns a copy of c with the settings of an instance entry
+// applied. c is copied through JSON so that the instance does not share
+// slices with it.
+func (c Config) withInstance(data json.RawMessage) (Config, error) {
+	encoded, err := json.Marshal(c)
+	if err != nil {
+		return Config{}, err
+	}
+
+	instance := Config{}
+	if err := json.Unmarshal(encoded, &instance); err != nil {
+		return Config{}, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&instance); err != nil {
+		return Config{}, err
+	}
+	return instance, nil
+}
+
+// validateInstances checks that the instances do not share what one
+// instance must own.
+func validateInstances(configs []Config) error {
+	owners := map[string]string{}
+	claim := func(kind, value, name string) error {
+		if value == "" {
+			return nil
+		}
+		key := kind + " " + value
+		if owner, ok := owners[key]; ok {
+			return fmt.Errorf("instances '%s' and '%s' share the %s '%s'", owner, name, kind, value)
+		}
+		owners[key] = name
+		return nil
+	}
+
+	for _, config := range configs {
+		if err := claim("name", config.Name, config.Name); err != nil {
+			return err
+		}
+		for _, root := range append([]string{config.MountPathRoot}, config.MountPathRoots...) {
+			if err := claim("mount path root", filepath.Clean(root), config.Name); err != nil {
+				return err
+			}
+		}
+		if err := claim("listen address", config.Listen.Address, config.Name); err != nil {
+			return err
+		}
+		if err := claim("admin address", config.Listen.AdminAddress, config.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Config) applyEnv(lookupEnv func(string) (string, bool)) error {
+	for _, setting := range settings {
+		value, ok := lookupEnv(setting.env())
+		if !ok {
+			continue
+		}
+		if err := setting.set(c, value); err != nil {
+			return fmt.Errorf("invalid %s: %s", setting.env(), err)
+		}
+	}
+	return nil
+}
+
+// pluginName matches the names docker accepts for plugins.
+var pluginName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Validate checks the settings that Load cannot check while parsing.
+func (c Config) Validate() error {
+	if !pluginName.MatchString(c.Name) {
+		return fmt.Errorf("invalid name '%s', must start with a letter or digit and contain only letters, digits, '.', '_' and '-'", c.Name)
+	}
+	if c.MountPathRoot == "" {
+		return fmt.Errorf("the mount path root must be set")
+	}
+	if c.Listen.Network != "tcp" && c.Listen.Network != "unix" {
+		return fmt.Errorf("invalid listen network '%s', must be tcp or unix", c.Listen.Network)
+	}
+	if c.Listen.Address == "" {
+		return fmt.Errorf("the listen address must be set")
+	}
+	if c.Listen.TLS != nil && c.Listen.Network != "tcp" {
+		return fmt.Errorf("tls needs a tcp listen network")
+	}
+	if _, err := lager.LogLevelFromString(c.LogLevel); err != nil {
+		return err
+	}
+	if err := logformat.Validate(c.LogFormat); err != nil {
+		return err
+	}
+	if c.StatsdAddress != "" {
+		if _, _, err := net.SplitHostPort(c.StatsdAddress); err != nil {
+			return fmt.Errorf("invalid statsd address '%s': %s", c.StatsdAddress, err)
+		}
+	}
+	if c.CriticalMountThreshold > 0 && c.SlowMountThreshold > c.CriticalMountThreshold {
+		return fmt.Errorf("the slow mount threshold must not exceed the critical mount threshold")
+	}
+	if err := c.HelperPaths().Validate(); err != nil {
+		return err
+	}
+
+	_, err := c.Options()
+	return err
+}
+
+// HelperPaths returns where the supervised invoker finds the helper
+// binaries.
+func (c Config) HelperPaths() invoker.Paths {
+	return invoker.Paths{Binaries: c.HelperBinaries, Path: c.HelperPath}
+}
+
+// WriteSpec publishes the driver to docker with a spec file for its name and
+// listener in Listen.SpecDir. Remove the spec when the driver shuts down.
+// It returns a nil spec when SpecDir is empty.
+func (c Config) WriteSpec() (*pluginspec.Spec, error) {
+	if c.Listen.SpecDir == "" {
+		return nil, nil
+	}
+	return pluginspec.Write(c.Listen.SpecDir, c.Name, c.Listen.Network, c.Listen.Address, c.Listen.TLS)
+}
+
+// LimitConcurrency wraps handler, such as the one of driverhttp.NewHandler,
+// so that it refuses requests as busy while MaxInFlight are being served.
+func (c Config) LimitConcurrency(logger lager.Logger, handler http.Handler) http.Handler {
+	return admission.NewConcurrencyLimitHandler(logger, handler, c.MaxInFlight)
+}
+
+// Options returns the driver options of the configuration. Options that
+// need objects, such as the mounters, are left for the caller to set.
+func (c Config) Options() (volumedriver.Options, error) {
+	checkDepth, err := volumedriver.ParseCheckDepth(c.CheckDepth)
+	if err != nil {
+		return volumedriver.Options{}, err
+	}
+
+	rootPolicy, err := volumedriver.ParseRootPolicy(c.RootPolicy)
+	if err != nil {
+		return volumedriver.Options{}, err
+	}
+
+	if err := volumedriver.ValidateAllowedSources(c.AllowedSources); err != nil {
+		return volumedriver.Options{}, err
+	}
+
+	if err := volumedriver.ValidateHardeningExemptions(c.HardeningExemptions); err != nil {
+		return volumedriver.Options{}, err
+	}
+
+	sourceDefaults, err := c.sourceDefaults()
+	if err != nil {
+		return volumedriver.Options{}, err
+	}
+
+	options := volumedriver.DefaultOptions()
+	options.MountErrorTTL = time.Duration(c.MountErrorTTL)
+	options.MaxMountErrorTTL = time.Duration(c.MaxMountErrorTTL)
+	options.GlobalRateLimit = c.GlobalRateLimit
+	options.VolumeRateLimit = c.VolumeRateLimit
+	options.MaxPendingMounts = c.MaxPendingMounts
+	options.MaxPendingMountsPerServer = c.MaxPendingMountsPerServer
+	options.BusyRetryAfter = time.Duration(c.BusyRetryAfter)
+	options.StatsdAddress = c.StatsdAddress
+	options.StatsdPrefix = c.StatsdPrefix
+	options.SlowMountThreshold = time.Duration(c.SlowMountThreshold)
+	options.CriticalMountThreshold = time.Duration(c.CriticalMountThreshold)
+	options.MountHeartbeatInterval = time.Duration(c.MountHeartbeatInterval)
+	options.CheckDepth = checkDepth
+	options.SourceDefaults = sourceDefaults
+	options.AllowedSources = c.AllowedSources
+	options.HardeningExemptions = c.HardeningExemptions
+	options.Quotas = c.Quotas
+	options.StateLimits = c.StateLimits
+	options.DrainTimeout = time.Duration(c.DrainTimeout)
+	options.Rootless = c.Rootless
+	options.SkipPurge = c.SkipPurge
+	options.LockRoot = c.LockRoot
+	options.RemountOnStart = c.RemountOnStart
+	options.ExpiryInterval = time.Duration(c.ExpiryInterval)
+	options.SnapshotInterval = time.Duration(c.SnapshotInterval)
+	options.SnapshotRetention = c.SnapshotRetention
+	options.OrphanInterval = time.Duration(c.OrphanInterval)
+	options.MountStatsInterval = time.Duration(c.MountStatsInterval)
+	options.PersistDebounce = time.Duration(c.PersistDebounce)
+	options.PersistRetryInterval = time.Duration(c.PersistRetryInterval)
+	options.MountPathRoots = c.MountPathRoots
+	options.RootPolicy = rootPolicy
+
+	return options, nil
+}
+
+func (c Config) sourceDefaults() ([]volumedriver.SourceDefaults, error) {
+	defaults := []volumedriver.SourceDefaults{}
+
+	if c.DefaultOpts != "" {
+		opts, err := volumedriver.ParseOptsTemplate(c.DefaultOpts)
+		if err != nil {
+			return nil, err
+		}
+		defaults = append(defaults, volumedriver.SourceDefaults{Pattern: "*", Opts: opts})
+	}
+
+	for _, sourceDefaults := range c.SourceDefaults {
+		opts, err := volumedriver.ParseOptsTemplate(sourceDefaults.Opts)
+		if err != nil {
+			return nil, err
+		}
+		defaults = append(defaults, volumedriver.SourceDefaults{Pattern: sourceDefaults.Pattern, Opts: opts})
+	}
+
+	if err := volumedriver.ValidateSourceDefaults(defaults); err != nil {
+		return nil, err
+	}
+	return defaults, nil
+}
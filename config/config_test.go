@@ -0,0 +1,345 @@
+package config_test
+
+import (
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/config"
+	"code.cloudfoundry.org/volumedriver/invoker"
+	"code.cloudfoundry.org/volumedriver/pluginspec"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Config", func() {
+	var (
+		dir        string
+		configFile string
+		env        map[string]string
+		fs         *flag.FlagSet
+		flags      *config.Flags
+		args       []string
+		cfg        config.Config
+		err        error
+	)
+
+	lookupEnv := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	BeforeEach(func() {
+		dir, err = ioutil.TempDir("", "config")
+		Expect(err).NotTo(HaveOccurred())
+
+		configFile = filepath.Join(dir, "config.json")
+		Expect(ioutil.WriteFile(configFile, []byte(`{
+			"Listen": {"Network": "unix", "Address": "/var/vcap/sys/run/driver.sock"},
+			"MountPathRoot": "/var/vcap/data/volumes",
+			"MountPathRoots": ["/var/vcap/store/volumes"],
+			"Backend": "fuse-nfs",
+			"DefaultOpts": "vers=4.1",
+			"SourceDefaults": [{"Pattern": "*.example.com", "Opts": "timeo=600,nosuid"}],
+			"AllowedSources": [{"Host": "10.0.0.0/8"}],
+			"DrainTimeout": "5m",
+			"Quotas": {"MaxVolumes": 10}
+		}`), 0600)).To(Succeed())
+
+		env = map[string]string{}
+		fs = flag.NewFlagSet("driver", flag.ContinueOnError)
+		flags = config.RegisterFlags(fs)
+		args = []string{"-config", configFile}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	JustBeforeEach(func() {
+		Expect(fs.Parse(args)).To(Succeed())
+		cfg, err = config.Load(flags, lookupEnv)
+	})
+
+	It("loads the file over the defaults", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.Listen).To(Equal(config.Listener{Network: "unix", Address: "/var/vcap/sys/run/driver.sock", SpecDir: pluginspec.Dir}))
+		Expect(cfg.MountPathRoot).To(Equal("/var/vcap/data/volumes"))
+		Expect(cfg.Backend).To(Equal("fuse-nfs"))
+		Expect(cfg.DrainTimeout).To(Equal(config.Duration(5 * time.Minute)))
+		Expect(cfg.MountErrorTTL).To(Equal(config.Duration(volumedriver.DefaultMountErrorTTL)))
+		Expect(cfg.LogLevel).To(Equal("info"))
+	})
+
+	It("converts the config to driver options", func() {
+		options, err := cfg.Options()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(options.DrainTimeout).To(Equal(5 * time.Minute))
+		Expect(options.MountPathRoots).To(Equal([]string{"/var/vcap/store/volumes"}))
+		Expect(options.RootPolicy).To(Equal(volumedriver.RootMostFree))
+		Expect(options.Quotas.MaxVolumes).To(Equal(10))
+		Expect(options.AllowedSources).To(Equal([]volumedriver.SourceRule{{Host: "10.0.0.0/8"}}))
+		Expect(options.SourceDefaults).To(Equal([]volumedriver.SourceDefaults{
+			{Pattern: "*", Opts: map[string]interface{}{"vers": "4.1"}},
+			{Pattern: "*.example.com", Opts: map[string]interface{}{"timeo": "600", "nosuid": true}},
+		}))
+	})
+
+	Context("when the environment overrides the file", func() {
+		BeforeEach(func() {
+			env["VOLUMEDRIVER_DRAIN_TIMEOUT"] = "30s"
+			env["VOLUMEDRIVER_MOUNT_PATH_ROOTS"] = "/a, /b"
+		})
+
+		It("uses the environment", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.DrainTimeout).To(Equal(config.Duration(30 * time.Second)))
+			Expect(cfg.MountPathRoots).To(Equal([]string{"/a", "/b"}))
+		})
+
+		Context("and the flags override the environment", func() {
+			BeforeEach(func() {
+				args = append([]string{"-drain-timeout", "1m"}, args...)
+			})
+
+			It("uses the flags", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cfg.DrainTimeout).To(Equal(config.Duration(time.Minute)))
+			})
+		})
+	})
+
+	Context("when the mount path root is locked", func() {
+		BeforeEach(func() {
+			args = append([]string{"-lock-root"}, args...)
+		})
+
+		It("sets the option", func() {
+			Expect(err).NotTo(HaveOccurred())
+			options, err := cfg.Options()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(options.LockRoot).To(BeTrue())
+		})
+	})
+
+	Context("when purging is skipped", func() {
+		BeforeEach(func() {
+			args = append([]string{"-skip-purge"}, args...)
+		})
+
+		It("accepts the flag without a value", func() {
+			Expect(err).NotTo(HaveOccurred())
+			options, err := cfg.Options()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(options.SkipPurge).To(BeTrue())
+		})
+
+		Context("and the environment has an invalid value", func() {
+			BeforeEach(func() {
+				args = []string{"-config", configFile}
+				env["VOLUMEDRIVER_SKIP_PURGE"] = "sometimes"
+			})
+
+			It("fails", func() {
+				Expect(err).To(MatchError(ContainSubstring("invalid VOLUMEDRIVER_SKIP_PURGE")))
+			})
+		})
+	})
+
+	Context("when the file is named in the environment", func() {
+		BeforeEach(func() {
+			env[config.ConfigEnv] = configFile
+			args = nil
+		})
+
+		It("loads it", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.MountPathRoot).To(Equal("/var/vcap/data/volumes"))
+		})
+	})
+
+	Context("without a file", func() {
+		BeforeEach(func() {
+			args = []string{"-mount-path-root", "/mounts"}
+		})
+
+		It("uses the defaults", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.Listen.Network).To(Equal("tcp"))
+			Expect(cfg.MountPathRoot).To(Equal("/mounts"))
+		})
+	})
+
+	Context("when the file has an unknown setting", func() {
+		BeforeEach(func() {
+			Expect(ioutil.WriteFile(configFile, []byte(`{"MountPathRoot": "/mounts", "DrainTimout": "5m"}`), 0600)).To(Succeed())
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring(`unknown field "DrainTimout"`)))
+		})
+	})
+
+	Context("when a duration is not valid", func() {
+		BeforeEach(func() {
+			env["VOLUMEDRIVER_ORPHAN_INTERVAL"] = "often"
+		})
+
+		It("returns an error naming the variable", func() {
+			Expect(err).To(MatchError(ContainSubstring("invalid VOLUMEDRIVER_ORPHAN_INTERVAL")))
+		})
+	})
+
+	Context("when a setting is not valid", func() {
+		BeforeEach(func() {
+			args = append([]string{"-root-policy", "random"}, args...)
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring("invalid root policy 'random'")))
+		})
+	})
+
+	Context("when metrics are sent to statsd", func() {
+		BeforeEach(func() {
+			env["VOLUMEDRIVER_STATSD_ADDRESS"] = "127.0.0.1:8125"
+			args = append([]string{"-statsd-prefix", "cell.volumedriver"}, args...)
+		})
+
+		It("passes the agent to the driver", func() {
+			Expect(err).NotTo(HaveOccurred())
+			options, err := cfg.Options()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(options.StatsdAddress).To(Equal("127.0.0.1:8125"))
+			Expect(options.StatsdPrefix).To(Equal("cell.volumedriver"))
+		})
+
+		Context("and the address has no port", func() {
+			BeforeEach(func() {
+				env["VOLUMEDRIVER_STATSD_ADDRESS"] = "localhost"
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(MatchError(ContainSubstring("invalid statsd address 'localhost'")))
+			})
+		})
+	})
+
+	Context("when hardening opts are exempted", func() {
+		BeforeEach(func() {
+			args = append([]string{"-hardening-exemptions", "noexec, nodev"}, args...)
+		})
+
+		It("passes them to the driver", func() {
+			Expect(err).NotTo(HaveOccurred())
+			options, err := cfg.Options()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(options.HardeningExemptions).To(Equal([]string{"noexec", "nodev"}))
+		})
+	})
+
+	Context("when an unknown hardening opt is exempted", func() {
+		BeforeEach(func() {
+			args = append([]string{"-hardening-exemptions", "suid"}, args...)
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring("invalid hardening exemption 'suid'")))
+		})
+	})
+
+	Context("when the requests in flight are limited", func() {
+		BeforeEach(func() {
+			args = append([]string{"-max-in-flight", "1"}, args...)
+		})
+
+		It("refuses the requests over the limit as busy", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.MaxInFlight).To(Equal(1))
+
+			var handler http.Handler
+			nested := httptest.NewRecorder()
+			handler = cfg.LimitConcurrency(lagertest.NewTestLogger("config"), http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if req.URL.Path == "/outer" {
+					handler.ServeHTTP(nested, httptest.NewRequest("POST", "/inner", nil))
+				}
+			}))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/outer", nil))
+
+			Expect(nested.Body.String()).To(ContainSubstring(`\"Code\":\"busy\"`))
+		})
+	})
+
+	Context("when helper binaries are configured", func() {
+		BeforeEach(func() {
+			args = append([]string{"-helper-binaries", "mount=/sbin/mount, umount=/sbin/umount", "-helper-path", "/sbin:/usr/sbin"}, args...)
+		})
+
+		It("returns the paths of the supervised invoker", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.HelperPaths()).To(Equal(invoker.Paths{
+				Binaries: map[string]string{"mount": "/sbin/mount", "umount": "/sbin/umount"},
+				Path:     "/sbin:/usr/sbin",
+			}))
+		})
+	})
+
+	Context("when a helper binary is not an absolute path", func() {
+		BeforeEach(func() {
+			args = append([]string{"-helper-binaries", "mount=sbin/mount"}, args...)
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring("invalid helper binary 'mount=sbin/mount'")))
+		})
+	})
+
+	Context("when a spec dir is set", func() {
+		BeforeEach(func() {
+			args = append([]string{"-name", "nfsdriver", "-spec-dir", filepath.Join(dir, "plugins")}, args...)
+		})
+
+		It("writes the spec file of the listener", func() {
+			Expect(err).NotTo(HaveOccurred())
+			spec, err := cfg.WriteSpec()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.Path()).To(Equal(filepath.Join(dir, "plugins", "nfsdriver.spec")))
+
+			contents, err := ioutil.ReadFile(spec.Path())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("unix:///var/vcap/sys/run/driver.sock\n"))
+
+			Expect(spec.Remove()).To(Succeed())
+			Expect(spec.Path()).NotTo(BeAnExistingFile())
+		})
+	})
+
+	Context("when the spec dir is empty", func() {
+		BeforeEach(func() {
+			args = append([]string{"-spec-dir", ""}, args...)
+		})
+
+		It("writes no spec file", func() {
+			Expect(err).NotTo(HaveOccurred())
+			spec, err := cfg.WriteSpec()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec).To(BeNil())
+		})
+	})
+
+	Context("when the mount path root is missing", func() {
+		BeforeEach(func() {
+			args = nil
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError("the mount path root must be set"))
+		})
+	})
+})
@@ -0,0 +1,207 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting is a scalar of Config that can be overridden by a flag and an
+// environment variable.
+type setting struct {
+	name  string
+	usage string
+	set   func(c *Config, value string) error
+	// boolean settings can be given as a flag without a value
+	boolean bool
+}
+
+func (s setting) env() string {
+	return EnvPrefix + strings.ToUpper(strings.Replace(s.name, "-", "_", -1))
+}
+
+var settings = []setting{
+	stringSetting("name", "plugin name of the driver", func(c *Config) *string { return &c.Name }),
+	stringSetting("listen-network", "network of the listen address, tcp or unix", func(c *Config) *string { return &c.Listen.Network }),
+	stringSetting("listen-address", "address the driver listens on", func(c *Config) *string { return &c.Listen.Address }),
+	stringSetting("admin-address", "address of the admin endpoints", func(c *Config) *string { return &c.Listen.AdminAddress }),
+	stringSetting("spec-dir", "directory of the docker plugin spec file, empty to write none", func(c *Config) *string { return &c.Listen.SpecDir }),
+	stringSetting("mount-path-root", "directory below which volumes are mounted", func(c *Config) *string { return &c.MountPathRoot }),
+	{
+		name:  "mount-path-roots",
+		usage: "comma separated further mount path roots",
+		set: func(c *Config, value string) error {
+			c.MountPathRoots = nil
+			for _, root := range strings.Split(value, ",") {
+				if root = strings.TrimSpace(root); root != "" {
+					c.MountPathRoots = append(c.MountPathRoots, root)
+				}
+			}
+			return nil
+		},
+	},
+	stringSetting("root-policy", "how volumes are placed on the mount path roots, most-free or fewest-volumes", func(c *Config) *string { return &c.RootPolicy }),
+	stringSetting("backend", "name of the default mounter", func(c *Config) *string { return &c.Backend }),
+	stringSetting("log-level", "minimum log level", func(c *Config) *string { return &c.LogLevel }),
+	stringSetting("log-format", "log format, json or human", func(c *Config) *string { return &c.LogFormat }),
+	stringSetting("command-log", "file for the logs and output of mount commands, instead of the driver log", func(c *Config) *string { return &c.CommandLog }),
+	stringSetting("check-depth", "default health check depth, stat, read or write", func(c *Config) *string { return &c.CheckDepth }),
+	stringSetting("default-opts", "default opts of every volume, such as vers=4.1,timeo=600", func(c *Config) *string { return &c.DefaultOpts }),
+	{
+		name:  "hardening-exemptions",
+		usage: "comma separated hardening opts, nosuid, nodev or noexec, that are not forced onto every mount",
+		set: func(c *Config, value string) error {
+			c.HardeningExemptions = nil
+			for _, opt := range strings.Split(value, ",") {
+				if opt = strings.TrimSpace(opt); opt != "" {
+					c.HardeningExemptions = append(c.HardeningExemptions, opt)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		name:  "helper-binaries",
+		usage: "comma separated absolute paths of helper binaries, such as mount=/sbin/mount",
+		set: func(c *Config, value string) error {
+			c.HelperBinaries = map[string]string{}
+			for _, pair := range strings.Split(value, ",") {
+				if pair = strings.TrimSpace(pair); pair == "" {
+					continue
+				}
+				kv := strings.SplitN(pair, "=", 2)
+				if len(kv) != 2 {
+					return fmt.Errorf("invalid helper binary '%s', must be of the form name=path", pair)
+				}
+				c.HelperBinaries[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+			}
+			return nil
+		},
+	},
+	stringSetting("helper-path", "PATH of the helper binaries, defaults to the PATH of the driver", func(c *Config) *string { return &c.HelperPath }),
+	durationSetting("mount-error-ttl", "how long mount failures are remembered", func(c *Config) *Duration { return &c.MountErrorTTL }),
+	durationSetting("max-mount-error-ttl", "how long repeated mount failures are remembered at most", func(c *Config) *Duration { return &c.MaxMountErrorTTL }),
+	durationSetting("slow-mount-threshold", "mount duration above which a mount is slow", func(c *Config) *Duration { return &c.SlowMountThreshold }),
+	durationSetting("critical-mount-threshold", "mount duration above which a slow mount is critical", func(c *Config) *Duration { return &c.CriticalMountThreshold }),
+	durationSetting("mount-heartbeat-interval", "how often mounts in progress are reported", func(c *Config) *Duration { return &c.MountHeartbeatInterval }),
+	durationSetting("drain-timeout", "how long drain waits for unmounts", func(c *Config) *Duration { return &c.DrainTimeout }),
+	boolSetting("rootless", "refuse the volumes and opts that need the driver to run as root", func(c *Config) *bool { return &c.Rootless }),
+	boolSetting("skip-purge", "keep drain from purging the mount path roots", func(c *Config) *bool { return &c.SkipPurge }),
+	boolSetting("lock-root", "refuse to start when another driver instance uses the mount path root", func(c *Config) *bool { return &c.LockRoot }),
+	boolSetting("remount-on-start", "mount the volumes that were mounted before a restart again", func(c *Config) *bool { return &c.RemountOnStart }),
+	durationSetting("expiry-interval", "how often volumes are checked for expiry", func(c *Config) *Duration { return &c.ExpiryInterval }),
+	durationSetting("snapshot-interval", "how often the state is snapshotted, zero disables the snapshots", func(c *Config) *Duration { return &c.SnapshotInterval }),
+	intSetting("snapshot-retention", "how many state snapshots are kept", func(c *Config) *int { return &c.SnapshotRetention }),
+	durationSetting("orphan-interval", "how often orphaned directories are collected", func(c *Config) *Duration { return &c.OrphanInterval }),
+	durationSetting("persist-debounce", "how long state writes that are safe to lose are batched", func(c *Config) *Duration { return &c.PersistDebounce }),
+	durationSetting("persist-retry-interval", "first backoff of failed state writes, which are retried instead of failing requests", func(c *Config) *Duration { return &c.PersistRetryInterval }),
+	durationSetting("mount-stats-interval", "how often nfs client statistics are emitted per volume", func(c *Config) *Duration { return &c.MountStatsInterval }),
+	intSetting("max-in-flight", "number of requests served at once above which requests are refused as busy, zero disables the limit", func(c *Config) *int { return &c.MaxInFlight }),
+	intSetting("max-pending-mounts", "number of mounts in progress above which mounts are refused as busy", func(c *Config) *int { return &c.MaxPendingMounts }),
+	intSetting("max-pending-mounts-per-server", "number of mounts in progress on one server above which its mounts are refused as busy", func(c *Config) *int { return &c.MaxPendingMountsPerServer }),
+	durationSetting("busy-retry-after", "how long callers are asked to wait before they retry a mount refused as busy", func(c *Config) *Duration { return &c.BusyRetryAfter }),
+	stringSetting("statsd-address", "host:port of the statsd agent that receives the metrics, empty to drop them", func(c *Config) *string { return &c.StatsdAddress }),
+	stringSetting("statsd-prefix", "prefix of the metric names sent to statsd", func(c *Config) *string { return &c.StatsdPrefix }),
+	intSetting("max-volumes", "number of volumes that can exist at once", func(c *Config) *int { return &c.Quotas.MaxVolumes }),
+	intSetting("max-mounts", "number of volumes that can be mounted at once", func(c *Config) *int { return &c.Quotas.MaxMounts }),
+	intSetting("max-mounts-per-source", "number of volumes of a source that can be mounted at once", func(c *Config) *int { return &c.Quotas.MaxMountsPerSource }),
+	intSetting("max-unmounted-volumes", "number of volumes that are not mounted that are kept, least recently mounted ones are pruned", func(c *Config) *int { return &c.StateLimits.MaxUnmountedVolumes }),
+	intSetting("max-state-bytes", "size of the state records above which volumes that are not mounted are pruned", func(c *Config) *int { return &c.StateLimits.MaxStateBytes }),
+}
+
+func stringSetting(name, usage string, field func(*Config) *string) setting {
+	return setting{name: name, usage: usage, set: func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}}
+}
+
+func durationSetting(name, usage string, field func(*Config) *Duration) setting {
+	return setting{name: name, usage: usage, set: func(c *Config, value string) error {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*field(c) = Duration(duration)
+		return nil
+	}}
+}
+
+func boolSetting(name, usage string, field func(*Config) *bool) setting {
+	return setting{name: name, usage: usage, boolean: true, set: func(c *Config, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*field(c) = b
+		return nil
+	}}
+}
+
+func intSetting(name, usage string, field func(*Config) *int) setting {
+	return setting{name: name, usage: usage, set: func(c *Config, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*field(c) = n
+		return nil
+	}}
+}
+
+// Flags are the command line overrides of a Config. Values are only checked
+// and applied by Load, so that flags take precedence over the file and the
+// environment wherever they appear on the command line.
+type Flags struct {
+	path   string
+	values map[string]string
+}
+
+// RegisterFlags adds the config flag and one flag per setting to fs.
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	flags := &Flags{values: map[string]string{}}
+
+	fs.StringVar(&flags.path, "config", "", "path to a JSON config file, defaults to $"+ConfigEnv)
+	for _, s := range settings {
+		fs.Var(flagValue{flags: flags, name: s.name, boolean: s.boolean}, s.name, s.usage+" (env "+s.env()+")")
+	}
+
+	return flags
+}
+
+func (f *Flags) apply(c *Config) error {
+	for _, s := range settings {
+		value, ok := f.values[s.name]
+		if !ok {
+			continue
+		}
+		if err := s.set(c, value); err != nil {
+			return fmt.Errorf("invalid -%s: %s", s.name, err)
+		}
+	}
+	return nil
+}
+
+type flagValue struct {
+	flags   *Flags
+	name    string
+	boolean bool
+}
+
+func (v flagValue) String() string {
+	if v.flags == nil {
+		return ""
+	}
+	return v.flags.values[v.name]
+}
+
+func (v flagValue) IsBoolFlag() bool {
+	return v.boolean
+}
+
+func (v flagValue) Set(value string) error {
+	v.flags.values[v.name] = value
+	return nil
+}
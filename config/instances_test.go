@@ -0,0 +1,134 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/volumedriver/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LoadInstances", func() {
+	var (
+		dir        string
+		configFile string
+		contents   string
+		env        map[string]string
+		flags      *config.Flags
+		configs    []config.Config
+		err        error
+	)
+
+	lookupEnv := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	BeforeEach(func() {
+		dir, err = ioutil.TempDir("", "instances")
+		Expect(err).NotTo(HaveOccurred())
+		configFile = filepath.Join(dir, "config.json")
+
+		contents = `{
+			"Listen": {"Network": "unix"},
+			"Backend": "kernel",
+			"DefaultOpts": "vers=4.1",
+			"Instances": [
+				{"Name": "nfsdriver", "MountPathRoot": "/var/vcap/data/nfs", "Listen": {"Network": "unix", "Address": "/run/docker/plugins/nfsdriver.sock"}},
+				{"Name": "nfsdriver-fuse", "MountPathRoot": "/var/vcap/data/nfs-fuse", "Listen": {"Network": "unix", "Address": "/run/docker/plugins/nfsdriver-fuse.sock"}, "Backend": "fuse-nfs"}
+			]
+		}`
+		env = map[string]string{config.ConfigEnv: configFile, "VOLUMEDRIVER_LOG_LEVEL": "debug"}
+		flags = nil
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	JustBeforeEach(func() {
+		Expect(ioutil.WriteFile(configFile, []byte(contents), 0600)).To(Succeed())
+		configs, err = config.LoadInstances(flags, lookupEnv)
+	})
+
+	It("returns every instance with its settings over the shared ones", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(configs).To(HaveLen(2))
+
+		Expect(configs[0].Name).To(Equal("nfsdriver"))
+		Expect(configs[0].MountPathRoot).To(Equal("/var/vcap/data/nfs"))
+		Expect(configs[0].Backend).To(Equal("kernel"))
+
+		Expect(configs[1].Name).To(Equal("nfsdriver-fuse"))
+		Expect(configs[1].Listen.Address).To(Equal("/run/docker/plugins/nfsdriver-fuse.sock"))
+		Expect(configs[1].Backend).To(Equal("fuse-nfs"))
+
+		for _, instance := range configs {
+			Expect(instance.DefaultOpts).To(Equal("vers=4.1"))
+			Expect(instance.LogLevel).To(Equal("debug"))
+		}
+	})
+
+	It("loads a single instance by name", func() {
+		instance, err := config.LoadInstance(flags, lookupEnv, "nfsdriver-fuse")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(instance.MountPathRoot).To(Equal("/var/vcap/data/nfs-fuse"))
+
+		_, err = config.LoadInstance(flags, lookupEnv, "smbdriver")
+		Expect(err).To(MatchError("no instance named 'smbdriver'"))
+	})
+
+	It("is refused by Load", func() {
+		_, err := config.Load(flags, lookupEnv)
+		Expect(err).To(MatchError("the config file defines instances, load them with LoadInstances"))
+	})
+
+	Context("when instances share a mount path root", func() {
+		BeforeEach(func() {
+			contents = `{
+				"Instances": [
+					{"Name": "a", "MountPathRoot": "/var/vcap/data/nfs", "Listen": {"Address": "127.0.0.1:7589"}},
+					{"Name": "b", "MountPathRoot": "/var/vcap/data/nfs/", "Listen": {"Address": "127.0.0.1:7590"}}
+				]
+			}`
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError("instances 'a' and 'b' share the mount path root '/var/vcap/data/nfs'"))
+		})
+	})
+
+	Context("when an instance has an invalid name", func() {
+		BeforeEach(func() {
+			contents = `{"Instances": [{"Name": "nfs/driver", "MountPathRoot": "/var/vcap/data/nfs"}]}`
+		})
+
+		It("returns an error naming the instance", func() {
+			Expect(err).To(MatchError(ContainSubstring("invalid instance 'nfs/driver': invalid name 'nfs/driver'")))
+		})
+	})
+
+	Context("when an instance has an unknown setting", func() {
+		BeforeEach(func() {
+			contents = `{"Instances": [{"Name": "a", "MountPathRoot": "/var/vcap/data/nfs", "MountRoot": "/tmp"}]}`
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring(`invalid instance 0: json: unknown field "MountRoot"`)))
+		})
+	})
+
+	Context("when the file defines no instances", func() {
+		BeforeEach(func() {
+			contents = `{"MountPathRoot": "/var/vcap/data/nfs"}`
+		})
+
+		It("returns the single configuration with the default name", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(configs).To(HaveLen(1))
+			Expect(configs[0].Name).To(Equal(config.DefaultName))
+		})
+	})
+})
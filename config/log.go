@@ -0,0 +1,39 @@
+package config
+
+import (
+	"io"
+	"os"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver/logformat"
+)
+
+// LogSink returns the sink of the driver logs, writing them to stdout in
+// LogFormat. When CommandLog is set, the logs of the commands the driver
+// runs go to that file instead, in the same format. Close the returned
+// closer when the process exits. Wrap the sink in a lager.ReconfigurableSink
+// to let Reload apply the log level.
+func (c Config) LogSink(stdout io.Writer) (lager.Sink, io.Closer, error) {
+	sink, err := logformat.NewSink(stdout, c.LogFormat, lager.DEBUG)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.CommandLog == "" {
+		return sink, nopCloser{}, nil
+	}
+
+	file, err := os.OpenFile(c.CommandLog, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0640)
+	if err != nil {
+		return nil, nil, err
+	}
+	commands, err := logformat.NewSink(file, c.LogFormat, lager.DEBUG)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return logformat.NewCommandRouter(sink, commands), file, nil
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
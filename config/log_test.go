@@ -0,0 +1,68 @@
+package config_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver/config"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LogSink", func() {
+	var (
+		dir    string
+		stdout *bytes.Buffer
+		cfg    config.Config
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "config-log")
+		Expect(err).NotTo(HaveOccurred())
+		stdout = &bytes.Buffer{}
+		cfg = config.Default()
+		cfg.LogFormat = "human"
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	log := func() {
+		sink, closer, err := cfg.LogSink(stdout)
+		Expect(err).NotTo(HaveOccurred())
+		defer closer.Close()
+
+		logger := lager.NewLogger("nfsdriver")
+		logger.RegisterSink(sink)
+		mount := logger.Session("mount", lager.Data{"volume": "volume"})
+		mount.Info("start")
+		mount.Session("invoking-supervised-command").Info("start")
+	}
+
+	It("writes the logs in the format to stdout", func() {
+		log()
+		Expect(stdout.String()).To(ContainSubstring("info  [volume] nfsdriver.mount.start"))
+		Expect(stdout.String()).To(ContainSubstring("invoking-supervised-command"))
+	})
+
+	It("writes the command logs to the command log", func() {
+		cfg.CommandLog = filepath.Join(dir, "commands.log")
+		log()
+
+		Expect(stdout.String()).NotTo(ContainSubstring("invoking-supervised-command"))
+		commands, err := ioutil.ReadFile(cfg.CommandLog)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(commands)).To(ContainSubstring("[volume] nfsdriver.mount.invoking-supervised-command"))
+	})
+
+	It("refuses unknown formats", func() {
+		cfg.MountPathRoot = dir
+		cfg.LogFormat = "xml"
+		Expect(cfg.Validate()).To(MatchError(ContainSubstring("invalid log format")))
+	})
+})
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"context"
+	"os"
+	"reflect"
+
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+)
+
+// Policy returns the settings of c that can be reloaded while the driver
+// runs.
+func (c Config) Policy() (volumedriver.Policy, error) {
+	options, err := c.Options()
+	if err != nil {
+		return volumedriver.Policy{}, err
+	}
+	return options.Policy(), nil
+}
+
+// restartSettings are the settings that only take effect when the driver
+// process is restarted.
+func (c Config) restartSettings() map[string]interface{} {
+	return map[string]interface{}{
+		"Name":                      c.Name,
+		"Listen":                    c.Listen,
+		"MountPathRoot":             c.MountPathRoot,
+		"MountPathRoots":            c.MountPathRoots,
+		"RootPolicy":                c.RootPolicy,
+		"Backend":                   c.Backend,
+		"Rootless":                  c.Rootless,
+		"LogFormat":                 c.LogFormat,
+		"CommandLog":                c.CommandLog,
+		"HelperBinaries":            c.HelperBinaries,
+		"HelperPath":                c.HelperPath,
+		"DrainTimeout":              c.DrainTimeout,
+		"SkipPurge":                 c.SkipPurge,
+		"LockRoot":                  c.LockRoot,
+		"RemountOnStart":            c.RemountOnStart,
+		"ExpiryInterval":            c.ExpiryInterval,
+		"SnapshotInterval":          c.SnapshotInterval,
+		"SnapshotRetention":         c.SnapshotRetention,
+		"OrphanInterval":            c.OrphanInterval,
+		"PersistDebounce":           c.PersistDebounce,
+		"PersistRetryInterval":      c.PersistRetryInterval,
+		"GlobalRateLimit":           c.GlobalRateLimit,
+		"VolumeRateLimit":           c.VolumeRateLimit,
+		"MaxInFlight":               c.MaxInFlight,
+		"MaxPendingMounts":          c.MaxPendingMounts,
+		"MaxPendingMountsPerServer": c.MaxPendingMountsPerServer,
+		"BusyRetryAfter":            c.BusyRetryAfter,
+		"StatsdAddress":             c.StatsdAddress,
+		"StatsdPrefix":              c.StatsdPrefix,
+	}
+}
+
+// Reload loads the configuration again and applies its policy to driver and
+// its log level to sink. Mounts are not touched. Settings that differ from
+// started, the configuration the process was started with, need a restart;
+// they are logged and otherwise ignored. On error nothing is applied.
+func Reload(logger lager.Logger, started Config, load func() (Config, error), driver *volumedriver.VolumeDriver, sink *lager.ReconfigurableSink) error {
+	logger = logger.Session("reload-config")
+	logger.Info("start")
+	defer logger.Info("end")
+
+	next, err := load()
+	if err != nil {
+		logger.Error("load-failed", err)
+		return err
+	}
+
+	policy, err := next.Policy()
+	if err != nil {
+		logger.Error("invalid-policy", err)
+		return err
+	}
+	level, err := lager.LogLevelFromString(next.LogLevel)
+	if err != nil {
+		logger.Error("invalid-log-level", err)
+		return err
+	}
+
+	env := driverhttp.NewHttpDriverEnv(logger, context.TODO())
+	if err := driver.UpdatePolicy(env, policy); err != nil {
+		return err
+	}
+	sink.SetMinLevel(level)
+
+	startedSettings := started.restartSettings()
+	for name, value := range next.restartSettings() {
+		if !reflect.DeepEqual(value, startedSettings[name]) {
+			logger.Info("restart-required", lager.Data{"setting": name})
+		}
+	}
+
+	return nil
+}
+
+// HandleReload calls Reload on every signal until signals is closed. Use
+// NotifyReload to subscribe signals to SIGHUP.
+func HandleReload(logger lager.Logger, started Config, load func() (Config, error), driver *volumedriver.VolumeDriver, sink *lager.ReconfigurableSink, signals <-chan os.Signal) {
+	for range signals {
+		// a failed reload is logged and keeps the running configuration
+		Reload(logger, started, load, driver, sink)
+	}
+}
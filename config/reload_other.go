@@ -0,0 +1,11 @@
+// +build !linux,!darwin
+
+package config
+
+import (
+	"os"
+)
+
+// NotifyReload does nothing, this platform has no SIGHUP. Call Reload
+// directly instead.
+func NotifyReload(c chan<- os.Signal) {}
@@ -0,0 +1,119 @@
+package config_test
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/config"
+	"code.cloudfoundry.org/volumedriver/testhelpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Reload", func() {
+	var (
+		logger  *lagertest.TestLogger
+		env     dockerdriver.Env
+		sink    *lager.ReconfigurableSink
+		driver  *testhelpers.MemoryDriver
+		started config.Config
+		next    config.Config
+		loadErr error
+	)
+
+	load := func() (config.Config, error) {
+		return next, loadErr
+	}
+
+	create := func(name, source string) string {
+		return driver.Create(env, dockerdriver.CreateRequest{Name: name, Opts: map[string]interface{}{"source": source}}).Err
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("reload")
+		env = driverhttp.NewHttpDriverEnv(logger, context.TODO())
+		sink = lager.NewReconfigurableSink(lager.NewWriterSink(GinkgoWriter, lager.DEBUG), lager.INFO)
+
+		started = config.Default()
+		started.MountPathRoot = testhelpers.MountPathRoot
+		options, err := started.Options()
+		Expect(err).NotTo(HaveOccurred())
+		driver = testhelpers.NewMemoryDriverWithOptions(logger, options)
+
+		next = started
+		next.AllowedSources = []volumedriver.SourceRule{{Host: "10.0.0.0/8"}}
+		next.LogLevel = "debug"
+		loadErr = nil
+	})
+
+	It("applies the policy and the log level", func() {
+		Expect(config.Reload(logger, started, load, driver.VolumeDriver, sink)).To(Succeed())
+
+		Expect(create("allowed", "10.1.2.3:/export")).To(BeEmpty())
+		Expect(create("refused", "192.168.0.1:/export")).To(Equal("source '192.168.0.1:/export' is not allowed on this cell"))
+		Expect(sink.GetMinLevel()).To(Equal(lager.DEBUG))
+	})
+
+	It("leaves mounted volumes alone", func() {
+		Expect(create("mounted", "192.168.0.1:/export")).To(BeEmpty())
+		mountResponse := driver.Mount(env, dockerdriver.MountRequest{Name: "mounted"})
+		Expect(mountResponse.Err).To(BeEmpty())
+
+		Expect(config.Reload(logger, started, load, driver.VolumeDriver, sink)).To(Succeed())
+
+		Expect(driver.Mounter.UnmountCalls()).To(BeEmpty())
+		Expect(driver.Get(env, dockerdriver.GetRequest{Name: "mounted"}).Volume.Mountpoint).To(Equal(mountResponse.Mountpoint))
+	})
+
+	It("logs settings that need a restart", func() {
+		next.MountPathRoot = "/elsewhere"
+
+		Expect(config.Reload(logger, started, load, driver.VolumeDriver, sink)).To(Succeed())
+		Expect(logger.LogMessages()).To(ContainElement("reload.reload-config.restart-required"))
+	})
+
+	Context("when the configuration cannot be loaded", func() {
+		BeforeEach(func() {
+			loadErr = errors.New("invalid config file")
+		})
+
+		It("keeps the running policy", func() {
+			Expect(config.Reload(logger, started, load, driver.VolumeDriver, sink)).To(MatchError("invalid config file"))
+			Expect(create("volume", "192.168.0.1:/export")).To(BeEmpty())
+			Expect(sink.GetMinLevel()).To(Equal(lager.INFO))
+		})
+	})
+
+	Context("when the policy is not valid", func() {
+		BeforeEach(func() {
+			next.AllowedSources = []volumedriver.SourceRule{{Export: "/export"}}
+		})
+
+		It("keeps the running policy", func() {
+			Expect(config.Reload(logger, started, load, driver.VolumeDriver, sink)).NotTo(Succeed())
+			Expect(create("volume", "192.168.0.1:/export")).To(BeEmpty())
+		})
+	})
+
+	Describe("HandleReload", func() {
+		It("reloads on every signal", func() {
+			signals := make(chan os.Signal)
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				config.HandleReload(logger, started, load, driver.VolumeDriver, sink, signals)
+			}()
+
+			signals <- os.Interrupt
+			close(signals)
+			Eventually(done).Should(BeClosed())
+			Expect(sink.GetMinLevel()).To(Equal(lager.DEBUG))
+		})
+	})
+})
@@ -0,0 +1,14 @@
+// +build linux darwin
+
+package config
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// NotifyReload relays SIGHUP to c.
+func NotifyReload(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGHUP)
+}
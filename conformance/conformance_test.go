@@ -0,0 +1,140 @@
+package conformance_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/goshims/filepathshim"
+	"code.cloudfoundry.org/goshims/ioutilshim"
+	"code.cloudfoundry.org/goshims/osshim"
+	"code.cloudfoundry.org/goshims/timeshim"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/conformance"
+	"code.cloudfoundry.org/volumedriver/oshelper"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// directoryMounter pretends to mount by remembering the targets, which is
+// enough to run the specs without root.
+type directoryMounter struct {
+	lock   sync.Mutex
+	mounts map[string]string
+}
+
+func newDirectoryMounter() *directoryMounter {
+	return &directoryMounter{mounts: map[string]string{}}
+}
+
+func (m *directoryMounter) Mount(env dockerdriver.Env, source string, target string, opts map[string]interface{}) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, err := os.Stat(target); err != nil {
+		return err
+	}
+	m.mounts[target] = source
+	return nil
+}
+
+func (m *directoryMounter) Unmount(env dockerdriver.Env, target string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.mounts[target]; !ok {
+		return errors.New("not mounted")
+	}
+	delete(m.mounts, target)
+	return nil
+}
+
+func (m *directoryMounter) Check(env dockerdriver.Env, name, mountPoint string, depth volumedriver.CheckDepth) bool {
+	if mounted, _ := m.Exists(mountPoint); !mounted {
+		return false
+	}
+	return volumedriver.ProbeMountPoint(&osshim.OsShim{}, &ioutilshim.IoutilShim{}, mountPoint, depth) == nil
+}
+
+func (m *directoryMounter) Purge(env dockerdriver.Env, path string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for target := range m.mounts {
+		if strings.HasPrefix(target, path+"/") {
+			delete(m.mounts, target)
+			os.Remove(target)
+		}
+	}
+}
+
+func (m *directoryMounter) Exists(mountPath string) (bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	_, ok := m.mounts[mountPath]
+	return ok, nil
+}
+
+func (m *directoryMounter) List(pattern *regexp.Regexp) ([]string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	mounts := []string{}
+	for target := range m.mounts {
+		if pattern.MatchString(target) {
+			mounts = append(mounts, target)
+		}
+	}
+	return mounts, nil
+}
+
+var _ = Describe("Conformance", func() {
+	var (
+		mountRoot string
+		mounter   *directoryMounter
+	)
+
+	BeforeEach(func() {
+		var err error
+		mountRoot, err = ioutil.TempDir("", "conformance")
+		Expect(err).NotTo(HaveOccurred())
+		mounter = newDirectoryMounter()
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(mountRoot)).To(Succeed())
+	})
+
+	conformance.DescribeMounter("directory", func() conformance.MounterConfig {
+		return conformance.MounterConfig{
+			Mounter:   mounter,
+			Source:    "server:/export",
+			MountRoot: mountRoot,
+		}
+	})
+
+	conformance.DescribeDriver("VolumeDriver", func() conformance.DriverConfig {
+		driver := volumedriver.NewVolumeDriver(
+			lagertest.NewTestLogger("conformance"),
+			&osshim.OsShim{},
+			&filepathshim.FilepathShim{},
+			&ioutilshim.IoutilShim{},
+			&timeshim.TimeShim{},
+			mounter,
+			mountRoot,
+			mounter,
+			oshelper.NewOsHelper(),
+		)
+
+		return conformance.DriverConfig{
+			Driver:     driver,
+			CreateOpts: map[string]interface{}{"source": "server:/export"},
+		}
+	})
+})
@@ -0,0 +1,9 @@
+// Package conformance exports Ginkgo specs that pin down the contract of a
+// Mounter and of a dockerdriver.Driver, so that new backends and downstream
+// forks can prove they behave like the ones in this repository. Register the
+// specs from a test file of the implementation:
+//
+//	var _ = conformance.DescribeMounter("my mounter", func() conformance.MounterConfig {
+//		return conformance.MounterConfig{Mounter: mymounter.New(), Source: "server:/export", MountRoot: tempDir}
+//	})
+package conformance
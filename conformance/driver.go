@@ -0,0 +1,135 @@
+package conformance
+
+import (
+	"context"
+	"fmt"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// DriverConfig is the driver under test together with the opts that create a
+// mountable volume.
+type DriverConfig struct {
+	Driver     dockerdriver.Driver
+	CreateOpts map[string]interface{}
+}
+
+// DescribeDriver registers the contract of a docker volume plugin, as the
+// volume services of the platform rely on it. config is called before every
+// spec.
+func DescribeDriver(description string, config func() DriverConfig) bool {
+	return Describe(description+" Driver conformance", func() {
+		var (
+			env    dockerdriver.Env
+			c      DriverConfig
+			volume string
+			count  int
+		)
+
+		create := func() {
+			opts := map[string]interface{}{}
+			for k, v := range c.CreateOpts {
+				opts[k] = v
+			}
+			Expect(c.Driver.Create(env, dockerdriver.CreateRequest{Name: volume, Opts: opts}).Err).To(BeEmpty())
+		}
+
+		mount := func() string {
+			mountResponse := c.Driver.Mount(env, dockerdriver.MountRequest{Name: volume})
+			Expect(mountResponse.Err).To(BeEmpty())
+			Expect(mountResponse.Mountpoint).NotTo(BeEmpty())
+			return mountResponse.Mountpoint
+		}
+
+		BeforeEach(func() {
+			env = driverhttp.NewHttpDriverEnv(lagertest.NewTestLogger("conformance"), context.TODO())
+			c = config()
+			count++
+			volume = fmt.Sprintf("conformance-volume-%d", count)
+		})
+
+		It("implements the VolumeDriver protocol", func() {
+			Expect(c.Driver.Activate(env).Implements).To(ContainElement("VolumeDriver"))
+		})
+
+		It("reports its scope", func() {
+			Expect(c.Driver.Capabilities(env).Capabilities.Scope).To(BeElementOf("local", "global"))
+		})
+
+		It("requires a volume name", func() {
+			Expect(c.Driver.Create(env, dockerdriver.CreateRequest{Opts: c.CreateOpts}).Err).NotTo(BeEmpty())
+			Expect(c.Driver.Mount(env, dockerdriver.MountRequest{}).Err).NotTo(BeEmpty())
+			Expect(c.Driver.Unmount(env, dockerdriver.UnmountRequest{}).Err).NotTo(BeEmpty())
+		})
+
+		It("lists and gets created volumes", func() {
+			create()
+
+			getResponse := c.Driver.Get(env, dockerdriver.GetRequest{Name: volume})
+			Expect(getResponse.Err).To(BeEmpty())
+			Expect(getResponse.Volume.Name).To(Equal(volume))
+
+			names := []string{}
+			for _, info := range c.Driver.List(env).Volumes {
+				names = append(names, info.Name)
+			}
+			Expect(names).To(ContainElement(volume))
+		})
+
+		It("accepts a second create of the same volume", func() {
+			create()
+			create()
+		})
+
+		It("reports the mountpoint of mounted volumes", func() {
+			create()
+			mountpoint := mount()
+
+			pathResponse := c.Driver.Path(env, dockerdriver.PathRequest{Name: volume})
+			Expect(pathResponse.Err).To(BeEmpty())
+			Expect(pathResponse.Mountpoint).To(Equal(mountpoint))
+			Expect(c.Driver.Get(env, dockerdriver.GetRequest{Name: volume}).Volume.Mountpoint).To(Equal(mountpoint))
+
+			Expect(c.Driver.Unmount(env, dockerdriver.UnmountRequest{Name: volume}).Err).To(BeEmpty())
+		})
+
+		It("counts mounts of the same volume", func() {
+			create()
+			mountpoint := mount()
+			Expect(mount()).To(Equal(mountpoint))
+
+			Expect(c.Driver.Unmount(env, dockerdriver.UnmountRequest{Name: volume}).Err).To(BeEmpty())
+			Expect(c.Driver.Path(env, dockerdriver.PathRequest{Name: volume}).Mountpoint).To(Equal(mountpoint))
+			Expect(c.Driver.Unmount(env, dockerdriver.UnmountRequest{Name: volume}).Err).To(BeEmpty())
+		})
+
+		It("refuses to mount volumes that were not created", func() {
+			Expect(c.Driver.Mount(env, dockerdriver.MountRequest{Name: volume}).Err).NotTo(BeEmpty())
+		})
+
+		It("refuses to unmount volumes that were not created", func() {
+			Expect(c.Driver.Unmount(env, dockerdriver.UnmountRequest{Name: volume}).Err).NotTo(BeEmpty())
+		})
+
+		It("forgets removed volumes", func() {
+			create()
+			Expect(c.Driver.Remove(env, dockerdriver.RemoveRequest{Name: volume}).Err).To(BeEmpty())
+			Expect(c.Driver.Get(env, dockerdriver.GetRequest{Name: volume}).Err).NotTo(BeEmpty())
+		})
+
+		It("refuses to remove volumes that are still mounted", func() {
+			create()
+			mountpoint := mount()
+			Expect(c.Driver.Remove(env, dockerdriver.RemoveRequest{Name: volume}).Err).NotTo(BeEmpty())
+			Expect(c.Driver.Path(env, dockerdriver.PathRequest{Name: volume}).Mountpoint).To(Equal(mountpoint))
+
+			Expect(c.Driver.Unmount(env, dockerdriver.UnmountRequest{Name: volume}).Err).To(BeEmpty())
+			Expect(c.Driver.Remove(env, dockerdriver.RemoveRequest{Name: volume}).Err).To(BeEmpty())
+			Expect(c.Driver.Path(env, dockerdriver.PathRequest{Name: volume}).Err).NotTo(BeEmpty())
+		})
+	})
+}
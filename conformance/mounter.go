@@ -0,0 +1,114 @@
+package conformance
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// MounterConfig is the Mounter under test together with a source it can
+// mount. MountRoot is an existing, empty scratch directory that the specs
+// create their mountpoints in; it must not be shared between specs.
+type MounterConfig struct {
+	Mounter   volumedriver.Mounter
+	Source    string
+	Opts      map[string]interface{}
+	MountRoot string
+
+	// ReadOnly skips the specs that write through the mount.
+	ReadOnly bool
+}
+
+// DescribeMounter registers the Mounter contract. config is called before
+// every spec.
+func DescribeMounter(description string, config func() MounterConfig) bool {
+	return Describe(description+" Mounter conformance", func() {
+		var (
+			env     dockerdriver.Env
+			c       MounterConfig
+			target  string
+			mounted bool
+		)
+
+		mount := func() {
+			Expect(c.Mounter.Mount(env, c.Source, target, c.Opts)).To(Succeed())
+			mounted = true
+		}
+
+		BeforeEach(func() {
+			env = driverhttp.NewHttpDriverEnv(lagertest.NewTestLogger("conformance"), context.TODO())
+			c = config()
+			target = filepath.Join(c.MountRoot, "target")
+			Expect(os.MkdirAll(target, 0755)).To(Succeed())
+			mounted = false
+		})
+
+		AfterEach(func() {
+			if mounted {
+				c.Mounter.Unmount(env, target)
+			}
+		})
+
+		It("reports a directory that was never mounted as unhealthy", func() {
+			Expect(c.Mounter.Check(env, "volume", target, volumedriver.CheckStat)).To(BeFalse())
+		})
+
+		It("mounts the source at the target", func() {
+			mount()
+			Expect(c.Mounter.Check(env, "volume", target, volumedriver.CheckStat)).To(BeTrue())
+			Expect(c.Mounter.Check(env, "volume", target, volumedriver.CheckRead)).To(BeTrue())
+		})
+
+		It("unmounts the target", func() {
+			mount()
+			Expect(c.Mounter.Unmount(env, target)).To(Succeed())
+			mounted = false
+			Expect(c.Mounter.Check(env, "volume", target, volumedriver.CheckStat)).To(BeFalse())
+		})
+
+		It("fails to unmount a target that is not mounted", func() {
+			Expect(c.Mounter.Unmount(env, target)).NotTo(Succeed())
+		})
+
+		It("exposes the data of the source", func() {
+			if c.ReadOnly {
+				Skip("the source is read-only")
+			}
+
+			mount()
+			Expect(c.Mounter.Check(env, "volume", target, volumedriver.CheckWrite)).To(BeTrue())
+			Expect(ioutil.WriteFile(filepath.Join(target, "conformance"), []byte("data"), 0644)).To(Succeed())
+			Expect(c.Mounter.Unmount(env, target)).To(Succeed())
+
+			mount()
+			data, err := ioutil.ReadFile(filepath.Join(target, "conformance"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("data"))
+			Expect(os.Remove(filepath.Join(target, "conformance"))).To(Succeed())
+		})
+
+		It("purges the mounts below a path", func() {
+			mount()
+			c.Mounter.Purge(env, c.MountRoot)
+			mounted = false
+			Expect(c.Mounter.Check(env, "volume", target, volumedriver.CheckStat)).To(BeFalse())
+		})
+
+		It("does not remove data below the purged path", func() {
+			data := filepath.Join(c.MountRoot, "not-a-mount", "data")
+			Expect(os.MkdirAll(filepath.Dir(data), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(data, []byte("data"), 0644)).To(Succeed())
+
+			c.Mounter.Purge(env, c.MountRoot)
+			Expect(data).To(BeARegularFile())
+		})
+	})
+}
@@ -0,0 +1,336 @@
+// Package consulrefs keeps the export references of a global-scope
+// deployment in the KV store of Consul, see volumedriver.RefStore.
+package consulrefs
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/cfhttp"
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/goshims/http_wrap"
+	"code.cloudfoundry.org/goshims/timeshim"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+)
+
+// DefaultPrefix is the KV path that the references are kept below.
+const DefaultPrefix = "volumedriver/refs"
+
+// DefaultReleaseTimeout is how long an export stays fenced off by a release
+// that is not forgotten.
+const DefaultReleaseTimeout = 10 * time.Minute
+
+// maxAttempts bounds how often an update is tried again after other cells
+// changed the references of the same export in between.
+const maxAttempts = 10
+
+// Config describes the Consul agent or server the driver talks to.
+type Config struct {
+	// Address is the address of the HTTP API, for example
+	// https://127.0.0.1:8501.
+	Address string
+	// CACertFile verifies the certificate of the agent. The system roots
+	// are used when it is empty.
+	CACertFile string
+	// Token is an ACL token with write access to Prefix.
+	Token string
+	// Datacenter defaults to the datacenter of the agent.
+	Datacenter string
+
+	// Prefix defaults to DefaultPrefix. Every export has a key below it,
+	// the URL-safe base64 of the export.
+	Prefix string
+
+	// ReleaseTimeout defaults to DefaultReleaseTimeout. A fence older than
+	// that, left behind by a cell that went away while it released the
+	// export, is taken over by the next Acquire. It must be longer than the
+	// release hook takes.
+	ReleaseTimeout time.Duration
+}
+
+// refs is the value of the key of an export.
+type refs struct {
+	Export  string
+	Holders []string `json:",omitempty"`
+	// Releasing fences the export off while its release hook runs. The
+	// holder that released the export last and the time it did are kept
+	// with it, so that only that holder forgets the fence, and so that a
+	// stale fence can be taken over.
+	Releasing      bool       `json:",omitempty"`
+	ReleasedBy     string     `json:",omitempty"`
+	ReleasingSince *time.Time `json:",omitempty"`
+}
+
+type kvPair struct {
+	Value       []byte
+	ModifyIndex uint64
+}
+
+type consulRefs struct {
+	config     Config
+	httpClient http_wrap.Client
+	time       timeshim.Time
+}
+
+// NewStore returns a RefStore that keeps the holders of every export in one
+// key, and changes it with check-and-set, so that concurrent changes of
+// several cells are never lost.
+func NewStore(config Config) (volumedriver.RefStore, error) {
+	client := cfhttp.NewClient()
+
+	if config.CACertFile != "" {
+		caCert, err := ioutil.ReadFile(config.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no certificates found in '%s'", config.CACertFile)
+		}
+		client.Transport.(*http.Transport).TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	return NewStoreWithClient(config, client, &timeshim.TimeShim{}), nil
+}
+
+func NewStoreWithClient(config Config, client http_wrap.Client, time timeshim.Time) volumedriver.RefStore {
+	if config.Prefix == "" {
+		config.Prefix = DefaultPrefix
+	}
+	if config.ReleaseTimeout == 0 {
+		config.ReleaseTimeout = DefaultReleaseTimeout
+	}
+	config.Prefix = strings.Trim(config.Prefix, "/")
+	config.Address = strings.TrimSuffix(config.Address, "/")
+
+	return &consulRefs{
+		config:     config,
+		httpClient: client,
+		time:       time,
+	}
+}
+
+func (c *consulRefs) Acquire(env dockerdriver.Env, export string, holder string) error {
+	logger := env.Logger().Session("consul-acquire", lager.Data{"export": export, "holder": holder})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	return c.update(env.Context(), export, func(r *refs) (bool, error) {
+		if r.Releasing {
+			if !c.stale(r) {
+				return false, volumedriver.ErrExportReleasing
+			}
+			logger.Info("taking-over-stale-fence", lager.Data{"released-by": r.ReleasedBy, "releasing-since": r.ReleasingSince})
+			r.Releasing = false
+			r.ReleasedBy = ""
+			r.ReleasingSince = nil
+		}
+		if contains(r.Holders, holder) {
+			return false, nil
+		}
+		r.Holders = append(r.Holders, holder)
+		sort.Strings(r.Holders)
+		return true, nil
+	})
+}
+
+func (c *consulRefs) Release(env dockerdriver.Env, export string, holder string) (bool, error) {
+	logger := env.Logger().Session("consul-release", lager.Data{"export": export, "holder": holder})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	released := false
+	err := c.update(env.Context(), export, func(r *refs) (bool, error) {
+		released = false
+		if !contains(r.Holders, holder) {
+			return false, nil
+		}
+		holders := []string{}
+		for _, h := range r.Holders {
+			if h != holder {
+				holders = append(holders, h)
+			}
+		}
+		r.Holders = holders
+		if len(holders) == 0 {
+			now := c.time.Now()
+			r.Releasing = true
+			r.ReleasedBy = holder
+			r.ReleasingSince = &now
+		}
+		released = r.Releasing
+		return true, nil
+	})
+	if err != nil {
+		return false, err
+	}
+	logger.Info("released", lager.Data{"released": released})
+	return released, nil
+}
+
+func (c *consulRefs) Forget(env dockerdriver.Env, export string, holder string) error {
+	logger := env.Logger().Session("consul-forget", lager.Data{"export": export, "holder": holder})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	return c.update(env.Context(), export, func(r *refs) (bool, error) {
+		if !r.Releasing || r.ReleasedBy != holder {
+			return false, nil
+		}
+		r.Releasing = false
+		r.ReleasedBy = ""
+		r.ReleasingSince = nil
+		return true, nil
+	})
+}
+
+// stale tells whether the fence of r has outlived the release timeout.
+// Fences without a time are stale.
+func (c *consulRefs) stale(r *refs) bool {
+	return r.ReleasingSince == nil || c.time.Now().Sub(*r.ReleasingSince) >= c.config.ReleaseTimeout
+}
+
+// update reads the references of export, lets change modify them, and
+// writes them back unless another cell changed them in between, in which
+// case it starts over. change returns false to leave them as they are. Keys
+// without holders are deleted.
+func (c *consulRefs) update(ctx context.Context, export string, change func(r *refs) (bool, error)) error {
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		r, index, err := c.read(ctx, export)
+		if err != nil {
+			return err
+		}
+
+		changed, err := change(r)
+		if err != nil || !changed {
+			return err
+		}
+
+		var written bool
+		if len(r.Holders) == 0 && !r.Releasing {
+			written, err = c.write(ctx, http.MethodDelete, export, index, nil)
+		} else {
+			written, err = c.write(ctx, http.MethodPut, export, index, r)
+		}
+		if err != nil || written {
+			return err
+		}
+	}
+	return fmt.Errorf("the references of export '%s' changed %d times while updating them", export, maxAttempts)
+}
+
+// read returns the references of export, and the index to check them
+// against when they are written. The index of a missing key is 0.
+func (c *consulRefs) read(ctx context.Context, export string) (*refs, uint64, error) {
+	r := &refs{Export: export}
+
+	status, data, err := c.do(ctx, http.MethodGet, c.kvPath(export), nil, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if status == http.StatusNotFound {
+		return r, 0, nil
+	}
+
+	var pairs []kvPair
+	if err := json.Unmarshal(data, &pairs); err != nil || len(pairs) != 1 {
+		return nil, 0, fmt.Errorf("invalid response from consul for export '%s'", export)
+	}
+	if len(pairs[0].Value) > 0 {
+		if err := json.Unmarshal(pairs[0].Value, r); err != nil {
+			return nil, 0, fmt.Errorf("invalid references of export '%s': %s", export, err.Error())
+		}
+	}
+	return r, pairs[0].ModifyIndex, nil
+}
+
+// write returns false when the key changed since index.
+func (c *consulRefs) write(ctx context.Context, method string, export string, index uint64, r *refs) (bool, error) {
+	var body io.Reader
+	if r != nil {
+		payload, err := json.Marshal(r)
+		if err != nil {
+			return false, err
+		}
+		body = bytes.NewBuffer(payload)
+	}
+
+	query := url.Values{"cas": {strconv.FormatUint(index, 10)}}
+	_, data, err := c.do(ctx, method, c.kvPath(export), query, body)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(string(data)) == "true", nil
+}
+
+func (c *consulRefs) kvPath(export string) string {
+	return "/v1/kv/" + c.config.Prefix + "/" + base64.RawURLEncoding.EncodeToString([]byte(export))
+}
+
+// do returns the status and the body of responses that succeeded or were
+// not found.
+func (c *consulRefs) do(ctx context.Context, method string, path string, query url.Values, body io.Reader) (int, []byte, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	if c.config.Datacenter != "" {
+		query.Set("dc", c.config.Datacenter)
+	}
+
+	address := c.config.Address + path
+	if len(query) > 0 {
+		address += "?" + query.Encode()
+	}
+	httpRequest, err := http.NewRequest(method, address, body)
+	if err != nil {
+		return 0, nil, err
+	}
+	if c.config.Token != "" {
+		httpRequest.Header.Set("X-Consul-Token", c.config.Token)
+	}
+
+	httpResponse, err := c.httpClient.Do(httpRequest.WithContext(ctx))
+	if err != nil {
+		return 0, nil, err
+	}
+	defer httpResponse.Body.Close()
+
+	data, err := ioutil.ReadAll(httpResponse.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	switch {
+	case httpResponse.StatusCode == http.StatusNotFound:
+		return httpResponse.StatusCode, nil, nil
+	case httpResponse.StatusCode == http.StatusForbidden:
+		return 0, nil, errors.New("permission denied by consul, check the ACL token")
+	case httpResponse.StatusCode >= http.StatusBadRequest:
+		return 0, nil, fmt.Errorf("%s %s failed with status %d: %s", method, path, httpResponse.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return httpResponse.StatusCode, data, nil
+}
+
+func contains(holders []string, holder string) bool {
+	for _, h := range holders {
+		if h == holder {
+			return true
+		}
+	}
+	return false
+}
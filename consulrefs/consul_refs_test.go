@@ -0,0 +1,230 @@
+package consulrefs_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/goshims/timeshim/time_fake"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/consulrefs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type kvEntry struct {
+	value []byte
+	index uint64
+}
+
+// fakeConsul serves the KV API with check-and-set to clients with the token
+// some-token.
+type fakeConsul struct {
+	lock       sync.Mutex
+	kv         map[string]*kvEntry
+	index      uint64
+	datacenter string
+	// beforeWrite runs once before the next write, to change the key in
+	// between the read and the write of a client
+	beforeWrite func()
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if req.Header.Get("X-Consul-Token") != "some-token" {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("ACL not found"))
+		return
+	}
+	f.datacenter = req.URL.Query().Get("dc")
+
+	key := strings.TrimPrefix(req.URL.Path, "/v1/kv/")
+	entry := f.kv[key]
+
+	if req.Method == http.MethodGet {
+		if entry == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		data, _ := json.Marshal([]map[string]interface{}{{"Key": key, "Value": entry.value, "ModifyIndex": entry.index}})
+		w.Write(data)
+		return
+	}
+
+	if f.beforeWrite != nil {
+		f.beforeWrite()
+		f.beforeWrite = nil
+		entry = f.kv[key]
+	}
+
+	cas, err := strconv.ParseUint(req.URL.Query().Get("cas"), 10, 64)
+	Expect(err).NotTo(HaveOccurred())
+	if (entry == nil && cas != 0) || (entry != nil && entry.index != cas) {
+		w.Write([]byte("false"))
+		return
+	}
+
+	if req.Method == http.MethodDelete {
+		delete(f.kv, key)
+	} else {
+		value, _ := ioutil.ReadAll(req.Body)
+		f.index++
+		f.kv[key] = &kvEntry{value: value, index: f.index}
+	}
+	w.Write([]byte("true"))
+}
+
+func (f *fakeConsul) refs(export string) map[string]interface{} {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	entry := f.kv["volumedriver/refs/"+base64.RawURLEncoding.EncodeToString([]byte(export))]
+	if entry == nil {
+		return nil
+	}
+	var r map[string]interface{}
+	Expect(json.Unmarshal(entry.value, &r)).To(Succeed())
+	return r
+}
+
+var _ = Describe("ConsulRefs", func() {
+	var (
+		env      dockerdriver.Env
+		consul   *fakeConsul
+		server   *httptest.Server
+		config   consulrefs.Config
+		store    volumedriver.RefStore
+		now      time.Time
+		fakeTime *time_fake.FakeTime
+	)
+
+	BeforeEach(func() {
+		env = driverhttp.NewHttpDriverEnv(lagertest.NewTestLogger("consulrefs"), context.TODO())
+		consul = &fakeConsul{kv: map[string]*kvEntry{}}
+		server = httptest.NewServer(consul)
+
+		now = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		fakeTime = &time_fake.FakeTime{}
+		fakeTime.NowStub = func() time.Time { return now }
+
+		config = consulrefs.Config{
+			Address:    server.URL + "/",
+			Token:      "some-token",
+			Datacenter: "dc1",
+		}
+	})
+
+	JustBeforeEach(func() {
+		store = consulrefs.NewStoreWithClient(config, server.Client(), fakeTime)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("keeps the holders of an export", func() {
+		Expect(store.Acquire(env, "server:/export", "cell-b/vol")).To(Succeed())
+		Expect(store.Acquire(env, "server:/export", "cell-a/vol")).To(Succeed())
+		Expect(store.Acquire(env, "server:/export", "cell-a/vol")).To(Succeed())
+
+		Expect(consul.refs("server:/export")).To(Equal(map[string]interface{}{
+			"Export":  "server:/export",
+			"Holders": []interface{}{"cell-a/vol", "cell-b/vol"},
+		}))
+		Expect(consul.datacenter).To(Equal("dc1"))
+	})
+
+	It("releases the export with its last holder", func() {
+		Expect(store.Acquire(env, "server:/export", "cell-a/vol")).To(Succeed())
+		Expect(store.Acquire(env, "server:/export", "cell-b/vol")).To(Succeed())
+
+		Expect(store.Release(env, "server:/export", "cell-a/vol")).To(BeFalse())
+		Expect(store.Release(env, "server:/export", "cell-a/vol")).To(BeFalse())
+		Expect(store.Release(env, "server:/export", "cell-b/vol")).To(BeTrue())
+	})
+
+	It("fences the export off until it is forgotten", func() {
+		Expect(store.Acquire(env, "server:/export", "cell-a/vol")).To(Succeed())
+		Expect(store.Release(env, "server:/export", "cell-a/vol")).To(BeTrue())
+
+		Expect(store.Acquire(env, "server:/export", "cell-b/vol")).To(Equal(volumedriver.ErrExportReleasing))
+		Expect(consul.refs("server:/export")).To(Equal(map[string]interface{}{
+			"Export":         "server:/export",
+			"Releasing":      true,
+			"ReleasedBy":     "cell-a/vol",
+			"ReleasingSince": "2020-01-01T00:00:00Z",
+		}))
+
+		Expect(store.Forget(env, "server:/export", "cell-a/vol")).To(Succeed())
+		Expect(consul.kv).To(BeEmpty())
+		Expect(store.Acquire(env, "server:/export", "cell-b/vol")).To(Succeed())
+	})
+
+	It("takes over a fence that went stale", func() {
+		Expect(store.Acquire(env, "server:/export", "cell-a/vol")).To(Succeed())
+		Expect(store.Release(env, "server:/export", "cell-a/vol")).To(BeTrue())
+
+		now = now.Add(consulrefs.DefaultReleaseTimeout - time.Second)
+		Expect(store.Acquire(env, "server:/export", "cell-b/vol")).To(Equal(volumedriver.ErrExportReleasing))
+
+		now = now.Add(time.Second)
+		Expect(store.Acquire(env, "server:/export", "cell-b/vol")).To(Succeed())
+		Expect(consul.refs("server:/export")).To(Equal(map[string]interface{}{
+			"Export":  "server:/export",
+			"Holders": []interface{}{"cell-b/vol"},
+		}))
+	})
+
+	It("leaves a fence alone that another holder set", func() {
+		Expect(store.Acquire(env, "server:/export", "cell-a/vol")).To(Succeed())
+		Expect(store.Release(env, "server:/export", "cell-a/vol")).To(BeTrue())
+
+		// cell-a went away, and cell-b took the fence over and released
+		now = now.Add(consulrefs.DefaultReleaseTimeout)
+		Expect(store.Acquire(env, "server:/export", "cell-b/vol")).To(Succeed())
+		Expect(store.Release(env, "server:/export", "cell-b/vol")).To(BeTrue())
+
+		Expect(store.Forget(env, "server:/export", "cell-a/vol")).To(Succeed())
+		Expect(store.Acquire(env, "server:/export", "cell-c/vol")).To(Equal(volumedriver.ErrExportReleasing))
+	})
+
+	It("does not release exports it holds no reference to", func() {
+		Expect(store.Release(env, "server:/export", "cell-a/vol")).To(BeFalse())
+		Expect(consul.kv).To(BeEmpty())
+	})
+
+	It("tries again when another cell changed the references in between", func() {
+		Expect(store.Acquire(env, "server:/export", "cell-a/vol")).To(Succeed())
+		consul.beforeWrite = func() {
+			key := "volumedriver/refs/" + base64.RawURLEncoding.EncodeToString([]byte("server:/export"))
+			consul.index++
+			consul.kv[key] = &kvEntry{value: []byte(`{"Export":"server:/export","Holders":["cell-a/vol","cell-c/vol"]}`), index: consul.index}
+		}
+
+		Expect(store.Release(env, "server:/export", "cell-a/vol")).To(BeFalse())
+		Expect(consul.refs("server:/export")["Holders"]).To(Equal([]interface{}{"cell-c/vol"}))
+	})
+
+	Context("when the token is refused", func() {
+		BeforeEach(func() {
+			config.Token = "other-token"
+		})
+
+		It("fails", func() {
+			err := store.Acquire(env, "server:/export", "cell-a/vol")
+			Expect(err).To(MatchError(ContainSubstring("permission denied")))
+		})
+	})
+})
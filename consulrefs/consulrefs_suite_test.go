@@ -0,0 +1,13 @@
+package consulrefs_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestConsulRefs(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ConsulRefs Suite")
+}
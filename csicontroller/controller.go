@@ -0,0 +1,343 @@
+// Package csicontroller is the CSI Controller service of the driver:
+// CreateVolume and DeleteVolume provision a subdirectory per volume on a
+// configured export, like the Kubernetes nfs-subdir provisioner, so that
+// volumes can be created without pre-existing directories. The package has
+// no gRPC server: its types follow the CSI messages field by field, so that
+// a server built with the CSI bindings can delegate to a SubdirController.
+package csicontroller
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/goshims/osshim"
+	"code.cloudfoundry.org/goshims/timeshim"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+)
+
+// ParentParameter is the parameter of CreateVolume, usually set in the
+// storage class, that names the directory below the export the volume is
+// created in. Volumes are created directly below the export without it.
+const ParentParameter = "parent"
+
+// SourceContext is the key of the volume context that carries the source
+// of the volume, the opt the node mounts it with.
+const SourceContext = "source"
+
+// ArchivePrefix is prepended to the directories of deleted volumes when
+// Config.ArchiveOnDelete is set. Volume names must not start with it.
+const ArchivePrefix = "archived-"
+
+// archiveTimeFormat is the UTC time appended to the directories of deleted
+// volumes, so that a volume deleted again does not collide with its archive.
+const archiveTimeFormat = "20060102T150405Z"
+
+const defaultDirMode os.FileMode = 0777
+
+// validName matches the names of volumes and the segments of parents.
+var validName = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
+
+// maxNameLength is the longest directory name of common filesystems.
+const maxNameLength = 255
+
+// Config describes the export that volumes are provisioned on.
+type Config struct {
+	// Export is the source of the export, for example
+	// nfs.example.com:/exports/k8s.
+	Export string
+	// MountOpts are passed to the mounter when the export is mounted.
+	MountOpts map[string]interface{}
+	// WorkDir is where the controller mounts the export.
+	WorkDir string
+
+	// DirMode is the mode of the created directories, 0777 when it is
+	// zero, so that the pods of any user can write to their volumes.
+	DirMode os.FileMode
+	// ArchiveOnDelete renames the directories of deleted volumes to
+	// archived-<name>-<time> instead of removing them with their data.
+	ArchiveOnDelete bool
+}
+
+// CapacityRange is the size a volume is requested with. The directories
+// have no quota; RequiredBytes is reported back as the capacity.
+type CapacityRange struct {
+	RequiredBytes int64
+	LimitBytes    int64
+}
+
+type CreateVolumeRequest struct {
+	Name          string
+	CapacityRange *CapacityRange
+	Parameters    map[string]string
+}
+
+// Volume is a provisioned volume. VolumeID is the path of its directory
+// below the export. VolumeContext carries the source that the node mounts
+// the volume from.
+type Volume struct {
+	VolumeID      string
+	CapacityBytes int64
+	VolumeContext map[string]string
+}
+
+type CreateVolumeResponse struct {
+	Volume Volume
+	Err    string
+}
+
+type DeleteVolumeRequest struct {
+	VolumeID string
+}
+
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -o ../csicontrollerfakes/fake_controller.go . Controller
+type Controller interface {
+	// CreateVolume creates the directory of the volume. Creating a volume
+	// again returns the existing volume.
+	CreateVolume(env dockerdriver.Env, createRequest CreateVolumeRequest) CreateVolumeResponse
+	// DeleteVolume removes or archives the directory of the volume.
+	// Deleting a volume that does not exist succeeds.
+	DeleteVolume(env dockerdriver.Env, deleteRequest DeleteVolumeRequest) dockerdriver.ErrorResponse
+}
+
+// SubdirController provisions the volumes as subdirectories of one export.
+type SubdirController struct {
+	config  Config
+	mounter volumedriver.Mounter
+	os      osshim.Os
+	time    timeshim.Time
+
+	// lock serializes the mount of the export and the changes below it
+	lock sync.Mutex
+}
+
+// NewController returns a Controller that mounts the export with mounter.
+// It also serves as the Provisioner of volumes created with the provision
+// opt, which then names the parent directory. time dates the archives of
+// deleted volumes.
+func NewController(config Config, mounter volumedriver.Mounter, os osshim.Os, time timeshim.Time) (*SubdirController, error) {
+	if config.Export == "" {
+		return nil, errors.New("no export to provision volumes on")
+	}
+	if !filepath.IsAbs(config.WorkDir) {
+		return nil, fmt.Errorf("invalid work dir '%s', must be an absolute path", config.WorkDir)
+	}
+	if config.DirMode == 0 {
+		config.DirMode = defaultDirMode
+	}
+	config.Export = strings.TrimSuffix(config.Export, "/")
+
+	return &SubdirController{
+		config:  config,
+		mounter: mounter,
+		os:      os,
+		time:    time,
+	}, nil
+}
+
+var _ Controller = &SubdirController{}
+var _ volumedriver.Provisioner = &SubdirController{}
+
+func (c *SubdirController) CreateVolume(env dockerdriver.Env, createRequest CreateVolumeRequest) CreateVolumeResponse {
+	logger := env.Logger().Session("create-volume", lager.Data{"name": createRequest.Name, "parameters": createRequest.Parameters})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	volumeID, err := volumeIDOf(createRequest.Name, createRequest.Parameters[ParentParameter])
+	if err != nil {
+		return CreateVolumeResponse{Err: err.Error()}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if err := c.mountExport(env); err != nil {
+		logger.Error("mount-export-failed", err)
+		return CreateVolumeResponse{Err: fmt.Sprintf("Error mounting export '%s': %s", c.config.Export, err.Error())}
+	}
+
+	dir := c.dirOf(volumeID)
+	if info, err := c.os.Stat(dir); err == nil {
+		if !info.IsDir() {
+			return CreateVolumeResponse{Err: fmt.Sprintf("Volume '%s' is in the way of a file", volumeID)}
+		}
+		logger.Info("volume-exists", lager.Data{"volume-id": volumeID})
+	} else {
+		if err := c.os.MkdirAll(dir, c.config.DirMode); err != nil {
+			logger.Error("mkdir-failed", err)
+			return CreateVolumeResponse{Err: fmt.Sprintf("Error creating volume '%s': %s", volumeID, err.Error())}
+		}
+		// the mode of MkdirAll is subject to the umask
+		if err := c.os.Chmod(dir, c.config.DirMode); err != nil {
+			logger.Error("chmod-failed", err)
+			return CreateVolumeResponse{Err: fmt.Sprintf("Error creating volume '%s': %s", volumeID, err.Error())}
+		}
+		logger.Info("volume-created", lager.Data{"volume-id": volumeID})
+	}
+
+	var capacity int64
+	if createRequest.CapacityRange != nil {
+		capacity = createRequest.CapacityRange.RequiredBytes
+	}
+	return CreateVolumeResponse{Volume: Volume{
+		VolumeID:      volumeID,
+		CapacityBytes: capacity,
+		VolumeContext: map[string]string{SourceContext: c.config.Export + "/" + volumeID},
+	}}
+}
+
+func (c *SubdirController) DeleteVolume(env dockerdriver.Env, deleteRequest DeleteVolumeRequest) dockerdriver.ErrorResponse {
+	logger := env.Logger().Session("delete-volume", lager.Data{"volume-id": deleteRequest.VolumeID})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	if err := validVolumeID(deleteRequest.VolumeID); err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if err := c.mountExport(env); err != nil {
+		logger.Error("mount-export-failed", err)
+		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("Error mounting export '%s': %s", c.config.Export, err.Error())}
+	}
+
+	dir := c.dirOf(deleteRequest.VolumeID)
+	if _, err := c.os.Stat(dir); os.IsNotExist(err) {
+		logger.Info("volume-not-found")
+		return dockerdriver.ErrorResponse{}
+	}
+
+	if c.config.ArchiveOnDelete {
+		archived := c.archiveDirOf(dir)
+		if err := c.os.Rename(dir, archived); err != nil {
+			logger.Error("archive-failed", err)
+			return dockerdriver.ErrorResponse{Err: fmt.Sprintf("Error archiving volume '%s': %s", deleteRequest.VolumeID, err.Error())}
+		}
+		logger.Info("volume-archived", lager.Data{"archived": archived})
+		return dockerdriver.ErrorResponse{}
+	}
+
+	if err := c.os.RemoveAll(dir); err != nil {
+		logger.Error("remove-failed", err)
+		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("Error deleting volume '%s': %s", deleteRequest.VolumeID, err.Error())}
+	}
+	logger.Info("volume-deleted")
+	return dockerdriver.ErrorResponse{}
+}
+
+// Provision creates the directory of a volume created with the provision
+// opt below the parent the opt names, and returns its source. The size is
+// not enforced, and the clients are those of the export.
+func (c *SubdirController) Provision(env dockerdriver.Env, request volumedriver.ProvisionRequest) (string, error) {
+	createResponse := c.CreateVolume(env, CreateVolumeRequest{
+		Name:          request.Volume,
+		CapacityRange: &CapacityRange{RequiredBytes: int64(request.Size)},
+		Parameters:    map[string]string{ParentParameter: request.Parent},
+	})
+	if createResponse.Err != "" {
+		return "", errors.New(createResponse.Err)
+	}
+	return createResponse.Volume.VolumeContext[SourceContext], nil
+}
+
+// mountExport must be called with lock held. The export stays mounted for
+// the next request, and is mounted again when the mount went away.
+func (c *SubdirController) mountExport(env dockerdriver.Env) error {
+	if c.mounter.Check(env, "csi-controller", c.config.WorkDir, volumedriver.CheckStat) {
+		return nil
+	}
+
+	if err := c.os.MkdirAll(c.config.WorkDir, 0700); err != nil {
+		return err
+	}
+
+	opts := map[string]interface{}{}
+	for k, v := range c.config.MountOpts {
+		opts[k] = v
+	}
+	return c.mounter.Mount(env, c.config.Export, c.config.WorkDir, opts)
+}
+
+func (c *SubdirController) dirOf(volumeID string) string {
+	return filepath.Join(c.config.WorkDir, filepath.FromSlash(volumeID))
+}
+
+// archiveDirOf must be called with lock held. It returns where the
+// directory of a deleted volume is archived: archived-<name>-<time> next to
+// it, with a counter appended when that is taken. Long names are shortened
+// to keep the archive a valid directory name.
+func (c *SubdirController) archiveDirOf(dir string) string {
+	name := filepath.Base(dir)
+	stamp := "-" + c.time.Now().UTC().Format(archiveTimeFormat)
+	// leave room for the counter
+	if max := maxNameLength - len(ArchivePrefix) - len(stamp) - len("-999"); len(name) > max {
+		name = name[:max]
+	}
+
+	base := filepath.Join(filepath.Dir(dir), ArchivePrefix+name+stamp)
+	archived := base
+	for i := 1; ; i++ {
+		if _, err := c.os.Stat(archived); err != nil {
+			return archived
+		}
+		archived = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// volumeIDOf returns the path of the directory of a volume below the export.
+func volumeIDOf(name string, parent string) (string, error) {
+	if err := validSegment(name); err != nil {
+		return "", fmt.Errorf("invalid volume name '%s', %s", name, err.Error())
+	}
+	if strings.HasPrefix(name, ArchivePrefix) {
+		return "", fmt.Errorf("invalid volume name '%s', must not start with '%s'", name, ArchivePrefix)
+	}
+	parent = strings.Trim(parent, "/")
+	if parent == "" {
+		return name, nil
+	}
+	for _, segment := range strings.Split(parent, "/") {
+		if err := validSegment(segment); err != nil {
+			return "", fmt.Errorf("invalid %s '%s', %s", ParentParameter, parent, err.Error())
+		}
+	}
+	return parent + "/" + name, nil
+}
+
+// validVolumeID refuses the IDs that CreateVolume would not return, so that
+// DeleteVolume never reaches beyond the export or into its archives. Only
+// '/' separates the parent from the name; '\' and '..' are refused.
+func validVolumeID(volumeID string) error {
+	if volumeID == "" || path.IsAbs(volumeID) || strings.Contains(volumeID, `\`) || path.Clean(volumeID) != volumeID {
+		return fmt.Errorf("invalid volume id '%s'", volumeID)
+	}
+	segments := strings.Split(volumeID, "/")
+	for _, segment := range segments {
+		if segment == ".." || validSegment(segment) != nil {
+			return fmt.Errorf("invalid volume id '%s'", volumeID)
+		}
+	}
+	if strings.HasPrefix(segments[len(segments)-1], ArchivePrefix) {
+		return fmt.Errorf("invalid volume id '%s'", volumeID)
+	}
+	return nil
+}
+
+func validSegment(segment string) error {
+	if len(segment) > maxNameLength {
+		return fmt.Errorf("must not be longer than %d characters", maxNameLength)
+	}
+	if !validName.MatchString(segment) {
+		return errors.New("must consist of letters, digits, '_', '.' and '-', and not start with '.' or '-'")
+	}
+	return nil
+}
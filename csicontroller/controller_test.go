@@ -0,0 +1,223 @@
+package csicontroller_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/goshims/osshim"
+	"code.cloudfoundry.org/goshims/timeshim/time_fake"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/csicontroller"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Controller", func() {
+	var (
+		env        dockerdriver.Env
+		workDir    string
+		mounter    *volumedriverfakes.FakeMounter
+		fakeTime   *time_fake.FakeTime
+		config     csicontroller.Config
+		controller *csicontroller.SubdirController
+	)
+
+	BeforeEach(func() {
+		env = driverhttp.NewHttpDriverEnv(lagertest.NewTestLogger("csicontroller"), context.TODO())
+
+		dir, err := ioutil.TempDir("", "csicontroller")
+		Expect(err).NotTo(HaveOccurred())
+		workDir = filepath.Join(dir, "export")
+
+		fakeTime = &time_fake.FakeTime{}
+		fakeTime.NowReturns(time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC))
+
+		// the export is mounted until the test fails a mount
+		mounter = &volumedriverfakes.FakeMounter{}
+		mounter.MountStub = func(dockerdriver.Env, string, string, map[string]interface{}) error {
+			mounter.CheckReturns(true)
+			return nil
+		}
+
+		config = csicontroller.Config{
+			Export:    "nfs.example.com:/exports/k8s/",
+			MountOpts: map[string]interface{}{"nfsvers": "4.1"},
+			WorkDir:   workDir,
+		}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(filepath.Dir(workDir))
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		controller, err = csicontroller.NewController(config, mounter, &osshim.OsShim{}, fakeTime)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("CreateVolume", func() {
+		It("creates a directory for the volume on the export", func() {
+			response := controller.CreateVolume(env, csicontroller.CreateVolumeRequest{
+				Name:          "pvc-1",
+				CapacityRange: &csicontroller.CapacityRange{RequiredBytes: 1 << 30},
+			})
+			Expect(response.Err).To(BeEmpty())
+			Expect(response.Volume).To(Equal(csicontroller.Volume{
+				VolumeID:      "pvc-1",
+				CapacityBytes: 1 << 30,
+				VolumeContext: map[string]string{"source": "nfs.example.com:/exports/k8s/pvc-1"},
+			}))
+
+			info, err := os.Stat(filepath.Join(workDir, "pvc-1"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.IsDir()).To(BeTrue())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0777)))
+
+			Expect(mounter.MountCallCount()).To(Equal(1))
+			_, source, target, opts := mounter.MountArgsForCall(0)
+			Expect(source).To(Equal("nfs.example.com:/exports/k8s"))
+			Expect(target).To(Equal(workDir))
+			Expect(opts).To(Equal(map[string]interface{}{"nfsvers": "4.1"}))
+		})
+
+		It("returns the existing volume when it is created again", func() {
+			Expect(controller.CreateVolume(env, csicontroller.CreateVolumeRequest{Name: "pvc-1"}).Err).To(BeEmpty())
+			Expect(ioutil.WriteFile(filepath.Join(workDir, "pvc-1", "data"), []byte("data"), 0600)).To(Succeed())
+
+			response := controller.CreateVolume(env, csicontroller.CreateVolumeRequest{Name: "pvc-1"})
+			Expect(response.Err).To(BeEmpty())
+			Expect(response.Volume.VolumeID).To(Equal("pvc-1"))
+			Expect(filepath.Join(workDir, "pvc-1", "data")).To(BeAnExistingFile())
+
+			Expect(mounter.MountCallCount()).To(Equal(1))
+		})
+
+		It("creates the volume below the parent directory", func() {
+			response := controller.CreateVolume(env, csicontroller.CreateVolumeRequest{
+				Name:       "pvc-1",
+				Parameters: map[string]string{"parent": "/team-a/apps/"},
+			})
+			Expect(response.Err).To(BeEmpty())
+			Expect(response.Volume.VolumeID).To(Equal("team-a/apps/pvc-1"))
+			Expect(response.Volume.VolumeContext).To(HaveKeyWithValue("source", "nfs.example.com:/exports/k8s/team-a/apps/pvc-1"))
+			Expect(filepath.Join(workDir, "team-a", "apps", "pvc-1")).To(BeADirectory())
+		})
+
+		It("refuses names that leave the export", func() {
+			Expect(controller.CreateVolume(env, csicontroller.CreateVolumeRequest{Name: ".."}).Err).To(HavePrefix("invalid volume name '..'"))
+			Expect(controller.CreateVolume(env, csicontroller.CreateVolumeRequest{Name: "a/b"}).Err).To(HavePrefix("invalid volume name 'a/b'"))
+			Expect(controller.CreateVolume(env, csicontroller.CreateVolumeRequest{Name: "archived-pvc-1"}).Err).To(Equal("invalid volume name 'archived-pvc-1', must not start with 'archived-'"))
+			Expect(controller.CreateVolume(env, csicontroller.CreateVolumeRequest{
+				Name:       "pvc-1",
+				Parameters: map[string]string{"parent": "team-a/../.."},
+			}).Err).To(HavePrefix("invalid parent 'team-a/../..'"))
+
+			Expect(mounter.MountCallCount()).To(Equal(0))
+		})
+
+		It("reports a file in the way of the volume", func() {
+			Expect(controller.CreateVolume(env, csicontroller.CreateVolumeRequest{Name: "pvc-1"}).Err).To(BeEmpty())
+			Expect(ioutil.WriteFile(filepath.Join(workDir, "pvc-2"), []byte("data"), 0600)).To(Succeed())
+
+			Expect(controller.CreateVolume(env, csicontroller.CreateVolumeRequest{Name: "pvc-2"}).Err).To(Equal("Volume 'pvc-2' is in the way of a file"))
+		})
+
+		Context("when the export cannot be mounted", func() {
+			BeforeEach(func() {
+				mounter.MountStub = nil
+				mounter.MountReturns(errors.New("access denied by server"))
+			})
+
+			It("reports the mount error", func() {
+				response := controller.CreateVolume(env, csicontroller.CreateVolumeRequest{Name: "pvc-1"})
+				Expect(response.Err).To(Equal("Error mounting export 'nfs.example.com:/exports/k8s': access denied by server"))
+			})
+		})
+	})
+
+	Describe("DeleteVolume", func() {
+		JustBeforeEach(func() {
+			Expect(controller.CreateVolume(env, csicontroller.CreateVolumeRequest{Name: "pvc-1"}).Err).To(BeEmpty())
+			Expect(ioutil.WriteFile(filepath.Join(workDir, "pvc-1", "data"), []byte("data"), 0600)).To(Succeed())
+		})
+
+		It("removes the directory of the volume", func() {
+			Expect(controller.DeleteVolume(env, csicontroller.DeleteVolumeRequest{VolumeID: "pvc-1"}).Err).To(BeEmpty())
+			Expect(filepath.Join(workDir, "pvc-1")).NotTo(BeAnExistingFile())
+		})
+
+		It("succeeds for volumes that do not exist", func() {
+			Expect(controller.DeleteVolume(env, csicontroller.DeleteVolumeRequest{VolumeID: "pvc-2"}).Err).To(BeEmpty())
+		})
+
+		It("refuses volume ids that leave the export", func() {
+			for _, volumeID := range []string{"", "..", "../pvc-1", "pvc-1/..", "/pvc-1", "team-a/../../pvc-1", "team-a//pvc-1", `..\pvc-1`, `team-a\pvc-1`} {
+				Expect(controller.DeleteVolume(env, csicontroller.DeleteVolumeRequest{VolumeID: volumeID}).Err).To(Equal("invalid volume id '" + volumeID + "'"))
+			}
+			Expect(filepath.Join(workDir, "pvc-1")).To(BeADirectory())
+		})
+
+		Context("when volumes are archived on delete", func() {
+			BeforeEach(func() {
+				config.ArchiveOnDelete = true
+			})
+
+			archives := func() []string {
+				matches, err := filepath.Glob(filepath.Join(workDir, "archived-pvc-1-*"))
+				Expect(err).NotTo(HaveOccurred())
+				return matches
+			}
+
+			It("keeps the data of the volume", func() {
+				Expect(controller.DeleteVolume(env, csicontroller.DeleteVolumeRequest{VolumeID: "pvc-1"}).Err).To(BeEmpty())
+				Expect(filepath.Join(workDir, "pvc-1")).NotTo(BeAnExistingFile())
+				Expect(archives()).To(HaveLen(1))
+				Expect(filepath.Base(archives()[0])).To(Equal("archived-pvc-1-20261017T093000Z"))
+				Expect(filepath.Join(archives()[0], "data")).To(BeAnExistingFile())
+			})
+
+			It("keeps the archive when the volume is deleted again", func() {
+				Expect(controller.DeleteVolume(env, csicontroller.DeleteVolumeRequest{VolumeID: "pvc-1"}).Err).To(BeEmpty())
+				Expect(controller.CreateVolume(env, csicontroller.CreateVolumeRequest{Name: "pvc-1"}).Err).To(BeEmpty())
+				Expect(controller.DeleteVolume(env, csicontroller.DeleteVolumeRequest{VolumeID: "pvc-1"}).Err).To(BeEmpty())
+
+				Expect(archives()).To(HaveLen(2))
+			})
+
+			It("refuses to delete the archives", func() {
+				Expect(controller.DeleteVolume(env, csicontroller.DeleteVolumeRequest{VolumeID: "pvc-1"}).Err).To(BeEmpty())
+				archived := filepath.Base(archives()[0])
+				Expect(controller.DeleteVolume(env, csicontroller.DeleteVolumeRequest{VolumeID: archived}).Err).To(Equal("invalid volume id '" + archived + "'"))
+				Expect(archives()).To(HaveLen(1))
+			})
+		})
+	})
+
+	Describe("Provision", func() {
+		It("returns the source of the volume below the parent", func() {
+			source, err := controller.Provision(env, volumedriver.ProvisionRequest{Volume: "vol", Parent: "cf", Size: 1024})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(source).To(Equal("nfs.example.com:/exports/k8s/cf/vol"))
+			Expect(filepath.Join(workDir, "cf", "vol")).To(BeADirectory())
+		})
+	})
+
+	Describe("NewController", func() {
+		It("requires an export and an absolute work dir", func() {
+			_, err := csicontroller.NewController(csicontroller.Config{WorkDir: "/var/vcap/data/csi"}, mounter, &osshim.OsShim{}, fakeTime)
+			Expect(err).To(MatchError("no export to provision volumes on"))
+
+			_, err = csicontroller.NewController(csicontroller.Config{Export: "server:/export", WorkDir: "csi"}, mounter, &osshim.OsShim{}, fakeTime)
+			Expect(err).To(MatchError("invalid work dir 'csi', must be an absolute path"))
+		})
+	})
+})
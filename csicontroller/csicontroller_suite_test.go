@@ -0,0 +1,13 @@
+package csicontroller_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestCsiController(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "CsiController Suite")
+}
@@ -0,0 +1,96 @@
+package volumedriver
+
+import (
+	"runtime"
+	"sort"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+)
+
+type DebugVolume struct {
+	Name           string
+	Mountpoint     string
+	MountDirectory string
+	MountCount     int
+	MountError     string `json:",omitempty"`
+	MountErrorTime time.Time
+}
+
+type DebugStateResponse struct {
+	Volumes         []DebugVolume
+	PendingPersists []PendingPersist `json:",omitempty"`
+	Err             string           `json:",omitempty"`
+	Lock            LockStats
+	InFlight        []InFlightOperation
+	Goroutines      int
+}
+
+//go:generate counterfeiter -o volumedriverfakes/fake_debugger.go . Debugger
+type Debugger interface {
+	DebugState(env dockerdriver.Env) DebugStateResponse
+}
+
+// debugLockTimeout bounds how long DebugState waits for the volume lock.
+const debugLockTimeout = time.Second
+
+// DebugState dumps the in-memory state of the driver. The lock statistics
+// and in-flight operations do not need the volume lock, so they are still
+// reported when a request is stuck holding it.
+func (d *VolumeDriver) DebugState(env dockerdriver.Env) DebugStateResponse {
+	logger := env.Logger().Session("debug-state")
+	logger.Info("start")
+	defer logger.Info("end")
+
+	debugResponse := DebugStateResponse{
+		Volumes:    []DebugVolume{},
+		Lock:       d.volumesLock.Stats(),
+		InFlight:   d.inFlight.list(),
+		Goroutines: runtime.NumGoroutine(),
+	}
+
+	volumes := make(chan DebugStateResponse, 1)
+	go func() {
+		volumes <- d.debugVolumes()
+	}()
+
+	select {
+	case locked := <-volumes:
+		debugResponse.Volumes = locked.Volumes
+		debugResponse.PendingPersists = locked.PendingPersists
+	case <-time.After(debugLockTimeout):
+		logger.Info("volume-lock-timeout")
+		debugResponse.Err = "timed out waiting for the volume lock"
+	}
+
+	return debugResponse
+}
+
+// debugVolumes returns the parts of the debug state that need the volume
+// lock.
+func (d *VolumeDriver) debugVolumes() DebugStateResponse {
+	d.volumesLock.RLock()
+	defer d.volumesLock.RUnlock()
+
+	volumes := []DebugVolume{}
+	for _, volume := range d.volumes {
+		volumes = append(volumes, DebugVolume{
+			Name:           volume.Name,
+			Mountpoint:     volume.Mountpoint,
+			MountDirectory: volume.MountDirectory,
+			MountCount:     volume.MountCount,
+			MountError:     volume.mountError,
+			MountErrorTime: volume.mountErrorTime,
+		})
+	}
+	sort.Slice(volumes, func(i, j int) bool {
+		return volumes[i].Name < volumes[j].Name
+	})
+
+	var pending []PendingPersist
+	if len(d.persistRetries) > 0 {
+		pending = d.pendingPersists()
+	}
+
+	return DebugStateResponse{Volumes: volumes, PendingPersists: pending}
+}
@@ -0,0 +1,13 @@
+package debughttp_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestDebugHttp(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "DebugHttp Suite")
+}
@@ -0,0 +1,44 @@
+package debughttp
+
+import (
+	"net/http"
+	"net/http/pprof"
+
+	cf_http_handlers "code.cloudfoundry.org/cfhttp/handlers"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+)
+
+const (
+	PprofPath = "/debug/pprof/"
+	StatePath = "/debug/state"
+)
+
+// NewHandler serves the runtime profiles under /debug/pprof/ and a dump of
+// the driver state under /debug/state. It exposes internals of the process
+// and must only be served on a local or otherwise protected address.
+func NewHandler(logger lager.Logger, debugger volumedriver.Debugger) http.Handler {
+	logger = logger.Session("debug-server")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(PprofPath, pprof.Index)
+	mux.HandleFunc(PprofPath+"cmdline", pprof.Cmdline)
+	mux.HandleFunc(PprofPath+"profile", pprof.Profile)
+	mux.HandleFunc(PprofPath+"symbol", pprof.Symbol)
+	mux.HandleFunc(PprofPath+"trace", pprof.Trace)
+	mux.HandleFunc(StatePath, newStateHandler(logger, debugger))
+
+	return mux
+}
+
+func newStateHandler(logger lager.Logger, debugger volumedriver.Debugger) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		logger := logger.Session("handle-debug-state")
+		logger.Info("start")
+		defer logger.Info("end")
+
+		debugResponse := debugger.DebugState(driverhttp.EnvWithMonitor(logger, req.Context(), w))
+		cf_http_handlers.WriteJSONResponse(w, http.StatusOK, debugResponse)
+	}
+}
@@ -0,0 +1,51 @@
+package debughttp_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/debughttp"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Debug Handlers", func() {
+	var (
+		fakeDebugger *volumedriverfakes.FakeDebugger
+		handler      http.Handler
+		recorder     *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		fakeDebugger = &volumedriverfakes.FakeDebugger{}
+		handler = debughttp.NewHandler(lagertest.NewTestLogger("debughttp-test"), fakeDebugger)
+		recorder = httptest.NewRecorder()
+	})
+
+	It("dumps the driver state", func() {
+		fakeDebugger.DebugStateReturns(volumedriver.DebugStateResponse{
+			Volumes:  []volumedriver.DebugVolume{{Name: "some-volume", MountCount: 2}},
+			Lock:     volumedriver.LockStats{Held: true, Waiting: 3},
+			InFlight: []volumedriver.InFlightOperation{{Operation: "mount", Volume: "some-volume"}},
+		})
+
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", debughttp.StatePath, nil))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+
+		var response volumedriver.DebugStateResponse
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+		Expect(response.Volumes).To(Equal([]volumedriver.DebugVolume{{Name: "some-volume", MountCount: 2}}))
+		Expect(response.Lock.Waiting).To(Equal(int64(3)))
+		Expect(response.InFlight[0].Operation).To(Equal("mount"))
+	})
+
+	It("serves the pprof index", func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", debughttp.PprofPath, nil))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(ContainSubstring("goroutine"))
+	})
+})
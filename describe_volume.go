@@ -0,0 +1,70 @@
+package volumedriver
+
+import (
+	"fmt"
+	"regexp"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+)
+
+const redacted = "REDACTED"
+
+// secretOpt matches the names of opts whose values must not be reported.
+var secretOpt = regexp.MustCompile(`(?i)(pass|secret|token|key|credential)`)
+
+// DescribeVolume reports the mount history and effective opts of a volume,
+// so that bindings can be debugged without access to the host.
+func (d *VolumeDriver) DescribeVolume(env dockerdriver.Env, describeRequest DescribeVolumeRequest) DescribeVolumeResponse {
+	logger := env.Logger().Session("describe-volume", lager.Data{"volume": describeRequest.Name})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	if describeRequest.Name == "" {
+		return DescribeVolumeResponse{Err: "Missing mandatory 'volume_name'"}
+	}
+
+	d.volumesLock.RLock()
+	defer d.volumesLock.RUnlock()
+
+	volume, ok := d.volumes[describeRequest.Name]
+	if !ok {
+		return DescribeVolumeResponse{Err: fmt.Sprintf("Volume '%s' not found", describeRequest.Name)}
+	}
+
+	return DescribeVolumeResponse{
+		Volume: VolumeDescription{
+			Name:             volume.Name,
+			Mountpoint:       volume.Mountpoint,
+			MountCount:       volume.MountCount,
+			Driver:           volume.Driver,
+			Opts:             redactOpts(volume.Opts),
+			Labels:           volume.Labels,
+			ExpiresAt:        volume.ExpiresAt,
+			MountError:       volume.mountError,
+			LastMountedAt:    volume.LastMountedAt,
+			LastUnmountedAt:  volume.LastUnmountedAt,
+			LastIOAt:         volume.LastIOAt,
+			LastMountError:   volume.LastMountError,
+			LastMountErrorAt: volume.LastMountErrorAt,
+			Frozen:           volume.Frozen,
+			References:       copyReferences(volume.References),
+			Binds:            copyBinds(volume.Binds),
+		},
+	}
+}
+
+func redactOpts(opts map[string]interface{}) map[string]interface{} {
+	if opts == nil {
+		return nil
+	}
+
+	redactedOpts := map[string]interface{}{}
+	for k, v := range opts {
+		if secretOpt.MatchString(k) {
+			v = redacted
+		}
+		redactedOpts[k] = v
+	}
+	return redactedOpts
+}
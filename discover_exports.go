@@ -0,0 +1,108 @@
+package volumedriver
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager"
+)
+
+// Export is a directory that a server exports. Clients are the hosts and
+// networks the server lets mount it, when the server reports them.
+type Export struct {
+	Path    string
+	Source  string   // host:/path, as passed in the source opt
+	Clients []string `json:",omitempty"`
+}
+
+//go:generate counterfeiter -o volumedriverfakes/fake_export_discoverer.go . ExportDiscoverer
+type ExportDiscoverer interface {
+	// Exports lists the exports of server, which is a host name or an
+	// address.
+	Exports(env dockerdriver.Env, server string) ([]Export, error)
+}
+
+// serverName matches host names and IPv4 addresses. IPv6 addresses are
+// checked with net.ParseIP.
+var serverName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9.-]*$`)
+
+// DiscoverExports lists the exports of a server, so that sources can be
+// checked before volumes are created from them. With AllowedSources set,
+// only servers and exports the rules allow are listed.
+func (d *VolumeDriver) DiscoverExports(env dockerdriver.Env, discoverRequest DiscoverExportsRequest) DiscoverExportsResponse {
+	logger := env.Logger().Session("discover-exports", lager.Data{"server": discoverRequest.Server})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	server := strings.TrimSuffix(strings.TrimPrefix(discoverRequest.Server, "["), "]")
+	if server == "" {
+		return DiscoverExportsResponse{Err: "Missing mandatory 'Server'"}
+	}
+	if !serverName.MatchString(server) && net.ParseIP(server) == nil {
+		return DiscoverExportsResponse{Err: fmt.Sprintf("invalid server '%s', must be a host name or an address", discoverRequest.Server)}
+	}
+
+	if d.options.ExportDiscoverer == nil {
+		return DiscoverExportsResponse{Err: "Export discovery is not supported by this driver"}
+	}
+
+	rules := d.currentPolicy().AllowedSources
+	if !serverAllowed(rules, server) {
+		logger.Info("server-not-allowed")
+		return DiscoverExportsResponse{Err: fmt.Sprintf("server '%s' is not allowed on this cell", server)}
+	}
+
+	exports, err := d.options.ExportDiscoverer.Exports(driverhttp.EnvWithLogger(logger, env), server)
+	if err != nil {
+		logger.Error("discover-exports-failed", err)
+		return DiscoverExportsResponse{Err: fmt.Sprintf("Error discovering the exports of '%s': %s", server, err.Error())}
+	}
+
+	allowed := []Export{}
+	for _, export := range exports {
+		if !exportAllowed(rules, server, export.Path) {
+			continue
+		}
+		export.Source = sourceOf(server, export.Path)
+		allowed = append(allowed, export)
+	}
+
+	return DiscoverExportsResponse{Exports: allowed}
+}
+
+func serverAllowed(rules []SourceRule, server string) bool {
+	if len(rules) == 0 {
+		return true
+	}
+	for _, rule := range rules {
+		if (SourceRule{Host: rule.Host}).matches(server, "/") {
+			return true
+		}
+	}
+	return false
+}
+
+func exportAllowed(rules []SourceRule, server string, export string) bool {
+	if len(rules) == 0 {
+		return true
+	}
+	for _, rule := range rules {
+		if rule.matches(server, export) {
+			return true
+		}
+	}
+	return false
+}
+
+// sourceOf returns the source opt of an export, with IPv6 addresses in
+// brackets.
+func sourceOf(server string, export string) string {
+	if strings.Contains(server, ":") {
+		server = "[" + server + "]"
+	}
+	return server + ":" + export
+}
@@ -0,0 +1,20 @@
+// +build linux darwin
+
+package volumedriver
+
+import "syscall"
+
+type diskSpace struct{}
+
+// NewDiskSpace measures free space with statfs(2).
+func NewDiskSpace() DiskSpace {
+	return diskSpace{}
+}
+
+func (diskSpace) Free(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,34 @@
+package volumedriver
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var getDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+type diskSpace struct{}
+
+// NewDiskSpace measures free space with GetDiskFreeSpaceEx.
+func NewDiskSpace() DiskSpace {
+	return diskSpace{}
+}
+
+func (diskSpace) Free(path string) (uint64, error) {
+	dir, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var available, total, free uint64
+	ret, _, err := getDiskFreeSpaceEx.Call(
+		uintptr(unsafe.Pointer(dir)),
+		uintptr(unsafe.Pointer(&available)),
+		uintptr(unsafe.Pointer(&total)),
+		uintptr(unsafe.Pointer(&free)),
+	)
+	if ret == 0 {
+		return 0, err
+	}
+	return available, nil
+}
@@ -0,0 +1,180 @@
+package driverclient
+
+import (
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/goshims/http_wrap"
+	"code.cloudfoundry.org/volumedriver"
+)
+
+// AdminClient calls the admin endpoints served by adminhttp.
+type AdminClient struct {
+	caller
+}
+
+var _ volumedriver.Admin = &AdminClient{}
+
+// NewAdminClient returns a client of the admin endpoints at addr, see
+// Endpoint.
+func NewAdminClient(addr string) (*AdminClient, error) {
+	baseURL, client, err := Endpoint(addr, DefaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return NewAdminClientWithHTTPClient(baseURL, client), nil
+}
+
+func NewAdminClientWithHTTPClient(baseURL string, client http_wrap.Client) *AdminClient {
+	return &AdminClient{caller: newCaller(baseURL, volumedriver.AdminRoutes, client)}
+}
+
+func (c *AdminClient) ResetMountError(env dockerdriver.Env, resetRequest volumedriver.ResetMountErrorRequest) dockerdriver.ErrorResponse {
+	var response dockerdriver.ErrorResponse
+	if err := c.call(env, volumedriver.ResetMountErrorRoute, resetRequest, &response); err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+	return response
+}
+
+func (c *AdminClient) ExportState(env dockerdriver.Env) volumedriver.ExportStateResponse {
+	var response volumedriver.ExportStateResponse
+	if err := c.call(env, volumedriver.ExportStateRoute, nil, &response); err != nil {
+		return volumedriver.ExportStateResponse{Err: err.Error()}
+	}
+	return response
+}
+
+func (c *AdminClient) ImportState(env dockerdriver.Env, importRequest volumedriver.ImportStateRequest) dockerdriver.ErrorResponse {
+	var response dockerdriver.ErrorResponse
+	if err := c.call(env, volumedriver.ImportStateRoute, importRequest, &response); err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+	return response
+}
+
+func (c *AdminClient) ListVolumes(env dockerdriver.Env, listRequest volumedriver.ListVolumesRequest) volumedriver.ListVolumesResponse {
+	var response volumedriver.ListVolumesResponse
+	if err := c.call(env, volumedriver.ListVolumesRoute, listRequest, &response); err != nil {
+		return volumedriver.ListVolumesResponse{Err: err.Error()}
+	}
+	return response
+}
+
+func (c *AdminClient) Clone(env dockerdriver.Env, cloneRequest volumedriver.CloneRequest) dockerdriver.ErrorResponse {
+	var response dockerdriver.ErrorResponse
+	if err := c.call(env, volumedriver.CloneRoute, cloneRequest, &response); err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+	return response
+}
+
+func (c *AdminClient) DescribeVolume(env dockerdriver.Env, describeRequest volumedriver.DescribeVolumeRequest) volumedriver.DescribeVolumeResponse {
+	var response volumedriver.DescribeVolumeResponse
+	if err := c.call(env, volumedriver.DescribeVolumeRoute, describeRequest, &response); err != nil {
+		return volumedriver.DescribeVolumeResponse{Err: err.Error()}
+	}
+	return response
+}
+
+func (c *AdminClient) FreezeVolume(env dockerdriver.Env, freezeRequest volumedriver.FreezeVolumeRequest) dockerdriver.ErrorResponse {
+	var response dockerdriver.ErrorResponse
+	if err := c.call(env, volumedriver.FreezeVolumeRoute, freezeRequest, &response); err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+	return response
+}
+
+func (c *AdminClient) ThawVolume(env dockerdriver.Env, thawRequest volumedriver.ThawVolumeRequest) dockerdriver.ErrorResponse {
+	var response dockerdriver.ErrorResponse
+	if err := c.call(env, volumedriver.ThawVolumeRoute, thawRequest, &response); err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+	return response
+}
+
+func (c *AdminClient) ImportMount(env dockerdriver.Env, importRequest volumedriver.ImportMountRequest) dockerdriver.ErrorResponse {
+	var response dockerdriver.ErrorResponse
+	if err := c.call(env, volumedriver.ImportMountRoute, importRequest, &response); err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+	return response
+}
+
+func (c *AdminClient) RemountVolume(env dockerdriver.Env, remountRequest volumedriver.RemountVolumeRequest) dockerdriver.ErrorResponse {
+	var response dockerdriver.ErrorResponse
+	if err := c.call(env, volumedriver.RemountVolumeRoute, remountRequest, &response); err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+	return response
+}
+
+func (c *AdminClient) DiscoverExports(env dockerdriver.Env, discoverRequest volumedriver.DiscoverExportsRequest) volumedriver.DiscoverExportsResponse {
+	var response volumedriver.DiscoverExportsResponse
+	if err := c.call(env, volumedriver.DiscoverExportsRoute, discoverRequest, &response); err != nil {
+		return volumedriver.DiscoverExportsResponse{Err: err.Error()}
+	}
+	return response
+}
+
+func (c *AdminClient) RevokeReferences(env dockerdriver.Env, revokeRequest volumedriver.RevokeReferencesRequest) volumedriver.RevokeReferencesResponse {
+	var response volumedriver.RevokeReferencesResponse
+	if err := c.call(env, volumedriver.RevokeReferencesRoute, revokeRequest, &response); err != nil {
+		return volumedriver.RevokeReferencesResponse{Err: err.Error()}
+	}
+	return response
+}
+
+func (c *AdminClient) RemoveVolume(env dockerdriver.Env, removeRequest volumedriver.RemoveVolumeRequest) dockerdriver.ErrorResponse {
+	var response dockerdriver.ErrorResponse
+	if err := c.call(env, volumedriver.RemoveVolumeRoute, removeRequest, &response); err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+	return response
+}
+
+func (c *AdminClient) InFlightOperations(env dockerdriver.Env) volumedriver.InFlightOperationsResponse {
+	var response volumedriver.InFlightOperationsResponse
+	if err := c.call(env, volumedriver.InFlightOperationsRoute, nil, &response); err != nil {
+		return volumedriver.InFlightOperationsResponse{Err: err.Error()}
+	}
+	return response
+}
+
+func (c *AdminClient) BindVolume(env dockerdriver.Env, bindRequest volumedriver.BindVolumeRequest) dockerdriver.ErrorResponse {
+	var response dockerdriver.ErrorResponse
+	if err := c.call(env, volumedriver.BindVolumeRoute, bindRequest, &response); err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+	return response
+}
+
+func (c *AdminClient) UnbindVolume(env dockerdriver.Env, unbindRequest volumedriver.UnbindVolumeRequest) dockerdriver.ErrorResponse {
+	var response dockerdriver.ErrorResponse
+	if err := c.call(env, volumedriver.UnbindVolumeRoute, unbindRequest, &response); err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+	return response
+}
+
+func (c *AdminClient) ListSnapshots(env dockerdriver.Env) volumedriver.ListSnapshotsResponse {
+	var response volumedriver.ListSnapshotsResponse
+	if err := c.call(env, volumedriver.ListSnapshotsRoute, nil, &response); err != nil {
+		return volumedriver.ListSnapshotsResponse{Err: err.Error()}
+	}
+	return response
+}
+
+func (c *AdminClient) RestoreSnapshot(env dockerdriver.Env, restoreRequest volumedriver.RestoreSnapshotRequest) dockerdriver.ErrorResponse {
+	var response dockerdriver.ErrorResponse
+	if err := c.call(env, volumedriver.RestoreSnapshotRoute, restoreRequest, &response); err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+	return response
+}
+
+func (c *AdminClient) Drain(env dockerdriver.Env) volumedriver.DrainResponse {
+	var response volumedriver.DrainResponse
+	if err := c.call(env, volumedriver.DrainRoute, nil, &response); err != nil {
+		return volumedriver.DrainResponse{Err: err.Error()}
+	}
+	return response
+}
@@ -0,0 +1,236 @@
+// Package driverclient calls a running driver over its plugin API, its
+// admin endpoints, its event stream and its debug endpoints. It speaks the
+// request and response types of packages dockerdriver and volumedriver, so
+// that brokers and the rep can integrate without copying them, and every
+// client implements the interface its endpoints are served from.
+package driverclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/goshims/http_wrap"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/validatehttp"
+	"github.com/tedsuo/rata"
+)
+
+// DefaultTimeout bounds the requests of the clients returned by the New
+// functions. Drain waits for unmounts, so it is generous.
+const DefaultTimeout = 5 * time.Minute
+
+// ValidateRoute is the route of Validate, which the plugin API routes of
+// dockerdriver do not know about.
+const ValidateRoute = "validate"
+
+// DriverRoutes are the routes of the plugin API, with Validate.
+var DriverRoutes = append(append(rata.Routes{}, dockerdriver.Routes...),
+	rata.Route{Path: validatehttp.ValidatePath, Method: "POST", Name: ValidateRoute},
+)
+
+// Endpoint returns the base URL of addr, which is unix:///path/to.sock,
+// tcp://host:port, http://host:port or https://host:port, and an http client
+// that reaches it. Zero timeout does not bound requests.
+func Endpoint(addr string, timeout time.Duration) (string, *http.Client, error) {
+	switch {
+	case strings.HasPrefix(addr, "unix://"):
+		socket := strings.TrimPrefix(addr, "unix://")
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		}
+		return "http://unix", &http.Client{Transport: transport, Timeout: timeout}, nil
+	case strings.HasPrefix(addr, "tcp://"):
+		return "http://" + strings.TrimPrefix(addr, "tcp://"), &http.Client{Timeout: timeout}, nil
+	case strings.HasPrefix(addr, "http://"), strings.HasPrefix(addr, "https://"):
+		return strings.TrimSuffix(addr, "/"), &http.Client{Timeout: timeout}, nil
+	case addr == "":
+		return "", nil, errors.New("no address given")
+	default:
+		return "", nil, fmt.Errorf("invalid address '%s', must be unix://, tcp://, http:// or https://", addr)
+	}
+}
+
+// caller sends requests to the routes of one server.
+type caller struct {
+	reqGen     *rata.RequestGenerator
+	httpClient http_wrap.Client
+}
+
+func newCaller(baseURL string, routes rata.Routes, client http_wrap.Client) caller {
+	return caller{
+		reqGen:     rata.NewRequestGenerator(strings.TrimSuffix(baseURL, "/"), routes),
+		httpClient: client,
+	}
+}
+
+// call sends request as JSON to route and decodes the response into
+// response. The servers report errors in the Err of a response with a 200
+// status, other statuses are errors of the transport.
+func (c caller) call(env dockerdriver.Env, route string, request interface{}, response interface{}) error {
+	logger := env.Logger().Session("driverclient-" + route)
+	logger.Info("start")
+	defer logger.Info("end")
+
+	// requests without a payload have an empty body, like those of the
+	// remote client of dockerdriver
+	var body io.Reader
+	if request != nil {
+		payload, err := json.Marshal(request)
+		if err != nil {
+			logger.Error("failed-marshalling-request", err)
+			return err
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	httpRequest, err := c.reqGen.CreateRequest(route, nil, body)
+	if err != nil {
+		logger.Error("failed-creating-request", err)
+		return err
+	}
+	if body != nil {
+		httpRequest.Header.Set("Content-Type", "application/json")
+	}
+
+	httpResponse, err := c.httpClient.Do(httpRequest.WithContext(env.Context()))
+	if err != nil {
+		logger.Error("failed-sending-request", err)
+		return err
+	}
+	defer httpResponse.Body.Close()
+
+	data, err := ioutil.ReadAll(httpResponse.Body)
+	if err != nil {
+		logger.Error("failed-reading-response", err)
+		return err
+	}
+	if httpResponse.StatusCode != http.StatusOK {
+		err := fmt.Errorf("%s %s: %s: %s", httpRequest.Method, httpRequest.URL.Path, httpResponse.Status, strings.TrimSpace(string(data)))
+		logger.Error("failed-request", err)
+		return err
+	}
+
+	if err := json.Unmarshal(data, response); err != nil {
+		logger.Error("failed-parsing-response", err)
+		return fmt.Errorf("invalid response from %s: %s", httpRequest.URL.Path, err.Error())
+	}
+	return nil
+}
+
+// Client calls the plugin API, and Validate, which is served next to it by
+// validatehttp. Unlike the remote client of dockerdriver, it reports the
+// errors of every response, Create included.
+type Client struct {
+	caller
+}
+
+var _ dockerdriver.Driver = &Client{}
+var _ volumedriver.Validator = &Client{}
+
+// NewClient returns a client of the plugin API at addr, see Endpoint.
+func NewClient(addr string) (*Client, error) {
+	baseURL, client, err := Endpoint(addr, DefaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return NewClientWithHTTPClient(baseURL, client), nil
+}
+
+func NewClientWithHTTPClient(baseURL string, client http_wrap.Client) *Client {
+	return &Client{caller: newCaller(baseURL, DriverRoutes, client)}
+}
+
+func (c *Client) Activate(env dockerdriver.Env) dockerdriver.ActivateResponse {
+	var response dockerdriver.ActivateResponse
+	if err := c.call(env, dockerdriver.ActivateRoute, nil, &response); err != nil {
+		return dockerdriver.ActivateResponse{Err: err.Error()}
+	}
+	return response
+}
+
+func (c *Client) Create(env dockerdriver.Env, createRequest dockerdriver.CreateRequest) dockerdriver.ErrorResponse {
+	var response dockerdriver.ErrorResponse
+	if err := c.call(env, dockerdriver.CreateRoute, createRequest, &response); err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+	return response
+}
+
+func (c *Client) Get(env dockerdriver.Env, getRequest dockerdriver.GetRequest) dockerdriver.GetResponse {
+	var response dockerdriver.GetResponse
+	if err := c.call(env, dockerdriver.GetRoute, getRequest, &response); err != nil {
+		return dockerdriver.GetResponse{Err: err.Error()}
+	}
+	return response
+}
+
+func (c *Client) List(env dockerdriver.Env) dockerdriver.ListResponse {
+	var response dockerdriver.ListResponse
+	if err := c.call(env, dockerdriver.ListRoute, nil, &response); err != nil {
+		return dockerdriver.ListResponse{Err: err.Error()}
+	}
+	return response
+}
+
+func (c *Client) Mount(env dockerdriver.Env, mountRequest dockerdriver.MountRequest) dockerdriver.MountResponse {
+	var response dockerdriver.MountResponse
+	if err := c.call(env, dockerdriver.MountRoute, mountRequest, &response); err != nil {
+		return dockerdriver.MountResponse{Err: err.Error()}
+	}
+	return response
+}
+
+func (c *Client) Path(env dockerdriver.Env, pathRequest dockerdriver.PathRequest) dockerdriver.PathResponse {
+	var response dockerdriver.PathResponse
+	if err := c.call(env, dockerdriver.PathRoute, pathRequest, &response); err != nil {
+		return dockerdriver.PathResponse{Err: err.Error()}
+	}
+	return response
+}
+
+func (c *Client) Unmount(env dockerdriver.Env, unmountRequest dockerdriver.UnmountRequest) dockerdriver.ErrorResponse {
+	var response dockerdriver.ErrorResponse
+	if err := c.call(env, dockerdriver.UnmountRoute, unmountRequest, &response); err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+	return response
+}
+
+func (c *Client) Remove(env dockerdriver.Env, removeRequest dockerdriver.RemoveRequest) dockerdriver.ErrorResponse {
+	var response dockerdriver.ErrorResponse
+	if err := c.call(env, dockerdriver.RemoveRoute, removeRequest, &response); err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+	return response
+}
+
+// Capabilities has no room for an error; failures are logged and report
+// no scope.
+func (c *Client) Capabilities(env dockerdriver.Env) dockerdriver.CapabilitiesResponse {
+	var response dockerdriver.CapabilitiesResponse
+	if err := c.call(env, dockerdriver.CapabilitiesRoute, nil, &response); err != nil {
+		return dockerdriver.CapabilitiesResponse{}
+	}
+	return response
+}
+
+func (c *Client) Validate(env dockerdriver.Env, validateRequest volumedriver.ValidateRequest) volumedriver.ValidateResponse {
+	var response volumedriver.ValidateResponse
+	if err := c.call(env, ValidateRoute, validateRequest, &response); err != nil {
+		return volumedriver.ValidateResponse{Err: err.Error()}
+	}
+	return response
+}
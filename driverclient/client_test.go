@@ -0,0 +1,246 @@
+package driverclient_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/adminhttp"
+	"code.cloudfoundry.org/volumedriver/debughttp"
+	"code.cloudfoundry.org/volumedriver/driverclient"
+	"code.cloudfoundry.org/volumedriver/eventshttp"
+	"code.cloudfoundry.org/volumedriver/testhelpers"
+	"code.cloudfoundry.org/volumedriver/validatehttp"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DriverClient", func() {
+	var (
+		logger *lagertest.TestLogger
+		env    dockerdriver.Env
+		server *httptest.Server
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("driverclient")
+		env = driverhttp.NewHttpDriverEnv(logger, context.TODO())
+	})
+
+	AfterEach(func() {
+		if server != nil {
+			server.Close()
+		}
+	})
+
+	Describe("Client", func() {
+		var (
+			driver *testhelpers.MemoryDriver
+			client *driverclient.Client
+		)
+
+		BeforeEach(func() {
+			driver = testhelpers.NewMemoryDriver(logger)
+			handler, err := driverhttp.NewHandler(logger, driver)
+			Expect(err).NotTo(HaveOccurred())
+			server = httptest.NewServer(validatehttp.NewHandler(logger, driver, handler))
+
+			client = driverclient.NewClientWithHTTPClient(server.URL, server.Client())
+		})
+
+		It("creates, mounts and unmounts volumes", func() {
+			Expect(client.Activate(env).Implements).To(ConsistOf("VolumeDriver"))
+			Expect(client.Create(env, dockerdriver.CreateRequest{Name: "vol", Opts: map[string]interface{}{"source": "server:/export"}}).Err).To(BeEmpty())
+
+			mountResponse := client.Mount(env, dockerdriver.MountRequest{Name: "vol"})
+			Expect(mountResponse.Err).To(BeEmpty())
+			Expect(driver.Mounter.Mounts()).To(HaveKeyWithValue(mountResponse.Mountpoint, "server:/export"))
+
+			getResponse := client.Get(env, dockerdriver.GetRequest{Name: "vol"})
+			Expect(getResponse.Err).To(BeEmpty())
+			Expect(getResponse.Volume.MountCount).To(Equal(1))
+			Expect(client.Path(env, dockerdriver.PathRequest{Name: "vol"}).Mountpoint).To(Equal(mountResponse.Mountpoint))
+			Expect(client.List(env).Volumes).To(HaveLen(1))
+
+			Expect(client.Unmount(env, dockerdriver.UnmountRequest{Name: "vol"}).Err).To(BeEmpty())
+			Expect(driver.Mounter.Mounts()).To(BeEmpty())
+			Expect(client.Capabilities(env).Capabilities.Scope).To(Equal("local"))
+		})
+
+		It("reports the errors of Create", func() {
+			Expect(client.Create(env, dockerdriver.CreateRequest{Name: "vol", Opts: map[string]interface{}{}}).Err).To(Equal("Missing mandatory 'source' field in 'Opts'"))
+		})
+
+		It("validates opts", func() {
+			validateResponse := client.Validate(env, volumedriver.ValidateRequest{Name: "vol", Opts: map[string]interface{}{"source": "server:/export"}})
+			Expect(validateResponse.Err).To(BeEmpty())
+			Expect(validateResponse.Valid).To(BeTrue())
+		})
+
+		Context("when the server does not serve the plugin API", func() {
+			BeforeEach(func() {
+				server.Close()
+				server = httptest.NewServer(http.NotFoundHandler())
+				client = driverclient.NewClientWithHTTPClient(server.URL, server.Client())
+			})
+
+			It("reports the status in the response", func() {
+				Expect(client.Mount(env, dockerdriver.MountRequest{Name: "vol"}).Err).To(ContainSubstring("POST /VolumeDriver.Mount: 404 Not Found"))
+			})
+		})
+	})
+
+	Describe("AdminClient", func() {
+		var (
+			admin  *volumedriverfakes.FakeAdmin
+			client *driverclient.AdminClient
+		)
+
+		BeforeEach(func() {
+			admin = &volumedriverfakes.FakeAdmin{}
+			handler, err := adminhttp.NewHandler(logger, admin)
+			Expect(err).NotTo(HaveOccurred())
+			server = httptest.NewServer(handler)
+
+			client = driverclient.NewAdminClientWithHTTPClient(server.URL, server.Client())
+		})
+
+		It("passes requests and responses through", func() {
+			admin.DescribeVolumeReturns(volumedriver.DescribeVolumeResponse{Volume: volumedriver.VolumeDescription{Name: "vol", MountCount: 2}})
+
+			describeResponse := client.DescribeVolume(env, volumedriver.DescribeVolumeRequest{Name: "vol"})
+			Expect(describeResponse.Err).To(BeEmpty())
+			Expect(describeResponse.Volume).To(Equal(volumedriver.VolumeDescription{Name: "vol", MountCount: 2}))
+
+			Expect(admin.DescribeVolumeCallCount()).To(Equal(1))
+			_, describeRequest := admin.DescribeVolumeArgsForCall(0)
+			Expect(describeRequest).To(Equal(volumedriver.DescribeVolumeRequest{Name: "vol"}))
+		})
+
+		It("calls the endpoints without a request", func() {
+			admin.DrainReturns(volumedriver.DrainResponse{Volumes: []volumedriver.DrainedVolume{{Name: "vol", Mountpoint: "/mnt/vol"}}})
+
+			Expect(client.Drain(env).Volumes).To(ConsistOf(volumedriver.DrainedVolume{Name: "vol", Mountpoint: "/mnt/vol"}))
+			Expect(admin.DrainCallCount()).To(Equal(1))
+		})
+
+		It("reports the errors of the driver", func() {
+			admin.RemoveVolumeReturns(dockerdriver.ErrorResponse{Err: "volume is frozen"})
+
+			Expect(client.RemoveVolume(env, volumedriver.RemoveVolumeRequest{Name: "vol", Force: true}).Err).To(Equal("volume is frozen"))
+			_, removeRequest := admin.RemoveVolumeArgsForCall(0)
+			Expect(removeRequest.Force).To(BeTrue())
+		})
+	})
+
+	Describe("DebugClient", func() {
+		It("reads the driver state", func() {
+			debugger := &volumedriverfakes.FakeDebugger{}
+			debugger.DebugStateReturns(volumedriver.DebugStateResponse{Volumes: []volumedriver.DebugVolume{{Name: "vol"}}, Goroutines: 12})
+			server = httptest.NewServer(debughttp.NewHandler(logger, debugger))
+
+			client := driverclient.NewDebugClientWithHTTPClient(server.URL, server.Client())
+			debugResponse := client.DebugState(env)
+			Expect(debugResponse.Err).To(BeEmpty())
+			Expect(debugResponse.Goroutines).To(Equal(12))
+			Expect(debugResponse.Volumes).To(HaveLen(1))
+		})
+	})
+
+	Describe("EventsClient", func() {
+		var (
+			source       *volumedriverfakes.FakeEventSource
+			published    chan volumedriver.Event
+			unsubscribed chan struct{}
+			client       *driverclient.EventsClient
+		)
+
+		BeforeEach(func() {
+			published = make(chan volumedriver.Event, 1)
+			unsubscribed = make(chan struct{})
+			source = &volumedriverfakes.FakeEventSource{}
+			source.SubscribeReturns(published, func() { close(unsubscribed) })
+			server = httptest.NewServer(eventshttp.NewHandler(logger, source))
+
+			client = driverclient.NewEventsClientWithHTTPClient(logger, server.URL, server.Client())
+		})
+
+		It("receives the events of the driver", func() {
+			events, unsubscribe := client.Subscribe()
+			defer unsubscribe()
+
+			event := volumedriver.Event{Type: volumedriver.EventMounted, Volume: "vol", Time: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+			Eventually(source.SubscribeCallCount).Should(Equal(1))
+			published <- event
+
+			Eventually(events).Should(Receive(Equal(event)))
+		})
+
+		It("closes the channel and the stream on unsubscribe", func() {
+			events, unsubscribe := client.Subscribe()
+			Eventually(source.SubscribeCallCount).Should(Equal(1))
+
+			unsubscribe()
+
+			Eventually(events).Should(BeClosed())
+			Eventually(unsubscribed).Should(BeClosed())
+		})
+
+		It("closes the channel when the server ends the stream", func() {
+			events, unsubscribe := client.Subscribe()
+			defer unsubscribe()
+			Eventually(source.SubscribeCallCount).Should(Equal(1))
+
+			close(published)
+
+			Eventually(events).Should(BeClosed())
+		})
+	})
+
+	Describe("Endpoint", func() {
+		It("reaches servers on unix sockets", func() {
+			dir, err := ioutil.TempDir("", "driverclient")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			listener, err := net.Listen("unix", filepath.Join(dir, "driver.sock"))
+			Expect(err).NotTo(HaveOccurred())
+			handler, err := driverhttp.NewHandler(logger, testhelpers.NewMemoryDriver(logger))
+			Expect(err).NotTo(HaveOccurred())
+			go http.Serve(listener, handler)
+			defer listener.Close()
+
+			client, err := driverclient.NewClient("unix://" + filepath.Join(dir, "driver.sock"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.Activate(env).Err).To(BeEmpty())
+		})
+
+		It("returns the base URL of tcp and http addresses", func() {
+			baseURL, _, err := driverclient.Endpoint("tcp://127.0.0.1:7589", time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(baseURL).To(Equal("http://127.0.0.1:7589"))
+
+			baseURL, _, err = driverclient.Endpoint("https://driver.example.com/", time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(baseURL).To(Equal("https://driver.example.com"))
+		})
+
+		It("refuses other addresses", func() {
+			_, _, err := driverclient.Endpoint("ftp://driver.example.com", time.Second)
+			Expect(err).To(MatchError("invalid address 'ftp://driver.example.com', must be unix://, tcp://, http:// or https://"))
+
+			_, err = driverclient.NewAdminClient("")
+			Expect(err).To(MatchError("no address given"))
+		})
+	})
+})
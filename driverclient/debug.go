@@ -0,0 +1,46 @@
+package driverclient
+
+import (
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/goshims/http_wrap"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/debughttp"
+	"github.com/tedsuo/rata"
+)
+
+const DebugStateRoute = "debug-state"
+
+// DebugRoutes are the routes of the debug endpoints that serve JSON.
+var DebugRoutes = rata.Routes{
+	{Path: debughttp.StatePath, Method: "GET", Name: DebugStateRoute},
+}
+
+// DebugClient reads the driver state from the debug endpoints served by
+// debughttp.
+type DebugClient struct {
+	caller
+}
+
+var _ volumedriver.Debugger = &DebugClient{}
+
+// NewDebugClient returns a client of the debug endpoints at addr, see
+// Endpoint.
+func NewDebugClient(addr string) (*DebugClient, error) {
+	baseURL, client, err := Endpoint(addr, DefaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return NewDebugClientWithHTTPClient(baseURL, client), nil
+}
+
+func NewDebugClientWithHTTPClient(baseURL string, client http_wrap.Client) *DebugClient {
+	return &DebugClient{caller: newCaller(baseURL, DebugRoutes, client)}
+}
+
+func (c *DebugClient) DebugState(env dockerdriver.Env) volumedriver.DebugStateResponse {
+	var response volumedriver.DebugStateResponse
+	if err := c.call(env, DebugStateRoute, nil, &response); err != nil {
+		return volumedriver.DebugStateResponse{Err: err.Error()}
+	}
+	return response
+}
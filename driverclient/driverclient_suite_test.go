@@ -0,0 +1,13 @@
+package driverclient_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestDriverClient(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "DriverClient Suite")
+}
@@ -0,0 +1,109 @@
+package driverclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"code.cloudfoundry.org/goshims/http_wrap"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/eventshttp"
+)
+
+// eventBufferSize is how many events are read ahead of the subscriber.
+const eventBufferSize = 64
+
+// maxEventSize bounds a data line of the stream.
+const maxEventSize = 1024 * 1024
+
+// EventsClient follows the event stream served by eventshttp.
+type EventsClient struct {
+	logger     lager.Logger
+	baseURL    string
+	httpClient http_wrap.Client
+}
+
+var _ volumedriver.EventSource = &EventsClient{}
+
+// NewEventsClient returns a client of the event stream at addr, see
+// Endpoint. Its requests are not bounded, the stream lasts until it is
+// unsubscribed from.
+func NewEventsClient(logger lager.Logger, addr string) (*EventsClient, error) {
+	baseURL, client, err := Endpoint(addr, 0)
+	if err != nil {
+		return nil, err
+	}
+	return NewEventsClientWithHTTPClient(logger, baseURL, client), nil
+}
+
+func NewEventsClientWithHTTPClient(logger lager.Logger, baseURL string, client http_wrap.Client) *EventsClient {
+	return &EventsClient{
+		logger:     logger.Session("events-client"),
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: client,
+	}
+}
+
+// Subscribe connects to the stream in the background. The channel is closed
+// shortly after unsubscribe, and when the connection fails or the server
+// ends the stream, in which case events may have been missed, and the
+// subscriber should resync with List and subscribe again.
+func (c *EventsClient) Subscribe() (<-chan volumedriver.Event, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan volumedriver.Event, eventBufferSize)
+
+	go func() {
+		defer close(events)
+		if err := c.stream(ctx, events); err != nil && ctx.Err() == nil {
+			c.logger.Error("stream-failed", err)
+		}
+	}()
+
+	return events, cancel
+}
+
+func (c *EventsClient) stream(ctx context.Context, events chan<- volumedriver.Event) error {
+	request, err := http.NewRequest(http.MethodGet, c.baseURL+eventshttp.EventsPath, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "text/event-stream")
+
+	response, err := c.httpClient.Do(request.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", eventshttp.EventsPath, response.Status)
+	}
+
+	// every event is an "event: <type>" and a "data: <json>" line, the type
+	// is in the data as well; comment lines keep the stream alive
+	scanner := bufio.NewScanner(response.Body)
+	scanner.Buffer(make([]byte, 4096), maxEventSize)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+
+		var event volumedriver.Event
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+			c.logger.Error("failed-parsing-event", err)
+			continue
+		}
+
+		select {
+		case events <- event:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,121 @@
+package volumedriver
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/volumedriver/invoker"
+)
+
+type EventType string
+
+const (
+	EventCreated     EventType = "created"
+	EventMounted     EventType = "mounted"
+	EventMountFailed EventType = "mount-failed"
+	EventUnmounted   EventType = "unmounted"
+	EventRemoved     EventType = "removed"
+	EventExpired     EventType = "expired"
+	EventPruned      EventType = "pruned"
+	EventFrozen      EventType = "frozen"
+	EventThawed      EventType = "thawed"
+	EventRemounted   EventType = "remounted"
+	// EventMounting is published every Options.MountHeartbeatInterval while
+	// a mount is in progress.
+	EventMounting EventType = "mounting"
+)
+
+// Event is a change in the lifecycle of a volume. Mounted and Unmounted are
+// only published when the kernel mount changes, not for every reference.
+type Event struct {
+	Type   EventType
+	Volume string
+	Time   time.Time
+	Err    string `json:",omitempty"`
+
+	// ElapsedSeconds and Helpers are set for EventMounting: how long the
+	// mount has taken so far, and the helper processes it is waiting for.
+	ElapsedSeconds int64             `json:",omitempty"`
+	Helpers        []invoker.Process `json:",omitempty"`
+}
+
+// eventBufferSize is how many events a subscriber can fall behind before it
+// is disconnected.
+const eventBufferSize = 64
+
+//go:generate counterfeiter -o volumedriverfakes/fake_event_source.go . EventSource
+type EventSource interface {
+	// Subscribe returns the events published from now on. The channel is
+	// closed by unsubscribe, or when the subscriber falls too far behind, in
+	// which case it has missed events and should resync with List.
+	Subscribe() (events <-chan Event, unsubscribe func())
+}
+
+type eventBroker struct {
+	lock        sync.Mutex
+	subscribers map[chan Event]bool
+}
+
+func newEventBroker() *eventBroker {
+	return &eventBroker{subscribers: map[chan Event]bool{}}
+}
+
+func (b *eventBroker) subscribe() (<-chan Event, func()) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	events := make(chan Event, eventBufferSize)
+	b.subscribers[events] = true
+
+	return events, func() {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+
+		b.drop(events)
+	}
+}
+
+// publish never blocks, the volume lock may be held.
+func (b *eventBroker) publish(event Event) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for events := range b.subscribers {
+		select {
+		case events <- event:
+		default:
+			b.drop(events)
+		}
+	}
+}
+
+func (b *eventBroker) subscribed() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return len(b.subscribers) > 0
+}
+
+// drop must be called with lock held.
+func (b *eventBroker) drop(events chan Event) {
+	if b.subscribers[events] {
+		delete(b.subscribers, events)
+		close(events)
+	}
+}
+
+func (d *VolumeDriver) Subscribe() (<-chan Event, func()) {
+	return d.events.subscribe()
+}
+
+func (d *VolumeDriver) publish(eventType EventType, volumeName string, err error) {
+	if !d.events.subscribed() {
+		return
+	}
+
+	event := Event{Type: eventType, Volume: volumeName, Time: d.time.Now()}
+	if err != nil {
+		event.Err = err.Error()
+	}
+	d.events.publish(event)
+}
@@ -0,0 +1,13 @@
+package eventshttp_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestEventsHttp(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Events Http Suite")
+}
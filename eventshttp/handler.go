@@ -0,0 +1,81 @@
+package eventshttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+)
+
+const EventsPath = "/events"
+
+// KeepaliveInterval is how often an idle stream gets a comment line, so that
+// proxies do not time it out.
+const KeepaliveInterval = 15 * time.Second
+
+// NewHandler streams the volume lifecycle events of source under /events as
+// server-sent events, one "event: <type>" and "data: <json>" pair per event.
+// The stream ends when the client falls too far behind; clients should then
+// resync with List and reconnect.
+func NewHandler(logger lager.Logger, source volumedriver.EventSource) http.Handler {
+	logger = logger.Session("events-server")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(EventsPath, newEventsHandler(logger, source))
+	return mux
+}
+
+func newEventsHandler(logger lager.Logger, source volumedriver.EventSource) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		logger := logger.Session("handle-events")
+		logger.Info("start")
+		defer logger.Info("end")
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+
+		events, unsubscribe := source.Subscribe()
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		ticker := time.NewTicker(KeepaliveInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-req.Context().Done():
+				return
+			case <-ticker.C:
+				if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+					return
+				}
+			case event, ok := <-events:
+				if !ok {
+					logger.Info("subscriber-dropped")
+					return
+				}
+
+				data, err := json.Marshal(event)
+				if err != nil {
+					logger.Error("failed-marshalling-event", err)
+					continue
+				}
+				if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+					logger.Error("failed-writing-event", err)
+					return
+				}
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package eventshttp_test
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/eventshttp"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Events Handler", func() {
+	var (
+		fakeSource   *volumedriverfakes.FakeEventSource
+		events       chan volumedriver.Event
+		unsubscribed chan struct{}
+		server       *httptest.Server
+	)
+
+	BeforeEach(func() {
+		events = make(chan volumedriver.Event, 1)
+		unsubscribed = make(chan struct{})
+		fakeSource = &volumedriverfakes.FakeEventSource{}
+		fakeSource.SubscribeReturns(events, func() { close(unsubscribed) })
+
+		server = httptest.NewServer(eventshttp.NewHandler(lagertest.NewTestLogger("events"), fakeSource))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("streams events as they are published", func() {
+		response, err := http.Get(server.URL + eventshttp.EventsPath)
+		Expect(err).NotTo(HaveOccurred())
+		defer response.Body.Close()
+		Expect(response.Header.Get("Content-Type")).To(Equal("text/event-stream"))
+
+		events <- volumedriver.Event{Type: volumedriver.EventMounted, Volume: "some-volume", Time: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+		reader := bufio.NewReader(response.Body)
+		Expect(reader.ReadString('\n')).To(Equal("event: mounted\n"))
+		Expect(reader.ReadString('\n')).To(Equal(`data: {"Type":"mounted","Volume":"some-volume","Time":"2020-01-01T00:00:00Z"}` + "\n"))
+		Expect(reader.ReadString('\n')).To(Equal("\n"))
+	})
+
+	It("ends the stream when the subscriber is dropped", func() {
+		response, err := http.Get(server.URL + eventshttp.EventsPath)
+		Expect(err).NotTo(HaveOccurred())
+		defer response.Body.Close()
+
+		close(events)
+
+		_, err = bufio.NewReader(response.Body).ReadString('\n')
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("unsubscribes when the client goes away", func() {
+		response, err := http.Get(server.URL + eventshttp.EventsPath)
+		Expect(err).NotTo(HaveOccurred())
+		response.Body.Close()
+
+		Eventually(unsubscribed).Should(BeClosed())
+	})
+})
@@ -0,0 +1,130 @@
+package exportdiscovery
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/goshims/ioutilshim"
+	"code.cloudfoundry.org/goshims/osshim"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/invoker"
+)
+
+type exportDiscoverer struct {
+	invoker   invoker.Invoker
+	mounter   volumedriver.Mounter
+	os        osshim.Os
+	ioutil    ioutilshim.Ioutil
+	browseDir string
+}
+
+// NewExportDiscoverer returns an ExportDiscoverer that asks the mountd of a
+// server with showmount -e. Servers that only speak NFSv4 run no mountd:
+// when mounter is set, their pseudo root is mounted read-only in a
+// directory below browseDir instead, and its top level directories are
+// reported as the exports.
+func NewExportDiscoverer(invoker invoker.Invoker, mounter volumedriver.Mounter, os osshim.Os, ioutil ioutilshim.Ioutil, browseDir string) volumedriver.ExportDiscoverer {
+	return &exportDiscoverer{
+		invoker:   invoker,
+		mounter:   mounter,
+		os:        os,
+		ioutil:    ioutil,
+		browseDir: browseDir,
+	}
+}
+
+func (e *exportDiscoverer) Exports(env dockerdriver.Env, server string) ([]volumedriver.Export, error) {
+	logger := env.Logger().Session("exports", lager.Data{"server": server})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	if strings.HasPrefix(server, "-") {
+		return nil, fmt.Errorf("invalid server '%s'", server)
+	}
+
+	result := e.invoker.Invoke(env, "showmount", []string{"--exports", "--no-headers", server})
+	err := result.Wait()
+	if err == nil {
+		return parseShowmount(result.StdOutput()), nil
+	}
+	logger.Info("showmount-failed", lager.Data{"err": err.Error(), "stderr": result.StdError()})
+
+	if e.mounter == nil {
+		return nil, fmt.Errorf("showmount failed: %s", strings.TrimSpace(result.StdError()))
+	}
+
+	return e.browse(env, server)
+}
+
+// browse lists the top level of the NFSv4 pseudo root of server.
+func (e *exportDiscoverer) browse(env dockerdriver.Env, server string) ([]volumedriver.Export, error) {
+	logger := env.Logger().Session("browse", lager.Data{"server": server})
+
+	dir, err := e.ioutil.TempDir(e.browseDir, "browse-")
+	if err != nil {
+		logger.Error("temp-dir-failed", err)
+		return nil, err
+	}
+	defer func() {
+		if err := e.os.Remove(dir); err != nil {
+			logger.Error("remove-browse-dir-failed", err, lager.Data{"dir": dir})
+		}
+	}()
+
+	source := server + ":/"
+	if strings.Contains(server, ":") {
+		source = "[" + server + "]:/"
+	}
+
+	if err := e.mounter.Mount(env, source, dir, map[string]interface{}{"vers": "4", "ro": true}); err != nil {
+		logger.Error("mount-pseudo-root-failed", err)
+		return nil, fmt.Errorf("browsing the pseudo root failed: %s", err.Error())
+	}
+	defer func() {
+		if err := e.mounter.Unmount(env, dir); err != nil {
+			logger.Error("unmount-pseudo-root-failed", err, lager.Data{"dir": dir})
+		}
+	}()
+
+	entries, err := e.ioutil.ReadDir(dir)
+	if err != nil {
+		logger.Error("read-pseudo-root-failed", err)
+		return nil, err
+	}
+
+	exports := []volumedriver.Export{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			exports = append(exports, volumedriver.Export{Path: "/" + entry.Name()})
+		}
+	}
+	return exports, nil
+}
+
+// parseShowmount parses the "path client1,client2" lines of showmount -e.
+func parseShowmount(output string) []volumedriver.Export {
+	exports := []volumedriver.Export{}
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+			continue
+		}
+
+		export := volumedriver.Export{Path: fields[0]}
+		for _, clients := range fields[1:] {
+			for _, client := range strings.Split(clients, ",") {
+				if client != "" && client != "(everyone)" {
+					export.Clients = append(export.Clients, client)
+				}
+			}
+		}
+		exports = append(exports, export)
+	}
+
+	sort.Slice(exports, func(i, j int) bool { return exports[i].Path < exports[j].Path })
+	return exports
+}
@@ -0,0 +1,112 @@
+package exportdiscovery_test
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/goshims/ioutilshim/ioutil_fake"
+	"code.cloudfoundry.org/goshims/osshim/os_fake"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/exportdiscovery"
+	"code.cloudfoundry.org/volumedriver/invokerfakes"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ExportDiscoverer", func() {
+	var (
+		env              dockerdriver.Env
+		fakeInvoker      *invokerfakes.FakeInvoker
+		fakeInvokeResult *invokerfakes.FakeInvokeResult
+		fakeMounter      *volumedriverfakes.FakeMounter
+		fakeOs           *os_fake.FakeOs
+		fakeIoutil       *ioutil_fake.FakeIoutil
+		subject          volumedriver.ExportDiscoverer
+	)
+
+	BeforeEach(func() {
+		env = driverhttp.NewHttpDriverEnv(lagertest.NewTestLogger("export-discoverer"), context.TODO())
+		fakeInvoker = &invokerfakes.FakeInvoker{}
+		fakeInvokeResult = &invokerfakes.FakeInvokeResult{}
+		fakeInvoker.InvokeReturns(fakeInvokeResult)
+		fakeMounter = &volumedriverfakes.FakeMounter{}
+		fakeOs = &os_fake.FakeOs{}
+		fakeIoutil = &ioutil_fake.FakeIoutil{}
+		fakeIoutil.TempDirReturns("/var/vcap/data/browse/browse-1", nil)
+		subject = exportdiscovery.NewExportDiscoverer(fakeInvoker, fakeMounter, fakeOs, fakeIoutil, "/var/vcap/data/browse")
+	})
+
+	It("lists the exports reported by showmount", func() {
+		fakeInvokeResult.StdOutputReturns("/exports/b 10.0.0.0/8,host1\n/exports/a (everyone)\n")
+
+		exports, err := subject.Exports(env, "nfs.example.com")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exports).To(Equal([]volumedriver.Export{
+			{Path: "/exports/a"},
+			{Path: "/exports/b", Clients: []string{"10.0.0.0/8", "host1"}},
+		}))
+
+		_, executable, args, _ := fakeInvoker.InvokeArgsForCall(0)
+		Expect(executable).To(Equal("showmount"))
+		Expect(args).To(Equal([]string{"--exports", "--no-headers", "nfs.example.com"}))
+		Expect(fakeMounter.MountCallCount()).To(BeZero())
+	})
+
+	It("refuses servers that look like flags", func() {
+		_, err := subject.Exports(env, "-h")
+		Expect(err).To(HaveOccurred())
+		Expect(fakeInvoker.InvokeCallCount()).To(BeZero())
+	})
+
+	Context("when showmount fails", func() {
+		BeforeEach(func() {
+			fakeInvokeResult.WaitReturns(errors.New("exit status 1"))
+			fakeInvokeResult.StdErrorReturns("clnt_create: RPC: Program not registered\n")
+
+			dirInfo := &ioutil_fake.FakeFileInfo{}
+			dirInfo.NameReturns("tenants")
+			dirInfo.IsDirReturns(true)
+			fileInfo := &ioutil_fake.FakeFileInfo{}
+			fileInfo.NameReturns("README")
+			fakeIoutil.ReadDirReturns([]os.FileInfo{dirInfo, fileInfo}, nil)
+		})
+
+		It("browses the NFSv4 pseudo root", func() {
+			exports, err := subject.Exports(env, "fd00::1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exports).To(Equal([]volumedriver.Export{{Path: "/tenants"}}))
+
+			_, source, target, opts := fakeMounter.MountArgsForCall(0)
+			Expect(source).To(Equal("[fd00::1]:/"))
+			Expect(target).To(Equal("/var/vcap/data/browse/browse-1"))
+			Expect(opts).To(Equal(map[string]interface{}{"vers": "4", "ro": true}))
+
+			Expect(fakeMounter.UnmountCallCount()).To(Equal(1))
+			Expect(fakeOs.RemoveArgsForCall(0)).To(Equal("/var/vcap/data/browse/browse-1"))
+		})
+
+		It("returns the mount error when the pseudo root cannot be mounted", func() {
+			fakeMounter.MountReturns(errors.New("access denied"))
+			_, err := subject.Exports(env, "nfs.example.com")
+			Expect(err).To(MatchError("browsing the pseudo root failed: access denied"))
+			Expect(fakeMounter.UnmountCallCount()).To(BeZero())
+			Expect(fakeOs.RemoveCallCount()).To(Equal(1))
+		})
+
+		Context("without a mounter", func() {
+			BeforeEach(func() {
+				subject = exportdiscovery.NewExportDiscoverer(fakeInvoker, nil, fakeOs, fakeIoutil, "/var/vcap/data/browse")
+			})
+
+			It("returns the showmount error", func() {
+				_, err := subject.Exports(env, "nfs.example.com")
+				Expect(err).To(MatchError("showmount failed: clnt_create: RPC: Program not registered"))
+			})
+		})
+	})
+})
@@ -0,0 +1,423 @@
+package filestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/cfhttp"
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/goshims/http_wrap"
+	"code.cloudfoundry.org/goshims/timeshim"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+)
+
+const (
+	// Filestore is the source scheme of Filestore shares, and the name to
+	// register the mounter under in Options.Mounters.
+	Filestore = "filestore"
+
+	// FailoverOpt lists further instances, as a comma separated list of
+	// [location/]instance, that serve a replica of the share. They are
+	// mounted in order when the instances before them are unavailable.
+	FailoverOpt = "failover"
+
+	// DefaultMetadataURL is the metadata server of Compute Engine VMs.
+	DefaultMetadataURL = "http://metadata.google.internal/computeMetadata/v1"
+	// DefaultAPIURL is the Cloud Filestore API.
+	DefaultAPIURL = "https://file.googleapis.com/v1"
+)
+
+// tokenRenewMargin is how long before it expires an access token is
+// replaced.
+const tokenRenewMargin = 30 * time.Second
+
+// stateReady is the state of instances that serve their shares.
+const stateReady = "READY"
+
+// errUnauthorized is returned for requests with an access token the API no
+// longer accepts.
+var errUnauthorized = errors.New("unauthorized")
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Config describes where the instances of the sources live.
+type Config struct {
+	// Project owns the instances. It defaults to the project of the cell.
+	Project string
+	// Location is the zone or region of the instances of sources that name
+	// none. It defaults to the zone of the cell.
+	Location string
+
+	// MetadataURL defaults to DefaultMetadataURL and APIURL to
+	// DefaultAPIURL.
+	MetadataURL string
+	APIURL      string
+}
+
+// instanceRef names an instance; an empty location is Config.Location.
+type instanceRef struct {
+	location string
+	name     string
+}
+
+type instanceResponse struct {
+	State      string `json:"state"`
+	FileShares []struct {
+		Name string `json:"name"`
+	} `json:"fileShares"`
+	Networks []struct {
+		IPAddresses     []string `json:"ipAddresses"`
+		ReservedIPRange string   `json:"reservedIpRange"`
+	} `json:"networks"`
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+type errorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type filestoreMounter struct {
+	mounter    volumedriver.Mounter
+	config     Config
+	httpClient http_wrap.Client
+	time       timeshim.Time
+
+	lock        sync.Mutex
+	project     string
+	location    string
+	token       string
+	tokenExpiry time.Time
+}
+
+// NewMounter returns a Mounter for shares of Cloud Filestore instances,
+// with sources of the form filestore://[location/]instance/share, so that
+// apps on GCP need not know the address of an instance. The address is
+// looked up in the reserved IP range of the instance on every mount, with
+// the service account of the cell, and the share is mounted from it by
+// mounter, the nfs mounter. Other opts are passed on to mounter.
+func NewMounter(mounter volumedriver.Mounter, config Config) volumedriver.Mounter {
+	return NewMounterWithClient(mounter, config, cfhttp.NewClient(), &timeshim.TimeShim{})
+}
+
+func NewMounterWithClient(mounter volumedriver.Mounter, config Config, client http_wrap.Client, time timeshim.Time) volumedriver.Mounter {
+	if config.MetadataURL == "" {
+		config.MetadataURL = DefaultMetadataURL
+	}
+	if config.APIURL == "" {
+		config.APIURL = DefaultAPIURL
+	}
+	config.MetadataURL = strings.TrimSuffix(config.MetadataURL, "/")
+	config.APIURL = strings.TrimSuffix(config.APIURL, "/")
+
+	return &filestoreMounter{
+		mounter:    mounter,
+		config:     config,
+		httpClient: client,
+		time:       time,
+		project:    config.Project,
+		location:   config.Location,
+	}
+}
+
+func (m *filestoreMounter) Mount(env dockerdriver.Env, source string, target string, opts map[string]interface{}) error {
+	logger := env.Logger().Session("filestore-mount", lager.Data{"source": source, "target": target})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	primary, share, err := parseSource(source)
+	if err != nil {
+		return err
+	}
+	instances := []instanceRef{primary}
+
+	nfsOpts := map[string]interface{}{}
+	for k, v := range opts {
+		nfsOpts[k] = v
+	}
+	if value, ok := opts[FailoverOpt]; ok {
+		failover, err := parseFailover(value)
+		if err != nil {
+			return err
+		}
+		instances = append(instances, failover...)
+		delete(nfsOpts, FailoverOpt)
+	}
+
+	for i, instance := range instances {
+		var address string
+		address, err = m.resolve(env, instance, share)
+		if err != nil {
+			logger.Error("resolve-failed", err, lager.Data{"instance": instance.name, "location": instance.location})
+			continue
+		}
+
+		logger.Info("mounting", lager.Data{"instance": instance.name, "address": address})
+		err = m.mounter.Mount(env, address+":/"+share, target, nfsOpts)
+		if err == nil {
+			if i > 0 {
+				logger.Info("mounted-failover-instance", lager.Data{"instance": instance.name})
+			}
+			return nil
+		}
+		if safe, ok := safeerrors.From(err); !ok || safe.Code != safeerrors.Unavailable {
+			return err
+		}
+		logger.Error("instance-unavailable", err, lager.Data{"instance": instance.name})
+	}
+
+	return err
+}
+
+func (m *filestoreMounter) Unmount(env dockerdriver.Env, target string) error {
+	return m.mounter.Unmount(env, target)
+}
+
+func (m *filestoreMounter) Check(env dockerdriver.Env, name, mountPoint string, depth volumedriver.CheckDepth) bool {
+	return m.mounter.Check(env, name, mountPoint, depth)
+}
+
+func (m *filestoreMounter) Purge(env dockerdriver.Env, path string) {
+	m.mounter.Purge(env, path)
+}
+
+// resolve returns the address to mount share of instance from.
+func (m *filestoreMounter) resolve(env dockerdriver.Env, instance instanceRef, share string) (string, error) {
+	project, location, err := m.placement(env)
+	if err != nil {
+		return "", err
+	}
+	if instance.location != "" {
+		location = instance.location
+	}
+	path := fmt.Sprintf("/projects/%s/locations/%s/instances/%s", project, location, instance.name)
+
+	var response instanceResponse
+	err = m.get(env, path, &response)
+	if err == errUnauthorized {
+		m.forgetToken()
+		err = m.get(env, path, &response)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if response.State != stateReady {
+		return "", safeerrors.New(safeerrors.Unavailable, "filestore instance '%s' in '%s' is %s", instance.name, location, strings.ToLower(response.State))
+	}
+
+	found := false
+	for _, fileShare := range response.FileShares {
+		if fileShare.Name == share {
+			found = true
+		}
+	}
+	if !found {
+		return "", safeerrors.New(safeerrors.NotFound, "filestore instance '%s' in '%s' has no share '%s'", instance.name, location, share)
+	}
+
+	for _, network := range response.Networks {
+		for _, address := range network.IPAddresses {
+			if ip := net.ParseIP(address); ip != nil && ip.To4() != nil {
+				return address, nil
+			}
+		}
+	}
+	return "", safeerrors.New(safeerrors.Unavailable, "filestore instance '%s' in '%s' has no address", instance.name, location)
+}
+
+// placement returns the project and the default location, looking up the
+// ones the Config leaves out on the metadata server once.
+func (m *filestoreMounter) placement(env dockerdriver.Env) (string, string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.project == "" {
+		project, err := m.metadata(env.Context(), "/project/project-id")
+		if err != nil {
+			return "", "", fmt.Errorf("cannot look up the project of the cell: %s", err.Error())
+		}
+		m.project = project
+	}
+	if m.location == "" {
+		// the zone is of the form projects/<number>/zones/<zone>
+		zone, err := m.metadata(env.Context(), "/instance/zone")
+		if err != nil {
+			return "", "", fmt.Errorf("cannot look up the zone of the cell: %s", err.Error())
+		}
+		m.location = zone[strings.LastIndex(zone, "/")+1:]
+	}
+
+	return m.project, m.location, nil
+}
+
+// accessToken returns the token of the service account of the cell while it
+// lasts.
+func (m *filestoreMounter) accessToken(env dockerdriver.Env) (string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.token != "" && m.time.Now().Before(m.tokenExpiry) {
+		return m.token, nil
+	}
+
+	data, err := m.metadata(env.Context(), "/instance/service-accounts/default/token")
+	if err != nil {
+		return "", fmt.Errorf("cannot get an access token: %s", err.Error())
+	}
+	var response tokenResponse
+	if err := json.Unmarshal([]byte(data), &response); err != nil || response.AccessToken == "" {
+		return "", errors.New("cannot get an access token: invalid response from the metadata server")
+	}
+
+	m.token = response.AccessToken
+	m.tokenExpiry = m.time.Now().Add(time.Duration(response.ExpiresIn)*time.Second - tokenRenewMargin)
+	return m.token, nil
+}
+
+func (m *filestoreMounter) forgetToken() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.token = ""
+}
+
+func (m *filestoreMounter) metadata(ctx context.Context, path string) (string, error) {
+	request, err := http.NewRequest(http.MethodGet, m.config.MetadataURL+path, nil)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Metadata-Flavor", "Google")
+
+	response, err := m.httpClient.Do(request.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return "", err
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GET %s failed with status %d", path, response.StatusCode)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (m *filestoreMounter) get(env dockerdriver.Env, path string, response interface{}) error {
+	token, err := m.accessToken(env)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodGet, m.config.APIURL+path, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+
+	httpResponse, err := m.httpClient.Do(request.WithContext(env.Context()))
+	if err != nil {
+		return safeerrors.Wrap(err, safeerrors.Unavailable, "the filestore api cannot be reached")
+	}
+	defer httpResponse.Body.Close()
+
+	data, err := ioutil.ReadAll(httpResponse.Body)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case httpResponse.StatusCode == http.StatusUnauthorized:
+		return errUnauthorized
+	case httpResponse.StatusCode == http.StatusNotFound:
+		return safeerrors.New(safeerrors.NotFound, "%s not found", path)
+	case httpResponse.StatusCode == http.StatusForbidden:
+		return safeerrors.New(safeerrors.PermissionDenied, "the service account of the cell may not read %s", path)
+	case httpResponse.StatusCode >= http.StatusBadRequest:
+		var apiError errorResponse
+		if json.Unmarshal(data, &apiError) == nil && apiError.Error.Message != "" {
+			return fmt.Errorf("GET %s failed: %s", path, apiError.Error.Message)
+		}
+		return fmt.Errorf("GET %s failed with status %d", path, httpResponse.StatusCode)
+	}
+
+	if err := json.Unmarshal(data, response); err != nil {
+		return errors.New("invalid response from the filestore api: " + err.Error())
+	}
+	return nil
+}
+
+func parseSource(source string) (instanceRef, string, error) {
+	invalid := safeerrors.New(safeerrors.InvalidSource, "invalid filestore source '%s', expected %s://[location/]instance/share", source, Filestore)
+
+	prefix := Filestore + "://"
+	if !strings.HasPrefix(strings.ToLower(source), prefix) {
+		return instanceRef{}, "", invalid
+	}
+	segments := strings.Split(strings.Trim(source[len(prefix):], "/"), "/")
+	for _, segment := range segments {
+		if !validName.MatchString(segment) {
+			return instanceRef{}, "", invalid
+		}
+	}
+
+	switch len(segments) {
+	case 2:
+		return instanceRef{name: segments[0]}, segments[1], nil
+	case 3:
+		return instanceRef{location: segments[0], name: segments[1]}, segments[2], nil
+	}
+	return instanceRef{}, "", invalid
+}
+
+func parseFailover(value interface{}) ([]instanceRef, error) {
+	invalid := safeerrors.New(safeerrors.InvalidOption, "invalid %s '%v', must be a comma separated list of [location/]instance", FailoverOpt, value)
+
+	list, ok := value.(string)
+	if !ok {
+		return nil, invalid
+	}
+
+	instances := []instanceRef{}
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		segments := strings.Split(entry, "/")
+		for _, segment := range segments {
+			if !validName.MatchString(segment) {
+				return nil, invalid
+			}
+		}
+		switch len(segments) {
+		case 1:
+			instances = append(instances, instanceRef{name: segments[0]})
+		case 2:
+			instances = append(instances, instanceRef{location: segments[0], name: segments[1]})
+		default:
+			return nil, invalid
+		}
+	}
+	if len(instances) == 0 {
+		return nil, invalid
+	}
+	return instances, nil
+}
@@ -0,0 +1,254 @@
+package filestore_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/goshims/timeshim/time_fake"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/filestore"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeGCP serves the metadata server of a cell in us-central1-a of
+// some-project and the instances of the Filestore API.
+type fakeGCP struct {
+	lock      sync.Mutex
+	instances map[string]string
+	tokens    map[string]bool
+	issued    int
+	metadata  int
+}
+
+func (f *fakeGCP) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	switch req.URL.Path {
+	case "/computeMetadata/v1/project/project-id":
+		Expect(req.Header.Get("Metadata-Flavor")).To(Equal("Google"))
+		f.metadata++
+		w.Write([]byte("some-project"))
+		return
+	case "/computeMetadata/v1/instance/zone":
+		f.metadata++
+		w.Write([]byte("projects/123456/zones/us-central1-a"))
+		return
+	case "/computeMetadata/v1/instance/service-accounts/default/token":
+		f.issued++
+		token := "some-token-" + strconv.Itoa(f.issued)
+		f.tokens[token] = true
+		w.Write([]byte(`{"access_token": "` + token + `", "expires_in": 3599, "token_type": "Bearer"}`))
+		return
+	}
+
+	if len(req.Header.Get("Authorization")) < 7 || !f.tokens[req.Header.Get("Authorization")[7:]] {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": {"code": 401, "message": "Request had invalid authentication credentials.", "status": "UNAUTHENTICATED"}}`))
+		return
+	}
+
+	instance, ok := f.instances[req.URL.Path]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": {"code": 404, "message": "not found", "status": "NOT_FOUND"}}`))
+		return
+	}
+	w.Write([]byte(instance))
+}
+
+var _ = Describe("FilestoreMounter", func() {
+	var (
+		env         dockerdriver.Env
+		gcp         *fakeGCP
+		server      *httptest.Server
+		fakeTime    *time_fake.FakeTime
+		fakeMounter *volumedriverfakes.FakeMounter
+		config      filestore.Config
+		mounter     volumedriver.Mounter
+		now         time.Time
+	)
+
+	BeforeEach(func() {
+		env = driverhttp.NewHttpDriverEnv(lagertest.NewTestLogger("filestore"), context.TODO())
+		gcp = &fakeGCP{
+			instances: map[string]string{
+				"/v1/projects/some-project/locations/us-central1-a/instances/nfs1": `{"state": "READY", "fileShares": [{"name": "vol1", "capacityGb": "1024"}], "networks": [{"network": "default", "modes": ["MODE_IPV4"], "reservedIpRange": "10.0.0.0/29", "ipAddresses": ["10.0.0.2"]}]}`,
+				"/v1/projects/some-project/locations/us-east1/instances/nfs2":      `{"state": "READY", "fileShares": [{"name": "vol1"}], "networks": [{"reservedIpRange": "10.1.0.0/29", "ipAddresses": ["10.1.0.2"]}]}`,
+			},
+			tokens: map[string]bool{},
+		}
+		server = httptest.NewServer(gcp)
+
+		now = time.Unix(1000, 0)
+		fakeTime = &time_fake.FakeTime{}
+		fakeTime.NowStub = func() time.Time { return now }
+
+		fakeMounter = &volumedriverfakes.FakeMounter{}
+		config = filestore.Config{
+			MetadataURL: server.URL + "/computeMetadata/v1/",
+			APIURL:      server.URL + "/v1",
+		}
+	})
+
+	JustBeforeEach(func() {
+		mounter = filestore.NewMounterWithClient(fakeMounter, config, server.Client(), fakeTime)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("Mount", func() {
+		It("mounts the share from the address of the instance in the zone of the cell", func() {
+			Expect(mounter.Mount(env, "filestore://nfs1/vol1", "/mnt/target", map[string]interface{}{"vers": "3"})).To(Succeed())
+
+			Expect(fakeMounter.MountCallCount()).To(Equal(1))
+			_, source, target, opts := fakeMounter.MountArgsForCall(0)
+			Expect(source).To(Equal("10.0.0.2:/vol1"))
+			Expect(target).To(Equal("/mnt/target"))
+			Expect(opts).To(Equal(map[string]interface{}{"vers": "3"}))
+		})
+
+		It("mounts from the location of the source", func() {
+			Expect(mounter.Mount(env, "filestore://us-east1/nfs2/vol1", "/mnt/target", map[string]interface{}{})).To(Succeed())
+			_, source, _, _ := fakeMounter.MountArgsForCall(0)
+			Expect(source).To(Equal("10.1.0.2:/vol1"))
+		})
+
+		It("looks up the cell and the token once while the token lasts", func() {
+			Expect(mounter.Mount(env, "filestore://nfs1/vol1", "/mnt/a", map[string]interface{}{})).To(Succeed())
+			Expect(mounter.Mount(env, "filestore://nfs1/vol1", "/mnt/b", map[string]interface{}{})).To(Succeed())
+			Expect(gcp.metadata).To(Equal(2))
+			Expect(gcp.issued).To(Equal(1))
+
+			now = now.Add(time.Hour)
+			Expect(mounter.Mount(env, "filestore://nfs1/vol1", "/mnt/c", map[string]interface{}{})).To(Succeed())
+			Expect(gcp.issued).To(Equal(2))
+		})
+
+		It("gets a new token when the token is no longer accepted", func() {
+			Expect(mounter.Mount(env, "filestore://nfs1/vol1", "/mnt/a", map[string]interface{}{})).To(Succeed())
+			gcp.tokens = map[string]bool{}
+
+			Expect(mounter.Mount(env, "filestore://nfs1/vol1", "/mnt/b", map[string]interface{}{})).To(Succeed())
+			Expect(gcp.issued).To(Equal(2))
+		})
+
+		Context("when the config names the project and location", func() {
+			BeforeEach(func() {
+				config.Project = "some-project"
+				config.Location = "us-east1"
+			})
+
+			It("does not ask the metadata server", func() {
+				Expect(mounter.Mount(env, "filestore://nfs2/vol1", "/mnt/target", map[string]interface{}{})).To(Succeed())
+				Expect(gcp.metadata).To(Equal(0))
+			})
+		})
+
+		It("rejects invalid sources and failover lists", func() {
+			invalid := []struct {
+				source  string
+				opts    map[string]interface{}
+				code    safeerrors.Code
+				message string
+			}{
+				{"filestore://vol1", nil, safeerrors.InvalidSource, "invalid filestore source 'filestore://vol1', expected filestore://[location/]instance/share"},
+				{"filestore://a/b/c/d", nil, safeerrors.InvalidSource, "invalid filestore source 'filestore://a/b/c/d', expected filestore://[location/]instance/share"},
+				{"filestore://nfs1/vol1,ro", nil, safeerrors.InvalidSource, "invalid filestore source 'filestore://nfs1/vol1,ro', expected filestore://[location/]instance/share"},
+				{"filestore://nfs1/vol1", map[string]interface{}{"failover": 1}, safeerrors.InvalidOption, "invalid failover '1', must be a comma separated list of [location/]instance"},
+				{"filestore://nfs1/vol1", map[string]interface{}{"failover": "a/b/c"}, safeerrors.InvalidOption, "invalid failover 'a/b/c', must be a comma separated list of [location/]instance"},
+			}
+			for _, c := range invalid {
+				err := mounter.Mount(env, c.source, "/mnt/target", c.opts)
+				Expect(err).To(MatchError(c.message))
+				safe, _ := safeerrors.From(err)
+				Expect(safe.Code).To(Equal(c.code))
+			}
+			Expect(fakeMounter.MountCallCount()).To(Equal(0))
+		})
+
+		It("fails for missing instances and shares", func() {
+			err := mounter.Mount(env, "filestore://nfs9/vol1", "/mnt/target", map[string]interface{}{})
+			Expect(err).To(MatchError("/projects/some-project/locations/us-central1-a/instances/nfs9 not found"))
+
+			err = mounter.Mount(env, "filestore://nfs1/vol9", "/mnt/target", map[string]interface{}{})
+			Expect(err).To(MatchError("filestore instance 'nfs1' in 'us-central1-a' has no share 'vol9'"))
+			safe, _ := safeerrors.From(err)
+			Expect(safe.Code).To(Equal(safeerrors.NotFound))
+
+			Expect(fakeMounter.MountCallCount()).To(Equal(0))
+		})
+
+		Context("with failover instances", func() {
+			var opts map[string]interface{}
+
+			BeforeEach(func() {
+				opts = map[string]interface{}{"failover": "us-east1/nfs2", "vers": "3"}
+			})
+
+			It("mounts the primary instance without the failover opt", func() {
+				Expect(mounter.Mount(env, "filestore://nfs1/vol1", "/mnt/target", opts)).To(Succeed())
+				Expect(fakeMounter.MountCallCount()).To(Equal(1))
+				_, source, _, mountOpts := fakeMounter.MountArgsForCall(0)
+				Expect(source).To(Equal("10.0.0.2:/vol1"))
+				Expect(mountOpts).To(Equal(map[string]interface{}{"vers": "3"}))
+			})
+
+			It("fails over when the primary instance is not ready", func() {
+				gcp.instances["/v1/projects/some-project/locations/us-central1-a/instances/nfs1"] = `{"state": "REPAIRING", "fileShares": [{"name": "vol1"}], "networks": [{"ipAddresses": ["10.0.0.2"]}]}`
+
+				Expect(mounter.Mount(env, "filestore://nfs1/vol1", "/mnt/target", opts)).To(Succeed())
+				Expect(fakeMounter.MountCallCount()).To(Equal(1))
+				_, source, _, _ := fakeMounter.MountArgsForCall(0)
+				Expect(source).To(Equal("10.1.0.2:/vol1"))
+			})
+
+			It("fails over when the primary instance does not respond", func() {
+				fakeMounter.MountReturnsOnCall(0, safeerrors.New(safeerrors.Unavailable, "nfs mount failed: the server did not respond in time"))
+
+				Expect(mounter.Mount(env, "filestore://nfs1/vol1", "/mnt/target", opts)).To(Succeed())
+				Expect(fakeMounter.MountCallCount()).To(Equal(2))
+				_, source, _, _ := fakeMounter.MountArgsForCall(1)
+				Expect(source).To(Equal("10.1.0.2:/vol1"))
+			})
+
+			It("does not fail over for other mount failures", func() {
+				fakeMounter.MountReturns(safeerrors.New(safeerrors.PermissionDenied, "nfs mount failed: access denied by the server"))
+
+				Expect(mounter.Mount(env, "filestore://nfs1/vol1", "/mnt/target", opts)).To(MatchError("nfs mount failed: access denied by the server"))
+				Expect(fakeMounter.MountCallCount()).To(Equal(1))
+			})
+
+			It("returns the failure of the last instance", func() {
+				fakeMounter.MountReturns(safeerrors.New(safeerrors.Unavailable, "nfs mount failed: the server did not respond in time"))
+				opts["failover"] = "us-east1/nfs2, nfs9"
+
+				Expect(mounter.Mount(env, "filestore://nfs1/vol1", "/mnt/target", opts)).To(MatchError("/projects/some-project/locations/us-central1-a/instances/nfs9 not found"))
+				Expect(fakeMounter.MountCallCount()).To(Equal(2))
+			})
+		})
+	})
+
+	It("leaves unmounting, checking and purging to the nfs mounter", func() {
+		fakeMounter.UnmountReturns(errors.New("busy"))
+		fakeMounter.CheckReturns(true)
+
+		Expect(mounter.Unmount(env, "/mnt/target")).To(MatchError("busy"))
+		Expect(mounter.Check(env, "volume", "/mnt/target", volumedriver.CheckStat)).To(BeTrue())
+		mounter.Purge(env, "/mnt")
+		Expect(fakeMounter.PurgeCallCount()).To(Equal(1))
+	})
+})
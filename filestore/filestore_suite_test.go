@@ -0,0 +1,13 @@
+package filestore_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestFilestore(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Filestore Suite")
+}
@@ -0,0 +1,128 @@
+package volumedriver
+
+import (
+	"fmt"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager"
+)
+
+//go:generate counterfeiter -o volumedriverfakes/fake_freezer.go . Freezer
+type Freezer interface {
+	// Freeze flushes the filesystem mounted at mountPoint and blocks writes
+	// to it until Thaw.
+	Freeze(env dockerdriver.Env, mountPoint string) error
+	Thaw(env dockerdriver.Env, mountPoint string) error
+}
+
+func (d *VolumeDriver) FreezeVolume(env dockerdriver.Env, freezeRequest FreezeVolumeRequest) dockerdriver.ErrorResponse {
+	logger := env.Logger().Session("freeze-volume", lager.Data{"volume": freezeRequest.Name})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	if freezeRequest.Name == "" {
+		return dockerdriver.ErrorResponse{Err: "Missing mandatory 'volume_name'"}
+	}
+
+	if d.options.Freezer == nil {
+		return dockerdriver.ErrorResponse{Err: "Freeze is not supported by this driver"}
+	}
+
+	// the volume is marked frozen before the lock is released, so that it
+	// cannot be unmounted while the filesystem is being flushed
+	mountPoint, errResponse := d.markFrozen(freezeRequest.Name, true)
+	if errResponse.Err != "" {
+		return errResponse
+	}
+
+	if err := d.options.Freezer.Freeze(driverhttp.EnvWithLogger(logger, env), mountPoint); err != nil {
+		logger.Error("freeze-failed", err)
+		d.markFrozen(freezeRequest.Name, false)
+		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("Error freezing volume '%s': %s", freezeRequest.Name, err.Error())}
+	}
+	d.publish(EventFrozen, freezeRequest.Name, nil)
+
+	return d.persistFrozen(driverhttp.EnvWithLogger(logger, env), freezeRequest.Name)
+}
+
+func (d *VolumeDriver) ThawVolume(env dockerdriver.Env, thawRequest ThawVolumeRequest) dockerdriver.ErrorResponse {
+	logger := env.Logger().Session("thaw-volume", lager.Data{"volume": thawRequest.Name})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	if thawRequest.Name == "" {
+		return dockerdriver.ErrorResponse{Err: "Missing mandatory 'volume_name'"}
+	}
+
+	if d.options.Freezer == nil {
+		return dockerdriver.ErrorResponse{Err: "Freeze is not supported by this driver"}
+	}
+
+	mountPoint, errResponse := d.frozenMountPoint(thawRequest.Name)
+	if errResponse.Err != "" {
+		return errResponse
+	}
+
+	if err := d.options.Freezer.Thaw(driverhttp.EnvWithLogger(logger, env), mountPoint); err != nil {
+		logger.Error("thaw-failed", err)
+		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("Error thawing volume '%s': %s", thawRequest.Name, err.Error())}
+	}
+
+	d.markFrozen(thawRequest.Name, false)
+	d.publish(EventThawed, thawRequest.Name, nil)
+	return d.persistFrozen(driverhttp.EnvWithLogger(logger, env), thawRequest.Name)
+}
+
+// markFrozen sets the frozen flag of a volume and returns its mountpoint.
+// Only mounted volumes that are not frozen yet can be frozen.
+func (d *VolumeDriver) markFrozen(volumeName string, frozen bool) (string, dockerdriver.ErrorResponse) {
+	d.volumesLock.Lock()
+	defer d.volumesLock.Unlock()
+
+	volume, ok := d.volumes[volumeName]
+	if !ok {
+		return "", dockerdriver.ErrorResponse{Err: fmt.Sprintf("Volume '%s' not found", volumeName)}
+	}
+
+	if frozen {
+		if volume.MountCount < 1 || volume.Mountpoint == "" {
+			return "", dockerdriver.ErrorResponse{Err: fmt.Sprintf("Volume '%s' is not mounted", volumeName)}
+		}
+		if volume.Frozen {
+			return "", dockerdriver.ErrorResponse{Err: fmt.Sprintf("Volume '%s' is already frozen", volumeName)}
+		}
+	}
+
+	volume.Frozen = frozen
+	return volume.Mountpoint, dockerdriver.ErrorResponse{}
+}
+
+func (d *VolumeDriver) frozenMountPoint(volumeName string) (string, dockerdriver.ErrorResponse) {
+	d.volumesLock.RLock()
+	defer d.volumesLock.RUnlock()
+
+	volume, ok := d.volumes[volumeName]
+	if !ok {
+		return "", dockerdriver.ErrorResponse{Err: fmt.Sprintf("Volume '%s' not found", volumeName)}
+	}
+	if !volume.Frozen {
+		return "", dockerdriver.ErrorResponse{Err: fmt.Sprintf("Volume '%s' is not frozen", volumeName)}
+	}
+	return volume.Mountpoint, dockerdriver.ErrorResponse{}
+}
+
+func (d *VolumeDriver) persistFrozen(env dockerdriver.Env, volumeName string) dockerdriver.ErrorResponse {
+	d.volumesLock.Lock()
+	defer d.volumesLock.Unlock()
+
+	if err := d.persistVolume(env, volumeName); err != nil {
+		env.Logger().Error("persist-state-failed", err)
+		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("failed to persist state: %s", err.Error())}
+	}
+	return dockerdriver.ErrorResponse{}
+}
+
+func frozenError(volumeName string) string {
+	return fmt.Sprintf("Volume '%s' is frozen, thaw it before unmounting", volumeName)
+}
@@ -0,0 +1,13 @@
+package freezer_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestFreezer(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Freezer Suite")
+}
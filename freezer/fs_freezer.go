@@ -0,0 +1,51 @@
+package freezer
+
+import (
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/invoker"
+)
+
+type fsFreezer struct {
+	invoker invoker.Invoker
+}
+
+// NewFsFreezer returns a Freezer that runs fsfreeze(8), which supports the
+// local and block device backed filesystems that implement FIFREEZE, such as
+// ext4 and xfs. It fails for NFS and FUSE mounts; snapshot those on the
+// server instead.
+func NewFsFreezer(invoker invoker.Invoker) volumedriver.Freezer {
+	return &fsFreezer{invoker: invoker}
+}
+
+func (f *fsFreezer) Freeze(env dockerdriver.Env, mountPoint string) error {
+	logger := env.Logger().Session("fs-freeze", lager.Data{"mount-point": mountPoint})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	result := f.invoker.Invoke(env, "fsfreeze", []string{"--freeze", mountPoint})
+	if err := result.Wait(); err != nil {
+		logger.Error("freeze-failed", err, lager.Data{"stderr": result.StdError()})
+		return fmt.Errorf("freeze failed: %s", strings.TrimSpace(result.StdError()))
+	}
+
+	return nil
+}
+
+func (f *fsFreezer) Thaw(env dockerdriver.Env, mountPoint string) error {
+	logger := env.Logger().Session("fs-thaw", lager.Data{"mount-point": mountPoint})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	result := f.invoker.Invoke(env, "fsfreeze", []string{"--unfreeze", mountPoint})
+	if err := result.Wait(); err != nil {
+		logger.Error("thaw-failed", err, lager.Data{"stderr": result.StdError()})
+		return fmt.Errorf("thaw failed: %s", strings.TrimSpace(result.StdError()))
+	}
+
+	return nil
+}
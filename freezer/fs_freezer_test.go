@@ -0,0 +1,58 @@
+package freezer_test
+
+import (
+	"context"
+	"errors"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/freezer"
+	"code.cloudfoundry.org/volumedriver/invokerfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FsFreezer", func() {
+	var (
+		env              dockerdriver.Env
+		fakeInvoker      *invokerfakes.FakeInvoker
+		fakeInvokeResult *invokerfakes.FakeInvokeResult
+		subject          volumedriver.Freezer
+	)
+
+	BeforeEach(func() {
+		env = driverhttp.NewHttpDriverEnv(lagertest.NewTestLogger("fs-freezer"), context.TODO())
+		fakeInvoker = &invokerfakes.FakeInvoker{}
+		fakeInvokeResult = &invokerfakes.FakeInvokeResult{}
+		fakeInvoker.InvokeReturns(fakeInvokeResult)
+		subject = freezer.NewFsFreezer(fakeInvoker)
+	})
+
+	It("freezes the mountpoint", func() {
+		Expect(subject.Freeze(env, "/mnt/volume")).To(Succeed())
+		_, executable, args, _ := fakeInvoker.InvokeArgsForCall(0)
+		Expect(executable).To(Equal("fsfreeze"))
+		Expect(args).To(Equal([]string{"--freeze", "/mnt/volume"}))
+	})
+
+	It("thaws the mountpoint", func() {
+		Expect(subject.Thaw(env, "/mnt/volume")).To(Succeed())
+		_, executable, args, _ := fakeInvoker.InvokeArgsForCall(0)
+		Expect(executable).To(Equal("fsfreeze"))
+		Expect(args).To(Equal([]string{"--unfreeze", "/mnt/volume"}))
+	})
+
+	Context("when fsfreeze fails", func() {
+		BeforeEach(func() {
+			fakeInvokeResult.WaitReturns(errors.New("exit status 1"))
+			fakeInvokeResult.StdErrorReturns("fsfreeze: /mnt/volume: freeze failed: Operation not supported\n")
+		})
+
+		It("returns its error", func() {
+			Expect(subject.Freeze(env, "/mnt/volume")).To(MatchError("freeze failed: fsfreeze: /mnt/volume: freeze failed: Operation not supported"))
+			Expect(subject.Thaw(env, "/mnt/volume")).To(MatchError(HavePrefix("thaw failed: ")))
+		})
+	})
+})
@@ -0,0 +1,68 @@
+package volumedriver
+
+import (
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+)
+
+// FSCacheOpt is the kernel nfs client opt that caches the pages read from
+// the server on local disk through fscache. Its value, if any, is the tag of
+// the cache. It is passed on to the mounter.
+const FSCacheOpt = "fsc"
+
+//go:generate counterfeiter -o volumedriverfakes/fake_fscache.go . FSCache
+type FSCache interface {
+	// Available fails when mounts with the fsc opt would not be cached,
+	// because the kernel has no fscache or no cache is bound by cachefilesd.
+	Available() error
+}
+
+// FSCacheStats are the fscache counters of an nfs mount with the fsc opt, in
+// pages since it was mounted. Pages read from the cache are hits, and pages
+// the cache did not hold are read from the server.
+type FSCacheStats struct {
+	PagesReadOK        uint64
+	PagesReadFailed    uint64
+	PagesWrittenOK     uint64
+	PagesWrittenFailed uint64
+}
+
+// fsCacheRequested reports whether opts ask for the fsc opt.
+func fsCacheRequested(opts map[string]interface{}) bool {
+	value, ok := opts[FSCacheOpt]
+	if !ok {
+		return false
+	}
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return strings.ToLower(v) != "false"
+	}
+	return true
+}
+
+// checkFSCache fails for volumes with the fsc opt while Options.FSCache
+// finds no cache. Without Options.FSCache the opt is passed on unchecked.
+func (d *VolumeDriver) checkFSCache(opts map[string]interface{}) error {
+	if !fsCacheRequested(opts) || d.options.FSCache == nil {
+		return nil
+	}
+	if err := d.options.FSCache.Available(); err != nil {
+		return fmt.Errorf("the %s opt needs a running cachefilesd: %s", FSCacheOpt, err.Error())
+	}
+	return nil
+}
+
+// warnUncached logs mounts with the fsc opt that the kernel will serve
+// without caching, because the cache went away after the volume was created.
+func (d *VolumeDriver) warnUncached(env dockerdriver.Env, opts map[string]interface{}) {
+	logger := env.Logger().Session("check-fscache")
+
+	if err := d.checkFSCache(opts); err != nil {
+		logger.Info("mounting-uncached", lager.Data{"reason": err.Error()})
+	}
+}
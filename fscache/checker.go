@@ -0,0 +1,80 @@
+package fscache
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"code.cloudfoundry.org/goshims/ioutilshim"
+	"code.cloudfoundry.org/goshims/osshim"
+	"code.cloudfoundry.org/volumedriver"
+)
+
+const (
+	// ProcFSCache is present when the kernel was built with fscache.
+	ProcFSCache = "/proc/fs/fscache"
+	// ProcCaches lists the bound caches, on kernels from 5.17 on.
+	ProcCaches = "/proc/fs/fscache/caches"
+	// PidFile is where cachefilesd records its pid on older kernels.
+	PidFile = "/var/run/cachefilesd.pid"
+)
+
+type checker struct {
+	os     osshim.Os
+	ioutil ioutilshim.Ioutil
+}
+
+// NewChecker returns an FSCache that finds a cache bound by cachefilesd in
+// /proc/fs/fscache/caches, or on kernels without it a running cachefilesd
+// by its pid file.
+func NewChecker(os osshim.Os, ioutil ioutilshim.Ioutil) volumedriver.FSCache {
+	return &checker{os: os, ioutil: ioutil}
+}
+
+func (c *checker) Available() error {
+	if _, err := c.os.Stat(ProcFSCache); err != nil {
+		return errors.New("the kernel has no fscache support")
+	}
+
+	caches, err := c.ioutil.ReadFile(ProcCaches)
+	if err == nil {
+		if activeCache(string(caches)) {
+			return nil
+		}
+		return errors.New("no cache is bound, is cachefilesd running?")
+	}
+
+	pid, err := c.ioutil.ReadFile(PidFile)
+	if err != nil {
+		return errors.New("cachefilesd is not running")
+	}
+	if _, err := strconv.Atoi(strings.TrimSpace(string(pid))); err != nil {
+		return fmt.Errorf("invalid pid file %s", PidFile)
+	}
+	if _, err := c.os.Stat(filepath.Join("/proc", strings.TrimSpace(string(pid)))); err != nil {
+		return errors.New("cachefilesd is not running")
+	}
+	return nil
+}
+
+// activeCache parses the caches table of the kernel, such as
+//
+//	CACHE    REF   VOLS  OBJS  ACCES S NAME
+//	======== ===== ===== ===== ===== = ===============
+//	00000001     2     1  2123     1 A default
+//
+// for a cache in state A, active.
+func activeCache(caches string) bool {
+	for _, line := range strings.Split(caches, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 6 || fields[0] == "CACHE" || strings.HasPrefix(fields[0], "=") {
+			continue
+		}
+		if fields[5] == "A" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package fscache_test
+
+import (
+	"errors"
+	"os"
+
+	"code.cloudfoundry.org/goshims/ioutilshim/ioutil_fake"
+	"code.cloudfoundry.org/goshims/osshim/os_fake"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/fscache"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const boundCaches = `CACHE    REF   VOLS  OBJS  ACCES S NAME
+======== ===== ===== ===== ===== = ===============
+00000001     2     1  2123     1 A default
+`
+
+var _ = Describe("Checker", func() {
+	var (
+		fakeOs     *os_fake.FakeOs
+		fakeIoutil *ioutil_fake.FakeIoutil
+		files      map[string]string
+		checker    volumedriver.FSCache
+	)
+
+	BeforeEach(func() {
+		fakeOs = &os_fake.FakeOs{}
+		fakeIoutil = &ioutil_fake.FakeIoutil{}
+		files = map[string]string{fscache.ProcCaches: boundCaches}
+		fakeIoutil.ReadFileStub = func(path string) ([]byte, error) {
+			content, ok := files[path]
+			if !ok {
+				return nil, os.ErrNotExist
+			}
+			return []byte(content), nil
+		}
+
+		checker = fscache.NewChecker(fakeOs, fakeIoutil)
+	})
+
+	It("finds an active cache", func() {
+		Expect(checker.Available()).To(Succeed())
+		Expect(fakeOs.StatArgsForCall(0)).To(Equal("/proc/fs/fscache"))
+	})
+
+	Context("when the kernel has no fscache", func() {
+		BeforeEach(func() {
+			fakeOs.StatReturns(nil, os.ErrNotExist)
+		})
+
+		It("fails", func() {
+			Expect(checker.Available()).To(MatchError("the kernel has no fscache support"))
+		})
+	})
+
+	Context("when no cache is bound", func() {
+		BeforeEach(func() {
+			files[fscache.ProcCaches] = "CACHE    REF   VOLS  OBJS  ACCES S NAME\n======== ===== ===== ===== ===== = ===============\n"
+		})
+
+		It("fails", func() {
+			Expect(checker.Available()).To(MatchError("no cache is bound, is cachefilesd running?"))
+		})
+	})
+
+	Context("when the kernel does not list its caches", func() {
+		BeforeEach(func() {
+			delete(files, fscache.ProcCaches)
+			files[fscache.PidFile] = "1234\n"
+		})
+
+		It("looks for a running cachefilesd", func() {
+			Expect(checker.Available()).To(Succeed())
+			Expect(fakeOs.StatArgsForCall(1)).To(Equal("/proc/1234"))
+		})
+
+		Context("and cachefilesd is gone", func() {
+			BeforeEach(func() {
+				fakeOs.StatStub = func(path string) (os.FileInfo, error) {
+					if path == "/proc/1234" {
+						return nil, errors.New("no such process")
+					}
+					return nil, nil
+				}
+			})
+
+			It("fails", func() {
+				Expect(checker.Available()).To(MatchError("cachefilesd is not running"))
+			})
+		})
+
+		Context("and there is no pid file", func() {
+			BeforeEach(func() {
+				delete(files, fscache.PidFile)
+			})
+
+			It("fails", func() {
+				Expect(checker.Available()).To(MatchError("cachefilesd is not running"))
+			})
+		})
+	})
+})
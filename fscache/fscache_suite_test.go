@@ -0,0 +1,13 @@
+package fscache_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestFSCache(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "FSCache Suite")
+}
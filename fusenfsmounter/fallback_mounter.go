@@ -0,0 +1,134 @@
+package fusenfsmounter
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+)
+
+type fallbackMounter struct {
+	kernel volumedriver.Mounter
+	fuse   volumedriver.Mounter
+
+	lock sync.Mutex
+	// fuseMounts holds the targets that were mounted with fuse
+	fuseMounts map[string]bool
+}
+
+// NewFallbackMounter returns a Mounter that mounts with the kernel client and
+// falls back to the fuse client when the host refuses the kernel mount,
+// because the driver may not call mount(2) or the kernel has no nfs support.
+// Other failures, such as an unreachable server, are returned as they are.
+//
+// Which client mounted a target is only known until the driver restarts;
+// afterwards a target the kernel client fails to unmount is unmounted with
+// the fuse client.
+func NewFallbackMounter(kernel volumedriver.Mounter, fuse volumedriver.Mounter) volumedriver.Mounter {
+	return &fallbackMounter{
+		kernel:     kernel,
+		fuse:       fuse,
+		fuseMounts: map[string]bool{},
+	}
+}
+
+func (m *fallbackMounter) Mount(env dockerdriver.Env, source string, target string, opts map[string]interface{}) error {
+	logger := env.Logger().Session("fallback-mount", lager.Data{"source": source, "target": target})
+
+	err := m.kernel.Mount(env, source, target, opts)
+	if err == nil || !kernelClientUnavailable(err) {
+		return err
+	}
+	logger.Info("falling-back-to-fuse", lager.Data{"kernel-error": err.Error()})
+
+	if err := m.fuse.Mount(env, source, target, opts); err != nil {
+		return err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.fuseMounts[target] = true
+	return nil
+}
+
+func (m *fallbackMounter) Unmount(env dockerdriver.Env, target string) error {
+	logger := env.Logger().Session("fallback-unmount", lager.Data{"target": target})
+
+	if m.isFuseMount(target) {
+		if err := m.fuse.Unmount(env, target); err != nil {
+			return err
+		}
+		m.forget(target)
+		return nil
+	}
+
+	err := m.kernel.Unmount(env, target)
+	if err == nil {
+		return nil
+	}
+
+	logger.Info("trying-fuse", lager.Data{"kernel-error": err.Error()})
+	if m.fuse.Unmount(env, target) == nil {
+		return nil
+	}
+	return err
+}
+
+func (m *fallbackMounter) Check(env dockerdriver.Env, name, mountPoint string, depth volumedriver.CheckDepth) bool {
+	if m.isFuseMount(mountPoint) {
+		return m.fuse.Check(env, name, mountPoint, depth)
+	}
+	return m.kernel.Check(env, name, mountPoint, depth)
+}
+
+func (m *fallbackMounter) Purge(env dockerdriver.Env, path string) {
+	m.kernel.Purge(env, path)
+	m.fuse.Purge(env, path)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	prefix := filepath.Clean(path) + string(filepath.Separator)
+	for target := range m.fuseMounts {
+		if strings.HasPrefix(target, prefix) {
+			delete(m.fuseMounts, target)
+		}
+	}
+}
+
+// Unprivileged reports whether the fuse client works for a driver that does
+// not run as root; the kernel client is then refused and every mount falls
+// back to it.
+func (m *fallbackMounter) Unprivileged() bool {
+	return volumedriver.IsUnprivileged(m.fuse)
+}
+
+func (m *fallbackMounter) isFuseMount(target string) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.fuseMounts[target]
+}
+
+func (m *fallbackMounter) forget(target string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.fuseMounts, target)
+}
+
+// kernelClientUnavailable recognizes the errors of mount(2) and of mount.nfs
+// for a driver without the privilege to mount and for kernels without nfs.
+func kernelClientUnavailable(err error) bool {
+	if errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.ENODEV) {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "operation not permitted") ||
+		strings.Contains(msg, "only root can") ||
+		strings.Contains(msg, "unknown filesystem type")
+}
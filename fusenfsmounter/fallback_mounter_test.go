@@ -0,0 +1,116 @@
+package fusenfsmounter_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/fusenfsmounter"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FallbackMounter", func() {
+	var (
+		env           dockerdriver.Env
+		kernelMounter *volumedriverfakes.FakeMounter
+		fuseMounter   *volumedriverfakes.FakeMounter
+		mounter       volumedriver.Mounter
+		opts          map[string]interface{}
+	)
+
+	BeforeEach(func() {
+		env = driverhttp.NewHttpDriverEnv(lagertest.NewTestLogger("fallback"), context.TODO())
+		kernelMounter = &volumedriverfakes.FakeMounter{}
+		fuseMounter = &volumedriverfakes.FakeMounter{}
+		mounter = fusenfsmounter.NewFallbackMounter(kernelMounter, fuseMounter)
+		opts = map[string]interface{}{"vers": "3"}
+	})
+
+	It("mounts with the kernel client", func() {
+		Expect(mounter.Mount(env, "server:/export", "/mnt/target", opts)).To(Succeed())
+		Expect(kernelMounter.MountCallCount()).To(Equal(1))
+		Expect(fuseMounter.MountCallCount()).To(BeZero())
+
+		Expect(mounter.Unmount(env, "/mnt/target")).To(Succeed())
+		Expect(kernelMounter.UnmountCallCount()).To(Equal(1))
+		Expect(fuseMounter.UnmountCallCount()).To(BeZero())
+	})
+
+	Context("when mount(2) is denied", func() {
+		BeforeEach(func() {
+			kernelMounter.MountReturns(fmt.Errorf("mount failed: %w", syscall.EPERM))
+		})
+
+		It("falls back to the fuse client", func() {
+			Expect(mounter.Mount(env, "server:/export", "/mnt/target", opts)).To(Succeed())
+			Expect(fuseMounter.MountCallCount()).To(Equal(1))
+			_, source, target, fuseOpts := fuseMounter.MountArgsForCall(0)
+			Expect(source).To(Equal("server:/export"))
+			Expect(target).To(Equal("/mnt/target"))
+			Expect(fuseOpts).To(Equal(opts))
+		})
+
+		It("checks and unmounts the target with the fuse client", func() {
+			Expect(mounter.Mount(env, "server:/export", "/mnt/target", opts)).To(Succeed())
+
+			fuseMounter.CheckReturns(true)
+			Expect(mounter.Check(env, "volume", "/mnt/target", volumedriver.CheckStat)).To(BeTrue())
+			Expect(kernelMounter.CheckCallCount()).To(BeZero())
+
+			Expect(mounter.Unmount(env, "/mnt/target")).To(Succeed())
+			Expect(fuseMounter.UnmountCallCount()).To(Equal(1))
+			Expect(kernelMounter.UnmountCallCount()).To(BeZero())
+		})
+	})
+
+	Context("when the kernel has no nfs support", func() {
+		BeforeEach(func() {
+			kernelMounter.MountReturns(errors.New("nfs mount failed: mount: unknown filesystem type 'nfs'"))
+		})
+
+		It("falls back to the fuse client", func() {
+			Expect(mounter.Mount(env, "server:/export", "/mnt/target", opts)).To(Succeed())
+			Expect(fuseMounter.MountCallCount()).To(Equal(1))
+		})
+	})
+
+	Context("when the server refuses the mount", func() {
+		BeforeEach(func() {
+			kernelMounter.MountReturns(fmt.Errorf("mount failed: %w", syscall.EACCES))
+		})
+
+		It("returns the error without falling back", func() {
+			Expect(mounter.Mount(env, "server:/export", "/mnt/target", opts)).To(MatchError("mount failed: permission denied"))
+			Expect(fuseMounter.MountCallCount()).To(BeZero())
+		})
+	})
+
+	Context("when a target mounted before a restart cannot be unmounted by the kernel client", func() {
+		BeforeEach(func() {
+			kernelMounter.UnmountReturns(errors.New("unmount failed: invalid argument"))
+		})
+
+		It("unmounts it with the fuse client", func() {
+			Expect(mounter.Unmount(env, "/mnt/target")).To(Succeed())
+			Expect(fuseMounter.UnmountCallCount()).To(Equal(1))
+		})
+
+		It("returns the kernel error when both fail", func() {
+			fuseMounter.UnmountReturns(errors.New("not a fuse mount"))
+			Expect(mounter.Unmount(env, "/mnt/target")).To(MatchError("unmount failed: invalid argument"))
+		})
+	})
+
+	It("purges with both clients", func() {
+		mounter.Purge(env, "/mnt/root")
+		Expect(kernelMounter.PurgeCallCount()).To(Equal(1))
+		Expect(fuseMounter.PurgeCallCount()).To(Equal(1))
+	})
+})
@@ -0,0 +1,222 @@
+package fusenfsmounter
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/goshims/ioutilshim"
+	"code.cloudfoundry.org/goshims/osshim"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/invoker"
+	"code.cloudfoundry.org/volumedriver/mountchecker"
+	"code.cloudfoundry.org/volumedriver/mounthelper"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+)
+
+// FuseNFS is the name to register the mounter under in Options.Mounters, for
+// volumes that select it with the driver opt.
+const FuseNFS = "fuse-nfs"
+
+// urlOpts are passed to libnfs in the query of the nfs:// url, with vers and
+// nfsvers renamed to the version parameter libnfs expects.
+var urlOpts = map[string]string{
+	"vers":      "version",
+	"nfsvers":   "version",
+	"uid":       "uid",
+	"gid":       "gid",
+	"readahead": "readahead",
+}
+
+var validValue = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+type fuseNFSMounter struct {
+	invoker      invoker.Invoker
+	os           osshim.Os
+	ioutil       ioutilshim.Ioutil
+	mountChecker mountchecker.MountChecker
+}
+
+// NewFuseNFSMounter returns a Mounter that mounts nfs exports with fuse-nfs,
+// the userspace client of libnfs, for hosts where the kernel client is not
+// available, such as unprivileged containers. Sources have the form
+// host:/export like for the kernel client. The vers, nfsvers, uid, gid and
+// readahead opts are passed to libnfs, and ro and readonly mount read-only.
+func NewFuseNFSMounter(invoker invoker.Invoker, os osshim.Os, ioutil ioutilshim.Ioutil, mountChecker mountchecker.MountChecker) volumedriver.Mounter {
+	return &fuseNFSMounter{
+		invoker:      invoker,
+		os:           os,
+		ioutil:       ioutil,
+		mountChecker: mountChecker,
+	}
+}
+
+func (m *fuseNFSMounter) Mount(env dockerdriver.Env, source string, target string, opts map[string]interface{}) error {
+	logger := env.Logger().Session("fuse-nfs-mount", lager.Data{"source": source, "target": target})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	// fuse-nfs takes no mount flags, so the hardening opts of the driver are
+	// ignored
+	opts, _ = volumedriver.HardeningFlags(opts)
+	nfsURL, readOnly, err := libnfsURL(source, opts)
+	if err != nil {
+		logger.Error("invalid-source-or-opts", err)
+		return err
+	}
+
+	args := []string{"--nfs-share", nfsURL, "--mountpoint", target, "--allow-other"}
+	if readOnly {
+		args = append(args, "--read-only")
+	}
+
+	result := m.invoker.Invoke(env, "fuse-nfs", args)
+	if err := result.Wait(); err != nil {
+		logger.Error("mount-failed", err, lager.Data{"stderr": result.StdError()})
+		return fmt.Errorf("fuse-nfs mount failed: %s", strings.TrimSpace(result.StdError()))
+	}
+
+	return nil
+}
+
+func (m *fuseNFSMounter) Unmount(env dockerdriver.Env, target string) error {
+	logger := env.Logger().Session("fuse-nfs-unmount", lager.Data{"target": target})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	result := m.invoker.Invoke(env, "fusermount", []string{"-u", target})
+	if err := result.Wait(); err != nil {
+		logger.Error("unmount-failed", err, lager.Data{"stderr": result.StdError()})
+		return safeerrors.FromMountHelper(err, result.StdError(), "unmount failed")
+	}
+
+	return nil
+}
+
+func (m *fuseNFSMounter) Check(env dockerdriver.Env, name, mountPoint string, depth volumedriver.CheckDepth) bool {
+	logger := env.Logger().Session("fuse-nfs-check", lager.Data{"name": name, "mount-point": mountPoint, "depth": depth})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	mounted, err := m.mountChecker.Exists(mountPoint)
+	if err != nil {
+		logger.Error("check-mounts-failed", err)
+		return false
+	}
+	if !mounted {
+		logger.Info("not-mounted")
+		return false
+	}
+
+	if err := volumedriver.ProbeMountPoint(m.os, m.ioutil, mountPoint, depth); err != nil {
+		logger.Error("probe-failed", err)
+		return false
+	}
+
+	return true
+}
+
+// Purge lazily unmounts everything below path and removes the emptied
+// mountpoints. Directory contents are never removed.
+func (m *fuseNFSMounter) Purge(env dockerdriver.Env, path string) {
+	logger := env.Logger().Session("fuse-nfs-purge", lager.Data{"path": path})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	mounthelper.Purge(logger, path, m.mountChecker, m.os, mounthelper.Unmount(env, m.invoker, "fusermount", "-u", "-z"))
+}
+
+// Unprivileged reports that fuse-nfs mounts without the driver running as
+// root.
+func (m *fuseNFSMounter) Unprivileged() bool {
+	return true
+}
+
+// libnfsURL turns host:/export and the opts into the nfs:// url of libnfs,
+// and reports whether the mount is read-only.
+func libnfsURL(source string, opts map[string]interface{}) (string, bool, error) {
+	host, export, err := splitSource(source)
+	if err != nil {
+		return "", false, err
+	}
+
+	keys := make([]string, 0, len(opts))
+	for key := range opts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	query := []string{}
+	readOnly := false
+	for _, key := range keys {
+		value := opts[key]
+
+		switch key {
+		case "ro", "readonly":
+			set, err := boolOpt(value)
+			if err != nil {
+				return "", false, safeerrors.New(safeerrors.InvalidOption, "Invalid value for option '%s': %v", key, value)
+			}
+			readOnly = readOnly || set
+			continue
+		}
+
+		param, ok := urlOpts[key]
+		if !ok {
+			return "", false, safeerrors.New(safeerrors.InvalidOption, "Not allowed options: %s", key)
+		}
+		if !validValue.MatchString(fmt.Sprintf("%v", value)) {
+			return "", false, safeerrors.New(safeerrors.InvalidOption, "Invalid value for option '%s': %v", key, value)
+		}
+		query = append(query, fmt.Sprintf("%s=%v", param, value))
+	}
+
+	nfsURL := (&url.URL{Scheme: "nfs", Host: host, Path: export}).String()
+	if len(query) > 0 {
+		nfsURL += "?" + strings.Join(query, "&")
+	}
+	return nfsURL, readOnly, nil
+}
+
+// splitSource splits host:/export, allowing bracketed IPv6 addresses, which
+// are kept in brackets for the url.
+func splitSource(source string) (string, string, error) {
+	invalid := safeerrors.New(safeerrors.InvalidSource, "invalid nfs source '%s', expected host:/export", source)
+
+	hostEnd := 0
+	if strings.HasPrefix(source, "[") {
+		hostEnd = strings.Index(source, "]")
+		if hostEnd < 0 {
+			return "", "", invalid
+		}
+		hostEnd++
+	}
+
+	sep := strings.Index(source[hostEnd:], ":/")
+	if sep < 0 || sep+hostEnd == 0 {
+		return "", "", invalid
+	}
+	sep += hostEnd
+
+	return source[:sep], source[sep+1:], nil
+}
+
+func boolOpt(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		switch strings.ToLower(v) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+
+	return false, fmt.Errorf("not a boolean: %v", value)
+}
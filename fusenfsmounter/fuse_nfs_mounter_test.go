@@ -0,0 +1,175 @@
+package fusenfsmounter_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/goshims/ioutilshim/ioutil_fake"
+	"code.cloudfoundry.org/goshims/osshim/os_fake"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/fusenfsmounter"
+	"code.cloudfoundry.org/volumedriver/invokerfakes"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FuseNFSMounter", func() {
+	var (
+		logger           *lagertest.TestLogger
+		env              dockerdriver.Env
+		fakeInvoker      *invokerfakes.FakeInvoker
+		fakeInvokeResult *invokerfakes.FakeInvokeResult
+		fakeOs           *os_fake.FakeOs
+		fakeIoutil       *ioutil_fake.FakeIoutil
+		fakeMountChecker *volumedriverfakes.FakeMountChecker
+		mounter          volumedriver.Mounter
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("fusenfsmounter")
+		env = driverhttp.NewHttpDriverEnv(logger, context.TODO())
+		fakeInvoker = &invokerfakes.FakeInvoker{}
+		fakeInvokeResult = &invokerfakes.FakeInvokeResult{}
+		fakeInvoker.InvokeReturns(fakeInvokeResult)
+		fakeOs = &os_fake.FakeOs{}
+		fakeIoutil = &ioutil_fake.FakeIoutil{}
+		fakeMountChecker = &volumedriverfakes.FakeMountChecker{}
+
+		dirInfo := &ioutil_fake.FakeFileInfo{}
+		dirInfo.IsDirReturns(true)
+		fakeOs.StatReturns(dirInfo, nil)
+
+		mounter = fusenfsmounter.NewFuseNFSMounter(fakeInvoker, fakeOs, fakeIoutil, fakeMountChecker)
+	})
+
+	Describe("Mount", func() {
+		var (
+			source string
+			opts   map[string]interface{}
+			err    error
+		)
+
+		BeforeEach(func() {
+			source = "nfs-server:/export/path"
+			opts = map[string]interface{}{"vers": "3", "uid": float64(1000), "ro": true}
+		})
+
+		JustBeforeEach(func() {
+			err = mounter.Mount(env, source, "/mnt/target", opts)
+		})
+
+		It("mounts the export with fuse-nfs", func() {
+			Expect(err).NotTo(HaveOccurred())
+			_, cmd, args, _ := fakeInvoker.InvokeArgsForCall(0)
+			Expect(cmd).To(Equal("fuse-nfs"))
+			Expect(args).To(Equal([]string{"--nfs-share", "nfs://nfs-server/export/path?uid=1000&version=3", "--mountpoint", "/mnt/target", "--allow-other", "--read-only"}))
+		})
+
+		Context("when the source is an IPv6 address", func() {
+			BeforeEach(func() {
+				source = "[fd00::1]:/export"
+				opts = map[string]interface{}{}
+			})
+
+			It("keeps the brackets in the url", func() {
+				_, _, args, _ := fakeInvoker.InvokeArgsForCall(0)
+				Expect(args).To(Equal([]string{"--nfs-share", "nfs://[fd00::1]/export", "--mountpoint", "/mnt/target", "--allow-other"}))
+			})
+		})
+
+		Context("when the driver hardens the mount", func() {
+			BeforeEach(func() {
+				opts["nosuid"] = true
+				opts["nodev"] = true
+				opts["noexec"] = true
+			})
+
+			It("ignores the hardening opts, which fuse-nfs does not take", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeInvoker.InvokeCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when an option is not supported", func() {
+			BeforeEach(func() {
+				opts["nolock"] = true
+			})
+
+			It("rejects the mount", func() {
+				Expect(err).To(Equal(safeerrors.New(safeerrors.InvalidOption, "Not allowed options: nolock")))
+				Expect(fakeInvoker.InvokeCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when a value would change the url", func() {
+			BeforeEach(func() {
+				opts["uid"] = "0&gid=0"
+			})
+
+			It("rejects the mount", func() {
+				Expect(err).To(Equal(safeerrors.New(safeerrors.InvalidOption, "Invalid value for option 'uid': 0&gid=0")))
+			})
+		})
+
+		Context("when the source has no export path", func() {
+			BeforeEach(func() {
+				source = "nfs-server"
+			})
+
+			It("rejects the mount", func() {
+				Expect(err).To(Equal(safeerrors.New(safeerrors.InvalidSource, "invalid nfs source 'nfs-server', expected host:/export")))
+			})
+		})
+
+		Context("when fuse-nfs fails", func() {
+			BeforeEach(func() {
+				fakeInvokeResult.WaitReturns(errors.New("exit status 1"))
+				fakeInvokeResult.StdErrorReturns("Failed to mount nfs share : nfs_service failed\n")
+			})
+
+			It("returns the command error", func() {
+				Expect(err).To(MatchError("fuse-nfs mount failed: Failed to mount nfs share : nfs_service failed"))
+			})
+		})
+	})
+
+	Describe("Unmount", func() {
+		It("unmounts the target with fusermount", func() {
+			Expect(mounter.Unmount(env, "/mnt/target")).To(Succeed())
+			_, cmd, args, _ := fakeInvoker.InvokeArgsForCall(0)
+			Expect(cmd).To(Equal("fusermount"))
+			Expect(args).To(Equal([]string{"-u", "/mnt/target"}))
+		})
+	})
+
+	Describe("Check", func() {
+		It("reports a healthy mount", func() {
+			fakeMountChecker.ExistsReturns(true, nil)
+			Expect(mounter.Check(env, "some-volume", "/mnt/target", volumedriver.CheckRead)).To(BeTrue())
+			Expect(fakeIoutil.ReadDirCallCount()).To(Equal(1))
+		})
+
+		It("reports a missing mount as unhealthy", func() {
+			fakeMountChecker.ExistsReturns(false, nil)
+			Expect(mounter.Check(env, "some-volume", "/mnt/target", volumedriver.CheckStat)).To(BeFalse())
+		})
+	})
+
+	Describe("Purge", func() {
+		It("lazily unmounts and removes every mountpoint below the path", func() {
+			fakeMountChecker.ListReturns([]string{"/mnt/root/a"}, nil)
+			mounter.Purge(env, "/mnt/root")
+
+			Expect(fakeMountChecker.ListArgsForCall(0)).To(Equal(regexp.MustCompile("^/mnt/root/")))
+			_, _, args, _ := fakeInvoker.InvokeArgsForCall(0)
+			Expect(args).To(Equal([]string{"-u", "-z", "/mnt/root/a"}))
+			Expect(fakeOs.RemoveArgsForCall(0)).To(Equal("/mnt/root/a"))
+		})
+	})
+})
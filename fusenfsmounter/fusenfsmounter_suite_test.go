@@ -0,0 +1,13 @@
+package fusenfsmounter_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestFuseNFSMounter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "FuseNFSMounter Suite")
+}
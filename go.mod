@@ -3,7 +3,7 @@ module code.cloudfoundry.org/volumedriver
 go 1.13
 
 require (
-	code.cloudfoundry.org/cfhttp v2.0.0+incompatible // indirect
+	code.cloudfoundry.org/cfhttp v2.0.0+incompatible
 	code.cloudfoundry.org/clock v1.0.0 // indirect
 	code.cloudfoundry.org/dockerdriver v0.0.0-20200131001834-1b34132928c1
 	code.cloudfoundry.org/goshims v0.4.0
@@ -16,5 +16,5 @@ require (
 	github.com/onsi/ginkgo v1.14.2
 	github.com/onsi/gomega v1.10.3
 	github.com/tedsuo/ifrit v0.0.0-20191009134036-9a97d0632f00 // indirect
-	github.com/tedsuo/rata v1.0.0 // indirect
+	github.com/tedsuo/rata v1.0.0
 )
@@ -0,0 +1,105 @@
+package volumedriver
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Hardening opts are added to every mount, so that the files of an export
+// cannot be used to escalate privileges on the cell. The opt that relaxes
+// each of them is stripped from the opts of volumes.
+const (
+	NosuidOpt = "nosuid"
+	NodevOpt  = "nodev"
+	NoexecOpt = "noexec"
+)
+
+var hardeningOpts = []struct {
+	opt     string
+	relaxed string
+}{
+	{NosuidOpt, "suid"},
+	{NodevOpt, "dev"},
+	{NoexecOpt, "exec"},
+}
+
+// ValidateHardeningExemptions checks that every exemption names one of the
+// hardening opts.
+func ValidateHardeningExemptions(exemptions []string) error {
+	for _, exemption := range exemptions {
+		if !isHardeningOpt(exemption) {
+			return fmt.Errorf("invalid hardening exemption '%s', must be one of nosuid, nodev or noexec", exemption)
+		}
+	}
+	return nil
+}
+
+func isHardeningOpt(opt string) bool {
+	for _, hardening := range hardeningOpts {
+		if hardening.opt == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// hardenOpts returns a copy of opts with the hardening opts set, except for
+// those the policy exempts, and the stripped opts that would have relaxed
+// them.
+func (d *VolumeDriver) hardenOpts(opts map[string]interface{}) (map[string]interface{}, []string) {
+	exemptions := d.currentPolicy().HardeningExemptions
+
+	hardened := map[string]interface{}{}
+	for k, v := range opts {
+		hardened[k] = v
+	}
+
+	stripped := []string{}
+	for _, hardening := range hardeningOpts {
+		if contains(exemptions, hardening.opt) {
+			continue
+		}
+		if _, ok := hardened[hardening.relaxed]; ok {
+			delete(hardened, hardening.relaxed)
+			stripped = append(stripped, hardening.relaxed)
+		}
+		hardened[hardening.opt] = true
+	}
+	sort.Strings(stripped)
+
+	return hardened, stripped
+}
+
+// HardeningFlags returns a copy of opts without the hardening opts, for
+// Mounters that refuse the opts they do not know, and the hardening opts that
+// were set, in a fixed order. The mounter passes them on as mount flags, or
+// ignores them if its mounts have no such flags.
+func HardeningFlags(opts map[string]interface{}) (map[string]interface{}, []string) {
+	rest := map[string]interface{}{}
+	for k, v := range opts {
+		rest[k] = v
+	}
+
+	flags := []string{}
+	for _, hardening := range hardeningOpts {
+		value, ok := rest[hardening.opt]
+		if !ok {
+			continue
+		}
+		delete(rest, hardening.opt)
+		if value == true || value == "true" {
+			flags = append(flags, hardening.opt)
+		}
+	}
+
+	return rest, flags
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,263 @@
+package hdfsmounter
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/goshims/ioutilshim"
+	"code.cloudfoundry.org/goshims/osshim"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/invoker"
+	"code.cloudfoundry.org/volumedriver/mountchecker"
+	"code.cloudfoundry.org/volumedriver/mounthelper"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+)
+
+const (
+	// HDFS is the source scheme of HDFS namespaces, and the name to register
+	// the mounter under in Options.Mounters.
+	HDFS = "hdfs"
+
+	// GatewayOpt names an HDFS NFS gateway to mount the source through,
+	// instead of hadoop-fuse-dfs.
+	GatewayOpt = "gateway"
+
+	// DefaultPort is the port of the namenode when the source has none.
+	DefaultPort = "8020"
+)
+
+// fuseOpts are passed to hadoop-fuse-dfs as they are. The NFS gateway only
+// takes ro and readonly.
+var fuseOpts = map[string]bool{
+	"rdbuffer":          true,
+	"entry_timeout":     true,
+	"attribute_timeout": true,
+}
+
+// gatewayMountOpts are the only nfs options the HDFS NFS gateway supports.
+const gatewayMountOpts = "vers=3,proto=tcp,nolock,noacl,sync"
+
+var validValue = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+type hdfsMounter struct {
+	invoker      invoker.Invoker
+	os           osshim.Os
+	ioutil       ioutilshim.Ioutil
+	mountChecker mountchecker.MountChecker
+}
+
+// NewHdfsMounter returns a Mounter that exposes the HDFS data of analytics
+// clusters to apps. Sources have the form hdfs://namenode[:port][/path].
+//
+// By default the namespace is mounted with hadoop-fuse-dfs, which always
+// mounts it from its root, so the path must be empty or /; use the subdir opt
+// of the driver to give apps a directory of it. With the gateway opt the
+// source is mounted through that HDFS NFS gateway instead, which allows
+// paths and needs no fuse on the cell. The rdbuffer, entry_timeout and
+// attribute_timeout opts are passed to hadoop-fuse-dfs, and ro and readonly
+// mount read-only in both cases.
+func NewHdfsMounter(invoker invoker.Invoker, os osshim.Os, ioutil ioutilshim.Ioutil, mountChecker mountchecker.MountChecker) volumedriver.Mounter {
+	return &hdfsMounter{
+		invoker:      invoker,
+		os:           os,
+		ioutil:       ioutil,
+		mountChecker: mountChecker,
+	}
+}
+
+func (m *hdfsMounter) Mount(env dockerdriver.Env, source string, target string, opts map[string]interface{}) error {
+	logger := env.Logger().Session("hdfs-mount", lager.Data{"source": source, "target": target})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	namenode, hdfsPath, err := parseSource(source)
+	if err != nil {
+		return err
+	}
+
+	mountOpts, gateway, err := parseOpts(opts)
+	if err != nil {
+		logger.Error("invalid-opts", err)
+		return err
+	}
+
+	var cmd string
+	var args []string
+	if gateway != "" {
+		logger.Info("mounting-through-gateway", lager.Data{"gateway": gateway})
+		mountOpts = append([]string{gatewayMountOpts}, mountOpts...)
+		cmd, args = "mount", []string{"-t", "nfs", "-o", strings.Join(mountOpts, ","), gatewaySource(gateway, hdfsPath), target}
+	} else {
+		if hdfsPath != "/" {
+			return safeerrors.New(safeerrors.InvalidSource, "hadoop-fuse-dfs mounts the whole namespace, use the subdir opt for '%s' or mount it through a gateway", hdfsPath)
+		}
+		mountOpts = append([]string{"allow_other"}, mountOpts...)
+		cmd, args = "hadoop-fuse-dfs", []string{"dfs://" + namenode, target, "-o", strings.Join(mountOpts, ",")}
+	}
+
+	result := m.invoker.Invoke(env, cmd, args)
+	if err := result.Wait(); err != nil {
+		logger.Error("mount-failed", err, lager.Data{"stderr": result.StdError()})
+		return fmt.Errorf("hdfs mount failed: %s", strings.TrimSpace(result.StdError()))
+	}
+
+	return nil
+}
+
+// Unmount uses umount for both kinds of mounts, which the driver makes as
+// root.
+func (m *hdfsMounter) Unmount(env dockerdriver.Env, target string) error {
+	logger := env.Logger().Session("hdfs-unmount", lager.Data{"target": target})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	result := m.invoker.Invoke(env, "umount", []string{target})
+	if err := result.Wait(); err != nil {
+		logger.Error("unmount-failed", err, lager.Data{"stderr": result.StdError()})
+		return safeerrors.FromMountHelper(err, result.StdError(), "unmount failed")
+	}
+
+	return nil
+}
+
+func (m *hdfsMounter) Check(env dockerdriver.Env, name, mountPoint string, depth volumedriver.CheckDepth) bool {
+	logger := env.Logger().Session("hdfs-check", lager.Data{"name": name, "mount-point": mountPoint, "depth": depth})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	mounted, err := m.mountChecker.Exists(mountPoint)
+	if err != nil {
+		logger.Error("check-mounts-failed", err)
+		return false
+	}
+	if !mounted {
+		logger.Info("not-mounted")
+		return false
+	}
+
+	if err := volumedriver.ProbeMountPoint(m.os, m.ioutil, mountPoint, depth); err != nil {
+		logger.Error("probe-failed", err)
+		return false
+	}
+
+	return true
+}
+
+// Purge lazily unmounts everything below path and removes the emptied
+// mountpoints. Directory contents are never removed.
+func (m *hdfsMounter) Purge(env dockerdriver.Env, path string) {
+	logger := env.Logger().Session("hdfs-purge", lager.Data{"path": path})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	mounthelper.Purge(logger, path, m.mountChecker, m.os, mounthelper.Unmount(env, m.invoker, "umount", "-l"))
+}
+
+// parseSource returns the namenode, with the default port filled in, and the
+// cleaned path of an hdfs:// source.
+func parseSource(source string) (string, string, error) {
+	invalid := safeerrors.New(safeerrors.InvalidSource, "invalid hdfs source '%s', expected hdfs://namenode:port/path", source)
+
+	parsed, err := url.Parse(source)
+	if err != nil || strings.ToLower(parsed.Scheme) != HDFS || parsed.Host == "" {
+		return "", "", invalid
+	}
+	if parsed.User != nil || parsed.RawQuery != "" || parsed.Fragment != "" {
+		return "", "", invalid
+	}
+
+	namenode := parsed.Host
+	if parsed.Port() == "" {
+		namenode = net.JoinHostPort(parsed.Hostname(), DefaultPort)
+	}
+
+	return namenode, path.Clean("/" + parsed.Path), nil
+}
+
+// gatewaySource returns the nfs source of hdfsPath on gateway, bracketing
+// IPv6 addresses.
+func gatewaySource(gateway, hdfsPath string) string {
+	if strings.Contains(gateway, ":") {
+		gateway = "[" + gateway + "]"
+	}
+	return gateway + ":" + hdfsPath
+}
+
+// parseOpts returns the mount options for either client, and the gateway to
+// mount through, if any. Both clients take the hardening opts of the driver
+// as mount flags.
+func parseOpts(opts map[string]interface{}) ([]string, string, error) {
+	opts, flags := volumedriver.HardeningFlags(opts)
+	keys := make([]string, 0, len(opts))
+	for key := range opts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	mountOpts := []string{}
+	gateway := ""
+	for _, key := range keys {
+		value := opts[key]
+
+		switch key {
+		case GatewayOpt:
+			host, ok := value.(string)
+			if !ok || net.ParseIP(host) == nil && !validValue.MatchString(host) {
+				return nil, "", safeerrors.New(safeerrors.InvalidOption, "Invalid value for option '%s': %v", key, value)
+			}
+			gateway = host
+			continue
+		case "ro", "readonly":
+			set, err := boolOpt(value)
+			if err != nil {
+				return nil, "", safeerrors.New(safeerrors.InvalidOption, "Invalid value for option '%s': %v", key, value)
+			}
+			if set {
+				mountOpts = append(mountOpts, "ro")
+			}
+			continue
+		}
+
+		if !fuseOpts[key] {
+			return nil, "", safeerrors.New(safeerrors.InvalidOption, "Not allowed options: %s", key)
+		}
+		formatted := fmt.Sprintf("%v", value)
+		if !validValue.MatchString(formatted) {
+			return nil, "", safeerrors.New(safeerrors.InvalidOption, "Invalid value for option '%s': %v", key, value)
+		}
+		mountOpts = append(mountOpts, key+"="+formatted)
+	}
+
+	if gateway != "" {
+		for _, opt := range mountOpts {
+			if opt != "ro" {
+				return nil, "", safeerrors.New(safeerrors.InvalidOption, "Not allowed options with a gateway: %s", strings.SplitN(opt, "=", 2)[0])
+			}
+		}
+	}
+
+	return append(mountOpts, flags...), gateway, nil
+}
+
+func boolOpt(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		switch strings.ToLower(v) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+
+	return false, fmt.Errorf("not a boolean: %v", value)
+}
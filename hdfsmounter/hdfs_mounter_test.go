@@ -0,0 +1,225 @@
+package hdfsmounter_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/goshims/ioutilshim/ioutil_fake"
+	"code.cloudfoundry.org/goshims/osshim/os_fake"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/hdfsmounter"
+	"code.cloudfoundry.org/volumedriver/invokerfakes"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HdfsMounter", func() {
+	var (
+		logger           *lagertest.TestLogger
+		env              dockerdriver.Env
+		fakeInvoker      *invokerfakes.FakeInvoker
+		fakeInvokeResult *invokerfakes.FakeInvokeResult
+		fakeOs           *os_fake.FakeOs
+		fakeIoutil       *ioutil_fake.FakeIoutil
+		fakeMountChecker *volumedriverfakes.FakeMountChecker
+		mounter          volumedriver.Mounter
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("hdfsmounter")
+		env = driverhttp.NewHttpDriverEnv(logger, context.TODO())
+		fakeInvoker = &invokerfakes.FakeInvoker{}
+		fakeInvokeResult = &invokerfakes.FakeInvokeResult{}
+		fakeInvoker.InvokeReturns(fakeInvokeResult)
+		fakeOs = &os_fake.FakeOs{}
+		fakeIoutil = &ioutil_fake.FakeIoutil{}
+		fakeMountChecker = &volumedriverfakes.FakeMountChecker{}
+
+		mounter = hdfsmounter.NewHdfsMounter(fakeInvoker, fakeOs, fakeIoutil, fakeMountChecker)
+	})
+
+	Describe("Mount", func() {
+		var (
+			source string
+			opts   map[string]interface{}
+			err    error
+		)
+
+		BeforeEach(func() {
+			source = "hdfs://namenode.example.com"
+			opts = map[string]interface{}{}
+		})
+
+		JustBeforeEach(func() {
+			err = mounter.Mount(env, source, "/mnt/target", opts)
+		})
+
+		It("mounts the namespace with hadoop-fuse-dfs on the default port", func() {
+			Expect(err).NotTo(HaveOccurred())
+			_, cmd, args, _ := fakeInvoker.InvokeArgsForCall(0)
+			Expect(cmd).To(Equal("hadoop-fuse-dfs"))
+			Expect(args).To(Equal([]string{"dfs://namenode.example.com:8020", "/mnt/target", "-o", "allow_other"}))
+		})
+
+		Context("when options are given", func() {
+			BeforeEach(func() {
+				source = "hdfs://namenode.example.com:9000/"
+				opts = map[string]interface{}{"rdbuffer": float64(131072), "readonly": "true", "entry_timeout": "60"}
+			})
+
+			It("passes them to hadoop-fuse-dfs", func() {
+				_, _, args, _ := fakeInvoker.InvokeArgsForCall(0)
+				Expect(args).To(Equal([]string{"dfs://namenode.example.com:9000", "/mnt/target", "-o", "allow_other,entry_timeout=60,rdbuffer=131072,ro"}))
+			})
+		})
+
+		Context("when the source has a path", func() {
+			BeforeEach(func() {
+				source = "hdfs://namenode.example.com/warehouse/sales"
+			})
+
+			It("rejects the mount", func() {
+				Expect(err).To(Equal(safeerrors.New(safeerrors.InvalidSource, "hadoop-fuse-dfs mounts the whole namespace, use the subdir opt for '/warehouse/sales' or mount it through a gateway")))
+				Expect(fakeInvoker.InvokeCallCount()).To(BeZero())
+			})
+
+			Context("when a gateway is given", func() {
+				BeforeEach(func() {
+					opts = map[string]interface{}{"gateway": "nfsgw.example.com", "ro": true}
+				})
+
+				It("mounts the path through the nfs gateway", func() {
+					Expect(err).NotTo(HaveOccurred())
+					_, cmd, args, _ := fakeInvoker.InvokeArgsForCall(0)
+					Expect(cmd).To(Equal("mount"))
+					Expect(args).To(Equal([]string{"-t", "nfs", "-o", "vers=3,proto=tcp,nolock,noacl,sync,ro", "nfsgw.example.com:/warehouse/sales", "/mnt/target"}))
+				})
+			})
+
+			Context("when the gateway is an IPv6 address", func() {
+				BeforeEach(func() {
+					opts = map[string]interface{}{"gateway": "fd00::12"}
+				})
+
+				It("brackets it", func() {
+					_, _, args, _ := fakeInvoker.InvokeArgsForCall(0)
+					Expect(args[4]).To(Equal("[fd00::12]:/warehouse/sales"))
+				})
+			})
+		})
+
+		Context("when a gateway is given with hadoop-fuse-dfs options", func() {
+			BeforeEach(func() {
+				opts = map[string]interface{}{"gateway": "nfsgw.example.com", "rdbuffer": "1024"}
+			})
+
+			It("rejects the mount", func() {
+				Expect(err).To(Equal(safeerrors.New(safeerrors.InvalidOption, "Not allowed options with a gateway: rdbuffer")))
+			})
+		})
+
+		Context("when the gateway would inject other arguments", func() {
+			BeforeEach(func() {
+				opts = map[string]interface{}{"gateway": "gw,suid"}
+			})
+
+			It("rejects the mount", func() {
+				Expect(err).To(Equal(safeerrors.New(safeerrors.InvalidOption, "Invalid value for option 'gateway': gw,suid")))
+			})
+		})
+
+		Context("when the source is not an hdfs url", func() {
+			BeforeEach(func() {
+				source = "namenode.example.com:/data"
+			})
+
+			It("rejects the mount", func() {
+				Expect(err).To(Equal(safeerrors.New(safeerrors.InvalidSource, "invalid hdfs source 'namenode.example.com:/data', expected hdfs://namenode:port/path")))
+			})
+		})
+
+		Context("when the driver hardens the mount", func() {
+			BeforeEach(func() {
+				opts["nosuid"] = true
+				opts["nodev"] = true
+				opts["noexec"] = true
+			})
+
+			It("passes the hardening opts on as mount flags", func() {
+				Expect(err).NotTo(HaveOccurred())
+				_, _, args, _ := fakeInvoker.InvokeArgsForCall(0)
+				Expect(args).To(ContainElement(HaveSuffix("nosuid,nodev,noexec")))
+			})
+		})
+
+		Context("when an option is not supported", func() {
+			BeforeEach(func() {
+				opts["uid"] = "1000"
+			})
+
+			It("rejects the mount", func() {
+				Expect(err).To(Equal(safeerrors.New(safeerrors.InvalidOption, "Not allowed options: uid")))
+			})
+		})
+
+		Context("when the mount command fails", func() {
+			BeforeEach(func() {
+				fakeInvokeResult.WaitReturns(errors.New("exit status 1"))
+				fakeInvokeResult.StdErrorReturns("fuse-dfs didn't recognize /mnt/target,-2\n")
+			})
+
+			It("returns the command error", func() {
+				Expect(err).To(MatchError("hdfs mount failed: fuse-dfs didn't recognize /mnt/target,-2"))
+			})
+		})
+	})
+
+	Describe("Unmount", func() {
+		It("unmounts the target", func() {
+			Expect(mounter.Unmount(env, "/mnt/target")).To(Succeed())
+			_, cmd, args, _ := fakeInvoker.InvokeArgsForCall(0)
+			Expect(cmd).To(Equal("umount"))
+			Expect(args).To(Equal([]string{"/mnt/target"}))
+		})
+
+		Context("when umount fails", func() {
+			BeforeEach(func() {
+				fakeInvokeResult.WaitReturns(errors.New("exit status 32"))
+				fakeInvokeResult.StdErrorReturns("umount: /mnt/target: target is busy\n")
+			})
+
+			It("returns the error", func() {
+				Expect(mounter.Unmount(env, "/mnt/target")).To(MatchError("unmount failed: the mount point is busy; stop the processes that use the volume"))
+			})
+		})
+	})
+
+	Describe("Check", func() {
+		It("fails when the mountpoint is not mounted", func() {
+			fakeMountChecker.ExistsReturns(false, nil)
+			Expect(mounter.Check(env, "volume", "/mnt/target", volumedriver.CheckStat)).To(BeFalse())
+		})
+	})
+
+	Describe("Purge", func() {
+		It("lazily unmounts the mounts below the path", func() {
+			fakeMountChecker.ListReturns([]string{"/var/vcap/data/volumes/a", "/var/vcap/data/volumes/b"}, nil)
+
+			mounter.Purge(env, "/var/vcap/data/volumes")
+
+			pattern := fakeMountChecker.ListArgsForCall(0)
+			Expect(pattern).To(Equal(regexp.MustCompile("^/var/vcap/data/volumes/")))
+			Expect(fakeInvoker.InvokeCallCount()).To(Equal(2))
+			_, cmd, args, _ := fakeInvoker.InvokeArgsForCall(0)
+			Expect(cmd).To(Equal("umount"))
+			Expect(args).To(Equal([]string{"-l", "/var/vcap/data/volumes/b"}))
+			Expect(fakeOs.RemoveCallCount()).To(Equal(2))
+		})
+	})
+})
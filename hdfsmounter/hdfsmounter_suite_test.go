@@ -0,0 +1,13 @@
+package hdfsmounter_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestHdfsMounter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "HdfsMounter Suite")
+}
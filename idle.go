@@ -0,0 +1,26 @@
+package volumedriver
+
+import "time"
+
+// IdleSince returns when the driver last handled a request, or false while a
+// request is in flight or any volume is mounted, so that an on-demand driver
+// only exits when nothing depends on it.
+func (d *VolumeDriver) IdleSince() (time.Time, bool) {
+	if d.anyMounted() {
+		return time.Time{}, false
+	}
+
+	return d.inFlight.idleSince()
+}
+
+func (d *VolumeDriver) anyMounted() bool {
+	d.volumesLock.RLock()
+	defer d.volumesLock.RUnlock()
+
+	for _, volume := range d.volumes {
+		if volume.MountCount > 0 {
+			return true
+		}
+	}
+	return false
+}
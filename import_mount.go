@@ -0,0 +1,169 @@
+package volumedriver
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager"
+)
+
+// ImportMount adopts a path that is already mounted, for example by hand
+// before the driver managed the host, as the volume Name. The mount is left
+// in place: the driver only checks that it exists and is healthy, and from
+// then on unmounts it once its MountCount drops to zero.
+func (d *VolumeDriver) ImportMount(env dockerdriver.Env, importRequest ImportMountRequest) dockerdriver.ErrorResponse {
+	logger := env.Logger().Session("import-mount", lager.Data{"volume": importRequest.Name, "mountpoint": importRequest.Mountpoint})
+	logger.Info("start")
+	defer logger.Info("end")
+	defer d.inFlight.start(env, "import-mount", importRequest.Name).done()
+
+	if importRequest.Name == "" {
+		return dockerdriver.ErrorResponse{Err: "Missing mandatory 'volume_name'"}
+	}
+	if err := ValidateVolumeName(importRequest.Name); err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+
+	if err := ValidateOpts(importRequest.Opts); err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+	if _, err := ParseSource(importRequest.Opts); err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+
+	opts, err := d.normalizeSource(importRequest.Opts)
+	if err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+
+	if err := d.checkSourceAllowed(sourceOpt(opts)); err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+
+	opts = d.applySourceDefaults(opts)
+	if err := validateDriverOpts(opts); err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+	if err := d.validateSecretRefs(opts); err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+
+	driver, err := d.volumeDriver(opts)
+	if err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+
+	// these are set up by the driver when it mounts, an existing mount has
+	// neither the export mount, the cgroup limits, the overlay nor the
+	// member mounts they depend on
+	if subdir, _ := parseSubdirOpts(opts); subdir.subdir != "" {
+		return dockerdriver.ErrorResponse{Err: "the subdir opt cannot be used with an imported mount"}
+	}
+	if ioLimits, _ := parseIOLimits(opts); ioLimits != nil {
+		return dockerdriver.ErrorResponse{Err: "io limits cannot be used with an imported mount"}
+	}
+	if scratch, _ := parseScratchOpt(opts); scratch {
+		return dockerdriver.ErrorResponse{Err: "the scratch opt cannot be used with an imported mount"}
+	}
+	if composite, _ := parseCompositeOpt(opts); composite != nil {
+		return dockerdriver.ErrorResponse{Err: "the composite opt cannot be used with an imported mount"}
+	}
+
+	mountCount := importRequest.MountCount
+	if mountCount == 0 {
+		mountCount = 1
+	}
+	if mountCount < 0 {
+		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("invalid mount count %d, must be positive", mountCount)}
+	}
+
+	mountpoint, mountRoot, errResponse := d.importMountpoint(importRequest.Mountpoint)
+	if errResponse.Err != "" {
+		return errResponse
+	}
+
+	mounted, err := d.mountChecker.Exists(mountpoint)
+	if err != nil {
+		logger.Error("check-mount-failed", err)
+		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("Error checking mountpoint '%s': %s", mountpoint, err.Error())}
+	}
+	if !mounted {
+		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("'%s' is not mounted", mountpoint)}
+	}
+
+	d.volumesLock.Lock()
+	defer d.volumesLock.Unlock()
+
+	if _, ok := d.volumes[importRequest.Name]; ok {
+		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("Volume '%s' already exists", importRequest.Name)}
+	}
+
+	if err := d.checkVolumeQuota(); err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+	if err := d.checkMountQuota(sourceOpt(opts)); err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+
+	directory := filepath.Base(mountpoint)
+	if d.mountDirectoryTaken(importRequest.Name, directory) {
+		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("'%s' is the mountpoint of another volume", mountpoint)}
+	}
+
+	volume := &NfsVolumeInfo{
+		VolumeInfo:     dockerdriver.VolumeInfo{Name: importRequest.Name, Mountpoint: mountpoint, MountCount: mountCount},
+		Opts:           opts,
+		Driver:         driver,
+		MountDirectory: directory,
+		MountRoot:      mountRoot,
+	}
+	volume.ExpiresAt = d.expiresAt(opts)
+	volume.Labels = volumeLabels(opts)
+
+	if !d.volumeMounter(volume).Check(driverhttp.EnvWithLogger(logger, env), volume.Name, mountpoint, d.checkDepth(volume)) {
+		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("the mount at '%s' failed its health check", mountpoint)}
+	}
+
+	mountedAt := d.time.Now()
+	volume.LastMountedAt = &mountedAt
+
+	d.volumes[importRequest.Name] = volume
+	if err := d.persistVolume(driverhttp.EnvWithLogger(logger, env), importRequest.Name); err != nil {
+		logger.Error("persist-state-failed", err)
+		delete(d.volumes, importRequest.Name)
+		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("persist state failed when importing: %s", err.Error())}
+	}
+	d.emitVolumeGauges()
+
+	logger.Info("mount-imported", lager.Data{"mount-count": mountCount})
+	d.publish(EventCreated, importRequest.Name, nil)
+	d.publish(EventMounted, importRequest.Name, nil)
+	return dockerdriver.ErrorResponse{}
+}
+
+// importMountpoint checks that mountpoint is a directory directly below one
+// of the mount path roots that the driver could have chosen itself, so that
+// the imported volume is handled like any other. It also returns the
+// MountRoot of the volume.
+func (d *VolumeDriver) importMountpoint(mountpoint string) (string, string, dockerdriver.ErrorResponse) {
+	if mountpoint == "" {
+		return "", "", dockerdriver.ErrorResponse{Err: "Missing mandatory 'mountpoint'"}
+	}
+
+	mountpoint = filepath.Clean(mountpoint)
+	mountRoot, ok := d.rootOfMountpoint(mountpoint)
+	if !ok {
+		root, err := d.filepath.Abs(d.mountPathRoot)
+		if err != nil {
+			return "", "", dockerdriver.ErrorResponse{Err: err.Error()}
+		}
+		return "", "", dockerdriver.ErrorResponse{Err: fmt.Sprintf("mountpoint '%s' must be directly below %s", mountpoint, root)}
+	}
+	if !isSafeMountDirectory(filepath.Base(mountpoint)) {
+		return "", "", dockerdriver.ErrorResponse{Err: fmt.Sprintf("'%s' cannot be used as a mountpoint", mountpoint)}
+	}
+
+	return mountpoint, mountRoot, dockerdriver.ErrorResponse{}
+}
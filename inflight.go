@@ -0,0 +1,137 @@
+package volumedriver
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+)
+
+// OperationState tells whether an in-flight operation is executing or
+// waiting for its turn.
+type OperationState string
+
+const (
+	// OperationQueued operations wait for the volume lock, or for the
+	// mount of the same volume by another request.
+	OperationQueued  OperationState = "queued"
+	OperationRunning OperationState = "running"
+)
+
+// InFlightOperation is a driver call that has not returned yet. Caller is
+// set for requests that identify with WithCaller.
+type InFlightOperation struct {
+	Operation string
+	Volume    string
+	Caller    string         `json:",omitempty"`
+	State     OperationState `json:",omitempty"`
+	StartedAt time.Time
+}
+
+// trackedOperation is an operation recorded by an inFlightTracker.
+type trackedOperation struct {
+	tracker *inFlightTracker
+	id      uint64
+}
+
+type inFlightTracker struct {
+	lock     sync.Mutex
+	nextId   uint64
+	ops      map[uint64]InFlightOperation
+	lastDone time.Time
+}
+
+func newInFlightTracker() *inFlightTracker {
+	return &inFlightTracker{ops: map[uint64]InFlightOperation{}, lastDone: time.Now()}
+}
+
+// start records an operation that is executing.
+func (t *inFlightTracker) start(env dockerdriver.Env, operation string, volume string) *trackedOperation {
+	return t.track(env, operation, volume, OperationRunning)
+}
+
+// queue records an operation that waits before it executes, see
+// trackedOperation.running.
+func (t *inFlightTracker) queue(env dockerdriver.Env, operation string, volume string) *trackedOperation {
+	return t.track(env, operation, volume, OperationQueued)
+}
+
+func (t *inFlightTracker) track(env dockerdriver.Env, operation string, volume string, state OperationState) *trackedOperation {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	id := t.nextId
+	t.nextId++
+	t.ops[id] = InFlightOperation{
+		Operation: operation,
+		Volume:    volume,
+		Caller:    CallerFrom(env.Context()),
+		State:     state,
+		StartedAt: time.Now(),
+	}
+
+	return &trackedOperation{tracker: t, id: id}
+}
+
+func (o *trackedOperation) running() {
+	o.setState(OperationRunning)
+}
+
+func (o *trackedOperation) queued() {
+	o.setState(OperationQueued)
+}
+
+func (o *trackedOperation) setState(state OperationState) {
+	o.tracker.lock.Lock()
+	defer o.tracker.lock.Unlock()
+
+	if op, ok := o.tracker.ops[o.id]; ok {
+		op.State = state
+		o.tracker.ops[o.id] = op
+	}
+}
+
+// done completes the operation.
+func (o *trackedOperation) done() {
+	o.tracker.lock.Lock()
+	defer o.tracker.lock.Unlock()
+
+	delete(o.tracker.ops, o.id)
+	o.tracker.lastDone = time.Now()
+}
+
+// list returns the operations oldest first.
+func (t *inFlightTracker) list() []InFlightOperation {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	ops := []InFlightOperation{}
+	for _, op := range t.ops {
+		ops = append(ops, op)
+	}
+	sort.SliceStable(ops, func(i, j int) bool {
+		return ops[i].StartedAt.Before(ops[j].StartedAt)
+	})
+
+	return ops
+}
+
+// idleSince returns when the last operation completed, or false while
+// operations are in flight.
+func (t *inFlightTracker) idleSince() (time.Time, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if len(t.ops) > 0 {
+		return time.Time{}, false
+	}
+	return t.lastDone, true
+}
+
+// InFlightOperations lists the operations that are executing or queued. It
+// does not need the volume lock, so that it answers while a stuck mount
+// holds it.
+func (d *VolumeDriver) InFlightOperations(env dockerdriver.Env) InFlightOperationsResponse {
+	return InFlightOperationsResponse{Operations: d.inFlight.list()}
+}
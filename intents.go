@@ -0,0 +1,156 @@
+package volumedriver
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+)
+
+const (
+	intentFileSuffix = ".intent"
+
+	IntentMount   = "mount"
+	IntentUnmount = "unmount"
+)
+
+// Intent is written ahead of every call to the Mounter that changes kernel
+// mounts and removed once the call returns. An intent that is still present
+// on startup belongs to an operation that was interrupted by a crash.
+type Intent struct {
+	Operation string
+	Volume    string
+	MountPath string
+}
+
+func (d *VolumeDriver) recordIntent(env dockerdriver.Env, operation string, volumeName string, mountPath string) error {
+	logger := env.Logger().Session("record-intent", lager.Data{"operation": operation, "volume": volumeName})
+
+	// intents are kept next to the state of the volume, on the root of its
+	// mountpoint
+	stateDir, err := d.stateDir(env, filepath.Dir(mountPath))
+	if err != nil {
+		return err
+	}
+	intentFile := filepath.Join(stateDir, intentFileName(volumeName))
+
+	intentData, err := json.Marshal(Intent{
+		Operation: operation,
+		Volume:    volumeName,
+		MountPath: mountPath,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := d.ioutil.WriteFile(intentFile, intentData, os.ModePerm); err != nil {
+		logger.Error("failed-to-write-intent-file", err, lager.Data{"intentFile": intentFile})
+		return fmt.Errorf("failed to record %s intent: %s", operation, err)
+	}
+
+	return nil
+}
+
+func (d *VolumeDriver) clearIntent(env dockerdriver.Env, volumeName string, mountPath string) {
+	logger := env.Logger().Session("clear-intent", lager.Data{"volume": volumeName})
+
+	intentFile := filepath.Join(filepath.Dir(mountPath), stateDirName, intentFileName(volumeName))
+	if err := d.os.Remove(intentFile); err != nil && !os.IsNotExist(err) {
+		logger.Error("failed-to-remove-intent-file", err, lager.Data{"intentFile": intentFile})
+	}
+}
+
+// restoreIntent parses an intent file found while restoring state.
+func (d *VolumeDriver) restoreIntent(env dockerdriver.Env, intentFile string) (Intent, bool) {
+	logger := env.Logger().Session("restore-intent")
+
+	var intent Intent
+	intentData, err := d.ioutil.ReadFile(intentFile)
+	if err == nil {
+		err = json.Unmarshal(intentData, &intent)
+	}
+	if err != nil || intent.Volume == "" {
+		logger.Error("failed-to-read-intent-file", err, lager.Data{"intentFile": intentFile})
+		return Intent{}, false
+	}
+
+	return intent, true
+}
+
+// resolveIntents must be called with volumesLock held. Interrupted mounts are
+// rolled back, since the caller never got a mountpoint, and interrupted
+// unmounts are completed.
+func (d *VolumeDriver) resolveIntents(env dockerdriver.Env, intents []Intent) {
+	logger := env.Logger().Session("resolve-intents")
+	logger.Info("start")
+	defer logger.Info("end")
+
+	for _, intent := range intents {
+		logger.Info("resolving-intent", lager.Data{"intent": intent})
+
+		volume := d.volumes[intent.Volume]
+
+		switch intent.Operation {
+		case IntentMount:
+			if volume != nil && volume.MountCount > 0 {
+				volume.MountCount--
+			}
+			if volume == nil || volume.MountCount < 1 {
+				d.removeKernelMount(env, d.volumeMounter(volume), intent.MountPath)
+			}
+			if volume != nil {
+				if volume.MountCount < 1 {
+					volume.Mountpoint = ""
+				}
+				if err := d.persistVolume(env, intent.Volume); err != nil {
+					logger.Error("persist-volume-failed", err, lager.Data{"volume": intent.Volume})
+					continue
+				}
+			}
+		case IntentUnmount:
+			d.removeKernelMount(env, d.volumeMounter(volume), intent.MountPath)
+			delete(d.volumes, intent.Volume)
+			if err := d.removeVolumeState(env, intent.Volume); err != nil {
+				logger.Error("remove-volume-state-failed", err, lager.Data{"volume": intent.Volume})
+				continue
+			}
+		default:
+			logger.Info("unknown-intent", lager.Data{"intent": intent})
+		}
+
+		d.clearIntent(env, intent.Volume, intent.MountPath)
+	}
+}
+
+func (d *VolumeDriver) removeKernelMount(env dockerdriver.Env, mounter Mounter, mountPath string) {
+	logger := env.Logger().Session("remove-kernel-mount", lager.Data{"mount-path": mountPath})
+
+	if mountPath == "" {
+		return
+	}
+
+	mounted, err := d.mountChecker.Exists(mountPath)
+	if err != nil {
+		logger.Error("failed-proc-mounts-check", err)
+		return
+	}
+
+	if mounted {
+		if err := mounter.Unmount(env, mountPath); err != nil {
+			logger.Error("unmount-failed", err)
+			return
+		}
+	}
+
+	if err := d.removeMountpoint(env, mountPath); err != nil && !os.IsNotExist(err) {
+		logger.Error("remove-mountpoint-failed", err)
+	}
+}
+
+func intentFileName(volumeName string) string {
+	return strings.TrimSuffix(stateFileName(volumeName), stateFileSuffix) + intentFileSuffix
+}
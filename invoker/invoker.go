@@ -6,10 +6,13 @@ import (
 	"errors"
 	"os/exec"
 	"strings"
-	"syscall"
 	"time"
 )
 
+// SessionPrefix starts the log session of every command an invoker runs, so
+// that the logs of commands can be told from the logs of the driver.
+const SessionPrefix = "invoking-"
+
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -o ../invokerfakes/fake_invoke_result.go . InvokeResult
 type InvokeResult interface {
 	StdError() string
@@ -46,6 +49,7 @@ func (i invokeResult) Wait() error {
 	}
 	wait := i.cmd.Wait()
 	*i.cmdDone = true
+	logOutput(i.logger, i.StdOutput(), i.StdError())
 	return wait
 }
 
@@ -71,7 +75,7 @@ func (i invokeResult) WaitFor(stringToWaitFor string, duration time.Duration) er
 			}
 			return e
 		case <-timeout:
-			err := syscall.Kill(-i.cmd.Process.Pid, syscall.SIGKILL)
+			err := killProcessGroup(i.cmd.Process.Pid)
 			if err != nil {
 				i.logger.Info("command-sigkill-error", lager.Data{"desc": err.Error()})
 			}
@@ -88,3 +92,11 @@ func (i invokeResult) WaitFor(stringToWaitFor string, duration time.Duration) er
 func (i invokeResult) isExpectedTextContainedInStdOut(stringToWaitFor string) bool {
 	return strings.Contains(i.StdOutput(), stringToWaitFor)
 }
+
+// logOutput logs the output of a finished command, if it wrote any.
+func logOutput(logger lager.Logger, stdout, stderr string) {
+	if stdout == "" && stderr == "" {
+		return
+	}
+	logger.Debug("command-output", lager.Data{"stdout": stdout, "stderr": stderr})
+}
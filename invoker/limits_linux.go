@@ -0,0 +1,21 @@
+package invoker
+
+import "fmt"
+
+// limitCommand runs the command through prlimit when limits are set.
+func limitCommand(limits Limits, executable string, args []string) (string, []string, error) {
+	if limits.CPUSeconds == 0 && limits.MemoryBytes == 0 {
+		return executable, args, nil
+	}
+
+	prlimitArgs := []string{}
+	if limits.CPUSeconds > 0 {
+		prlimitArgs = append(prlimitArgs, fmt.Sprintf("--cpu=%d", limits.CPUSeconds))
+	}
+	if limits.MemoryBytes > 0 {
+		prlimitArgs = append(prlimitArgs, fmt.Sprintf("--as=%d", limits.MemoryBytes))
+	}
+	prlimitArgs = append(prlimitArgs, "--", executable)
+
+	return "prlimit", append(prlimitArgs, args...), nil
+}
@@ -0,0 +1,13 @@
+// +build !linux
+
+package invoker
+
+import "errors"
+
+func limitCommand(limits Limits, executable string, args []string) (string, []string, error) {
+	if limits.CPUSeconds == 0 && limits.MemoryBytes == 0 {
+		return executable, args, nil
+	}
+
+	return "", nil, errors.New("resource limits are only supported on linux")
+}
@@ -0,0 +1,85 @@
+package invoker
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Exec creates the commands of helper processes. Tests inject one that
+// runs stand-ins for the helpers.
+type Exec interface {
+	Command(name string, args ...string) *exec.Cmd
+}
+
+type osExec struct{}
+
+// NewExec returns the Exec of os/exec.
+func NewExec() Exec {
+	return osExec{}
+}
+
+func (osExec) Command(name string, args ...string) *exec.Cmd {
+	return exec.Command(name, args...)
+}
+
+// Paths locate the helper binaries in minimal root filesystems. Binaries
+// maps the names the mounters invoke, such as mount or umount, to absolute
+// paths. Other names are looked up in Path, which also becomes the PATH of
+// the helpers, so that mount finds its mount.nfs; the PATH of the driver
+// is used when it is empty.
+type Paths struct {
+	Binaries map[string]string
+	Path     string
+}
+
+// Validate fails for binaries that are not absolute paths.
+func (p Paths) Validate() error {
+	for name, binary := range p.Binaries {
+		if name == "" || !filepath.IsAbs(binary) {
+			return fmt.Errorf("invalid helper binary '%s=%s', must be a name and an absolute path", name, binary)
+		}
+	}
+	for _, dir := range filepath.SplitList(p.Path) {
+		if !filepath.IsAbs(dir) {
+			return fmt.Errorf("invalid helper path '%s', must be a list of absolute directories", p.Path)
+		}
+	}
+	return nil
+}
+
+// Resolve returns the path that executable is run from. Names without a
+// configured binary or a match in Path are left to the PATH of the driver.
+func (p Paths) Resolve(executable string) string {
+	if binary, ok := p.Binaries[executable]; ok {
+		return binary
+	}
+	if p.Path == "" || strings.ContainsRune(executable, filepath.Separator) {
+		return executable
+	}
+
+	for _, dir := range filepath.SplitList(p.Path) {
+		candidate := filepath.Join(dir, executable)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+			return candidate
+		}
+	}
+	return executable
+}
+
+// env returns the environment of the helpers, without envVars when it is
+// the one of the driver.
+func (p Paths) env(envVars []string) []string {
+	if p.Path == "" && len(envVars) == 0 {
+		return nil
+	}
+
+	env := os.Environ()
+	if p.Path != "" {
+		// the last PATH wins
+		env = append(env, "PATH="+p.Path)
+	}
+	return append(env, envVars...)
+}
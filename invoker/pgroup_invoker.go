@@ -6,7 +6,6 @@ import (
 	"context"
 	"os"
 	"os/exec"
-	"syscall"
 )
 
 type pgroupInvoker struct {
@@ -17,14 +16,13 @@ func NewProcessGroupInvoker() Invoker {
 }
 
 func (r *pgroupInvoker) Invoke(env dockerdriver.Env, executable string, cmdArgs []string, envVars... string) InvokeResult {
-	logger := env.Logger().Session("invoking-command-pgroup", lager.Data{"executable": executable, "args": cmdArgs})
+	logger := env.Logger().Session(SessionPrefix+"command-pgroup", lager.Data{"executable": executable, "args": cmdArgs})
 	logger.Info("start")
 	defer logger.Info("end")
 
 	// We do not pass in the docker context to let the exec.Command handle timeout/cancel, because we want to kill the entire process group. (Mount spawns child processes, which we also want to kill)
 	cmdHandle := exec.CommandContext(context.Background(), executable, cmdArgs...)
-	cmdHandle.SysProcAttr = &syscall.SysProcAttr{}
-	cmdHandle.SysProcAttr.Setpgid = true
+	setProcessGroup(cmdHandle)
 
 	var stdOutBuffer, stdErrBuffer Buffer
 	cmdHandle.Stdout = &stdOutBuffer
@@ -57,7 +55,7 @@ func (r *pgroupInvoker) Invoke(env dockerdriver.Env, executable string, cmdArgs
 				return
 			}
 			logger.Info("command-sigkill", lager.Data{"exe": executable, "pid": -cmdHandle.Process.Pid})
-			err := syscall.Kill(-cmdHandle.Process.Pid, syscall.SIGKILL)
+			err := killProcessGroup(cmdHandle.Process.Pid)
 			if err != nil {
 				logger.Info("command-sigkill-error", lager.Data{"desc": err.Error()})
 			}
@@ -1,3 +1,5 @@
+// +build linux darwin
+
 package invoker_test
 
 import (
@@ -231,13 +233,20 @@ sleep 7777`}
 			})
 
 			Context("timing out on the docker context", func() {
+				var cancelfunc context.CancelFunc
+
 				BeforeEach(func() {
-					deadline, _ := context.WithDeadline(context.Background(), time.Now().Add(1*time.Second))
+					var deadline context.Context
+					deadline, cancelfunc = context.WithDeadline(context.Background(), time.Now().Add(1*time.Second))
 					dockerDriverEnv = driverhttp.NewHttpDriverEnv(lagertest.NewTestLogger("test-pgInvoker"), deadline)
 					execToInvoke = "bash"
 					argsToExecToInvoke = []string{"-c", "echo $$; sleep 10"}
 				})
 
+				AfterEach(func() {
+					cancelfunc()
+				})
+
 				It("should kill the process group", func() {
 					var pid int
 					By("determining the pid of the invoked process", func() {
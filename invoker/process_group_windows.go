@@ -0,0 +1,16 @@
+// +build windows
+
+package invoker
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+// setProcessGroup is a no-op, windows has no process groups. The process
+// tree is killed by pid instead.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func killProcessGroup(pid int) error {
+	return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(pid)).Run()
+}
@@ -0,0 +1,36 @@
+package invoker
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+var processStates = map[string]string{
+	"R": "running",
+	"S": "sleeping",
+	"D": "disk sleep",
+	"Z": "zombie",
+	"T": "stopped",
+	"t": "tracing stop",
+	"X": "dead",
+	"I": "idle",
+}
+
+// processState reads the state of pid from /proc/<pid>/stat, whose second
+// field, the command name in parentheses, may itself contain spaces.
+func processState(pid int) string {
+	stat, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return ""
+	}
+
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	if len(fields) == 0 {
+		return ""
+	}
+	if state, ok := processStates[fields[0]]; ok {
+		return state
+	}
+	return fields[0]
+}
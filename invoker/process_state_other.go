@@ -0,0 +1,8 @@
+// +build !linux
+
+package invoker
+
+// processState is only known on linux.
+func processState(pid int) string {
+	return ""
+}
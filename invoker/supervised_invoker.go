@@ -0,0 +1,229 @@
+package invoker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+)
+
+// Limits bound the resources of a helper process. Zero is unlimited.
+type Limits struct {
+	CPUSeconds  uint64
+	MemoryBytes uint64
+}
+
+// SupervisorOptions configure NewSupervisedInvoker. A zero Timeout lets
+// commands run until the request is cancelled. Commands are created by Exec,
+// or by os/exec when it is nil, from the binaries located by Paths.
+type SupervisorOptions struct {
+	Timeout time.Duration
+	Limits  Limits
+	Paths   Paths
+	Exec    Exec
+}
+
+// CommandError is returned by the results of a supervised invoker when a
+// command fails, along with the output it captured.
+type CommandError struct {
+	Executable string
+	Args       []string
+	Stdout     string
+	Stderr     string
+	TimedOut   bool
+	Timeout    time.Duration
+	Err        error
+}
+
+func (e *CommandError) Error() string {
+	var msg string
+	if e.TimedOut {
+		msg = fmt.Sprintf("%s timed out after %s", e.Executable, e.Timeout)
+	} else {
+		msg = fmt.Sprintf("%s failed: %s", e.Executable, e.Err)
+	}
+
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		msg += ", stderr: " + stderr
+	}
+	if stdout := strings.TrimSpace(e.Stdout); stdout != "" {
+		msg += ", stdout: " + stdout
+	}
+	return msg
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+type supervisedInvoker struct {
+	options SupervisorOptions
+}
+
+// NewSupervisedInvoker runs mount helpers in their own process group, like
+// NewProcessGroupInvoker, and kills the group once the timeout runs out or
+// the request is cancelled, so that a hung mount.nfs never blocks the driver
+// silently. Failures are returned as a *CommandError with the output of the
+// command.
+//
+// Once WaitFor has seen its text the command is left running, as helpers
+// that stay in the foreground to serve the mount must not be killed.
+//
+// Commands are recorded in the Tracker of the request context until they
+// exit, see WithTracker.
+func NewSupervisedInvoker(options SupervisorOptions) Invoker {
+	if options.Exec == nil {
+		options.Exec = NewExec()
+	}
+	return &supervisedInvoker{options: options}
+}
+
+func (s *supervisedInvoker) Invoke(env dockerdriver.Env, executable string, args []string, envVars ...string) InvokeResult {
+	logger := env.Logger().Session(SessionPrefix+"supervised-command", lager.Data{"executable": executable, "args": args, "timeout": s.options.Timeout.String()})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	result := &supervisedResult{
+		executable: executable,
+		args:       args,
+		timeout:    s.options.Timeout,
+		done:       make(chan struct{}),
+		detach:     make(chan struct{}),
+	}
+
+	command, commandArgs, err := limitCommand(s.options.Limits, s.options.Paths.Resolve(executable), args)
+	if err != nil {
+		logger.Error("limit-command-failed", err)
+		result.err = err
+		close(result.done)
+		return result
+	}
+
+	cmd := s.options.Exec.Command(s.options.Paths.Resolve(command), commandArgs...)
+	setProcessGroup(cmd)
+	result.cmd = cmd
+	cmd.Stdout = &result.stdout
+	cmd.Stderr = &result.stderr
+	// a helper that daemonizes out of the group must not hold Wait forever
+	cmd.WaitDelay = time.Second
+	cmd.Env = s.options.Paths.env(envVars)
+
+	if err := cmd.Start(); err != nil {
+		logger.Error("command-start-failed", err)
+		result.err = err
+		close(result.done)
+		return result
+	}
+
+	untrack := track(env.Context(), executable, cmd.Process.Pid)
+
+	ctx, cancel := env.Context(), context.CancelFunc(func() {})
+	if s.options.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(env.Context(), s.options.Timeout)
+	}
+
+	go func() {
+		defer close(result.done)
+		defer cancel()
+		defer untrack()
+
+		exited := make(chan error, 1)
+		go func() { exited <- cmd.Wait() }()
+
+		select {
+		case result.err = <-exited:
+		case <-result.detach:
+			result.err = <-exited
+		case <-ctx.Done():
+			result.timedOut = ctx.Err() == context.DeadlineExceeded
+			logger.Info("command-sigkill", lager.Data{"pid": -cmd.Process.Pid, "timed-out": result.timedOut})
+			if err := killProcessGroup(cmd.Process.Pid); err != nil {
+				logger.Info("command-sigkill-error", lager.Data{"desc": err.Error()})
+			}
+			result.err = <-exited
+		}
+		logOutput(logger, result.StdOutput(), result.StdError())
+	}()
+
+	return result
+}
+
+type supervisedResult struct {
+	executable string
+	args       []string
+	timeout    time.Duration
+	stdout     Buffer
+	stderr     Buffer
+	cmd        *exec.Cmd
+
+	// done is closed once the command has exited and err and timedOut are set
+	done       chan struct{}
+	detach     chan struct{}
+	detachOnce sync.Once
+	err        error
+	timedOut   bool
+}
+
+func (r *supervisedResult) StdError() string {
+	return r.stderr.String()
+}
+
+func (r *supervisedResult) StdOutput() string {
+	return r.stdout.String()
+}
+
+func (r *supervisedResult) Wait() error {
+	<-r.done
+	if r.err == nil {
+		return nil
+	}
+	return r.commandError(r.err, r.timedOut, r.timeout)
+}
+
+func (r *supervisedResult) WaitFor(stringToWaitFor string, duration time.Duration) error {
+	timeout := time.NewTimer(duration)
+	defer timeout.Stop()
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		if strings.Contains(r.StdOutput(), stringToWaitFor) {
+			r.detachOnce.Do(func() { close(r.detach) })
+			return nil
+		}
+
+		select {
+		case <-r.done:
+			if r.err != nil {
+				return r.commandError(r.err, r.timedOut, r.timeout)
+			}
+			if !strings.Contains(r.StdOutput(), stringToWaitFor) {
+				return r.commandError(errors.New("command finished without expected Text"), false, 0)
+			}
+		case <-timeout.C:
+			if r.cmd != nil {
+				killProcessGroup(r.cmd.Process.Pid)
+			}
+			return r.commandError(errors.New("command timed out"), true, duration)
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *supervisedResult) commandError(err error, timedOut bool, timeout time.Duration) error {
+	return &CommandError{
+		Executable: r.executable,
+		Args:       r.args,
+		Stdout:     r.StdOutput(),
+		Stderr:     r.StdError(),
+		TimedOut:   timedOut,
+		Timeout:    timeout,
+		Err:        err,
+	}
+}
@@ -0,0 +1,26 @@
+package invoker_test
+
+import (
+	"context"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver/invoker"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SupervisedInvoker limits", func() {
+	It("runs the command with the cpu and memory limits", func() {
+		supervisedInvoker := invoker.NewSupervisedInvoker(invoker.SupervisorOptions{
+			Timeout: 2 * time.Second,
+			Limits:  invoker.Limits{CPUSeconds: 7, MemoryBytes: 512 * 1024 * 1024},
+		})
+		env := driverhttp.NewHttpDriverEnv(lagertest.NewTestLogger("test-supervisedInvoker"), context.TODO())
+
+		result := supervisedInvoker.Invoke(env, "sh", []string{"-c", "ulimit -t; ulimit -v"})
+		Expect(result.Wait()).To(Succeed())
+		Expect(result.StdOutput()).To(Equal("7\n524288\n"))
+	})
+})
@@ -0,0 +1,177 @@
+// +build linux darwin
+
+package invoker_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver/invoker"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SupervisedInvoker", func() {
+	var (
+		supervisedInvoker invoker.Invoker
+		options           invoker.SupervisorOptions
+		dockerDriverEnv   dockerdriver.Env
+		args              []string
+		result            invoker.InvokeResult
+	)
+
+	BeforeEach(func() {
+		dockerDriverEnv = driverhttp.NewHttpDriverEnv(lagertest.NewTestLogger("test-supervisedInvoker"), context.TODO())
+		options = invoker.SupervisorOptions{Timeout: 2 * time.Second}
+	})
+
+	JustBeforeEach(func() {
+		supervisedInvoker = invoker.NewSupervisedInvoker(options)
+		result = supervisedInvoker.Invoke(dockerDriverEnv, "sh", args)
+	})
+
+	Context("when the command succeeds", func() {
+		BeforeEach(func() {
+			args = []string{"-c", "echo mounted"}
+		})
+
+		It("returns its output", func() {
+			Expect(result.Wait()).To(Succeed())
+			Expect(result.StdOutput()).To(Equal("mounted\n"))
+		})
+	})
+
+	Context("when the command fails", func() {
+		BeforeEach(func() {
+			args = []string{"-c", "echo trying; echo access denied >&2; exit 32"}
+		})
+
+		It("attaches the captured output to the error", func() {
+			err := result.Wait()
+			Expect(err).To(MatchError("sh failed: exit status 32, stderr: access denied, stdout: trying"))
+
+			var commandErr *invoker.CommandError
+			Expect(errors.As(err, &commandErr)).To(BeTrue())
+			Expect(commandErr.Args).To(Equal(args))
+			Expect(commandErr.TimedOut).To(BeFalse())
+		})
+	})
+
+	Context("when the request context has a tracker", func() {
+		var tracker *invoker.Tracker
+
+		BeforeEach(func() {
+			tracker = invoker.NewTracker()
+			dockerDriverEnv = driverhttp.EnvWithContext(invoker.WithTracker(context.TODO(), tracker), dockerDriverEnv)
+			args = []string{"-c", "sleep 0.5"}
+		})
+
+		It("records the process until it exits", func() {
+			Eventually(tracker.Processes).Should(HaveLen(1))
+			process := tracker.Processes()[0]
+			Expect(process.Executable).To(Equal("sh"))
+			Expect(process.Pid).NotTo(BeZero())
+
+			Expect(result.Wait()).To(Succeed())
+			Expect(tracker.Processes()).To(BeEmpty())
+		})
+	})
+
+	Context("when the command hangs", func() {
+		BeforeEach(func() {
+			options.Timeout = 100 * time.Millisecond
+			args = []string{"-c", "echo hanging >&2; sleep 5"}
+		})
+
+		It("kills it once the timeout runs out", func() {
+			start := time.Now()
+			err := result.Wait()
+			Expect(time.Since(start)).To(BeNumerically("<", 4*time.Second))
+			Expect(err).To(MatchError(ContainSubstring("sh timed out after 100ms")))
+
+			var commandErr *invoker.CommandError
+			Expect(errors.As(err, &commandErr)).To(BeTrue())
+			Expect(commandErr.TimedOut).To(BeTrue())
+		})
+	})
+
+	Context("when helper binaries are configured", func() {
+		var commands *recordingExec
+
+		BeforeEach(func() {
+			commands = &recordingExec{}
+			options.Exec = commands
+			options.Paths = invoker.Paths{Binaries: map[string]string{"sh": "/bin/sh"}}
+			args = []string{"-c", "echo mounted"}
+		})
+
+		It("runs them from their path through the exec", func() {
+			Expect(result.Wait()).To(Succeed())
+			Expect(commands.names).To(Equal([]string{"/bin/sh"}))
+		})
+	})
+
+	Context("when a helper path is configured", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "helper-path")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "sh"), []byte("#!/bin/sh\necho \"$PATH\"\n"), 0755)).To(Succeed())
+
+			options.Paths = invoker.Paths{Path: dir}
+			args = []string{}
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		It("looks the helpers up in it and passes it on as their PATH", func() {
+			Expect(result.Wait()).To(Succeed())
+			Expect(result.StdOutput()).To(Equal(dir + "\n"))
+		})
+	})
+
+	Context("when waiting for output", func() {
+		BeforeEach(func() {
+			args = []string{"-c", "echo ready; sleep 5"}
+		})
+
+		It("returns once the output appears", func() {
+			Expect(result.WaitFor("ready", time.Second)).To(Succeed())
+		})
+	})
+})
+
+type recordingExec struct {
+	names []string
+}
+
+func (e *recordingExec) Command(name string, args ...string) *exec.Cmd {
+	e.names = append(e.names, name)
+	return exec.Command(name, args...)
+}
+
+var _ = Describe("Paths", func() {
+	It("refuses relative binaries and directories", func() {
+		Expect(invoker.Paths{Binaries: map[string]string{"mount": "/usr/bin/mount"}, Path: "/usr/sbin:/usr/bin"}.Validate()).To(Succeed())
+		Expect(invoker.Paths{Binaries: map[string]string{"mount": "bin/mount"}}.Validate()).To(MatchError("invalid helper binary 'mount=bin/mount', must be a name and an absolute path"))
+		Expect(invoker.Paths{Path: "/usr/bin:sbin"}.Validate()).To(MatchError("invalid helper path '/usr/bin:sbin', must be a list of absolute directories"))
+	})
+
+	It("resolves names without a binary to themselves", func() {
+		Expect(invoker.Paths{}.Resolve("mount")).To(Equal("mount"))
+		Expect(invoker.Paths{Binaries: map[string]string{"mount": "/opt/bin/mount"}}.Resolve("mount")).To(Equal("/opt/bin/mount"))
+		Expect(invoker.Paths{Path: "/nonexistent"}.Resolve("mount")).To(Equal("mount"))
+	})
+})
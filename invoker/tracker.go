@@ -0,0 +1,82 @@
+package invoker
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Process is a helper process that an invoker started and that has not
+// exited yet. State is the scheduler state of the process, such as
+// "disk sleep" for a helper blocked on the server, or empty where it
+// cannot be read.
+type Process struct {
+	Executable string
+	Pid        int
+	StartedAt  time.Time
+	State      string `json:",omitempty"`
+}
+
+// Tracker records the helper processes of a request, so that a request
+// that takes long can report what its helpers are doing. It is passed to
+// the invokers in the request context, see WithTracker.
+type Tracker struct {
+	lock      sync.Mutex
+	processes map[int]Process
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{processes: map[int]Process{}}
+}
+
+type trackerKey struct{}
+
+// WithTracker returns a context that records the processes of the commands
+// it is passed with in tracker.
+func WithTracker(ctx context.Context, tracker *Tracker) context.Context {
+	return context.WithValue(ctx, trackerKey{}, tracker)
+}
+
+func trackerFromContext(ctx context.Context) *Tracker {
+	if ctx == nil {
+		return nil
+	}
+	tracker, _ := ctx.Value(trackerKey{}).(*Tracker)
+	return tracker
+}
+
+// Processes returns the running processes by pid, with their current state.
+func (t *Tracker) Processes() []Process {
+	t.lock.Lock()
+	processes := []Process{}
+	for _, process := range t.processes {
+		processes = append(processes, process)
+	}
+	t.lock.Unlock()
+
+	sort.Slice(processes, func(i, j int) bool { return processes[i].Pid < processes[j].Pid })
+	for i := range processes {
+		processes[i].State = processState(processes[i].Pid)
+	}
+	return processes
+}
+
+// track records a started process in the tracker of ctx, if any, and
+// returns the func that forgets it once it has exited.
+func track(ctx context.Context, executable string, pid int) func() {
+	tracker := trackerFromContext(ctx)
+	if tracker == nil {
+		return func() {}
+	}
+
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+	tracker.processes[pid] = Process{Executable: executable, Pid: pid, StartedAt: time.Now()}
+
+	return func() {
+		tracker.lock.Lock()
+		defer tracker.lock.Unlock()
+		delete(tracker.processes, pid)
+	}
+}
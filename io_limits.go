@@ -0,0 +1,111 @@
+package volumedriver
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+)
+
+// IOLimits caps the bandwidth, in bytes per second, and the operations per
+// second of a volume. Zero leaves the respective direction unlimited.
+type IOLimits struct {
+	ReadBPS   uint64 `json:",omitempty"`
+	WriteBPS  uint64 `json:",omitempty"`
+	ReadIOPS  uint64 `json:",omitempty"`
+	WriteIOPS uint64 `json:",omitempty"`
+}
+
+//go:generate counterfeiter -o volumedriverfakes/fake_io_throttler.go . IOThrottler
+type IOThrottler interface {
+	// Limit applies limits to the IO of the processes using the mount at
+	// mountPoint.
+	Limit(env dockerdriver.Env, mountPoint string, limits IOLimits) error
+	// Unlimit removes the limits again, before mountPoint is unmounted.
+	Unlimit(env dockerdriver.Env, mountPoint string) error
+}
+
+// parseIOLimits returns nil when opts set no limits.
+func parseIOLimits(opts map[string]interface{}) (*IOLimits, error) {
+	limits := IOLimits{}
+	set := false
+
+	for _, limit := range []struct {
+		opt   string
+		value *uint64
+	}{
+		{ReadBPSOpt, &limits.ReadBPS},
+		{WriteBPSOpt, &limits.WriteBPS},
+		{ReadIOPSOpt, &limits.ReadIOPS},
+		{WriteIOPSOpt, &limits.WriteIOPS},
+	} {
+		value, ok := opts[limit.opt]
+		if !ok {
+			continue
+		}
+
+		parsed, err := parseLimit(value)
+		if err != nil || parsed == 0 {
+			return nil, fmt.Errorf("invalid %s '%v', must be a positive integer", limit.opt, value)
+		}
+		*limit.value = parsed
+		set = true
+	}
+
+	if !set {
+		return nil, nil
+	}
+	return &limits, nil
+}
+
+// parseLimit accepts strings and the float64 that JSON numbers decode to.
+func parseLimit(value interface{}) (uint64, error) {
+	if v, ok := value.(float64); ok {
+		if v < 0 || v != math.Trunc(v) || v > math.MaxUint64 {
+			return 0, errors.New("not a positive integer")
+		}
+		return uint64(v), nil
+	}
+
+	return strconv.ParseUint(fmt.Sprintf("%v", value), 10, 64)
+}
+
+// throttledMounter applies the IO limits of a volume once it is mounted.
+type throttledMounter struct {
+	Mounter
+	throttler IOThrottler
+	limits    IOLimits
+}
+
+func (m *throttledMounter) Mount(env dockerdriver.Env, source string, target string, opts map[string]interface{}) error {
+	logger := env.Logger().Session("throttled-mount", lager.Data{"target": target, "limits": m.limits})
+
+	if err := m.Mounter.Mount(env, source, target, opts); err != nil {
+		return err
+	}
+
+	if err := m.throttler.Limit(env, target, m.limits); err != nil {
+		logger.Error("limit-failed", err)
+
+		// an unthrottled mount is what the limits are there to prevent
+		if unmountErr := m.Mounter.Unmount(env, target); unmountErr != nil {
+			logger.Error("unmount-failed", unmountErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (m *throttledMounter) Unmount(env dockerdriver.Env, target string) error {
+	logger := env.Logger().Session("throttled-unmount", lager.Data{"target": target})
+
+	if err := m.throttler.Unlimit(env, target); err != nil {
+		logger.Error("unlimit-failed", err)
+	}
+
+	return m.Mounter.Unmount(env, target)
+}
@@ -0,0 +1,148 @@
+package volumedriver
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+)
+
+type labelRequirement struct {
+	key    string
+	value  string
+	negate bool
+	exists bool
+}
+
+// ListVolumes is List with filters and pagination. Volumes are returned in
+// name order, and NextToken is set while more volumes match.
+func (d *VolumeDriver) ListVolumes(env dockerdriver.Env, listRequest ListVolumesRequest) ListVolumesResponse {
+	logger := env.Logger().Session("list-volumes", lager.Data{"request": listRequest})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	requirements, err := parseSelector(listRequest.Selector)
+	if err != nil {
+		return ListVolumesResponse{Err: err.Error()}
+	}
+
+	after, err := decodeListToken(listRequest.Token)
+	if err != nil {
+		return ListVolumesResponse{Err: err.Error()}
+	}
+
+	if listRequest.Limit < 0 {
+		return ListVolumesResponse{Err: fmt.Sprintf("invalid limit %d", listRequest.Limit)}
+	}
+	if listRequest.UnusedFor < 0 {
+		return ListVolumesResponse{Err: fmt.Sprintf("invalid unused duration %s", listRequest.UnusedFor)}
+	}
+	unusedSince := d.time.Now().Add(-listRequest.UnusedFor)
+
+	volumes := d.loadVolumes()
+
+	names := []string{}
+	for name, volume := range volumes {
+		if after != "" && name <= after {
+			continue
+		}
+		if !strings.HasPrefix(name, listRequest.NamePrefix) {
+			continue
+		}
+		if listRequest.MountedOnly && volume.MountCount < 1 {
+			continue
+		}
+		if !matchesLabels(volume.Labels, requirements) {
+			continue
+		}
+		if listRequest.UnusedFor > 0 && !d.unusedSince(volume, unusedSince) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	listResponse := ListVolumesResponse{
+		Volumes: []dockerdriver.VolumeInfo{},
+		Usage:   map[string]VolumeUsage{},
+	}
+
+	if listRequest.Limit > 0 && len(names) > listRequest.Limit {
+		names = names[:listRequest.Limit]
+		listResponse.NextToken = encodeListToken(names[len(names)-1])
+	}
+
+	for _, name := range names {
+		listResponse.Volumes = append(listResponse.Volumes, volumes[name].VolumeInfo)
+		listResponse.Usage[name] = volumeUsage(volumes[name])
+	}
+
+	return listResponse
+}
+
+func parseSelector(selector string) ([]labelRequirement, error) {
+	requirements := []labelRequirement{}
+
+	for _, term := range strings.Split(selector, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+
+		var requirement labelRequirement
+		if kv := strings.SplitN(term, "!=", 2); len(kv) == 2 {
+			requirement = labelRequirement{key: strings.TrimSpace(kv[0]), value: strings.TrimSpace(kv[1]), negate: true}
+		} else if kv := strings.SplitN(term, "=", 2); len(kv) == 2 {
+			requirement = labelRequirement{key: strings.TrimSpace(kv[0]), value: strings.TrimSpace(kv[1])}
+		} else {
+			requirement = labelRequirement{key: term, exists: true}
+		}
+
+		if requirement.key == "" {
+			return nil, fmt.Errorf("invalid selector '%s'", selector)
+		}
+		requirements = append(requirements, requirement)
+	}
+
+	return requirements, nil
+}
+
+func matchesLabels(labels map[string]string, requirements []labelRequirement) bool {
+	for _, requirement := range requirements {
+		value, ok := labels[requirement.key]
+
+		switch {
+		case requirement.exists:
+			if !ok {
+				return false
+			}
+		case requirement.negate:
+			if ok && value == requirement.value {
+				return false
+			}
+		default:
+			if !ok || value != requirement.value {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// List tokens are opaque to callers; they hold the last name of the previous
+// page so that paging is stable while volumes come and go.
+func encodeListToken(name string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(name))
+}
+
+func decodeListToken(token string) (string, error) {
+	name, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", fmt.Errorf("invalid token '%s'", token)
+	}
+	return string(name), nil
+}
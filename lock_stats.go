@@ -0,0 +1,83 @@
+package volumedriver
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LockStats describes the volume map lock, to diagnose requests that hang
+// waiting for it.
+type LockStats struct {
+	Held        bool
+	HeldFor     time.Duration
+	MaxHeldFor  time.Duration
+	Waiting     int64
+	Acquisition int64
+}
+
+// statsRWMutex is a sync.RWMutex that records how long the write lock is
+// held and how many goroutines wait for it.
+type statsRWMutex struct {
+	sync.RWMutex
+
+	// beforeUnlock runs with the write lock still held, so that what the
+	// holder changed is published before the next writer can change it.
+	beforeUnlock func()
+
+	waiting     int64
+	acquisition int64
+
+	statsLock  sync.Mutex
+	lockedAt   time.Time
+	maxHeldFor time.Duration
+}
+
+func (m *statsRWMutex) Lock() {
+	atomic.AddInt64(&m.waiting, 1)
+	m.RWMutex.Lock()
+	atomic.AddInt64(&m.waiting, -1)
+	atomic.AddInt64(&m.acquisition, 1)
+
+	m.statsLock.Lock()
+	m.lockedAt = time.Now()
+	m.statsLock.Unlock()
+}
+
+func (m *statsRWMutex) Unlock() {
+	if m.beforeUnlock != nil {
+		m.beforeUnlock()
+	}
+
+	m.statsLock.Lock()
+	if heldFor := time.Since(m.lockedAt); heldFor > m.maxHeldFor {
+		m.maxHeldFor = heldFor
+	}
+	m.lockedAt = time.Time{}
+	m.statsLock.Unlock()
+
+	m.RWMutex.Unlock()
+}
+
+func (m *statsRWMutex) RLock() {
+	atomic.AddInt64(&m.waiting, 1)
+	m.RWMutex.RLock()
+	atomic.AddInt64(&m.waiting, -1)
+}
+
+func (m *statsRWMutex) Stats() LockStats {
+	m.statsLock.Lock()
+	defer m.statsLock.Unlock()
+
+	stats := LockStats{
+		MaxHeldFor:  m.maxHeldFor,
+		Waiting:     atomic.LoadInt64(&m.waiting),
+		Acquisition: atomic.LoadInt64(&m.acquisition),
+	}
+	if !m.lockedAt.IsZero() {
+		stats.Held = true
+		stats.HeldFor = time.Since(m.lockedAt)
+	}
+
+	return stats
+}
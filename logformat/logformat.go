@@ -0,0 +1,152 @@
+// Package logformat builds the log sinks of a driver process: JSON lines for
+// log pipelines, or a concise human format for reading on the cell, and a
+// separate stream for the commands the driver runs, such as mount.
+package logformat
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver/invoker"
+)
+
+const (
+	// JSON writes every log as a line of lager JSON.
+	JSON = "json"
+	// Human writes every log as a line of time, level, volume and message,
+	// followed by its data as key=value pairs.
+	Human = "human"
+)
+
+// volumeKey is the data key the driver logs the volume of a request under.
+const volumeKey = "volume"
+
+// Validate refuses unknown formats. The empty format is JSON.
+func Validate(format string) error {
+	switch format {
+	case "", JSON, Human:
+		return nil
+	}
+	return fmt.Errorf("invalid log format '%s', must be %s or %s", format, JSON, Human)
+}
+
+// NewSink returns a sink that writes the logs of at least minLevel to w in
+// format.
+func NewSink(w io.Writer, format string, minLevel lager.LogLevel) (lager.Sink, error) {
+	if err := Validate(format); err != nil {
+		return nil, err
+	}
+	if format == Human {
+		return &humanSink{writer: w, minLevel: minLevel}, nil
+	}
+	return lager.NewWriterSink(w, minLevel), nil
+}
+
+type humanSink struct {
+	writer   io.Writer
+	minLevel lager.LogLevel
+	lock     sync.Mutex
+}
+
+// Log writes a line such as
+//
+//	2020-01-01T00:00:00.000Z info  [volume] nfsdriver.mount.start source=server:/export
+//
+// The session numbers are left out; the volume prefix takes their place
+// for telling requests apart.
+func (s *humanSink) Log(log lager.LogFormat) {
+	if log.LogLevel < s.minLevel {
+		return
+	}
+
+	line := &strings.Builder{}
+	fmt.Fprintf(line, "%s %-5s ", formatTime(log.Timestamp), log.LogLevel.String())
+	if volume, ok := log.Data[volumeKey].(string); ok && volume != "" {
+		fmt.Fprintf(line, "[%s] ", volume)
+	}
+	line.WriteString(log.Message)
+
+	keys := make([]string, 0, len(log.Data))
+	for key := range log.Data {
+		if key != volumeKey && key != "session" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(line, " %s=%s", key, formatValue(log.Data[key]))
+	}
+	line.WriteString("\n")
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	io.WriteString(s.writer, line.String())
+}
+
+// formatTime turns the epoch seconds of lager into UTC with milliseconds.
+func formatTime(timestamp string) string {
+	seconds, err := strconv.ParseFloat(timestamp, 64)
+	if err != nil {
+		return timestamp
+	}
+	return time.Unix(0, int64(seconds*1e9)).UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+// formatValue leaves plain words bare and quotes everything else, so that
+// every line splits into its pairs.
+func formatValue(value interface{}) string {
+	s, ok := value.(string)
+	if !ok {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return strconv.Quote(fmt.Sprintf("%v", value))
+		}
+		s = string(data)
+		if _, isNumber := value.(float64); isNumber || s == "true" || s == "false" || s == "null" {
+			return s
+		}
+	}
+
+	if s != "" && !strings.ContainsAny(s, " \t\n\"=") {
+		return s
+	}
+	return strconv.Quote(s)
+}
+
+// NewCommandRouter returns a sink that passes the logs of the commands the
+// driver invokes, with their output, to commands, and all other logs to
+// sink.
+func NewCommandRouter(sink lager.Sink, commands lager.Sink) lager.Sink {
+	return &commandRouter{sink: sink, commands: commands}
+}
+
+type commandRouter struct {
+	sink     lager.Sink
+	commands lager.Sink
+}
+
+func (r *commandRouter) Log(log lager.LogFormat) {
+	if IsCommandLog(log) {
+		r.commands.Log(log)
+		return
+	}
+	r.sink.Log(log)
+}
+
+// IsCommandLog tells whether log was written in the session of an invoked
+// command.
+func IsCommandLog(log lager.LogFormat) bool {
+	for _, session := range strings.Split(log.Message, ".") {
+		if strings.HasPrefix(session, invoker.SessionPrefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,13 @@
+package logformat_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestLogformat(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Logformat Suite")
+}
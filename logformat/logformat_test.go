@@ -0,0 +1,94 @@
+package logformat_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver/logformat"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Logformat", func() {
+	var (
+		buffer *bytes.Buffer
+		logger lager.Logger
+	)
+
+	newLogger := func(format string) {
+		sink, err := logformat.NewSink(buffer, format, lager.INFO)
+		Expect(err).NotTo(HaveOccurred())
+		logger = lager.NewLogger("nfsdriver")
+		logger.RegisterSink(sink)
+	}
+
+	BeforeEach(func() {
+		buffer = &bytes.Buffer{}
+	})
+
+	It("refuses unknown formats", func() {
+		_, err := logformat.NewSink(buffer, "xml", lager.INFO)
+		Expect(err).To(MatchError("invalid log format 'xml', must be json or human"))
+		Expect(logformat.Validate("")).To(Succeed())
+	})
+
+	Context("json", func() {
+		BeforeEach(func() {
+			newLogger(logformat.JSON)
+		})
+
+		It("writes lager JSON", func() {
+			logger.Info("mount", lager.Data{"volume": "volume"})
+
+			var log lager.LogFormat
+			Expect(json.Unmarshal(buffer.Bytes(), &log)).To(Succeed())
+			Expect(log.Message).To(Equal("nfsdriver.mount"))
+			Expect(log.Data).To(HaveKeyWithValue("volume", "volume"))
+		})
+	})
+
+	Context("human", func() {
+		BeforeEach(func() {
+			newLogger(logformat.Human)
+		})
+
+		It("prefixes the line with the volume and writes the data as pairs", func() {
+			logger.Session("mount", lager.Data{"volume": "volume"}).Info("start", lager.Data{"source": "server:/export", "opts": "vers=4.1,timeo=600", "attempt": 2})
+
+			Expect(buffer.String()).To(MatchRegexp(`^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{3}Z info  \[volume\] nfsdriver\.mount\.start attempt=2 opts="vers=4\.1,timeo=600" source=server:/export\n$`))
+		})
+
+		It("writes errors", func() {
+			logger.Error("mount-failed", errors.New("connection refused"))
+
+			Expect(buffer.String()).To(HaveSuffix(` error nfsdriver.mount-failed error="connection refused"` + "\n"))
+		})
+
+		It("leaves out logs under the minimum level", func() {
+			logger.Debug("noise")
+			Expect(buffer.String()).To(BeEmpty())
+		})
+	})
+
+	Describe("routing command logs", func() {
+		var commands *bytes.Buffer
+
+		BeforeEach(func() {
+			commands = &bytes.Buffer{}
+			logger = lager.NewLogger("nfsdriver")
+			logger.RegisterSink(logformat.NewCommandRouter(lager.NewWriterSink(buffer, lager.DEBUG), lager.NewWriterSink(commands, lager.DEBUG)))
+		})
+
+		It("passes the logs of invoked commands to the command sink", func() {
+			mount := logger.Session("mount", lager.Data{"volume": "volume"})
+			mount.Info("start")
+			mount.Session("invoking-supervised-command").Debug("command-output", lager.Data{"stderr": "access denied"})
+
+			Expect(buffer.String()).To(ContainSubstring("nfsdriver.mount.start"))
+			Expect(buffer.String()).NotTo(ContainSubstring("command-output"))
+			Expect(commands.String()).To(ContainSubstring("access denied"))
+		})
+	})
+})
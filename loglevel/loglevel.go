@@ -0,0 +1,86 @@
+package loglevel
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+
+	cf_http_handlers "code.cloudfoundry.org/cfhttp/handlers"
+	"code.cloudfoundry.org/lager"
+)
+
+const Path = "/log-level"
+
+type LogLevel struct {
+	Level string
+}
+
+// NewHandler reports the minimum level of sink on GET and changes it on PUT
+// or POST with a body such as {"Level":"debug"}. Like the debug endpoints it
+// must only be served on a local or otherwise protected address.
+func NewHandler(logger lager.Logger, sink *lager.ReconfigurableSink) http.Handler {
+	logger = logger.Session("log-level-server")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(Path, func(w http.ResponseWriter, req *http.Request) {
+		logger := logger.Session("handle-log-level")
+
+		switch req.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			body, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				logger.Error("failed-reading-log-level-request-body", err)
+				cf_http_handlers.WriteJSONResponse(w, http.StatusInternalServerError, map[string]string{"Err": err.Error()})
+				return
+			}
+
+			var request LogLevel
+			if err := json.Unmarshal(body, &request); err != nil {
+				logger.Error("failed-unmarshalling-log-level-request-body", err)
+				cf_http_handlers.WriteJSONResponse(w, http.StatusBadRequest, map[string]string{"Err": err.Error()})
+				return
+			}
+
+			level, err := lager.LogLevelFromString(request.Level)
+			if err != nil {
+				logger.Error("invalid-log-level", err, lager.Data{"level": request.Level})
+				cf_http_handlers.WriteJSONResponse(w, http.StatusBadRequest, map[string]string{"Err": err.Error()})
+				return
+			}
+
+			setLevel(logger, sink, level)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		cf_http_handlers.WriteJSONResponse(w, http.StatusOK, LogLevel{Level: sink.GetMinLevel().String()})
+	})
+
+	return mux
+}
+
+// HandleSignals switches sink to debug on SIGUSR1 and back to the level it
+// had when HandleSignals was called on SIGUSR2, until signals is closed.
+// Use Notify to subscribe signals to both.
+func HandleSignals(logger lager.Logger, sink *lager.ReconfigurableSink, signals <-chan os.Signal) {
+	logger = logger.Session("log-level-signals")
+	initial := sink.GetMinLevel()
+
+	for signal := range signals {
+		switch signal {
+		case raiseSignal:
+			setLevel(logger, sink, lager.DEBUG)
+		case lowerSignal:
+			setLevel(logger, sink, initial)
+		}
+	}
+}
+
+func setLevel(logger lager.Logger, sink *lager.ReconfigurableSink, level lager.LogLevel) {
+	from := sink.GetMinLevel()
+	sink.SetMinLevel(level)
+	logger.Info("log-level-changed", lager.Data{"from": from.String(), "to": level.String()})
+}
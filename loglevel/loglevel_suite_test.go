@@ -0,0 +1,13 @@
+package loglevel_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestLogLevel(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Log Level Suite")
+}
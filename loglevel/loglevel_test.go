@@ -0,0 +1,74 @@
+package loglevel_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver/loglevel"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Log level", func() {
+	var (
+		logger *lagertest.TestLogger
+		sink   *lager.ReconfigurableSink
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("log-level")
+		sink = lager.NewReconfigurableSink(lager.NewWriterSink(GinkgoWriter, lager.DEBUG), lager.INFO)
+	})
+
+	Describe("NewHandler", func() {
+		var handler http.Handler
+
+		BeforeEach(func() {
+			handler = loglevel.NewHandler(logger, sink)
+		})
+
+		serve := func(method, body string) *httptest.ResponseRecorder {
+			req, err := http.NewRequest(method, "http://0.0.0.0"+loglevel.Path, strings.NewReader(body))
+			Expect(err).NotTo(HaveOccurred())
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			return w
+		}
+
+		It("reports the current level", func() {
+			w := serve("GET", "")
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(MatchJSON(`{"Level":"info"}`))
+		})
+
+		It("changes the level", func() {
+			w := serve("PUT", `{"Level":"debug"}`)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(MatchJSON(`{"Level":"debug"}`))
+			Expect(sink.GetMinLevel()).To(Equal(lager.DEBUG))
+			Expect(logger.Buffer()).To(gbytes.Say("log-level-changed"))
+		})
+
+		It("rejects unknown levels", func() {
+			w := serve("POST", `{"Level":"verbose"}`)
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+			Expect(w.Body.String()).To(ContainSubstring("invalid log level: verbose"))
+			Expect(sink.GetMinLevel()).To(Equal(lager.INFO))
+		})
+
+		It("rejects malformed requests", func() {
+			w := serve("PUT", `{`)
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+			Expect(sink.GetMinLevel()).To(Equal(lager.INFO))
+		})
+
+		It("rejects other methods", func() {
+			w := serve("DELETE", "")
+			Expect(w.Code).To(Equal(http.StatusMethodNotAllowed))
+		})
+	})
+})
@@ -0,0 +1,15 @@
+// +build !linux,!darwin
+
+package loglevel
+
+import (
+	"os"
+)
+
+var (
+	raiseSignal os.Signal
+	lowerSignal os.Signal
+)
+
+// Notify does nothing, this platform has no user signals. Use the handler instead.
+func Notify(c chan<- os.Signal) {}
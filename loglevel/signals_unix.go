@@ -0,0 +1,19 @@
+// +build linux darwin
+
+package loglevel
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+var (
+	raiseSignal os.Signal = syscall.SIGUSR1
+	lowerSignal os.Signal = syscall.SIGUSR2
+)
+
+// Notify relays SIGUSR1 and SIGUSR2 to c.
+func Notify(c chan<- os.Signal) {
+	signal.Notify(c, raiseSignal, lowerSignal)
+}
@@ -0,0 +1,53 @@
+// +build linux darwin
+
+package loglevel_test
+
+import (
+	"os"
+	"syscall"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver/loglevel"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HandleSignals", func() {
+	var (
+		logger  *lagertest.TestLogger
+		sink    *lager.ReconfigurableSink
+		signals chan os.Signal
+		done    chan struct{}
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("log-level")
+		sink = lager.NewReconfigurableSink(lager.NewWriterSink(GinkgoWriter, lager.DEBUG), lager.INFO)
+		signals = make(chan os.Signal)
+		done = make(chan struct{})
+		go func() {
+			loglevel.HandleSignals(logger, sink, signals)
+			close(done)
+		}()
+	})
+
+	AfterEach(func() {
+		close(signals)
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("switches to debug on SIGUSR1 and back on SIGUSR2", func() {
+		signals <- syscall.SIGUSR1
+		Eventually(sink.GetMinLevel).Should(Equal(lager.DEBUG))
+
+		signals <- syscall.SIGUSR2
+		Eventually(sink.GetMinLevel).Should(Equal(lager.INFO))
+	})
+
+	It("ignores other signals", func() {
+		signals <- syscall.SIGHUP
+		signals <- syscall.SIGUSR2
+		Consistently(sink.GetMinLevel).Should(Equal(lager.INFO))
+	})
+})
@@ -0,0 +1,83 @@
+package metrics
+
+import "time"
+
+const (
+	MountDuration = "mount.duration"
+	Mounts        = "mount.count"
+	Unmounts      = "unmount.count"
+	VolumeCount   = "volumes"
+	MountedCount  = "volumes.mounted"
+	Expirations   = "volume.expired"
+	Orphans       = "mountpoint.orphans"
+	SlowMounts    = "mount.slow"
+	StillMounted  = "mountpoint.still_mounted"
+
+	UnmountedCount = "volumes.unmounted"
+	StateBytes     = "state.bytes"
+	StatePruned    = "state.pruned"
+
+	PersistRetries        = "state.persist.retries"
+	PersistPending        = "state.persist.pending"
+	PersistFailingSeconds = "state.persist.failing_seconds"
+
+	NFSBytesRead    = "nfs.bytes_read"
+	NFSBytesWritten = "nfs.bytes_written"
+	NFSRetransmits  = "nfs.rpc.retransmits"
+	NFSRPCLatency   = "nfs.rpc.latency"
+	FSCacheHits     = "nfs.fscache.hits"
+	FSCacheMisses   = "nfs.fscache.misses"
+
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+type Tag struct {
+	Name  string
+	Value string
+}
+
+func SourceTag(source string) Tag {
+	return Tag{Name: "source", Value: source}
+}
+
+func OutcomeTag(err error) Tag {
+	if err != nil {
+		return Tag{Name: "outcome", Value: OutcomeFailure}
+	}
+	return Tag{Name: "outcome", Value: OutcomeSuccess}
+}
+
+func SeverityTag(severity string) Tag {
+	return Tag{Name: "severity", Value: severity}
+}
+
+func VolumeTag(volume string) Tag {
+	return Tag{Name: "volume", Value: volume}
+}
+
+// OpTag names the NFS RPC, such as READ or GETATTR, of a latency.
+func OpTag(op string) Tag {
+	return Tag{Name: "op", Value: op}
+}
+
+//go:generate counterfeiter -o ../volumedriverfakes/fake_metrics_emitter.go . Emitter
+type Emitter interface {
+	Timing(name string, duration time.Duration, tags ...Tag)
+	Count(name string, delta int64, tags ...Tag)
+	Gauge(name string, value float64, tags ...Tag)
+}
+
+type noopEmitter struct{}
+
+// NewNoopEmitter returns an Emitter that drops every metric.
+func NewNoopEmitter() Emitter {
+	return noopEmitter{}
+}
+
+func (noopEmitter) Timing(string, time.Duration, ...Tag) {}
+func (noopEmitter) Count(string, int64, ...Tag)          {}
+func (noopEmitter) Gauge(string, float64, ...Tag)        {}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+)
+
+type statsdEmitter struct {
+	logger lager.Logger
+	conn   net.Conn
+	prefix string
+}
+
+// NewStatsdEmitter sends metrics over UDP to a statsd agent at address, using
+// the DogStatsD extension for tags. Sending is fire and forget.
+func NewStatsdEmitter(logger lager.Logger, address string, prefix string) (Emitter, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	if prefix != "" && !strings.HasSuffix(prefix, ".") {
+		prefix = prefix + "."
+	}
+
+	return &statsdEmitter{
+		logger: logger.Session("statsd-emitter", lager.Data{"address": address}),
+		conn:   conn,
+		prefix: prefix,
+	}, nil
+}
+
+func (s *statsdEmitter) Timing(name string, duration time.Duration, tags ...Tag) {
+	s.send(name, strconv.FormatInt(int64(duration/time.Millisecond), 10), "ms", tags)
+}
+
+func (s *statsdEmitter) Count(name string, delta int64, tags ...Tag) {
+	s.send(name, strconv.FormatInt(delta, 10), "c", tags)
+}
+
+func (s *statsdEmitter) Gauge(name string, value float64, tags ...Tag) {
+	s.send(name, strconv.FormatFloat(value, 'f', -1, 64), "g", tags)
+}
+
+func (s *statsdEmitter) send(name, value, metricType string, tags []Tag) {
+	line := fmt.Sprintf("%s%s:%s|%s", s.prefix, name, value, metricType)
+
+	if len(tags) > 0 {
+		formatted := make([]string, 0, len(tags))
+		for _, tag := range tags {
+			formatted = append(formatted, sanitize(tag.Name)+":"+sanitize(tag.Value))
+		}
+		line = line + "|#" + strings.Join(formatted, ",")
+	}
+
+	if _, err := s.conn.Write([]byte(line)); err != nil {
+		s.logger.Debug("send-failed", lager.Data{"error": err.Error(), "metric": name})
+	}
+}
+
+var tagReplacer = strings.NewReplacer("|", "_", ",", "_", "#", "_", "\n", "_")
+
+func sanitize(value string) string {
+	return tagReplacer.Replace(value)
+}
@@ -0,0 +1,60 @@
+package metrics_test
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver/metrics"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("StatsdEmitter", func() {
+	var (
+		listener net.PacketConn
+		emitter  metrics.Emitter
+	)
+
+	BeforeEach(func() {
+		var err error
+		listener, err = net.ListenPacket("udp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+
+		emitter, err = metrics.NewStatsdEmitter(lagertest.NewTestLogger("statsd"), listener.LocalAddr().String(), "volumedriver")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		listener.Close()
+	})
+
+	receive := func() string {
+		buffer := make([]byte, 1024)
+		Expect(listener.SetReadDeadline(time.Now().Add(5 * time.Second))).To(Succeed())
+		n, _, err := listener.ReadFrom(buffer)
+		Expect(err).NotTo(HaveOccurred())
+		return string(buffer[:n])
+	}
+
+	It("emits timings in milliseconds with tags", func() {
+		emitter.Timing(metrics.MountDuration, 1500*time.Millisecond, metrics.SourceTag("server:/export"), metrics.OutcomeTag(nil))
+		Expect(receive()).To(Equal("volumedriver.mount.duration:1500|ms|#source:server:/export,outcome:success"))
+	})
+
+	It("emits counts", func() {
+		emitter.Count(metrics.Mounts, 1, metrics.OutcomeTag(errors.New("badness")))
+		Expect(receive()).To(Equal("volumedriver.mount.count:1|c|#outcome:failure"))
+	})
+
+	It("emits gauges without tags", func() {
+		emitter.Gauge(metrics.VolumeCount, 3)
+		Expect(receive()).To(Equal("volumedriver.volumes:3|g"))
+	})
+
+	It("sanitizes characters that are reserved by the protocol", func() {
+		emitter.Count(metrics.Mounts, 1, metrics.SourceTag("a|b,c#d"))
+		Expect(receive()).To(Equal("volumedriver.mount.count:1|c|#source:a_b_c_d"))
+	})
+})
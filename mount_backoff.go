@@ -0,0 +1,62 @@
+package volumedriver
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// mountErrorTTL must be called with volumesLock held. It returns how long the
+// mount error of volume is remembered: the MountErrorTTL, doubled with every
+// further consecutive failure up to the MaxMountErrorTTL.
+func (d *VolumeDriver) mountErrorTTL(volume *NfsVolumeInfo) time.Duration {
+	policy := d.currentPolicy()
+
+	ttl := policy.MountErrorTTL
+	if ttl <= 0 || policy.MaxMountErrorTTL <= ttl {
+		return ttl
+	}
+	for i := 1; i < volume.mountFailures && ttl < policy.MaxMountErrorTTL; i++ {
+		ttl *= 2
+	}
+	if ttl > policy.MaxMountErrorTTL {
+		ttl = policy.MaxMountErrorTTL
+	}
+	return ttl
+}
+
+// retryAfter must be called with volumesLock held. It returns how long until
+// the remembered mount error of volume expires and the volume is mounted
+// again, rounded up to seconds, or zero when the error is kept until reset.
+func (d *VolumeDriver) retryAfter(volume *NfsVolumeInfo) time.Duration {
+	ttl := d.mountErrorTTL(volume)
+	if ttl <= 0 {
+		return 0
+	}
+
+	remaining := volume.mountErrorTime.Add(ttl).Sub(d.time.Now())
+	if remaining < time.Second {
+		return time.Second
+	}
+	return (remaining + time.Second - 1).Truncate(time.Second)
+}
+
+// withRetryAfter adds a retry after hint to a mount error reported back to
+// the caller. Safe errors, which are JSON objects, get it as their
+// RetryAfter field, in seconds, so that they stay readable for Diego.
+func withRetryAfter(err string, retryAfter time.Duration) string {
+	if err == "" || retryAfter <= 0 {
+		return err
+	}
+
+	var object map[string]interface{}
+	if json.Unmarshal([]byte(err), &object) == nil && object != nil {
+		object["RetryAfter"] = int(retryAfter / time.Second)
+		if annotated, marshalErr := json.Marshal(object); marshalErr == nil {
+			return string(annotated)
+		}
+		return err
+	}
+
+	return fmt.Sprintf("%s (retry after %s)", err, retryAfter)
+}
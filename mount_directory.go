@@ -0,0 +1,99 @@
+package volumedriver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"code.cloudfoundry.org/volumedriver/rootlock"
+)
+
+const (
+	maxMountDirectoryLength = 64
+	hashedMountDirPrefix    = "vol-"
+	mountDirectoryHashChars = 16
+)
+
+var safeMountDirectory = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// mountDirectory must be called with volumesLock held. It returns the
+// directory below mountPathRoot that holds the mountpoint of the volume,
+// assigning one if the volume does not have one yet.
+func (d *VolumeDriver) mountDirectory(volume *NfsVolumeInfo) string {
+	if !isMountDirectoryElement(volume.MountDirectory) {
+		// a directory that is not one element of a path, from state that
+		// was written by hand or imported, could place the mountpoint
+		// outside the mount path root
+		volume.MountDirectory = ""
+		if base := filepath.Base(volume.Mountpoint); volume.Mountpoint != "" && isMountDirectoryElement(base) {
+			// volumes restored from state written before directories were
+			// assigned keep the mountpoint they are mounted at
+			volume.MountDirectory = base
+		} else {
+			volume.MountDirectory = d.assignMountDirectory(volume.Name)
+		}
+	}
+
+	return volume.MountDirectory
+}
+
+// assignMountDirectory must be called with volumesLock held. Volume names that
+// are short, portable file names are used as they are, so that mountpoints stay
+// readable. Any other name is replaced by a hash of the name.
+func (d *VolumeDriver) assignMountDirectory(volumeName string) string {
+	candidates := []string{}
+	if isSafeMountDirectory(volumeName) {
+		candidates = append(candidates, volumeName)
+	}
+	hashed := hashedMountDirectory(volumeName)
+	candidates = append(candidates, hashed)
+
+	for _, candidate := range candidates {
+		if !d.mountDirectoryTaken(volumeName, candidate) {
+			return candidate
+		}
+	}
+
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", hashed, i)
+		if !d.mountDirectoryTaken(volumeName, candidate) {
+			return candidate
+		}
+	}
+}
+
+func (d *VolumeDriver) mountDirectoryTaken(volumeName, directory string) bool {
+	for name, volume := range d.volumes {
+		if name != volumeName && volume.MountDirectory == directory {
+			return true
+		}
+	}
+	return false
+}
+
+// isMountDirectoryElement reports whether dir names a directory right below a
+// mount path root.
+func isMountDirectoryElement(dir string) bool {
+	return dir != "" && dir != "." && dir != ".." && !strings.ContainsAny(dir, `/\`)
+}
+
+func isSafeMountDirectory(name string) bool {
+	if len(name) > maxMountDirectoryLength || !safeMountDirectory.MatchString(name) {
+		return false
+	}
+
+	switch name {
+	case stateDirName, exportsDirName, scratchDirName, legacyStateFile, rootlock.FileName:
+		return false
+	}
+
+	return true
+}
+
+func hashedMountDirectory(volumeName string) string {
+	sum := sha256.Sum256([]byte(volumeName))
+	return hashedMountDirPrefix + hex.EncodeToString(sum[:])[:mountDirectoryHashChars]
+}
@@ -0,0 +1,67 @@
+package volumedriver
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver/invoker"
+)
+
+// startMountHeartbeat logs a still-mounting line and publishes an
+// EventMounting every MountHeartbeatInterval until stop is called, with the
+// time the mount has taken so far and the state of its helper processes.
+// A helper in disk sleep waits on the server, while a mount without
+// helpers is stuck in the driver. The returned env passes a Tracker to the
+// invokers of the mount.
+func (d *VolumeDriver) startMountHeartbeat(env dockerdriver.Env, name string, source string) (dockerdriver.Env, func()) {
+	interval := d.currentPolicy().MountHeartbeatInterval
+	if interval <= 0 {
+		return env, func() {}
+	}
+
+	logger := env.Logger().Session("mount-heartbeat", lager.Data{"volume": name, "source": source})
+	tracker := invoker.NewTracker()
+	started := time.Now()
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				elapsed := time.Since(started)
+				helpers := tracker.Processes()
+				logger.Info("still-mounting", lager.Data{"elapsed": elapsed.Round(time.Second).String(), "helpers": helpers})
+				d.publishMounting(name, elapsed, helpers)
+			}
+		}
+	}()
+
+	return driverhttp.EnvWithContext(invoker.WithTracker(env.Context(), tracker), env), func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func (d *VolumeDriver) publishMounting(name string, elapsed time.Duration, helpers []invoker.Process) {
+	if !d.events.subscribed() {
+		return
+	}
+
+	d.events.publish(Event{
+		Type:           EventMounting,
+		Volume:         name,
+		Time:           d.time.Now(),
+		ElapsedSeconds: int64(elapsed / time.Second),
+		Helpers:        helpers,
+	})
+}
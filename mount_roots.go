@@ -0,0 +1,118 @@
+package volumedriver
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+)
+
+// RootPolicy selects the mount path root of new volumes when
+// Options.MountPathRoots configures more than one.
+type RootPolicy string
+
+const (
+	// RootMostFree places volumes on the root with the most free space.
+	RootMostFree RootPolicy = "most-free"
+	// RootFewestVolumes spreads volumes evenly over the roots.
+	RootFewestVolumes RootPolicy = "fewest-volumes"
+)
+
+//go:generate counterfeiter -o volumedriverfakes/fake_disk_space.go . DiskSpace
+type DiskSpace interface {
+	// Free returns the bytes available to the driver on the filesystem of
+	// path.
+	Free(path string) (uint64, error)
+}
+
+// ParseRootPolicy validates a policy given in configuration. The empty
+// string selects RootMostFree.
+func ParseRootPolicy(policy string) (RootPolicy, error) {
+	switch RootPolicy(policy) {
+	case "", RootMostFree:
+		return RootMostFree, nil
+	case RootFewestVolumes:
+		return RootFewestVolumes, nil
+	}
+	return "", fmt.Errorf("invalid root policy '%s', must be one of [%s, %s]", policy, RootMostFree, RootFewestVolumes)
+}
+
+// roots returns the mount path root followed by the MountPathRoots.
+func (d *VolumeDriver) roots() []string {
+	return append([]string{d.mountPathRoot}, d.options.MountPathRoots...)
+}
+
+// volumeRoot returns the root that holds the mountpoint and state of a
+// volume. Volumes on the mount path root have no MountRoot.
+func (d *VolumeDriver) volumeRoot(volume *NfsVolumeInfo) string {
+	if volume == nil || volume.MountRoot == "" {
+		return d.mountPathRoot
+	}
+	return volume.MountRoot
+}
+
+// volumeMountPath must be called with volumesLock held.
+func (d *VolumeDriver) volumeMountPath(env dockerdriver.Env, volume *NfsVolumeInfo) string {
+	return d.mountPathOn(env, d.volumeRoot(volume), d.mountDirectory(volume))
+}
+
+// assignMountRoot must be called with volumesLock held. It returns the
+// MountRoot of a new volume, which is empty for the mount path root.
+func (d *VolumeDriver) assignMountRoot(env dockerdriver.Env) string {
+	if len(d.options.MountPathRoots) == 0 {
+		return ""
+	}
+	logger := env.Logger().Session("assign-mount-root", lager.Data{"policy": d.options.RootPolicy})
+
+	roots := d.roots()
+	best := roots[0]
+
+	if d.options.RootPolicy == RootFewestVolumes {
+		counts := map[string]int{}
+		for _, volume := range d.volumes {
+			counts[d.volumeRoot(volume)]++
+		}
+		for _, root := range roots[1:] {
+			if counts[root] < counts[best] {
+				best = root
+			}
+		}
+	} else {
+		var bestFree uint64
+		for i, root := range roots {
+			free, err := d.diskSpace.Free(root)
+			if err != nil {
+				logger.Error("free-space-failed", err, lager.Data{"root": root})
+				continue
+			}
+			if i == 0 || free > bestFree {
+				best, bestFree = root, free
+			}
+		}
+	}
+
+	logger.Info("assigned", lager.Data{"root": best})
+	if best == d.mountPathRoot {
+		return ""
+	}
+	return best
+}
+
+// rootOfMountpoint returns the MountRoot for a mountpoint directly below one
+// of the roots, and false for any other path.
+func (d *VolumeDriver) rootOfMountpoint(mountpoint string) (string, bool) {
+	for _, root := range d.roots() {
+		abs, err := d.filepath.Abs(root)
+		if err != nil {
+			continue
+		}
+		if filepath.Dir(mountpoint) == filepath.Clean(abs) {
+			if root == d.mountPathRoot {
+				return "", true
+			}
+			return root, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,89 @@
+package volumedriver
+
+// mountPhase is where a volume is in its mount lifecycle. Requests that find
+// a mount or unmount of the volume in progress wait for it to finish instead
+// of starting another one.
+type mountPhase int
+
+const (
+	phaseUnmounted mountPhase = iota
+	phaseMounting
+	phaseMounted
+	phaseFailed
+	phaseUnmounting
+)
+
+func (p mountPhase) String() string {
+	switch p {
+	case phaseMounting:
+		return "mounting"
+	case phaseMounted:
+		return "mounted"
+	case phaseFailed:
+		return "failed"
+	case phaseUnmounting:
+		return "unmounting"
+	default:
+		return "unmounted"
+	}
+}
+
+// mountState must only be used with volumesLock held. Waiters are woken
+// whenever a mount or unmount in progress finishes, and look at the volume
+// again under the lock.
+type mountState struct {
+	phase   mountPhase
+	waiters []chan struct{}
+}
+
+// inProgress tells whether a mount or unmount of the volume is running.
+func (s *mountState) inProgress() bool {
+	return s.phase == phaseMounting || s.phase == phaseUnmounting
+}
+
+// begin moves the volume into phaseMounting or phaseUnmounting.
+func (s *mountState) begin(phase mountPhase) {
+	s.phase = phase
+}
+
+// wait returns a channel that is closed when the phase in progress finishes.
+func (s *mountState) wait() <-chan struct{} {
+	waiter := make(chan struct{})
+	s.waiters = append(s.waiters, waiter)
+	return waiter
+}
+
+// finish moves the volume into phase and wakes every waiter.
+func (s *mountState) finish(phase mountPhase) {
+	s.phase = phase
+	for _, waiter := range s.waiters {
+		close(waiter)
+	}
+	s.waiters = nil
+}
+
+// finishMount ends a mount in progress with its outcome.
+func (s *mountState) finishMount(err error) {
+	if err != nil {
+		s.finish(phaseFailed)
+	} else {
+		s.finish(phaseMounted)
+	}
+}
+
+// lockSettled takes volumesLock once no mount or unmount of the volume is in
+// progress, and marks op queued while it waits. The caller unlocks.
+func (d *VolumeDriver) lockSettled(name string, op *trackedOperation) {
+	for {
+		d.volumesLock.Lock()
+		volume, ok := d.volumes[name]
+		if !ok || !volume.mount.inProgress() {
+			return
+		}
+
+		waiter := volume.mount.wait()
+		d.volumesLock.Unlock()
+		op.queued()
+		<-waiter
+	}
+}
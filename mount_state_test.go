@@ -0,0 +1,167 @@
+package volumedriver_test
+
+import (
+	"context"
+	"errors"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/testhelpers"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Concurrent mounts", func() {
+	const source = "server:/export"
+
+	var (
+		env     dockerdriver.Env
+		driver  *testhelpers.MemoryDriver
+		store   *volumedriverfakes.FakeRefStore
+		release func()
+	)
+
+	mountConcurrently := func(requests int) chan dockerdriver.MountResponse {
+		responses := make(chan dockerdriver.MountResponse, requests)
+		for i := 0; i < requests; i++ {
+			go func() {
+				defer GinkgoRecover()
+				responses <- driver.Mount(env, dockerdriver.MountRequest{Name: "volume"})
+			}()
+		}
+		return responses
+	}
+
+	queued := func() int {
+		count := 0
+		for _, op := range driver.InFlightOperations(env).Operations {
+			if op.State == volumedriver.OperationQueued {
+				count++
+			}
+		}
+		return count
+	}
+
+	BeforeEach(func() {
+		logger := lagertest.NewTestLogger("concurrent-mounts")
+		env = driverhttp.NewHttpDriverEnv(logger, context.TODO())
+		store = &volumedriverfakes.FakeRefStore{}
+		options := volumedriver.DefaultOptions()
+		options.RefStore = store
+		options.CellID = "cell-a"
+		driver = testhelpers.NewMemoryDriverWithOptions(logger, options)
+
+		Expect(driver.Create(env, dockerdriver.CreateRequest{
+			Name: "volume",
+			Opts: map[string]interface{}{"source": source},
+		}).Err).To(BeEmpty())
+
+		release = driver.Mounter.HoldMounts(source)
+	})
+
+	AfterEach(func() {
+		release()
+	})
+
+	It("mounts the volume once for requests that arrive during the mount", func() {
+		responses := mountConcurrently(5)
+
+		Eventually(driver.Mounter.MountCalls).Should(HaveLen(1))
+		Eventually(queued).Should(Equal(4))
+		Consistently(responses).ShouldNot(Receive())
+
+		release()
+		for i := 0; i < 5; i++ {
+			var response dockerdriver.MountResponse
+			Eventually(responses).Should(Receive(&response))
+			Expect(response.Err).To(BeEmpty())
+			Expect(response.Mountpoint).NotTo(BeEmpty())
+		}
+
+		Expect(driver.Mounter.MountCalls()).To(HaveLen(1))
+		Expect(driver.Get(env, dockerdriver.GetRequest{Name: "volume"}).Volume.MountCount).To(Equal(5))
+	})
+
+	It("fails every waiting request when the mount fails", func() {
+		driver.Mounter.FailMount(source, errors.New("connection refused"))
+		responses := mountConcurrently(3)
+
+		Eventually(driver.Mounter.MountCalls).Should(HaveLen(1))
+		Eventually(queued).Should(Equal(2))
+
+		release()
+		for i := 0; i < 3; i++ {
+			var response dockerdriver.MountResponse
+			Eventually(responses).Should(Receive(&response))
+			Expect(response.Err).To(ContainSubstring("connection refused"))
+		}
+		Expect(driver.Mounter.MountCalls()).To(HaveLen(1))
+	})
+
+	It("serves later requests from the finished mount", func() {
+		responses := mountConcurrently(1)
+		Eventually(driver.Mounter.MountCalls).Should(HaveLen(1))
+
+		release()
+		Eventually(responses).Should(Receive())
+
+		Expect(driver.Mount(env, dockerdriver.MountRequest{Name: "volume"}).Err).To(BeEmpty())
+		Expect(driver.Mounter.MountCalls()).To(HaveLen(1))
+	})
+
+	It("unmounts the volume once the mount in progress finished", func() {
+		responses := mountConcurrently(1)
+		Eventually(driver.Mounter.MountCalls).Should(HaveLen(1))
+
+		unmounted := make(chan dockerdriver.ErrorResponse, 1)
+		go func() {
+			defer GinkgoRecover()
+			unmounted <- driver.Unmount(env, dockerdriver.UnmountRequest{Name: "volume"})
+		}()
+		Eventually(queued).Should(Equal(1))
+		Consistently(unmounted).ShouldNot(Receive())
+
+		release()
+		Eventually(responses).Should(Receive())
+
+		var response dockerdriver.ErrorResponse
+		Eventually(unmounted).Should(Receive(&response))
+		Expect(response.Err).To(BeEmpty())
+		Expect(driver.Mounter.Mounts()).To(BeEmpty())
+	})
+
+	It("wakes the waiting requests when the volume is removed during the mount", func() {
+		responses := mountConcurrently(2)
+		Eventually(driver.Mounter.MountCalls).Should(HaveLen(1))
+		Eventually(queued).Should(Equal(1))
+
+		removed := make(chan dockerdriver.ErrorResponse, 1)
+		go func() {
+			defer GinkgoRecover()
+			removed <- driver.RemoveVolume(env, volumedriver.RemoveVolumeRequest{Name: "volume", Force: true})
+		}()
+		Eventually(queued).Should(Equal(2))
+		Consistently(removed).ShouldNot(Receive())
+
+		release()
+		for i := 0; i < 2; i++ {
+			var response dockerdriver.MountResponse
+			Eventually(responses).Should(Receive(&response))
+			if response.Err != "" {
+				Expect(response.Err).To(Equal("Volume 'volume' not found"))
+			}
+		}
+
+		var response dockerdriver.ErrorResponse
+		Eventually(removed).Should(Receive(&response))
+		Expect(response.Err).To(BeEmpty())
+		Expect(driver.Get(env, dockerdriver.GetRequest{Name: "volume"}).Err).To(Equal("Volume not found"))
+
+		Expect(driver.Mounter.Mounts()).To(BeEmpty())
+		Expect(store.AcquireCallCount()).To(Equal(1))
+		Expect(store.ReleaseCallCount()).To(Equal(1))
+	})
+})
@@ -0,0 +1,161 @@
+package volumedriver
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver/metrics"
+)
+
+// NFSStats are the cumulative NFS client statistics of a mount since it was
+// mounted, as reported in /proc/self/mountstats.
+type NFSStats struct {
+	BytesRead    uint64
+	BytesWritten uint64
+	// Ops are keyed by the name of the RPC, such as READ or GETATTR.
+	Ops map[string]NFSOpStats
+	// FSCache is only reported for mounts with the fsc opt.
+	FSCache *FSCacheStats
+}
+
+type NFSOpStats struct {
+	Ops           uint64
+	Transmissions uint64
+	RTT           time.Duration // total round trip time of all Ops
+	Execute       time.Duration // total time from queueing to completion of all Ops
+}
+
+//go:generate counterfeiter -o volumedriverfakes/fake_mount_stats_reader.go . MountStatsReader
+type MountStatsReader interface {
+	// Read returns the statistics of every NFS mount, keyed by mountpoint.
+	Read() (map[string]NFSStats, error)
+}
+
+// EmitMountStats emits the bytes read and written, the retransmitted RPCs,
+// the average RPC latency and the fscache hits and misses of every mounted
+// volume since the previous call, tagged with the volume name. The first call after a volume was
+// mounted reports everything since the mount. Volumes that did I/O since the
+// previous call have their LastIOAt set.
+func (d *VolumeDriver) EmitMountStats(env dockerdriver.Env) {
+	logger := env.Logger().Session("emit-mount-stats")
+
+	if d.options.MountStats == nil {
+		return
+	}
+
+	stats, err := d.options.MountStats.Read()
+	if err != nil {
+		logger.Error("read-mount-stats-failed", err)
+		return
+	}
+
+	mountpoints := map[string]string{}
+	func() {
+		d.volumesLock.RLock()
+		defer d.volumesLock.RUnlock()
+
+		for name, volume := range d.volumes {
+			if volume.MountCount > 0 && volume.Mountpoint != "" {
+				mountpoints[name] = volume.Mountpoint
+			}
+		}
+	}()
+
+	// the first sample of a mount only counts for the metrics, since its I/O
+	// may have happened long before
+	active := []string{}
+	func() {
+		d.mountStatsLock.Lock()
+		defer d.mountStatsLock.Unlock()
+
+		current := map[string]NFSStats{}
+		for name, mountpoint := range mountpoints {
+			sample, ok := stats[mountpoint]
+			if !ok {
+				logger.Debug("no-mount-stats", lager.Data{"volume": name, "mountpoint": mountpoint})
+				continue
+			}
+			current[name] = sample
+
+			previous, seen := d.mountStats[name]
+			if seen && hadIO(previous, sample) {
+				active = append(active, name)
+			}
+			d.emitNFSStats(name, previous, sample)
+		}
+		d.mountStats = current
+	}()
+
+	if len(active) == 0 {
+		return
+	}
+
+	d.volumesLock.Lock()
+	defer d.volumesLock.Unlock()
+
+	for _, name := range active {
+		if volume, ok := d.volumes[name]; ok {
+			d.recordIO(env, volume)
+		}
+	}
+}
+
+// emitNFSStats must be called with mountStatsLock held. Counters that went
+// backwards belong to a new mount and are reported in full.
+func (d *VolumeDriver) emitNFSStats(volumeName string, previous, sample NFSStats) {
+	volumeTag := metrics.VolumeTag(volumeName)
+
+	d.metrics.Count(metrics.NFSBytesRead, int64(counterDelta(previous.BytesRead, sample.BytesRead)), volumeTag)
+	d.metrics.Count(metrics.NFSBytesWritten, int64(counterDelta(previous.BytesWritten, sample.BytesWritten)), volumeTag)
+
+	var retransmits uint64
+	for op, opStats := range sample.Ops {
+		last := previous.Ops[op]
+		if opStats.Ops < last.Ops || opStats.Transmissions < last.Transmissions {
+			last = NFSOpStats{}
+		}
+
+		ops := opStats.Ops - last.Ops
+		transmissions := opStats.Transmissions - last.Transmissions
+		if transmissions > ops {
+			retransmits += transmissions - ops
+		}
+
+		if ops > 0 {
+			latency := (opStats.RTT - last.RTT) / time.Duration(ops)
+			d.metrics.Timing(metrics.NFSRPCLatency, latency, volumeTag, metrics.OpTag(op))
+		}
+	}
+	d.metrics.Count(metrics.NFSRetransmits, int64(retransmits), volumeTag)
+
+	if sample.FSCache != nil {
+		last := FSCacheStats{}
+		if previous.FSCache != nil {
+			last = *previous.FSCache
+		}
+		d.metrics.Count(metrics.FSCacheHits, int64(counterDelta(last.PagesReadOK, sample.FSCache.PagesReadOK)), volumeTag)
+		d.metrics.Count(metrics.FSCacheMisses, int64(counterDelta(last.PagesReadFailed, sample.FSCache.PagesReadFailed)), volumeTag)
+	}
+}
+
+func counterDelta(previous, current uint64) uint64 {
+	if current < previous {
+		return current
+	}
+	return current - previous
+}
+
+func (d *VolumeDriver) runMountStats(env dockerdriver.Env, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.EmitMountStats(env)
+		case <-d.stop:
+			return
+		}
+	}
+}
@@ -2,6 +2,7 @@ package mountchecker
 
 import (
 	"os"
+	"regexp"
 
 	"code.cloudfoundry.org/goshims/bufioshim"
 	"code.cloudfoundry.org/goshims/osshim"
@@ -9,7 +10,7 @@ import (
 
 type MountChecker interface {
 	Exists(string) (bool, error)
-	List(string) ([]string, error)
+	List(*regexp.Regexp) ([]string, error)
 }
 
 type Checker struct {
@@ -39,6 +40,6 @@ func (c Checker) Exists(mountPath string) (bool, error) {
 	return true, nil
 }
 
-func (c Checker) List(mountPathRegexp string) ([]string, error) {
+func (c Checker) List(pattern *regexp.Regexp) ([]string, error) {
 	return []string{}, nil
 }
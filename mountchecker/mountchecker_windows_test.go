@@ -3,6 +3,7 @@ package mountchecker_test
 import (
 	"errors"
 	"os"
+	"regexp"
 
 	"code.cloudfoundry.org/goshims/bufioshim/bufio_fake"
 	"code.cloudfoundry.org/goshims/osshim/os_fake"
@@ -68,7 +69,7 @@ var _ = Describe("Mountchecker", func() {
 
 	Describe("List", func() {
 		It("returns an empty list", func() {
-			mounts, err := mountChecker.List("^/anything/.*")
+			mounts, err := mountChecker.List(regexp.MustCompile("^/anything/.*"))
 			Expect(err).NotTo(HaveOccurred())
 			Expect(mounts).To(ConsistOf([]string{}))
 		})
@@ -0,0 +1,60 @@
+package volumedriver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+)
+
+// volumeDriver returns the key in Options.Mounters of the Mounter that serves
+// a volume created with opts. The driver opt wins; otherwise a source of the
+// form scheme://... selects the Mounter registered for the scheme. Any other
+// volume is served by the default mounter, which has the empty key.
+func (d *VolumeDriver) volumeDriver(opts map[string]interface{}) (string, error) {
+	if value, ok := opts[DriverOpt]; ok {
+		driver, _ := value.(string)
+		if _, registered := d.options.Mounters[driver]; !registered {
+			return "", fmt.Errorf("unknown driver '%v', must be one of [%s]", value, strings.Join(d.mounterNames(), ", "))
+		}
+		return driver, nil
+	}
+
+	source := sourceOpt(opts)
+	if i := strings.Index(source, "://"); i > 0 {
+		scheme := strings.ToLower(source[:i])
+		if _, registered := d.options.Mounters[scheme]; registered {
+			return scheme, nil
+		}
+	}
+
+	return "", nil
+}
+
+// mounterFor returns the Mounter registered for driver, falling back to the
+// default mounter for the empty driver and for drivers that are no longer
+// registered.
+func (d *VolumeDriver) mounterFor(driver string) Mounter {
+	if mounter, ok := d.options.Mounters[driver]; ok && driver != "" {
+		return mounter
+	}
+	return d.mounter
+}
+
+func (d *VolumeDriver) mounterNames() []string {
+	names := []string{}
+	for name := range d.options.Mounters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// purge lets every mounter clean up below path, the default mounter first.
+func (d *VolumeDriver) purge(env dockerdriver.Env, path string) {
+	d.mounter.Purge(env, path)
+	for _, name := range d.mounterNames() {
+		d.options.Mounters[name].Purge(env, path)
+	}
+}
@@ -0,0 +1,56 @@
+package mounthelper
+
+import (
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/volumedriver/invoker"
+)
+
+// helperEnv is the whole environment of mount and umount; nothing of the
+// environment of the caller reaches them.
+var helperEnv = []string{"PATH=/usr/sbin:/usr/bin:/sbin:/bin"}
+
+// Helper carries out the requests of the setuid helper binary.
+type Helper struct {
+	Policy Policy
+	Exec   invoker.Exec
+	// Mount and Umount are the absolute paths of mount(8) and umount(8).
+	Mount  string
+	Umount string
+}
+
+// NewHelper returns the Helper of policy, running /bin/mount and
+// /bin/umount.
+func NewHelper(policy Policy) Helper {
+	return Helper{Policy: policy, Exec: invoker.NewExec(), Mount: "/bin/mount", Umount: "/bin/umount"}
+}
+
+// Run parses args, checks the request against the policy and runs it. The
+// error carries the output of a failed mount or umount.
+func (h Helper) Run(args []string) error {
+	request, err := ParseArgs(args)
+	if err != nil {
+		return err
+	}
+	request, err = h.Policy.Check(request)
+	if err != nil {
+		return err
+	}
+
+	var name string
+	var cmdArgs []string
+	if request.Op == OpUnmount {
+		name, cmdArgs = h.Umount, []string{request.Target}
+	} else {
+		name, cmdArgs = h.Mount, []string{"-t", "nfs", "-o", strings.Join(request.Opts, ","), request.Source, request.Target}
+	}
+
+	cmd := h.Exec.Command(name, cmdArgs...)
+	cmd.Env = helperEnv
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package mounthelper_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"code.cloudfoundry.org/volumedriver/mounthelper"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type recordingExec struct {
+	script string
+	calls  [][]string
+}
+
+func (e *recordingExec) Command(name string, args ...string) *exec.Cmd {
+	e.calls = append(e.calls, append([]string{name}, args...))
+	return exec.Command("sh", "-c", e.script)
+}
+
+var _ = Describe("Helper", func() {
+	var (
+		fakeExec *recordingExec
+		helper   mounthelper.Helper
+		root     string
+	)
+
+	BeforeEach(func() {
+		var err error
+		root, err = ioutil.TempDir("", "mounthelper")
+		Expect(err).NotTo(HaveOccurred())
+		root, err = filepath.EvalSymlinks(root)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Mkdir(filepath.Join(root, "volume"), 0755)).To(Succeed())
+
+		fakeExec = &recordingExec{script: "true"}
+		helper = mounthelper.NewHelper(mounthelper.Policy{Roots: []string{root}})
+		helper.Exec = fakeExec
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(root)
+	})
+
+	It("mounts allowed requests with the forced opts", func() {
+		err := helper.Run([]string{"mount", "server:/export", root + "/volume", "vers=3"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fakeExec.calls).To(Equal([][]string{{"/bin/mount", "-t", "nfs", "-o", "vers=3,nosuid,nodev", "server:/export", root + "/volume"}}))
+	})
+
+	It("unmounts targets below the roots", func() {
+		err := helper.Run([]string{"unmount", root + "/volume"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fakeExec.calls).To(Equal([][]string{{"/bin/umount", root + "/volume"}}))
+	})
+
+	It("runs nothing for refused requests", func() {
+		err := helper.Run([]string{"unmount", "/proc"})
+		Expect(err).To(HaveOccurred())
+		Expect(fakeExec.calls).To(BeEmpty())
+	})
+
+	It("returns the output of failed mounts", func() {
+		fakeExec.script = "echo access denied by server >&2; exit 32"
+		err := helper.Run([]string{"mount", "server:/export", root + "/volume", ""})
+		Expect(err).To(MatchError("exit status 32: access denied by server"))
+	})
+})
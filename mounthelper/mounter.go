@@ -0,0 +1,105 @@
+package mounthelper
+
+import (
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/goshims/ioutilshim"
+	"code.cloudfoundry.org/goshims/osshim"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/invoker"
+	"code.cloudfoundry.org/volumedriver/mountchecker"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+)
+
+type helperMounter struct {
+	invoker      invoker.Invoker
+	helper       string
+	os           osshim.Os
+	ioutil       ioutilshim.Ioutil
+	mountChecker mountchecker.MountChecker
+}
+
+// NewMounter returns a Mounter that mounts nfs exports through the setuid
+// helper at the path helper, for drivers that run unprivileged. Opts are
+// passed to the helper as mount options; the helper refuses the ones its
+// policy does not allow.
+func NewMounter(invoker invoker.Invoker, helper string, os osshim.Os, ioutil ioutilshim.Ioutil, mountChecker mountchecker.MountChecker) volumedriver.Mounter {
+	return &helperMounter{
+		invoker:      invoker,
+		helper:       helper,
+		os:           os,
+		ioutil:       ioutil,
+		mountChecker: mountChecker,
+	}
+}
+
+func (m *helperMounter) Mount(env dockerdriver.Env, source string, target string, opts map[string]interface{}) error {
+	logger := env.Logger().Session("helper-mount", lager.Data{"source": source, "target": target})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	request := Request{Op: OpMount, Source: source, Target: target, Opts: mountOpts(opts)}
+	result := m.invoker.Invoke(env, m.helper, request.Args())
+	if err := result.Wait(); err != nil {
+		logger.Error("mount-failed", err, lager.Data{"stderr": result.StdError()})
+		return safeerrors.FromMountHelper(err, result.StdError(), "mount failed")
+	}
+
+	return nil
+}
+
+func (m *helperMounter) Unmount(env dockerdriver.Env, target string) error {
+	logger := env.Logger().Session("helper-unmount", lager.Data{"target": target})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	result := m.invoker.Invoke(env, m.helper, Request{Op: OpUnmount, Target: target}.Args())
+	if err := result.Wait(); err != nil {
+		logger.Error("unmount-failed", err, lager.Data{"stderr": result.StdError()})
+		return safeerrors.FromMountHelper(err, result.StdError(), "unmount failed")
+	}
+
+	return nil
+}
+
+func (m *helperMounter) Check(env dockerdriver.Env, name, mountPoint string, depth volumedriver.CheckDepth) bool {
+	logger := env.Logger().Session("helper-check", lager.Data{"name": name, "mount-point": mountPoint, "depth": depth})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	mounted, err := m.mountChecker.Exists(mountPoint)
+	if err != nil {
+		logger.Error("check-mounts-failed", err)
+		return false
+	}
+	if !mounted {
+		logger.Info("not-mounted")
+		return false
+	}
+
+	if err := volumedriver.ProbeMountPoint(m.os, m.ioutil, mountPoint, depth); err != nil {
+		logger.Error("probe-failed", err)
+		return false
+	}
+
+	return true
+}
+
+// Purge unmounts everything below path through the helper and removes the
+// emptied mountpoints. The helper offers no forced unmount, so mounts of
+// unreachable servers stay behind.
+func (m *helperMounter) Purge(env dockerdriver.Env, path string) {
+	logger := env.Logger().Session("helper-purge", lager.Data{"path": path})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	Purge(logger, path, m.mountChecker, m.os, func(mountPoint string) error {
+		return m.Unmount(env, mountPoint)
+	})
+}
+
+// Unprivileged reports that the mounter works for a driver that does not run
+// as root.
+func (m *helperMounter) Unprivileged() bool {
+	return true
+}
@@ -0,0 +1,86 @@
+package mounthelper_test
+
+import (
+	"context"
+	"errors"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/goshims/ioutilshim/ioutil_fake"
+	"code.cloudfoundry.org/goshims/osshim/os_fake"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/invokerfakes"
+	"code.cloudfoundry.org/volumedriver/mounthelper"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Mounter", func() {
+	var (
+		env              dockerdriver.Env
+		fakeInvoker      *invokerfakes.FakeInvoker
+		fakeInvokeResult *invokerfakes.FakeInvokeResult
+		fakeOs           *os_fake.FakeOs
+		fakeMountChecker *volumedriverfakes.FakeMountChecker
+		mounter          volumedriver.Mounter
+	)
+
+	BeforeEach(func() {
+		env = driverhttp.NewHttpDriverEnv(lagertest.NewTestLogger("mounthelper"), context.TODO())
+		fakeInvoker = &invokerfakes.FakeInvoker{}
+		fakeInvokeResult = &invokerfakes.FakeInvokeResult{}
+		fakeInvoker.InvokeReturns(fakeInvokeResult)
+		fakeOs = &os_fake.FakeOs{}
+		fakeMountChecker = &volumedriverfakes.FakeMountChecker{}
+
+		mounter = mounthelper.NewMounter(fakeInvoker, "/var/vcap/packages/driver/bin/volumedriver-mount-helper", fakeOs, &ioutil_fake.FakeIoutil{}, fakeMountChecker)
+	})
+
+	It("works for drivers that do not run as root", func() {
+		Expect(volumedriver.IsUnprivileged(mounter)).To(BeTrue())
+	})
+
+	Describe("Mount", func() {
+		It("invokes the helper with the opts as mount options", func() {
+			opts := map[string]interface{}{"source": "server:/export", "vers": "4.1", "readonly": true, "hard": "", "nolock": false}
+			err := mounter.Mount(env, "server:/export", "/mnt/target", opts)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, cmd, args, _ := fakeInvoker.InvokeArgsForCall(0)
+			Expect(cmd).To(Equal("/var/vcap/packages/driver/bin/volumedriver-mount-helper"))
+			Expect(args).To(Equal([]string{"mount", "server:/export", "/mnt/target", "hard,ro,vers=4.1"}))
+		})
+
+		It("describes the failures of the helper", func() {
+			fakeInvokeResult.WaitReturns(errors.New("exit status 1"))
+			fakeInvokeResult.StdErrorReturns("opt 'suid' is not allowed by the policy")
+
+			err := mounter.Mount(env, "server:/export", "/mnt/target", map[string]interface{}{})
+			Expect(err).To(MatchError(HavePrefix("mount failed")))
+		})
+	})
+
+	Describe("Unmount", func() {
+		It("invokes the helper with the target", func() {
+			Expect(mounter.Unmount(env, "/mnt/target")).To(Succeed())
+
+			_, _, args, _ := fakeInvoker.InvokeArgsForCall(0)
+			Expect(args).To(Equal([]string{"unmount", "/mnt/target"}))
+		})
+	})
+
+	Describe("Purge", func() {
+		It("unmounts the deepest mountpoints first and removes them", func() {
+			fakeMountChecker.ListReturns([]string{"/mnt/root/a", "/mnt/root/a/b"}, nil)
+			mounter.Purge(env, "/mnt/root")
+
+			Expect(fakeInvoker.InvokeCallCount()).To(Equal(2))
+			_, _, args, _ := fakeInvoker.InvokeArgsForCall(0)
+			Expect(args).To(Equal([]string{"unmount", "/mnt/root/a/b"}))
+			Expect(fakeOs.RemoveArgsForCall(0)).To(Equal("/mnt/root/a/b"))
+			Expect(fakeOs.RemoveArgsForCall(1)).To(Equal("/mnt/root/a"))
+		})
+	})
+})
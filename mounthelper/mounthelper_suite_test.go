@@ -0,0 +1,13 @@
+package mounthelper_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestMountHelper(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "MountHelper Suite")
+}
@@ -0,0 +1,162 @@
+package mounthelper
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DefaultPolicyPath is where the helper reads its policy. It is fixed at
+// build time, since a setuid helper must not let its caller choose it.
+const DefaultPolicyPath = "/etc/volumedriver/mount-helper.json"
+
+// DefaultAllowedOpts are the nfs opts the helper accepts when the policy does
+// not list its own.
+var DefaultAllowedOpts = []string{
+	"vers", "nfsvers", "proto", "port", "timeo", "retrans", "rsize", "wsize",
+	"hard", "soft", "intr", "nolock", "noac", "actimeo", "lookupcache",
+	"nconnect", "sec", "ro", "rw", "noexec", "noatime", "nodiratime",
+	"context", "fscontext", "defcontext", "rootcontext", "seclabel",
+}
+
+// forcedOpts are added to every mount; a setuid helper must never let its
+// caller mount setuid binaries or device files.
+var forcedOpts = []string{"nosuid", "nodev"}
+
+var (
+	validOptValue = regexp.MustCompile(`^[A-Za-z0-9_.:-]*$`)
+	validSource   = regexp.MustCompile(`^[A-Za-z0-9\[][A-Za-z0-9_.:%\[\]-]*:/[^,\s]*$`)
+)
+
+// Policy is what the helper allows its caller to do. Roots must only be
+// writable by the driver user and root, since mountpoints below them are
+// trusted to stay what the helper checked.
+type Policy struct {
+	// Roots are the directories mounts can be made below, usually the
+	// mount path roots of the driver.
+	Roots []string
+	// AllowedOpts are the names of the opts mounts can use. They default to
+	// DefaultAllowedOpts.
+	AllowedOpts []string `json:",omitempty"`
+}
+
+// LoadPolicy reads the policy at path, which must be owned by root and not be
+// writable by anyone else.
+func LoadPolicy(path string) (Policy, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Policy{}, err
+	}
+	if err := checkOwnedByRoot(info); err != nil {
+		return Policy{}, fmt.Errorf("insecure policy %s: %s", path, err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Policy{}, err
+	}
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return Policy{}, fmt.Errorf("invalid policy %s: %s", path, err)
+	}
+	return policy, policy.Validate()
+}
+
+// Validate fails for policies without roots or with relative roots.
+func (p Policy) Validate() error {
+	if len(p.Roots) == 0 {
+		return fmt.Errorf("the policy must list at least one root")
+	}
+	for _, root := range p.Roots {
+		if !filepath.IsAbs(root) || filepath.Clean(root) == "/" {
+			return fmt.Errorf("invalid root '%s', must be an absolute directory other than /", root)
+		}
+	}
+	return nil
+}
+
+// Check fails for requests the policy does not allow. Mount requests get the
+// forced opts added.
+func (p Policy) Check(request Request) (Request, error) {
+	if err := p.checkTarget(request.Target); err != nil {
+		return Request{}, err
+	}
+	if request.Op == OpUnmount {
+		return request, nil
+	}
+
+	if !validSource.MatchString(request.Source) {
+		return Request{}, fmt.Errorf("invalid source '%s', must be host:/export", request.Source)
+	}
+
+	allowed := map[string]bool{}
+	for _, name := range p.allowedOpts() {
+		allowed[name] = true
+	}
+	opts := []string{}
+	for _, opt := range request.Opts {
+		kv := strings.SplitN(opt, "=", 2)
+		if isForcedOpt(opt) {
+			// the driver hardens its mounts with them too
+			continue
+		}
+		if !allowed[kv[0]] {
+			return Request{}, fmt.Errorf("opt '%s' is not allowed by the policy", kv[0])
+		}
+		if len(kv) == 2 && !validOptValue.MatchString(kv[1]) {
+			return Request{}, fmt.Errorf("invalid value '%s' of opt '%s'", kv[1], kv[0])
+		}
+		opts = append(opts, opt)
+	}
+	request.Opts = append(opts, forcedOpts...)
+	return request, nil
+}
+
+func isForcedOpt(opt string) bool {
+	for _, forced := range forcedOpts {
+		if opt == forced {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Policy) allowedOpts() []string {
+	if len(p.AllowedOpts) > 0 {
+		return p.AllowedOpts
+	}
+	return DefaultAllowedOpts
+}
+
+// checkTarget requires target to be a directory strictly below a root that
+// is reached without following symlinks.
+func (p Policy) checkTarget(target string) error {
+	if !filepath.IsAbs(target) || filepath.Clean(target) != target {
+		return fmt.Errorf("invalid target '%s', must be a clean absolute path", target)
+	}
+
+	for _, root := range p.Roots {
+		root = filepath.Clean(root)
+		if !strings.HasPrefix(target, root+string(filepath.Separator)) {
+			continue
+		}
+
+		resolved, err := filepath.EvalSymlinks(target)
+		if err != nil {
+			return err
+		}
+		resolvedRoot, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			return err
+		}
+		if resolved != filepath.Join(resolvedRoot, strings.TrimPrefix(target, root)) {
+			return fmt.Errorf("invalid target '%s', must not be reached through a symlink", target)
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid target '%s', must be below one of the roots %s", target, strings.Join(p.Roots, ", "))
+}
@@ -0,0 +1,160 @@
+package mounthelper_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/volumedriver/mounthelper"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Policy", func() {
+	var (
+		root   string
+		policy mounthelper.Policy
+	)
+
+	BeforeEach(func() {
+		var err error
+		root, err = ioutil.TempDir("", "mounthelper")
+		Expect(err).NotTo(HaveOccurred())
+		root, err = filepath.EvalSymlinks(root)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Mkdir(filepath.Join(root, "volume"), 0755)).To(Succeed())
+
+		policy = mounthelper.Policy{Roots: []string{root}}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(root)
+	})
+
+	Describe("Validate", func() {
+		It("fails without roots", func() {
+			Expect(mounthelper.Policy{}.Validate()).To(MatchError(ContainSubstring("at least one root")))
+		})
+
+		It("fails for relative roots and /", func() {
+			Expect(mounthelper.Policy{Roots: []string{"var/vcap"}}.Validate()).To(HaveOccurred())
+			Expect(mounthelper.Policy{Roots: []string{"/"}}.Validate()).To(HaveOccurred())
+		})
+
+		It("succeeds for absolute roots", func() {
+			Expect(policy.Validate()).To(Succeed())
+		})
+	})
+
+	Describe("Check", func() {
+		var request mounthelper.Request
+
+		BeforeEach(func() {
+			request = mounthelper.Request{
+				Op:     mounthelper.OpMount,
+				Source: "server:/export",
+				Target: filepath.Join(root, "volume"),
+				Opts:   []string{"vers=4.1", "ro"},
+			}
+		})
+
+		It("forces nosuid and nodev onto mounts", func() {
+			checked, err := policy.Check(request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(checked.Opts).To(Equal([]string{"vers=4.1", "ro", "nosuid", "nodev"}))
+		})
+
+		It("allows unmounts below the roots", func() {
+			_, err := policy.Check(mounthelper.Request{Op: mounthelper.OpUnmount, Target: request.Target})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("refuses targets outside the roots", func() {
+			for _, target := range []string{"/etc", root, root + "-other/volume", filepath.Join(root, "volume", "..", "..", "etc"), "volume"} {
+				request.Target = target
+				_, err := policy.Check(request)
+				Expect(err).To(HaveOccurred(), target)
+			}
+		})
+
+		It("refuses targets reached through a symlink", func() {
+			Expect(os.Symlink("/etc", filepath.Join(root, "link"))).To(Succeed())
+			request.Target = filepath.Join(root, "link")
+
+			_, err := policy.Check(request)
+			Expect(err).To(MatchError(ContainSubstring("symlink")))
+		})
+
+		It("does not repeat the forced opts the driver hardened the mount with", func() {
+			request.Opts = []string{"nodev", "noexec", "nosuid"}
+			checked, err := policy.Check(request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(checked.Opts).To(Equal([]string{"noexec", "nosuid", "nodev"}))
+		})
+
+		It("refuses opts that are not allowed", func() {
+			request.Opts = []string{"suid"}
+			_, err := policy.Check(request)
+			Expect(err).To(MatchError("opt 'suid' is not allowed by the policy"))
+		})
+
+		It("refuses opt values that could smuggle in other opts", func() {
+			request.Opts = []string{"sec=sys suid"}
+			_, err := policy.Check(request)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("refuses sources that are not nfs exports", func() {
+			for _, source := range []string{"/dev/sda1", "-oexec", "server:/export,suid", "proc"} {
+				request.Source = source
+				_, err := policy.Check(request)
+				Expect(err).To(HaveOccurred(), source)
+			}
+		})
+
+		Context("when the policy lists its own opts", func() {
+			BeforeEach(func() {
+				policy.AllowedOpts = []string{"hard"}
+			})
+
+			It("allows only those", func() {
+				_, err := policy.Check(request)
+				Expect(err).To(MatchError("opt 'vers' is not allowed by the policy"))
+			})
+		})
+	})
+
+	Describe("LoadPolicy", func() {
+		It("refuses policies that are not owned by root", func() {
+			if os.Getuid() == 0 {
+				Skip("the policy of root is owned by root")
+			}
+			path := filepath.Join(root, "policy.json")
+			Expect(ioutil.WriteFile(path, []byte(`{"Roots":["/var/vcap/data/volumes"]}`), 0644)).To(Succeed())
+
+			_, err := mounthelper.LoadPolicy(path)
+			Expect(err).To(MatchError(ContainSubstring("insecure policy")))
+		})
+
+		It("refuses policies writable by others", func() {
+			path := filepath.Join(root, "policy.json")
+			Expect(ioutil.WriteFile(path, []byte(`{"Roots":["/var/vcap/data/volumes"]}`), 0644)).To(Succeed())
+			Expect(os.Chmod(path, 0666)).To(Succeed())
+
+			_, err := mounthelper.LoadPolicy(path)
+			Expect(err).To(MatchError(ContainSubstring("insecure policy")))
+		})
+
+		It("loads policies owned by root", func() {
+			if os.Getuid() != 0 {
+				Skip("only root can write a policy owned by root")
+			}
+			path := filepath.Join(root, "policy.json")
+			Expect(ioutil.WriteFile(path, []byte(`{"Roots":["/var/vcap/data/volumes"]}`), 0644)).To(Succeed())
+
+			policy, err := mounthelper.LoadPolicy(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policy.Roots).To(Equal([]string{"/var/vcap/data/volumes"}))
+		})
+	})
+})
@@ -0,0 +1,23 @@
+// +build linux darwin
+
+package mounthelper
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+func checkOwnedByRoot(info os.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("cannot read the owner")
+	}
+	if stat.Uid != 0 {
+		return fmt.Errorf("owned by uid %d instead of root", stat.Uid)
+	}
+	if info.Mode().Perm()&0022 != 0 {
+		return fmt.Errorf("mode %s is writable by group or others", info.Mode().Perm())
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+// Package mounthelper lets a driver that runs unprivileged mount nfs exports
+// through a small setuid helper. The driver invokes the helper with one
+// request on its command line:
+//
+//	volumedriver-mount-helper mount SOURCE TARGET OPTS
+//	volumedriver-mount-helper unmount TARGET
+//
+// The helper trusts nothing but its policy file, which must be owned by root:
+// targets must lie below one of its roots, opts must be in its allow list,
+// and every mount gets nosuid and nodev.
+package mounthelper
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	OpMount   = "mount"
+	OpUnmount = "unmount"
+)
+
+// Request is one operation of the helper. Opts are mount options such as
+// vers=4.1 or ro.
+type Request struct {
+	Op     string
+	Source string
+	Target string
+	Opts   []string
+}
+
+// Args returns the command line of the helper for r.
+func (r Request) Args() []string {
+	if r.Op == OpUnmount {
+		return []string{OpUnmount, r.Target}
+	}
+	return []string{OpMount, r.Source, r.Target, strings.Join(r.Opts, ",")}
+}
+
+// ParseArgs parses the command line of the helper, without the name of the
+// helper itself.
+func ParseArgs(args []string) (Request, error) {
+	if len(args) == 0 {
+		return Request{}, fmt.Errorf("missing operation, must be %s or %s", OpMount, OpUnmount)
+	}
+
+	switch args[0] {
+	case OpMount:
+		if len(args) != 4 {
+			return Request{}, fmt.Errorf("usage: %s SOURCE TARGET OPTS", OpMount)
+		}
+		request := Request{Op: OpMount, Source: args[1], Target: args[2]}
+		for _, opt := range strings.Split(args[3], ",") {
+			if opt != "" {
+				request.Opts = append(request.Opts, opt)
+			}
+		}
+		return request, nil
+	case OpUnmount:
+		if len(args) != 2 {
+			return Request{}, fmt.Errorf("usage: %s TARGET", OpUnmount)
+		}
+		return Request{Op: OpUnmount, Target: args[1]}, nil
+	}
+	return Request{}, fmt.Errorf("invalid operation '%s', must be %s or %s", args[0], OpMount, OpUnmount)
+}
+
+// mountOpts turns the opts of a volume into mount options in a stable
+// order. Booleans become flags, such as ro, and are dropped when false.
+func mountOpts(opts map[string]interface{}) []string {
+	keys := make([]string, 0, len(opts))
+	for key := range opts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	mountOpts := []string{}
+	for _, key := range keys {
+		if key == "source" {
+			continue
+		}
+		name := key
+		if name == "readonly" {
+			name = "ro"
+		}
+
+		switch value := opts[key].(type) {
+		case bool:
+			if value {
+				mountOpts = append(mountOpts, name)
+			}
+		case string:
+			if value == "" {
+				mountOpts = append(mountOpts, name)
+			} else {
+				mountOpts = append(mountOpts, fmt.Sprintf("%s=%s", name, value))
+			}
+		default:
+			mountOpts = append(mountOpts, fmt.Sprintf("%s=%v", name, value))
+		}
+	}
+	return mountOpts
+}
@@ -0,0 +1,38 @@
+package mounthelper_test
+
+import (
+	"code.cloudfoundry.org/volumedriver/mounthelper"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseArgs", func() {
+	It("parses mount requests", func() {
+		request, err := mounthelper.ParseArgs([]string{"mount", "server:/export", "/mnt/target", "vers=4.1,ro"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(request).To(Equal(mounthelper.Request{Op: mounthelper.OpMount, Source: "server:/export", Target: "/mnt/target", Opts: []string{"vers=4.1", "ro"}}))
+	})
+
+	It("parses unmount requests", func() {
+		request, err := mounthelper.ParseArgs([]string{"unmount", "/mnt/target"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(request).To(Equal(mounthelper.Request{Op: mounthelper.OpUnmount, Target: "/mnt/target"}))
+	})
+
+	It("round-trips the args of requests", func() {
+		request := mounthelper.Request{Op: mounthelper.OpMount, Source: "server:/export", Target: "/mnt/target", Opts: []string{"hard"}}
+		Expect(mounthelper.ParseArgs(request.Args())).To(Equal(request))
+	})
+
+	It("fails for unknown operations and wrong numbers of arguments", func() {
+		for _, args := range [][]string{
+			{},
+			{"bind", "/a", "/b"},
+			{"mount", "server:/export", "/mnt/target"},
+			{"unmount", "/mnt/target", "-l"},
+		} {
+			_, err := mounthelper.ParseArgs(args)
+			Expect(err).To(HaveOccurred(), "%v", args)
+		}
+	})
+})
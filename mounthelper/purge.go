@@ -0,0 +1,56 @@
+package mounthelper
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/goshims/osshim"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver/invoker"
+	"code.cloudfoundry.org/volumedriver/mountchecker"
+)
+
+// Purge unmounts and removes every mountpoint below path, for the Purge of
+// a Mounter. unmount unmounts one mountpoint; the mountpoints it fails on
+// are left in place.
+func Purge(logger lager.Logger, path string, mountChecker mountchecker.MountChecker, os osshim.Os, unmount func(mountPoint string) error) {
+	mounts, err := mountChecker.List(regexp.MustCompile("^" + regexp.QuoteMeta(filepath.Clean(path)+"/")))
+	if err != nil {
+		logger.Error("list-mounts-failed", err)
+		return
+	}
+
+	// unmount the deepest mounts first so that nested mounts do not keep their
+	// parents busy
+	sort.Sort(sort.Reverse(sort.StringSlice(mounts)))
+
+	for _, mountPoint := range mounts {
+		if err := unmount(mountPoint); err != nil {
+			logger.Error("purge-unmount-failed", err, lager.Data{"mount-point": mountPoint})
+			continue
+		}
+
+		if err := os.Remove(mountPoint); err != nil {
+			logger.Error("purge-remove-failed", err, lager.Data{"mount-point": mountPoint})
+		}
+	}
+}
+
+// Unmount returns an unmount for Purge that runs cmd with args and the
+// mountpoint, such as umount -l.
+func Unmount(env dockerdriver.Env, invoker invoker.Invoker, cmd string, args ...string) func(mountPoint string) error {
+	return func(mountPoint string) error {
+		result := invoker.Invoke(env, cmd, append(append([]string{}, args...), mountPoint))
+		if err := result.Wait(); err != nil {
+			if stderr := strings.TrimSpace(result.StdError()); stderr != "" {
+				return fmt.Errorf("%s: %s", err.Error(), stderr)
+			}
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,92 @@
+package mounthelper_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/goshims/osshim/os_fake"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver/invokerfakes"
+	"code.cloudfoundry.org/volumedriver/mounthelper"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Purge", func() {
+	var (
+		env              dockerdriver.Env
+		logger           *lagertest.TestLogger
+		fakeOs           *os_fake.FakeOs
+		fakeMountChecker *volumedriverfakes.FakeMountChecker
+		unmounted        []string
+		unmount          func(string) error
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("purge")
+		env = driverhttp.NewHttpDriverEnv(logger, context.TODO())
+		fakeOs = &os_fake.FakeOs{}
+		fakeMountChecker = &volumedriverfakes.FakeMountChecker{}
+		fakeMountChecker.ListReturns([]string{"/mnt/root/a", "/mnt/root/a/b", "/mnt/root/c"}, nil)
+
+		unmounted = []string{}
+		unmount = func(mountPoint string) error {
+			unmounted = append(unmounted, mountPoint)
+			if mountPoint == "/mnt/root/c" {
+				return errors.New("busy")
+			}
+			return nil
+		}
+	})
+
+	It("unmounts the deepest mounts below the path first and removes them", func() {
+		mounthelper.Purge(logger, "/mnt/root/", fakeMountChecker, fakeOs, unmount)
+
+		Expect(fakeMountChecker.ListArgsForCall(0)).To(Equal(regexp.MustCompile("^/mnt/root/")))
+		Expect(unmounted).To(Equal([]string{"/mnt/root/c", "/mnt/root/a/b", "/mnt/root/a"}))
+		Expect(fakeOs.RemoveCallCount()).To(Equal(2))
+		Expect(fakeOs.RemoveArgsForCall(0)).To(Equal("/mnt/root/a/b"))
+		Expect(fakeOs.RemoveArgsForCall(1)).To(Equal("/mnt/root/a"))
+	})
+
+	It("leaves everything alone when the mounts cannot be listed", func() {
+		fakeMountChecker.ListReturns(nil, errors.New("badness"))
+		mounthelper.Purge(logger, "/mnt/root", fakeMountChecker, fakeOs, unmount)
+
+		Expect(unmounted).To(BeEmpty())
+		Expect(fakeOs.RemoveCallCount()).To(Equal(0))
+	})
+
+	Describe("Unmount", func() {
+		var (
+			fakeInvoker      *invokerfakes.FakeInvoker
+			fakeInvokeResult *invokerfakes.FakeInvokeResult
+		)
+
+		BeforeEach(func() {
+			fakeInvoker = &invokerfakes.FakeInvoker{}
+			fakeInvokeResult = &invokerfakes.FakeInvokeResult{}
+			fakeInvoker.InvokeReturns(fakeInvokeResult)
+		})
+
+		It("runs the command with the mountpoint", func() {
+			Expect(mounthelper.Unmount(env, fakeInvoker, "umount", "-l")("/mnt/root/a")).To(Succeed())
+
+			_, cmd, args, _ := fakeInvoker.InvokeArgsForCall(0)
+			Expect(cmd).To(Equal("umount"))
+			Expect(args).To(Equal([]string{"-l", "/mnt/root/a"}))
+		})
+
+		It("describes the failures of the command", func() {
+			fakeInvokeResult.WaitReturns(errors.New("exit status 32"))
+			fakeInvokeResult.StdErrorReturns("umount: /mnt/root/a: target is busy\n")
+
+			err := mounthelper.Unmount(env, fakeInvoker, "umount", "-l")("/mnt/root/a")
+			Expect(err).To(MatchError("exit status 32: umount: /mnt/root/a: target is busy"))
+		})
+	})
+})
@@ -0,0 +1,178 @@
+package mountstats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/goshims/osshim"
+	"code.cloudfoundry.org/volumedriver"
+)
+
+// ProcMountStats lists the mounts of the driver's mount namespace with the
+// statistics the kernel keeps for them.
+const ProcMountStats = "/proc/self/mountstats"
+
+type reader struct {
+	os   osshim.Os
+	path string
+}
+
+// NewReader returns a MountStatsReader that parses /proc/self/mountstats.
+func NewReader(os osshim.Os) volumedriver.MountStatsReader {
+	return &reader{os: os, path: ProcMountStats}
+}
+
+func (r *reader) Read() (map[string]volumedriver.NFSStats, error) {
+	file, err := r.os.Open(r.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Parse(file)
+}
+
+// Parse returns the statistics of the nfs and nfs4 mounts in the
+// mountstats format, keyed by mountpoint. Other mounts are skipped.
+func Parse(in io.Reader) (map[string]volumedriver.NFSStats, error) {
+	stats := map[string]*volumedriver.NFSStats{}
+
+	var current *volumedriver.NFSStats
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, "device ") {
+			current = nil
+
+			mountpoint, fstype, ok := parseDevice(line)
+			if !ok || (fstype != "nfs" && fstype != "nfs4") {
+				continue
+			}
+
+			current = &volumedriver.NFSStats{Ops: map[string]volumedriver.NFSOpStats{}}
+			stats[mountpoint] = current
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		if fields[0] == "bytes:" {
+			counters, err := parseCounters(fields[1:], 2)
+			if err != nil {
+				return nil, err
+			}
+			current.BytesRead = counters[0]
+			current.BytesWritten = counters[1]
+			continue
+		}
+
+		// fsc lines are "fsc: pages_read_ok pages_read_fail
+		// pages_written_ok pages_written_fail [pages_uncached]"
+		if fields[0] == "fsc:" {
+			counters, err := parseCounters(fields[1:], 4)
+			if err != nil {
+				return nil, err
+			}
+			current.FSCache = &volumedriver.FSCacheStats{
+				PagesReadOK:        counters[0],
+				PagesReadFailed:    counters[1],
+				PagesWrittenOK:     counters[2],
+				PagesWrittenFailed: counters[3],
+			}
+			continue
+		}
+
+		// per-op lines are "NAME: ops transmissions timeouts bytes_sent
+		// bytes_recv queue_ms rtt_ms execute_ms [errors]"
+		if strings.HasSuffix(fields[0], ":") && len(fields) >= 9 && isOpName(fields[0]) {
+			counters, err := parseCounters(fields[1:], 8)
+			if err != nil {
+				return nil, err
+			}
+			current.Ops[strings.TrimSuffix(fields[0], ":")] = volumedriver.NFSOpStats{
+				Ops:           counters[0],
+				Transmissions: counters[1],
+				RTT:           time.Duration(counters[6]) * time.Millisecond,
+				Execute:       time.Duration(counters[7]) * time.Millisecond,
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	parsed := map[string]volumedriver.NFSStats{}
+	for mountpoint, sample := range stats {
+		parsed[mountpoint] = *sample
+	}
+	return parsed, nil
+}
+
+// parseDevice parses "device <source> mounted on <mountpoint> with fstype
+// <type> ...".
+func parseDevice(line string) (string, string, bool) {
+	fields := strings.Fields(line)
+	for i := 0; i+5 < len(fields); i++ {
+		if fields[i] == "mounted" && fields[i+1] == "on" && fields[i+3] == "with" && fields[i+4] == "fstype" {
+			return unescape(fields[i+2]), fields[i+5], true
+		}
+	}
+	return "", "", false
+}
+
+func parseCounters(fields []string, count int) ([]uint64, error) {
+	if len(fields) < count {
+		return nil, fmt.Errorf("expected %d counters, got %d", count, len(fields))
+	}
+
+	counters := make([]uint64, count)
+	for i := 0; i < count; i++ {
+		value, err := strconv.ParseUint(fields[i], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid counter '%s': %s", fields[i], err.Error())
+		}
+		counters[i] = value
+	}
+	return counters, nil
+}
+
+// isOpName tells RPC names, which are upper case, from the other
+// "name:" lines of a mount, such as "opts:" or "xprt:".
+func isOpName(field string) bool {
+	name := strings.TrimSuffix(field, ":")
+	return name != "" && strings.ToUpper(name) == name
+}
+
+// unescape reverts the octal escapes, such as \040 for a space, that the
+// kernel writes for whitespace and backslashes in paths.
+func unescape(path string) string {
+	if !strings.Contains(path, `\`) {
+		return path
+	}
+
+	var unescaped strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+3 < len(path) {
+			if value, err := strconv.ParseUint(path[i+1:i+4], 8, 8); err == nil {
+				unescaped.WriteByte(byte(value))
+				i += 3
+				continue
+			}
+		}
+		unescaped.WriteByte(path[i])
+	}
+	return unescaped.String()
+}
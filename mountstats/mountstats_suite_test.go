@@ -0,0 +1,13 @@
+package mountstats_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestMountStats(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "MountStats Suite")
+}
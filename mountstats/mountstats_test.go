@@ -0,0 +1,76 @@
+package mountstats_test
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/goshims/osshim/os_fake"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/mountstats"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const procMountStats = `device rootfs mounted on / with fstype rootfs
+device proc mounted on /proc with fstype proc
+device 10.0.0.1:/export mounted on /var/vcap/data/volumes/nfs/my\040volume with fstype nfs4 statvers=1.1
+	opts:	rw,vers=4.1,rsize=1048576,wsize=1048576,hard,proto=tcp
+	age:	3600
+	caps:	caps=0x3ffdf,wtmult=512,dtsize=32768,bsize=0,namlen=255
+	events:	12 34 0 0 5 6 7 0 0 1 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0
+	bytes:	4096 8192 0 0 4096 8192 1 2
+	RPC iostats version: 1.1  p/v: 100003/4 (nfs)
+	xprt:	tcp 0 1 1 0 4 100 100 0 200 0 2 50 10
+	per-op statistics
+	        NULL: 1 1 0 44 24 0 0 0 0
+	        READ: 10 12 1 1600 5200 3 40 45 0
+	       WRITE: 5 5 0 9000 800 1 25 30 0
+
+device 10.0.0.2:/other mounted on /var/vcap/data/volumes/nfs/v3 with fstype nfs statvers=1.1
+	bytes:	1 2 3 4 5 6 7 8
+	fsc:	120 30 150 0 2
+	per-op statistics
+	     GETATTR: 3 3 0 300 300 0 6 9
+`
+
+var _ = Describe("Parse", func() {
+	It("returns the statistics of every nfs mount by mountpoint", func() {
+		stats, err := mountstats.Parse(strings.NewReader(procMountStats))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stats).To(HaveLen(2))
+
+		volume := stats["/var/vcap/data/volumes/nfs/my volume"]
+		Expect(volume.BytesRead).To(Equal(uint64(4096)))
+		Expect(volume.BytesWritten).To(Equal(uint64(8192)))
+		Expect(volume.Ops).To(HaveLen(3))
+		Expect(volume.Ops["READ"]).To(Equal(volumedriver.NFSOpStats{
+			Ops:           10,
+			Transmissions: 12,
+			RTT:           40 * time.Millisecond,
+			Execute:       45 * time.Millisecond,
+		}))
+
+		v3 := stats["/var/vcap/data/volumes/nfs/v3"]
+		Expect(v3.BytesRead).To(Equal(uint64(1)))
+		Expect(v3.Ops["GETATTR"].RTT).To(Equal(6 * time.Millisecond))
+		Expect(v3.FSCache).To(Equal(&volumedriver.FSCacheStats{PagesReadOK: 120, PagesReadFailed: 30, PagesWrittenOK: 150}))
+		Expect(volume.FSCache).To(BeNil())
+	})
+
+	It("fails on malformed counters", func() {
+		_, err := mountstats.Parse(strings.NewReader("device s mounted on /m with fstype nfs\n\tbytes:\t1 x\n"))
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Reader", func() {
+	It("reads /proc/self/mountstats", func() {
+		fakeOs := &os_fake.FakeOs{}
+		fakeOs.OpenReturns(nil, errors.New("badness"))
+
+		_, err := mountstats.NewReader(fakeOs).Read()
+		Expect(err).To(MatchError("badness"))
+		Expect(fakeOs.OpenArgsForCall(0)).To(Equal("/proc/self/mountstats"))
+	})
+})
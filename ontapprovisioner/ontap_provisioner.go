@@ -0,0 +1,420 @@
+package ontapprovisioner
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/cfhttp"
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/goshims/http_wrap"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+)
+
+// DefaultJobPollInterval is how often the state of an ONTAP job is polled
+// once the request that started it returned before the job finished.
+const DefaultJobPollInterval = time.Second
+
+// maxQtreeName is the longest qtree name ONTAP accepts.
+const maxQtreeName = 64
+
+var invalidNameChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// Config describes the cluster and the SVM that exports are provisioned on.
+type Config struct {
+	// URL is the address of the cluster management interface, for example
+	// https://cluster1.example.com.
+	URL      string
+	Username string
+	Password string
+	// CACertFile verifies the certificate of the cluster. The system roots
+	// are used when it is empty.
+	CACertFile string
+
+	// SVM owns the qtrees and the export policies.
+	SVM string
+	// NFSHost is the data interface of the SVM that clients mount the
+	// qtrees from.
+	NFSHost string
+
+	// QtreePrefix is prepended to the names of the qtrees and export
+	// policies, so that they can be told apart from manually created ones.
+	QtreePrefix string
+	// DefaultClients may mount the qtrees of volumes that are provisioned
+	// without clients. They inherit the export policy of their ONTAP volume
+	// when it is empty.
+	DefaultClients []string
+
+	// JobPollInterval defaults to DefaultJobPollInterval.
+	JobPollInterval time.Duration
+}
+
+type ontapProvisioner struct {
+	config     Config
+	httpClient http_wrap.Client
+}
+
+// NewProvisioner returns a Provisioner that creates a qtree in the ONTAP
+// volume named by the provision opt for every volume, through the REST API
+// of ONTAP 9.6 or later. The qtree gets an export policy of its own when
+// clients are set, and a tree quota when a size is set; quotas must be
+// enabled on the ONTAP volume.
+func NewProvisioner(config Config) (volumedriver.Provisioner, error) {
+	client := cfhttp.NewClient()
+
+	if config.CACertFile != "" {
+		caCert, err := ioutil.ReadFile(config.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no certificates found in '%s'", config.CACertFile)
+		}
+		client.Transport.(*http.Transport).TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	return NewProvisionerWithClient(config, client), nil
+}
+
+func NewProvisionerWithClient(config Config, client http_wrap.Client) volumedriver.Provisioner {
+	if config.JobPollInterval <= 0 {
+		config.JobPollInterval = DefaultJobPollInterval
+	}
+	config.URL = strings.TrimSuffix(config.URL, "/")
+
+	return &ontapProvisioner{
+		config:     config,
+		httpClient: client,
+	}
+}
+
+func (p *ontapProvisioner) Provision(env dockerdriver.Env, request volumedriver.ProvisionRequest) (string, error) {
+	logger := env.Logger().Session("ontap-provision", lager.Data{"volume": request.Volume, "parent": request.Parent, "svm": p.config.SVM})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	qtree, err := p.qtreeName(request.Volume)
+	if err != nil {
+		return "", err
+	}
+
+	junctionPath, err := p.junctionPath(env, request.Parent)
+	if err != nil {
+		logger.Error("volume-lookup-failed", err)
+		return "", err
+	}
+
+	clients := request.Clients
+	if len(clients) == 0 {
+		clients = p.config.DefaultClients
+	}
+
+	policy := ""
+	if len(clients) > 0 {
+		policy = qtree
+		if err := p.ensureExportPolicy(env, policy, clients); err != nil {
+			logger.Error("export-policy-failed", err, lager.Data{"policy": policy})
+			return "", err
+		}
+	}
+
+	if err := p.ensureQtree(env, request.Parent, qtree, policy); err != nil {
+		logger.Error("qtree-failed", err, lager.Data{"qtree": qtree})
+		return "", err
+	}
+
+	if request.Size > 0 {
+		if err := p.ensureQuota(env, request.Parent, qtree, request.Size); err != nil {
+			logger.Error("quota-failed", err, lager.Data{"qtree": qtree})
+			return "", err
+		}
+	}
+
+	return p.nfsHost() + ":" + strings.TrimSuffix(junctionPath, "/") + "/" + qtree, nil
+}
+
+// qtreeName derives the name of the qtree, and of its export policy, from
+// the name of the volume.
+func (p *ontapProvisioner) qtreeName(volume string) (string, error) {
+	name := p.config.QtreePrefix + invalidNameChars.ReplaceAllString(volume, "_")
+	if len(name) > maxQtreeName {
+		return "", fmt.Errorf("qtree name '%s' is longer than %d characters", name, maxQtreeName)
+	}
+	return name, nil
+}
+
+func (p *ontapProvisioner) nfsHost() string {
+	if strings.Contains(p.config.NFSHost, ":") && !strings.HasPrefix(p.config.NFSHost, "[") {
+		return "[" + p.config.NFSHost + "]"
+	}
+	return p.config.NFSHost
+}
+
+type reference struct {
+	Name string `json:"name"`
+}
+
+type volumeRecord struct {
+	Name string `json:"name"`
+	Nas  struct {
+		Path string `json:"path"`
+	} `json:"nas"`
+}
+
+type exportRule struct {
+	Clients   []exportClient `json:"clients"`
+	Protocols []string       `json:"protocols"`
+	RoRule    []string       `json:"ro_rule"`
+	RwRule    []string       `json:"rw_rule"`
+	Superuser []string       `json:"superuser"`
+}
+
+type exportClient struct {
+	Match string `json:"match"`
+}
+
+type exportPolicy struct {
+	SVM   reference    `json:"svm"`
+	Name  string       `json:"name"`
+	Rules []exportRule `json:"rules"`
+}
+
+type qtree struct {
+	SVM             reference  `json:"svm"`
+	Volume          reference  `json:"volume"`
+	Name            string     `json:"name"`
+	SecurityStyle   string     `json:"security_style"`
+	UnixPermissions int        `json:"unix_permissions"`
+	ExportPolicy    *reference `json:"export_policy,omitempty"`
+}
+
+type quotaRule struct {
+	SVM    reference `json:"svm"`
+	Volume reference `json:"volume"`
+	Type   string    `json:"type"`
+	Qtree  reference `json:"qtree"`
+	Space  struct {
+		HardLimit uint64 `json:"hard_limit"`
+	} `json:"space"`
+}
+
+type records struct {
+	NumRecords int             `json:"num_records"`
+	Records    json.RawMessage `json:"records"`
+}
+
+type jobResponse struct {
+	Job *struct {
+		UUID string `json:"uuid"`
+	} `json:"job"`
+}
+
+type job struct {
+	State   string `json:"state"`
+	Message string `json:"message"`
+}
+
+type errorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+		Code    string `json:"code"`
+	} `json:"error"`
+}
+
+// junctionPath returns the path that the ONTAP volume is mounted at in the
+// namespace of the SVM.
+func (p *ontapProvisioner) junctionPath(env dockerdriver.Env, volume string) (string, error) {
+	var volumes []volumeRecord
+	query := url.Values{"name": {volume}, "svm.name": {p.config.SVM}, "fields": {"nas.path"}}
+	found, err := p.list(env, "/api/storage/volumes", query, &volumes)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("volume '%s' not found on svm '%s'", volume, p.config.SVM)
+	}
+	if volumes[0].Nas.Path == "" {
+		return "", fmt.Errorf("volume '%s' is not mounted in the namespace of svm '%s'", volume, p.config.SVM)
+	}
+	return volumes[0].Nas.Path, nil
+}
+
+func (p *ontapProvisioner) ensureExportPolicy(env dockerdriver.Env, name string, clients []string) error {
+	found, err := p.list(env, "/api/protocols/nfs/export-policies", url.Values{"name": {name}, "svm.name": {p.config.SVM}}, nil)
+	if err != nil || found {
+		return err
+	}
+
+	rule := exportRule{
+		Protocols: []string{"nfs"},
+		RoRule:    []string{"sys"},
+		RwRule:    []string{"sys"},
+		Superuser: []string{"none"},
+	}
+	for _, client := range clients {
+		rule.Clients = append(rule.Clients, exportClient{Match: client})
+	}
+
+	return p.create(env, "/api/protocols/nfs/export-policies", exportPolicy{
+		SVM:   reference{Name: p.config.SVM},
+		Name:  name,
+		Rules: []exportRule{rule},
+	})
+}
+
+func (p *ontapProvisioner) ensureQtree(env dockerdriver.Env, volume string, name string, policy string) error {
+	found, err := p.list(env, "/api/storage/qtrees", url.Values{"name": {name}, "volume.name": {volume}, "svm.name": {p.config.SVM}}, nil)
+	if err != nil || found {
+		return err
+	}
+
+	request := qtree{
+		SVM:             reference{Name: p.config.SVM},
+		Volume:          reference{Name: volume},
+		Name:            name,
+		SecurityStyle:   "unix",
+		UnixPermissions: 0755,
+	}
+	if policy != "" {
+		request.ExportPolicy = &reference{Name: policy}
+	}
+
+	return p.create(env, "/api/storage/qtrees", request)
+}
+
+func (p *ontapProvisioner) ensureQuota(env dockerdriver.Env, volume string, name string, size uint64) error {
+	query := url.Values{"qtree.name": {name}, "volume.name": {volume}, "svm.name": {p.config.SVM}, "type": {"tree"}}
+	found, err := p.list(env, "/api/storage/quota/rules", query, nil)
+	if err != nil || found {
+		return err
+	}
+
+	rule := quotaRule{
+		SVM:    reference{Name: p.config.SVM},
+		Volume: reference{Name: volume},
+		Type:   "tree",
+		Qtree:  reference{Name: name},
+	}
+	rule.Space.HardLimit = size
+
+	return p.create(env, "/api/storage/quota/rules", rule)
+}
+
+// list reports whether path has records matching query, and decodes them
+// into result unless it is nil.
+func (p *ontapProvisioner) list(env dockerdriver.Env, path string, query url.Values, result interface{}) (bool, error) {
+	var response records
+	if err := p.do(env.Context(), http.MethodGet, path+"?"+query.Encode(), nil, &response); err != nil {
+		return false, err
+	}
+	if response.NumRecords == 0 {
+		return false, nil
+	}
+
+	if result != nil {
+		if err := json.Unmarshal(response.Records, result); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// create posts a new object and waits for the job that creates it, for the
+// objects that ONTAP creates asynchronously.
+func (p *ontapProvisioner) create(env dockerdriver.Env, path string, object interface{}) error {
+	var response jobResponse
+	if err := p.do(env.Context(), http.MethodPost, path+"?return_timeout=120", object, &response); err != nil {
+		return err
+	}
+	if response.Job == nil {
+		return nil
+	}
+
+	return p.waitForJob(env, response.Job.UUID)
+}
+
+func (p *ontapProvisioner) waitForJob(env dockerdriver.Env, uuid string) error {
+	ticker := time.NewTicker(p.config.JobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		var state job
+		if err := p.do(env.Context(), http.MethodGet, "/api/cluster/jobs/"+url.PathEscape(uuid)+"?fields=state,message", nil, &state); err != nil {
+			return err
+		}
+
+		switch state.State {
+		case "success":
+			return nil
+		case "failure":
+			return fmt.Errorf("job %s failed: %s", uuid, state.Message)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-env.Context().Done():
+			return fmt.Errorf("job %s did not finish: %s", uuid, env.Context().Err())
+		}
+	}
+}
+
+func (p *ontapProvisioner) do(ctx context.Context, method string, path string, request interface{}, response interface{}) error {
+	var body io.Reader
+	if request != nil {
+		payload, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewBuffer(payload)
+	}
+
+	httpRequest, err := http.NewRequest(method, p.config.URL+path, body)
+	if err != nil {
+		return err
+	}
+	httpRequest.SetBasicAuth(p.config.Username, p.config.Password)
+	httpRequest.Header.Set("Accept", "application/json")
+	if body != nil {
+		httpRequest.Header.Set("Content-Type", "application/json")
+	}
+
+	httpResponse, err := p.httpClient.Do(httpRequest.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer httpResponse.Body.Close()
+
+	data, err := ioutil.ReadAll(httpResponse.Body)
+	if err != nil {
+		return err
+	}
+
+	if httpResponse.StatusCode >= http.StatusBadRequest {
+		var ontapError errorResponse
+		if json.Unmarshal(data, &ontapError) == nil && ontapError.Error.Message != "" {
+			return fmt.Errorf("%s %s failed: %s (code %s)", method, strings.SplitN(path, "?", 2)[0], ontapError.Error.Message, ontapError.Error.Code)
+		}
+		return fmt.Errorf("%s %s failed with status %d", method, strings.SplitN(path, "?", 2)[0], httpResponse.StatusCode)
+	}
+
+	if len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, response); err != nil {
+		return errors.New("invalid response from ontap: " + err.Error())
+	}
+	return nil
+}
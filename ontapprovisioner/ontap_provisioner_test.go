@@ -0,0 +1,264 @@
+package ontapprovisioner_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/ontapprovisioner"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type ontapRequest struct {
+	Method string
+	Path   string
+	Query  string
+	Body   map[string]interface{}
+}
+
+// fakeOntap answers the ONTAP REST API with the records of existing, keyed
+// by path, and records the requests it receives.
+type fakeOntap struct {
+	lock      sync.Mutex
+	existing  map[string][]map[string]interface{}
+	responses map[string]string
+	jobStates []string
+	requests  []ontapRequest
+}
+
+func (f *fakeOntap) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if user, password, ok := req.BasicAuth(); !ok || user != "admin" || password != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	request := ontapRequest{Method: req.Method, Path: req.URL.Path, Query: req.URL.RawQuery}
+	if data, _ := ioutil.ReadAll(req.Body); len(data) > 0 {
+		Expect(json.Unmarshal(data, &request.Body)).To(Succeed())
+	}
+	f.requests = append(f.requests, request)
+
+	if response, ok := f.responses[req.Method+" "+req.URL.Path]; ok {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(response))
+		return
+	}
+
+	switch {
+	case req.Method == http.MethodGet && strings.HasPrefix(req.URL.Path, "/api/cluster/jobs/"):
+		state := f.jobStates[0]
+		if len(f.jobStates) > 1 {
+			f.jobStates = f.jobStates[1:]
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"state": state, "message": "job " + state})
+	case req.Method == http.MethodGet:
+		existing := f.existing[req.URL.Path]
+		if existing == nil {
+			existing = []map[string]interface{}{}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"records": existing, "num_records": len(existing)})
+	case len(f.jobStates) > 0:
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"job": {"uuid": "some-job"}}`))
+	default:
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+func (f *fakeOntap) posts() []ontapRequest {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	posts := []ontapRequest{}
+	for _, request := range f.requests {
+		if request.Method == http.MethodPost {
+			posts = append(posts, request)
+		}
+	}
+	return posts
+}
+
+var _ = Describe("OntapProvisioner", func() {
+	var (
+		env     dockerdriver.Env
+		ontap   *fakeOntap
+		server  *httptest.Server
+		config  ontapprovisioner.Config
+		request volumedriver.ProvisionRequest
+		source  string
+		err     error
+	)
+
+	BeforeEach(func() {
+		env = driverhttp.NewHttpDriverEnv(lagertest.NewTestLogger("ontapprovisioner"), context.TODO())
+		ontap = &fakeOntap{
+			existing: map[string][]map[string]interface{}{
+				"/api/storage/volumes": {{"name": "vol1", "nas": map[string]interface{}{"path": "/vol1"}}},
+			},
+			responses: map[string]string{},
+		}
+		server = httptest.NewServer(ontap)
+
+		config = ontapprovisioner.Config{
+			URL:             server.URL + "/",
+			Username:        "admin",
+			Password:        "secret",
+			SVM:             "svm1",
+			NFSHost:         "nfs.example.com",
+			QtreePrefix:     "cf_",
+			JobPollInterval: time.Millisecond,
+		}
+		request = volumedriver.ProvisionRequest{Volume: "some/volume", Parent: "vol1", Size: 1073741824, Clients: []string{"10.0.0.0/8"}}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	JustBeforeEach(func() {
+		provisioner := ontapprovisioner.NewProvisionerWithClient(config, server.Client())
+		source, err = provisioner.Provision(env, request)
+	})
+
+	It("creates an export policy, a qtree and a quota", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(source).To(Equal("nfs.example.com:/vol1/cf_some_volume"))
+
+		posts := ontap.posts()
+		Expect(posts).To(HaveLen(3))
+
+		Expect(posts[0].Path).To(Equal("/api/protocols/nfs/export-policies"))
+		Expect(posts[0].Body["name"]).To(Equal("cf_some_volume"))
+		Expect(posts[0].Body["svm"]).To(Equal(map[string]interface{}{"name": "svm1"}))
+		rules := posts[0].Body["rules"].([]interface{})
+		Expect(rules[0].(map[string]interface{})["clients"]).To(Equal([]interface{}{map[string]interface{}{"match": "10.0.0.0/8"}}))
+
+		Expect(posts[1].Path).To(Equal("/api/storage/qtrees"))
+		Expect(posts[1].Body["name"]).To(Equal("cf_some_volume"))
+		Expect(posts[1].Body["volume"]).To(Equal(map[string]interface{}{"name": "vol1"}))
+		Expect(posts[1].Body["export_policy"]).To(Equal(map[string]interface{}{"name": "cf_some_volume"}))
+
+		Expect(posts[2].Path).To(Equal("/api/storage/quota/rules"))
+		Expect(posts[2].Body["type"]).To(Equal("tree"))
+		Expect(posts[2].Body["qtree"]).To(Equal(map[string]interface{}{"name": "cf_some_volume"}))
+		Expect(posts[2].Body["space"]).To(Equal(map[string]interface{}{"hard_limit": float64(1073741824)}))
+	})
+
+	Context("when the volume sets neither clients nor a size", func() {
+		BeforeEach(func() {
+			request.Clients = nil
+			request.Size = 0
+		})
+
+		It("only creates the qtree, which inherits the export policy of its volume", func() {
+			Expect(err).NotTo(HaveOccurred())
+			posts := ontap.posts()
+			Expect(posts).To(HaveLen(1))
+			Expect(posts[0].Path).To(Equal("/api/storage/qtrees"))
+			Expect(posts[0].Body).NotTo(HaveKey("export_policy"))
+		})
+
+		Context("when default clients are configured", func() {
+			BeforeEach(func() {
+				config.DefaultClients = []string{"10.1.0.0/16", "10.2.0.0/16"}
+			})
+
+			It("exports the qtree to them", func() {
+				posts := ontap.posts()
+				Expect(posts[0].Path).To(Equal("/api/protocols/nfs/export-policies"))
+				rules := posts[0].Body["rules"].([]interface{})
+				Expect(rules[0].(map[string]interface{})["clients"]).To(HaveLen(2))
+			})
+		})
+	})
+
+	Context("when the qtree exists already", func() {
+		BeforeEach(func() {
+			ontap.existing["/api/protocols/nfs/export-policies"] = []map[string]interface{}{{"name": "cf_some_volume"}}
+			ontap.existing["/api/storage/qtrees"] = []map[string]interface{}{{"name": "cf_some_volume"}}
+			ontap.existing["/api/storage/quota/rules"] = []map[string]interface{}{{"type": "tree"}}
+		})
+
+		It("returns its source without changing it", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(source).To(Equal("nfs.example.com:/vol1/cf_some_volume"))
+			Expect(ontap.posts()).To(BeEmpty())
+		})
+	})
+
+	Context("when ONTAP creates the objects in a job", func() {
+		BeforeEach(func() {
+			ontap.jobStates = []string{"running", "success"}
+		})
+
+		It("waits for the job", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ontap.requests).To(ContainElement(ontapRequest{Method: http.MethodGet, Path: "/api/cluster/jobs/some-job", Query: "fields=state,message"}))
+		})
+
+		Context("when the job fails", func() {
+			BeforeEach(func() {
+				ontap.jobStates = []string{"failure"}
+			})
+
+			It("returns the error of the job", func() {
+				Expect(err).To(MatchError("job some-job failed: job failure"))
+			})
+		})
+	})
+
+	Context("when the volume does not exist", func() {
+		BeforeEach(func() {
+			delete(ontap.existing, "/api/storage/volumes")
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError("volume 'vol1' not found on svm 'svm1'"))
+			Expect(ontap.posts()).To(BeEmpty())
+		})
+	})
+
+	Context("when ONTAP rejects a request", func() {
+		BeforeEach(func() {
+			ontap.responses["POST /api/storage/qtrees"] = `{"error": {"message": "quota policy is full", "code": "5242955"}}`
+		})
+
+		It("returns the error of ONTAP", func() {
+			Expect(err).To(MatchError("POST /api/storage/qtrees failed: quota policy is full (code 5242955)"))
+		})
+	})
+
+	Context("when the NFS host is an IPv6 address", func() {
+		BeforeEach(func() {
+			config.NFSHost = "fd00::1"
+		})
+
+		It("brackets the address in the source", func() {
+			Expect(source).To(Equal("[fd00::1]:/vol1/cf_some_volume"))
+		})
+	})
+
+	Context("when the name of the volume is too long for a qtree", func() {
+		BeforeEach(func() {
+			request.Volume = strings.Repeat("a", 64)
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring("is longer than 64 characters")))
+			Expect(ontap.requests).To(BeEmpty())
+		})
+	})
+})
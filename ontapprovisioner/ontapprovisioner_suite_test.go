@@ -0,0 +1,13 @@
+package ontapprovisioner_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestOntapProvisioner(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "OntapProvisioner Suite")
+}
@@ -0,0 +1,132 @@
+package volumedriver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Bounds of the opts of a request. Opts beyond them are refused before any
+// of them is interpreted.
+const (
+	MaxOpts           = 128
+	MaxOptKeyLength   = 128
+	MaxOptValueLength = 16 * 1024
+	MaxSourceLength   = 4096
+)
+
+// errMissingSource is the error of a request without a source.
+var errMissingSource = errors.New(`Missing mandatory 'source' field in 'Opts'`)
+
+// objectOpts are the opts that take an object of scalars as their value,
+// besides the string form every opt accepts.
+var objectOpts = map[string]bool{LabelsOpt: true, CompositeOpt: true}
+
+// ValidateOpts checks the shape of the opts of a request as decoded from
+// JSON: at most MaxOpts keys, scalar values, objects only for the opts that
+// take them, and bounded lengths. The values themselves are checked by the
+// code that interprets them.
+func ValidateOpts(opts map[string]interface{}) error {
+	if len(opts) > MaxOpts {
+		return fmt.Errorf("invalid opts, must not have more than %d keys", MaxOpts)
+	}
+
+	// sorted so that the same opts always fail on the same key
+	for _, key := range sortedKeys(opts) {
+		if err := validateOptKey(key); err != nil {
+			return err
+		}
+
+		value := opts[key]
+		object, isObject := value.(map[string]interface{})
+		if !isObject {
+			if err := validateOptValue(key, value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !objectOpts[key] {
+			return fmt.Errorf("invalid opt '%s', must be a string, number or boolean", key)
+		}
+		if len(object) > MaxOpts {
+			return fmt.Errorf("invalid opt '%s', must not have more than %d keys", key, MaxOpts)
+		}
+		for _, nestedKey := range sortedKeys(object) {
+			if err := validateOptKey(nestedKey); err != nil {
+				return fmt.Errorf("invalid opt '%s': %s", key, err)
+			}
+			if err := validateOptValue(key+"."+nestedKey, object[nestedKey]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateOptKey(key string) error {
+	if key == "" {
+		return errors.New("invalid opt '', must not be empty")
+	}
+	if len(key) > MaxOptKeyLength {
+		return fmt.Errorf("invalid opt %.32q..., must not be longer than %d bytes", key, MaxOptKeyLength)
+	}
+	if !printable(key) {
+		return fmt.Errorf("invalid opt %q, must be valid UTF-8 without control characters", key)
+	}
+	return nil
+}
+
+func validateOptValue(key string, value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		if len(v) > MaxOptValueLength {
+			return fmt.Errorf("invalid opt '%s', must not be longer than %d bytes", key, MaxOptValueLength)
+		}
+		if !utf8.ValidString(v) {
+			return fmt.Errorf("invalid opt '%s', must be valid UTF-8", key)
+		}
+		return nil
+	case bool, float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, json.Number:
+		return nil
+	}
+	return fmt.Errorf("invalid opt '%s', must be a string, number or boolean", key)
+}
+
+// ParseSource returns the source of opts. Unlike a type assertion it tells
+// a missing source from one that is not a string, and refuses sources that
+// no mounter could use.
+func ParseSource(opts map[string]interface{}) (string, error) {
+	value, ok := opts["source"]
+	if !ok || value == nil {
+		return "", errMissingSource
+	}
+	source, ok := value.(string)
+	if !ok {
+		return "", errors.New("invalid source, must be a string")
+	}
+	if source == "" {
+		return "", errMissingSource
+	}
+	if len(source) > MaxSourceLength {
+		return "", fmt.Errorf("invalid source, must not be longer than %d bytes", MaxSourceLength)
+	}
+	if !printable(source) {
+		return "", fmt.Errorf("invalid source %q, must be valid UTF-8 without control characters", source)
+	}
+	return source, nil
+}
+
+func printable(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
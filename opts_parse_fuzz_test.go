@@ -0,0 +1,100 @@
+package volumedriver_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/testhelpers"
+)
+
+// The fuzz targets decode request bodies the way the plugin API does and
+// check that the driver answers every one without panicking. Run them with
+// go test -fuzz FuzzCreateRequest; plain go test runs the seeds.
+
+var requestSeeds = []string{
+	`{"Name":"volume","Opts":{"source":"server:/export"}}`,
+	`{"Name":"volume","Opts":{"source":"nfs://server:2049/export?vers=4.1&uid=1000"}}`,
+	`{"Name":"volume","Opts":{"source":42}}`,
+	`{"Name":"volume","Opts":{"source":null}}`,
+	`{"Name":"volume","Opts":{"source":{"nested":{"deeper":[1,2,3]}}}}`,
+	`{"Name":"volume","Opts":{"source":"server:/export","labels":{"team":"a","tier":1}}}`,
+	`{"Name":"volume","Opts":{"source":"server:/export","labels":{"team":{"nested":true}}}}`,
+	`{"Name":"volume","Opts":{"source":"server:/export","composite":{"logs":"server:/logs"}}}`,
+	`{"Name":"volume","Opts":{"source":"server:/export","composite":"logs=server:/logs,=,"}}`,
+	`{"Name":"volume","Opts":{"source":"server:/export","uid":[1000],"ttl":"1h","subdir":"../.."}}`,
+	`{"Name":"volume","Opts":{"source":"server:/export","read_bps":-1,"check_depth":true}}`,
+	`{"Name":"volume","Opts":{"source":"nfs://[::1/export?%zz"}}`,
+	`{"Name":"volume","Opts":{"":"","source":"\u0000"}}`,
+	`{"Name":"../volume","Opts":{}}`,
+	`{"Name":"volume","Opts":null}`,
+}
+
+func fuzzDriver() *testhelpers.MemoryDriver {
+	return testhelpers.NewMemoryDriverWithOptions(lager.NewLogger("fuzz"), volumedriver.DefaultOptions())
+}
+
+func fuzzEnv() dockerdriver.Env {
+	return driverhttp.NewHttpDriverEnv(lager.NewLogger("fuzz"), context.TODO())
+}
+
+func FuzzCreateRequest(f *testing.F) {
+	for _, seed := range requestSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Add([]byte(`{"Name":"volume","Opts":{"source":"server:/` + strings.Repeat("a", volumedriver.MaxSourceLength) + `"}}`))
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		var createRequest dockerdriver.CreateRequest
+		if json.Unmarshal(body, &createRequest) != nil {
+			return
+		}
+
+		driver := fuzzDriver()
+		env := fuzzEnv()
+		if driver.Create(env, createRequest).Err != "" {
+			return
+		}
+
+		mountResponse := driver.Mount(env, dockerdriver.MountRequest{Name: createRequest.Name})
+		if mountResponse.Err == "" {
+			driver.Unmount(env, dockerdriver.UnmountRequest{Name: createRequest.Name})
+		}
+		driver.Remove(env, dockerdriver.RemoveRequest{Name: createRequest.Name})
+	})
+}
+
+func FuzzValidateRequest(f *testing.F) {
+	for _, seed := range requestSeeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		var validateRequest volumedriver.ValidateRequest
+		if json.Unmarshal(body, &validateRequest) != nil {
+			return
+		}
+		fuzzDriver().Validate(fuzzEnv(), validateRequest)
+	})
+}
+
+func FuzzParseSource(f *testing.F) {
+	for _, seed := range []string{"server:/export", "nfs://server/export?vers=4.1", "", "\x00", "\xff\xfe", "[::1]:/export"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, source string) {
+		parsed, err := volumedriver.ParseSource(map[string]interface{}{"source": source})
+		if err == nil && parsed != source {
+			t.Fatalf("parsed source %q as %q", source, parsed)
+		}
+		if err == nil && volumedriver.ValidateOpts(map[string]interface{}{"source": source}) != nil {
+			t.Fatalf("accepted source %q with invalid opts", source)
+		}
+	})
+}
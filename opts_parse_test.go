@@ -0,0 +1,101 @@
+package volumedriver_test
+
+import (
+	"context"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/testhelpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Parsing request opts", func() {
+	checkOpts := func(opts map[string]interface{}, expected string) {
+		err := volumedriver.ValidateOpts(opts)
+		if expected == "" {
+			Expect(err).NotTo(HaveOccurred())
+		} else {
+			Expect(err).To(MatchError(expected))
+		}
+	}
+
+	checkSource := func(value interface{}, expected string) {
+		source, err := volumedriver.ParseSource(map[string]interface{}{"source": value})
+		if expected == "" {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(source).To(Equal(value))
+		} else {
+			Expect(err).To(MatchError(expected))
+		}
+	}
+
+	Describe("ValidateOpts", func() {
+		It("accepts scalars", func() {
+			checkOpts(map[string]interface{}{"source": "server:/export", "uid": float64(1000), "ro": true}, "")
+		})
+		It("accepts objects of the opts that take them", func() {
+			checkOpts(map[string]interface{}{"labels": map[string]interface{}{"team": "a"}}, "")
+		})
+		It("refuses nested objects", func() {
+			checkOpts(map[string]interface{}{"uid": map[string]interface{}{"a": "b"}}, "invalid opt 'uid', must be a string, number or boolean")
+		})
+		It("refuses objects in objects", func() {
+			checkOpts(map[string]interface{}{"labels": map[string]interface{}{"team": map[string]interface{}{}}}, "invalid opt 'labels.team', must be a string, number or boolean")
+		})
+		It("refuses arrays", func() {
+			checkOpts(map[string]interface{}{"uid": []interface{}{"1000"}}, "invalid opt 'uid', must be a string, number or boolean")
+		})
+		It("refuses null", func() {
+			checkOpts(map[string]interface{}{"uid": nil}, "invalid opt 'uid', must be a string, number or boolean")
+		})
+		It("refuses empty keys", func() {
+			checkOpts(map[string]interface{}{"": "value"}, "invalid opt '', must not be empty")
+		})
+		It("refuses control characters in keys", func() {
+			checkOpts(map[string]interface{}{"u\nid": "value"}, `invalid opt "u\nid", must be valid UTF-8 without control characters`)
+		})
+		It("refuses huge values", func() {
+			checkOpts(map[string]interface{}{"uid": strings.Repeat("1", volumedriver.MaxOptValueLength+1)}, "invalid opt 'uid', must not be longer than 16384 bytes")
+		})
+	})
+
+	Describe("ParseSource", func() {
+		It("accepts a source", func() {
+			checkSource("server:/export", "")
+		})
+		It("refuses a missing source", func() {
+			checkSource(nil, "Missing mandatory 'source' field in 'Opts'")
+		})
+		It("refuses an empty source", func() {
+			checkSource("", "Missing mandatory 'source' field in 'Opts'")
+		})
+		It("refuses a number", func() {
+			checkSource(float64(42), "invalid source, must be a string")
+		})
+		It("refuses an object", func() {
+			checkSource(map[string]interface{}{"host": "server"}, "invalid source, must be a string")
+		})
+		It("refuses control characters", func() {
+			checkSource("server:/export\x00", `invalid source "server:/export\x00", must be valid UTF-8 without control characters`)
+		})
+		It("refuses huge sources", func() {
+			checkSource("server:/"+strings.Repeat("a", volumedriver.MaxSourceLength), "invalid source, must not be longer than 4096 bytes")
+		})
+	})
+
+	It("fails Create with a validation error instead of panicking", func() {
+		logger := lagertest.NewTestLogger("opts-parse")
+		env := driverhttp.NewHttpDriverEnv(logger, context.TODO())
+		driver := testhelpers.NewMemoryDriver(logger)
+
+		response := driver.Create(env, dockerdriver.CreateRequest{Name: "volume", Opts: map[string]interface{}{"source": float64(42)}})
+		Expect(response.Err).To(Equal("invalid source, must be a string"))
+		response = driver.Create(env, dockerdriver.CreateRequest{Name: "volume", Opts: map[string]interface{}{"source": map[string]interface{}{"host": "server"}}})
+		Expect(response.Err).To(Equal("invalid opt 'source', must be a string, number or boolean"))
+		Expect(driver.Get(env, dockerdriver.GetRequest{Name: "volume"}).Err).NotTo(BeEmpty())
+	})
+})
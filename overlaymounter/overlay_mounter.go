@@ -0,0 +1,136 @@
+package overlaymounter
+
+import (
+	"path/filepath"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/goshims/ioutilshim"
+	"code.cloudfoundry.org/goshims/osshim"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/invoker"
+	"code.cloudfoundry.org/volumedriver/mountchecker"
+	"code.cloudfoundry.org/volumedriver/mounthelper"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+)
+
+const (
+	UpperDirOpt = "upperdir"
+	WorkDirOpt  = "workdir"
+)
+
+type overlayMounter struct {
+	invoker      invoker.Invoker
+	os           osshim.Os
+	ioutil       ioutilshim.Ioutil
+	mountChecker mountchecker.MountChecker
+}
+
+// NewOverlayMounter returns a Mounter for Options.OverlayMounter. It mounts
+// an overlayfs with the source as the read-only lower directory and the
+// upperdir and workdir opts, which must be absolute paths on the same local
+// filesystem, as the writable layer.
+func NewOverlayMounter(invoker invoker.Invoker, os osshim.Os, ioutil ioutilshim.Ioutil, mountChecker mountchecker.MountChecker) volumedriver.Mounter {
+	return &overlayMounter{
+		invoker:      invoker,
+		os:           os,
+		ioutil:       ioutil,
+		mountChecker: mountChecker,
+	}
+}
+
+func (m *overlayMounter) Mount(env dockerdriver.Env, source string, target string, opts map[string]interface{}) error {
+	logger := env.Logger().Session("overlay-mount", lager.Data{"source": source, "target": target})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	mountOpts, err := overlayMountOpts(source, opts)
+	if err != nil {
+		logger.Error("invalid-opts", err)
+		return err
+	}
+
+	result := m.invoker.Invoke(env, "mount", []string{"-t", "overlay", "-o", mountOpts, "overlay", target})
+	if err := result.Wait(); err != nil {
+		logger.Error("mount-failed", err, lager.Data{"stderr": result.StdError()})
+		return safeerrors.FromMountHelper(err, result.StdError(), "overlay mount failed")
+	}
+
+	return nil
+}
+
+func (m *overlayMounter) Unmount(env dockerdriver.Env, target string) error {
+	logger := env.Logger().Session("overlay-unmount", lager.Data{"target": target})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	result := m.invoker.Invoke(env, "umount", []string{target})
+	if err := result.Wait(); err != nil {
+		logger.Error("unmount-failed", err, lager.Data{"stderr": result.StdError()})
+		return safeerrors.FromMountHelper(err, result.StdError(), "unmount failed")
+	}
+
+	return nil
+}
+
+func (m *overlayMounter) Check(env dockerdriver.Env, name, mountPoint string, depth volumedriver.CheckDepth) bool {
+	logger := env.Logger().Session("overlay-check", lager.Data{"name": name, "mount-point": mountPoint, "depth": depth})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	mounted, err := m.mountChecker.Exists(mountPoint)
+	if err != nil {
+		logger.Error("check-mounts-failed", err)
+		return false
+	}
+	if !mounted {
+		logger.Info("not-mounted")
+		return false
+	}
+
+	if err := volumedriver.ProbeMountPoint(m.os, m.ioutil, mountPoint, depth); err != nil {
+		logger.Error("probe-failed", err)
+		return false
+	}
+
+	return true
+}
+
+// Purge lazily unmounts everything below path and removes the emptied
+// mountpoints. Directory contents are never removed.
+func (m *overlayMounter) Purge(env dockerdriver.Env, path string) {
+	logger := env.Logger().Session("overlay-purge", lager.Data{"path": path})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	mounthelper.Purge(logger, path, m.mountChecker, m.os, mounthelper.Unmount(env, m.invoker, "umount", "-l"))
+}
+
+// overlayMountOpts refuses paths that the comma and colon separated options
+// of overlayfs cannot hold, and passes the hardening opts of the driver on as
+// mount flags.
+func overlayMountOpts(lower string, opts map[string]interface{}) (string, error) {
+	opts, flags := volumedriver.HardeningFlags(opts)
+	for key := range opts {
+		if key != UpperDirOpt && key != WorkDirOpt {
+			return "", safeerrors.New(safeerrors.InvalidOption, "Not allowed options: %s", key)
+		}
+	}
+
+	dirs := []struct {
+		name  string
+		value interface{}
+	}{{"lowerdir", lower}, {UpperDirOpt, opts[UpperDirOpt]}, {WorkDirOpt, opts[WorkDirOpt]}}
+
+	mountOpts := []string{}
+	for _, dir := range dirs {
+		path, _ := dir.value.(string)
+		if !filepath.IsAbs(path) || strings.ContainsAny(path, ",:\\") {
+			return "", safeerrors.New(safeerrors.InvalidOption, "invalid %s '%v', must be an absolute path without ',' or ':'", dir.name, dir.value)
+		}
+		mountOpts = append(mountOpts, dir.name+"="+path)
+	}
+
+	return strings.Join(append(mountOpts, flags...), ","), nil
+}
@@ -0,0 +1,164 @@
+package overlaymounter_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/goshims/ioutilshim/ioutil_fake"
+	"code.cloudfoundry.org/goshims/osshim/os_fake"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/invokerfakes"
+	"code.cloudfoundry.org/volumedriver/overlaymounter"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OverlayMounter", func() {
+	var (
+		logger           *lagertest.TestLogger
+		env              dockerdriver.Env
+		fakeInvoker      *invokerfakes.FakeInvoker
+		fakeInvokeResult *invokerfakes.FakeInvokeResult
+		fakeOs           *os_fake.FakeOs
+		fakeIoutil       *ioutil_fake.FakeIoutil
+		fakeMountChecker *volumedriverfakes.FakeMountChecker
+		mounter          volumedriver.Mounter
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("overlaymounter")
+		env = driverhttp.NewHttpDriverEnv(logger, context.TODO())
+		fakeInvoker = &invokerfakes.FakeInvoker{}
+		fakeInvokeResult = &invokerfakes.FakeInvokeResult{}
+		fakeInvoker.InvokeReturns(fakeInvokeResult)
+		fakeOs = &os_fake.FakeOs{}
+		fakeIoutil = &ioutil_fake.FakeIoutil{}
+		fakeMountChecker = &volumedriverfakes.FakeMountChecker{}
+
+		dirInfo := &ioutil_fake.FakeFileInfo{}
+		dirInfo.IsDirReturns(true)
+		fakeOs.StatReturns(dirInfo, nil)
+
+		mounter = overlaymounter.NewOverlayMounter(fakeInvoker, fakeOs, fakeIoutil, fakeMountChecker)
+	})
+
+	Describe("Mount", func() {
+		var (
+			lower string
+			opts  map[string]interface{}
+			err   error
+		)
+
+		BeforeEach(func() {
+			lower = "/mnt/root/driver-scratch.d/volume"
+			opts = map[string]interface{}{"upperdir": "/var/scratch/volume/upper", "workdir": "/var/scratch/volume/work"}
+		})
+
+		JustBeforeEach(func() {
+			err = mounter.Mount(env, lower, "/mnt/root/volume", opts)
+		})
+
+		It("mounts an overlay of the lower directory", func() {
+			Expect(err).NotTo(HaveOccurred())
+			_, cmd, args, _ := fakeInvoker.InvokeArgsForCall(0)
+			Expect(cmd).To(Equal("mount"))
+			Expect(args).To(Equal([]string{"-t", "overlay", "-o", "lowerdir=/mnt/root/driver-scratch.d/volume,upperdir=/var/scratch/volume/upper,workdir=/var/scratch/volume/work", "overlay", "/mnt/root/volume"}))
+		})
+
+		Context("when a directory is missing", func() {
+			BeforeEach(func() {
+				delete(opts, "workdir")
+			})
+
+			It("rejects the mount", func() {
+				Expect(err).To(Equal(safeerrors.New(safeerrors.InvalidOption, "invalid workdir '<nil>', must be an absolute path without ',' or ':'")))
+				Expect(fakeInvoker.InvokeCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when a directory would inject options", func() {
+			BeforeEach(func() {
+				opts["upperdir"] = "/var/scratch,lowerdir=/etc"
+			})
+
+			It("rejects the mount", func() {
+				Expect(err).To(Equal(safeerrors.New(safeerrors.InvalidOption, "invalid upperdir '/var/scratch,lowerdir=/etc', must be an absolute path without ',' or ':'")))
+			})
+		})
+
+		Context("when the driver hardens the mount", func() {
+			BeforeEach(func() {
+				opts["nosuid"] = true
+				opts["nodev"] = true
+				opts["noexec"] = true
+			})
+
+			It("passes the hardening opts on as mount flags", func() {
+				Expect(err).NotTo(HaveOccurred())
+				_, _, args, _ := fakeInvoker.InvokeArgsForCall(0)
+				Expect(args).To(ContainElement(HaveSuffix(",nosuid,nodev,noexec")))
+			})
+		})
+
+		Context("when other options are given", func() {
+			BeforeEach(func() {
+				opts["redirect_dir"] = "on"
+			})
+
+			It("rejects the mount", func() {
+				Expect(err).To(Equal(safeerrors.New(safeerrors.InvalidOption, "Not allowed options: redirect_dir")))
+			})
+		})
+
+		Context("when the mount command fails", func() {
+			BeforeEach(func() {
+				fakeInvokeResult.WaitReturns(errors.New("exit status 32"))
+				fakeInvokeResult.StdErrorReturns("mount: unknown filesystem type 'overlay'\n")
+			})
+
+			It("returns the command error", func() {
+				Expect(err).To(MatchError("overlay mount failed: the cell cannot mount this file system; check that its mount helper and kernel module are installed on the cells"))
+			})
+		})
+	})
+
+	Describe("Unmount", func() {
+		It("unmounts the target", func() {
+			Expect(mounter.Unmount(env, "/mnt/root/volume")).To(Succeed())
+			_, cmd, args, _ := fakeInvoker.InvokeArgsForCall(0)
+			Expect(cmd).To(Equal("umount"))
+			Expect(args).To(Equal([]string{"/mnt/root/volume"}))
+		})
+	})
+
+	Describe("Check", func() {
+		It("reports a healthy mount", func() {
+			fakeMountChecker.ExistsReturns(true, nil)
+			Expect(mounter.Check(env, "some-volume", "/mnt/root/volume", volumedriver.CheckRead)).To(BeTrue())
+			Expect(fakeIoutil.ReadDirCallCount()).To(Equal(1))
+		})
+
+		It("reports a missing mount as unhealthy", func() {
+			fakeMountChecker.ExistsReturns(false, nil)
+			Expect(mounter.Check(env, "some-volume", "/mnt/root/volume", volumedriver.CheckStat)).To(BeFalse())
+		})
+	})
+
+	Describe("Purge", func() {
+		It("unmounts and removes every mountpoint below the path", func() {
+			fakeMountChecker.ListReturns([]string{"/mnt/root/a"}, nil)
+			mounter.Purge(env, "/mnt/root")
+
+			Expect(fakeMountChecker.ListArgsForCall(0)).To(Equal(regexp.MustCompile("^/mnt/root/")))
+			_, _, args, _ := fakeInvoker.InvokeArgsForCall(0)
+			Expect(args).To(Equal([]string{"-l", "/mnt/root/a"}))
+			Expect(fakeOs.RemoveArgsForCall(0)).To(Equal("/mnt/root/a"))
+		})
+	})
+})
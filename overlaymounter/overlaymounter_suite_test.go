@@ -0,0 +1,13 @@
+package overlaymounter_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestOverlayMounter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "OverlayMounter Suite")
+}
@@ -0,0 +1,65 @@
+package volumedriver
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+)
+
+// persistVolumeLater must be called with volumesLock held. With
+// Options.PersistDebounce set it only marks the record of the volume dirty,
+// for FlushState to write it with the other changes of the window.
+//
+// It is meant for changes that are safe to lose in a crash: those that
+// keep the volume mounted with fewer references, and bookkeeping such as
+// the time of the last mount. A record restored without them overstates
+// the references of the volume, which keeps its mount alive, and never
+// understates them, which could unmount it under a container.
+func (d *VolumeDriver) persistVolumeLater(env dockerdriver.Env, volumeName string) error {
+	if d.options.PersistDebounce <= 0 {
+		return d.persistVolumeOrQueue(env, volumeName)
+	}
+
+	d.dirtyVolumes[volumeName] = true
+	return nil
+}
+
+// FlushState writes the records of the volumes with changes that are still
+// waiting for their debounce window. Call it before the driver process
+// exits, unless it drains.
+func (d *VolumeDriver) FlushState(env dockerdriver.Env) {
+	logger := env.Logger().Session("flush-state")
+
+	d.volumesLock.Lock()
+	defer d.volumesLock.Unlock()
+
+	if len(d.dirtyVolumes) == 0 {
+		return
+	}
+	logger.Debug("start", lager.Data{"volumes": len(d.dirtyVolumes)})
+	defer logger.Debug("end")
+
+	for name := range d.dirtyVolumes {
+		// persistVolume clears the mark, and removes the record of a volume
+		// that is gone by now. Failed records are retried with the next
+		// flush.
+		if err := d.persistVolume(env, name); err != nil {
+			logger.Error("persist-state-failed", err, lager.Data{"volume": name})
+		}
+	}
+}
+
+func (d *VolumeDriver) runStateFlush(env dockerdriver.Env, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.FlushState(env)
+		case <-d.stop:
+			return
+		}
+	}
+}
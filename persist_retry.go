@@ -0,0 +1,170 @@
+package volumedriver
+
+import (
+	"sort"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver/metrics"
+)
+
+// MaxPersistRetryBackoff bounds the backoff between the retries of a state
+// write.
+const MaxPersistRetryBackoff = 5 * time.Minute
+
+// PendingPersist is a volume record whose write failed and is waiting for
+// its next retry.
+type PendingPersist struct {
+	Volume       string
+	Attempts     int
+	FailingSince time.Time
+	NextRetry    time.Time
+	LastError    string
+}
+
+type persistRetry struct {
+	attempts     int
+	failingSince time.Time
+	nextRetry    time.Time
+	lastError    string
+}
+
+// persistVolumeOrQueue must be called with volumesLock held. It writes the
+// record of the volume, or removes it once the volume is gone. With
+// Options.PersistRetryInterval set a failed write is queued for retry and
+// nil is returned, so that a request whose change took effect does not fail
+// on a transient write error.
+func (d *VolumeDriver) persistVolumeOrQueue(env dockerdriver.Env, volumeName string) error {
+	err := d.persistVolume(env, volumeName)
+	if err == nil || d.options.PersistRetryInterval <= 0 {
+		return err
+	}
+
+	logger := env.Logger().Session("persist-volume-or-queue", lager.Data{"volume": volumeName})
+	logger.Error("queued-for-retry", err)
+
+	// a write that is queued already keeps its backoff, which only the
+	// retries advance
+	if retry, ok := d.persistRetries[volumeName]; ok {
+		retry.lastError = err.Error()
+		return nil
+	}
+
+	now := d.time.Now()
+	retry := &persistRetry{failingSince: now}
+	d.persistRetries[volumeName] = retry
+	d.scheduleRetry(retry, now, err)
+	d.emitPersistGauges()
+
+	return nil
+}
+
+// RetryStateWrites retries the queued state writes that are due. It is
+// called every Options.PersistRetryInterval.
+func (d *VolumeDriver) RetryStateWrites(env dockerdriver.Env) {
+	logger := env.Logger().Session("retry-state-writes")
+
+	d.volumesLock.Lock()
+	defer d.volumesLock.Unlock()
+
+	now := d.time.Now()
+	for name, retry := range d.persistRetries {
+		if now.Before(retry.nextRetry) {
+			continue
+		}
+
+		// persistVolume drops the volume from the queue when it succeeds
+		err := d.persistVolume(env, name)
+		d.metrics.Count(metrics.PersistRetries, 1, metrics.OutcomeTag(err))
+		if err != nil {
+			d.scheduleRetry(retry, now, err)
+			logger.Error("retry-failed", err, lager.Data{"volume": name, "attempts": retry.attempts, "failing-since": retry.failingSince})
+			continue
+		}
+		logger.Info("retry-succeeded", lager.Data{"volume": name, "attempts": retry.attempts})
+	}
+
+	d.emitPersistGauges()
+}
+
+// PendingPersists lists the state writes waiting for a retry, the longest
+// failing first.
+func (d *VolumeDriver) PendingPersists() []PendingPersist {
+	d.volumesLock.RLock()
+	defer d.volumesLock.RUnlock()
+
+	return d.pendingPersists()
+}
+
+// pendingPersists must be called with volumesLock held.
+func (d *VolumeDriver) pendingPersists() []PendingPersist {
+	pending := []PendingPersist{}
+	for name, retry := range d.persistRetries {
+		pending = append(pending, PendingPersist{
+			Volume:       name,
+			Attempts:     retry.attempts,
+			FailingSince: retry.failingSince,
+			NextRetry:    retry.nextRetry,
+			LastError:    retry.lastError,
+		})
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		if !pending[i].FailingSince.Equal(pending[j].FailingSince) {
+			return pending[i].FailingSince.Before(pending[j].FailingSince)
+		}
+		return pending[i].Volume < pending[j].Volume
+	})
+
+	return pending
+}
+
+// scheduleRetry doubles the backoff with every failed attempt, starting at
+// Options.PersistRetryInterval.
+func (d *VolumeDriver) scheduleRetry(retry *persistRetry, now time.Time, err error) {
+	backoff := d.options.PersistRetryInterval
+	for i := 0; i < retry.attempts && backoff < MaxPersistRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > MaxPersistRetryBackoff {
+		backoff = MaxPersistRetryBackoff
+	}
+
+	retry.attempts++
+	retry.nextRetry = now.Add(backoff)
+	retry.lastError = err.Error()
+}
+
+// emitPersistGauges must be called with volumesLock held. The failing time
+// of the oldest queued write is the signal to alert on: it only grows while
+// writes keep failing, for example on a full or read-only disk.
+func (d *VolumeDriver) emitPersistGauges() {
+	var oldest time.Time
+	for _, retry := range d.persistRetries {
+		if oldest.IsZero() || retry.failingSince.Before(oldest) {
+			oldest = retry.failingSince
+		}
+	}
+
+	failing := 0.0
+	if !oldest.IsZero() {
+		failing = d.time.Now().Sub(oldest).Seconds()
+	}
+
+	d.metrics.Gauge(metrics.PersistPending, float64(len(d.persistRetries)))
+	d.metrics.Gauge(metrics.PersistFailingSeconds, failing)
+}
+
+func (d *VolumeDriver) runPersistRetries(env dockerdriver.Env, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.RetryStateWrites(env)
+		case <-d.stop:
+			return
+		}
+	}
+}
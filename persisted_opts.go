@@ -0,0 +1,53 @@
+package volumedriver
+
+import "sort"
+
+// savedOpts returns the opts of a volume as they are persisted, and the
+// names of the opts left out. Opts whose name looks like a secret, such as
+// password, are only persisted when the state is encrypted with
+// Options.StateKey; references to secrets in the SecretResolver are always
+// persisted, since they hold no secret themselves.
+func (d *VolumeDriver) savedOpts(opts map[string]interface{}) (map[string]interface{}, []string) {
+	return withoutSecretOpts(opts, d.stateCipher != nil)
+}
+
+// exportedOpts returns the opts of a volume as ExportState reports them. The
+// export is not encrypted, so secret opts are always left out.
+func exportedOpts(volume *NfsVolumeInfo) (map[string]interface{}, []string) {
+	if len(volume.DroppedOpts) > 0 {
+		return volume.SavedOpts, volume.DroppedOpts
+	}
+	return withoutSecretOpts(volume.Opts, false)
+}
+
+func withoutSecretOpts(opts map[string]interface{}, keepSecrets bool) (map[string]interface{}, []string) {
+	if opts == nil {
+		return nil, nil
+	}
+
+	saved := map[string]interface{}{}
+	dropped := []string{}
+	for k, v := range opts {
+		if _, isRef, _ := ParseSecretRef(v); !keepSecrets && !isRef && secretOpt.MatchString(k) {
+			dropped = append(dropped, k)
+			continue
+		}
+		saved[k] = v
+	}
+
+	if len(dropped) == 0 {
+		return saved, nil
+	}
+	sort.Strings(dropped)
+	return saved, dropped
+}
+
+// restoredOpts returns the opts a volume is restored with. A volume whose
+// secret opts were left out has none, so that it is not mounted without
+// them; it must be created again first.
+func restoredOpts(volume *NfsVolumeInfo) map[string]interface{} {
+	if len(volume.DroppedOpts) > 0 {
+		return nil
+	}
+	return volume.SavedOpts
+}
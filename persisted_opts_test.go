@@ -0,0 +1,129 @@
+package volumedriver_test
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/testhelpers"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Persisted opts", func() {
+	const stateFile = testhelpers.MountPathRoot + "/driver-state.d/volume.json"
+
+	var (
+		env        dockerdriver.Env
+		options    volumedriver.Options
+		driver     *testhelpers.MemoryDriver
+		opts       map[string]interface{}
+		mountpoint string
+	)
+
+	restart := func() {
+		driver = driver.Restart(lagertest.NewTestLogger("persisted-opts"))
+	}
+
+	restoredOpts := func() map[string]interface{} {
+		return driver.DescribeVolume(env, volumedriver.DescribeVolumeRequest{Name: "volume"}).Volume.Opts
+	}
+
+	BeforeEach(func() {
+		env = driverhttp.NewHttpDriverEnv(lagertest.NewTestLogger("persisted-opts"), context.TODO())
+		options = volumedriver.DefaultOptions()
+		options.SecretResolver = &volumedriverfakes.FakeSecretResolver{}
+		opts = map[string]interface{}{"source": "server:/export", "vers": "4.1"}
+	})
+
+	JustBeforeEach(func() {
+		driver = testhelpers.NewMemoryDriverWithOptions(lagertest.NewTestLogger("persisted-opts"), options)
+		Expect(driver.Create(env, dockerdriver.CreateRequest{Name: "volume", Opts: opts}).Err).To(BeEmpty())
+		mountResponse := driver.Mount(env, dockerdriver.MountRequest{Name: "volume"})
+		Expect(mountResponse.Err).To(BeEmpty())
+		mountpoint = mountResponse.Mountpoint
+	})
+
+	It("restores the opts of volumes", func() {
+		restart()
+		Expect(restoredOpts()).To(Equal(opts))
+	})
+
+	It("writes the records only readable by the driver", func() {
+		info, err := driver.FS.Os().Stat(stateFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+		Expect(driver.FS.Exists(stateFile + ".tmp")).To(BeFalse())
+	})
+
+	It("mounts restored volumes again when their mount is gone", func() {
+		restart()
+		driver.Mounter.Break(mountpoint)
+
+		Expect(driver.Mount(env, dockerdriver.MountRequest{Name: "volume"}).Err).To(BeEmpty())
+		calls := driver.Mounter.MountCalls()
+		Expect(calls).To(HaveLen(2))
+		Expect(calls[1].Opts).To(HaveKeyWithValue("vers", "4.1"))
+	})
+
+	It("mounts volumes exported to another driver", func() {
+		exportResponse := driver.ExportState(env)
+		Expect(exportResponse.Err).To(BeEmpty())
+
+		other := testhelpers.NewMemoryDriverWithOptions(lagertest.NewTestLogger("persisted-opts"), options)
+		Expect(other.ImportState(env, volumedriver.ImportStateRequest{Volumes: exportResponse.Volumes}).Err).To(BeEmpty())
+
+		mountResponse := other.Mount(env, dockerdriver.MountRequest{Name: "volume"})
+		Expect(mountResponse.Err).To(BeEmpty())
+		Expect(mountResponse.Mountpoint).To(Equal(mountpoint))
+		Expect(other.Mounter.MountCalls()[0].Opts).To(HaveKeyWithValue("vers", "4.1"))
+		Expect(other.Get(env, dockerdriver.GetRequest{Name: "volume"}).Volume.MountCount).To(Equal(1))
+	})
+
+	Context("with secret opts", func() {
+		BeforeEach(func() {
+			opts["password"] = "hunter2"
+			opts["token"] = "vault://secret/data/filer#token"
+		})
+
+		It("does not write them to the state without a state key", func() {
+			data, err := driver.FS.ReadFile(stateFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).NotTo(ContainSubstring("hunter2"))
+			Expect(string(data)).To(ContainSubstring(`"DroppedOpts":["password"]`))
+			Expect(string(data)).To(ContainSubstring("vault://secret/data/filer#token"))
+		})
+
+		It("restores the volume without opts until it is created again", func() {
+			restart()
+			Expect(restoredOpts()).To(BeNil())
+
+			Expect(driver.Create(env, dockerdriver.CreateRequest{Name: "volume", Opts: opts}).Err).To(BeEmpty())
+			Expect(restoredOpts()).To(HaveKey("vers"))
+		})
+
+		Context("when the state is encrypted", func() {
+			BeforeEach(func() {
+				key, err := volumedriver.ParseStateKey(base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")))
+				Expect(err).NotTo(HaveOccurred())
+				options.StateKey = key
+			})
+
+			It("persists them encrypted", func() {
+				data, err := driver.FS.ReadFile(stateFile)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(data)).NotTo(ContainSubstring("hunter2"))
+
+				restart()
+				driver.Mounter.Break(mountpoint)
+				Expect(driver.Mount(env, dockerdriver.MountRequest{Name: "volume"}).Err).To(BeEmpty())
+				Expect(driver.Mounter.MountCalls()[1].Opts).To(HaveKeyWithValue("password", "hunter2"))
+			})
+		})
+	})
+})
@@ -0,0 +1,128 @@
+// Package pluginspec publishes a driver to docker's plugin discovery, see
+// https://docs.docker.com/engine/extend/plugin_api/#plugin-discovery.
+package pluginspec
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Dir is where docker discovers plugins by their spec files.
+const Dir = "/etc/docker/plugins"
+
+const (
+	specSuffix = ".spec"
+	jsonSuffix = ".json"
+)
+
+// TLSConfig is how docker connects to a plugin that serves TLS.
+type TLSConfig struct {
+	InsecureSkipVerify bool
+	CAFile             string `json:",omitempty"`
+	CertFile           string `json:",omitempty"` // client certificate docker presents
+	KeyFile            string `json:",omitempty"`
+}
+
+type jsonSpec struct {
+	Name      string
+	Addr      string
+	TLSConfig *TLSConfig `json:",omitempty"`
+}
+
+// Spec is a written spec file.
+type Spec struct {
+	path string
+}
+
+// Write publishes the plugin name, listening on address of network tcp or
+// unix, in dir. It writes <name>.spec with the address, or <name>.json with
+// an https address when tls is set, and removes a file of the other format
+// left by an earlier run, so that docker does not find two.
+func Write(dir, name, network, address string, tls *TLSConfig) (*Spec, error) {
+	var url string
+	switch network {
+	case "unix":
+		url = "unix://" + address
+	case "tcp":
+		url = "tcp://" + address
+	default:
+		return nil, fmt.Errorf("invalid network '%s', must be tcp or unix", network)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dir, name+specSuffix)
+	stale := filepath.Join(dir, name+jsonSuffix)
+	data := []byte(url + "\n")
+
+	if tls != nil {
+		if network != "tcp" {
+			return nil, fmt.Errorf("tls needs a tcp listener")
+		}
+		path, stale = stale, path
+
+		var err error
+		data, err = json.MarshalIndent(jsonSpec{Name: name, Addr: "https://" + address, TLSConfig: tls}, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writeFile(path, data); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(stale); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return &Spec{path: path}, nil
+}
+
+// writeFile replaces path at once, so that docker never reads a partial
+// spec.
+func writeFile(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Path returns the path of the spec file.
+func (s *Spec) Path() string {
+	if s == nil {
+		return ""
+	}
+	return s.path
+}
+
+// Remove deletes the spec file when the driver shuts down. It does nothing
+// for a nil Spec.
+func (s *Spec) Remove() error {
+	if s == nil {
+		return nil
+	}
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package pluginspec_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestPluginSpec(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "PluginSpec Suite")
+}
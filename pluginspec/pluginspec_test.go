@@ -0,0 +1,101 @@
+package pluginspec_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/volumedriver/pluginspec"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Write", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "pluginspec")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("writes the address of a unix listener to a .spec file", func() {
+		spec, err := pluginspec.Write(dir, "nfsdriver", "unix", "/var/vcap/sys/run/nfsdriver.sock", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(spec.Path()).To(Equal(filepath.Join(dir, "nfsdriver.spec")))
+
+		contents, err := ioutil.ReadFile(spec.Path())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(Equal("unix:///var/vcap/sys/run/nfsdriver.sock\n"))
+	})
+
+	It("writes the address of a tcp listener to a .spec file", func() {
+		spec, err := pluginspec.Write(dir, "nfsdriver", "tcp", "127.0.0.1:7589", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		contents, err := ioutil.ReadFile(spec.Path())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(Equal("tcp://127.0.0.1:7589\n"))
+	})
+
+	Context("with tls", func() {
+		var tls *pluginspec.TLSConfig
+
+		BeforeEach(func() {
+			tls = &pluginspec.TLSConfig{CAFile: "/certs/ca.pem", CertFile: "/certs/client.pem", KeyFile: "/certs/client.key"}
+		})
+
+		It("writes a .json file and removes an earlier .spec file", func() {
+			Expect(ioutil.WriteFile(filepath.Join(dir, "nfsdriver.spec"), []byte("tcp://127.0.0.1:7589\n"), 0644)).To(Succeed())
+
+			spec, err := pluginspec.Write(dir, "nfsdriver", "tcp", "127.0.0.1:7589", tls)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.Path()).To(Equal(filepath.Join(dir, "nfsdriver.json")))
+
+			contents, err := ioutil.ReadFile(spec.Path())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(MatchJSON(`{
+				"Name": "nfsdriver",
+				"Addr": "https://127.0.0.1:7589",
+				"TLSConfig": {"InsecureSkipVerify": false, "CAFile": "/certs/ca.pem", "CertFile": "/certs/client.pem", "KeyFile": "/certs/client.key"}
+			}`))
+			Expect(filepath.Join(dir, "nfsdriver.spec")).NotTo(BeAnExistingFile())
+		})
+
+		It("refuses unix listeners", func() {
+			_, err := pluginspec.Write(dir, "nfsdriver", "unix", "/var/vcap/sys/run/nfsdriver.sock", tls)
+			Expect(err).To(MatchError("tls needs a tcp listener"))
+		})
+	})
+
+	It("refuses unknown networks", func() {
+		_, err := pluginspec.Write(dir, "nfsdriver", "udp", "127.0.0.1:7589", nil)
+		Expect(err).To(MatchError("invalid network 'udp', must be tcp or unix"))
+	})
+
+	It("creates the directory", func() {
+		spec, err := pluginspec.Write(filepath.Join(dir, "plugins"), "nfsdriver", "tcp", "127.0.0.1:7589", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(spec.Path()).To(BeAnExistingFile())
+	})
+
+	Describe("Remove", func() {
+		It("deletes the spec file", func() {
+			spec, err := pluginspec.Write(dir, "nfsdriver", "tcp", "127.0.0.1:7589", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(spec.Remove()).To(Succeed())
+			Expect(spec.Path()).NotTo(BeAnExistingFile())
+			Expect(spec.Remove()).To(Succeed())
+		})
+
+		It("does nothing for a nil spec", func() {
+			var spec *pluginspec.Spec
+			Expect(spec.Remove()).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,81 @@
+package volumedriver
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+)
+
+// Policy is the part of the Options that can be changed while the driver
+// runs, see UpdatePolicy. Volumes that are already created or mounted are
+// not affected by a change.
+type Policy struct {
+	AllowedSources         []SourceRule
+	SourceDefaults         []SourceDefaults
+	SlowMountThreshold     time.Duration
+	CriticalMountThreshold time.Duration
+	MountHeartbeatInterval time.Duration
+	CheckDepth             CheckDepth
+	MountErrorTTL          time.Duration
+	MaxMountErrorTTL       time.Duration
+	Quotas                 Quotas
+	StateLimits            StateLimits
+	HardeningExemptions    []string
+}
+
+// Policy returns the policy settings of o.
+func (o Options) Policy() Policy {
+	return Policy{
+		AllowedSources:         o.AllowedSources,
+		SourceDefaults:         o.SourceDefaults,
+		SlowMountThreshold:     o.SlowMountThreshold,
+		CriticalMountThreshold: o.CriticalMountThreshold,
+		MountHeartbeatInterval: o.MountHeartbeatInterval,
+		CheckDepth:             o.CheckDepth,
+		MountErrorTTL:          o.MountErrorTTL,
+		MaxMountErrorTTL:       o.MaxMountErrorTTL,
+		Quotas:                 o.Quotas,
+		StateLimits:            o.StateLimits,
+		HardeningExemptions:    o.HardeningExemptions,
+	}
+}
+
+// UpdatePolicy replaces the policy the driver was created with, for example
+// when its configuration is reloaded. An invalid policy is refused and the
+// current one is kept.
+func (d *VolumeDriver) UpdatePolicy(env dockerdriver.Env, policy Policy) error {
+	logger := env.Logger().Session("update-policy")
+
+	if err := ValidateAllowedSources(policy.AllowedSources); err != nil {
+		logger.Error("invalid-policy", err)
+		return err
+	}
+	if err := ValidateSourceDefaults(policy.SourceDefaults); err != nil {
+		logger.Error("invalid-policy", err)
+		return err
+	}
+	if err := ValidateHardeningExemptions(policy.HardeningExemptions); err != nil {
+		logger.Error("invalid-policy", err)
+		return err
+	}
+	if policy.CheckDepth != "" {
+		if _, err := ParseCheckDepth(string(policy.CheckDepth)); err != nil {
+			logger.Error("invalid-policy", err)
+			return err
+		}
+	}
+
+	d.policyLock.Lock()
+	defer d.policyLock.Unlock()
+	d.policy = policy
+
+	logger.Info("policy-updated", lager.Data{"policy": policy})
+	return nil
+}
+
+func (d *VolumeDriver) currentPolicy() Policy {
+	d.policyLock.RLock()
+	defer d.policyLock.RUnlock()
+	return d.policy
+}
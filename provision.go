@@ -0,0 +1,123 @@
+package volumedriver
+
+import (
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+)
+
+// ProvisionRequest describes the export to create for a volume created with
+// the provision opt.
+type ProvisionRequest struct {
+	// Volume is the name of the volume the export is created for.
+	Volume string
+	// Parent is the value of the provision opt, where the provisioner
+	// creates the export, for example the ONTAP volume of a qtree.
+	Parent string
+	// Size is the quota of the export in bytes. Zero sets no quota.
+	Size uint64
+	// Clients are the hosts and networks allowed to mount the export. The
+	// provisioner falls back to its own default when it is empty.
+	Clients []string
+}
+
+//go:generate counterfeiter -o volumedriverfakes/fake_provisioner.go . Provisioner
+type Provisioner interface {
+	// Provision creates the export of a volume and returns its source, as
+	// passed in the source opt. Create calls it again when a volume is
+	// created again, it then returns the source of the existing export.
+	Provision(env dockerdriver.Env, request ProvisionRequest) (string, error)
+}
+
+// parseProvisionOpts returns nil when opts do not ask for provisioning.
+func parseProvisionOpts(volumeName string, opts map[string]interface{}) (*ProvisionRequest, error) {
+	value, ok := opts[ProvisionOpt]
+	if !ok {
+		for _, opt := range []string{ProvisionSizeOpt, ProvisionClientsOpt} {
+			if _, ok := opts[opt]; ok {
+				return nil, fmt.Errorf("the %s opt requires the %s opt", opt, ProvisionOpt)
+			}
+		}
+		return nil, nil
+	}
+
+	parent, ok := value.(string)
+	if !ok || strings.TrimSpace(parent) == "" {
+		return nil, fmt.Errorf("invalid %s '%v', must be the name of the parent of the export", ProvisionOpt, value)
+	}
+	if _, ok := opts["source"]; ok {
+		return nil, fmt.Errorf("the %s opt cannot be combined with a source", ProvisionOpt)
+	}
+
+	request := &ProvisionRequest{Volume: volumeName, Parent: strings.TrimSpace(parent)}
+
+	if value, ok := opts[ProvisionSizeOpt]; ok {
+		size, err := parseLimit(value)
+		if err != nil || size == 0 {
+			return nil, fmt.Errorf("invalid %s '%v', must be a positive number of bytes", ProvisionSizeOpt, value)
+		}
+		request.Size = size
+	}
+
+	if value, ok := opts[ProvisionClientsOpt]; ok {
+		clients, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid %s '%v', must be a comma separated list of hosts and networks", ProvisionClientsOpt, value)
+		}
+		for _, client := range strings.Split(clients, ",") {
+			if client = strings.TrimSpace(client); client != "" {
+				request.Clients = append(request.Clients, client)
+			}
+		}
+	}
+
+	return request, nil
+}
+
+// provision creates the export of volumes created with the provision opt and
+// returns their opts with the source of the export. Other opts are returned
+// unchanged.
+func (d *VolumeDriver) provision(env dockerdriver.Env, volumeName string, opts map[string]interface{}) (map[string]interface{}, error) {
+	request, err := parseProvisionOpts(volumeName, opts)
+	if err != nil || request == nil {
+		return opts, err
+	}
+
+	if d.options.Provisioner == nil {
+		return nil, fmt.Errorf("the %s opt is not supported by this driver", ProvisionOpt)
+	}
+
+	logger := env.Logger().Session("provision", lager.Data{"volume": volumeName, "parent": request.Parent})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	source, err := d.options.Provisioner.Provision(env, *request)
+	if err != nil {
+		logger.Error("provision-failed", err)
+		return nil, fmt.Errorf("Error provisioning volume '%s': %s", volumeName, err.Error())
+	}
+	logger.Info("provisioned", lager.Data{"source": source})
+
+	provisioned := map[string]interface{}{}
+	for k, v := range opts {
+		provisioned[k] = v
+	}
+	provisioned["source"] = source
+
+	return provisioned, nil
+}
+
+// withoutProvisionOpts returns a copy of opts without the provisioning opts,
+// for volumes that are created from the source of another volume.
+func withoutProvisionOpts(opts map[string]interface{}) map[string]interface{} {
+	filtered := map[string]interface{}{}
+	for k, v := range opts {
+		filtered[k] = v
+	}
+	for _, k := range []string{ProvisionOpt, ProvisionSizeOpt, ProvisionClientsOpt} {
+		delete(filtered, k)
+	}
+	return filtered
+}
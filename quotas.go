@@ -0,0 +1,74 @@
+package volumedriver
+
+import "fmt"
+
+// Quotas cap how many volumes and mounts the driver manages, so that a
+// runaway number of bindings cannot exhaust the cell. Zero means unlimited.
+type Quotas struct {
+	// MaxVolumes is the number of volumes that can exist at once.
+	MaxVolumes int
+	// MaxMounts is the number of volumes that can be mounted at once.
+	MaxMounts int
+	// MaxMountsPerSource is the number of volumes of a single source that
+	// can be mounted at once.
+	MaxMountsPerSource int
+}
+
+const (
+	QuotaVolumes         = "volumes"
+	QuotaMounts          = "mounts"
+	QuotaMountsPerSource = "mounts per source"
+)
+
+// QuotaExceededErrPrefix starts the Err of every response refused by a
+// quota, so that clients can tell them from other failures.
+const QuotaExceededErrPrefix = "quota exceeded: "
+
+// QuotaExceededError is returned when a request would take the driver past
+// one of its Quotas.
+type QuotaExceededError struct {
+	Quota string
+	Limit int
+}
+
+func (e QuotaExceededError) Error() string {
+	return fmt.Sprintf("%sthe %s limit of %d is reached", QuotaExceededErrPrefix, e.Quota, e.Limit)
+}
+
+// checkVolumeQuota must be called with volumesLock held, before a new
+// volume is added.
+func (d *VolumeDriver) checkVolumeQuota() error {
+	quotas := d.currentPolicy().Quotas
+	if quotas.MaxVolumes > 0 && len(d.volumes) >= quotas.MaxVolumes {
+		return QuotaExceededError{Quota: QuotaVolumes, Limit: quotas.MaxVolumes}
+	}
+	return nil
+}
+
+// checkMountQuota must be called with volumesLock held, before a volume of
+// source that is not mounted yet is mounted.
+func (d *VolumeDriver) checkMountQuota(source string) error {
+	quotas := d.currentPolicy().Quotas
+	if quotas.MaxMounts <= 0 && quotas.MaxMountsPerSource <= 0 {
+		return nil
+	}
+
+	mounted, mountedFromSource := 0, 0
+	for _, volume := range d.volumes {
+		if volume.MountCount < 1 {
+			continue
+		}
+		mounted++
+		if sourceOpt(volume.Opts) == source {
+			mountedFromSource++
+		}
+	}
+
+	if quotas.MaxMounts > 0 && mounted >= quotas.MaxMounts {
+		return QuotaExceededError{Quota: QuotaMounts, Limit: quotas.MaxMounts}
+	}
+	if quotas.MaxMountsPerSource > 0 && mountedFromSource >= quotas.MaxMountsPerSource {
+		return QuotaExceededError{Quota: QuotaMountsPerSource, Limit: quotas.MaxMountsPerSource}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package remotemounter
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	cf_http_handlers "code.cloudfoundry.org/cfhttp/handlers"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+	"github.com/tedsuo/rata"
+)
+
+// NewHandler serves a Mounter over the remote mounter protocol. It is meant to
+// be run by sidecar processes that provide mounts for the driver.
+func NewHandler(logger lager.Logger, mounter volumedriver.Mounter) (http.Handler, error) {
+	logger = logger.Session("mounter-server")
+	logger.Info("start")
+	defer logger.Info("end")
+
+	var handlers = rata.Handlers{
+		MountRoute:   newMountHandler(logger, mounter),
+		UnmountRoute: newUnmountHandler(logger, mounter),
+		CheckRoute:   newCheckHandler(logger, mounter),
+		PurgeRoute:   newPurgeHandler(logger, mounter),
+	}
+
+	return rata.NewRouter(Routes, handlers)
+}
+
+func newMountHandler(logger lager.Logger, mounter volumedriver.Mounter) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		logger := logger.Session("handle-mount")
+		logger.Info("start")
+		defer logger.Info("end")
+
+		var mountRequest MountRequest
+		if err := readRequest(req, &mountRequest); err != nil {
+			logger.Error("failed-reading-mount-request", err)
+			cf_http_handlers.WriteJSONResponse(w, http.StatusOK, ErrorResponse{Err: err.Error()})
+			return
+		}
+
+		err := mounter.Mount(driverhttp.EnvWithMonitor(logger, req.Context(), w), mountRequest.Source, mountRequest.Target, mountRequest.Opts)
+		if err != nil {
+			logger.Error("failed-mounting", err, lager.Data{"target": mountRequest.Target})
+		}
+
+		cf_http_handlers.WriteJSONResponse(w, http.StatusOK, errorResponse(err))
+	}
+}
+
+func newUnmountHandler(logger lager.Logger, mounter volumedriver.Mounter) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		logger := logger.Session("handle-unmount")
+		logger.Info("start")
+		defer logger.Info("end")
+
+		var unmountRequest UnmountRequest
+		if err := readRequest(req, &unmountRequest); err != nil {
+			logger.Error("failed-reading-unmount-request", err)
+			cf_http_handlers.WriteJSONResponse(w, http.StatusOK, ErrorResponse{Err: err.Error()})
+			return
+		}
+
+		err := mounter.Unmount(driverhttp.EnvWithMonitor(logger, req.Context(), w), unmountRequest.Target)
+		if err != nil {
+			logger.Error("failed-unmounting", err, lager.Data{"target": unmountRequest.Target})
+		}
+
+		cf_http_handlers.WriteJSONResponse(w, http.StatusOK, errorResponse(err))
+	}
+}
+
+func newCheckHandler(logger lager.Logger, mounter volumedriver.Mounter) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		logger := logger.Session("handle-check")
+		logger.Info("start")
+		defer logger.Info("end")
+
+		var checkRequest CheckRequest
+		if err := readRequest(req, &checkRequest); err != nil {
+			logger.Error("failed-reading-check-request", err)
+			cf_http_handlers.WriteJSONResponse(w, http.StatusOK, CheckResponse{Err: err.Error()})
+			return
+		}
+
+		mounted := mounter.Check(driverhttp.EnvWithMonitor(logger, req.Context(), w), checkRequest.Name, checkRequest.MountPoint, checkRequest.Depth)
+
+		cf_http_handlers.WriteJSONResponse(w, http.StatusOK, CheckResponse{Mounted: mounted})
+	}
+}
+
+func newPurgeHandler(logger lager.Logger, mounter volumedriver.Mounter) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		logger := logger.Session("handle-purge")
+		logger.Info("start")
+		defer logger.Info("end")
+
+		var purgeRequest PurgeRequest
+		if err := readRequest(req, &purgeRequest); err != nil {
+			logger.Error("failed-reading-purge-request", err)
+			cf_http_handlers.WriteJSONResponse(w, http.StatusOK, ErrorResponse{Err: err.Error()})
+			return
+		}
+
+		mounter.Purge(driverhttp.EnvWithMonitor(logger, req.Context(), w), purgeRequest.Path)
+
+		cf_http_handlers.WriteJSONResponse(w, http.StatusOK, ErrorResponse{})
+	}
+}
+
+func readRequest(req *http.Request, request interface{}) error {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(body, request)
+}
+
+func errorResponse(err error) ErrorResponse {
+	if err == nil {
+		return ErrorResponse{}
+	}
+
+	if safe, ok := safeerrors.From(err); ok {
+		return ErrorResponse{Err: safe.SafeDescription, Safe: true, Code: safe.Code}
+	}
+	return ErrorResponse{Err: err.Error()}
+}
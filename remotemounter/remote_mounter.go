@@ -0,0 +1,155 @@
+package remotemounter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"code.cloudfoundry.org/cfhttp"
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/goshims/http_wrap"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+	"github.com/tedsuo/rata"
+)
+
+type remoteMounter struct {
+	httpClient http_wrap.Client
+	reqGen     *rata.RequestGenerator
+}
+
+// NewRemoteMounter returns a Mounter that delegates to a sidecar process
+// serving the remote mounter protocol. Addresses ending in .sock are treated
+// as unix sockets.
+func NewRemoteMounter(url string) volumedriver.Mounter {
+	client := cfhttp.NewClient()
+
+	if strings.Contains(url, ".sock") {
+		client = cfhttp.NewUnixClient(url)
+		url = fmt.Sprintf("unix://%s", url)
+	}
+
+	return NewRemoteMounterWithClient(url, client)
+}
+
+func NewRemoteMounterWithClient(url string, client http_wrap.Client) volumedriver.Mounter {
+	return &remoteMounter{
+		httpClient: client,
+		reqGen:     rata.NewRequestGenerator(url, Routes),
+	}
+}
+
+func (m *remoteMounter) Mount(env dockerdriver.Env, source string, target string, opts map[string]interface{}) error {
+	logger := env.Logger().Session("remote-mount", lager.Data{"source": source, "target": target})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	var response ErrorResponse
+	err := m.do(env.Context(), MountRoute, MountRequest{Source: source, Target: target, Opts: opts}, &response)
+	if err != nil {
+		logger.Error("request-failed", err)
+		return err
+	}
+
+	return responseError(response)
+}
+
+func (m *remoteMounter) Unmount(env dockerdriver.Env, target string) error {
+	logger := env.Logger().Session("remote-unmount", lager.Data{"target": target})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	var response ErrorResponse
+	err := m.do(env.Context(), UnmountRoute, UnmountRequest{Target: target}, &response)
+	if err != nil {
+		logger.Error("request-failed", err)
+		return err
+	}
+
+	return responseError(response)
+}
+
+func (m *remoteMounter) Check(env dockerdriver.Env, name, mountPoint string, depth volumedriver.CheckDepth) bool {
+	logger := env.Logger().Session("remote-check", lager.Data{"name": name, "mount-point": mountPoint, "depth": depth})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	var response CheckResponse
+	err := m.do(env.Context(), CheckRoute, CheckRequest{Name: name, MountPoint: mountPoint, Depth: depth}, &response)
+	if err != nil {
+		logger.Error("request-failed", err)
+		return false
+	}
+
+	if response.Err != "" {
+		logger.Error("check-failed", errors.New(response.Err))
+		return false
+	}
+
+	return response.Mounted
+}
+
+func (m *remoteMounter) Purge(env dockerdriver.Env, path string) {
+	logger := env.Logger().Session("remote-purge", lager.Data{"path": path})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	var response ErrorResponse
+	err := m.do(env.Context(), PurgeRoute, PurgeRequest{Path: path}, &response)
+	if err != nil {
+		logger.Error("request-failed", err)
+		return
+	}
+
+	if response.Err != "" {
+		logger.Error("purge-failed", errors.New(response.Err))
+	}
+}
+
+// Unprivileged reports that the sidecar mounts for the driver, which then
+// does not need to run as root.
+func (m *remoteMounter) Unprivileged() bool {
+	return true
+}
+
+func (m *remoteMounter) do(ctx context.Context, route string, request interface{}, response interface{}) error {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	httpRequest, err := m.reqGen.CreateRequest(route, nil, bytes.NewBuffer(payload))
+	if err != nil {
+		return err
+	}
+
+	httpResponse, err := m.httpClient.Do(httpRequest.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer httpResponse.Body.Close()
+
+	data, err := ioutil.ReadAll(httpResponse.Body)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, response)
+}
+
+func responseError(response ErrorResponse) error {
+	if response.Err == "" {
+		return nil
+	}
+
+	if response.Safe {
+		return safeerrors.New(response.Code, "%s", response.Err)
+	}
+
+	return errors.New(response.Err)
+}
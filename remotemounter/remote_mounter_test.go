@@ -0,0 +1,154 @@
+package remotemounter_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/remotemounter"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RemoteMounter", func() {
+	var (
+		logger      *lagertest.TestLogger
+		env         dockerdriver.Env
+		fakeMounter *volumedriverfakes.FakeMounter
+		server      *httptest.Server
+		mounter     volumedriver.Mounter
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("remotemounter")
+		env = driverhttp.NewHttpDriverEnv(logger, context.TODO())
+		fakeMounter = &volumedriverfakes.FakeMounter{}
+
+		handler, err := remotemounter.NewHandler(logger, fakeMounter)
+		Expect(err).NotTo(HaveOccurred())
+		server = httptest.NewServer(handler)
+
+		mounter = remotemounter.NewRemoteMounter(server.URL)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("Mount", func() {
+		var err error
+
+		JustBeforeEach(func() {
+			err = mounter.Mount(env, "server:/export", "/mnt/target", map[string]interface{}{"uid": "1000"})
+		})
+
+		It("mounts through the remote mounter", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeMounter.MountCallCount()).To(Equal(1))
+			_, source, target, opts := fakeMounter.MountArgsForCall(0)
+			Expect(source).To(Equal("server:/export"))
+			Expect(target).To(Equal("/mnt/target"))
+			Expect(opts).To(Equal(map[string]interface{}{"uid": "1000"}))
+		})
+
+		Context("when the remote mount fails", func() {
+			BeforeEach(func() {
+				fakeMounter.MountReturns(errors.New("badness"))
+			})
+
+			It("returns the error", func() {
+				Expect(err).To(MatchError("badness"))
+			})
+		})
+
+		Context("when the remote mount fails with a safe error", func() {
+			BeforeEach(func() {
+				fakeMounter.MountReturns(safeerrors.New(safeerrors.NotFound, "safe-badness"))
+			})
+
+			It("preserves the safe error", func() {
+				Expect(err).To(Equal(safeerrors.New(safeerrors.NotFound, "safe-badness")))
+			})
+		})
+
+		Context("when the remote mounter is not reachable", func() {
+			BeforeEach(func() {
+				server.Close()
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("Unmount", func() {
+		It("unmounts through the remote mounter", func() {
+			Expect(mounter.Unmount(env, "/mnt/target")).To(Succeed())
+			Expect(fakeMounter.UnmountCallCount()).To(Equal(1))
+			_, target := fakeMounter.UnmountArgsForCall(0)
+			Expect(target).To(Equal("/mnt/target"))
+		})
+
+		Context("when the remote unmount fails", func() {
+			BeforeEach(func() {
+				fakeMounter.UnmountReturns(errors.New("badness"))
+			})
+
+			It("returns the error", func() {
+				Expect(mounter.Unmount(env, "/mnt/target")).To(MatchError("badness"))
+			})
+		})
+	})
+
+	Describe("Check", func() {
+		It("reports the remote result", func() {
+			fakeMounter.CheckReturns(true)
+			Expect(mounter.Check(env, "volume", "/mnt/target", volumedriver.CheckRead)).To(BeTrue())
+
+			fakeMounter.CheckReturns(false)
+			Expect(mounter.Check(env, "volume", "/mnt/target", volumedriver.CheckRead)).To(BeFalse())
+
+			_, name, mountPoint, depth := fakeMounter.CheckArgsForCall(0)
+			Expect(name).To(Equal("volume"))
+			Expect(mountPoint).To(Equal("/mnt/target"))
+			Expect(depth).To(Equal(volumedriver.CheckRead))
+		})
+
+		Context("when the remote mounter is not reachable", func() {
+			BeforeEach(func() {
+				fakeMounter.CheckReturns(true)
+				server.Close()
+			})
+
+			It("reports the volume as not mounted", func() {
+				Expect(mounter.Check(env, "volume", "/mnt/target", volumedriver.CheckRead)).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("Purge", func() {
+		It("purges through the remote mounter", func() {
+			mounter.Purge(env, "/mnt")
+			Expect(fakeMounter.PurgeCallCount()).To(Equal(1))
+			_, path := fakeMounter.PurgeArgsForCall(0)
+			Expect(path).To(Equal("/mnt"))
+		})
+	})
+
+	Describe("Handler", func() {
+		It("rejects malformed requests without calling the mounter", func() {
+			response, err := http.Post(server.URL+"/Mounter.Mount", "application/json", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			Expect(fakeMounter.MountCallCount()).To(Equal(0))
+		})
+	})
+})
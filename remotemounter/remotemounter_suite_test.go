@@ -0,0 +1,13 @@
+package remotemounter_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestRemoteMounter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "RemoteMounter Suite")
+}
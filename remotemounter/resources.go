@@ -0,0 +1,58 @@
+package remotemounter
+
+import (
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+	"github.com/tedsuo/rata"
+)
+
+// The remote mounter protocol lets a sidecar process implement
+// volumedriver.Mounter. Like the docker volume plugin protocol it is JSON
+// over HTTP, served on a unix socket or TCP address, and errors are reported
+// in the response body.
+const (
+	MountRoute   = "mount"
+	UnmountRoute = "unmount"
+	CheckRoute   = "check"
+	PurgeRoute   = "purge"
+)
+
+var Routes = rata.Routes{
+	{Path: "/Mounter.Mount", Method: "POST", Name: MountRoute},
+	{Path: "/Mounter.Unmount", Method: "POST", Name: UnmountRoute},
+	{Path: "/Mounter.Check", Method: "POST", Name: CheckRoute},
+	{Path: "/Mounter.Purge", Method: "POST", Name: PurgeRoute},
+}
+
+type MountRequest struct {
+	Source string
+	Target string
+	Opts   map[string]interface{}
+}
+
+type UnmountRequest struct {
+	Target string
+}
+
+type CheckRequest struct {
+	Name       string
+	MountPoint string
+	Depth      volumedriver.CheckDepth
+}
+
+type CheckResponse struct {
+	Err     string
+	Mounted bool
+}
+
+type PurgeRequest struct {
+	Path string
+}
+
+// ErrorResponse carries a mounter error. Safe and Code are set when the
+// error is a safe error, so that it keeps its type on the driver side.
+type ErrorResponse struct {
+	Err  string
+	Safe bool
+	Code safeerrors.Code `json:",omitempty"`
+}
@@ -0,0 +1,145 @@
+package volumedriver
+
+import (
+	"fmt"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager"
+)
+
+//go:generate counterfeiter -o volumedriverfakes/fake_remounter.go . Remounter
+type Remounter interface {
+	// Remount changes the options of the filesystem mounted at mountPoint
+	// in place, without unmounting it.
+	Remount(env dockerdriver.Env, mountPoint string, opts map[string]interface{}) error
+}
+
+// RemountVolume applies new mount options, such as ro or actimeo, to a
+// mounted volume while containers keep using it. The options are kept for
+// later mounts of the volume, also when it is created again with its
+// original opts.
+func (d *VolumeDriver) RemountVolume(env dockerdriver.Env, remountRequest RemountVolumeRequest) dockerdriver.ErrorResponse {
+	logger := env.Logger().Session("remount-volume", lager.Data{"volume": remountRequest.Name, "opts": remountRequest.Opts})
+	logger.Info("start")
+	defer logger.Info("end")
+	defer d.inFlight.start(env, "remount-volume", remountRequest.Name).done()
+
+	if remountRequest.Name == "" {
+		return dockerdriver.ErrorResponse{Err: "Missing mandatory 'volume_name'"}
+	}
+
+	if d.options.Remounter == nil {
+		return dockerdriver.ErrorResponse{Err: "Remount is not supported by this driver"}
+	}
+
+	if len(remountRequest.Opts) == 0 {
+		return dockerdriver.ErrorResponse{Err: "Missing mandatory 'Opts'"}
+	}
+	if err := ValidateOpts(remountRequest.Opts); err != nil {
+		logger.Info("invalid-opts", lager.Data{"err": err.Error()})
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+	for key := range remountRequest.Opts {
+		if key == "source" || isDriverOpt(key) {
+			return dockerdriver.ErrorResponse{Err: fmt.Sprintf("the %s opt cannot be changed by a remount", key)}
+		}
+	}
+
+	opts, err := normalizeCommonOpts(remountRequest.Opts)
+	if err != nil {
+		logger.Info("invalid-opts", lager.Data{"err": err.Error()})
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+	remountRequest.Opts = opts
+
+	if err := d.validateSecretRefs(remountRequest.Opts); err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+
+	mountPoint, errResponse := d.remountTarget(remountRequest.Name)
+	if errResponse.Err != "" {
+		return errResponse
+	}
+
+	hardened, stripped := d.hardenOpts(remountRequest.Opts)
+	if len(stripped) > 0 {
+		logger.Info("stripped-privileged-opts", lager.Data{"opts": stripped})
+	}
+
+	resolved, secrets, err := d.resolveSecrets(driverhttp.EnvWithLogger(logger, env), hardened)
+	if err == nil {
+		err = redactSecrets(d.options.Remounter.Remount(driverhttp.EnvWithLogger(logger, env), mountPoint, resolved), secrets)
+	}
+	if err != nil {
+		logger.Error("remount-failed", err)
+		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("Error remounting volume '%s': %s", remountRequest.Name, err.Error())}
+	}
+
+	d.volumesLock.Lock()
+	defer d.volumesLock.Unlock()
+
+	volume, ok := d.volumes[remountRequest.Name]
+	if !ok {
+		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("Volume '%s' not found", remountRequest.Name)}
+	}
+	volume.Opts = withRemountOpts(volume.Opts, remountRequest.Opts)
+	volume.RemountOpts = withRemountOpts(volume.RemountOpts, remountRequest.Opts)
+
+	if err := d.persistVolume(driverhttp.EnvWithLogger(logger, env), remountRequest.Name); err != nil {
+		logger.Error("persist-state-failed", err)
+		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("failed to persist state: %s", err.Error())}
+	}
+
+	d.publish(EventRemounted, remountRequest.Name, nil)
+	return dockerdriver.ErrorResponse{}
+}
+
+// remountTarget returns the mountpoint of a volume that can be remounted.
+func (d *VolumeDriver) remountTarget(volumeName string) (string, dockerdriver.ErrorResponse) {
+	d.volumesLock.RLock()
+	defer d.volumesLock.RUnlock()
+
+	volume, ok := d.volumes[volumeName]
+	if !ok {
+		return "", dockerdriver.ErrorResponse{Err: fmt.Sprintf("Volume '%s' not found", volumeName)}
+	}
+	if volume.MountCount < 1 || volume.Mountpoint == "" {
+		return "", dockerdriver.ErrorResponse{Err: fmt.Sprintf("Volume '%s' is not mounted", volumeName)}
+	}
+	if volume.Frozen {
+		return "", dockerdriver.ErrorResponse{Err: fmt.Sprintf("Volume '%s' is frozen, thaw it before remounting", volumeName)}
+	}
+	return volume.Mountpoint, dockerdriver.ErrorResponse{}
+}
+
+// withRemountOpts returns a copy of opts with changes applied. Setting ro or
+// rw drops the other one, and rw also the readonly opt.
+func withRemountOpts(opts map[string]interface{}, changes map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for k, v := range opts {
+		merged[k] = v
+	}
+
+	for k, v := range changes {
+		switch k {
+		case "ro":
+			delete(merged, "rw")
+		case "rw":
+			delete(merged, ReadOnlyAliasOpt)
+			delete(merged, ReadOnlyOpt)
+		}
+		merged[k] = v
+	}
+
+	return merged
+}
+
+func isDriverOpt(key string) bool {
+	for _, opt := range driverOpts {
+		if opt == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package volumedriver
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+)
+
+// remountRestoredVolumes mounts the restored volumes that were mounted when
+// the driver stopped, unless their mount survived the restart. The mounts
+// run in the background; Mount requests for the volumes wait for them, like
+// for any mount in progress.
+func (d *VolumeDriver) remountRestoredVolumes(env dockerdriver.Env) {
+	logger := env.Logger().Session("remount-restored-volumes")
+	logger.Info("start")
+	defer logger.Info("end")
+
+	type restoredMount struct {
+		volume    *NfsVolumeInfo
+		mounter   Mounter
+		opts      map[string]interface{}
+		mountPath string
+		depth     CheckDepth
+	}
+
+	mounts := []restoredMount{}
+	d.volumesLock.Lock()
+	for _, volume := range d.volumes {
+		if volume.MountCount < 1 {
+			continue
+		}
+		if sourceOpt(volume.Opts) == "" {
+			logger.Info("skipping-volume-without-opts", lager.Data{"volume": volume.Name})
+			continue
+		}
+
+		volume.mount.begin(phaseMounting)
+		mounts = append(mounts, restoredMount{
+			volume:    volume,
+			mounter:   d.volumeMounter(volume),
+			opts:      copyOpts(volume.Opts),
+			mountPath: d.volumeMountPath(env, volume),
+			depth:     d.checkDepth(volume),
+		})
+	}
+	d.volumesLock.Unlock()
+
+	logger.Info("remounting", lager.Data{"volumes": len(mounts)})
+
+	var remounts sync.WaitGroup
+	for _, mount := range mounts {
+		remounts.Add(1)
+		go func(mount restoredMount) {
+			defer remounts.Done()
+
+			name := mount.volume.Name
+			if mount.mounter.Check(env, name, mount.mountPath, mount.depth) {
+				logger.Info("still-mounted", lager.Data{"volume": name})
+
+				d.volumesLock.Lock()
+				defer d.volumesLock.Unlock()
+				mount.volume.mount.finish(phaseMounted)
+				return
+			}
+
+			err := d.mount(env, mount.mounter, name, mount.opts, mount.mountPath)
+			if err != nil {
+				logger.Error("remount-failed", err, lager.Data{"volume": name})
+			} else {
+				logger.Info("remounted", lager.Data{"volume": name, "mountpoint": mount.mountPath})
+			}
+
+			d.volumesLock.Lock()
+			defer d.volumesLock.Unlock()
+
+			mount.volume.mount.finishMount(err)
+			if volume, ok := d.volumes[name]; ok && volume == mount.volume {
+				volume.Mountpoint = mount.mountPath
+				d.recordMountOutcome(env, volume, err)
+			}
+		}(mount)
+	}
+
+	go func() {
+		remounts.Wait()
+		logger.Info("remounts-finished", lager.Data{"volumes": len(mounts)})
+	}()
+}
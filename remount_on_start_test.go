@@ -0,0 +1,92 @@
+package volumedriver_test
+
+import (
+	"context"
+	"errors"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/testhelpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RemountOnStart", func() {
+	const stateFile = testhelpers.MountPathRoot + "/driver-state.d/volume.json"
+
+	var (
+		logger     *lagertest.TestLogger
+		env        dockerdriver.Env
+		options    volumedriver.Options
+		driver     *testhelpers.MemoryDriver
+		mountpoint string
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("remount-on-start")
+		env = driverhttp.NewHttpDriverEnv(logger, context.TODO())
+
+		options = volumedriver.DefaultOptions()
+		options.RemountOnStart = true
+		driver = testhelpers.NewMemoryDriverWithOptions(logger, options)
+
+		Expect(driver.Create(env, dockerdriver.CreateRequest{
+			Name: "volume",
+			Opts: map[string]interface{}{"source": "server:/export", "vers": "4.1"},
+		}).Err).To(BeEmpty())
+		mountResponse := driver.Mount(env, dockerdriver.MountRequest{Name: "volume"})
+		Expect(mountResponse.Err).To(BeEmpty())
+		mountpoint = mountResponse.Mountpoint
+	})
+
+	It("persists the opts of volumes", func() {
+		data, err := driver.FS.ReadFile(stateFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring(`"SavedOpts":{"source":"server:/export","vers":"4.1"}`))
+	})
+
+	Context("when the mounts are gone after the restart", func() {
+		BeforeEach(func() {
+			Expect(driver.Mounter.Unmount(env, mountpoint)).To(Succeed())
+		})
+
+		It("mounts the volumes that were mounted again", func() {
+			driver = driver.Restart(logger)
+
+			Eventually(driver.Mounter.Mounts).Should(Equal(map[string]string{mountpoint: "server:/export"}))
+			calls := driver.Mounter.MountCalls()
+			Expect(calls[len(calls)-1].Opts).To(HaveKeyWithValue("vers", "4.1"))
+
+			mountResponse := driver.Mount(env, dockerdriver.MountRequest{Name: "volume"})
+			Expect(mountResponse.Err).To(BeEmpty())
+			Expect(driver.Get(env, dockerdriver.GetRequest{Name: "volume"}).Volume.MountCount).To(Equal(2))
+		})
+
+		It("remembers a failed remount", func() {
+			driver.Mounter.FailMount("server:/export", errors.New("connection refused"))
+			driver = driver.Restart(logger)
+
+			Eventually(func() string {
+				return driver.DescribeVolume(env, volumedriver.DescribeVolumeRequest{Name: "volume"}).Volume.LastMountError
+			}).Should(ContainSubstring("connection refused"))
+			Expect(driver.Get(env, dockerdriver.GetRequest{Name: "volume"}).Volume.MountCount).To(Equal(1))
+		})
+
+		It("leaves the volumes alone without the option", func() {
+			options.RemountOnStart = false
+			driver = driver.RestartWithOptions(logger, options)
+
+			Consistently(driver.Mounter.Mounts).Should(BeEmpty())
+		})
+	})
+
+	It("does not mount volumes again that are still mounted", func() {
+		mountCalls := len(driver.Mounter.MountCalls())
+		driver = driver.Restart(logger)
+
+		Consistently(func() int { return len(driver.Mounter.MountCalls()) }).Should(Equal(mountCalls))
+		Expect(driver.Mounter.Mounts()).To(HaveKey(mountpoint))
+	})
+})
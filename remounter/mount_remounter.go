@@ -0,0 +1,75 @@
+package remounter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/invoker"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+)
+
+type mountRemounter struct {
+	invoker invoker.Invoker
+}
+
+// NewMountRemounter returns a Remounter that runs mount -o remount,<opts>.
+// Which options can be changed in place depends on the filesystem: nfs
+// accepts ro and rw and most caching options such as actimeo, but not vers
+// or proto.
+func NewMountRemounter(invoker invoker.Invoker) volumedriver.Remounter {
+	return &mountRemounter{invoker: invoker}
+}
+
+func (r *mountRemounter) Remount(env dockerdriver.Env, mountPoint string, opts map[string]interface{}) error {
+	logger := env.Logger().Session("remount", lager.Data{"mount-point": mountPoint, "opts": opts})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	mountOpts, err := remountOpts(opts)
+	if err != nil {
+		return err
+	}
+
+	result := r.invoker.Invoke(env, "mount", []string{"-o", mountOpts, mountPoint})
+	if err := result.Wait(); err != nil {
+		logger.Error("remount-failed", err, lager.Data{"stderr": result.StdError()})
+		return safeerrors.FromMountHelper(err, result.StdError(), "remount failed")
+	}
+
+	return nil
+}
+
+// remountOpts formats opts as remount,k1=v1,k2. Options set to false are
+// left out.
+func remountOpts(opts map[string]interface{}) (string, error) {
+	keys := make([]string, 0, len(opts))
+	for k := range opts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	mountOpts := []string{"remount"}
+	for _, k := range keys {
+		var opt string
+		switch v := opts[k].(type) {
+		case bool:
+			if !v {
+				continue
+			}
+			opt = k
+		default:
+			opt = fmt.Sprintf("%s=%v", k, v)
+		}
+
+		if k == "" || strings.ContainsAny(opt, ", \t\n") {
+			return "", safeerrors.New(safeerrors.InvalidOption, fmt.Sprintf("invalid remount option '%s'", opt))
+		}
+		mountOpts = append(mountOpts, opt)
+	}
+
+	return strings.Join(mountOpts, ","), nil
+}
@@ -0,0 +1,57 @@
+package remounter_test
+
+import (
+	"context"
+	"errors"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/invokerfakes"
+	"code.cloudfoundry.org/volumedriver/remounter"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MountRemounter", func() {
+	var (
+		env              dockerdriver.Env
+		fakeInvoker      *invokerfakes.FakeInvoker
+		fakeInvokeResult *invokerfakes.FakeInvokeResult
+		subject          volumedriver.Remounter
+	)
+
+	BeforeEach(func() {
+		env = driverhttp.NewHttpDriverEnv(lagertest.NewTestLogger("mount-remounter"), context.TODO())
+		fakeInvoker = &invokerfakes.FakeInvoker{}
+		fakeInvokeResult = &invokerfakes.FakeInvokeResult{}
+		fakeInvoker.InvokeReturns(fakeInvokeResult)
+		subject = remounter.NewMountRemounter(fakeInvoker)
+	})
+
+	It("remounts the mountpoint with the opts", func() {
+		Expect(subject.Remount(env, "/mnt/volume", map[string]interface{}{"ro": true, "actimeo": 30, "sync": false})).To(Succeed())
+		_, executable, args, _ := fakeInvoker.InvokeArgsForCall(0)
+		Expect(executable).To(Equal("mount"))
+		Expect(args).To(Equal([]string{"-o", "remount,actimeo=30,ro", "/mnt/volume"}))
+	})
+
+	It("refuses opts that would inject further options", func() {
+		err := subject.Remount(env, "/mnt/volume", map[string]interface{}{"actimeo": "30,rw"})
+		Expect(err).To(Equal(safeerrors.New(safeerrors.InvalidOption, "invalid remount option 'actimeo=30,rw'")))
+		Expect(fakeInvoker.InvokeCallCount()).To(BeZero())
+	})
+
+	Context("when mount fails", func() {
+		BeforeEach(func() {
+			fakeInvokeResult.WaitReturns(errors.New("exit status 32"))
+			fakeInvokeResult.StdErrorReturns("mount.nfs: an incorrect mount option was specified\n")
+		})
+
+		It("returns its error", func() {
+			Expect(subject.Remount(env, "/mnt/volume", map[string]interface{}{"vers": "3"})).To(MatchError("remount failed: the mount options were rejected; check the opts of the volume"))
+		})
+	})
+})
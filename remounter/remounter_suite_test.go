@@ -0,0 +1,13 @@
+package remounter_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestRemounter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Remounter Suite")
+}
@@ -0,0 +1,114 @@
+package volumedriver
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+)
+
+// RemoveVolume removes a volume like Remove. With Force set it also removes
+// volumes that are still mounted: the mount is detached lazily when it is
+// busy, and the mount references of the containers are dropped.
+func (d *VolumeDriver) RemoveVolume(env dockerdriver.Env, removeRequest RemoveVolumeRequest) dockerdriver.ErrorResponse {
+	logger := env.Logger().Session("remove-volume", lager.Data{"volume": removeRequest.Name, "force": removeRequest.Force})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	if !removeRequest.Force {
+		return d.Remove(driverhttp.EnvWithLogger(logger, env), dockerdriver.RemoveRequest{Name: removeRequest.Name})
+	}
+
+	if removeRequest.Name == "" {
+		return dockerdriver.ErrorResponse{Err: "Missing mandatory 'volume_name'"}
+	}
+	op := d.inFlight.queue(env, "remove", removeRequest.Name)
+	defer op.done()
+
+	// a mount in progress finishes first, so that its mount is unmounted
+	// here rather than left behind
+	d.lockSettled(removeRequest.Name, op)
+	defer d.volumesLock.Unlock()
+	op.running()
+
+	volume, ok := d.volumes[removeRequest.Name]
+	if !ok {
+		logger.Info("volume-not-found")
+		return dockerdriver.ErrorResponse{}
+	}
+
+	if volume.Frozen {
+		return dockerdriver.ErrorResponse{Err: frozenError(removeRequest.Name)}
+	}
+
+	if volume.Mountpoint != "" {
+		if err := d.forceUnmount(driverhttp.EnvWithLogger(logger, env), volume); err != nil {
+			return dockerdriver.ErrorResponse{Err: err.Error()}
+		}
+		d.releaseExport(driverhttp.EnvWithLogger(logger, env), removeRequest.Name, sourceOpt(volume.Opts))
+		d.publish(EventUnmounted, removeRequest.Name, nil)
+	}
+
+	logger.Info("dropping-references", lager.Data{"mount-count": volume.MountCount, "references": volume.References})
+	delete(d.volumes, removeRequest.Name)
+	d.volumeLimiter.Forget(removeRequest.Name)
+	d.publish(EventRemoved, removeRequest.Name, nil)
+	d.emitVolumeGauges()
+
+	if err := d.persistVolumeOrQueue(driverhttp.EnvWithLogger(logger, env), removeRequest.Name); err != nil {
+		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("failed to persist state when removing: %s", err.Error())}
+	}
+
+	return dockerdriver.ErrorResponse{}
+}
+
+// forceUnmount must be called with volumesLock held. It unmounts the binds
+// and the volume like the last Unmount would, and detaches the mount lazily
+// when it is still busy.
+func (d *VolumeDriver) forceUnmount(env dockerdriver.Env, volume *NfsVolumeInfo) error {
+	logger := env.Logger().Session("force-unmount", lager.Data{"volume": volume.Name, "mountpoint": volume.Mountpoint})
+
+	d.unbindAll(env, volume)
+
+	mounter := d.volumeMounter(volume)
+	err := d.unmount(env, mounter, volume.Name, volume.Mountpoint)
+	if err == nil {
+		return nil
+	}
+
+	if mounted, checkErr := d.mountChecker.Exists(volume.Mountpoint); checkErr == nil && !mounted {
+		logger.Info("already-unmounted")
+		return nil
+	}
+
+	lazy, ok := mounter.(LazyUnmounter)
+	if !ok {
+		return fmt.Errorf("%s, and the mounter cannot detach busy mounts", err.Error())
+	}
+
+	logger.Info("detaching-busy-mount", lager.Data{"unmount-error": err.Error()})
+	if err := d.recordIntent(env, IntentUnmount, volume.Name, volume.Mountpoint); err != nil {
+		return err
+	}
+	defer d.clearIntent(env, volume.Name, volume.Mountpoint)
+
+	if err := lazy.LazyUnmount(env, volume.Mountpoint); err != nil {
+		logger.Error("lazy-unmount-failed", err)
+		return fmt.Errorf("Error detaching volume: %s", err.Error())
+	}
+
+	return d.removeMountpoint(env, volume.Mountpoint)
+}
+
+// inUseError is the safe error of Remove for volumes that are still mounted.
+func inUseError(volumeName string, mountCount int) string {
+	safe := safeerrors.New(safeerrors.InUse, "Volume '%s' is in use by %d mounts, unmount it first or remove it with force", volumeName, mountCount)
+	errBytes, err := json.Marshal(safe)
+	if err != nil {
+		return safe.Error()
+	}
+	return string(errBytes)
+}
@@ -0,0 +1,149 @@
+package requestid
+
+import (
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/volumedriver"
+)
+
+type admin struct {
+	admin volumedriver.Admin
+}
+
+// NewAdmin wraps a so that the log lines and the errors of every admin
+// request carry its request ID. Serve it behind NewHandler.
+func NewAdmin(a volumedriver.Admin) volumedriver.Admin {
+	return &admin{admin: a}
+}
+
+func (a *admin) ResetMountError(env dockerdriver.Env, resetRequest volumedriver.ResetMountErrorRequest) dockerdriver.ErrorResponse {
+	env, id := withID(env)
+	response := a.admin.ResetMountError(env, resetRequest)
+	response.Err = annotate(response.Err, id)
+	return response
+}
+
+func (a *admin) ExportState(env dockerdriver.Env) volumedriver.ExportStateResponse {
+	env, id := withID(env)
+	response := a.admin.ExportState(env)
+	response.Err = annotate(response.Err, id)
+	return response
+}
+
+func (a *admin) ImportState(env dockerdriver.Env, importRequest volumedriver.ImportStateRequest) dockerdriver.ErrorResponse {
+	env, id := withID(env)
+	response := a.admin.ImportState(env, importRequest)
+	response.Err = annotate(response.Err, id)
+	return response
+}
+
+func (a *admin) ListVolumes(env dockerdriver.Env, listRequest volumedriver.ListVolumesRequest) volumedriver.ListVolumesResponse {
+	env, id := withID(env)
+	response := a.admin.ListVolumes(env, listRequest)
+	response.Err = annotate(response.Err, id)
+	return response
+}
+
+func (a *admin) Clone(env dockerdriver.Env, cloneRequest volumedriver.CloneRequest) dockerdriver.ErrorResponse {
+	env, id := withID(env)
+	response := a.admin.Clone(env, cloneRequest)
+	response.Err = annotate(response.Err, id)
+	return response
+}
+
+func (a *admin) DescribeVolume(env dockerdriver.Env, describeRequest volumedriver.DescribeVolumeRequest) volumedriver.DescribeVolumeResponse {
+	env, id := withID(env)
+	response := a.admin.DescribeVolume(env, describeRequest)
+	response.Err = annotate(response.Err, id)
+	return response
+}
+
+func (a *admin) FreezeVolume(env dockerdriver.Env, freezeRequest volumedriver.FreezeVolumeRequest) dockerdriver.ErrorResponse {
+	env, id := withID(env)
+	response := a.admin.FreezeVolume(env, freezeRequest)
+	response.Err = annotate(response.Err, id)
+	return response
+}
+
+func (a *admin) ThawVolume(env dockerdriver.Env, thawRequest volumedriver.ThawVolumeRequest) dockerdriver.ErrorResponse {
+	env, id := withID(env)
+	response := a.admin.ThawVolume(env, thawRequest)
+	response.Err = annotate(response.Err, id)
+	return response
+}
+
+func (a *admin) ImportMount(env dockerdriver.Env, importRequest volumedriver.ImportMountRequest) dockerdriver.ErrorResponse {
+	env, id := withID(env)
+	response := a.admin.ImportMount(env, importRequest)
+	response.Err = annotate(response.Err, id)
+	return response
+}
+
+func (a *admin) RemountVolume(env dockerdriver.Env, remountRequest volumedriver.RemountVolumeRequest) dockerdriver.ErrorResponse {
+	env, id := withID(env)
+	response := a.admin.RemountVolume(env, remountRequest)
+	response.Err = annotate(response.Err, id)
+	return response
+}
+
+func (a *admin) DiscoverExports(env dockerdriver.Env, discoverRequest volumedriver.DiscoverExportsRequest) volumedriver.DiscoverExportsResponse {
+	env, id := withID(env)
+	response := a.admin.DiscoverExports(env, discoverRequest)
+	response.Err = annotate(response.Err, id)
+	return response
+}
+
+func (a *admin) RevokeReferences(env dockerdriver.Env, revokeRequest volumedriver.RevokeReferencesRequest) volumedriver.RevokeReferencesResponse {
+	env, id := withID(env)
+	response := a.admin.RevokeReferences(env, revokeRequest)
+	response.Err = annotate(response.Err, id)
+	return response
+}
+
+func (a *admin) RemoveVolume(env dockerdriver.Env, removeRequest volumedriver.RemoveVolumeRequest) dockerdriver.ErrorResponse {
+	env, id := withID(env)
+	response := a.admin.RemoveVolume(env, removeRequest)
+	response.Err = annotate(response.Err, id)
+	return response
+}
+
+func (a *admin) InFlightOperations(env dockerdriver.Env) volumedriver.InFlightOperationsResponse {
+	env, id := withID(env)
+	response := a.admin.InFlightOperations(env)
+	response.Err = annotate(response.Err, id)
+	return response
+}
+
+func (a *admin) BindVolume(env dockerdriver.Env, bindRequest volumedriver.BindVolumeRequest) dockerdriver.ErrorResponse {
+	env, id := withID(env)
+	response := a.admin.BindVolume(env, bindRequest)
+	response.Err = annotate(response.Err, id)
+	return response
+}
+
+func (a *admin) UnbindVolume(env dockerdriver.Env, unbindRequest volumedriver.UnbindVolumeRequest) dockerdriver.ErrorResponse {
+	env, id := withID(env)
+	response := a.admin.UnbindVolume(env, unbindRequest)
+	response.Err = annotate(response.Err, id)
+	return response
+}
+
+func (a *admin) ListSnapshots(env dockerdriver.Env) volumedriver.ListSnapshotsResponse {
+	env, id := withID(env)
+	response := a.admin.ListSnapshots(env)
+	response.Err = annotate(response.Err, id)
+	return response
+}
+
+func (a *admin) RestoreSnapshot(env dockerdriver.Env, restoreRequest volumedriver.RestoreSnapshotRequest) dockerdriver.ErrorResponse {
+	env, id := withID(env)
+	response := a.admin.RestoreSnapshot(env, restoreRequest)
+	response.Err = annotate(response.Err, id)
+	return response
+}
+
+func (a *admin) Drain(env dockerdriver.Env) volumedriver.DrainResponse {
+	env, id := withID(env)
+	response := a.admin.Drain(env)
+	response.Err = annotate(response.Err, id)
+	return response
+}
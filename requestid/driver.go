@@ -0,0 +1,76 @@
+package requestid
+
+import (
+	"code.cloudfoundry.org/dockerdriver"
+)
+
+type driver struct {
+	driver dockerdriver.Driver
+}
+
+// NewDriver wraps d so that the log lines and the errors of every request
+// carry its request ID. Serve it behind NewHandler.
+func NewDriver(d dockerdriver.Driver) dockerdriver.Driver {
+	return &driver{driver: d}
+}
+
+func (d *driver) Activate(env dockerdriver.Env) dockerdriver.ActivateResponse {
+	env, id := withID(env)
+	response := d.driver.Activate(env)
+	response.Err = annotate(response.Err, id)
+	return response
+}
+
+func (d *driver) Get(env dockerdriver.Env, getRequest dockerdriver.GetRequest) dockerdriver.GetResponse {
+	env, id := withID(env)
+	response := d.driver.Get(env, getRequest)
+	response.Err = annotate(response.Err, id)
+	return response
+}
+
+func (d *driver) List(env dockerdriver.Env) dockerdriver.ListResponse {
+	env, id := withID(env)
+	response := d.driver.List(env)
+	response.Err = annotate(response.Err, id)
+	return response
+}
+
+func (d *driver) Mount(env dockerdriver.Env, mountRequest dockerdriver.MountRequest) dockerdriver.MountResponse {
+	env, id := withID(env)
+	response := d.driver.Mount(env, mountRequest)
+	response.Err = annotate(response.Err, id)
+	return response
+}
+
+func (d *driver) Path(env dockerdriver.Env, pathRequest dockerdriver.PathRequest) dockerdriver.PathResponse {
+	env, id := withID(env)
+	response := d.driver.Path(env, pathRequest)
+	response.Err = annotate(response.Err, id)
+	return response
+}
+
+func (d *driver) Unmount(env dockerdriver.Env, unmountRequest dockerdriver.UnmountRequest) dockerdriver.ErrorResponse {
+	env, id := withID(env)
+	response := d.driver.Unmount(env, unmountRequest)
+	response.Err = annotate(response.Err, id)
+	return response
+}
+
+func (d *driver) Capabilities(env dockerdriver.Env) dockerdriver.CapabilitiesResponse {
+	env, _ = withID(env)
+	return d.driver.Capabilities(env)
+}
+
+func (d *driver) Create(env dockerdriver.Env, createRequest dockerdriver.CreateRequest) dockerdriver.ErrorResponse {
+	env, id := withID(env)
+	response := d.driver.Create(env, createRequest)
+	response.Err = annotate(response.Err, id)
+	return response
+}
+
+func (d *driver) Remove(env dockerdriver.Env, removeRequest dockerdriver.RemoveRequest) dockerdriver.ErrorResponse {
+	env, id := withID(env)
+	response := d.driver.Remove(env, removeRequest)
+	response.Err = annotate(response.Err, id)
+	return response
+}
@@ -0,0 +1,104 @@
+// Package requestid correlates the requests of a driver with its logs. The
+// handler takes the ID of a request from its header, or generates one, and
+// returns it in the response; the driver and admin wrappers add it to every
+// log line of the request and to the errors reported back, so that a failed
+// container start can be traced to the driver logs.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"regexp"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager"
+)
+
+// Header carries the request ID in requests and responses.
+const Header = "X-Request-Id"
+
+// LogKey is the lager data key of the request ID.
+const LogKey = "request-id"
+
+// validID bounds what callers can inject into the logs.
+var validID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+type idKey struct{}
+
+// WithID returns a context that carries id.
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, idKey{}, id)
+}
+
+// FromContext returns the request ID set by WithID, or the empty string.
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(idKey{}).(string)
+	return id
+}
+
+// NewHandler passes the request ID of every request on to handler in the
+// request context, and returns it in the Header of the response. IDs that
+// are missing or not made of letters, digits and ._:- are replaced by a
+// generated one.
+func NewHandler(logger lager.Logger, handler http.Handler) http.Handler {
+	logger = logger.Session("request-id")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(Header)
+		if !validID.MatchString(id) {
+			if id != "" {
+				logger.Info("invalid-request-id-replaced", lager.Data{"path": req.URL.Path})
+			}
+			id = newID()
+		}
+
+		w.Header().Set(Header, id)
+		logger.Debug("request", lager.Data{LogKey: id, "method": req.Method, "path": req.URL.Path})
+		handler.ServeHTTP(w, req.WithContext(WithID(req.Context(), id)))
+	})
+}
+
+func newID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(id)
+}
+
+// withID returns env with a logger that adds the request ID of its context
+// to every line, and the request ID.
+func withID(env dockerdriver.Env) (dockerdriver.Env, string) {
+	id := FromContext(env.Context())
+	if id == "" {
+		return env, ""
+	}
+	return driverhttp.EnvWithLogger(env.Logger().WithData(lager.Data{LogKey: id}), env), id
+}
+
+// annotate adds the request ID to an error reported back to the caller. Safe
+// errors, which are JSON objects, get it as their RequestID field so that
+// they stay readable for Diego.
+func annotate(err string, id string) string {
+	if err == "" || id == "" {
+		return err
+	}
+
+	var object map[string]interface{}
+	if json.Unmarshal([]byte(err), &object) == nil && object != nil {
+		object["RequestID"] = id
+		if annotated, marshalErr := json.Marshal(object); marshalErr == nil {
+			return string(annotated)
+		}
+		return err
+	}
+
+	return err + " (request id " + id + ")"
+}
@@ -0,0 +1,13 @@
+package requestid_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "RequestID Suite")
+}
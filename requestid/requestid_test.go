@@ -0,0 +1,152 @@
+package requestid_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/requestid"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+// stubDriver logs every mount and fails it with err.
+type stubDriver struct {
+	dockerdriver.Driver
+	err string
+}
+
+func (d *stubDriver) Mount(env dockerdriver.Env, mountRequest dockerdriver.MountRequest) dockerdriver.MountResponse {
+	env.Logger().Info("mounting", nil)
+	return dockerdriver.MountResponse{Err: d.err}
+}
+
+var _ = Describe("RequestID", func() {
+	var logger *lagertest.TestLogger
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("requestid")
+	})
+
+	Describe("NewHandler", func() {
+		var (
+			request  *http.Request
+			recorder *httptest.ResponseRecorder
+			seen     string
+		)
+
+		BeforeEach(func() {
+			request = httptest.NewRequest("POST", "/VolumeDriver.Mount", nil)
+			recorder = httptest.NewRecorder()
+			seen = ""
+		})
+
+		JustBeforeEach(func() {
+			handler := requestid.NewHandler(logger, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				seen = requestid.FromContext(req.Context())
+			}))
+			handler.ServeHTTP(recorder, request)
+		})
+
+		Context("when the request carries an ID", func() {
+			BeforeEach(func() {
+				request.Header.Set(requestid.Header, "diego-1234.abc")
+			})
+
+			It("passes it on and returns it", func() {
+				Expect(seen).To(Equal("diego-1234.abc"))
+				Expect(recorder.Header().Get(requestid.Header)).To(Equal("diego-1234.abc"))
+			})
+		})
+
+		Context("when the request carries no ID", func() {
+			It("generates one", func() {
+				Expect(seen).To(MatchRegexp("^[0-9a-f]{32}$"))
+				Expect(recorder.Header().Get(requestid.Header)).To(Equal(seen))
+			})
+		})
+
+		Context("when the request carries an invalid ID", func() {
+			BeforeEach(func() {
+				request.Header.Set(requestid.Header, "bad id\n")
+			})
+
+			It("replaces it", func() {
+				Expect(seen).To(MatchRegexp("^[0-9a-f]{32}$"))
+				Expect(logger.Buffer()).To(gbytes.Say("invalid-request-id-replaced"))
+			})
+		})
+	})
+
+	Describe("NewDriver", func() {
+		var (
+			stub *stubDriver
+			env  dockerdriver.Env
+		)
+
+		BeforeEach(func() {
+			stub = &stubDriver{}
+			env = driverhttp.NewHttpDriverEnv(logger, requestid.WithID(context.TODO(), "req-1"))
+		})
+
+		It("adds the request ID to the log lines of the request", func() {
+			requestid.NewDriver(stub).Mount(env, dockerdriver.MountRequest{Name: "vol"})
+			Expect(logger.LogMessages()).To(ContainElement("requestid.mounting"))
+			Expect(logger.Logs()[0].Data).To(HaveKeyWithValue(requestid.LogKey, "req-1"))
+		})
+
+		It("adds the request ID to plain errors", func() {
+			stub.err = "mount failed"
+			response := requestid.NewDriver(stub).Mount(env, dockerdriver.MountRequest{Name: "vol"})
+			Expect(response.Err).To(Equal("mount failed (request id req-1)"))
+		})
+
+		It("adds the request ID to safe errors as a field", func() {
+			stub.err = `{"SafeDescription":"mount failed"}`
+			response := requestid.NewDriver(stub).Mount(env, dockerdriver.MountRequest{Name: "vol"})
+			Expect(response.Err).To(MatchJSON(`{"SafeDescription":"mount failed","RequestID":"req-1"}`))
+		})
+
+		It("leaves requests without an ID alone", func() {
+			stub.err = "mount failed"
+			env = driverhttp.NewHttpDriverEnv(logger, context.TODO())
+			response := requestid.NewDriver(stub).Mount(env, dockerdriver.MountRequest{Name: "vol"})
+			Expect(response.Err).To(Equal("mount failed"))
+			Expect(logger.Logs()[0].Data).NotTo(HaveKey(requestid.LogKey))
+		})
+	})
+
+	Describe("NewAdmin", func() {
+		var (
+			fakeAdmin *volumedriverfakes.FakeAdmin
+			env       dockerdriver.Env
+		)
+
+		BeforeEach(func() {
+			fakeAdmin = &volumedriverfakes.FakeAdmin{}
+			env = driverhttp.NewHttpDriverEnv(logger, requestid.WithID(context.TODO(), "req-2"))
+		})
+
+		It("annotates errors and passes the request ID on", func() {
+			fakeAdmin.ResetMountErrorReturns(dockerdriver.ErrorResponse{Err: "no such volume"})
+			response := requestid.NewAdmin(fakeAdmin).ResetMountError(env, volumedriver.ResetMountErrorRequest{Name: "vol"})
+			Expect(response.Err).To(Equal("no such volume (request id req-2)"))
+
+			passed, _ := fakeAdmin.ResetMountErrorArgsForCall(0)
+			passed.Logger().Info("reset", nil)
+			Expect(logger.Logs()[0].Data).To(HaveKeyWithValue(requestid.LogKey, "req-2"))
+		})
+
+		It("annotates drain errors", func() {
+			fakeAdmin.DrainReturns(volumedriver.DrainResponse{Err: "busy"})
+			response := requestid.NewAdmin(fakeAdmin).Drain(env)
+			Expect(response.Err).To(Equal("busy (request id req-2)"))
+		})
+	})
+})
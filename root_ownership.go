@@ -0,0 +1,121 @@
+package volumedriver
+
+import (
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver/rootlock"
+)
+
+// OwnershipMarker is the file that marks a mount path root as the driver's.
+// Drain only purges marked roots, so that a root pointed at a directory of
+// other data by mistake is never force-unmounted and emptied.
+const OwnershipMarker = ".volumedriver-owned"
+
+// claimRoots marks the mount path roots the driver can tell are its own:
+// roots that do not exist yet, are empty, or hold its state directory or
+// legacy state file. The rootlock lock file is not taken into account.
+// Other roots are left unmarked until an operator creates the marker.
+func (d *VolumeDriver) claimRoots(env dockerdriver.Env) {
+	logger := env.Logger().Session("claim-roots")
+
+	for _, root := range d.roots() {
+		root, err := d.filepath.Abs(root)
+		if err != nil {
+			logger.Error("abs-failed", err, lager.Data{"root": root})
+			continue
+		}
+		marker := filepath.Join(root, OwnershipMarker)
+		if _, err := d.os.Stat(marker); err == nil {
+			continue
+		}
+
+		entries, err := d.ioutil.ReadDir(root)
+		if err != nil && !os.IsNotExist(err) {
+			logger.Error("read-mount-path-root-failed", err, lager.Data{"root": root})
+			continue
+		}
+		owned, foreign := false, false
+		for _, entry := range entries {
+			switch entry.Name() {
+			case stateDirName, legacyStateFile:
+				owned = true
+			case rootlock.FileName:
+				// taken before the driver is constructed, says nothing
+				// about who owns the rest of the root
+			default:
+				foreign = true
+			}
+		}
+		if foreign && !owned {
+			logger.Info("mount-path-root-not-owned", lager.Data{"root": root, "marker": marker})
+			continue
+		}
+
+		if err := d.os.MkdirAll(root, os.ModePerm); err != nil {
+			logger.Error("mkdir-rootpath-failed", err, lager.Data{"root": root})
+			continue
+		}
+		if err := d.ioutil.WriteFile(marker, []byte{}, 0644); err != nil {
+			logger.Error("write-ownership-marker-failed", err, lager.Data{"marker": marker})
+			continue
+		}
+		logger.Info("mount-path-root-claimed", lager.Data{"root": root})
+	}
+}
+
+// purgeRoots lets the mounters clean up below the mount path roots that
+// carry the OwnershipMarker. Nothing is purged with Options.SkipPurge.
+func (d *VolumeDriver) purgeRoots(env dockerdriver.Env) {
+	logger := env.Logger().Session("purge-roots")
+
+	if d.options.SkipPurge {
+		logger.Info("purge-skipped")
+		return
+	}
+
+	for _, root := range d.roots() {
+		abs, err := d.filepath.Abs(root)
+		if err != nil {
+			logger.Error("abs-failed", err, lager.Data{"root": root})
+			continue
+		}
+		if _, err := d.os.Stat(filepath.Join(abs, OwnershipMarker)); err != nil {
+			logger.Info("mount-path-root-not-owned", lager.Data{"root": abs})
+			continue
+		}
+		d.purge(env, root)
+	}
+}
+
+// lockRoot takes the rootlock of the mount path root, before the root is
+// claimed or its state restored. The driver cannot run without it.
+func (d *VolumeDriver) lockRoot(env dockerdriver.Env) {
+	logger := env.Logger().Session("lock-root", lager.Data{"root": d.mountPathRoot})
+
+	lock, err := rootlock.Acquire(d.mountPathRoot)
+	if err != nil {
+		logger.Fatal("acquire-failed", err)
+	}
+	d.rootLock = lock
+	logger.Info("acquired")
+}
+
+// unlockRoot releases the rootlock taken by lockRoot, once, so that another
+// driver instance can take over the mount path root after a drain.
+func (d *VolumeDriver) unlockRoot(env dockerdriver.Env) {
+	if d.rootLock == nil {
+		return
+	}
+
+	d.rootUnlockOnce.Do(func() {
+		logger := env.Logger().Session("unlock-root", lager.Data{"root": d.mountPathRoot})
+		if err := d.rootLock.Release(); err != nil {
+			logger.Error("release-failed", err)
+			return
+		}
+		logger.Info("released")
+	})
+}
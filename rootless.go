@@ -0,0 +1,50 @@
+package volumedriver
+
+import "fmt"
+
+// UnprivilegedMounter is implemented by Mounters that mount without the
+// driver running as root, such as fuse clients, a setuid mount helper or a
+// sidecar that mounts for the driver.
+type UnprivilegedMounter interface {
+	Unprivileged() bool
+}
+
+// IsUnprivileged reports whether mounter works for a driver that does not
+// run as root.
+func IsUnprivileged(mounter Mounter) bool {
+	unprivileged, ok := mounter.(UnprivilegedMounter)
+	return ok && unprivileged.Unprivileged()
+}
+
+// checkRootless refuses the opts that need root when the driver runs with
+// Options.Rootless: volumes of mounters that are not unprivileged, and the
+// features that bind, chown, throttle or overlay on the host.
+func (d *VolumeDriver) checkRootless(opts map[string]interface{}) error {
+	if !d.options.Rootless {
+		return nil
+	}
+
+	driver, _ := d.volumeDriver(opts)
+	if !IsUnprivileged(d.mounterFor(driver)) {
+		if driver == "" {
+			driver = "default"
+		}
+		return fmt.Errorf("the %s mounter needs root, which this driver does not run as", driver)
+	}
+
+	if subdir, _ := parseSubdirOpts(opts); subdir.subdir != "" {
+		if !IsUnprivileged(d.options.BindMounter) {
+			return fmt.Errorf("the %s opt needs root, which this driver does not run as", SubdirOpt)
+		}
+		if subdir.uid >= 0 || subdir.gid >= 0 {
+			return fmt.Errorf("the %s and %s opts need root, which this driver does not run as", SubdirUIDOpt, SubdirGIDOpt)
+		}
+	}
+	if ioLimits, _ := parseIOLimits(opts); ioLimits != nil {
+		return fmt.Errorf("io limits need root, which this driver does not run as")
+	}
+	if scratch, _ := parseScratchOpt(opts); scratch && !IsUnprivileged(d.options.OverlayMounter) {
+		return fmt.Errorf("the %s opt needs root, which this driver does not run as", ScratchOpt)
+	}
+	return nil
+}
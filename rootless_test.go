@@ -0,0 +1,96 @@
+package volumedriver_test
+
+import (
+	"context"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/testhelpers"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type unprivilegedMounter struct {
+	volumedriver.Mounter
+}
+
+func (unprivilegedMounter) Unprivileged() bool {
+	return true
+}
+
+var _ = Describe("Rootless", func() {
+	var (
+		env     dockerdriver.Env
+		options volumedriver.Options
+		driver  *testhelpers.MemoryDriver
+	)
+
+	create := func(opts map[string]interface{}) dockerdriver.ErrorResponse {
+		return driver.Create(env, dockerdriver.CreateRequest{Name: "volume", Opts: opts})
+	}
+
+	BeforeEach(func() {
+		env = driverhttp.NewHttpDriverEnv(lagertest.NewTestLogger("rootless"), context.TODO())
+
+		options = volumedriver.DefaultOptions()
+		options.Rootless = true
+		options.Mounters = map[string]volumedriver.Mounter{"fuse": unprivilegedMounter{&volumedriverfakes.FakeMounter{}}}
+	})
+
+	JustBeforeEach(func() {
+		driver = testhelpers.NewMemoryDriverWithOptions(lagertest.NewTestLogger("rootless"), options)
+	})
+
+	It("refuses volumes of mounters that need root", func() {
+		Expect(create(map[string]interface{}{"source": "server:/export"}).Err).To(Equal("the default mounter needs root, which this driver does not run as"))
+	})
+
+	It("creates volumes of unprivileged mounters", func() {
+		Expect(create(map[string]interface{}{"source": "server:/export", "driver": "fuse"}).Err).To(BeEmpty())
+	})
+
+	Context("when the driver binds, throttles and overlays volumes", func() {
+		BeforeEach(func() {
+			options.BindMounter = &volumedriverfakes.FakeMounter{}
+			options.IOThrottler = &volumedriverfakes.FakeIOThrottler{}
+			options.OverlayMounter = &volumedriverfakes.FakeMounter{}
+			options.ScratchDir = "/var/vcap/data/scratch"
+		})
+
+		It("refuses the opts that need root on the host", func() {
+			for opt, value := range map[string]interface{}{
+				volumedriver.SubdirOpt:  "data",
+				volumedriver.ReadBPSOpt: "1048576",
+				volumedriver.ScratchOpt: true,
+			} {
+				Expect(create(map[string]interface{}{"source": "server:/export", "driver": "fuse", opt: value}).Err).To(ContainSubstring("root"), opt)
+			}
+		})
+	})
+
+	Context("when the bind mounter is unprivileged", func() {
+		BeforeEach(func() {
+			options.BindMounter = unprivilegedMounter{&volumedriverfakes.FakeMounter{}}
+		})
+
+		It("allows subdirs but not their owners", func() {
+			Expect(create(map[string]interface{}{"source": "server:/export", "driver": "fuse", "subdir": "data"}).Err).To(BeEmpty())
+			Expect(driver.Remove(env, dockerdriver.RemoveRequest{Name: "volume"}).Err).To(BeEmpty())
+
+			Expect(create(map[string]interface{}{"source": "server:/export", "driver": "fuse", "subdir": "data", "subdir_uid": "1000"}).Err).To(Equal("the subdir_uid and subdir_gid opts need root, which this driver does not run as"))
+		})
+	})
+
+	Context("when the driver is not rootless", func() {
+		BeforeEach(func() {
+			options.Rootless = false
+		})
+
+		It("creates volumes of any mounter", func() {
+			Expect(create(map[string]interface{}{"source": "server:/export"}).Err).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,68 @@
+package rootlock
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileName is the lock file kept in the mount path root. The driver never
+// uses it as a mount directory.
+const FileName = "driver.lock"
+
+var errLocked = errors.New("locked")
+
+// HeldError is returned when another driver instance holds the lock.
+type HeldError struct {
+	Root  string
+	Owner string
+}
+
+func (e HeldError) Error() string {
+	owner := e.Owner
+	if owner == "" {
+		owner = "unknown owner"
+	}
+	return fmt.Sprintf("mount path root '%s' is already in use by another driver instance (%s); two drivers sharing a root corrupt each other's state", e.Root, owner)
+}
+
+// Lock gives a driver instance exclusive ownership of a mount path root. It
+// is backed by an OS file lock, so it is released when the process exits,
+// even if it crashes.
+type Lock struct {
+	file *os.File
+}
+
+// Acquire takes the lock on mountPathRoot, creating the root if needed. It
+// fails with a HeldError instead of waiting when the lock is held.
+func Acquire(mountPathRoot string) (*Lock, error) {
+	if err := os.MkdirAll(mountPathRoot, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(mountPathRoot, FileName)
+	file, err := openLocked(path)
+	if err == errLocked {
+		owner, _ := ioutil.ReadFile(path)
+		return nil, HeldError{Root: mountPathRoot, Owner: strings.TrimSpace(string(owner))}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+	if err := file.Truncate(0); err == nil {
+		file.WriteAt([]byte(fmt.Sprintf("pid %d on %s\n", os.Getpid(), hostname)), 0)
+	}
+
+	return &Lock{file: file}, nil
+}
+
+// Release gives up the lock. The lock file is left in place, removing it
+// would let a waiting instance lock a file that is about to disappear.
+func (l *Lock) Release() error {
+	return l.file.Close()
+}
@@ -0,0 +1,13 @@
+package rootlock_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestRootLock(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "RootLock Suite")
+}
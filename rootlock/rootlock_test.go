@@ -0,0 +1,61 @@
+package rootlock_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/volumedriver/rootlock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RootLock", func() {
+	var (
+		tempDir string
+		root    string
+		lock    *rootlock.Lock
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir("", "rootlock")
+		Expect(err).NotTo(HaveOccurred())
+		root = filepath.Join(tempDir, "mounts")
+
+		lock, err = rootlock.Acquire(root)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		lock.Release()
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	It("records the owner in the lock file", func() {
+		owner, err := ioutil.ReadFile(filepath.Join(root, rootlock.FileName))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(owner)).To(HavePrefix(fmt.Sprintf("pid %d on ", os.Getpid())))
+	})
+
+	It("refuses a second owner", func() {
+		_, err := rootlock.Acquire(root)
+		Expect(err).To(BeAssignableToTypeOf(rootlock.HeldError{}))
+		Expect(err.Error()).To(ContainSubstring(fmt.Sprintf("is already in use by another driver instance (pid %d on ", os.Getpid())))
+	})
+
+	It("can be taken again once released", func() {
+		Expect(lock.Release()).To(Succeed())
+
+		var err error
+		lock, err = rootlock.Acquire(root)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("does not lock other roots", func() {
+		other, err := rootlock.Acquire(filepath.Join(tempDir, "other"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(other.Release()).To(Succeed())
+	})
+})
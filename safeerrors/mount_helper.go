@@ -0,0 +1,143 @@
+package safeerrors
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// helperFailure is a failure that mount helpers report on stderr in words
+// that tell its cause, along with what the operator or app developer can do
+// about it.
+type helperFailure struct {
+	patterns    []string
+	code        Code
+	description string
+	hint        string
+}
+
+// helperFailures are matched in order against the lower cased stderr, so
+// that the specific messages of mount.nfs win over the generic ones of
+// mount(8) that they are often printed with.
+var helperFailures = []helperFailure{
+	{
+		patterns:    []string{"access denied by server"},
+		code:        PermissionDenied,
+		description: "access denied by the server",
+		hint:        "check that the export allows the addresses of the cells",
+	},
+	{
+		patterns:    []string{"program not registered"},
+		code:        Unsupported,
+		description: "the server does not serve the requested nfs version",
+		hint:        "check that nfsd and mountd run on the server and the vers opt",
+	},
+	{
+		patterns:    []string{"protocol not supported", "version or transport protocol is not supported"},
+		code:        Unsupported,
+		description: "the server does not support the requested nfs version or transport",
+		hint:        "check the vers and proto opts",
+	},
+	{
+		patterns:    []string{"timed out"},
+		code:        Unavailable,
+		description: "the server did not respond in time",
+		hint:        "check that the server is up and that firewalls let the cells reach it",
+	},
+	{
+		patterns:    []string{"connection refused"},
+		code:        Unavailable,
+		description: "the server refused the connection",
+		hint:        "check that the server serves nfs on the port of the volume",
+	},
+	{
+		patterns:    []string{"no route to host", "network is unreachable"},
+		code:        Unavailable,
+		description: "the server cannot be reached",
+		hint:        "check the routes and firewalls between the cells and the server",
+	},
+	{
+		patterns:    []string{"failed to resolve server", "name or service not known"},
+		code:        NotFound,
+		description: "the server name cannot be resolved",
+		hint:        "check the host of the source",
+	},
+	{
+		patterns:    []string{"stale file handle"},
+		code:        NotFound,
+		description: "the export was removed or replaced on the server",
+		hint:        "check the export on the server and mount the volume again",
+	},
+	{
+		patterns:    []string{"no such file or directory", "does not exist"},
+		code:        NotFound,
+		description: "the export or the mount point does not exist",
+		hint:        "check the path of the source",
+	},
+	{
+		patterns:    []string{"unknown filesystem type", "wrong fs type"},
+		code:        Unsupported,
+		description: "the cell cannot mount this file system",
+		hint:        "check that its mount helper and kernel module are installed on the cells",
+	},
+	{
+		patterns:    []string{"incorrect mount option", "bad option"},
+		code:        InvalidOption,
+		description: "the mount options were rejected",
+		hint:        "check the opts of the volume",
+	},
+	{
+		patterns:    []string{"unauthorized", "permission denied", "operation not permitted", "only root can"},
+		code:        PermissionDenied,
+		description: "permission denied",
+		hint:        "check the credentials of the volume and that the driver runs as root",
+	},
+	{
+		patterns:    []string{"target is busy", "device is busy", "device or resource busy"},
+		code:        InUse,
+		description: "the mount point is busy",
+		hint:        "stop the processes that use the volume",
+	},
+}
+
+// helperExitStatuses are the exit statuses of mount(8) and umount(8), for
+// failures that stderr does not explain.
+var helperExitStatuses = map[int]string{
+	1:  "the mount helper was called incorrectly or without permissions",
+	2:  "system error on the cell",
+	4:  "internal error of the mount helper",
+	8:  "the mount helper was interrupted",
+	16: "cannot write or lock /etc/mtab",
+	32: "the mount failed",
+	64: "only some mounts succeeded",
+}
+
+// FromMountHelper translates the failure err of a mount(8) or umount(8)
+// call, with the stderr it printed, into an Error that describes the cause
+// and a remedy after the description given by format. The raw stderr,
+// which may name internal addresses, is only kept as the cause for the logs.
+func FromMountHelper(err error, stderr string, format string, args ...interface{}) error {
+	stderr = strings.TrimSpace(stderr)
+	cause := fmt.Errorf("%w: %s", err, stderr)
+	prefix := fmt.Sprintf(format, args...)
+
+	lower := strings.ToLower(stderr)
+	for _, failure := range helperFailures {
+		for _, pattern := range failure.patterns {
+			if strings.Contains(lower, pattern) {
+				return Wrap(cause, failure.code, "%s: %s; %s", prefix, failure.description, failure.hint)
+			}
+		}
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() < 0 {
+		return Wrap(cause, "", "%s: the mount helper did not complete", prefix)
+	}
+	status := exitErr.ExitCode()
+	if description, ok := helperExitStatuses[status]; ok {
+		return Wrap(cause, "", "%s: %s (exit status %d)", prefix, description, status)
+	}
+	return Wrap(cause, "", "%s: exit status %d", prefix, status)
+}
@@ -0,0 +1,73 @@
+package safeerrors_test
+
+import (
+	"errors"
+	"os/exec"
+
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FromMountHelper", func() {
+	var exitErr error
+
+	BeforeEach(func() {
+		exitErr = exec.Command("sh", "-c", "exit 32").Run()
+		Expect(exitErr).To(BeAssignableToTypeOf(&exec.ExitError{}))
+	})
+
+	It("translates common failures into codes with a remedy", func() {
+		failures := []struct {
+			stderr      string
+			code        safeerrors.Code
+			description string
+		}{
+			{"mount.nfs: access denied by server while mounting 10.0.0.1:/data\n", safeerrors.PermissionDenied, "nfs mount failed: access denied by the server; check that the export allows the addresses of the cells"},
+			{"mount.nfs: requested NFS version or transport protocol is not supported\n", safeerrors.Unsupported, "nfs mount failed: the server does not support the requested nfs version or transport; check the vers and proto opts"},
+			{"mount.nfs: rpc.statd is not running but is required for remote locking.\nclnt_create: RPC: Program not registered\n", safeerrors.Unsupported, "nfs mount failed: the server does not serve the requested nfs version; check that nfsd and mountd run on the server and the vers opt"},
+			{"mount.nfs: Connection timed out\n", safeerrors.Unavailable, "nfs mount failed: the server did not respond in time; check that the server is up and that firewalls let the cells reach it"},
+			{"mount.nfs: Connection refused\n", safeerrors.Unavailable, "nfs mount failed: the server refused the connection; check that the server serves nfs on the port of the volume"},
+			{"mount.nfs: Failed to resolve server filer: Name or service not known\n", safeerrors.NotFound, "nfs mount failed: the server name cannot be resolved; check the host of the source"},
+			{"mount.nfs: mounting 10.0.0.1:/data failed, reason given by server: No such file or directory\n", safeerrors.NotFound, "nfs mount failed: the export or the mount point does not exist; check the path of the source"},
+			{"mount: wrong fs type, bad option, bad superblock on 10.0.0.1:/data\n", safeerrors.Unsupported, "nfs mount failed: the cell cannot mount this file system; check that its mount helper and kernel module are installed on the cells"},
+			{"mount.nfs: an incorrect mount option was specified\n", safeerrors.InvalidOption, "nfs mount failed: the mount options were rejected; check the opts of the volume"},
+			{"mount: only root can use \"--options\" option\n", safeerrors.PermissionDenied, "nfs mount failed: permission denied; check the credentials of the volume and that the driver runs as root"},
+		}
+		for _, f := range failures {
+			err := safeerrors.FromMountHelper(exitErr, f.stderr, "%s mount failed", "nfs")
+			Expect(err).To(MatchError(f.description))
+			safe, ok := safeerrors.From(err)
+			Expect(ok).To(BeTrue())
+			Expect(safe.Code).To(Equal(f.code))
+		}
+	})
+
+	It("keeps the raw failure out of the description", func() {
+		err := safeerrors.FromMountHelper(exitErr, "mount.nfs: access denied by server while mounting 10.0.0.1:/data\n", "nfs mount failed")
+		Expect(err.Error()).NotTo(ContainSubstring("10.0.0.1"))
+		Expect(errors.Is(err, exitErr)).To(BeTrue())
+
+		safe, _ := safeerrors.From(err)
+		Expect(safe.Detail()).To(HaveSuffix("exit status 32: mount.nfs: access denied by server while mounting 10.0.0.1:/data"))
+	})
+
+	Context("when stderr does not tell the cause", func() {
+		It("describes the exit status", func() {
+			err := safeerrors.FromMountHelper(exitErr, "mount: something unexpected", "nfs mount failed")
+			Expect(err).To(MatchError("nfs mount failed: the mount failed (exit status 32)"))
+			safe, _ := safeerrors.From(err)
+			Expect(safe.Code).To(BeEmpty())
+		})
+
+		It("reports unknown exit statuses as they are", func() {
+			err := safeerrors.FromMountHelper(exec.Command("sh", "-c", "exit 3").Run(), "", "nfs mount failed")
+			Expect(err).To(MatchError("nfs mount failed: exit status 3"))
+		})
+
+		It("reports helpers that did not exit", func() {
+			err := safeerrors.FromMountHelper(errors.New("context canceled"), "", "nfs mount failed")
+			Expect(err).To(MatchError("nfs mount failed: the mount helper did not complete"))
+		})
+	})
+})
@@ -0,0 +1,105 @@
+// Package safeerrors is the error type every Mounter returns for failures
+// that app developers can act on. The SafeDescription and Code of an Error
+// are reported back to Diego, while the error it wraps is internal detail
+// that is only logged.
+package safeerrors
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+)
+
+// Code classifies an Error, so that clients can react to a failure without
+// parsing its description.
+type Code string
+
+const (
+	// InvalidSource is a source the mounter cannot parse.
+	InvalidSource Code = "invalid-source"
+	// InvalidOption is an opt the mounter does not accept, or a value it
+	// cannot use.
+	InvalidOption Code = "invalid-option"
+	// NotFound is a server, export or directory that does not exist.
+	NotFound Code = "not-found"
+	// AlreadyExists is a directory that is in the way.
+	AlreadyExists Code = "already-exists"
+	// PermissionDenied is a request the server or the host refused.
+	PermissionDenied Code = "permission-denied"
+	// Unsupported is a request the mounter or the host cannot serve.
+	Unsupported Code = "unsupported"
+	// Unavailable is a server that cannot be reached or does not respond.
+	Unavailable Code = "unavailable"
+	// Busy is a driver or server that is overloaded. The request can be
+	// retried once its RetryAfter has passed.
+	Busy Code = "busy"
+
+	// InUse is a volume that is still mounted for containers.
+	InUse Code = "in-use"
+)
+
+// Error marshals to the JSON of a dockerdriver.SafeError, with the Code
+// added, so that Diego shows its SafeDescription to app developers.
+type Error struct {
+	SafeDescription string `json:"SafeDescription"`
+	Code            Code   `json:"Code,omitempty"`
+	// RetryAfter is the number of seconds after which a Busy request can
+	// be retried.
+	RetryAfter int `json:"RetryAfter,omitempty"`
+	cause      error
+}
+
+// New returns an Error with a description that is safe to report.
+func New(code Code, format string, args ...interface{}) error {
+	return Error{Code: code, SafeDescription: fmt.Sprintf(format, args...)}
+}
+
+// NewBusy returns a Busy Error that asks the caller to retry after
+// retryAfter, rounded up to whole seconds and at least one second.
+func NewBusy(retryAfter time.Duration, format string, args ...interface{}) error {
+	seconds := int((retryAfter + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return Error{Code: Busy, SafeDescription: fmt.Sprintf(format, args...), RetryAfter: seconds}
+}
+
+// Wrap returns an Error that reports the safe description and keeps cause
+// for the logs.
+func Wrap(cause error, code Code, format string, args ...interface{}) error {
+	return Error{Code: code, SafeDescription: fmt.Sprintf(format, args...), cause: cause}
+}
+
+func (e Error) Error() string {
+	return e.SafeDescription
+}
+
+func (e Error) Unwrap() error {
+	return e.cause
+}
+
+// Detail returns the description along with the internal cause, for logs.
+func (e Error) Detail() string {
+	if e.cause == nil {
+		return e.SafeDescription
+	}
+	return e.SafeDescription + ": " + e.cause.Error()
+}
+
+// From returns the safe part of err, if err is or wraps an Error or a
+// dockerdriver.SafeError from a mounter that does not use this package.
+func From(err error) (Error, bool) {
+	var safe Error
+	if errors.As(err, &safe) {
+		return safe, true
+	}
+
+	var legacy dockerdriver.SafeError
+	if errors.As(err, &legacy) {
+		return Error{SafeDescription: legacy.SafeDescription}, true
+	}
+
+	return Error{}, false
+}
@@ -0,0 +1,13 @@
+package safeerrors_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestSafeErrors(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "SafeErrors Suite")
+}
@@ -0,0 +1,60 @@
+package safeerrors_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Error", func() {
+	It("marshals like a dockerdriver.SafeError with a code", func() {
+		data, err := json.Marshal(safeerrors.New(safeerrors.NotFound, "export '%s' does not exist", "/data"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(`{"SafeDescription":"export '/data' does not exist","Code":"not-found"}`))
+
+		var safe dockerdriver.SafeError
+		Expect(json.Unmarshal(data, &safe)).To(Succeed())
+		Expect(safe.SafeDescription).To(Equal("export '/data' does not exist"))
+	})
+
+	It("keeps the cause out of the description", func() {
+		cause := errors.New("mount.nfs: access denied by server while mounting 10.0.0.1:/data")
+		err := safeerrors.Wrap(cause, safeerrors.PermissionDenied, "the server refused the mount")
+
+		Expect(err.Error()).To(Equal("the server refused the mount"))
+		Expect(errors.Is(err, cause)).To(BeTrue())
+
+		data, _ := json.Marshal(err)
+		Expect(string(data)).NotTo(ContainSubstring("10.0.0.1"))
+
+		safe, ok := safeerrors.From(err)
+		Expect(ok).To(BeTrue())
+		Expect(safe.Detail()).To(Equal("the server refused the mount: mount.nfs: access denied by server while mounting 10.0.0.1:/data"))
+	})
+})
+
+var _ = Describe("From", func() {
+	It("finds wrapped errors", func() {
+		err := fmt.Errorf("mounting: %w", safeerrors.New(safeerrors.InvalidOption, "Not allowed options: uid"))
+
+		safe, ok := safeerrors.From(err)
+		Expect(ok).To(BeTrue())
+		Expect(safe.Code).To(Equal(safeerrors.InvalidOption))
+	})
+
+	It("converts dockerdriver.SafeError", func() {
+		safe, ok := safeerrors.From(dockerdriver.SafeError{SafeDescription: "safe"})
+		Expect(ok).To(BeTrue())
+		Expect(safe).To(Equal(safeerrors.Error{SafeDescription: "safe"}))
+	})
+
+	It("does not treat other errors as safe", func() {
+		_, ok := safeerrors.From(errors.New("internal"))
+		Expect(ok).To(BeFalse())
+	})
+})
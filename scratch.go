@@ -0,0 +1,148 @@
+package volumedriver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+)
+
+const (
+	// scratchDirName holds the read-only mounts that scratch volumes layer
+	// their overlay on.
+	scratchDirName = "driver-scratch.d"
+
+	scratchUpperDir = "upper"
+	scratchWorkDir  = "work"
+)
+
+func parseScratchOpt(opts map[string]interface{}) (bool, error) {
+	value, ok := opts[ScratchOpt]
+	if !ok {
+		return false, nil
+	}
+
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		switch strings.ToLower(v) {
+		case "true", "":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+
+	return false, fmt.Errorf("invalid scratch '%v', must be true or false", value)
+}
+
+// scratchMounter mounts the volume read-only below the scratch directory of
+// the mount path root and layers an overlay over it at the mountpoint, with
+// the upper and work directories on cell-local disk below
+// Options.ScratchDir. Writes only ever reach the local disk, and are thrown
+// away when the volume is unmounted.
+type scratchMounter struct {
+	driver       *VolumeDriver
+	lowerMounter Mounter
+}
+
+func (m *scratchMounter) Mount(env dockerdriver.Env, source string, target string, opts map[string]interface{}) error {
+	logger := env.Logger().Session("scratch-mount", lager.Data{"source": source, "target": target})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	d := m.driver
+	lower, scratch := m.paths(env, target)
+
+	if err := d.os.MkdirAll(lower, os.ModePerm); err != nil {
+		logger.Error("create-lower-dir-failed", err)
+		return err
+	}
+
+	lowerOpts := map[string]interface{}{}
+	for k, v := range opts {
+		lowerOpts[k] = v
+	}
+	lowerOpts["ro"] = true
+
+	if err := m.lowerMounter.Mount(env, source, lower, lowerOpts); err != nil {
+		logger.Error("mount-lower-failed", err)
+		d.os.Remove(lower)
+		return err
+	}
+
+	// a scratch directory left over from a crash is never reused
+	upper, work := filepath.Join(scratch, scratchUpperDir), filepath.Join(scratch, scratchWorkDir)
+	err := d.os.RemoveAll(scratch)
+	if err == nil {
+		err = d.os.MkdirAll(upper, 0755)
+	}
+	if err == nil {
+		err = d.os.MkdirAll(work, 0755)
+	}
+	if err == nil {
+		err = d.options.OverlayMounter.Mount(env, lower, target, map[string]interface{}{"upperdir": upper, "workdir": work})
+	}
+	if err != nil {
+		logger.Error("mount-overlay-failed", err)
+		m.release(env, lower, scratch)
+		return err
+	}
+
+	return nil
+}
+
+func (m *scratchMounter) Unmount(env dockerdriver.Env, target string) error {
+	if err := m.driver.options.OverlayMounter.Unmount(env, target); err != nil {
+		return err
+	}
+
+	lower, scratch := m.paths(env, target)
+	return m.release(env, lower, scratch)
+}
+
+// Check probes the overlay, and the lower mount below it at most for reads
+// as it is read-only.
+func (m *scratchMounter) Check(env dockerdriver.Env, name, mountPoint string, depth CheckDepth) bool {
+	lower, _ := m.paths(env, mountPoint)
+
+	lowerDepth := depth
+	if lowerDepth == CheckWrite {
+		lowerDepth = CheckRead
+	}
+
+	return m.driver.options.OverlayMounter.Check(env, name, mountPoint, depth) &&
+		m.lowerMounter.Check(env, name, lower, lowerDepth)
+}
+
+func (m *scratchMounter) Purge(env dockerdriver.Env, path string) {
+	m.driver.options.OverlayMounter.Purge(env, path)
+}
+
+func (m *scratchMounter) paths(env dockerdriver.Env, target string) (string, string) {
+	name := filepath.Base(target)
+	return filepath.Join(m.driver.mountPath(env, scratchDirName), name), filepath.Join(m.driver.options.ScratchDir, name)
+}
+
+// release unmounts the lower mount and removes the scratch data.
+func (m *scratchMounter) release(env dockerdriver.Env, lower, scratch string) error {
+	logger := env.Logger().Session("release-scratch", lager.Data{"lower": lower, "scratch": scratch})
+
+	d := m.driver
+	if err := m.lowerMounter.Unmount(env, lower); err != nil {
+		logger.Error("unmount-lower-failed", err)
+		return err
+	}
+	if err := d.os.Remove(lower); err != nil {
+		logger.Error("remove-lower-dir-failed", err)
+	}
+	if err := d.os.RemoveAll(scratch); err != nil {
+		logger.Error("remove-scratch-dir-failed", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package volumedriver
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+)
+
+// SecretScheme prefixes opt values that reference a secret, as in
+// vault://secret/data/filer#password.
+const SecretScheme = "vault://"
+
+//go:generate counterfeiter -o volumedriverfakes/fake_secret_resolver.go . SecretResolver
+type SecretResolver interface {
+	// Resolve returns the value of key in the secret at path.
+	Resolve(env dockerdriver.Env, path string, key string) (string, error)
+}
+
+// SecretRef is an opt value of the form vault://path#key.
+type SecretRef struct {
+	Path string
+	Key  string
+}
+
+// ParseSecretRef returns false for values that do not reference a secret.
+func ParseSecretRef(value interface{}) (SecretRef, bool, error) {
+	s, ok := value.(string)
+	if !ok || !strings.HasPrefix(s, SecretScheme) {
+		return SecretRef{}, false, nil
+	}
+
+	ref := strings.TrimPrefix(s, SecretScheme)
+	hash := strings.LastIndex(ref, "#")
+	if hash < 0 {
+		return SecretRef{}, true, fmt.Errorf("invalid secret reference '%s', must be of the form %spath#key", s, SecretScheme)
+	}
+
+	path, key := strings.Trim(ref[:hash], "/"), ref[hash+1:]
+	if path == "" || key == "" {
+		return SecretRef{}, true, fmt.Errorf("invalid secret reference '%s', must be of the form %spath#key", s, SecretScheme)
+	}
+	return SecretRef{Path: path, Key: key}, true, nil
+}
+
+// validateSecretRefs checks the secret references in the opts of a volume
+// when it is created, so that a malformed reference does not first fail
+// the mount. The source cannot reference a secret.
+func (d *VolumeDriver) validateSecretRefs(opts map[string]interface{}) error {
+	for _, k := range sortedKeys(opts) {
+		_, isRef, err := ParseSecretRef(opts[k])
+		if !isRef {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if k == "source" {
+			return errors.New("the source cannot reference a secret")
+		}
+		if d.options.SecretResolver == nil {
+			return fmt.Errorf("the %s opt references a secret, which is not supported by this driver", k)
+		}
+	}
+	return nil
+}
+
+// resolveSecrets returns a copy of the mounter opts with their secret
+// references replaced by the secrets, and the secrets it resolved. The
+// copy is only handed to the mounter; the opts of the volume keep the
+// references, so that secrets never reach the state.
+func (d *VolumeDriver) resolveSecrets(env dockerdriver.Env, opts map[string]interface{}) (map[string]interface{}, []string, error) {
+	logger := env.Logger().Session("resolve-secrets")
+
+	resolved := map[string]interface{}{}
+	secrets := []string{}
+	for _, k := range sortedKeys(opts) {
+		resolved[k] = opts[k]
+
+		ref, isRef, err := ParseSecretRef(opts[k])
+		if !isRef || k == "source" {
+			continue
+		}
+		if err != nil {
+			return nil, nil, safeerrors.New(safeerrors.InvalidOption, "%s", err.Error())
+		}
+		if d.options.SecretResolver == nil {
+			return nil, nil, safeerrors.New(safeerrors.Unsupported, "the %s opt references a secret, which is not supported by this driver", k)
+		}
+
+		secret, err := d.options.SecretResolver.Resolve(env, ref.Path, ref.Key)
+		if err != nil {
+			logger.Error("resolve-failed", err, lager.Data{"opt": k, "path": ref.Path, "key": ref.Key})
+			return nil, nil, safeerrors.Wrap(err, safeerrors.PermissionDenied, "unable to resolve the secret of the %s opt", k)
+		}
+		logger.Info("resolved", lager.Data{"opt": k, "path": ref.Path, "key": ref.Key})
+
+		resolved[k] = secret
+		if secret != "" {
+			secrets = append(secrets, secret)
+		}
+	}
+
+	return resolved, secrets, nil
+}
+
+// redactSecrets replaces the secrets in err, for mounters that report the
+// options they were called with. The internal cause of a safe error is
+// dropped when it contains a secret.
+func redactSecrets(err error, secrets []string) error {
+	if err == nil || len(secrets) == 0 {
+		return err
+	}
+
+	detail := err.Error()
+	if safe, ok := safeerrors.From(err); ok {
+		detail = safe.Detail()
+	}
+	if !containsAny(detail, secrets) {
+		return err
+	}
+
+	redact := func(s string) string {
+		for _, secret := range secrets {
+			s = strings.Replace(s, secret, redacted, -1)
+		}
+		return s
+	}
+
+	if safe, ok := safeerrors.From(err); ok {
+		return safeerrors.New(safe.Code, "%s", redact(safe.SafeDescription))
+	}
+	return errors.New(redact(err.Error()))
+}
+
+func containsAny(s string, values []string) bool {
+	for _, value := range values {
+		if strings.Contains(s, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(opts map[string]interface{}) []string {
+	keys := make([]string, 0, len(opts))
+	for k := range opts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,109 @@
+package volumedriver
+
+import (
+	"fmt"
+	"regexp"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+)
+
+// SELinux mount opts. They are passed on to the Mounter and label every file
+// of the mount, or with SeclabelOpt the labels of the server are used. Hosts
+// that enforce SELinux deny containers the unlabeled files of nfs mounts.
+const (
+	ContextOpt     = "context"
+	FSContextOpt   = "fscontext"
+	DefContextOpt  = "defcontext"
+	RootContextOpt = "rootcontext"
+	SeclabelOpt    = "seclabel"
+)
+
+// RelabelOpt labels the mountpoint of the volume with its value through
+// Options.Labeler once it is mounted, for exports that keep labels, such as
+// nfs 4.2 with seclabel.
+const RelabelOpt = "relabel"
+
+var contextOpts = []string{ContextOpt, FSContextOpt, DefContextOpt, RootContextOpt, RelabelOpt}
+
+// validContext matches SELinux contexts, user:role:type with an optional MLS
+// range such as s0:c1,c2.
+var validContext = regexp.MustCompile(`^[A-Za-z0-9_.]+:[A-Za-z0-9_.]+:[A-Za-z0-9_.]+(:[A-Za-z0-9_.:,-]+)?$`)
+
+//go:generate counterfeiter -o volumedriverfakes/fake_labeler.go . Labeler
+type Labeler interface {
+	// Relabel sets the SELinux label of path, like chcon(1).
+	Relabel(env dockerdriver.Env, path string, label string) error
+}
+
+// validateSELinuxOpts checks the contexts of the SELinux opts, and refuses
+// the combinations the kernel refuses: context with fscontext or defcontext,
+// and any context with seclabel.
+func validateSELinuxOpts(opts map[string]interface{}) error {
+	for _, opt := range contextOpts {
+		value, ok := opts[opt]
+		if !ok {
+			continue
+		}
+		if context, _ := value.(string); !validContext.MatchString(context) {
+			return fmt.Errorf("invalid %s '%v', must be an SELinux context such as system_u:object_r:container_file_t:s0", opt, value)
+		}
+	}
+
+	if _, ok := opts[ContextOpt]; ok {
+		for _, opt := range []string{FSContextOpt, DefContextOpt} {
+			if _, ok := opts[opt]; ok {
+				return fmt.Errorf("the %s and %s opts cannot be combined", ContextOpt, opt)
+			}
+		}
+	}
+	if value, ok := opts[SeclabelOpt]; ok {
+		seclabel, err := parseBoolOpt(SeclabelOpt, value)
+		if err != nil {
+			return err
+		}
+		if !seclabel {
+			return nil
+		}
+		for _, opt := range []string{ContextOpt, FSContextOpt, DefContextOpt, RootContextOpt} {
+			if _, ok := opts[opt]; ok {
+				return fmt.Errorf("the %s and %s opts cannot be combined", SeclabelOpt, opt)
+			}
+		}
+	}
+	return nil
+}
+
+// relabelOpt returns the label of the relabel opt of opts, which have
+// already been validated.
+func relabelOpt(opts map[string]interface{}) string {
+	label, _ := opts[RelabelOpt].(string)
+	return label
+}
+
+// labeledMounter labels the mountpoint once the volume is mounted. A volume
+// that cannot be labeled is unmounted again, since containers would be
+// denied access to it anyway.
+type labeledMounter struct {
+	Mounter
+	labeler Labeler
+	label   string
+}
+
+func (m *labeledMounter) Mount(env dockerdriver.Env, source string, target string, opts map[string]interface{}) error {
+	logger := env.Logger().Session("labeled-mount", lager.Data{"target": target, "label": m.label})
+
+	if err := m.Mounter.Mount(env, source, target, opts); err != nil {
+		return err
+	}
+
+	if err := m.labeler.Relabel(env, target, m.label); err != nil {
+		logger.Error("relabel-failed", err)
+		if unmountErr := m.Mounter.Unmount(env, target); unmountErr != nil {
+			logger.Error("unmount-failed", unmountErr)
+		}
+		return fmt.Errorf("relabel failed, use the %s opt for exports without SELinux labels: %s", ContextOpt, err)
+	}
+
+	return nil
+}
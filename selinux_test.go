@@ -0,0 +1,97 @@
+package volumedriver_test
+
+import (
+	"context"
+	"errors"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/testhelpers"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SELinux labels", func() {
+	const label = "system_u:object_r:container_file_t:s0:c1,c2"
+
+	var (
+		env         dockerdriver.Env
+		fakeLabeler *volumedriverfakes.FakeLabeler
+		options     volumedriver.Options
+		driver      *testhelpers.MemoryDriver
+	)
+
+	create := func(opts map[string]interface{}) dockerdriver.ErrorResponse {
+		opts["source"] = "server:/export"
+		return driver.Create(env, dockerdriver.CreateRequest{Name: "volume", Opts: opts})
+	}
+
+	BeforeEach(func() {
+		logger := lagertest.NewTestLogger("selinux")
+		env = driverhttp.NewHttpDriverEnv(logger, context.TODO())
+		fakeLabeler = &volumedriverfakes.FakeLabeler{}
+
+		options = volumedriver.DefaultOptions()
+		options.Labeler = fakeLabeler
+		driver = testhelpers.NewMemoryDriverWithOptions(logger, options)
+	})
+
+	It("passes the context opts on to the mounter", func() {
+		Expect(create(map[string]interface{}{"context": label}).Err).To(BeEmpty())
+		Expect(driver.Mount(env, dockerdriver.MountRequest{Name: "volume"}).Err).To(BeEmpty())
+
+		Expect(driver.Mounter.MountCalls()[0].Opts).To(HaveKeyWithValue("context", label))
+		Expect(fakeLabeler.RelabelCallCount()).To(BeZero())
+	})
+
+	It("refuses invalid contexts and combinations the kernel refuses", func() {
+		Expect(create(map[string]interface{}{"context": "container_file_t"}).Err).To(HavePrefix("invalid context 'container_file_t'"))
+		Expect(create(map[string]interface{}{"context": label, "fscontext": label}).Err).To(Equal("the context and fscontext opts cannot be combined"))
+		Expect(create(map[string]interface{}{"seclabel": true, "rootcontext": label}).Err).To(Equal("the seclabel and rootcontext opts cannot be combined"))
+		Expect(create(map[string]interface{}{"seclabel": "yes"}).Err).To(Equal("invalid seclabel 'yes', must be true or false"))
+	})
+
+	Describe("the relabel opt", func() {
+		BeforeEach(func() {
+			Expect(create(map[string]interface{}{"relabel": label, "seclabel": true}).Err).To(BeEmpty())
+		})
+
+		It("labels the mountpoint after mounting", func() {
+			response := driver.Mount(env, dockerdriver.MountRequest{Name: "volume"})
+			Expect(response.Err).To(BeEmpty())
+
+			Expect(fakeLabeler.RelabelCallCount()).To(Equal(1))
+			_, path, relabel := fakeLabeler.RelabelArgsForCall(0)
+			Expect(path).To(Equal(response.Mountpoint))
+			Expect(relabel).To(Equal(label))
+			Expect(driver.Mounter.MountCalls()[0].Opts).NotTo(HaveKey("relabel"))
+		})
+
+		It("unmounts volumes that cannot be labeled", func() {
+			fakeLabeler.RelabelReturns(errors.New("chcon failed: Operation not supported"))
+
+			response := driver.Mount(env, dockerdriver.MountRequest{Name: "volume"})
+			Expect(response.Err).To(Equal("relabel failed, use the context opt for exports without SELinux labels: chcon failed: Operation not supported"))
+			Expect(driver.Mounter.Mounts()).To(BeEmpty())
+		})
+
+		It("drops the label when the volume is created again without the opt", func() {
+			Expect(create(map[string]interface{}{"seclabel": true}).Err).To(BeEmpty())
+			Expect(driver.Mount(env, dockerdriver.MountRequest{Name: "volume"}).Err).To(BeEmpty())
+			Expect(fakeLabeler.RelabelCallCount()).To(BeZero())
+		})
+
+		Context("when the driver has no labeler", func() {
+			BeforeEach(func() {
+				driver = testhelpers.NewMemoryDriverWithOptions(lagertest.NewTestLogger("selinux"), volumedriver.DefaultOptions())
+			})
+
+			It("is refused", func() {
+				Expect(create(map[string]interface{}{"relabel": label}).Err).To(Equal("the relabel opt is not supported by this driver"))
+			})
+		})
+	})
+})
@@ -0,0 +1,37 @@
+package selinuxlabeler
+
+import (
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/invoker"
+)
+
+type chconLabeler struct {
+	invoker invoker.Invoker
+}
+
+// NewChconLabeler returns a Labeler that runs chcon(1). Only the mountpoint
+// itself is labeled, never the tree below it, which would walk the whole
+// export. It fails for exports that do not keep SELinux labels, such as nfs
+// mounts without seclabel; mount those with the context opt instead.
+func NewChconLabeler(invoker invoker.Invoker) volumedriver.Labeler {
+	return &chconLabeler{invoker: invoker}
+}
+
+func (l *chconLabeler) Relabel(env dockerdriver.Env, path string, label string) error {
+	logger := env.Logger().Session("chcon-relabel", lager.Data{"path": path, "label": label})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	result := l.invoker.Invoke(env, "chcon", []string{label, path})
+	if err := result.Wait(); err != nil {
+		logger.Error("relabel-failed", err, lager.Data{"stderr": result.StdError()})
+		return fmt.Errorf("chcon failed: %s", strings.TrimSpace(result.StdError()))
+	}
+
+	return nil
+}
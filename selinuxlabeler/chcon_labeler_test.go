@@ -0,0 +1,50 @@
+package selinuxlabeler_test
+
+import (
+	"context"
+	"errors"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/invokerfakes"
+	"code.cloudfoundry.org/volumedriver/selinuxlabeler"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ChconLabeler", func() {
+	var (
+		env              dockerdriver.Env
+		fakeInvoker      *invokerfakes.FakeInvoker
+		fakeInvokeResult *invokerfakes.FakeInvokeResult
+		subject          volumedriver.Labeler
+	)
+
+	BeforeEach(func() {
+		env = driverhttp.NewHttpDriverEnv(lagertest.NewTestLogger("chcon-labeler"), context.TODO())
+		fakeInvoker = &invokerfakes.FakeInvoker{}
+		fakeInvokeResult = &invokerfakes.FakeInvokeResult{}
+		fakeInvoker.InvokeReturns(fakeInvokeResult)
+		subject = selinuxlabeler.NewChconLabeler(fakeInvoker)
+	})
+
+	It("labels the mountpoint", func() {
+		Expect(subject.Relabel(env, "/mnt/volume", "system_u:object_r:container_file_t:s0")).To(Succeed())
+		_, executable, args, _ := fakeInvoker.InvokeArgsForCall(0)
+		Expect(executable).To(Equal("chcon"))
+		Expect(args).To(Equal([]string{"system_u:object_r:container_file_t:s0", "/mnt/volume"}))
+	})
+
+	Context("when chcon fails", func() {
+		BeforeEach(func() {
+			fakeInvokeResult.WaitReturns(errors.New("exit status 1"))
+			fakeInvokeResult.StdErrorReturns("chcon: failed to change context of '/mnt/volume': Operation not supported\n")
+		})
+
+		It("returns its error", func() {
+			Expect(subject.Relabel(env, "/mnt/volume", "system_u:object_r:container_file_t:s0")).To(MatchError("chcon failed: chcon: failed to change context of '/mnt/volume': Operation not supported"))
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package selinuxlabeler_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestSELinuxLabeler(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "SELinuxLabeler Suite")
+}
@@ -0,0 +1,200 @@
+package volumedriver
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+)
+
+// ErrExportReleasing is returned by RefStore.Acquire while the export is
+// being released, until RefStore.Forget is called for it.
+var ErrExportReleasing = errors.New("export is being released")
+
+// forgetAttempts bounds how often the end of a release is tried, the first
+// retry after forgetRetryInterval and every further one after twice as long.
+// A fence that is never forgotten is only taken over once it is stale, see
+// RefStore.
+const (
+	forgetAttempts      = 3
+	forgetRetryInterval = 250 * time.Millisecond
+)
+
+//go:generate counterfeiter -o volumedriverfakes/fake_ref_store.go . RefStore
+type RefStore interface {
+	// Acquire records that holder references export. Acquiring a reference
+	// that is already held succeeds. It fails with ErrExportReleasing while
+	// the export is being released.
+	Acquire(env dockerdriver.Env, export string, holder string) error
+	// Release drops the reference of holder to export. It returns true when
+	// no holder is left, in which case the export is fenced off, and Acquire
+	// fails, until holder calls Forget for it. A fence that is never
+	// forgotten, because the cell of holder went away during the release,
+	// goes stale after a while and is taken over by the next Acquire.
+	Release(env dockerdriver.Env, export string, holder string) (released bool, err error)
+	// Forget ends the release of export by holder. It leaves a fence that
+	// was taken over and set again by another holder alone.
+	Forget(env dockerdriver.Env, export string, holder string) error
+}
+
+//go:generate counterfeiter -o volumedriverfakes/fake_export_releaser.go . ExportReleaser
+type ExportReleaser interface {
+	// ExportReleased is called once no cell references export anymore, for
+	// example to clean up or revoke it on the server. No cell mounts the
+	// export until it returns.
+	ExportReleased(env dockerdriver.Env, export string) error
+}
+
+// refHolder names the reference of a volume mounted on this cell.
+func (d *VolumeDriver) refHolder(volumeName string) string {
+	return d.cellID + "/" + volumeName
+}
+
+// acquireExport references the export of a volume before it is mounted.
+// Test mounts hold no reference.
+func (d *VolumeDriver) acquireExport(env dockerdriver.Env, volumeName string, export string) error {
+	if d.options.RefStore == nil || strings.HasPrefix(volumeName, testMountPrefix) {
+		return nil
+	}
+	logger := env.Logger().Session("acquire-export", lager.Data{"export": export, "holder": d.refHolder(volumeName)})
+
+	// a release of the previous mount that is still running would drop
+	// the reference again
+	d.awaitRelease(volumeName)
+
+	err := d.options.RefStore.Acquire(env, export, d.refHolder(volumeName))
+	if err == ErrExportReleasing {
+		logger.Info("export-releasing")
+		return safeerrors.NewBusy(d.busyRetryAfter(), "Export '%s' is being released, try again later", export)
+	}
+	if err != nil {
+		logger.Error("acquire-failed", err)
+		return safeerrors.Wrap(err, safeerrors.Unavailable, "Failed to reference export '%s' in the shared state", export)
+	}
+	return nil
+}
+
+// releaseExport drops the reference of a volume once it is unmounted, and
+// runs the ExportReleaser when it was the last reference of any cell. The
+// release runs in the background, so that callers holding volumesLock do not
+// wait for the shared state or the hook; releases of the same volume run in
+// order, and Drain waits for them. Failures are logged, the volume is
+// unmounted all the same; a reference that is left behind only keeps the
+// export from being released.
+func (d *VolumeDriver) releaseExport(env dockerdriver.Env, volumeName string, export string) {
+	if d.options.RefStore == nil || strings.HasPrefix(volumeName, testMountPrefix) {
+		return
+	}
+
+	d.releasesLock.Lock()
+	previous := d.releases[volumeName]
+	done := make(chan struct{})
+	d.releases[volumeName] = done
+	d.releasesLock.Unlock()
+
+	// the request that unmounted the volume may be over before the release
+	releaseEnv := driverhttp.NewHttpDriverEnv(env.Logger(), context.Background())
+	go func() {
+		defer func() {
+			d.releasesLock.Lock()
+			defer d.releasesLock.Unlock()
+			if d.releases[volumeName] == done {
+				delete(d.releases, volumeName)
+			}
+			close(done)
+		}()
+
+		if previous != nil {
+			<-previous
+		}
+		d.release(releaseEnv, volumeName, export)
+	}()
+}
+
+func (d *VolumeDriver) release(env dockerdriver.Env, volumeName string, export string) {
+	logger := env.Logger().Session("release-export", lager.Data{"export": export, "holder": d.refHolder(volumeName)})
+
+	// volumes restored without their opts have no known export
+	if export == "" {
+		logger.Info("export-unknown")
+		return
+	}
+
+	released, err := d.options.RefStore.Release(env, export, d.refHolder(volumeName))
+	if err != nil {
+		logger.Error("release-failed", err)
+		return
+	}
+	if !released {
+		return
+	}
+
+	logger.Info("export-released")
+	if d.options.ExportReleaser != nil {
+		if err := d.options.ExportReleaser.ExportReleased(env, export); err != nil {
+			logger.Error("export-released-hook-failed", err)
+		}
+	}
+	d.forgetExport(env, export, d.refHolder(volumeName))
+}
+
+// forgetExport ends the release of export, trying again when the shared
+// state cannot be reached, so that a transient failure does not keep every
+// cell from mounting the export until the fence goes stale.
+func (d *VolumeDriver) forgetExport(env dockerdriver.Env, export string, holder string) {
+	logger := env.Logger().Session("forget-export", lager.Data{"export": export, "holder": holder})
+
+	interval := forgetRetryInterval
+	for attempt := 1; ; attempt++ {
+		err := d.options.RefStore.Forget(env, export, holder)
+		if err == nil {
+			return
+		}
+		if attempt == forgetAttempts {
+			logger.Error("forget-failed", err, lager.Data{"attempts": attempt})
+			return
+		}
+		logger.Info("forget-failed-retrying", lager.Data{"attempt": attempt, "err": err.Error()})
+		time.Sleep(interval)
+		interval *= 2
+	}
+}
+
+// awaitRelease waits for the releases of a volume that are still running.
+func (d *VolumeDriver) awaitRelease(volumeName string) {
+	d.releasesLock.Lock()
+	pending := d.releases[volumeName]
+	d.releasesLock.Unlock()
+
+	if pending != nil {
+		<-pending
+	}
+}
+
+// awaitReleases waits for the releases of every volume that are running.
+func (d *VolumeDriver) awaitReleases() {
+	d.releasesLock.Lock()
+	pending := []chan struct{}{}
+	for _, done := range d.releases {
+		pending = append(pending, done)
+	}
+	d.releasesLock.Unlock()
+
+	for _, done := range pending {
+		<-done
+	}
+}
+
+// scope is the scope reported by Capabilities: global when the references
+// are shared between cells.
+func (d *VolumeDriver) scope() string {
+	if d.options.RefStore != nil {
+		return "global"
+	}
+	return "local"
+}
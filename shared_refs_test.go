@@ -0,0 +1,241 @@
+package volumedriver_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+	"code.cloudfoundry.org/volumedriver/testhelpers"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Shared references", func() {
+	var (
+		env      dockerdriver.Env
+		options  volumedriver.Options
+		driver   *testhelpers.MemoryDriver
+		store    *volumedriverfakes.FakeRefStore
+		releaser *volumedriverfakes.FakeExportReleaser
+	)
+
+	safeError := func(err string) safeerrors.Error {
+		var safe safeerrors.Error
+		Expect(json.Unmarshal([]byte(err), &safe)).To(Succeed())
+		return safe
+	}
+
+	BeforeEach(func() {
+		store = &volumedriverfakes.FakeRefStore{}
+		releaser = &volumedriverfakes.FakeExportReleaser{}
+
+		options = volumedriver.DefaultOptions()
+		options.RefStore = store
+		options.CellID = "cell-a"
+		options.ExportReleaser = releaser
+	})
+
+	JustBeforeEach(func() {
+		logger := lagertest.NewTestLogger("shared-refs")
+		env = driverhttp.NewHttpDriverEnv(logger, context.TODO())
+		driver = testhelpers.NewMemoryDriverWithOptions(logger, options)
+
+		Expect(driver.Create(env, dockerdriver.CreateRequest{Name: "vol", Opts: map[string]interface{}{"source": "server:/export"}}).Err).To(BeEmpty())
+	})
+
+	It("reports the global scope", func() {
+		Expect(driver.Capabilities(env).Capabilities.Scope).To(Equal("global"))
+	})
+
+	It("references the export while the volume is mounted on the cell", func() {
+		Expect(driver.Mount(env, dockerdriver.MountRequest{Name: "vol"}).Err).To(BeEmpty())
+		Expect(driver.Mount(env, dockerdriver.MountRequest{Name: "vol"}).Err).To(BeEmpty())
+
+		Expect(store.AcquireCallCount()).To(Equal(1))
+		_, export, holder := store.AcquireArgsForCall(0)
+		Expect(export).To(Equal("server:/export"))
+		Expect(holder).To(Equal("cell-a/vol"))
+
+		Expect(driver.Unmount(env, dockerdriver.UnmountRequest{Name: "vol"}).Err).To(BeEmpty())
+		Expect(store.ReleaseCallCount()).To(Equal(0))
+
+		Expect(driver.Unmount(env, dockerdriver.UnmountRequest{Name: "vol"}).Err).To(BeEmpty())
+		Eventually(store.ReleaseCallCount).Should(Equal(1))
+		_, export, holder = store.ReleaseArgsForCall(0)
+		Expect(export).To(Equal("server:/export"))
+		Expect(holder).To(Equal("cell-a/vol"))
+	})
+
+	It("leaves the export alone while other cells reference it", func() {
+		Expect(driver.Mount(env, dockerdriver.MountRequest{Name: "vol"}).Err).To(BeEmpty())
+		Expect(driver.Unmount(env, dockerdriver.UnmountRequest{Name: "vol"}).Err).To(BeEmpty())
+
+		Expect(releaser.ExportReleasedCallCount()).To(Equal(0))
+		Expect(store.ForgetCallCount()).To(Equal(0))
+	})
+
+	Context("when the cell held the last reference", func() {
+		BeforeEach(func() {
+			store.ReleaseReturns(true, nil)
+		})
+
+		It("runs the release hook before the export can be referenced again", func() {
+			Expect(driver.Mount(env, dockerdriver.MountRequest{Name: "vol"}).Err).To(BeEmpty())
+			Expect(driver.Unmount(env, dockerdriver.UnmountRequest{Name: "vol"}).Err).To(BeEmpty())
+
+			Eventually(store.ForgetCallCount).Should(Equal(1))
+			Expect(releaser.ExportReleasedCallCount()).To(Equal(1))
+			_, export := releaser.ExportReleasedArgsForCall(0)
+			Expect(export).To(Equal("server:/export"))
+
+			_, export, holder := store.ForgetArgsForCall(0)
+			Expect(export).To(Equal("server:/export"))
+			Expect(holder).To(Equal("cell-a/vol"))
+		})
+
+		It("tries again to end the release when the shared state cannot be reached", func() {
+			store.ForgetReturnsOnCall(0, errors.New("connection refused"))
+
+			Expect(driver.Mount(env, dockerdriver.MountRequest{Name: "vol"}).Err).To(BeEmpty())
+			Expect(driver.Unmount(env, dockerdriver.UnmountRequest{Name: "vol"}).Err).To(BeEmpty())
+
+			Eventually(store.ForgetCallCount).Should(Equal(2))
+			Consistently(store.ForgetCallCount).Should(Equal(2))
+		})
+
+		It("ends the release even when the hook fails", func() {
+			releaser.ExportReleasedReturns(errors.New("cleanup failed"))
+
+			Expect(driver.Mount(env, dockerdriver.MountRequest{Name: "vol"}).Err).To(BeEmpty())
+			Expect(driver.Unmount(env, dockerdriver.UnmountRequest{Name: "vol"}).Err).To(BeEmpty())
+
+			Eventually(store.ForgetCallCount).Should(Equal(1))
+		})
+
+		It("releases the export when the volume is removed with force", func() {
+			Expect(driver.Mount(env, dockerdriver.MountRequest{Name: "vol"}).Err).To(BeEmpty())
+			Expect(driver.RemoveVolume(env, volumedriver.RemoveVolumeRequest{Name: "vol", Force: true}).Err).To(BeEmpty())
+
+			Eventually(releaser.ExportReleasedCallCount).Should(Equal(1))
+			Expect(store.ReleaseCallCount()).To(Equal(1))
+		})
+
+		It("releases the export when the cell is drained", func() {
+			Expect(driver.Mount(env, dockerdriver.MountRequest{Name: "vol"}).Err).To(BeEmpty())
+			driver.Drain(env)
+
+			Expect(store.ReleaseCallCount()).To(Equal(1))
+			Expect(releaser.ExportReleasedCallCount()).To(Equal(1))
+		})
+	})
+
+	Context("while the export is released", func() {
+		var hookDone chan struct{}
+
+		BeforeEach(func() {
+			done := make(chan struct{})
+			hookDone = done
+			store.ReleaseReturns(true, nil)
+			releaser.ExportReleasedStub = func(dockerdriver.Env, string) error {
+				<-done
+				return nil
+			}
+		})
+
+		AfterEach(func() {
+			close(hookDone)
+		})
+
+		It("serves the requests of other volumes", func() {
+			Expect(driver.Mount(env, dockerdriver.MountRequest{Name: "vol"}).Err).To(BeEmpty())
+			Expect(driver.Unmount(env, dockerdriver.UnmountRequest{Name: "vol"}).Err).To(BeEmpty())
+			Eventually(releaser.ExportReleasedCallCount).Should(Equal(1))
+
+			Expect(driver.Create(env, dockerdriver.CreateRequest{Name: "other", Opts: map[string]interface{}{"source": "server:/other"}}).Err).To(BeEmpty())
+			Expect(driver.Mount(env, dockerdriver.MountRequest{Name: "other"}).Err).To(BeEmpty())
+		})
+
+		It("mounts the volume again once its release finished", func() {
+			Expect(driver.Mount(env, dockerdriver.MountRequest{Name: "vol"}).Err).To(BeEmpty())
+			Expect(driver.Unmount(env, dockerdriver.UnmountRequest{Name: "vol"}).Err).To(BeEmpty())
+			Expect(driver.Create(env, dockerdriver.CreateRequest{Name: "vol", Opts: map[string]interface{}{"source": "server:/export"}}).Err).To(BeEmpty())
+			Eventually(releaser.ExportReleasedCallCount).Should(Equal(1))
+
+			mounted := make(chan dockerdriver.MountResponse, 1)
+			go func() {
+				defer GinkgoRecover()
+				mounted <- driver.Mount(env, dockerdriver.MountRequest{Name: "vol"})
+			}()
+			Consistently(store.AcquireCallCount).Should(Equal(1))
+
+			hookDone <- struct{}{}
+			Eventually(mounted).Should(Receive())
+			Expect(store.AcquireCallCount()).To(Equal(2))
+			Expect(store.ForgetCallCount()).To(Equal(1))
+		})
+	})
+
+	Context("when the export is being released", func() {
+		BeforeEach(func() {
+			store.AcquireReturns(volumedriver.ErrExportReleasing)
+		})
+
+		It("refuses the mount with a retriable busy error", func() {
+			safe := safeError(driver.Mount(env, dockerdriver.MountRequest{Name: "vol"}).Err)
+			Expect(safe.Code).To(Equal(safeerrors.Busy))
+			Expect(safe.SafeDescription).To(Equal("Export 'server:/export' is being released, try again later"))
+			Expect(safe.RetryAfter).To(Equal(5))
+
+			Expect(driver.Mounter.MountCalls()).To(BeEmpty())
+		})
+	})
+
+	Context("when the shared state cannot be reached", func() {
+		BeforeEach(func() {
+			store.AcquireReturns(errors.New("connection refused"))
+		})
+
+		It("fails the mount as unavailable", func() {
+			safe := safeError(driver.Mount(env, dockerdriver.MountRequest{Name: "vol"}).Err)
+			Expect(safe.Code).To(Equal(safeerrors.Unavailable))
+			Expect(safe.SafeDescription).To(Equal("Failed to reference export 'server:/export' in the shared state"))
+
+			Expect(driver.Mounter.MountCalls()).To(BeEmpty())
+		})
+	})
+
+	Context("when the mount fails", func() {
+		It("drops the reference again", func() {
+			driver.Mounter.FailMount("server:/export", errors.New("no route to host"))
+
+			Expect(driver.Mount(env, dockerdriver.MountRequest{Name: "vol"}).Err).NotTo(BeEmpty())
+
+			Expect(store.AcquireCallCount()).To(Equal(1))
+			Eventually(store.ReleaseCallCount).Should(Equal(1))
+		})
+	})
+
+	It("holds no references for test mounts", func() {
+		Expect(driver.Validate(env, volumedriver.ValidateRequest{Name: "probe", Opts: map[string]interface{}{"source": "server:/export"}, TestMount: true}).Valid).To(BeTrue())
+		Expect(driver.Mounter.MountCalls()).To(HaveLen(1))
+
+		Expect(store.AcquireCallCount()).To(Equal(0))
+		Expect(store.ReleaseCallCount()).To(Equal(0))
+	})
+
+	Context("without a shared state", func() {
+		BeforeEach(func() {
+			options.RefStore = nil
+		})
+
+		It("reports the local scope", func() {
+			Expect(driver.Capabilities(env).Capabilities.Scope).To(Equal("local"))
+		})
+	})
+})
@@ -0,0 +1,28 @@
+package volumedriver
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver/metrics"
+)
+
+// reportSlowMount logs and counts mounts that took longer than the configured
+// thresholds, so that operators can alert before orchestrators time out.
+func (d *VolumeDriver) reportSlowMount(logger lager.Logger, source string, duration time.Duration) {
+	data := lager.Data{"mount-duration-in-second": duration / time.Second, "source": source}
+	policy := d.currentPolicy()
+
+	switch {
+	case policy.CriticalMountThreshold > 0 && duration > policy.CriticalMountThreshold:
+		data["threshold"] = policy.CriticalMountThreshold.String()
+		data["warning"] = "Container creation is likely to fail!"
+		logger.Error("mount-duration-critical", nil, data)
+		d.metrics.Count(metrics.SlowMounts, 1, metrics.SourceTag(source), metrics.SeverityTag(metrics.SeverityCritical))
+	case policy.SlowMountThreshold > 0 && duration > policy.SlowMountThreshold:
+		data["threshold"] = policy.SlowMountThreshold.String()
+		data["warning"] = "This may result in container creation failure!"
+		logger.Error("mount-duration-too-high", nil, data)
+		d.metrics.Count(metrics.SlowMounts, 1, metrics.SourceTag(source), metrics.SeverityTag(metrics.SeverityWarning))
+	}
+}
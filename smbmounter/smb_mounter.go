@@ -0,0 +1,218 @@
+package smbmounter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/goshims/ioutilshim"
+	"code.cloudfoundry.org/goshims/osshim"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/invoker"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+)
+
+const (
+	UsernameOpt = "username"
+	PasswordOpt = "password"
+	DomainOpt   = "domain"
+
+	powershell = "powershell.exe"
+)
+
+type smbMounter struct {
+	invoker invoker.Invoker
+	os      osshim.Os
+	ioutil  ioutilshim.Ioutil
+}
+
+// NewSmbMounter returns a Mounter for Windows cells. The share is mapped
+// with New-SmbMapping and the mountpoint is replaced by a symbolic link to
+// the share, the equivalent of mklink /D. Sources are UNC paths, either
+// \\server\share or //server/share.
+func NewSmbMounter(invoker invoker.Invoker, os osshim.Os, ioutil ioutilshim.Ioutil) volumedriver.Mounter {
+	return &smbMounter{
+		invoker: invoker,
+		os:      os,
+		ioutil:  ioutil,
+	}
+}
+
+func (m *smbMounter) Mount(env dockerdriver.Env, source string, target string, opts map[string]interface{}) error {
+	logger := env.Logger().Session("smb-mount", lager.Data{"source": source, "target": target})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	remotePath, err := uncPath(source)
+	if err != nil {
+		return err
+	}
+
+	credentials, err := credentialEnv(opts)
+	if err != nil {
+		return err
+	}
+
+	script := fmt.Sprintf("$ErrorActionPreference = 'Stop'; New-SmbMapping -RemotePath %s -Persistent $false", quote(remotePath))
+	if len(credentials) > 0 {
+		script += " -UserName $env:SMB_USERNAME -Password $env:SMB_PASSWORD"
+	}
+
+	if err := m.powershell(env, script, credentials...); err != nil {
+		logger.Error("smb-mapping-failed", err)
+		return fmt.Errorf("mapping %s failed: %s", remotePath, err)
+	}
+
+	// the driver creates the mountpoint as a directory, the link takes its place
+	if err := m.os.Remove(target); err != nil && !os.IsNotExist(err) {
+		logger.Error("remove-mountpoint-failed", err)
+		m.removeMapping(env, remotePath)
+		return err
+	}
+
+	if err := m.os.Symlink(remotePath, target); err != nil {
+		logger.Error("link-failed", err)
+		m.removeMapping(env, remotePath)
+		return fmt.Errorf("linking %s to %s failed: %s", target, remotePath, err)
+	}
+
+	return nil
+}
+
+func (m *smbMounter) Unmount(env dockerdriver.Env, target string) error {
+	logger := env.Logger().Session("smb-unmount", lager.Data{"target": target})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	remotePath, err := m.os.Readlink(target)
+	if err != nil {
+		logger.Error("readlink-failed", err)
+		return fmt.Errorf("%s is not linked to a share: %s", target, err)
+	}
+
+	// the link itself is removed by the driver along with the mountpoint
+	return m.removeMapping(env, remotePath)
+}
+
+func (m *smbMounter) Check(env dockerdriver.Env, name, mountPoint string, depth volumedriver.CheckDepth) bool {
+	logger := env.Logger().Session("smb-check", lager.Data{"name": name, "mount-point": mountPoint, "depth": depth})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	if _, err := m.os.Readlink(mountPoint); err != nil {
+		logger.Info("not-linked", lager.Data{"err": err.Error()})
+		return false
+	}
+
+	if err := volumedriver.ProbeMountPoint(m.os, m.ioutil, mountPoint, depth); err != nil {
+		logger.Error("probe-failed", err)
+		return false
+	}
+
+	return true
+}
+
+// Purge removes every share link below path along with its mapping.
+func (m *smbMounter) Purge(env dockerdriver.Env, path string) {
+	logger := env.Logger().Session("smb-purge", lager.Data{"path": path})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	entries, err := m.ioutil.ReadDir(path)
+	if err != nil {
+		logger.Error("read-dir-failed", err)
+		return
+	}
+
+	for _, entry := range entries {
+		link := filepath.Join(path, entry.Name())
+
+		remotePath, err := m.os.Readlink(link)
+		if err != nil {
+			continue
+		}
+
+		if err := m.removeMapping(env, remotePath); err != nil {
+			logger.Error("purge-remove-mapping-failed", err, lager.Data{"link": link})
+		}
+
+		if err := m.os.Remove(link); err != nil {
+			logger.Error("purge-remove-link-failed", err, lager.Data{"link": link})
+		}
+	}
+}
+
+func (m *smbMounter) removeMapping(env dockerdriver.Env, remotePath string) error {
+	script := fmt.Sprintf("$ErrorActionPreference = 'Stop'; Remove-SmbMapping -RemotePath %s -Force", quote(remotePath))
+	if err := m.powershell(env, script); err != nil {
+		env.Logger().Error("remove-smb-mapping-failed", err, lager.Data{"remote-path": remotePath})
+		return fmt.Errorf("removing mapping %s failed: %s", remotePath, err)
+	}
+	return nil
+}
+
+func (m *smbMounter) powershell(env dockerdriver.Env, script string, envVars ...string) error {
+	result := m.invoker.Invoke(env, powershell, []string{"-NoProfile", "-NonInteractive", "-Command", script}, envVars...)
+	if err := result.Wait(); err != nil {
+		if stderr := strings.TrimSpace(result.StdError()); stderr != "" {
+			return fmt.Errorf("%s", stderr)
+		}
+		return err
+	}
+	return nil
+}
+
+// credentialEnv passes credentials in the environment of the powershell
+// process rather than on its command line, where other users could see them.
+// SMB global mappings have no hardening flags, so the hardening opts of the
+// driver are ignored.
+func credentialEnv(opts map[string]interface{}) ([]string, error) {
+	opts, _ = volumedriver.HardeningFlags(opts)
+	values := map[string]string{}
+	for key, value := range opts {
+		switch key {
+		case UsernameOpt, PasswordOpt, DomainOpt:
+			s, ok := value.(string)
+			if !ok {
+				return nil, safeerrors.New(safeerrors.InvalidOption, "Invalid value for option '%s'", key)
+			}
+			values[key] = s
+		default:
+			return nil, safeerrors.New(safeerrors.InvalidOption, "Not allowed options: %s", key)
+		}
+	}
+
+	username, ok := values[UsernameOpt]
+	if !ok {
+		if len(values) > 0 {
+			return nil, safeerrors.New(safeerrors.InvalidOption, "'username' is required when passing credentials")
+		}
+		return nil, nil
+	}
+
+	if domain := values[DomainOpt]; domain != "" {
+		username = domain + `\` + username
+	}
+
+	return []string{"SMB_USERNAME=" + username, "SMB_PASSWORD=" + values[PasswordOpt]}, nil
+}
+
+// uncPath normalizes //server/share/path and \\server\share\path.
+func uncPath(source string) (string, error) {
+	normalized := strings.Replace(source, "/", `\`, -1)
+	parts := strings.Split(strings.TrimPrefix(normalized, `\\`), `\`)
+
+	if !strings.HasPrefix(normalized, `\\`) || len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return "", safeerrors.New(safeerrors.InvalidSource, "invalid smb source '%s', expected //server/share", source)
+	}
+
+	return strings.TrimSuffix(normalized, `\`), nil
+}
+
+// quote returns a single quoted powershell string literal.
+func quote(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
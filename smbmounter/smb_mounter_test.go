@@ -0,0 +1,213 @@
+package smbmounter_test
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/goshims/ioutilshim/ioutil_fake"
+	"code.cloudfoundry.org/goshims/osshim/os_fake"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/invokerfakes"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+	"code.cloudfoundry.org/volumedriver/smbmounter"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SmbMounter", func() {
+	var (
+		logger           *lagertest.TestLogger
+		env              dockerdriver.Env
+		fakeInvoker      *invokerfakes.FakeInvoker
+		fakeInvokeResult *invokerfakes.FakeInvokeResult
+		fakeOs           *os_fake.FakeOs
+		fakeIoutil       *ioutil_fake.FakeIoutil
+		mounter          volumedriver.Mounter
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("smbmounter")
+		env = driverhttp.NewHttpDriverEnv(logger, context.TODO())
+		fakeInvoker = &invokerfakes.FakeInvoker{}
+		fakeInvokeResult = &invokerfakes.FakeInvokeResult{}
+		fakeInvoker.InvokeReturns(fakeInvokeResult)
+		fakeOs = &os_fake.FakeOs{}
+		fakeIoutil = &ioutil_fake.FakeIoutil{}
+
+		mounter = smbmounter.NewSmbMounter(fakeInvoker, fakeOs, fakeIoutil)
+	})
+
+	Describe("Mount", func() {
+		var (
+			source string
+			opts   map[string]interface{}
+			err    error
+		)
+
+		BeforeEach(func() {
+			source = "//server/share"
+			opts = map[string]interface{}{"username": "user", "password": "secret", "domain": "CORP"}
+		})
+
+		JustBeforeEach(func() {
+			err = mounter.Mount(env, source, `C:\mounts\volume`, opts)
+		})
+
+		It("maps the share with the credentials in the environment", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeInvoker.InvokeCallCount()).To(Equal(1))
+
+			_, cmd, args, envVars := fakeInvoker.InvokeArgsForCall(0)
+			Expect(cmd).To(Equal("powershell.exe"))
+			Expect(args[len(args)-1]).To(ContainSubstring(`New-SmbMapping -RemotePath '\\server\share'`))
+			Expect(args[len(args)-1]).To(ContainSubstring("-UserName $env:SMB_USERNAME -Password $env:SMB_PASSWORD"))
+			Expect(args[len(args)-1]).NotTo(ContainSubstring("secret"))
+			Expect(envVars).To(Equal([]string{`SMB_USERNAME=CORP\user`, "SMB_PASSWORD=secret"}))
+		})
+
+		It("replaces the mountpoint with a link to the share", func() {
+			Expect(fakeOs.RemoveArgsForCall(0)).To(Equal(`C:\mounts\volume`))
+			oldname, newname := fakeOs.SymlinkArgsForCall(0)
+			Expect(oldname).To(Equal(`\\server\share`))
+			Expect(newname).To(Equal(`C:\mounts\volume`))
+		})
+
+		Context("without credentials", func() {
+			BeforeEach(func() {
+				opts = map[string]interface{}{}
+			})
+
+			It("maps the share as the driver user", func() {
+				_, _, args, envVars := fakeInvoker.InvokeArgsForCall(0)
+				Expect(args[len(args)-1]).NotTo(ContainSubstring("-UserName"))
+				Expect(envVars).To(BeEmpty())
+			})
+		})
+
+		Context("when the source is not a UNC path", func() {
+			BeforeEach(func() {
+				source = "server:/export"
+			})
+
+			It("rejects the mount", func() {
+				Expect(err).To(Equal(safeerrors.New(safeerrors.InvalidSource, "invalid smb source 'server:/export', expected //server/share")))
+				Expect(fakeInvoker.InvokeCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when the driver hardens the mount", func() {
+			BeforeEach(func() {
+				opts["nosuid"] = true
+				opts["nodev"] = true
+				opts["noexec"] = true
+			})
+
+			It("ignores the hardening opts, which smb mappings do not have", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeInvoker.InvokeCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when an unknown option is given", func() {
+			BeforeEach(func() {
+				opts["uid"] = "1000"
+			})
+
+			It("rejects the mount", func() {
+				Expect(err).To(Equal(safeerrors.New(safeerrors.InvalidOption, "Not allowed options: uid")))
+			})
+		})
+
+		Context("when the mapping fails", func() {
+			BeforeEach(func() {
+				fakeInvokeResult.WaitReturns(errors.New("exit status 1"))
+				fakeInvokeResult.StdErrorReturns("Access is denied.")
+			})
+
+			It("returns the error", func() {
+				Expect(err).To(MatchError(`mapping \\server\share failed: Access is denied.`))
+				Expect(fakeOs.SymlinkCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when the link cannot be created", func() {
+			BeforeEach(func() {
+				fakeOs.SymlinkReturns(errors.New("privilege not held"))
+			})
+
+			It("removes the mapping again", func() {
+				Expect(err).To(MatchError(`linking C:\mounts\volume to \\server\share failed: privilege not held`))
+				Expect(fakeInvoker.InvokeCallCount()).To(Equal(2))
+				_, _, args, _ := fakeInvoker.InvokeArgsForCall(1)
+				Expect(args[len(args)-1]).To(ContainSubstring(`Remove-SmbMapping -RemotePath '\\server\share' -Force`))
+			})
+		})
+	})
+
+	Describe("Unmount", func() {
+		BeforeEach(func() {
+			fakeOs.ReadlinkReturns(`\\server\share`, nil)
+		})
+
+		It("removes the mapping of the linked share", func() {
+			Expect(mounter.Unmount(env, `C:\mounts\volume`)).To(Succeed())
+			_, _, args, _ := fakeInvoker.InvokeArgsForCall(0)
+			Expect(args[len(args)-1]).To(ContainSubstring(`Remove-SmbMapping -RemotePath '\\server\share' -Force`))
+			Expect(fakeOs.RemoveCallCount()).To(BeZero())
+		})
+
+		Context("when the mountpoint is not a link", func() {
+			BeforeEach(func() {
+				fakeOs.ReadlinkReturns("", errors.New("not a link"))
+			})
+
+			It("returns an error", func() {
+				Expect(mounter.Unmount(env, `C:\mounts\volume`)).To(MatchError(`C:\mounts\volume is not linked to a share: not a link`))
+			})
+		})
+	})
+
+	Describe("Check", func() {
+		It("reports a linked, reachable share as healthy", func() {
+			dirInfo := &ioutil_fake.FakeFileInfo{}
+			dirInfo.IsDirReturns(true)
+			fakeOs.StatReturns(dirInfo, nil)
+			fakeOs.ReadlinkReturns(`\\server\share`, nil)
+
+			Expect(mounter.Check(env, "volume", `C:\mounts\volume`, volumedriver.CheckStat)).To(BeTrue())
+		})
+
+		It("reports an unreachable share as unhealthy", func() {
+			fakeOs.ReadlinkReturns(`\\server\share`, nil)
+			fakeOs.StatReturns(nil, os.ErrNotExist)
+
+			Expect(mounter.Check(env, "volume", `C:\mounts\volume`, volumedriver.CheckStat)).To(BeFalse())
+		})
+	})
+
+	Describe("Purge", func() {
+		It("removes the links and their mappings", func() {
+			link := &ioutil_fake.FakeFileInfo{}
+			link.NameReturns("volume")
+			dir := &ioutil_fake.FakeFileInfo{}
+			dir.NameReturns("driver-state.d")
+			fakeIoutil.ReadDirReturns([]os.FileInfo{link, dir}, nil)
+			fakeOs.ReadlinkStub = func(name string) (string, error) {
+				if name == "mounts/volume" {
+					return `\\server\share`, nil
+				}
+				return "", errors.New("not a link")
+			}
+
+			mounter.Purge(env, "mounts")
+
+			Expect(fakeInvoker.InvokeCallCount()).To(Equal(1))
+			Expect(fakeOs.RemoveCallCount()).To(Equal(1))
+			Expect(fakeOs.RemoveArgsForCall(0)).To(Equal("mounts/volume"))
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package smbmounter_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestSmbMounter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "SmbMounter Suite")
+}
@@ -0,0 +1,102 @@
+package volumedriver
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// SourceDefaults supplies default opts for volumes whose source host matches
+// Pattern, a glob such as "*.nfs.example.com" or "10.0.*". Opts given on
+// Create take precedence over the defaults.
+type SourceDefaults struct {
+	Pattern string
+	Opts    map[string]interface{}
+}
+
+// ParseOptsTemplate parses mount style options such as
+// "vers=4.1,timeo=600,nosuid". Options without a value are set to true.
+func ParseOptsTemplate(template string) (map[string]interface{}, error) {
+	opts := map[string]interface{}{}
+
+	for _, opt := range strings.Split(template, ",") {
+		opt = strings.TrimSpace(opt)
+		if opt == "" {
+			continue
+		}
+
+		kv := strings.SplitN(opt, "=", 2)
+		key := strings.TrimSpace(kv[0])
+		if key == "" {
+			return nil, fmt.Errorf("invalid option '%s' in '%s'", opt, template)
+		}
+
+		if len(kv) == 1 {
+			opts[key] = true
+		} else {
+			opts[key] = strings.TrimSpace(kv[1])
+		}
+	}
+
+	return opts, nil
+}
+
+// ValidateSourceDefaults checks that every pattern is a valid glob.
+func ValidateSourceDefaults(defaults []SourceDefaults) error {
+	for _, sourceDefaults := range defaults {
+		if _, err := path.Match(sourceDefaults.Pattern, ""); err != nil {
+			return fmt.Errorf("invalid source pattern '%s': %s", sourceDefaults.Pattern, err)
+		}
+	}
+	return nil
+}
+
+// applySourceDefaults returns opts merged over the defaults of every template
+// matching the source host, in order, so later templates override earlier
+// ones.
+func (d *VolumeDriver) applySourceDefaults(opts map[string]interface{}) map[string]interface{} {
+	defaults := d.currentPolicy().SourceDefaults
+	if len(defaults) == 0 {
+		return opts
+	}
+
+	source := sourceOpt(opts)
+	host := sourceHost(source)
+
+	merged := map[string]interface{}{}
+	for _, sourceDefaults := range defaults {
+		if matched, _ := path.Match(sourceDefaults.Pattern, host); !matched {
+			continue
+		}
+		for k, v := range sourceDefaults.Opts {
+			merged[k] = v
+		}
+	}
+
+	for k, v := range opts {
+		merged[k] = v
+	}
+
+	return merged
+}
+
+// sourceHost extracts the server from sources such as host:/export,
+// nfs://host/export, //server/share and [fd00::1]:/export.
+func sourceHost(source string) string {
+	if i := strings.Index(source, "://"); i >= 0 {
+		source = source[i+3:]
+	}
+	source = strings.TrimLeft(source, `/\`)
+
+	if strings.HasPrefix(source, "[") {
+		if end := strings.Index(source, "]"); end > 0 {
+			return source[1:end]
+		}
+	}
+
+	if end := strings.IndexAny(source, `:/\`); end >= 0 {
+		source = source[:end]
+	}
+
+	return source
+}
@@ -0,0 +1,27 @@
+package volumedriver_test
+
+import (
+	"code.cloudfoundry.org/volumedriver"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseOptsTemplate", func() {
+	It("parses key=value options and flags", func() {
+		opts, err := volumedriver.ParseOptsTemplate("vers=4.1, timeo=600,nosuid")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(opts).To(Equal(map[string]interface{}{"vers": "4.1", "timeo": "600", "nosuid": true}))
+	})
+
+	It("rejects options without a name", func() {
+		_, err := volumedriver.ParseOptsTemplate("vers=4.1,=600")
+		Expect(err).To(MatchError("invalid option '=600' in 'vers=4.1,=600'"))
+	})
+})
+
+var _ = Describe("ValidateSourceDefaults", func() {
+	It("rejects invalid patterns", func() {
+		err := volumedriver.ValidateSourceDefaults([]volumedriver.SourceDefaults{{Pattern: "[nfs"}})
+		Expect(err).To(MatchError(ContainSubstring("invalid source pattern '[nfs'")))
+	})
+})
@@ -0,0 +1,100 @@
+package volumedriver
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"strings"
+)
+
+// SourceRule allows the sources whose server matches Host and whose export
+// matches Export. Host is either a glob such as "*.nfs.example.com" or a
+// CIDR such as "10.0.0.0/8"; a CIDR only matches sources that name their
+// server by address, as host names are not resolved. Export is a glob over
+// the exported path such as "/tenants/*", where "*" does not cross a "/".
+// An empty Export allows every export of the server.
+type SourceRule struct {
+	Host   string
+	Export string
+}
+
+// ValidateAllowedSources checks that every rule is a valid glob or CIDR.
+func ValidateAllowedSources(rules []SourceRule) error {
+	for _, rule := range rules {
+		if rule.Host == "" {
+			return fmt.Errorf("invalid source rule, the host must be set")
+		}
+		if _, _, err := net.ParseCIDR(rule.Host); err != nil && strings.Contains(rule.Host, "/") {
+			return fmt.Errorf("invalid source host '%s': %s", rule.Host, err)
+		}
+		if _, err := path.Match(rule.Host, ""); err != nil {
+			return fmt.Errorf("invalid source host '%s': %s", rule.Host, err)
+		}
+		if _, err := path.Match(rule.Export, ""); err != nil {
+			return fmt.Errorf("invalid source export '%s': %s", rule.Export, err)
+		}
+	}
+	return nil
+}
+
+// checkSourceAllowed refuses sources that no rule of the AllowedSources
+// option matches. Every source is allowed when the option is empty.
+func (d *VolumeDriver) checkSourceAllowed(source string) error {
+	rules := d.currentPolicy().AllowedSources
+	if len(rules) == 0 {
+		return nil
+	}
+
+	host, export := sourceHost(source), sourceExport(source)
+	for _, rule := range rules {
+		if rule.matches(host, export) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("source '%s' is not allowed on this cell", source)
+}
+
+func (r SourceRule) matches(host string, export string) bool {
+	if _, network, err := net.ParseCIDR(r.Host); err == nil {
+		// drop the zone of link-local addresses such as fe80::1%eth0
+		ip := net.ParseIP(strings.SplitN(host, "%", 2)[0])
+		if ip == nil || !network.Contains(ip) {
+			return false
+		}
+	} else if matched, _ := path.Match(r.Host, host); !matched {
+		return false
+	}
+
+	if r.Export == "" {
+		return true
+	}
+	matched, _ := path.Match(r.Export, export)
+	return matched
+}
+
+// sourceExport extracts the exported path from the sources understood by
+// sourceHost, such as "/export" from host:/export or nfs://host/export.
+func sourceExport(source string) string {
+	if i := strings.Index(source, "://"); i >= 0 {
+		source = source[i+3:]
+	}
+	source = strings.TrimLeft(source, `/\`)
+
+	if strings.HasPrefix(source, "[") {
+		if end := strings.Index(source, "]"); end > 0 {
+			source = source[end+1:]
+		}
+	}
+
+	end := strings.IndexAny(source, `:/\`)
+	if end < 0 {
+		return "/"
+	}
+	export := strings.TrimPrefix(source[end:], ":")
+	export = strings.Replace(export, `\`, "/", -1)
+	if !strings.HasPrefix(export, "/") {
+		export = "/" + export
+	}
+	return path.Clean(export)
+}
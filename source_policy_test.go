@@ -0,0 +1,32 @@
+package volumedriver_test
+
+import (
+	"code.cloudfoundry.org/volumedriver"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ValidateAllowedSources", func() {
+	It("accepts globs and CIDRs", func() {
+		Expect(volumedriver.ValidateAllowedSources([]volumedriver.SourceRule{
+			{Host: "*.nfs.example.com", Export: "/tenants/*"},
+			{Host: "10.0.0.0/8"},
+		})).To(Succeed())
+	})
+
+	It("rejects invalid hosts", func() {
+		err := volumedriver.ValidateAllowedSources([]volumedriver.SourceRule{{Host: "10.0.0.0/33"}})
+		Expect(err).To(MatchError(ContainSubstring("invalid source host '10.0.0.0/33'")))
+
+		err = volumedriver.ValidateAllowedSources([]volumedriver.SourceRule{{Host: "[nfs"}})
+		Expect(err).To(MatchError(ContainSubstring("invalid source host '[nfs'")))
+
+		err = volumedriver.ValidateAllowedSources([]volumedriver.SourceRule{{Export: "/tenants"}})
+		Expect(err).To(MatchError("invalid source rule, the host must be set"))
+	})
+
+	It("rejects invalid exports", func() {
+		err := volumedriver.ValidateAllowedSources([]volumedriver.SourceRule{{Host: "filer", Export: "/[tenants"}})
+		Expect(err).To(MatchError(ContainSubstring("invalid source export '/[tenants'")))
+	})
+})
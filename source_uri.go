@@ -0,0 +1,73 @@
+package volumedriver
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// NFSScheme sources such as nfs://host/export?vers=4.1&uid=1000, as used by
+// the nfs-volume-release broker, are rewritten to host:/export with the query
+// merged into the opts. Opts given on Create take precedence over the query,
+// and a port in the URI becomes the port opt.
+const NFSScheme = "nfs"
+
+// normalizeSource returns opts with an nfs:// source rewritten for the
+// default mounter. Sources are left alone when a Mounter is registered for
+// the scheme, which then parses them itself.
+func (d *VolumeDriver) normalizeSource(opts map[string]interface{}) (map[string]interface{}, error) {
+	source := sourceOpt(opts)
+	if !strings.HasPrefix(strings.ToLower(source), NFSScheme+"://") {
+		return opts, nil
+	}
+	if _, registered := d.options.Mounters[NFSScheme]; registered {
+		return opts, nil
+	}
+
+	uri, err := url.Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source '%s': %s", source, err)
+	}
+	if uri.Hostname() == "" || uri.User != nil || uri.Fragment != "" {
+		return nil, fmt.Errorf("invalid source '%s', must be of the form nfs://host/export?opt=value", source)
+	}
+
+	query, err := url.ParseQuery(uri.RawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("invalid options in source '%s': %s", source, err)
+	}
+
+	merged := map[string]interface{}{}
+	for key, values := range query {
+		if key == "" || key == "source" || len(values) > 1 {
+			return nil, fmt.Errorf("invalid option '%s' in source '%s'", key, source)
+		}
+		if values[0] == "" {
+			merged[key] = true
+		} else {
+			merged[key] = values[0]
+		}
+	}
+	if port := uri.Port(); port != "" {
+		merged["port"] = port
+	}
+
+	for k, v := range opts {
+		merged[k] = v
+	}
+
+	host := uri.Hostname()
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	} else if net.ParseIP(host) == nil {
+		host = strings.ToLower(host)
+	}
+	export := uri.Path
+	if export == "" {
+		export = "/"
+	}
+	merged["source"] = host + ":" + export
+
+	return merged, nil
+}
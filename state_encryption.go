@@ -0,0 +1,97 @@
+package volumedriver
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// encryptedStateHeader starts every encrypted state record, so that records
+// written before a key was configured can still be read and migrated.
+var encryptedStateHeader = []byte("volumedriver-aesgcm-v1:")
+
+// ParseStateKey decodes a base64 encoded AES key of 16, 24 or 32 bytes for
+// Options.StateKey.
+func ParseStateKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid state key, must be base64 encoded: %s", err)
+	}
+
+	switch len(key) {
+	case 16, 24, 32:
+		return key, nil
+	}
+	return nil, fmt.Errorf("invalid state key, must be 16, 24 or 32 bytes long, not %d", len(key))
+}
+
+// StateKeyFromEnv reads the state key from the environment variable name,
+// where deployments usually render it from CredHub. It returns nil when the
+// variable is not set, which leaves the state unencrypted.
+func StateKeyFromEnv(name string) ([]byte, error) {
+	encoded, ok := os.LookupEnv(name)
+	if !ok || encoded == "" {
+		return nil, nil
+	}
+	return ParseStateKey(encoded)
+}
+
+func newStateCipher(key []byte) (cipher.AEAD, error) {
+	if key == nil {
+		return nil, nil
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealState encrypts a state record when a state key is configured. The
+// name of the record file is authenticated with it, so that records cannot
+// be swapped between volumes.
+func (d *VolumeDriver) sealState(fileName string, data []byte) ([]byte, error) {
+	if d.stateCipher == nil {
+		return data, nil
+	}
+
+	nonce := make([]byte, d.stateCipher.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	sealed := append([]byte{}, encryptedStateHeader...)
+	sealed = append(sealed, nonce...)
+	return d.stateCipher.Seal(sealed, nonce, data, []byte(fileName)), nil
+}
+
+// openState returns the plaintext of a state record, and whether it was
+// encrypted.
+func (d *VolumeDriver) openState(fileName string, data []byte) ([]byte, bool, error) {
+	if !bytes.HasPrefix(data, encryptedStateHeader) {
+		return data, false, nil
+	}
+
+	if d.stateCipher == nil {
+		return nil, true, errors.New("state is encrypted but no state key is configured")
+	}
+
+	data = data[len(encryptedStateHeader):]
+	if len(data) < d.stateCipher.NonceSize() {
+		return nil, true, errors.New("encrypted state is truncated")
+	}
+
+	nonce, ciphertext := data[:d.stateCipher.NonceSize()], data[d.stateCipher.NonceSize():]
+	plaintext, err := d.stateCipher.Open(nil, nonce, ciphertext, []byte(fileName))
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to decrypt state, the state key may have changed: %s", err)
+	}
+	return plaintext, true, nil
+}
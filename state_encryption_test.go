@@ -0,0 +1,120 @@
+package volumedriver_test
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/testhelpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("State encryption", func() {
+	const stateFile = testhelpers.MountPathRoot + "/driver-state.d/volume.json"
+
+	var (
+		logger  *lagertest.TestLogger
+		env     dockerdriver.Env
+		key     []byte
+		options volumedriver.Options
+		driver  *testhelpers.MemoryDriver
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("state-encryption")
+		env = driverhttp.NewHttpDriverEnv(logger, context.TODO())
+
+		var err error
+		key, err = volumedriver.ParseStateKey(base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")))
+		Expect(err).NotTo(HaveOccurred())
+
+		options = volumedriver.DefaultOptions()
+		options.StateKey = key
+		driver = testhelpers.NewMemoryDriverWithOptions(logger, options)
+
+		Expect(driver.Create(env, dockerdriver.CreateRequest{
+			Name: "volume",
+			Opts: map[string]interface{}{"source": "secret-server.example.com:/export"},
+		}).Err).To(BeEmpty())
+		Expect(driver.Mount(env, dockerdriver.MountRequest{Name: "volume"}).Err).To(BeEmpty())
+	})
+
+	It("does not write the state in plaintext", func() {
+		data, err := driver.FS.ReadFile(stateFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).NotTo(ContainSubstring(`"Name":"volume"`))
+		Expect(string(data)).NotTo(ContainSubstring("Mountpoint"))
+	})
+
+	It("restores the encrypted state", func() {
+		driver = driver.Restart(logger)
+		Expect(driver.Get(env, dockerdriver.GetRequest{Name: "volume"}).Volume.MountCount).To(Equal(1))
+	})
+
+	Context("when the driver restarts without the key", func() {
+		It("cannot restore the state", func() {
+			driver = driver.RestartWithOptions(logger, volumedriver.DefaultOptions())
+			Expect(driver.Get(env, dockerdriver.GetRequest{Name: "volume"}).Err).To(Equal("Volume not found"))
+			Expect(logger).To(gbytes.Say("no state key is configured"))
+		})
+	})
+
+	Context("when the state was written without a key", func() {
+		BeforeEach(func() {
+			driver = testhelpers.NewMemoryDriver(logger)
+			Expect(driver.Create(env, dockerdriver.CreateRequest{
+				Name: "volume",
+				Opts: map[string]interface{}{"source": "server:/export"},
+			}).Err).To(BeEmpty())
+
+			data, err := driver.FS.ReadFile(stateFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(ContainSubstring(`"Name":"volume"`))
+		})
+
+		It("restores it and encrypts it", func() {
+			driver = driver.RestartWithOptions(logger, options)
+			Expect(driver.Get(env, dockerdriver.GetRequest{Name: "volume"}).Err).To(BeEmpty())
+
+			data, err := driver.FS.ReadFile(stateFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).NotTo(ContainSubstring(`"Name":"volume"`))
+
+			driver = driver.Restart(logger)
+			Expect(driver.Get(env, dockerdriver.GetRequest{Name: "volume"}).Err).To(BeEmpty())
+		})
+	})
+})
+
+var _ = Describe("ParseStateKey", func() {
+	It("rejects keys that are not base64", func() {
+		_, err := volumedriver.ParseStateKey("not base64!")
+		Expect(err).To(MatchError(HavePrefix("invalid state key, must be base64 encoded")))
+	})
+
+	It("rejects keys of the wrong length", func() {
+		_, err := volumedriver.ParseStateKey(base64.StdEncoding.EncodeToString([]byte("short")))
+		Expect(err).To(MatchError("invalid state key, must be 16, 24 or 32 bytes long, not 5"))
+	})
+})
+
+var _ = Describe("StateKeyFromEnv", func() {
+	AfterEach(func() {
+		os.Unsetenv("VOLUMEDRIVER_TEST_STATE_KEY")
+	})
+
+	It("returns no key when the variable is not set", func() {
+		Expect(volumedriver.StateKeyFromEnv("VOLUMEDRIVER_TEST_STATE_KEY")).To(BeNil())
+	})
+
+	It("parses the key in the variable", func() {
+		os.Setenv("VOLUMEDRIVER_TEST_STATE_KEY", base64.StdEncoding.EncodeToString([]byte("0123456789abcdef")))
+		Expect(volumedriver.StateKeyFromEnv("VOLUMEDRIVER_TEST_STATE_KEY")).To(Equal([]byte("0123456789abcdef")))
+	})
+})
@@ -0,0 +1,128 @@
+package volumedriver
+
+import (
+	"sort"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver/metrics"
+)
+
+// StateLimits bound the state kept for volumes that are not mounted, which
+// grows without bound when volumes are created and never mounted or
+// removed. PruneState removes the least recently used of them until the
+// state is within the limits; mounted volumes are never pruned. Zero means
+// unlimited.
+type StateLimits struct {
+	// MaxUnmountedVolumes is the number of volumes that are not mounted
+	// that are kept.
+	MaxUnmountedVolumes int
+	// MaxStateBytes is the size of the state records of all volumes.
+	MaxStateBytes int
+}
+
+// StateSize returns the number of bytes of the state records of the volumes
+// and how many of the volumes are not mounted.
+func (d *VolumeDriver) StateSize() (bytes int, unmounted int) {
+	d.volumesLock.RLock()
+	defer d.volumesLock.RUnlock()
+	return d.stateSize()
+}
+
+// stateSize must be called with volumesLock held.
+func (d *VolumeDriver) stateSize() (bytes int, unmounted int) {
+	for name, volume := range d.volumes {
+		bytes += d.stateSizes[name]
+		if volume.MountCount < 1 {
+			unmounted++
+		}
+	}
+	return bytes, unmounted
+}
+
+// PruneState removes the volumes that are not mounted, least recently
+// created or mounted first, while the state exceeds the StateLimits of the
+// policy. It returns the names of the pruned volumes.
+func (d *VolumeDriver) PruneState(env dockerdriver.Env) []string {
+	logger := env.Logger().Session("prune-state")
+
+	d.volumesLock.Lock()
+	defer d.volumesLock.Unlock()
+
+	limits := d.currentPolicy().StateLimits
+	bytes, unmounted := d.stateSize()
+	defer func() {
+		d.metrics.Gauge(metrics.StateBytes, float64(bytes))
+		d.metrics.Gauge(metrics.UnmountedCount, float64(unmounted))
+	}()
+
+	over := func() bool {
+		return (limits.MaxUnmountedVolumes > 0 && unmounted > limits.MaxUnmountedVolumes) ||
+			(limits.MaxStateBytes > 0 && bytes > limits.MaxStateBytes)
+	}
+	if !over() {
+		return nil
+	}
+
+	pruned := []string{}
+	for _, name := range d.pruneCandidates() {
+		if !over() {
+			break
+		}
+
+		logger.Info("volume-pruned", lager.Data{"volume": name, "state-bytes": bytes, "unmounted": unmounted, "limits": limits})
+
+		bytes -= d.stateSizes[name]
+		unmounted--
+		delete(d.volumes, name)
+		d.volumeLimiter.Forget(name)
+		d.metrics.Count(metrics.StatePruned, 1)
+		d.publish(EventPruned, name, nil)
+		pruned = append(pruned, name)
+
+		if err := d.removeVolumeState(driverhttp.EnvWithLogger(logger, env), name); err != nil {
+			logger.Error("remove-volume-state-failed", err, lager.Data{"volume": name})
+		}
+	}
+
+	if over() {
+		// mounted volumes alone exceed the limits
+		logger.Info("state-limits-exceeded", lager.Data{"state-bytes": bytes, "unmounted": unmounted, "limits": limits})
+	}
+	if len(pruned) > 0 {
+		d.emitVolumeGauges()
+	}
+	return pruned
+}
+
+// pruneCandidates must be called with volumesLock held. It returns the
+// volumes that are not mounted in the order they are pruned.
+func (d *VolumeDriver) pruneCandidates() []string {
+	names := []string{}
+	for name, volume := range d.volumes {
+		if volume.MountCount < 1 {
+			names = append(names, name)
+		}
+	}
+
+	// volumes created by older drivers that were never mounted go first
+	lastUsed := func(name string) time.Time {
+		used := time.Time{}
+		for _, at := range []*time.Time{d.volumes[name].CreatedAt, d.volumes[name].LastMountedAt} {
+			if at != nil && at.After(used) {
+				used = *at
+			}
+		}
+		return used
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := lastUsed(names[i]), lastUsed(names[j])
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
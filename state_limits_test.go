@@ -0,0 +1,105 @@
+package volumedriver_test
+
+import (
+	"context"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/metrics"
+	"code.cloudfoundry.org/volumedriver/testhelpers"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("State limits", func() {
+	var (
+		logger      *lagertest.TestLogger
+		env         dockerdriver.Env
+		fakeEmitter *volumedriverfakes.FakeEmitter
+		options     volumedriver.Options
+		driver      *testhelpers.MemoryDriver
+	)
+
+	create := func(name string) {
+		Expect(driver.Create(env, dockerdriver.CreateRequest{
+			Name: name,
+			Opts: map[string]interface{}{"source": "server:/" + name},
+		}).Err).To(BeEmpty())
+	}
+
+	gauge := func(name string) float64 {
+		value := -1.0
+		for i := 0; i < fakeEmitter.GaugeCallCount(); i++ {
+			if gaugeName, gaugeValue, _ := fakeEmitter.GaugeArgsForCall(i); gaugeName == name {
+				value = gaugeValue
+			}
+		}
+		return value
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("state-limits")
+		env = driverhttp.NewHttpDriverEnv(logger, context.TODO())
+		fakeEmitter = &volumedriverfakes.FakeEmitter{}
+
+		options = volumedriver.DefaultOptions()
+		options.MetricsEmitter = fakeEmitter
+		driver = testhelpers.NewMemoryDriverWithOptions(logger, options)
+
+		for _, name := range []string{"first", "second", "third", "mounted"} {
+			create(name)
+		}
+		Expect(driver.Mount(env, dockerdriver.MountRequest{Name: "mounted"}).Err).To(BeEmpty())
+	})
+
+	It("tracks the size of the state and the volumes that are not mounted", func() {
+		bytes, unmounted := driver.StateSize()
+		Expect(bytes).To(BeNumerically(">", 0))
+		Expect(unmounted).To(Equal(3))
+
+		Expect(driver.PruneState(env)).To(BeEmpty())
+		Expect(gauge(metrics.StateBytes)).To(Equal(float64(bytes)))
+		Expect(gauge(metrics.UnmountedCount)).To(Equal(float64(3)))
+	})
+
+	It("prunes the least recently created volumes past the unmounted limit", func() {
+		policy := options.Policy()
+		policy.StateLimits.MaxUnmountedVolumes = 1
+		Expect(driver.UpdatePolicy(env, policy)).To(Succeed())
+
+		Expect(driver.PruneState(env)).To(Equal([]string{"first", "second"}))
+		Expect(driver.Get(env, dockerdriver.GetRequest{Name: "first"}).Err).To(Equal("Volume not found"))
+		Expect(driver.Get(env, dockerdriver.GetRequest{Name: "third"}).Err).To(BeEmpty())
+		Expect(driver.FS.Exists(testhelpers.MountPathRoot + "/driver-state.d/first.json")).To(BeFalse())
+		pruned := int64(0)
+		for i := 0; i < fakeEmitter.CountCallCount(); i++ {
+			if name, delta, _ := fakeEmitter.CountArgsForCall(i); name == metrics.StatePruned {
+				pruned += delta
+			}
+		}
+		Expect(pruned).To(Equal(int64(2)))
+		Expect(gauge(metrics.UnmountedCount)).To(Equal(float64(1)))
+
+		driver = driver.Restart(logger)
+		_, unmounted := driver.StateSize()
+		Expect(unmounted).To(Equal(1))
+	})
+
+	It("prunes volumes that are not mounted past the size limit, but not the mounted ones", func() {
+		policy := options.Policy()
+		policy.StateLimits.MaxStateBytes = 1
+		Expect(driver.UpdatePolicy(env, policy)).To(Succeed())
+
+		Expect(driver.PruneState(env)).To(Equal([]string{"first", "second", "third"}))
+		Expect(driver.Get(env, dockerdriver.GetRequest{Name: "mounted"}).Volume.MountCount).To(Equal(1))
+
+		bytes, unmounted := driver.StateSize()
+		Expect(bytes).To(BeNumerically(">", 1))
+		Expect(unmounted).To(BeZero())
+		Expect(logger).To(gbytes.Say("state-limits-exceeded"))
+	})
+})
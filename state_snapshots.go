@@ -0,0 +1,323 @@
+package volumedriver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager"
+)
+
+const (
+	// snapshotsDirName holds the snapshots of the state directory of a root,
+	// one directory per snapshot. restoreState skips directories, so the
+	// snapshots are never read as records.
+	snapshotsDirName = "snapshots"
+	// snapshotIDLayout names snapshots by the time they were taken, so that
+	// they sort oldest first.
+	snapshotIDLayout = "20060102T150405.000Z"
+)
+
+// DefaultSnapshotRetention is how many state snapshots are kept when
+// Options.SnapshotRetention is zero.
+const DefaultSnapshotRetention = 24
+
+// StateSnapshot is a copy of the volume records of every mount path root.
+// Records are copied as they are on disk, encrypted if the state is.
+type StateSnapshot struct {
+	ID      string
+	TakenAt time.Time
+	Volumes int
+}
+
+// SnapshotState copies the volume records of every root to a new snapshot
+// and removes the oldest snapshots past Options.SnapshotRetention. Records
+// that wait for a debounced write are copied as they were last written.
+func (d *VolumeDriver) SnapshotState(env dockerdriver.Env) (StateSnapshot, error) {
+	logger := env.Logger().Session("snapshot-state")
+	logger.Info("start")
+	defer logger.Info("end")
+
+	d.volumesLock.RLock()
+	defer d.volumesLock.RUnlock()
+
+	return d.snapshotState(driverhttp.EnvWithLogger(logger, env))
+}
+
+// snapshotState must be called with volumesLock held.
+func (d *VolumeDriver) snapshotState(env dockerdriver.Env) (StateSnapshot, error) {
+	logger := env.Logger()
+
+	// volumesLock may only be held for reading, so concurrent snapshots would
+	// otherwise pick the same ID and write into the same directory
+	d.snapshotsLock.Lock()
+	defer d.snapshotsLock.Unlock()
+
+	// IDs must stay unique and ordered, also for snapshots taken within the
+	// same millisecond, such as the one a restore takes.
+	takenAt := d.time.Now().UTC().Truncate(time.Millisecond)
+	if snapshots := d.listSnapshots(env); len(snapshots) > 0 {
+		if latest := snapshots[len(snapshots)-1].TakenAt; !takenAt.After(latest) {
+			takenAt = latest.Add(time.Millisecond)
+		}
+	}
+	snapshot := StateSnapshot{ID: takenAt.Format(snapshotIDLayout), TakenAt: takenAt}
+
+	for _, root := range d.roots() {
+		stateDir, err := d.stateDir(env, root)
+		if err != nil {
+			return StateSnapshot{}, err
+		}
+		copied, err := d.copyRecords(stateDir, filepath.Join(stateDir, snapshotsDirName, snapshot.ID))
+		if err != nil {
+			logger.Error("copy-records-failed", err, lager.Data{"state-dir": stateDir})
+			return StateSnapshot{}, err
+		}
+		snapshot.Volumes += copied
+	}
+	logger.Info("snapshot-taken", lager.Data{"id": snapshot.ID, "volumes": snapshot.Volumes})
+
+	d.pruneSnapshots(env)
+	return snapshot, nil
+}
+
+// ListSnapshots returns the state snapshots, newest first.
+func (d *VolumeDriver) ListSnapshots(env dockerdriver.Env) ListSnapshotsResponse {
+	logger := env.Logger().Session("list-snapshots")
+	logger.Info("start")
+	defer logger.Info("end")
+
+	d.volumesLock.RLock()
+	defer d.volumesLock.RUnlock()
+
+	snapshots := d.listSnapshots(driverhttp.EnvWithLogger(logger, env))
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].ID > snapshots[j].ID })
+	return ListSnapshotsResponse{Snapshots: snapshots}
+}
+
+// RestoreSnapshot replaces the volume records of every root with those of a
+// snapshot and restores the driver from them, as if it had restarted. The
+// state it replaces is snapshotted first, so that a restore can be undone.
+// Mounted volumes would lose track of their mounts, so the restore is
+// refused while there are any, unless it is forced.
+func (d *VolumeDriver) RestoreSnapshot(env dockerdriver.Env, restoreRequest RestoreSnapshotRequest) dockerdriver.ErrorResponse {
+	logger := env.Logger().Session("restore-snapshot", lager.Data{"id": restoreRequest.ID})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	if restoreRequest.ID == "" {
+		return dockerdriver.ErrorResponse{Err: "Missing mandatory 'id'"}
+	}
+
+	err := func() error {
+		d.volumesLock.Lock()
+		defer d.volumesLock.Unlock()
+
+		env := driverhttp.EnvWithLogger(logger, env)
+		found := false
+		for _, snapshot := range d.listSnapshots(env) {
+			found = found || snapshot.ID == restoreRequest.ID
+		}
+		if !found {
+			return fmt.Errorf("snapshot '%s' not found", restoreRequest.ID)
+		}
+
+		mounted := 0
+		for _, volume := range d.volumes {
+			if volume.MountCount > 0 {
+				mounted++
+			}
+		}
+		if mounted > 0 && !restoreRequest.Force {
+			return fmt.Errorf("%d volumes are mounted, unmount them or force the restore", mounted)
+		}
+
+		if _, err := d.snapshotState(env); err != nil {
+			return fmt.Errorf("snapshot of the current state failed: %s", err)
+		}
+
+		for _, root := range d.roots() {
+			stateDir, err := d.stateDir(env, root)
+			if err != nil {
+				return err
+			}
+			if err := d.removeRecords(stateDir); err != nil {
+				return err
+			}
+			if _, err := d.copyRecords(filepath.Join(stateDir, snapshotsDirName, restoreRequest.ID), stateDir); err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+	if err != nil {
+		logger.Error("restore-failed", err)
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+
+	d.restoreState(driverhttp.EnvWithLogger(logger, env))
+
+	d.volumesLock.Lock()
+	d.emitVolumeGauges()
+	d.volumesLock.Unlock()
+
+	return dockerdriver.ErrorResponse{}
+}
+
+// listSnapshots must be called with volumesLock held. A snapshot lists the
+// records of every root it was taken of.
+func (d *VolumeDriver) listSnapshots(env dockerdriver.Env) []StateSnapshot {
+	logger := env.Logger().Session("list-snapshots")
+
+	snapshots := map[string]*StateSnapshot{}
+	for _, root := range d.roots() {
+		snapshotsDir := filepath.Join(root, stateDirName, snapshotsDirName)
+		entries, err := d.ioutil.ReadDir(snapshotsDir)
+		if err != nil {
+			logger.Debug("failed-to-read-snapshots-dir", lager.Data{"err": err, "snapshots-dir": snapshotsDir})
+			continue
+		}
+
+		for _, entry := range entries {
+			takenAt, ok := parseSnapshotID(entry.Name())
+			if !entry.IsDir() || !ok {
+				continue
+			}
+			if snapshots[entry.Name()] == nil {
+				snapshots[entry.Name()] = &StateSnapshot{ID: entry.Name(), TakenAt: takenAt}
+			}
+
+			records, err := d.ioutil.ReadDir(filepath.Join(snapshotsDir, entry.Name()))
+			if err != nil {
+				logger.Error("failed-to-read-snapshot", err, lager.Data{"snapshot": entry.Name()})
+				continue
+			}
+			for _, record := range records {
+				if isRecordFile(record) {
+					snapshots[entry.Name()].Volumes++
+				}
+			}
+		}
+	}
+
+	list := []StateSnapshot{}
+	for _, snapshot := range snapshots {
+		list = append(list, *snapshot)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// pruneSnapshots must be called with volumesLock held. It removes the
+// oldest snapshots past the retention.
+func (d *VolumeDriver) pruneSnapshots(env dockerdriver.Env) {
+	logger := env.Logger().Session("prune-snapshots")
+
+	retention := d.options.SnapshotRetention
+	if retention <= 0 {
+		retention = DefaultSnapshotRetention
+	}
+
+	snapshots := d.listSnapshots(env)
+	for len(snapshots) > retention {
+		id := snapshots[0].ID
+		snapshots = snapshots[1:]
+
+		for _, root := range d.roots() {
+			snapshotDir := filepath.Join(root, stateDirName, snapshotsDirName, id)
+			if err := d.removeRecords(snapshotDir); err != nil && !os.IsNotExist(err) {
+				logger.Error("failed-to-remove-snapshot", err, lager.Data{"snapshot-dir": snapshotDir})
+				continue
+			}
+			if err := d.os.Remove(snapshotDir); err != nil && !os.IsNotExist(err) {
+				logger.Error("failed-to-remove-snapshot", err, lager.Data{"snapshot-dir": snapshotDir})
+			}
+		}
+		logger.Info("snapshot-removed", lager.Data{"id": id})
+	}
+}
+
+// copyRecords copies the volume records of from, not its intents, to the
+// directory to and returns how many it copied. A missing from has none.
+func (d *VolumeDriver) copyRecords(from, to string) (int, error) {
+	entries, err := d.ioutil.ReadDir(from)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if err := d.os.MkdirAll(to, os.ModePerm); err != nil {
+		return 0, err
+	}
+
+	copied := 0
+	for _, entry := range entries {
+		if !isRecordFile(entry) {
+			continue
+		}
+		data, err := d.ioutil.ReadFile(filepath.Join(from, entry.Name()))
+		if err != nil {
+			return copied, err
+		}
+		if err := d.writeStateFile(filepath.Join(to, entry.Name()), data); err != nil {
+			return copied, err
+		}
+		copied++
+	}
+	return copied, nil
+}
+
+// removeRecords removes the volume records of dir.
+func (d *VolumeDriver) removeRecords(dir string) error {
+	entries, err := d.ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !isRecordFile(entry) {
+			continue
+		}
+		if err := d.os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *VolumeDriver) runSnapshots(env dockerdriver.Env, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := d.SnapshotState(env); err != nil {
+				env.Logger().Error("scheduled-snapshot-failed", err)
+			}
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+func isRecordFile(entry os.FileInfo) bool {
+	return !entry.IsDir() && strings.HasSuffix(entry.Name(), stateFileSuffix)
+}
+
+// parseSnapshotID accepts only the names snapshots are given, so that an ID
+// never names a path outside the snapshots directory.
+func parseSnapshotID(id string) (time.Time, bool) {
+	takenAt, err := time.Parse(snapshotIDLayout, id)
+	if err != nil || takenAt.Format(snapshotIDLayout) != id {
+		return time.Time{}, false
+	}
+	return takenAt, true
+}
@@ -0,0 +1,139 @@
+package volumedriver_test
+
+import (
+	"context"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/testhelpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("State snapshots", func() {
+	var (
+		env     dockerdriver.Env
+		options volumedriver.Options
+		driver  *testhelpers.MemoryDriver
+	)
+
+	create := func(name string) {
+		Expect(driver.Create(env, dockerdriver.CreateRequest{
+			Name: name,
+			Opts: map[string]interface{}{"source": "server:/" + name},
+		}).Err).To(BeEmpty())
+	}
+
+	snapshot := func() volumedriver.StateSnapshot {
+		// snapshots are named by the millisecond they are taken in
+		time.Sleep(2 * time.Millisecond)
+		snapshot, err := driver.SnapshotState(env)
+		Expect(err).NotTo(HaveOccurred())
+		return snapshot
+	}
+
+	volumeNames := func() []string {
+		names := []string{}
+		for _, volume := range driver.List(env).Volumes {
+			names = append(names, volume.Name)
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		logger := lagertest.NewTestLogger("state-snapshots")
+		env = driverhttp.NewHttpDriverEnv(logger, context.TODO())
+
+		options = volumedriver.DefaultOptions()
+		options.SnapshotRetention = 2
+		driver = testhelpers.NewMemoryDriverWithOptions(logger, options)
+
+		create("first")
+		create("second")
+	})
+
+	It("copies the volume records to a snapshot", func() {
+		taken := snapshot()
+		Expect(taken.Volumes).To(Equal(2))
+
+		snapshots := driver.ListSnapshots(env)
+		Expect(snapshots.Err).To(BeEmpty())
+		Expect(snapshots.Snapshots).To(Equal([]volumedriver.StateSnapshot{taken}))
+		Expect(driver.FS.Exists(testhelpers.MountPathRoot + "/driver-state.d/snapshots/" + taken.ID + "/first.json")).To(BeTrue())
+	})
+
+	It("keeps the newest snapshots up to the retention", func() {
+		snapshot()
+		second := snapshot()
+		third := snapshot()
+
+		Expect(driver.ListSnapshots(env).Snapshots).To(Equal([]volumedriver.StateSnapshot{third, second}))
+	})
+
+	It("gives snapshots taken at the same time their own IDs", func() {
+		ids := make(chan string, 2)
+		for i := 0; i < 2; i++ {
+			go func() {
+				defer GinkgoRecover()
+				taken, err := driver.SnapshotState(env)
+				Expect(err).NotTo(HaveOccurred())
+				ids <- taken.ID
+			}()
+		}
+
+		first, second := <-ids, <-ids
+		Expect(first).NotTo(Equal(second))
+		Expect(driver.ListSnapshots(env).Snapshots).To(HaveLen(2))
+	})
+
+	Describe("RestoreSnapshot", func() {
+		var taken volumedriver.StateSnapshot
+
+		BeforeEach(func() {
+			taken = snapshot()
+			Expect(driver.Remove(env, dockerdriver.RemoveRequest{Name: "first"}).Err).To(BeEmpty())
+			create("third")
+		})
+
+		It("restores the volumes of the snapshot", func() {
+			Expect(driver.RestoreSnapshot(env, volumedriver.RestoreSnapshotRequest{ID: taken.ID}).Err).To(BeEmpty())
+			Expect(volumeNames()).To(ConsistOf("first", "second"))
+
+			restarted := driver.Restart(lagertest.NewTestLogger("state-snapshots"))
+			Expect(restarted.List(env).Volumes).To(HaveLen(2))
+		})
+
+		It("snapshots the state it replaces", func() {
+			Expect(driver.RestoreSnapshot(env, volumedriver.RestoreSnapshotRequest{ID: taken.ID}).Err).To(BeEmpty())
+
+			snapshots := driver.ListSnapshots(env).Snapshots
+			Expect(snapshots).To(HaveLen(2))
+			Expect(snapshots[0].Volumes).To(Equal(2))
+			Expect(snapshots[1]).To(Equal(taken))
+		})
+
+		It("fails for unknown snapshots", func() {
+			for _, id := range []string{"20200101T000000.000Z", "../driver-state.d", ""} {
+				Expect(driver.RestoreSnapshot(env, volumedriver.RestoreSnapshotRequest{ID: id}).Err).NotTo(BeEmpty(), id)
+			}
+			Expect(volumeNames()).To(ConsistOf("second", "third"))
+		})
+
+		Context("when volumes are mounted", func() {
+			BeforeEach(func() {
+				Expect(driver.Mount(env, dockerdriver.MountRequest{Name: "third"}).Err).To(BeEmpty())
+			})
+
+			It("refuses to restore unless forced", func() {
+				Expect(driver.RestoreSnapshot(env, volumedriver.RestoreSnapshotRequest{ID: taken.ID}).Err).To(Equal("1 volumes are mounted, unmount them or force the restore"))
+				Expect(volumeNames()).To(ConsistOf("second", "third"))
+
+				Expect(driver.RestoreSnapshot(env, volumedriver.RestoreSnapshotRequest{ID: taken.ID, Force: true}).Err).To(BeEmpty())
+				Expect(volumeNames()).To(ConsistOf("first", "second"))
+			})
+		})
+	})
+})
@@ -0,0 +1,118 @@
+package volumedriver
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+)
+
+// StateVersion is the schema version of the volume records this driver
+// writes. Records of older versions are migrated when the state is
+// restored. Records of newer versions, written by a later driver, are
+// neither loaded nor touched, so that a downgrade does not misread or
+// overwrite them.
+//
+// Version 0 are the records written before versioning, including the
+// entries of the legacy driver-state.json.
+const StateVersion = 1
+
+// stateMigrations[n] migrates a record of version n to version n+1. Records
+// are migrated as decoded JSON, so that fields which have been renamed or
+// dropped from NfsVolumeInfo can still be read.
+var stateMigrations = []func(record map[string]interface{}) error{
+	migrateStateV0,
+}
+
+// migrateStateV0 fills in the name of records that only had it as their key
+// in driver-state.json.
+func migrateStateV0(record map[string]interface{}) error {
+	if name, _ := record["Name"].(string); name != "" {
+		return nil
+	}
+	key, _ := record[legacyKeyField].(string)
+	if key == "" {
+		return fmt.Errorf("record has no name")
+	}
+	record["Name"] = key
+	return nil
+}
+
+// legacyKeyField carries the key of a driver-state.json entry into its
+// migration. It is not part of the persisted records.
+const legacyKeyField = "legacy-key"
+
+// stateTooNewError is returned for records written by a later driver.
+type stateTooNewError struct {
+	Version int
+}
+
+func (e stateTooNewError) Error() string {
+	return fmt.Sprintf("state version %d is newer than the supported version %d", e.Version, StateVersion)
+}
+
+// decodeVolumeState decodes a persisted volume record, migrating it to
+// StateVersion first. It returns whether the record was migrated and must
+// be rewritten.
+func decodeVolumeState(data []byte, legacyKey string) (*NfsVolumeInfo, bool, error) {
+	record := map[string]interface{}{}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, false, err
+	}
+
+	version := 0
+	if value, ok := record["StateVersion"]; ok {
+		number, ok := value.(float64)
+		if !ok || number < 0 || number != float64(int(number)) {
+			return nil, false, fmt.Errorf("invalid state version '%v'", value)
+		}
+		version = int(number)
+	}
+	if version > StateVersion {
+		return nil, false, stateTooNewError{Version: version}
+	}
+
+	if legacyKey != "" {
+		record[legacyKeyField] = legacyKey
+	}
+	for v := version; v < StateVersion; v++ {
+		if err := stateMigrations[v](record); err != nil {
+			return nil, false, fmt.Errorf("migrating state version %d: %s", v, err.Error())
+		}
+	}
+	delete(record, legacyKeyField)
+	record["StateVersion"] = StateVersion
+
+	migrated, err := json.Marshal(record)
+	if err != nil {
+		return nil, false, err
+	}
+
+	volume := &NfsVolumeInfo{}
+	if err := json.Unmarshal(migrated, volume); err != nil {
+		return nil, false, err
+	}
+	if volume.Name == "" {
+		return nil, false, fmt.Errorf("record has no name")
+	}
+
+	return volume, version < StateVersion, nil
+}
+
+// migrateState must be called with volumesLock held. It rewrites the records
+// of volumes that were persisted with an older state version.
+func (d *VolumeDriver) migrateState(env dockerdriver.Env, volumeNames []string) {
+	logger := env.Logger().Session("migrate-state")
+	logger.Info("start", lager.Data{"volumes": len(volumeNames), "version": StateVersion})
+	defer logger.Info("end")
+
+	for _, name := range volumeNames {
+		if _, ok := d.volumes[name]; !ok {
+			continue
+		}
+		if err := d.persistVolume(env, name); err != nil {
+			logger.Error("failed-to-migrate-volume", err, lager.Data{"volume": name})
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package volumedriver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+)
+
+const (
+	// exportsDirName holds the shared mounts of the exports that subdir
+	// volumes are bound from.
+	exportsDirName = "driver-exports.d"
+
+	defaultSubdirMode os.FileMode = 0755
+)
+
+// subdirOptions are the validated subdir opts of a volume. uid and gid are -1
+// when the owner is left alone.
+type subdirOptions struct {
+	subdir string
+	mode   os.FileMode
+	uid    int
+	gid    int
+}
+
+func parseSubdirOpts(opts map[string]interface{}) (subdirOptions, error) {
+	options := subdirOptions{mode: defaultSubdirMode, uid: -1, gid: -1}
+
+	value, ok := opts[SubdirOpt]
+	if !ok {
+		return options, nil
+	}
+
+	subdir, _ := value.(string)
+	cleaned := path.Clean("/" + subdir)
+	if subdir == "" || cleaned == "/" || cleaned != "/"+strings.Trim(subdir, "/") {
+		return options, fmt.Errorf("invalid subdir '%v', must be a relative path below the export", value)
+	}
+	options.subdir = strings.TrimPrefix(cleaned, "/")
+
+	if value, ok := opts[SubdirModeOpt]; ok {
+		mode, err := strconv.ParseUint(fmt.Sprintf("%v", value), 8, 32)
+		if err != nil || mode > 0777 {
+			return options, fmt.Errorf("invalid subdir_mode '%v', must be an octal mode such as 0750", value)
+		}
+		options.mode = os.FileMode(mode)
+	}
+
+	for _, id := range []struct {
+		opt   string
+		value *int
+	}{{SubdirUIDOpt, &options.uid}, {SubdirGIDOpt, &options.gid}} {
+		if value, ok := opts[id.opt]; ok {
+			parsed, err := strconv.Atoi(fmt.Sprintf("%v", value))
+			if err != nil || parsed < 0 {
+				return options, fmt.Errorf("invalid %s '%v', must be a numeric id", id.opt, value)
+			}
+			*id.value = parsed
+		}
+	}
+
+	return options, nil
+}
+
+// exportMountPath returns where the export of a subdir volume is mounted.
+// Volumes with the same driver, source and mount opts share the mount.
+func (d *VolumeDriver) exportMountPath(env dockerdriver.Env, driver string, opts map[string]interface{}) string {
+	key, _ := json.Marshal(struct {
+		Driver string
+		Opts   map[string]interface{}
+	}{driver, mounterOpts(opts)})
+
+	sum := sha256.Sum256(key)
+	return filepath.Join(d.mountPath(env, exportsDirName), hex.EncodeToString(sum[:])[:mountDirectoryHashChars])
+}
+
+// volumeMounter returns the Mounter for a volume. Subdir volumes get one
+// that binds their directory from the shared mount of the export, composite
+// volumes one that mounts their members below it, scratch volumes one
+// that layers an overlay over it, and relabeled volumes one that labels
+// the mountpoint.
+func (d *VolumeDriver) volumeMounter(volume *NfsVolumeInfo) Mounter {
+	if volume == nil {
+		return d.mounter
+	}
+
+	mounter := d.mounterFor(volume.Driver)
+	if volume.Subdir != "" && d.options.BindMounter != nil {
+		// opts, and with them mode and owner, are not known after a restart;
+		// they only matter when the directory is created
+		options, _ := parseSubdirOpts(volume.Opts)
+		options.subdir = volume.Subdir
+
+		mounter = &subdirMounter{
+			driver:        d,
+			exportMounter: mounter,
+			exportPath:    volume.ExportMount,
+			options:       options,
+		}
+	}
+
+	if len(volume.Composite) > 0 {
+		mounter = &compositeMounter{
+			driver:  d,
+			mounter: mounter,
+			members: volume.Composite,
+		}
+	}
+
+	if volume.Scratch && d.options.OverlayMounter != nil && d.options.ScratchDir != "" {
+		mounter = &scratchMounter{
+			driver:       d,
+			lowerMounter: mounter,
+		}
+	}
+
+	if volume.Relabel != "" && d.options.Labeler != nil {
+		mounter = &labeledMounter{
+			Mounter: mounter,
+			labeler: d.options.Labeler,
+			label:   volume.Relabel,
+		}
+	}
+
+	if volume.IOLimits != nil && d.options.IOThrottler != nil {
+		mounter = &throttledMounter{
+			Mounter:   mounter,
+			throttler: d.options.IOThrottler,
+			limits:    *volume.IOLimits,
+		}
+	}
+
+	return mounter
+}
+
+// restoreExports must be called with volumesLock held.
+func (d *VolumeDriver) restoreExports() {
+	d.exportsLock.Lock()
+	defer d.exportsLock.Unlock()
+
+	for _, volume := range d.volumes {
+		if volume.ExportMount != "" && volume.Mountpoint != "" && volume.MountCount > 0 {
+			d.addExportUser(volume.ExportMount, volume.Mountpoint)
+		}
+	}
+}
+
+// addExportUser must be called with exportsLock held.
+func (d *VolumeDriver) addExportUser(exportPath, target string) {
+	if d.exportUsers[exportPath] == nil {
+		d.exportUsers[exportPath] = map[string]bool{}
+	}
+	d.exportUsers[exportPath][target] = true
+}
+
+type subdirMounter struct {
+	driver        *VolumeDriver
+	exportMounter Mounter
+	exportPath    string
+	options       subdirOptions
+}
+
+func (m *subdirMounter) Mount(env dockerdriver.Env, source string, target string, opts map[string]interface{}) error {
+	logger := env.Logger().Session("subdir-mount", lager.Data{"source": source, "subdir": m.options.subdir, "target": target})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	if err := m.acquireExport(env, source, target, opts); err != nil {
+		return err
+	}
+
+	dir := filepath.Join(m.exportPath, filepath.FromSlash(m.options.subdir))
+	if err := m.ensureDir(env, dir); err != nil {
+		m.releaseExport(env, target)
+		return err
+	}
+
+	if err := m.driver.options.BindMounter.Mount(env, dir, target, map[string]interface{}{}); err != nil {
+		logger.Error("bind-mount-failed", err)
+		m.releaseExport(env, target)
+		return err
+	}
+
+	return nil
+}
+
+func (m *subdirMounter) Unmount(env dockerdriver.Env, target string) error {
+	if err := m.driver.options.BindMounter.Unmount(env, target); err != nil {
+		return err
+	}
+
+	m.releaseExport(env, target)
+	return nil
+}
+
+func (m *subdirMounter) Check(env dockerdriver.Env, name, mountPoint string, depth CheckDepth) bool {
+	return m.driver.options.BindMounter.Check(env, name, mountPoint, depth)
+}
+
+func (m *subdirMounter) Purge(env dockerdriver.Env, path string) {
+	m.driver.options.BindMounter.Purge(env, path)
+}
+
+// acquireExport mounts the export unless it is mounted already, and records
+// target as one of its users.
+func (m *subdirMounter) acquireExport(env dockerdriver.Env, source, target string, opts map[string]interface{}) error {
+	logger := env.Logger().Session("acquire-export", lager.Data{"export-path": m.exportPath})
+
+	d := m.driver
+	d.exportsLock.Lock()
+	defer d.exportsLock.Unlock()
+
+	mounted, err := d.mountChecker.Exists(m.exportPath)
+	if err != nil {
+		logger.Error("failed-proc-mounts-check", err)
+		return err
+	}
+
+	if !mounted {
+		if err := d.os.MkdirAll(m.exportPath, os.ModePerm); err != nil {
+			logger.Error("create-export-dir-failed", err)
+			return err
+		}
+
+		if err := m.exportMounter.Mount(env, source, m.exportPath, opts); err != nil {
+			logger.Error("mount-export-failed", err)
+			d.os.Remove(m.exportPath)
+			return err
+		}
+	}
+
+	d.addExportUser(m.exportPath, target)
+	return nil
+}
+
+// releaseExport unmounts the export once its last user is gone.
+func (m *subdirMounter) releaseExport(env dockerdriver.Env, target string) {
+	logger := env.Logger().Session("release-export", lager.Data{"export-path": m.exportPath})
+
+	d := m.driver
+	d.exportsLock.Lock()
+	defer d.exportsLock.Unlock()
+
+	delete(d.exportUsers[m.exportPath], target)
+	if len(d.exportUsers[m.exportPath]) > 0 {
+		return
+	}
+	delete(d.exportUsers, m.exportPath)
+
+	if err := m.exportMounter.Unmount(env, m.exportPath); err != nil {
+		logger.Error("unmount-export-failed", err)
+		return
+	}
+	if err := d.os.Remove(m.exportPath); err != nil {
+		logger.Error("remove-export-dir-failed", err)
+	}
+}
+
+// ensureDir creates the subdirectory with the requested mode and owner.
+// Existing directories are left as they are.
+func (m *subdirMounter) ensureDir(env dockerdriver.Env, dir string) error {
+	logger := env.Logger().Session("ensure-subdir", lager.Data{"dir": dir})
+
+	d := m.driver
+	if _, err := d.os.Stat(dir); err == nil {
+		return nil
+	}
+
+	orig := d.osHelper.Umask(000)
+	defer d.osHelper.Umask(orig)
+
+	if err := d.os.MkdirAll(dir, m.options.mode); err != nil {
+		logger.Error("create-subdir-failed", err)
+		return safeerrors.Wrap(err, safeerrors.PermissionDenied, "unable to create subdir '%s' on the export", m.options.subdir)
+	}
+
+	if m.options.uid >= 0 || m.options.gid >= 0 {
+		if err := d.os.Chown(dir, m.options.uid, m.options.gid); err != nil {
+			logger.Error("chown-subdir-failed", err)
+			return safeerrors.Wrap(err, safeerrors.PermissionDenied, "unable to change the owner of subdir '%s'", m.options.subdir)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,19 @@
+package syscallmounter
+
+// Mount flags understood by the kernel. They are spelled out here because the
+// syscall package only defines them on linux.
+const (
+	msRdonly = 0x1
+	msNosuid = 0x2
+	msNodev  = 0x4
+	msNoexec = 0x8
+
+	mntForce  = 0x1
+	mntDetach = 0x2
+)
+
+//go:generate counterfeiter -o ../volumedriverfakes/fake_mount_syscall.go . MountSyscall
+type MountSyscall interface {
+	Mount(source string, target string, fstype string, flags uintptr, data string) error
+	Unmount(target string, flags int) error
+}
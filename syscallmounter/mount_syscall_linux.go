@@ -0,0 +1,20 @@
+// +build linux
+
+package syscallmounter
+
+import "syscall"
+
+type mountSyscall struct{}
+
+// NewMountSyscall returns a MountSyscall backed by mount(2) and umount2(2).
+func NewMountSyscall() MountSyscall {
+	return &mountSyscall{}
+}
+
+func (*mountSyscall) Mount(source string, target string, fstype string, flags uintptr, data string) error {
+	return syscall.Mount(source, target, fstype, flags, data)
+}
+
+func (*mountSyscall) Unmount(target string, flags int) error {
+	return syscall.Unmount(target, flags)
+}
@@ -0,0 +1,23 @@
+// +build !linux
+
+package syscallmounter
+
+import "errors"
+
+var errUnsupported = errors.New("mount syscall is only supported on linux")
+
+type mountSyscall struct{}
+
+// NewMountSyscall returns a MountSyscall that fails every call, since only
+// linux mounts nfs through mount(2) with a text data string.
+func NewMountSyscall() MountSyscall {
+	return &mountSyscall{}
+}
+
+func (*mountSyscall) Mount(source string, target string, fstype string, flags uintptr, data string) error {
+	return errUnsupported
+}
+
+func (*mountSyscall) Unmount(target string, flags int) error {
+	return errUnsupported
+}
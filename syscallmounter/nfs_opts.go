@@ -0,0 +1,152 @@
+package syscallmounter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+)
+
+const (
+	maxNconnect = 16
+	// maxTimeo is ten minutes, in the tenths of a second of the timeo opt.
+	maxTimeo       = 6000
+	minIOSize      = 1024
+	maxIOSize      = 1048576
+	defaultTimeo   = 600
+	defaultRetrans = 2
+)
+
+// versionDefaults are the transport opts a mount gets for the version it
+// asks for, unless it sets them itself. They follow nfs(5) for tcp, and
+// raise the transfer sizes to what current servers offer; the kernel still
+// lowers them to the limits of the server. Mounts that leave the version to
+// the kernel keep its defaults.
+var versionDefaults = map[string]map[string]interface{}{
+	"":    {},
+	"3":   {"timeo": defaultTimeo, "retrans": defaultRetrans, "rsize": 65536, "wsize": 65536},
+	"4":   {"timeo": defaultTimeo, "retrans": defaultRetrans, "rsize": maxIOSize, "wsize": maxIOSize},
+	"4.0": {"timeo": defaultTimeo, "retrans": defaultRetrans, "rsize": maxIOSize, "wsize": maxIOSize},
+	"4.1": {"timeo": defaultTimeo, "retrans": defaultRetrans, "rsize": maxIOSize, "wsize": maxIOSize},
+	"4.2": {"timeo": defaultTimeo, "retrans": defaultRetrans, "rsize": maxIOSize, "wsize": maxIOSize},
+}
+
+// transportOpts validates the version and transport opts and returns a copy
+// of opts with the defaults of the version added. Other opts are passed to
+// the kernel as they are.
+func transportOpts(opts map[string]interface{}) (map[string]interface{}, error) {
+	version, err := nfsVersion(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range []string{"proto", "mountproto"} {
+		value, ok := opts[key]
+		if !ok {
+			continue
+		}
+		proto := strings.TrimSuffix(fmt.Sprintf("%v", value), "6")
+		if proto != "tcp" && proto != "udp" && proto != "rdma" {
+			return nil, safeerrors.New(safeerrors.InvalidOption, "invalid %s '%v', must be tcp, udp or rdma", key, value)
+		}
+		if key == "proto" && proto == "udp" && strings.HasPrefix(version, "4") {
+			return nil, safeerrors.New(safeerrors.InvalidOption, "nfs version %s does not support proto '%v'", version, value)
+		}
+	}
+
+	limits := []struct {
+		key      string
+		min, max int
+	}{
+		{"nconnect", 1, maxNconnect},
+		{"timeo", 1, maxTimeo},
+		{"retrans", 0, -1},
+		{"rsize", minIOSize, maxIOSize},
+		{"wsize", minIOSize, maxIOSize},
+	}
+	// JSON numbers arrive as float64, which the data string would spell in
+	// exponent notation
+	numbers := map[string]int{}
+	for _, limit := range limits {
+		value, ok := opts[limit.key]
+		if !ok {
+			continue
+		}
+		n, ok := intValue(value)
+		if !ok || n < limit.min || (limit.max >= 0 && n > limit.max) {
+			if limit.max < 0 {
+				return nil, safeerrors.New(safeerrors.InvalidOption, "invalid %s '%v', must be a number of at least %d", limit.key, value, limit.min)
+			}
+			return nil, safeerrors.New(safeerrors.InvalidOption, "invalid %s '%v', must be a number from %d to %d", limit.key, value, limit.min, limit.max)
+		}
+		if (limit.key == "rsize" || limit.key == "wsize") && n%minIOSize != 0 {
+			return nil, safeerrors.New(safeerrors.InvalidOption, "invalid %s '%v', must be a multiple of %d", limit.key, value, minIOSize)
+		}
+		numbers[limit.key] = n
+	}
+
+	if _, ok := opts["nconnect"]; ok && strings.HasPrefix(fmt.Sprintf("%v", opts["proto"]), "udp") {
+		return nil, safeerrors.New(safeerrors.InvalidOption, "the nconnect opt requires a connection oriented proto")
+	}
+
+	withDefaults := map[string]interface{}{}
+	for key, value := range versionDefaults[version] {
+		withDefaults[key] = value
+	}
+	// timeo is in tenths of a second for tcp and rdma, but the first of
+	// several exponentially growing retries for udp, see nfs(5)
+	if strings.HasPrefix(fmt.Sprintf("%v", opts["proto"]), "udp") {
+		delete(withDefaults, "timeo")
+	}
+	for key, value := range opts {
+		withDefaults[key] = value
+	}
+	for key, n := range numbers {
+		withDefaults[key] = n
+	}
+	return withDefaults, nil
+}
+
+// nfsVersion returns the version of the vers or nfsvers opt, or the empty
+// string when the kernel negotiates it.
+func nfsVersion(opts map[string]interface{}) (string, error) {
+	version := ""
+	for _, key := range []string{"vers", "nfsvers"} {
+		value, ok := opts[key]
+		if !ok {
+			continue
+		}
+
+		v := fmt.Sprintf("%v", value)
+		if f, ok := value.(float64); ok {
+			v = strconv.FormatFloat(f, 'f', -1, 64)
+		}
+		if _, known := versionDefaults[v]; !known || v == "" {
+			return "", safeerrors.New(safeerrors.InvalidOption, "invalid %s '%v', must be 3, 4, 4.0, 4.1 or 4.2", key, value)
+		}
+		if version != "" && version != v {
+			return "", safeerrors.New(safeerrors.InvalidOption, "the vers and nfsvers opts disagree")
+		}
+		version = v
+	}
+	return version, nil
+}
+
+// intValue accepts integers, also as the float64 of a JSON number, and
+// decimal strings.
+func intValue(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case float64:
+		if v != float64(int(v)) {
+			return 0, false
+		}
+		return int(v), true
+	case string:
+		n, err := strconv.Atoi(v)
+		return n, err == nil
+	}
+	return 0, false
+}
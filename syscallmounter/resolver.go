@@ -0,0 +1,14 @@
+package syscallmounter
+
+import (
+	"context"
+	"net"
+)
+
+//go:generate counterfeiter -o ../volumedriverfakes/fake_resolver.go . Resolver
+
+// Resolver is satisfied by *net.Resolver. The kernel nfs client only accepts
+// server addresses, so host names are resolved before mounting.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
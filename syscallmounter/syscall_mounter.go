@@ -0,0 +1,340 @@
+package syscallmounter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/goshims/ioutilshim"
+	"code.cloudfoundry.org/goshims/osshim"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/mountchecker"
+	"code.cloudfoundry.org/volumedriver/mounthelper"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+)
+
+const fsType = "nfs"
+
+// addrOpt, mountAddrOpt and clientAddrOpt hold addresses, which the kernel
+// expects without the brackets of an IPv6 source.
+const (
+	addrOpt       = "addr"
+	mountAddrOpt  = "mountaddr"
+	clientAddrOpt = "clientaddr"
+)
+
+// protoOpts name a transport, which the kernel refuses unless it matches the
+// family of the server address, such as tcp6 for an IPv6 server.
+var protoOpts = map[string]bool{
+	"proto":      true,
+	"mountproto": true,
+}
+
+// flagOpts are translated to mount flags instead of being passed to the nfs
+// client in the data string.
+var flagOpts = map[string]uintptr{
+	"ro":       msRdonly,
+	"readonly": msRdonly,
+	"nosuid":   msNosuid,
+	"nodev":    msNodev,
+	"noexec":   msNoexec,
+}
+
+// contextOpts hold SELinux contexts, whose MLS ranges such as s0:c1,c2 are
+// quoted so that their commas do not split the data string.
+var contextOpts = map[string]bool{
+	"context":     true,
+	"fscontext":   true,
+	"defcontext":  true,
+	"rootcontext": true,
+}
+
+type syscallMounter struct {
+	syscall      MountSyscall
+	resolver     Resolver
+	os           osshim.Os
+	ioutil       ioutilshim.Ioutil
+	mountChecker mountchecker.MountChecker
+}
+
+// NewSyscallMounter returns a Mounter that mounts nfs exports with mount(2)
+// directly, so that neither mount.nfs nor the rest of nfs-utils has to be
+// installed. Sources have the form host:/export, with IPv6 addresses in
+// brackets as in [fd00::1]:/export, and every opt except the flag opts is
+// passed to the kernel nfs client. The version and transport opts, such as
+// nconnect, proto, timeo, retrans, rsize and wsize, are validated, and get
+// defaults for the NFS version unless they are set. Host names that resolve to several
+// addresses are tried address by address until one mounts, with the proto
+// and mountproto opts adjusted to the family of each address. The addr opt
+// skips the resolution.
+func NewSyscallMounter(syscall MountSyscall, resolver Resolver, os osshim.Os, ioutil ioutilshim.Ioutil, mountChecker mountchecker.MountChecker) volumedriver.Mounter {
+	return &syscallMounter{
+		syscall:      syscall,
+		resolver:     resolver,
+		os:           os,
+		ioutil:       ioutil,
+		mountChecker: mountChecker,
+	}
+}
+
+func (m *syscallMounter) Mount(env dockerdriver.Env, source string, target string, opts map[string]interface{}) error {
+	logger := env.Logger().Session("syscall-mount", lager.Data{"source": source, "target": target})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	host, export, err := splitSource(source)
+	if err != nil {
+		return err
+	}
+
+	opts, err = transportOpts(opts)
+	if err != nil {
+		logger.Error("invalid-opts", err)
+		return err
+	}
+
+	// the host is resolved again on every mount, so that remounting after a
+	// failover picks up the addresses DNS hands out now, unless the addr opt
+	// names the address to use
+	addrs := []string{}
+	if addr, ok := opts[addrOpt]; ok {
+		addrs = append(addrs, unbracket(fmt.Sprintf("%v", addr)))
+	} else {
+		addrs, err = m.resolve(env.Context(), host)
+		if err != nil {
+			logger.Error("resolve-failed", err, lager.Data{"host": host})
+			return safeerrors.Wrap(err, safeerrors.NotFound, "unable to resolve nfs server '%s'", host)
+		}
+	}
+
+	// try the addresses in the order they were resolved, so that servers
+	// behind round-robin DNS fail over to the next one
+	for i, addr := range addrs {
+		flags, data := mountData(addr, opts)
+		device := fmt.Sprintf("%s:%s", bracketIPv6(addr), export)
+
+		logger.Debug("mounting", lager.Data{"device": device, "flags": flags, "data": data})
+		err = m.syscall.Mount(device, target, fsType, flags, data)
+		if err == nil {
+			return nil
+		}
+
+		logger.Error("mount-failed", err, lager.Data{"addr": addr, "remaining": len(addrs) - i - 1})
+		if env.Context().Err() != nil {
+			break
+		}
+	}
+
+	return fmt.Errorf("mount failed: %w", err)
+}
+
+func (m *syscallMounter) Unmount(env dockerdriver.Env, target string) error {
+	logger := env.Logger().Session("syscall-unmount", lager.Data{"target": target})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	if err := m.syscall.Unmount(target, 0); err != nil {
+		logger.Error("unmount-failed", err)
+		return fmt.Errorf("unmount failed: %s", err)
+	}
+
+	return nil
+}
+
+func (m *syscallMounter) Check(env dockerdriver.Env, name, mountPoint string, depth volumedriver.CheckDepth) bool {
+	logger := env.Logger().Session("syscall-check", lager.Data{"name": name, "mount-point": mountPoint, "depth": depth})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	mounted, err := m.mountChecker.Exists(mountPoint)
+	if err != nil {
+		logger.Error("check-mounts-failed", err)
+		return false
+	}
+	if !mounted {
+		logger.Info("not-mounted")
+		return false
+	}
+
+	if err := volumedriver.ProbeMountPoint(m.os, m.ioutil, mountPoint, depth); err != nil {
+		logger.Error("probe-failed", err)
+		return false
+	}
+
+	return true
+}
+
+// LazyUnmount detaches the mount at target while it is still in use, for
+// removing volumes with force.
+func (m *syscallMounter) LazyUnmount(env dockerdriver.Env, target string) error {
+	logger := env.Logger().Session("syscall-lazy-unmount", lager.Data{"target": target})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	if err := m.syscall.Unmount(target, mntDetach); err != nil {
+		logger.Error("lazy-unmount-failed", err)
+		return fmt.Errorf("lazy unmount failed: %s", err)
+	}
+
+	return nil
+}
+
+// Purge force-detaches everything mounted below path, so that unreachable
+// servers cannot block it, and removes the emptied mountpoints.
+func (m *syscallMounter) Purge(env dockerdriver.Env, path string) {
+	logger := env.Logger().Session("syscall-purge", lager.Data{"path": path})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	mounthelper.Purge(logger, path, m.mountChecker, m.os, func(mountPoint string) error {
+		return m.syscall.Unmount(mountPoint, mntForce|mntDetach)
+	})
+}
+
+// resolve returns the addresses of host, keeping the order of the resolver
+// and dropping duplicates.
+func (m *syscallMounter) resolve(ctx context.Context, host string) ([]string, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []string{ip.String()}, nil
+	}
+	// link-local addresses carry the zone of their interface, as in fe80::1%eth0
+	if i := strings.LastIndex(host, "%"); i > 0 && net.ParseIP(host[:i]) != nil {
+		return []string{net.ParseIP(host[:i]).String() + host[i:]}, nil
+	}
+
+	ipAddrs, err := m.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := []string{}
+	seen := map[string]bool{}
+	for _, ipAddr := range ipAddrs {
+		addr := ipAddr.IP.String()
+		if !seen[addr] {
+			seen[addr] = true
+			addrs = append(addrs, addr)
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses found for %s", host)
+	}
+
+	return addrs, nil
+}
+
+// splitSource splits host:/export, allowing bracketed IPv6 addresses.
+func splitSource(source string) (string, string, error) {
+	invalid := safeerrors.New(safeerrors.InvalidSource, "invalid nfs source '%s', expected host:/export", source)
+
+	hostEnd := 0
+	if strings.HasPrefix(source, "[") {
+		hostEnd = strings.Index(source, "]")
+		if hostEnd < 0 {
+			return "", "", invalid
+		}
+		hostEnd++
+	}
+
+	sep := strings.Index(source[hostEnd:], ":/")
+	if sep < 0 {
+		return "", "", invalid
+	}
+	sep += hostEnd
+
+	host := strings.TrimSuffix(strings.TrimPrefix(source[:sep], "["), "]")
+	if host == "" {
+		return "", "", invalid
+	}
+
+	return host, source[sep+1:], nil
+}
+
+func mountData(addr string, opts map[string]interface{}) (uintptr, string) {
+	var flags uintptr
+	data := []string{"addr=" + addr}
+
+	keys := make([]string, 0, len(opts))
+	for key := range opts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := opts[key]
+
+		if key == addrOpt {
+			continue
+		}
+		if key == mountAddrOpt || key == clientAddrOpt {
+			value = unbracket(fmt.Sprintf("%v", value))
+		}
+		if protoOpts[key] {
+			value = protoFor(addr, fmt.Sprintf("%v", value))
+		}
+
+		if flag, ok := flagOpts[key]; ok {
+			if enabled(value) {
+				flags |= flag
+			}
+			continue
+		}
+
+		switch v := value.(type) {
+		case bool:
+			if v {
+				data = append(data, key)
+			}
+		case nil:
+			data = append(data, key)
+		default:
+			if s := fmt.Sprintf("%v", v); contextOpts[key] && strings.Contains(s, ",") {
+				data = append(data, fmt.Sprintf("%s=\"%s\"", key, s))
+				continue
+			}
+			data = append(data, fmt.Sprintf("%s=%v", key, v))
+		}
+	}
+
+	return flags, strings.Join(data, ",")
+}
+
+func enabled(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "" || strings.EqualFold(v, "true")
+	case nil:
+		return true
+	}
+	return false
+}
+
+func bracketIPv6(addr string) string {
+	if strings.Contains(addr, ":") {
+		return "[" + addr + "]"
+	}
+	return addr
+}
+
+func unbracket(addr string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+}
+
+// protoFor returns the netid of proto for the family of addr, so that a
+// single proto opt works for servers that resolve to IPv4 and IPv6 addresses.
+func protoFor(addr string, proto string) string {
+	base := strings.TrimSuffix(proto, "6")
+	if base != "tcp" && base != "udp" && base != "rdma" {
+		return proto
+	}
+	if strings.Contains(addr, ":") {
+		return base + "6"
+	}
+	return base
+}
@@ -0,0 +1,372 @@
+package syscallmounter_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/goshims/ioutilshim/ioutil_fake"
+	"code.cloudfoundry.org/goshims/osshim/os_fake"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+	"code.cloudfoundry.org/volumedriver/syscallmounter"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SyscallMounter", func() {
+	var (
+		logger           *lagertest.TestLogger
+		env              dockerdriver.Env
+		fakeSyscall      *volumedriverfakes.FakeMountSyscall
+		fakeResolver     *volumedriverfakes.FakeResolver
+		fakeOs           *os_fake.FakeOs
+		fakeIoutil       *ioutil_fake.FakeIoutil
+		fakeMountChecker *volumedriverfakes.FakeMountChecker
+		mounter          volumedriver.Mounter
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("syscallmounter")
+		env = driverhttp.NewHttpDriverEnv(logger, context.TODO())
+		fakeSyscall = &volumedriverfakes.FakeMountSyscall{}
+		fakeResolver = &volumedriverfakes.FakeResolver{}
+		fakeOs = &os_fake.FakeOs{}
+		fakeIoutil = &ioutil_fake.FakeIoutil{}
+		fakeMountChecker = &volumedriverfakes.FakeMountChecker{}
+
+		fakeResolver.LookupIPAddrReturns([]net.IPAddr{{IP: net.ParseIP("10.0.0.1")}}, nil)
+
+		mounter = syscallmounter.NewSyscallMounter(fakeSyscall, fakeResolver, fakeOs, fakeIoutil, fakeMountChecker)
+	})
+
+	Describe("Mount", func() {
+		var (
+			source string
+			opts   map[string]interface{}
+			err    error
+		)
+
+		BeforeEach(func() {
+			source = "nfs-server:/export/path"
+			opts = map[string]interface{}{"vers": "3", "nolock": true, "ro": "true"}
+		})
+
+		JustBeforeEach(func() {
+			err = mounter.Mount(env, source, "/mnt/target", opts)
+		})
+
+		It("mounts the resolved server address with mount(2)", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeResolver.LookupIPAddrCallCount()).To(Equal(1))
+			_, host := fakeResolver.LookupIPAddrArgsForCall(0)
+			Expect(host).To(Equal("nfs-server"))
+
+			Expect(fakeSyscall.MountCallCount()).To(Equal(1))
+			device, target, fstype, flags, data := fakeSyscall.MountArgsForCall(0)
+			Expect(device).To(Equal("10.0.0.1:/export/path"))
+			Expect(target).To(Equal("/mnt/target"))
+			Expect(fstype).To(Equal("nfs"))
+			Expect(flags).To(Equal(uintptr(0x1)))
+			Expect(data).To(Equal("addr=10.0.0.1,nolock,retrans=2,rsize=65536,timeo=600,vers=3,wsize=65536"))
+		})
+
+		Context("when the source is an IPv6 address", func() {
+			BeforeEach(func() {
+				source = "[fd00::1]:/export"
+				opts = map[string]interface{}{}
+			})
+
+			It("does not resolve the address", func() {
+				Expect(fakeResolver.LookupIPAddrCallCount()).To(BeZero())
+				device, _, _, _, data := fakeSyscall.MountArgsForCall(0)
+				Expect(device).To(Equal("[fd00::1]:/export"))
+				Expect(data).To(Equal("addr=fd00::1"))
+			})
+		})
+
+		Context("when the source is a link-local IPv6 address", func() {
+			BeforeEach(func() {
+				source = "[fe80::1%eth0]:/export"
+				opts = map[string]interface{}{}
+			})
+
+			It("keeps the zone of the address", func() {
+				Expect(fakeResolver.LookupIPAddrCallCount()).To(BeZero())
+				device, _, _, _, data := fakeSyscall.MountArgsForCall(0)
+				Expect(device).To(Equal("[fe80::1%eth0]:/export"))
+				Expect(data).To(Equal("addr=fe80::1%eth0"))
+			})
+		})
+
+		Context("when the host resolves to an IPv6 address", func() {
+			BeforeEach(func() {
+				fakeResolver.LookupIPAddrReturns([]net.IPAddr{{IP: net.ParseIP("fd00::1")}, {IP: net.ParseIP("10.0.0.1")}}, nil)
+				fakeSyscall.MountReturns(errors.New("connection timed out"))
+				opts = map[string]interface{}{"proto": "tcp", "mountproto": "udp6", "mountaddr": "[fd00::2]", "clientaddr": "[fd00::3]"}
+			})
+
+			It("matches the transports to the family of each address", func() {
+				device, _, _, _, data := fakeSyscall.MountArgsForCall(0)
+				Expect(device).To(Equal("[fd00::1]:/export/path"))
+				Expect(data).To(Equal("addr=fd00::1,clientaddr=fd00::3,mountaddr=fd00::2,mountproto=udp6,proto=tcp6"))
+
+				_, _, _, _, data = fakeSyscall.MountArgsForCall(1)
+				Expect(data).To(Equal("addr=10.0.0.1,clientaddr=fd00::3,mountaddr=fd00::2,mountproto=udp,proto=tcp"))
+			})
+		})
+
+		Context("when an SELinux context has an MLS range", func() {
+			BeforeEach(func() {
+				source = "[fd00::1]:/export"
+				opts = map[string]interface{}{"context": "system_u:object_r:container_file_t:s0:c1,c2"}
+			})
+
+			It("quotes the context", func() {
+				_, _, _, _, data := fakeSyscall.MountArgsForCall(0)
+				Expect(data).To(Equal(`addr=fd00::1,context="system_u:object_r:container_file_t:s0:c1,c2"`))
+			})
+		})
+
+		Context("when the addr opt is set", func() {
+			BeforeEach(func() {
+				opts = map[string]interface{}{"addr": "[fd00::1]", "vers": "4.1"}
+			})
+
+			It("mounts that address without resolving the host", func() {
+				Expect(fakeResolver.LookupIPAddrCallCount()).To(BeZero())
+				Expect(fakeSyscall.MountCallCount()).To(Equal(1))
+				device, _, _, _, data := fakeSyscall.MountArgsForCall(0)
+				Expect(device).To(Equal("[fd00::1]:/export/path"))
+				Expect(data).To(Equal("addr=fd00::1,retrans=2,rsize=1048576,timeo=600,vers=4.1,wsize=1048576"))
+			})
+		})
+
+		Context("when the source has no export path", func() {
+			BeforeEach(func() {
+				source = "nfs-server"
+			})
+
+			It("rejects the mount", func() {
+				Expect(err).To(Equal(safeerrors.New(safeerrors.InvalidSource, "invalid nfs source 'nfs-server', expected host:/export")))
+				Expect(fakeSyscall.MountCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when the server cannot be resolved", func() {
+			BeforeEach(func() {
+				fakeResolver.LookupIPAddrReturns(nil, errors.New("no such host"))
+			})
+
+			It("rejects the mount", func() {
+				Expect(err).To(MatchError("unable to resolve nfs server 'nfs-server'"))
+				safe, _ := safeerrors.From(err)
+				Expect(safe.Code).To(Equal(safeerrors.NotFound))
+			})
+		})
+
+		Context("when the host resolves to several addresses", func() {
+			BeforeEach(func() {
+				fakeResolver.LookupIPAddrReturns([]net.IPAddr{
+					{IP: net.ParseIP("10.0.0.1")},
+					{IP: net.ParseIP("10.0.0.2")},
+					{IP: net.ParseIP("10.0.0.1")},
+					{IP: net.ParseIP("10.0.0.3")},
+				}, nil)
+				fakeSyscall.MountStub = func(device, target, fstype string, flags uintptr, data string) error {
+					if strings.HasPrefix(device, "10.0.0.3:") {
+						return nil
+					}
+					return errors.New("connection timed out")
+				}
+			})
+
+			It("tries each address in order until one mounts", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeSyscall.MountCallCount()).To(Equal(3))
+				for i, addr := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+					device, _, _, _, data := fakeSyscall.MountArgsForCall(i)
+					Expect(device).To(Equal(addr + ":/export/path"))
+					Expect(data).To(HavePrefix("addr=" + addr + ","))
+				}
+			})
+
+			Context("when every address fails", func() {
+				BeforeEach(func() {
+					fakeSyscall.MountStub = nil
+					fakeSyscall.MountReturns(errors.New("connection timed out"))
+				})
+
+				It("returns the last error", func() {
+					Expect(err).To(MatchError("mount failed: connection timed out"))
+					Expect(fakeSyscall.MountCallCount()).To(Equal(3))
+				})
+			})
+
+			Context("when mounting again", func() {
+				BeforeEach(func() {
+					fakeSyscall.MountStub = nil
+				})
+
+				It("resolves the host again", func() {
+					fakeResolver.LookupIPAddrReturns([]net.IPAddr{{IP: net.ParseIP("10.0.0.4")}}, nil)
+					Expect(mounter.Mount(env, source, "/mnt/target", opts)).To(Succeed())
+					Expect(fakeResolver.LookupIPAddrCallCount()).To(Equal(2))
+					device, _, _, _, _ := fakeSyscall.MountArgsForCall(1)
+					Expect(device).To(Equal("10.0.0.4:/export/path"))
+				})
+			})
+		})
+
+		Context("when transport opts are set", func() {
+			BeforeEach(func() {
+				opts = map[string]interface{}{"vers": 4.2, "nconnect": float64(8), "proto": "rdma", "rsize": "262144", "timeo": float64(150)}
+			})
+
+			It("passes them on in place of the defaults", func() {
+				Expect(err).NotTo(HaveOccurred())
+				_, _, _, _, data := fakeSyscall.MountArgsForCall(0)
+				Expect(data).To(Equal("addr=10.0.0.1,nconnect=8,proto=rdma,retrans=2,rsize=262144,timeo=150,vers=4.2,wsize=1048576"))
+			})
+		})
+
+		Context("when the version is left to the kernel", func() {
+			BeforeEach(func() {
+				opts = map[string]interface{}{"nolock": true}
+			})
+
+			It("adds no defaults", func() {
+				_, _, _, _, data := fakeSyscall.MountArgsForCall(0)
+				Expect(data).To(Equal("addr=10.0.0.1,nolock"))
+			})
+		})
+
+		Context("when transport opts are invalid", func() {
+			BeforeEach(func() {
+				opts = map[string]interface{}{"vers": "5"}
+			})
+
+			It("rejects them without mounting", func() {
+				Expect(err).To(MatchError("invalid vers '5', must be 3, 4, 4.0, 4.1 or 4.2"))
+				safe, ok := safeerrors.From(err)
+				Expect(ok).To(BeTrue())
+				Expect(safe.Code).To(Equal(safeerrors.InvalidOption))
+				Expect(fakeSyscall.MountCallCount()).To(Equal(0))
+			})
+
+			It("rejects every kind of invalid value", func() {
+				invalid := []struct {
+					opts    map[string]interface{}
+					message string
+				}{
+					{map[string]interface{}{"vers": "3", "nfsvers": "4.1"}, "the vers and nfsvers opts disagree"},
+					{map[string]interface{}{"nconnect": "32"}, "invalid nconnect '32', must be a number from 1 to 16"},
+					{map[string]interface{}{"vers": "3", "nconnect": "2", "proto": "udp"}, "the nconnect opt requires a connection oriented proto"},
+					{map[string]interface{}{"vers": "4.1", "proto": "udp"}, "nfs version 4.1 does not support proto 'udp'"},
+					{map[string]interface{}{"proto": "sctp"}, "invalid proto 'sctp', must be tcp, udp or rdma"},
+					{map[string]interface{}{"wsize": float64(4194304)}, "must be a number from 1024 to 1048576"},
+					{map[string]interface{}{"rsize": "5000"}, "invalid rsize '5000', must be a multiple of 1024"},
+					{map[string]interface{}{"retrans": "-1"}, "invalid retrans '-1', must be a number of at least 0"},
+					{map[string]interface{}{"timeo": "0"}, "invalid timeo '0', must be a number from 1 to 6000"},
+				}
+				for _, c := range invalid {
+					err := mounter.Mount(env, source, "/mnt/target", c.opts)
+					Expect(err).To(MatchError(ContainSubstring(c.message)))
+					safe, ok := safeerrors.From(err)
+					Expect(ok).To(BeTrue())
+					Expect(safe.Code).To(Equal(safeerrors.InvalidOption))
+				}
+				Expect(fakeSyscall.MountCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the proto is udp", func() {
+			BeforeEach(func() {
+				opts = map[string]interface{}{"vers": "3", "proto": "udp"}
+			})
+
+			It("leaves the timeout to the kernel", func() {
+				Expect(err).NotTo(HaveOccurred())
+				_, _, _, _, data := fakeSyscall.MountArgsForCall(0)
+				Expect(data).To(Equal("addr=10.0.0.1,proto=udp,retrans=2,rsize=65536,vers=3,wsize=65536"))
+			})
+		})
+
+		Context("when the syscall fails", func() {
+			BeforeEach(func() {
+				fakeSyscall.MountReturns(errors.New("permission denied"))
+			})
+
+			It("returns the error", func() {
+				Expect(err).To(MatchError("mount failed: permission denied"))
+			})
+		})
+	})
+
+	Describe("Unmount", func() {
+		It("unmounts the target", func() {
+			Expect(mounter.Unmount(env, "/mnt/target")).To(Succeed())
+			target, flags := fakeSyscall.UnmountArgsForCall(0)
+			Expect(target).To(Equal("/mnt/target"))
+			Expect(flags).To(BeZero())
+		})
+
+		Context("when the syscall fails", func() {
+			BeforeEach(func() {
+				fakeSyscall.UnmountReturns(errors.New("device or resource busy"))
+			})
+
+			It("returns the error", func() {
+				Expect(mounter.Unmount(env, "/mnt/target")).To(MatchError("unmount failed: device or resource busy"))
+			})
+		})
+	})
+
+	Describe("LazyUnmount", func() {
+		It("detaches the target", func() {
+			lazy, ok := mounter.(volumedriver.LazyUnmounter)
+			Expect(ok).To(BeTrue())
+
+			Expect(lazy.LazyUnmount(env, "/mnt/target")).To(Succeed())
+			target, flags := fakeSyscall.UnmountArgsForCall(0)
+			Expect(target).To(Equal("/mnt/target"))
+			Expect(flags).To(Equal(0x2))
+		})
+	})
+
+	Describe("Check", func() {
+		It("reports a mounted mountpoint as healthy", func() {
+			dirInfo := &ioutil_fake.FakeFileInfo{}
+			dirInfo.IsDirReturns(true)
+			fakeOs.StatReturns(dirInfo, nil)
+			fakeMountChecker.ExistsReturns(true, nil)
+			Expect(mounter.Check(env, "some-volume", "/mnt/target", volumedriver.CheckStat)).To(BeTrue())
+		})
+
+		It("reports a missing mount as unhealthy", func() {
+			fakeMountChecker.ExistsReturns(false, nil)
+			Expect(mounter.Check(env, "some-volume", "/mnt/target", volumedriver.CheckStat)).To(BeFalse())
+		})
+	})
+
+	Describe("Purge", func() {
+		BeforeEach(func() {
+			fakeMountChecker.ListReturns([]string{"/mnt/root/a", "/mnt/root/b"}, nil)
+		})
+
+		It("force-detaches and removes every mountpoint below the path", func() {
+			mounter.Purge(env, "/mnt/root")
+
+			Expect(fakeSyscall.UnmountCallCount()).To(Equal(2))
+			target, flags := fakeSyscall.UnmountArgsForCall(0)
+			Expect(target).To(Equal("/mnt/root/b"))
+			Expect(flags).To(Equal(0x3))
+			Expect(fakeOs.RemoveCallCount()).To(Equal(2))
+		})
+	})
+})
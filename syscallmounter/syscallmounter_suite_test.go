@@ -0,0 +1,13 @@
+package syscallmounter_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestSyscallMounter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "SyscallMounter Suite")
+}
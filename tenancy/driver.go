@@ -0,0 +1,186 @@
+package tenancy
+
+import (
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager"
+)
+
+type driver struct {
+	driver dockerdriver.Driver
+}
+
+// NewDriver wraps d so that the volumes of every request are those of its
+// tenant. Requests without a tenant see the volumes of all tenants under
+// their scoped names, tenant/volume, and can create a volume for a tenant
+// with the TenantOpt. Serve it behind NewHandler.
+func NewDriver(d dockerdriver.Driver) dockerdriver.Driver {
+	return &driver{driver: d}
+}
+
+func (d *driver) Activate(env dockerdriver.Env) dockerdriver.ActivateResponse {
+	return d.driver.Activate(env)
+}
+
+func (d *driver) Capabilities(env dockerdriver.Env) dockerdriver.CapabilitiesResponse {
+	return d.driver.Capabilities(env)
+}
+
+func (d *driver) Create(env dockerdriver.Env, createRequest dockerdriver.CreateRequest) dockerdriver.ErrorResponse {
+	tenant := FromContext(env.Context())
+
+	if value, ok := createRequest.Opts[TenantOpt]; ok {
+		optTenant, _ := value.(string)
+		if !validTenant.MatchString(optTenant) {
+			return dockerdriver.ErrorResponse{Err: fmt.Sprintf("invalid %s '%v', must be made of letters, digits and ._-", TenantOpt, value)}
+		}
+		if tenant != "" && tenant != optTenant {
+			return dockerdriver.ErrorResponse{Err: fmt.Sprintf("the %s opt '%s' does not match the tenant of the request", TenantOpt, optTenant)}
+		}
+		tenant = optTenant
+
+		opts := map[string]interface{}{}
+		for k, v := range createRequest.Opts {
+			opts[k] = v
+		}
+		delete(opts, TenantOpt)
+		createRequest.Opts = opts
+	}
+
+	// a scoped name created without a tenant would take over the volume
+	// of that name of the tenant
+	if tenant == "" && strings.Contains(createRequest.Name, Separator) {
+		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("Invalid volume name '%s', must not contain '%s', create volumes of a tenant with the %s opt", createRequest.Name, Separator, TenantOpt)}
+	}
+
+	env, name, err := scope(env, tenant, createRequest.Name)
+	if err != "" {
+		return dockerdriver.ErrorResponse{Err: err}
+	}
+	createRequest.Name = name
+
+	response := d.driver.Create(env, createRequest)
+	response.Err = unscopeError(response.Err, tenant, name)
+	return response
+}
+
+func (d *driver) Get(env dockerdriver.Env, getRequest dockerdriver.GetRequest) dockerdriver.GetResponse {
+	tenant := FromContext(env.Context())
+	env, name, err := scope(env, tenant, getRequest.Name)
+	if err != "" {
+		return dockerdriver.GetResponse{Err: err}
+	}
+	requested := getRequest.Name
+	getRequest.Name = name
+
+	response := d.driver.Get(env, getRequest)
+	if response.Volume.Name == name {
+		response.Volume.Name = requested
+	}
+	response.Err = unscopeError(response.Err, tenant, name)
+	return response
+}
+
+func (d *driver) List(env dockerdriver.Env) dockerdriver.ListResponse {
+	tenant := FromContext(env.Context())
+	env, _, _ = scope(env, tenant, "")
+
+	response := d.driver.List(env)
+	if tenant == "" {
+		return response
+	}
+
+	volumes := []dockerdriver.VolumeInfo{}
+	for _, volume := range response.Volumes {
+		if name, ok := Unscope(tenant, volume.Name); ok {
+			volume.Name = name
+			volumes = append(volumes, volume)
+		}
+	}
+	response.Volumes = volumes
+	return response
+}
+
+func (d *driver) Mount(env dockerdriver.Env, mountRequest dockerdriver.MountRequest) dockerdriver.MountResponse {
+	tenant := FromContext(env.Context())
+	env, name, err := scope(env, tenant, mountRequest.Name)
+	if err != "" {
+		return dockerdriver.MountResponse{Err: err}
+	}
+	mountRequest.Name = name
+
+	response := d.driver.Mount(env, mountRequest)
+	response.Err = unscopeError(response.Err, tenant, name)
+	return response
+}
+
+func (d *driver) Path(env dockerdriver.Env, pathRequest dockerdriver.PathRequest) dockerdriver.PathResponse {
+	tenant := FromContext(env.Context())
+	env, name, err := scope(env, tenant, pathRequest.Name)
+	if err != "" {
+		return dockerdriver.PathResponse{Err: err}
+	}
+	pathRequest.Name = name
+
+	response := d.driver.Path(env, pathRequest)
+	response.Err = unscopeError(response.Err, tenant, name)
+	return response
+}
+
+func (d *driver) Unmount(env dockerdriver.Env, unmountRequest dockerdriver.UnmountRequest) dockerdriver.ErrorResponse {
+	tenant := FromContext(env.Context())
+	env, name, err := scope(env, tenant, unmountRequest.Name)
+	if err != "" {
+		return dockerdriver.ErrorResponse{Err: err}
+	}
+	unmountRequest.Name = name
+
+	response := d.driver.Unmount(env, unmountRequest)
+	response.Err = unscopeError(response.Err, tenant, name)
+	return response
+}
+
+func (d *driver) Remove(env dockerdriver.Env, removeRequest dockerdriver.RemoveRequest) dockerdriver.ErrorResponse {
+	tenant := FromContext(env.Context())
+	env, name, err := scope(env, tenant, removeRequest.Name)
+	if err != "" {
+		return dockerdriver.ErrorResponse{Err: err}
+	}
+	removeRequest.Name = name
+
+	response := d.driver.Remove(env, removeRequest)
+	response.Err = unscopeError(response.Err, tenant, name)
+	return response
+}
+
+// scope returns env with a logger that adds tenant to every line, and the
+// scoped name of volume. Names of a tenant cannot contain the Separator,
+// which would let them reach into other namespaces; empty names are left to
+// the driver to refuse.
+func scope(env dockerdriver.Env, tenant string, volume string) (dockerdriver.Env, string, string) {
+	if tenant == "" {
+		return env, volume, ""
+	}
+	if strings.Contains(volume, Separator) {
+		return env, "", fmt.Sprintf("Invalid volume name '%s', must not contain '%s'", volume, Separator)
+	}
+
+	env = driverhttp.EnvWithLogger(env.Logger().WithData(lager.Data{LogKey: tenant}), env)
+	if volume == "" {
+		return env, "", ""
+	}
+	return env, Scope(tenant, volume), ""
+}
+
+// unscopeError replaces the scoped name in an error of the driver with the
+// name the tenant knows the volume by.
+func unscopeError(err string, tenant string, scoped string) string {
+	if err == "" || tenant == "" || scoped == "" {
+		return err
+	}
+	name, _ := Unscope(tenant, scoped)
+	return strings.Replace(err, scoped, name, -1)
+}
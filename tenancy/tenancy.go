@@ -0,0 +1,93 @@
+// Package tenancy gives every tenant, such as a CF org, a namespace of volume
+// names of its own, so that two tenants can both create a volume named data.
+// The handler takes the tenant of a request from a header set by a trusted
+// proxy; the driver wrapper stores the volumes of a tenant under scoped
+// names and only lists the volumes of the tenant of the request.
+package tenancy
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+
+	cf_http_handlers "code.cloudfoundry.org/cfhttp/handlers"
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+)
+
+// TenantOpt places a volume in the namespace of a tenant when it is created
+// by a request without a tenant, such as one of an operator.
+const TenantOpt = "tenant"
+
+// Separator joins a tenant and a volume name into the scoped name that the
+// driver stores the volume under. Only the TenantOpt creates names that
+// contain it, so scoped names never collide with the names of volumes
+// without a tenant.
+const Separator = "/"
+
+// LogKey is the lager data key of the tenant.
+const LogKey = "tenant"
+
+var validTenant = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,127}$`)
+
+type tenantKey struct{}
+
+// WithTenant returns a context that scopes the requests it is passed with to
+// tenant.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// FromContext returns the tenant set by WithTenant, or the empty string.
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// NewHandler passes the tenant named by header on to handler in the request
+// context. Requests without the header are served without a tenant, and
+// requests with a tenant that is not made of letters, digits and ._- are
+// refused with a 200 status code, following the docker plugin API.
+func NewHandler(logger lager.Logger, handler http.Handler, header string) http.Handler {
+	logger = logger.Session("tenancy")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		tenant := req.Header.Get(header)
+		if tenant == "" {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		if !validTenant.MatchString(tenant) {
+			logger.Info("invalid-tenant", lager.Data{"path": req.URL.Path})
+			cf_http_handlers.WriteJSONResponse(w, http.StatusOK, dockerdriver.ErrorResponse{Err: "Invalid tenant in header " + header})
+			return
+		}
+
+		handler.ServeHTTP(w, req.WithContext(WithTenant(req.Context(), tenant)))
+	})
+}
+
+// Scope returns the name that the driver stores volume of tenant under.
+func Scope(tenant string, volume string) string {
+	if tenant == "" {
+		return volume
+	}
+	return tenant + Separator + volume
+}
+
+// Unscope returns the name of a volume stored as scoped as the tenant sees
+// it, and false for volumes of other namespaces.
+func Unscope(tenant string, scoped string) (string, bool) {
+	if tenant == "" {
+		return scoped, true
+	}
+	prefix := tenant + Separator
+	if !strings.HasPrefix(scoped, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(scoped, prefix), true
+}
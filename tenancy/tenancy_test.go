@@ -0,0 +1,214 @@
+package tenancy_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver/tenancy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+// memDriver keeps volumes in memory, with the opts they were created with,
+// and mounts them below /mnt.
+type memDriver struct {
+	dockerdriver.Driver
+	volumes map[string]map[string]interface{}
+}
+
+func (d *memDriver) Create(env dockerdriver.Env, createRequest dockerdriver.CreateRequest) dockerdriver.ErrorResponse {
+	env.Logger().Info("creating", nil)
+	d.volumes[createRequest.Name] = createRequest.Opts
+	return dockerdriver.ErrorResponse{}
+}
+
+func (d *memDriver) Get(env dockerdriver.Env, getRequest dockerdriver.GetRequest) dockerdriver.GetResponse {
+	if _, ok := d.volumes[getRequest.Name]; !ok {
+		return dockerdriver.GetResponse{Err: "Volume not found"}
+	}
+	return dockerdriver.GetResponse{Volume: dockerdriver.VolumeInfo{Name: getRequest.Name}}
+}
+
+func (d *memDriver) List(env dockerdriver.Env) dockerdriver.ListResponse {
+	response := dockerdriver.ListResponse{Volumes: []dockerdriver.VolumeInfo{}}
+	for name := range d.volumes {
+		response.Volumes = append(response.Volumes, dockerdriver.VolumeInfo{Name: name})
+	}
+	sort.Slice(response.Volumes, func(i, j int) bool { return response.Volumes[i].Name < response.Volumes[j].Name })
+	return response
+}
+
+func (d *memDriver) Mount(env dockerdriver.Env, mountRequest dockerdriver.MountRequest) dockerdriver.MountResponse {
+	if _, ok := d.volumes[mountRequest.Name]; !ok {
+		return dockerdriver.MountResponse{Err: fmt.Sprintf("Volume '%s' must be created before being mounted", mountRequest.Name)}
+	}
+	return dockerdriver.MountResponse{Mountpoint: "/mnt/" + mountRequest.Name}
+}
+
+func (d *memDriver) Remove(env dockerdriver.Env, removeRequest dockerdriver.RemoveRequest) dockerdriver.ErrorResponse {
+	delete(d.volumes, removeRequest.Name)
+	return dockerdriver.ErrorResponse{}
+}
+
+var _ = Describe("Tenancy", func() {
+	var logger *lagertest.TestLogger
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("tenancy")
+	})
+
+	Describe("NewHandler", func() {
+		var (
+			request  *http.Request
+			recorder *httptest.ResponseRecorder
+			served   bool
+			seen     string
+		)
+
+		BeforeEach(func() {
+			request = httptest.NewRequest("POST", "/VolumeDriver.Mount", nil)
+			recorder = httptest.NewRecorder()
+			served = false
+		})
+
+		JustBeforeEach(func() {
+			handler := tenancy.NewHandler(logger, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				served = true
+				seen = tenancy.FromContext(req.Context())
+			}), "X-Cf-Org-Guid")
+			handler.ServeHTTP(recorder, request)
+		})
+
+		Context("when the request names a tenant", func() {
+			BeforeEach(func() {
+				request.Header.Set("X-Cf-Org-Guid", "org-1234")
+			})
+
+			It("passes it on", func() {
+				Expect(served).To(BeTrue())
+				Expect(seen).To(Equal("org-1234"))
+			})
+		})
+
+		Context("when the request names no tenant", func() {
+			It("serves it without one", func() {
+				Expect(served).To(BeTrue())
+				Expect(seen).To(BeEmpty())
+			})
+		})
+
+		Context("when the request names an invalid tenant", func() {
+			BeforeEach(func() {
+				request.Header.Set("X-Cf-Org-Guid", "../org")
+			})
+
+			It("refuses it", func() {
+				Expect(served).To(BeFalse())
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+				Expect(recorder.Body.String()).To(MatchJSON(`{"Err": "Invalid tenant in header X-Cf-Org-Guid"}`))
+			})
+		})
+	})
+
+	Describe("NewDriver", func() {
+		var (
+			mem    *memDriver
+			driver dockerdriver.Driver
+		)
+
+		envOf := func(tenant string) dockerdriver.Env {
+			ctx := context.TODO()
+			if tenant != "" {
+				ctx = tenancy.WithTenant(ctx, tenant)
+			}
+			return driverhttp.NewHttpDriverEnv(logger, ctx)
+		}
+
+		BeforeEach(func() {
+			mem = &memDriver{volumes: map[string]map[string]interface{}{}}
+			driver = tenancy.NewDriver(mem)
+
+			Expect(driver.Create(envOf("org-a"), dockerdriver.CreateRequest{Name: "data", Opts: map[string]interface{}{"source": "filer:/a"}}).Err).To(BeEmpty())
+			Expect(driver.Create(envOf("org-b"), dockerdriver.CreateRequest{Name: "data", Opts: map[string]interface{}{"source": "filer:/b"}}).Err).To(BeEmpty())
+			Expect(driver.Create(envOf(""), dockerdriver.CreateRequest{Name: "shared", Opts: map[string]interface{}{"source": "filer:/shared"}}).Err).To(BeEmpty())
+		})
+
+		It("keeps volumes of the same name of different tenants apart", func() {
+			Expect(mem.volumes).To(HaveLen(3))
+			Expect(mem.volumes["org-a/data"]["source"]).To(Equal("filer:/a"))
+			Expect(mem.volumes["org-b/data"]["source"]).To(Equal("filer:/b"))
+
+			Expect(driver.Mount(envOf("org-a"), dockerdriver.MountRequest{Name: "data"}).Mountpoint).To(Equal("/mnt/org-a/data"))
+			Expect(driver.Get(envOf("org-b"), dockerdriver.GetRequest{Name: "data"}).Volume.Name).To(Equal("data"))
+
+			Expect(driver.Remove(envOf("org-a"), dockerdriver.RemoveRequest{Name: "data"}).Err).To(BeEmpty())
+			Expect(mem.volumes).To(HaveKey("org-b/data"))
+		})
+
+		It("lists the volumes of the tenant of the request", func() {
+			Expect(driver.List(envOf("org-a")).Volumes).To(Equal([]dockerdriver.VolumeInfo{{Name: "data"}}))
+			Expect(driver.List(envOf("org-c")).Volumes).To(BeEmpty())
+		})
+
+		It("lists every volume under its scoped name for requests without a tenant", func() {
+			Expect(driver.List(envOf("")).Volumes).To(Equal([]dockerdriver.VolumeInfo{{Name: "org-a/data"}, {Name: "org-b/data"}, {Name: "shared"}}))
+			Expect(driver.Mount(envOf(""), dockerdriver.MountRequest{Name: "org-b/data"}).Mountpoint).To(Equal("/mnt/org-b/data"))
+		})
+
+		It("does not let tenants reach into other namespaces", func() {
+			response := driver.Mount(envOf("org-a"), dockerdriver.MountRequest{Name: "shared"})
+			Expect(response.Err).To(Equal("Volume 'shared' must be created before being mounted"))
+
+			response = driver.Mount(envOf("org-a"), dockerdriver.MountRequest{Name: "../org-b/data"})
+			Expect(response.Err).To(Equal("Invalid volume name '../org-b/data', must not contain '/'"))
+		})
+
+		It("does not let requests without a tenant create scoped names", func() {
+			response := driver.Create(envOf(""), dockerdriver.CreateRequest{Name: "org-a/data", Opts: map[string]interface{}{"source": "filer:/other"}})
+			Expect(response.Err).To(Equal("Invalid volume name 'org-a/data', must not contain '/', create volumes of a tenant with the tenant opt"))
+			Expect(mem.volumes["org-a/data"]["source"]).To(Equal("filer:/a"))
+
+			response = driver.Create(envOf(""), dockerdriver.CreateRequest{Name: "org-c/data", Opts: map[string]interface{}{}})
+			Expect(response.Err).NotTo(BeEmpty())
+			Expect(mem.volumes).NotTo(HaveKey("org-c/data"))
+		})
+
+		It("adds the tenant to the log lines of the request", func() {
+			driver.Create(envOf("org-a"), dockerdriver.CreateRequest{Name: "logs", Opts: map[string]interface{}{}})
+			Expect(logger.Buffer()).To(gbytes.Say(`"tenant":"org-a"`))
+		})
+
+		Context("when the tenant opt is set", func() {
+			It("creates the volume for that tenant without passing the opt on", func() {
+				response := driver.Create(envOf(""), dockerdriver.CreateRequest{Name: "scratch", Opts: map[string]interface{}{"source": "filer:/c", "tenant": "org-c"}})
+				Expect(response.Err).To(BeEmpty())
+				Expect(mem.volumes["org-c/scratch"]).To(Equal(map[string]interface{}{"source": "filer:/c"}))
+				Expect(driver.List(envOf("org-c")).Volumes).To(Equal([]dockerdriver.VolumeInfo{{Name: "scratch"}}))
+			})
+
+			It("accepts the tenant of the request", func() {
+				response := driver.Create(envOf("org-a"), dockerdriver.CreateRequest{Name: "more", Opts: map[string]interface{}{"tenant": "org-a"}})
+				Expect(response.Err).To(BeEmpty())
+				Expect(mem.volumes).To(HaveKey("org-a/more"))
+			})
+
+			It("refuses another tenant than the one of the request", func() {
+				response := driver.Create(envOf("org-a"), dockerdriver.CreateRequest{Name: "more", Opts: map[string]interface{}{"tenant": "org-b"}})
+				Expect(response.Err).To(Equal("the tenant opt 'org-b' does not match the tenant of the request"))
+				Expect(mem.volumes).NotTo(HaveKey("org-b/more"))
+			})
+
+			It("refuses invalid tenants", func() {
+				response := driver.Create(envOf(""), dockerdriver.CreateRequest{Name: "more", Opts: map[string]interface{}{"tenant": "a/b"}})
+				Expect(response.Err).To(Equal("invalid tenant 'a/b', must be made of letters, digits and ._-"))
+			})
+		})
+	})
+})
@@ -0,0 +1,84 @@
+package testhelpers
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/goshims/filepathshim"
+	"code.cloudfoundry.org/goshims/timeshim"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+)
+
+// MountPathRoot is where a MemoryDriver mounts volumes. It only exists in
+// the MemoryFS of the driver.
+const MountPathRoot = "/var/vcap/data/volumes"
+
+// MemoryDriver is a volumedriver.VolumeDriver that keeps its state in a
+// MemoryFS and mounts with a ScriptedMounter, so that projects embedding the
+// driver can unit test against the real request handling without root, a
+// filer or goshims fakes.
+type MemoryDriver struct {
+	*volumedriver.VolumeDriver
+	FS      *MemoryFS
+	Mounter *ScriptedMounter
+
+	options volumedriver.Options
+}
+
+func NewMemoryDriver(logger lager.Logger) *MemoryDriver {
+	return NewMemoryDriverWithOptions(logger, volumedriver.DefaultOptions())
+}
+
+func NewMemoryDriverWithOptions(logger lager.Logger, options volumedriver.Options) *MemoryDriver {
+	return newMemoryDriver(logger, NewMemoryFS(), NewScriptedMounter(), options)
+}
+
+// Restart returns a new driver on the filesystem and mounts of d, which
+// restores the volumes d persisted, like a driver restarted on the same
+// cell. d must not be used afterwards.
+func (d *MemoryDriver) Restart(logger lager.Logger) *MemoryDriver {
+	return newMemoryDriver(logger, d.FS, d.Mounter, d.options)
+}
+
+// RestartWithOptions is Restart with different options, like a driver
+// restarted with a changed configuration.
+func (d *MemoryDriver) RestartWithOptions(logger lager.Logger, options volumedriver.Options) *MemoryDriver {
+	return newMemoryDriver(logger, d.FS, d.Mounter, options)
+}
+
+func newMemoryDriver(logger lager.Logger, fs *MemoryFS, mounter *ScriptedMounter, options volumedriver.Options) *MemoryDriver {
+	driver := volumedriver.NewVolumeDriverWithOptions(
+		logger,
+		fs.Os(),
+		&filepathshim.FilepathShim{},
+		fs.Ioutil(),
+		&timeshim.TimeShim{},
+		mounter,
+		MountPathRoot,
+		mounter,
+		&umask{},
+		options,
+	)
+
+	return &MemoryDriver{
+		VolumeDriver: driver,
+		FS:           fs,
+		Mounter:      mounter,
+		options:      options,
+	}
+}
+
+// umask keeps the mask instead of changing the one of the test process.
+type umask struct {
+	lock sync.Mutex
+	mask int
+}
+
+func (u *umask) Umask(mask int) int {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	old := u.mask
+	u.mask = mask
+	return old
+}
@@ -0,0 +1,250 @@
+package testhelpers
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/goshims/ioutilshim"
+	"code.cloudfoundry.org/goshims/osshim"
+)
+
+var (
+	errDirectoryNotEmpty = errors.New("directory not empty")
+	errIsDirectory       = errors.New("is a directory")
+	errNotDirectory      = errors.New("not a directory")
+)
+
+// MemoryFS is a file tree held in memory. Os and Ioutil expose it through the
+// goshims interfaces, implementing the calls the driver makes; any other call
+// panics, since the driver silently depending on the host filesystem would
+// defeat the purpose.
+type MemoryFS struct {
+	lock  sync.Mutex
+	files map[string]*memoryFile
+}
+
+type memoryFile struct {
+	name    string
+	dir     bool
+	mode    os.FileMode
+	data    []byte
+	modTime time.Time
+}
+
+func (f *memoryFile) Name() string       { return f.name }
+func (f *memoryFile) Size() int64        { return int64(len(f.data)) }
+func (f *memoryFile) Mode() os.FileMode  { return f.mode }
+func (f *memoryFile) ModTime() time.Time { return f.modTime }
+func (f *memoryFile) IsDir() bool        { return f.dir }
+func (f *memoryFile) Sys() interface{}   { return nil }
+
+func NewMemoryFS() *MemoryFS {
+	return &MemoryFS{
+		files: map[string]*memoryFile{
+			"/": {name: "/", dir: true, mode: os.ModeDir | 0755},
+		},
+	}
+}
+
+func (fs *MemoryFS) Os() osshim.Os {
+	return &memoryOs{fs: fs}
+}
+
+func (fs *MemoryFS) Ioutil() ioutilshim.Ioutil {
+	return &memoryIoutil{fs: fs}
+}
+
+// Exists reports whether path is a file or directory in fs.
+func (fs *MemoryFS) Exists(path string) bool {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	_, ok := fs.files[filepath.Clean(path)]
+	return ok
+}
+
+// ReadFile returns the contents of a file in fs, such as a persisted state
+// file.
+func (fs *MemoryFS) ReadFile(path string) ([]byte, error) {
+	return fs.readFile("read", path)
+}
+
+func (fs *MemoryFS) stat(op, path string) (*memoryFile, error) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	f, ok := fs.files[filepath.Clean(path)]
+	if !ok {
+		return nil, &os.PathError{Op: op, Path: path, Err: os.ErrNotExist}
+	}
+	return f, nil
+}
+
+func (fs *MemoryFS) mkdirAll(path string, perm os.FileMode) error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	path = filepath.Clean(path)
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if f, ok := fs.files[dir]; ok {
+			if !f.dir {
+				return &os.PathError{Op: "mkdir", Path: dir, Err: os.ErrExist}
+			}
+			break
+		}
+		fs.files[dir] = &memoryFile{name: filepath.Base(dir), dir: true, mode: os.ModeDir | perm.Perm(), modTime: time.Now()}
+	}
+	return nil
+}
+
+func (fs *MemoryFS) remove(path string) error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	path = filepath.Clean(path)
+	f, ok := fs.files[path]
+	if !ok {
+		return &os.PathError{Op: "remove", Path: path, Err: os.ErrNotExist}
+	}
+	if f.dir && len(fs.children(path)) > 0 {
+		return &os.PathError{Op: "remove", Path: path, Err: errDirectoryNotEmpty}
+	}
+	delete(fs.files, path)
+	return nil
+}
+
+func (fs *MemoryFS) readFile(op, path string) ([]byte, error) {
+	f, err := fs.stat(op, path)
+	if err != nil {
+		return nil, err
+	}
+	if f.dir {
+		return nil, &os.PathError{Op: op, Path: path, Err: errIsDirectory}
+	}
+	return append([]byte{}, f.data...), nil
+}
+
+func (fs *MemoryFS) writeFile(path string, data []byte, perm os.FileMode) error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	path = filepath.Clean(path)
+	if parent, ok := fs.files[filepath.Dir(path)]; !ok || !parent.dir {
+		return &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+	}
+	if f, ok := fs.files[path]; ok && f.dir {
+		return &os.PathError{Op: "open", Path: path, Err: errIsDirectory}
+	}
+	fs.files[path] = &memoryFile{name: filepath.Base(path), mode: perm.Perm(), data: append([]byte{}, data...), modTime: time.Now()}
+	return nil
+}
+
+func (fs *MemoryFS) rename(oldpath, newpath string) error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	oldpath, newpath = filepath.Clean(oldpath), filepath.Clean(newpath)
+	f, ok := fs.files[oldpath]
+	if !ok {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: os.ErrNotExist}
+	}
+	if f.dir {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: errIsDirectory}
+	}
+	if parent, ok := fs.files[filepath.Dir(newpath)]; !ok || !parent.dir {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: os.ErrNotExist}
+	}
+	if existing, ok := fs.files[newpath]; ok && existing.dir {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: errIsDirectory}
+	}
+	delete(fs.files, oldpath)
+	renamed := *f
+	renamed.name = filepath.Base(newpath)
+	fs.files[newpath] = &renamed
+	return nil
+}
+
+func (fs *MemoryFS) readDir(path string) ([]os.FileInfo, error) {
+	f, err := fs.stat("open", path)
+	if err != nil {
+		return nil, err
+	}
+	if !f.dir {
+		return nil, &os.PathError{Op: "readdirent", Path: path, Err: errNotDirectory}
+	}
+
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	entries := []os.FileInfo{}
+	for _, child := range fs.children(filepath.Clean(path)) {
+		entries = append(entries, child)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// children must be called with lock held.
+func (fs *MemoryFS) children(dir string) []*memoryFile {
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+
+	children := []*memoryFile{}
+	for path, f := range fs.files {
+		if path != dir && strings.HasPrefix(path, prefix) && !strings.Contains(path[len(prefix):], "/") {
+			children = append(children, f)
+		}
+	}
+	return children
+}
+
+type memoryOs struct {
+	osshim.Os
+	fs *MemoryFS
+}
+
+func (o *memoryOs) Stat(name string) (os.FileInfo, error) {
+	f, err := o.fs.stat("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (o *memoryOs) MkdirAll(path string, perm os.FileMode) error {
+	return o.fs.mkdirAll(path, perm)
+}
+
+func (o *memoryOs) Remove(name string) error {
+	return o.fs.remove(name)
+}
+
+func (o *memoryOs) Rename(oldpath, newpath string) error {
+	return o.fs.rename(oldpath, newpath)
+}
+
+func (o *memoryOs) Chown(name string, uid, gid int) error {
+	_, err := o.fs.stat("chown", name)
+	return err
+}
+
+type memoryIoutil struct {
+	ioutilshim.Ioutil
+	fs *MemoryFS
+}
+
+func (i *memoryIoutil) ReadFile(filename string) ([]byte, error) {
+	return i.fs.readFile("open", filename)
+}
+
+func (i *memoryIoutil) WriteFile(filename string, data []byte, perm os.FileMode) error {
+	return i.fs.writeFile(filename, data, perm)
+}
+
+func (i *memoryIoutil) ReadDir(dirname string) ([]os.FileInfo, error) {
+	return i.fs.readDir(dirname)
+}
@@ -0,0 +1,205 @@
+package testhelpers
+
+import (
+	"errors"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/volumedriver"
+)
+
+// MountCall is a Mount request received by a ScriptedMounter.
+type MountCall struct {
+	Source string
+	Target string
+	Opts   map[string]interface{}
+}
+
+// ScriptedMounter is a volumedriver.Mounter that only remembers what is
+// mounted where. Failures are scripted up front with FailMount and
+// FailUnmount, mounts are broken with Break and held up with HoldMounts. It also serves as the
+// mountchecker.MountChecker of the driver, so that the driver sees the same
+// mounts.
+type ScriptedMounter struct {
+	lock         sync.Mutex
+	mounts       map[string]string
+	broken       map[string]bool
+	mountErrs    map[string][]error
+	unmountErrs  map[string][]error
+	held         map[string]chan struct{}
+	mountCalls   []MountCall
+	unmountCalls []string
+}
+
+func NewScriptedMounter() *ScriptedMounter {
+	return &ScriptedMounter{
+		mounts:      map[string]string{},
+		broken:      map[string]bool{},
+		mountErrs:   map[string][]error{},
+		unmountErrs: map[string][]error{},
+		held:        map[string]chan struct{}{},
+	}
+}
+
+// FailMount makes the next mounts of source fail with errs, one error per
+// mount. Mounts succeed again once errs are used up.
+func (m *ScriptedMounter) FailMount(source string, errs ...error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.mountErrs[source] = append(m.mountErrs[source], errs...)
+}
+
+// FailUnmount makes the next unmounts of target fail with errs, one error
+// per unmount.
+func (m *ScriptedMounter) FailUnmount(target string, errs ...error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.unmountErrs[target] = append(m.unmountErrs[target], errs...)
+}
+
+// HoldMounts makes the next mounts of source wait, once they show in
+// MountCalls, until release is called.
+func (m *ScriptedMounter) HoldMounts(source string) (release func()) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	held := make(chan struct{})
+	m.held[source] = held
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.lock.Lock()
+			defer m.lock.Unlock()
+
+			if m.held[source] == held {
+				delete(m.held, source)
+			}
+			close(held)
+		})
+	}
+}
+
+// Break makes Check report the mount at target as unhealthy, like a stale
+// NFS handle, until it is mounted again.
+func (m *ScriptedMounter) Break(target string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.broken[target] = true
+}
+
+// Mounts returns the sources currently mounted, by target.
+func (m *ScriptedMounter) Mounts() map[string]string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	mounts := map[string]string{}
+	for target, source := range m.mounts {
+		mounts[target] = source
+	}
+	return mounts
+}
+
+func (m *ScriptedMounter) MountCalls() []MountCall {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return append([]MountCall{}, m.mountCalls...)
+}
+
+func (m *ScriptedMounter) UnmountCalls() []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return append([]string{}, m.unmountCalls...)
+}
+
+func (m *ScriptedMounter) Mount(env dockerdriver.Env, source string, target string, opts map[string]interface{}) error {
+	m.lock.Lock()
+	m.mountCalls = append(m.mountCalls, MountCall{Source: source, Target: target, Opts: opts})
+	held := m.held[source]
+	m.lock.Unlock()
+
+	if held != nil {
+		<-held
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if errs := m.mountErrs[source]; len(errs) > 0 {
+		m.mountErrs[source] = errs[1:]
+		return errs[0]
+	}
+
+	m.mounts[target] = source
+	delete(m.broken, target)
+	return nil
+}
+
+func (m *ScriptedMounter) Unmount(env dockerdriver.Env, target string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.unmountCalls = append(m.unmountCalls, target)
+
+	if errs := m.unmountErrs[target]; len(errs) > 0 {
+		m.unmountErrs[target] = errs[1:]
+		return errs[0]
+	}
+
+	if _, ok := m.mounts[target]; !ok {
+		return errors.New("unmount failed: not mounted")
+	}
+	delete(m.mounts, target)
+	delete(m.broken, target)
+	return nil
+}
+
+func (m *ScriptedMounter) Check(env dockerdriver.Env, name, mountPoint string, depth volumedriver.CheckDepth) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	_, ok := m.mounts[mountPoint]
+	return ok && !m.broken[mountPoint]
+}
+
+func (m *ScriptedMounter) Purge(env dockerdriver.Env, path string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	prefix := filepath.Clean(path) + "/"
+	for target := range m.mounts {
+		if strings.HasPrefix(target, prefix) {
+			delete(m.mounts, target)
+			delete(m.broken, target)
+		}
+	}
+}
+
+func (m *ScriptedMounter) Exists(mountPath string) (bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	_, ok := m.mounts[mountPath]
+	return ok, nil
+}
+
+func (m *ScriptedMounter) List(pattern *regexp.Regexp) ([]string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	mounts := []string{}
+	for target := range m.mounts {
+		if pattern.MatchString(target) {
+			mounts = append(mounts, target)
+		}
+	}
+	return mounts, nil
+}
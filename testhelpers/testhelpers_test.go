@@ -0,0 +1,148 @@
+package testhelpers_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver/conformance"
+	"code.cloudfoundry.org/volumedriver/testhelpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Testhelpers", func() {
+	conformance.DescribeDriver("MemoryDriver", func() conformance.DriverConfig {
+		return conformance.DriverConfig{
+			Driver:     testhelpers.NewMemoryDriver(lagertest.NewTestLogger("memory-driver")),
+			CreateOpts: map[string]interface{}{"source": "server:/export"},
+		}
+	})
+
+	Context("with a scratch directory", func() {
+		var mountRoot string
+
+		BeforeEach(func() {
+			var err error
+			mountRoot, err = ioutil.TempDir("", "testhelpers")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(mountRoot)).To(Succeed())
+		})
+
+		conformance.DescribeMounter("ScriptedMounter", func() conformance.MounterConfig {
+			return conformance.MounterConfig{
+				Mounter:   testhelpers.NewScriptedMounter(),
+				Source:    "server:/export",
+				MountRoot: mountRoot,
+			}
+		})
+	})
+
+	Describe("MemoryDriver", func() {
+		var (
+			logger *lagertest.TestLogger
+			env    dockerdriver.Env
+			driver *testhelpers.MemoryDriver
+		)
+
+		BeforeEach(func() {
+			logger = lagertest.NewTestLogger("memory-driver")
+			env = driverhttp.NewHttpDriverEnv(logger, context.TODO())
+			driver = testhelpers.NewMemoryDriver(logger)
+
+			Expect(driver.Create(env, dockerdriver.CreateRequest{
+				Name: "volume",
+				Opts: map[string]interface{}{"source": "server:/export", "uid": "1000"},
+			}).Err).To(BeEmpty())
+		})
+
+		It("mounts through the scripted mounter", func() {
+			mountResponse := driver.Mount(env, dockerdriver.MountRequest{Name: "volume"})
+			Expect(mountResponse.Err).To(BeEmpty())
+			Expect(mountResponse.Mountpoint).To(Equal(filepath.Join(testhelpers.MountPathRoot, "volume")))
+
+			Expect(driver.Mounter.Mounts()).To(Equal(map[string]string{mountResponse.Mountpoint: "server:/export"}))
+			Expect(driver.Mounter.MountCalls()).To(ConsistOf(testhelpers.MountCall{
+				Source: "server:/export",
+				Target: mountResponse.Mountpoint,
+				Opts: map[string]interface{}{
+					"uid":    "1000",
+					"nosuid": true,
+					"nodev":  true,
+					"noexec": true,
+				},
+			}))
+			Expect(driver.FS.Exists(mountResponse.Mountpoint)).To(BeTrue())
+		})
+
+		It("reports scripted mount failures", func() {
+			driver.Mounter.FailMount("server:/export", errors.New("access denied"))
+
+			Expect(driver.Mount(env, dockerdriver.MountRequest{Name: "volume"}).Err).To(ContainSubstring("access denied"))
+			Expect(driver.Mounter.Mounts()).To(BeEmpty())
+		})
+
+		It("remounts broken mounts", func() {
+			mountpoint := driver.Mount(env, dockerdriver.MountRequest{Name: "volume"}).Mountpoint
+			driver.Mounter.Break(mountpoint)
+
+			Expect(driver.Mount(env, dockerdriver.MountRequest{Name: "volume"}).Err).To(BeEmpty())
+			Expect(driver.Mounter.MountCalls()).To(HaveLen(2))
+			Expect(driver.Mounter.Check(env, "volume", mountpoint, "stat")).To(BeTrue())
+		})
+
+		It("holds mounts until they are released", func() {
+			release := driver.Mounter.HoldMounts("server:/export")
+
+			mounted := make(chan dockerdriver.MountResponse)
+			go func() {
+				mounted <- driver.Mount(env, dockerdriver.MountRequest{Name: "volume"})
+			}()
+
+			Eventually(driver.Mounter.MountCalls).Should(HaveLen(1))
+			Consistently(mounted).ShouldNot(Receive())
+
+			release()
+			var mountResponse dockerdriver.MountResponse
+			Eventually(mounted).Should(Receive(&mountResponse))
+			Expect(mountResponse.Err).To(BeEmpty())
+			Expect(driver.Mounter.Mounts()).To(HaveKey(mountResponse.Mountpoint))
+		})
+
+		It("unmounts and removes the mountpoint", func() {
+			mountpoint := driver.Mount(env, dockerdriver.MountRequest{Name: "volume"}).Mountpoint
+
+			Expect(driver.Unmount(env, dockerdriver.UnmountRequest{Name: "volume"}).Err).To(BeEmpty())
+			Expect(driver.Mounter.UnmountCalls()).To(Equal([]string{mountpoint}))
+			Expect(driver.Mounter.Mounts()).To(BeEmpty())
+			Expect(driver.FS.Exists(mountpoint)).To(BeFalse())
+		})
+
+		It("reports scripted unmount failures", func() {
+			mountpoint := driver.Mount(env, dockerdriver.MountRequest{Name: "volume"}).Mountpoint
+			driver.Mounter.FailUnmount(mountpoint, errors.New("device busy"))
+
+			Expect(driver.Unmount(env, dockerdriver.UnmountRequest{Name: "volume"}).Err).To(ContainSubstring("device busy"))
+			Expect(driver.Mounter.Mounts()).To(HaveKey(mountpoint))
+		})
+
+		It("restores mounted volumes after a restart", func() {
+			mountpoint := driver.Mount(env, dockerdriver.MountRequest{Name: "volume"}).Mountpoint
+
+			restarted := driver.Restart(logger)
+
+			getResponse := restarted.Get(env, dockerdriver.GetRequest{Name: "volume"})
+			Expect(getResponse.Err).To(BeEmpty())
+			Expect(getResponse.Volume.Mountpoint).To(Equal(mountpoint))
+			Expect(getResponse.Volume.MountCount).To(Equal(1))
+		})
+	})
+})
@@ -0,0 +1,323 @@
+package tlstunnel
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/goshims/ioutilshim"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+)
+
+// TLSOpt mounts the volume through a TLS tunnel to its server. TLSCAOpt is a
+// PEM file of the CAs that sign the certificate of the server, instead of
+// the CAs of the host, TLSServerNameOpt the name the certificate is verified
+// against, the host of the source by default, and TLSPortOpt the port the
+// server terminates TLS on.
+const (
+	TLSOpt           = "tls"
+	TLSCAOpt         = "tls_ca"
+	TLSServerNameOpt = "tls_server_name"
+	TLSPortOpt       = "tls_port"
+)
+
+// DefaultTLSPort is the port of nfs, on which servers such as stunnel in
+// server mode commonly terminate TLS in front of the filer.
+const DefaultTLSPort = 2049
+
+var tlsOpts = []string{TLSOpt, TLSCAOpt, TLSServerNameOpt, TLSPortOpt}
+
+// sharedTunnel is the tunnel to one server, shared by the mounts of all
+// volumes on it.
+type sharedTunnel struct {
+	tunnel Tunnel
+	mounts int
+}
+
+type tlsMounter struct {
+	mounter  volumedriver.Mounter
+	tunneler Tunneler
+	ioutil   ioutilshim.Ioutil
+
+	lock sync.Mutex
+	// tunnels holds the open tunnels by tunnelKey, and tunneled the
+	// tunnelKey of every target mounted through one of them
+	tunnels  map[string]*sharedTunnel
+	tunneled map[string]string
+}
+
+// NewTLSMounter returns a Mounter that mounts volumes created with the tls
+// opt through a tunnel of tunneler, so that data in transit is encrypted
+// when the filer does not support krb5p. Servers need a TLS endpoint, such
+// as stunnel in server mode, in front of nfs. Other volumes are mounted by
+// mounter directly.
+//
+// Only nfs version 4 is tunneled, because version 3 also needs the ports of
+// mountd and the lock manager. The tunnels live in the driver process and
+// are shared by the volumes of a server; after a restart of the driver, the
+// mounts through them stall until the volumes are mounted again.
+func NewTLSMounter(mounter volumedriver.Mounter, tunneler Tunneler, ioutil ioutilshim.Ioutil) volumedriver.Mounter {
+	return &tlsMounter{
+		mounter:  mounter,
+		tunneler: tunneler,
+		ioutil:   ioutil,
+		tunnels:  map[string]*sharedTunnel{},
+		tunneled: map[string]string{},
+	}
+}
+
+func (m *tlsMounter) Mount(env dockerdriver.Env, source string, target string, opts map[string]interface{}) error {
+	logger := env.Logger().Session("tls-mount", lager.Data{"source": source, "target": target})
+
+	enabled, err := tlsEnabled(opts)
+	if err != nil {
+		logger.Error("invalid-opts", err)
+		return err
+	}
+	if !enabled {
+		return m.mounter.Mount(env, source, target, opts)
+	}
+	logger.Info("start")
+	defer logger.Info("end")
+
+	host, export, err := splitSource(source)
+	if err != nil {
+		logger.Error("invalid-source", err)
+		return err
+	}
+	remote, config, err := m.tunnelConfig(host, opts)
+	if err != nil {
+		logger.Error("invalid-opts", err)
+		return err
+	}
+
+	key := tunnelKey(remote, config.ServerName, opts)
+	tunnel, err := m.acquire(env, key, remote, config)
+	if err != nil {
+		return err
+	}
+
+	tunneledOpts := map[string]interface{}{}
+	for k, v := range opts {
+		tunneledOpts[k] = v
+	}
+	for _, k := range tlsOpts {
+		delete(tunneledOpts, k)
+	}
+	tunneledOpts["port"] = tunnel.Port()
+	tunneledOpts["proto"] = "tcp"
+
+	logger.Info("mounting-through-tunnel", lager.Data{"remote": remote, "port": tunnel.Port()})
+	if err := m.mounter.Mount(env, "127.0.0.1:"+export, target, tunneledOpts); err != nil {
+		m.release(env, key)
+		return err
+	}
+
+	m.lock.Lock()
+	previous, remounted := m.tunneled[target]
+	m.tunneled[target] = key
+	m.lock.Unlock()
+	if remounted {
+		m.release(env, previous)
+	}
+	return nil
+}
+
+func (m *tlsMounter) Unmount(env dockerdriver.Env, target string) error {
+	if err := m.mounter.Unmount(env, target); err != nil {
+		return err
+	}
+
+	m.lock.Lock()
+	key, ok := m.tunneled[target]
+	delete(m.tunneled, target)
+	m.lock.Unlock()
+	if ok {
+		m.release(env, key)
+	}
+	return nil
+}
+
+func (m *tlsMounter) Check(env dockerdriver.Env, name, mountPoint string, depth volumedriver.CheckDepth) bool {
+	return m.mounter.Check(env, name, mountPoint, depth)
+}
+
+func (m *tlsMounter) Purge(env dockerdriver.Env, path string) {
+	m.mounter.Purge(env, path)
+
+	prefix := filepath.Clean(path) + string(filepath.Separator)
+	purged := []string{}
+
+	m.lock.Lock()
+	for target, key := range m.tunneled {
+		if strings.HasPrefix(target, prefix) {
+			delete(m.tunneled, target)
+			purged = append(purged, key)
+		}
+	}
+	m.lock.Unlock()
+
+	for _, key := range purged {
+		m.release(env, key)
+	}
+}
+
+// acquire opens the tunnel of key, or shares it when it is open.
+func (m *tlsMounter) acquire(env dockerdriver.Env, key string, remote string, config *tls.Config) (Tunnel, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if shared, ok := m.tunnels[key]; ok {
+		shared.mounts++
+		return shared.tunnel, nil
+	}
+
+	tunnel, err := m.tunneler.Open(env, remote, config)
+	if err != nil {
+		return nil, err
+	}
+	m.tunnels[key] = &sharedTunnel{tunnel: tunnel, mounts: 1}
+	return tunnel, nil
+}
+
+// release closes the tunnel of key with its last mount.
+func (m *tlsMounter) release(env dockerdriver.Env, key string) {
+	logger := env.Logger().Session("release-tunnel")
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	shared, ok := m.tunnels[key]
+	if !ok {
+		return
+	}
+	shared.mounts--
+	if shared.mounts > 0 {
+		return
+	}
+
+	delete(m.tunnels, key)
+	if err := shared.tunnel.Close(); err != nil {
+		logger.Error("close-failed", err)
+	}
+}
+
+// tunnelConfig returns the remote address and TLS config of the tunnel for
+// a source on host.
+func (m *tlsMounter) tunnelConfig(host string, opts map[string]interface{}) (string, *tls.Config, error) {
+	version := fmt.Sprintf("%v", opts["vers"])
+	if _, ok := opts["vers"]; !ok {
+		version = fmt.Sprintf("%v", opts["nfsvers"])
+	}
+	if !strings.HasPrefix(version, "4") {
+		return "", nil, safeerrors.New(safeerrors.InvalidOption, "the %s opt requires vers 4, 4.0, 4.1 or 4.2", TLSOpt)
+	}
+	if proto, ok := opts["proto"]; ok && fmt.Sprintf("%v", proto) != "tcp" && fmt.Sprintf("%v", proto) != "tcp6" {
+		return "", nil, safeerrors.New(safeerrors.InvalidOption, "the %s opt cannot be combined with proto '%v'", TLSOpt, proto)
+	}
+	if _, ok := opts["port"]; ok {
+		return "", nil, safeerrors.New(safeerrors.InvalidOption, "the port opt cannot be combined with the %s opt, use %s", TLSOpt, TLSPortOpt)
+	}
+
+	port := DefaultTLSPort
+	if value, ok := opts[TLSPortOpt]; ok {
+		var err error
+		port, err = strconv.Atoi(fmt.Sprintf("%v", value))
+		if err != nil || port < 1 || port > 65535 {
+			return "", nil, safeerrors.New(safeerrors.InvalidOption, "invalid %s '%v', must be a port number", TLSPortOpt, value)
+		}
+	}
+
+	config := &tls.Config{
+		ServerName: host,
+		MinVersion: tls.VersionTLS12,
+	}
+	if serverName, ok := opts[TLSServerNameOpt]; ok {
+		config.ServerName = fmt.Sprintf("%v", serverName)
+	}
+
+	if caFile, ok := opts[TLSCAOpt]; ok {
+		pem, err := m.ioutil.ReadFile(fmt.Sprintf("%v", caFile))
+		if err != nil {
+			return "", nil, safeerrors.New(safeerrors.InvalidOption, "cannot read %s '%v': %s", TLSCAOpt, caFile, err.Error())
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return "", nil, safeerrors.New(safeerrors.InvalidOption, "%s '%v' holds no PEM certificates", TLSCAOpt, caFile)
+		}
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(port)), config, nil
+}
+
+// tlsEnabled reports whether opts ask for a tunnel. The other tls opts are
+// refused without the tls opt, rather than mounting in the clear.
+func tlsEnabled(opts map[string]interface{}) (bool, error) {
+	enabled := false
+	if value, ok := opts[TLSOpt]; ok {
+		switch v := value.(type) {
+		case bool:
+			enabled = v
+		case string:
+			switch strings.ToLower(v) {
+			case "true", "":
+				enabled = true
+			case "false":
+			default:
+				return false, safeerrors.New(safeerrors.InvalidOption, "invalid %s '%v', must be true or false", TLSOpt, value)
+			}
+		default:
+			return false, safeerrors.New(safeerrors.InvalidOption, "invalid %s '%v', must be true or false", TLSOpt, value)
+		}
+	}
+
+	if !enabled {
+		for _, k := range tlsOpts[1:] {
+			if _, ok := opts[k]; ok {
+				return false, safeerrors.New(safeerrors.InvalidOption, "the %s opt requires the %s opt", k, TLSOpt)
+			}
+		}
+	}
+	return enabled, nil
+}
+
+// tunnelKey identifies the tunnels that mounts can share. Mounts that
+// verify the server differently get tunnels of their own.
+func tunnelKey(remote string, serverName string, opts map[string]interface{}) string {
+	caFile, _ := opts[TLSCAOpt]
+	return fmt.Sprintf("%s|%s|%v", remote, serverName, caFile)
+}
+
+func splitSource(source string) (string, string, error) {
+	invalid := safeerrors.New(safeerrors.InvalidSource, "invalid nfs source '%s', expected host:/export", source)
+
+	hostEnd := 0
+	if strings.HasPrefix(source, "[") {
+		hostEnd = strings.Index(source, "]")
+		if hostEnd < 0 {
+			return "", "", invalid
+		}
+		hostEnd++
+	}
+
+	sep := strings.Index(source[hostEnd:], ":/")
+	if sep < 0 {
+		return "", "", invalid
+	}
+	sep += hostEnd
+
+	host := strings.TrimSuffix(strings.TrimPrefix(source[:sep], "["), "]")
+	if host == "" {
+		return "", "", invalid
+	}
+
+	return host, source[sep+1:], nil
+}
@@ -0,0 +1,233 @@
+package tlstunnel_test
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/goshims/ioutilshim/ioutil_fake"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+	"code.cloudfoundry.org/volumedriver/tlstunnel"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TLSMounter", func() {
+	var (
+		logger       *lagertest.TestLogger
+		env          dockerdriver.Env
+		fakeMounter  *volumedriverfakes.FakeMounter
+		fakeTunneler *volumedriverfakes.FakeTunneler
+		fakeTunnel   *volumedriverfakes.FakeTunnel
+		fakeIoutil   *ioutil_fake.FakeIoutil
+		mounter      volumedriver.Mounter
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("tlstunnel")
+		env = driverhttp.NewHttpDriverEnv(logger, context.TODO())
+		fakeMounter = &volumedriverfakes.FakeMounter{}
+		fakeTunneler = &volumedriverfakes.FakeTunneler{}
+		fakeTunnel = &volumedriverfakes.FakeTunnel{}
+		fakeTunnel.PortReturns(40123)
+		fakeTunneler.OpenReturns(fakeTunnel, nil)
+		fakeIoutil = &ioutil_fake.FakeIoutil{}
+
+		mounter = tlstunnel.NewTLSMounter(fakeMounter, fakeTunneler, fakeIoutil)
+	})
+
+	Describe("Mount", func() {
+		var (
+			opts map[string]interface{}
+			err  error
+		)
+
+		BeforeEach(func() {
+			opts = map[string]interface{}{"tls": true, "vers": "4.1", "hard": true}
+		})
+
+		JustBeforeEach(func() {
+			err = mounter.Mount(env, "filer.example.com:/export/path", "/mnt/target", opts)
+		})
+
+		It("mounts the export through a tunnel to the server", func() {
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeTunneler.OpenCallCount()).To(Equal(1))
+			_, remote, config := fakeTunneler.OpenArgsForCall(0)
+			Expect(remote).To(Equal("filer.example.com:2049"))
+			Expect(config.ServerName).To(Equal("filer.example.com"))
+			Expect(config.MinVersion).To(Equal(uint16(tls.VersionTLS12)))
+			Expect(config.RootCAs).To(BeNil())
+
+			Expect(fakeMounter.MountCallCount()).To(Equal(1))
+			_, source, target, mountOpts := fakeMounter.MountArgsForCall(0)
+			Expect(source).To(Equal("127.0.0.1:/export/path"))
+			Expect(target).To(Equal("/mnt/target"))
+			Expect(mountOpts).To(Equal(map[string]interface{}{"vers": "4.1", "hard": true, "port": 40123, "proto": "tcp"}))
+		})
+
+		It("shares the tunnel with other mounts of the server", func() {
+			Expect(mounter.Mount(env, "filer.example.com:/export/other", "/mnt/other", opts)).To(Succeed())
+			Expect(fakeTunneler.OpenCallCount()).To(Equal(1))
+
+			Expect(mounter.Unmount(env, "/mnt/target")).To(Succeed())
+			Expect(fakeTunnel.CloseCallCount()).To(Equal(0))
+			Expect(mounter.Unmount(env, "/mnt/other")).To(Succeed())
+			Expect(fakeTunnel.CloseCallCount()).To(Equal(1))
+		})
+
+		It("opens a tunnel of its own for a server verified differently", func() {
+			opts[tlstunnel.TLSServerNameOpt] = "nfs.internal"
+			Expect(mounter.Mount(env, "filer.example.com:/export/other", "/mnt/other", opts)).To(Succeed())
+			Expect(fakeTunneler.OpenCallCount()).To(Equal(2))
+			_, _, config := fakeTunneler.OpenArgsForCall(1)
+			Expect(config.ServerName).To(Equal("nfs.internal"))
+		})
+
+		It("closes the tunnel when the mounts below a purged path go away", func() {
+			mounter.Purge(env, "/mnt")
+			Expect(fakeMounter.PurgeCallCount()).To(Equal(1))
+			Expect(fakeTunnel.CloseCallCount()).To(Equal(1))
+		})
+
+		Context("when the tls opt is not set", func() {
+			BeforeEach(func() {
+				opts = map[string]interface{}{"vers": "3"}
+			})
+
+			It("mounts directly", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeTunneler.OpenCallCount()).To(Equal(0))
+				_, source, _, mountOpts := fakeMounter.MountArgsForCall(0)
+				Expect(source).To(Equal("filer.example.com:/export/path"))
+				Expect(mountOpts).To(Equal(opts))
+			})
+		})
+
+		Context("when the tls opts are set", func() {
+			BeforeEach(func() {
+				opts = map[string]interface{}{"tls": "true", "nfsvers": 4.2, "tls_port": float64(20490), "tls_ca": "/var/vcap/jobs/nfs/ca.pem"}
+				_, caPEM := selfSignedCertificate("filer.example.com")
+				fakeIoutil.ReadFileReturns(caPEM, nil)
+			})
+
+			It("configures the tunnel with them", func() {
+				Expect(err).NotTo(HaveOccurred())
+				_, remote, config := fakeTunneler.OpenArgsForCall(0)
+				Expect(remote).To(Equal("filer.example.com:20490"))
+				Expect(config.RootCAs).NotTo(BeNil())
+				Expect(fakeIoutil.ReadFileArgsForCall(0)).To(Equal("/var/vcap/jobs/nfs/ca.pem"))
+
+				_, _, _, mountOpts := fakeMounter.MountArgsForCall(0)
+				Expect(mountOpts).To(Equal(map[string]interface{}{"nfsvers": 4.2, "port": 40123, "proto": "tcp"}))
+			})
+		})
+
+		Context("when the tunnel cannot be opened", func() {
+			BeforeEach(func() {
+				fakeTunneler.OpenReturns(nil, errors.New("tls handshake with 'filer.example.com:2049' failed: EOF"))
+			})
+
+			It("fails without mounting", func() {
+				Expect(err).To(MatchError("tls handshake with 'filer.example.com:2049' failed: EOF"))
+				Expect(fakeMounter.MountCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the mount fails", func() {
+			BeforeEach(func() {
+				fakeMounter.MountReturns(errors.New("connection refused"))
+			})
+
+			It("closes the tunnel", func() {
+				Expect(err).To(MatchError("connection refused"))
+				Expect(fakeTunnel.CloseCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when the opts are invalid", func() {
+			BeforeEach(func() {
+				opts = map[string]interface{}{"tls": true, "vers": "3"}
+			})
+
+			It("rejects them without opening a tunnel", func() {
+				Expect(err).To(MatchError("the tls opt requires vers 4, 4.0, 4.1 or 4.2"))
+				safe, ok := safeerrors.From(err)
+				Expect(ok).To(BeTrue())
+				Expect(safe.Code).To(Equal(safeerrors.InvalidOption))
+				Expect(fakeTunneler.OpenCallCount()).To(Equal(0))
+				Expect(fakeMounter.MountCallCount()).To(Equal(0))
+			})
+
+			It("rejects every kind of invalid value", func() {
+				fakeIoutil.ReadFileStub = func(path string) ([]byte, error) {
+					if path == "/missing.pem" {
+						return nil, errors.New("no such file or directory")
+					}
+					return []byte("not a certificate"), nil
+				}
+
+				invalid := []struct {
+					opts    map[string]interface{}
+					message string
+				}{
+					{map[string]interface{}{"tls": "yes", "vers": "4.1"}, "invalid tls 'yes', must be true or false"},
+					{map[string]interface{}{"tls_ca": "/ca.pem", "vers": "4.1"}, "the tls_ca opt requires the tls opt"},
+					{map[string]interface{}{"tls": false, "tls_port": "20490"}, "the tls_port opt requires the tls opt"},
+					{map[string]interface{}{"tls": true}, "the tls opt requires vers 4, 4.0, 4.1 or 4.2"},
+					{map[string]interface{}{"tls": true, "vers": "4.1", "proto": "rdma"}, "the tls opt cannot be combined with proto 'rdma'"},
+					{map[string]interface{}{"tls": true, "vers": "4.1", "port": "2049"}, "the port opt cannot be combined with the tls opt, use tls_port"},
+					{map[string]interface{}{"tls": true, "vers": "4.1", "tls_port": "70000"}, "invalid tls_port '70000', must be a port number"},
+					{map[string]interface{}{"tls": true, "vers": "4.1", "tls_ca": "/missing.pem"}, "cannot read tls_ca '/missing.pem': no such file or directory"},
+					{map[string]interface{}{"tls": true, "vers": "4.1", "tls_ca": "/garbage.pem"}, "tls_ca '/garbage.pem' holds no PEM certificates"},
+				}
+				for _, c := range invalid {
+					err := mounter.Mount(env, "filer.example.com:/export/path", "/mnt/target", c.opts)
+					Expect(err).To(MatchError(c.message))
+					safe, ok := safeerrors.From(err)
+					Expect(ok).To(BeTrue())
+					Expect(safe.Code).To(Equal(safeerrors.InvalidOption))
+				}
+				Expect(fakeTunneler.OpenCallCount()).To(Equal(0))
+				Expect(fakeMounter.MountCallCount()).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("Unmount", func() {
+		BeforeEach(func() {
+			Expect(mounter.Mount(env, "filer.example.com:/export/path", "/mnt/target", map[string]interface{}{"tls": true, "vers": "4"})).To(Succeed())
+		})
+
+		It("unmounts and closes the tunnel of the last mount", func() {
+			Expect(mounter.Unmount(env, "/mnt/target")).To(Succeed())
+			Expect(fakeMounter.UnmountCallCount()).To(Equal(1))
+			Expect(fakeTunnel.CloseCallCount()).To(Equal(1))
+		})
+
+		Context("when the unmount fails", func() {
+			BeforeEach(func() {
+				fakeMounter.UnmountReturns(errors.New("device busy"))
+			})
+
+			It("keeps the tunnel open", func() {
+				Expect(mounter.Unmount(env, "/mnt/target")).To(MatchError("device busy"))
+				Expect(fakeTunnel.CloseCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the target was not mounted through a tunnel", func() {
+			It("only unmounts", func() {
+				Expect(mounter.Unmount(env, "/mnt/other")).To(Succeed())
+				Expect(fakeMounter.UnmountCallCount()).To(Equal(1))
+				Expect(fakeTunnel.CloseCallCount()).To(Equal(0))
+			})
+		})
+	})
+})
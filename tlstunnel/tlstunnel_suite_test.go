@@ -0,0 +1,13 @@
+package tlstunnel_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestTLSTunnel(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "TLSTunnel Suite")
+}
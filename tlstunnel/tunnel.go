@@ -0,0 +1,184 @@
+package tlstunnel
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+)
+
+// DefaultDialTimeout bounds how long the proxy waits for the TLS handshake
+// with the server.
+const DefaultDialTimeout = 10 * time.Second
+
+//go:generate counterfeiter -o ../volumedriverfakes/fake_tunneler.go . Tunneler
+type Tunneler interface {
+	// Open starts a tunnel that accepts plain connections on a local port
+	// and forwards them to remote, a host:port, wrapped in TLS with config.
+	Open(env dockerdriver.Env, remote string, config *tls.Config) (Tunnel, error)
+}
+
+//go:generate counterfeiter -o ../volumedriverfakes/fake_tunnel.go . Tunnel
+type Tunnel interface {
+	// Port is the local port on 127.0.0.1 the nfs client connects to.
+	Port() int
+	// Close stops accepting connections and closes the open ones.
+	Close() error
+}
+
+type proxyTunneler struct {
+	dialTimeout time.Duration
+}
+
+// NewProxyTunneler returns a Tunneler that proxies connections in the driver
+// process, so that no stunnel has to be installed on the cell. Open fails
+// when the server does not complete a TLS handshake, which tells a server
+// without TLS and an untrusted certificate apart from an unreachable export.
+func NewProxyTunneler(dialTimeout time.Duration) Tunneler {
+	if dialTimeout <= 0 {
+		dialTimeout = DefaultDialTimeout
+	}
+	return &proxyTunneler{dialTimeout: dialTimeout}
+}
+
+func (t *proxyTunneler) Open(env dockerdriver.Env, remote string, config *tls.Config) (Tunnel, error) {
+	logger := env.Logger().Session("open-tunnel", lager.Data{"remote": remote})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: t.dialTimeout}, "tcp", remote, config)
+	if err != nil {
+		logger.Error("handshake-failed", err)
+		return nil, fmt.Errorf("tls handshake with '%s' failed: %s", remote, err.Error())
+	}
+	conn.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		logger.Error("listen-failed", err)
+		return nil, err
+	}
+
+	p := &proxy{
+		listener:    listener,
+		remote:      remote,
+		config:      config,
+		dialTimeout: t.dialTimeout,
+		logger:      logger.Session("proxy", lager.Data{"port": listener.Addr().(*net.TCPAddr).Port}),
+		conns:       map[net.Conn]bool{},
+	}
+	go p.serve()
+
+	return p, nil
+}
+
+type proxy struct {
+	listener    net.Listener
+	remote      string
+	config      *tls.Config
+	dialTimeout time.Duration
+	logger      lager.Logger
+
+	lock   sync.Mutex
+	conns  map[net.Conn]bool
+	closed bool
+}
+
+func (p *proxy) Port() int {
+	return p.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (p *proxy) Close() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+
+	err := p.listener.Close()
+	for conn := range p.conns {
+		conn.Close()
+	}
+	p.conns = nil
+	return err
+}
+
+func (p *proxy) serve() {
+	for {
+		client, err := p.listener.Accept()
+		if err != nil {
+			if !p.isClosed() {
+				p.logger.Error("accept-failed", err)
+			}
+			return
+		}
+		go p.forward(client)
+	}
+}
+
+// forward pipes a client connection through a new TLS connection to the
+// server. The nfs client reconnects by itself when either end goes away.
+func (p *proxy) forward(client net.Conn) {
+	server, err := tls.DialWithDialer(&net.Dialer{Timeout: p.dialTimeout}, "tcp", p.remote, p.config)
+	if err != nil {
+		p.logger.Error("dial-failed", err)
+		client.Close()
+		return
+	}
+
+	if !p.track(client, server) {
+		client.Close()
+		server.Close()
+		return
+	}
+	defer p.untrack(client, server)
+
+	done := make(chan struct{}, 2)
+	pipe := func(dst, src net.Conn) {
+		io.Copy(dst, src)
+		done <- struct{}{}
+	}
+	go pipe(server, client)
+	go pipe(client, server)
+
+	// a connection half closed by one side is of no further use to nfs
+	<-done
+	client.Close()
+	server.Close()
+	<-done
+}
+
+func (p *proxy) track(conns ...net.Conn) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.closed {
+		return false
+	}
+	for _, conn := range conns {
+		p.conns[conn] = true
+	}
+	return true
+}
+
+func (p *proxy) untrack(conns ...net.Conn) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for _, conn := range conns {
+		delete(p.conns, conn)
+	}
+}
+
+func (p *proxy) isClosed() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.closed
+}
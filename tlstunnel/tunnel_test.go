@@ -0,0 +1,141 @@
+package tlstunnel_test
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"strconv"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver/tlstunnel"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ProxyTunneler", func() {
+	var (
+		env      dockerdriver.Env
+		server   net.Listener
+		config   *tls.Config
+		tunneler tlstunnel.Tunneler
+	)
+
+	BeforeEach(func() {
+		env = driverhttp.NewHttpDriverEnv(lagertest.NewTestLogger("tlstunnel"), context.TODO())
+
+		certificate, caPEM := selfSignedCertificate("localhost")
+		var err error
+		server, err = tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{certificate}})
+		Expect(err).NotTo(HaveOccurred())
+		go echo(server)
+
+		config = &tls.Config{ServerName: "localhost", RootCAs: x509.NewCertPool()}
+		config.RootCAs.AppendCertsFromPEM(caPEM)
+
+		tunneler = tlstunnel.NewProxyTunneler(time.Second)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("forwards plain connections to the server over TLS", func() {
+		tunnel, err := tunneler.Open(env, server.Addr().String(), config)
+		Expect(err).NotTo(HaveOccurred())
+		defer tunnel.Close()
+
+		conn, err := net.Dial("tcp", "127.0.0.1:"+strconv.Itoa(tunnel.Port()))
+		Expect(err).NotTo(HaveOccurred())
+		defer conn.Close()
+
+		_, err = conn.Write([]byte("ping\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bufio.NewReader(conn).ReadString('\n')).To(Equal("ping\n"))
+	})
+
+	It("closes the open connections and stops listening on Close", func() {
+		tunnel, err := tunneler.Open(env, server.Addr().String(), config)
+		Expect(err).NotTo(HaveOccurred())
+
+		conn, err := net.Dial("tcp", "127.0.0.1:"+strconv.Itoa(tunnel.Port()))
+		Expect(err).NotTo(HaveOccurred())
+		defer conn.Close()
+		_, err = conn.Write([]byte("ping\n"))
+		Expect(err).NotTo(HaveOccurred())
+		reader := bufio.NewReader(conn)
+		Expect(reader.ReadString('\n')).To(Equal("ping\n"))
+
+		Expect(tunnel.Close()).To(Succeed())
+		_, err = reader.ReadString('\n')
+		Expect(err).To(HaveOccurred())
+
+		_, err = net.Dial("tcp", "127.0.0.1:"+strconv.Itoa(tunnel.Port()))
+		Expect(err).To(HaveOccurred())
+	})
+
+	Context("when the certificate of the server does not verify", func() {
+		BeforeEach(func() {
+			config.ServerName = "filer.example.com"
+		})
+
+		It("fails to open the tunnel", func() {
+			_, err := tunneler.Open(env, server.Addr().String(), config)
+			Expect(err).To(MatchError(ContainSubstring("tls handshake with '" + server.Addr().String() + "' failed")))
+		})
+	})
+})
+
+// echo answers every line of the connections of listener with itself.
+func echo(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			reader := bufio.NewReader(conn)
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				conn.Write([]byte(line))
+			}
+		}()
+	}
+}
+
+// selfSignedCertificate returns a certificate for host and its PEM, which
+// serves as its own CA.
+func selfSignedCertificate(host string) (tls.Certificate, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: host},
+		DNSNames:              []string{host},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
@@ -0,0 +1,242 @@
+package volumedriver
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager"
+)
+
+// testMountPrefix names the directories of test mounts below the mount path
+// root. Volumes never get a mount directory that starts with an underscore,
+// so test mounts cannot collide with them; leftovers are collected as
+// orphans.
+const testMountPrefix = "_validate-"
+
+// The checks of a ValidateResponse, in the order Create performs them.
+const (
+	CheckName      = "name"
+	CheckProvision = "provision"
+	CheckSource    = "source"
+	CheckOpts      = "opts"
+	CheckSecrets   = "secrets"
+	CheckDriver    = "driver"
+	CheckFeatures  = "features"
+	CheckQuota     = "quota"
+	CheckTestMount = "test-mount"
+)
+
+// ValidateRequest asks whether Create would accept Name and Opts. With
+// TestMount the source is also mounted to a throwaway mountpoint and
+// unmounted again, which proves that the export is reachable and that the
+// mount options are accepted.
+type ValidateRequest struct {
+	Name      string
+	Opts      map[string]interface{}
+	TestMount bool
+}
+
+// Diagnostic is the outcome of one check. Message explains failures, and
+// for some passed checks what Create would do.
+type Diagnostic struct {
+	Check   string
+	Passed  bool
+	Message string `json:",omitempty"`
+}
+
+// ValidateResponse is Valid when every check passed. Checks that depend on
+// a failed check are not performed. Err is set when the request could not
+// be validated at all.
+type ValidateResponse struct {
+	Valid       bool
+	Diagnostics []Diagnostic
+	Err         string
+}
+
+//go:generate counterfeiter -o volumedriverfakes/fake_validator.go . Validator
+type Validator interface {
+	// Validate performs the checks of Create without changing any state.
+	Validate(env dockerdriver.Env, validateRequest ValidateRequest) ValidateResponse
+}
+
+type validation struct {
+	diagnostics []Diagnostic
+	failed      bool
+}
+
+func (v *validation) pass(check string, message string) {
+	v.diagnostics = append(v.diagnostics, Diagnostic{Check: check, Passed: true, Message: message})
+}
+
+func (v *validation) check(check string, err error) bool {
+	if err != nil {
+		v.diagnostics = append(v.diagnostics, Diagnostic{Check: check, Message: err.Error()})
+		v.failed = true
+		return false
+	}
+	v.pass(check, "")
+	return true
+}
+
+func (v *validation) response() ValidateResponse {
+	return ValidateResponse{Valid: !v.failed, Diagnostics: v.diagnostics}
+}
+
+func (d *VolumeDriver) Validate(env dockerdriver.Env, validateRequest ValidateRequest) ValidateResponse {
+	logger := env.Logger().Session("validate", lager.Data{"volume": validateRequest.Name, "test-mount": validateRequest.TestMount})
+	logger.Info("start")
+	defer logger.Info("end")
+	defer d.inFlight.start(env, "validate", validateRequest.Name).done()
+
+	v := &validation{}
+	defer func() {
+		logger.Info("validated", lager.Data{"diagnostics": v.diagnostics})
+	}()
+
+	if validateRequest.Name == "" {
+		v.check(CheckName, errors.New("Missing mandatory 'volume_name'"))
+		return v.response()
+	}
+	if err := ValidateVolumeName(validateRequest.Name); err != nil {
+		v.check(CheckName, err)
+		return v.response()
+	}
+	if validateRequest.TestMount {
+		if err := d.admit(driverhttp.EnvWithLogger(logger, env), validateRequest.Name); err != nil {
+			return ValidateResponse{Err: errResponse(err)}
+		}
+	}
+
+	d.volumesLock.RLock()
+	_, exists := d.volumes[validateRequest.Name]
+	d.volumesLock.RUnlock()
+	if exists {
+		v.pass(CheckName, fmt.Sprintf("volume '%s' exists, Create would update its opts", validateRequest.Name))
+	} else {
+		v.pass(CheckName, "")
+	}
+
+	// the other checks interpret the opts, which must have the right shape
+	if err := ValidateOpts(validateRequest.Opts); err != nil {
+		v.check(CheckOpts, err)
+		return v.response()
+	}
+
+	opts := map[string]interface{}{}
+	for k, value := range validateRequest.Opts {
+		opts[k] = value
+	}
+
+	provision, err := parseProvisionOpts(validateRequest.Name, opts)
+	if err == nil && provision != nil && d.options.Provisioner == nil {
+		err = fmt.Errorf("the %s opt is not supported by this driver", ProvisionOpt)
+	}
+	if provision != nil || err != nil {
+		if !v.check(CheckProvision, err) {
+			return v.response()
+		}
+		// the source is only known once the export exists
+		v.diagnostics[len(v.diagnostics)-1].Message = fmt.Sprintf("Create would provision an export on '%s'", provision.Parent)
+	} else {
+		if _, err := ParseSource(opts); err != nil {
+			v.check(CheckSource, err)
+			return v.response()
+		}
+		opts, err = d.normalizeSource(opts)
+		if err == nil {
+			err = d.checkSourceAllowed(sourceOpt(opts))
+		}
+		if !v.check(CheckSource, err) {
+			return v.response()
+		}
+		opts = d.applySourceDefaults(opts)
+	}
+
+	opts, err = normalizeCommonOpts(opts)
+	if err == nil {
+		err = validateDriverOpts(opts)
+	}
+	if err == nil {
+		err = d.checkCompositeSourcesAllowed(opts)
+	}
+	if !v.check(CheckOpts, err) {
+		return v.response()
+	}
+	if !v.check(CheckSecrets, d.validateSecretRefs(opts)) {
+		return v.response()
+	}
+
+	driver, err := d.volumeDriver(opts)
+	if !v.check(CheckDriver, err) {
+		return v.response()
+	}
+
+	if !v.check(CheckFeatures, d.checkFeatures(opts)) {
+		return v.response()
+	}
+
+	if !exists {
+		d.volumesLock.RLock()
+		err = d.checkVolumeQuota()
+		d.volumesLock.RUnlock()
+		if !v.check(CheckQuota, err) {
+			return v.response()
+		}
+	}
+
+	if validateRequest.TestMount {
+		if provision != nil {
+			v.check(CheckTestMount, errors.New("volumes that are provisioned cannot be test mounted before they are created"))
+			return v.response()
+		}
+		mounter := d.mounterFor(driver)
+		if composite, _ := parseCompositeOpt(opts); composite != nil {
+			mounter = &compositeMounter{driver: d, mounter: mounter, members: composite}
+		}
+		v.check(CheckTestMount, d.testMount(driverhttp.EnvWithLogger(logger, env), mounter, opts))
+	}
+
+	return v.response()
+}
+
+// checkFeatures fails for opts that ask for features the driver was not
+// configured with.
+func (d *VolumeDriver) checkFeatures(opts map[string]interface{}) error {
+	if subdir, _ := parseSubdirOpts(opts); subdir.subdir != "" && d.options.BindMounter == nil {
+		return errors.New("the subdir opt is not supported by this driver")
+	}
+	if ioLimits, _ := parseIOLimits(opts); ioLimits != nil && d.options.IOThrottler == nil {
+		return errors.New("io limits are not supported by this driver")
+	}
+	if scratch, _ := parseScratchOpt(opts); scratch && (d.options.OverlayMounter == nil || d.options.ScratchDir == "") {
+		return errors.New("the scratch opt is not supported by this driver")
+	}
+	if relabelOpt(opts) != "" && d.options.Labeler == nil {
+		return errors.New("the relabel opt is not supported by this driver")
+	}
+	if err := d.checkRootless(opts); err != nil {
+		return err
+	}
+	return d.checkFSCache(opts)
+}
+
+var testMounts uint64
+
+// testMount mounts the export of opts to a throwaway mountpoint and unmounts
+// it again. The mount is recorded as an intent under the name of its
+// directory, so that a crash does not leave it behind.
+func (d *VolumeDriver) testMount(env dockerdriver.Env, mounter Mounter, opts map[string]interface{}) error {
+	name := fmt.Sprintf("%s%d", testMountPrefix, atomic.AddUint64(&testMounts, 1))
+	mountPath := d.mountPath(env, name)
+
+	if err := d.mount(env, mounter, name, opts, mountPath); err != nil {
+		return fmt.Errorf("mount failed: %s", err.Error())
+	}
+	if err := d.unmount(env, mounter, name, mountPath); err != nil {
+		return fmt.Errorf("mounted, but the unmount failed: %s", err.Error())
+	}
+	return nil
+}
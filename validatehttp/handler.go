@@ -0,0 +1,55 @@
+// Package validatehttp serves Validate next to the volume plugin API, which
+// the docker plugin handler does not know about.
+package validatehttp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	cf_http_handlers "code.cloudfoundry.org/cfhttp/handlers"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+)
+
+const ValidatePath = "/VolumeDriver.Validate"
+
+// NewHandler serves POST /VolumeDriver.Validate with validator and passes
+// every other request to handler, the plugin API handler. Like the plugin
+// API, errors are reported in the response body with a 200 status code.
+func NewHandler(logger lager.Logger, validator volumedriver.Validator, handler http.Handler) http.Handler {
+	logger = logger.Session("validate-server")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != ValidatePath || req.Method != http.MethodPost {
+			handler.ServeHTTP(w, req)
+			return
+		}
+
+		logger := logger.Session("handle-validate")
+		logger.Info("start")
+		defer logger.Info("end")
+
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			logger.Error("failed-reading-validate-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, http.StatusOK, volumedriver.ValidateResponse{Err: err.Error()})
+			return
+		}
+
+		var validateRequest volumedriver.ValidateRequest
+		if err = json.Unmarshal(body, &validateRequest); err != nil {
+			logger.Error("failed-unmarshalling-validate-request-body", err)
+			cf_http_handlers.WriteJSONResponse(w, http.StatusOK, volumedriver.ValidateResponse{Err: err.Error()})
+			return
+		}
+
+		validateResponse := validator.Validate(driverhttp.EnvWithMonitor(logger, req.Context(), w), validateRequest)
+		if validateResponse.Err != "" {
+			logger.Info("failed-validating", lager.Data{"err": validateResponse.Err})
+		}
+
+		cf_http_handlers.WriteJSONResponse(w, http.StatusOK, validateResponse)
+	})
+}
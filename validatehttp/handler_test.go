@@ -0,0 +1,74 @@
+package validatehttp_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/validatehttp"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Handler", func() {
+	var (
+		fakeValidator *volumedriverfakes.FakeValidator
+		passed        []string
+		handler       http.Handler
+	)
+
+	BeforeEach(func() {
+		fakeValidator = &volumedriverfakes.FakeValidator{}
+		passed = []string{}
+		inner := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			passed = append(passed, req.URL.Path)
+		})
+		handler = validatehttp.NewHandler(lagertest.NewTestLogger("validatehttp"), fakeValidator, inner)
+	})
+
+	serve := func(path string, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("POST", path, strings.NewReader(body)))
+		return recorder
+	}
+
+	It("validates requests with the validator", func() {
+		fakeValidator.ValidateReturns(volumedriver.ValidateResponse{
+			Diagnostics: []volumedriver.Diagnostic{{Check: volumedriver.CheckSource, Message: "not allowed"}},
+		})
+
+		recorder := serve(validatehttp.ValidatePath, `{"Name":"volume","Opts":{"source":"server:/export"},"TestMount":true}`)
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(passed).To(BeEmpty())
+
+		Expect(fakeValidator.ValidateCallCount()).To(Equal(1))
+		_, validateRequest := fakeValidator.ValidateArgsForCall(0)
+		Expect(validateRequest).To(Equal(volumedriver.ValidateRequest{
+			Name:      "volume",
+			Opts:      map[string]interface{}{"source": "server:/export"},
+			TestMount: true,
+		}))
+
+		var response volumedriver.ValidateResponse
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+		Expect(response.Valid).To(BeFalse())
+		Expect(response.Diagnostics).To(ConsistOf(volumedriver.Diagnostic{Check: volumedriver.CheckSource, Message: "not allowed"}))
+	})
+
+	It("reports malformed requests in the body", func() {
+		recorder := serve(validatehttp.ValidatePath, `{"Name":`)
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(ContainSubstring(`"Err":"unexpected end of JSON input"`))
+		Expect(fakeValidator.ValidateCallCount()).To(BeZero())
+	})
+
+	It("passes the other requests on", func() {
+		serve("/VolumeDriver.Create", `{"Name":"volume"}`)
+		Expect(passed).To(Equal([]string{"/VolumeDriver.Create"}))
+		Expect(fakeValidator.ValidateCallCount()).To(BeZero())
+	})
+})
@@ -0,0 +1,13 @@
+package validatehttp_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestValidateHTTP(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Validate HTTP Suite")
+}
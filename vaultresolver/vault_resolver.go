@@ -0,0 +1,253 @@
+package vaultresolver
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/cfhttp"
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/goshims/http_wrap"
+	"code.cloudfoundry.org/goshims/timeshim"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+)
+
+// DefaultAuthMount is the path that the AppRole auth method is enabled at.
+const DefaultAuthMount = "approle"
+
+// tokenRenewMargin is how long before its lease ends a token is replaced.
+const tokenRenewMargin = 30 * time.Second
+
+// errForbidden is returned for requests that Vault refused, for example
+// because the token has been revoked.
+var errForbidden = errors.New("permission denied")
+
+// Config describes the Vault server and the AppRole the driver logs in with.
+type Config struct {
+	// Address is the address of the server, for example
+	// https://vault.example.com:8200.
+	Address string
+	// CACertFile verifies the certificate of the server. The system roots
+	// are used when it is empty.
+	CACertFile string
+	// Namespace is sent with every request, for Vault Enterprise.
+	Namespace string
+
+	// AuthMount defaults to DefaultAuthMount.
+	AuthMount string
+	RoleID    string
+	SecretID  string
+}
+
+type vaultResolver struct {
+	config     Config
+	httpClient http_wrap.Client
+	time       timeshim.Time
+
+	lock        sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// NewResolver returns a SecretResolver that reads secrets from Vault. Both
+// KV version 1 and version 2 engines are supported; the path of a secret is
+// the path of the API, which is data/... below the mount for version 2.
+// Secrets are read again for every mount, so that rotated secrets are used
+// right away; only the token of the AppRole login is kept, in memory.
+func NewResolver(config Config) (volumedriver.SecretResolver, error) {
+	client := cfhttp.NewClient()
+
+	if config.CACertFile != "" {
+		caCert, err := ioutil.ReadFile(config.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no certificates found in '%s'", config.CACertFile)
+		}
+		client.Transport.(*http.Transport).TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	return NewResolverWithClient(config, client, &timeshim.TimeShim{}), nil
+}
+
+func NewResolverWithClient(config Config, client http_wrap.Client, time timeshim.Time) volumedriver.SecretResolver {
+	if config.AuthMount == "" {
+		config.AuthMount = DefaultAuthMount
+	}
+	config.Address = strings.TrimSuffix(config.Address, "/")
+
+	return &vaultResolver{
+		config:     config,
+		httpClient: client,
+		time:       time,
+	}
+}
+
+type loginRequest struct {
+	RoleID   string `json:"role_id"`
+	SecretID string `json:"secret_id"`
+}
+
+type loginResponse struct {
+	Auth struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"`
+	} `json:"auth"`
+}
+
+type secretResponse struct {
+	Data map[string]interface{} `json:"data"`
+}
+
+type errorResponse struct {
+	Errors []string `json:"errors"`
+}
+
+func (r *vaultResolver) Resolve(env dockerdriver.Env, path string, key string) (string, error) {
+	logger := env.Logger().Session("vault-resolve", lager.Data{"path": path, "key": key})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	data, err := r.read(env, path)
+	if err == errForbidden {
+		// the token may have been revoked before its lease ended
+		logger.Info("logging-in-again")
+		r.forgetToken()
+		data, err = r.read(env, path)
+	}
+	if err != nil {
+		logger.Error("read-failed", err)
+		return "", err
+	}
+
+	// version 2 of the KV engine nests the secret below data, next to its
+	// metadata
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = nested
+		}
+	}
+
+	value, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("secret '%s' has no key '%s'", path, key)
+	}
+	secret, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("key '%s' of secret '%s' is not a string", key, path)
+	}
+
+	return secret, nil
+}
+
+func (r *vaultResolver) read(env dockerdriver.Env, path string) (map[string]interface{}, error) {
+	token, err := r.login(env)
+	if err != nil {
+		return nil, err
+	}
+
+	var response secretResponse
+	if err := r.do(env.Context(), http.MethodGet, "/v1/"+strings.Trim(path, "/"), token, nil, &response); err != nil {
+		return nil, err
+	}
+	if response.Data == nil {
+		return nil, fmt.Errorf("secret '%s' not found", path)
+	}
+	return response.Data, nil
+}
+
+// login returns the token of the last login while its lease lasts.
+func (r *vaultResolver) login(env dockerdriver.Env) (string, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.token != "" && r.time.Now().Before(r.tokenExpiry) {
+		return r.token, nil
+	}
+
+	var response loginResponse
+	request := loginRequest{RoleID: r.config.RoleID, SecretID: r.config.SecretID}
+	if err := r.do(env.Context(), http.MethodPost, "/v1/auth/"+strings.Trim(r.config.AuthMount, "/")+"/login", "", request, &response); err != nil {
+		return "", fmt.Errorf("approle login failed: %s", err.Error())
+	}
+	if response.Auth.ClientToken == "" {
+		return "", errors.New("approle login failed: no token returned")
+	}
+
+	r.token = response.Auth.ClientToken
+	r.tokenExpiry = r.time.Now().Add(time.Duration(response.Auth.LeaseDuration)*time.Second - tokenRenewMargin)
+	env.Logger().Info("logged-in", lager.Data{"lease-duration": response.Auth.LeaseDuration})
+
+	return r.token, nil
+}
+
+func (r *vaultResolver) forgetToken() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.token = ""
+}
+
+func (r *vaultResolver) do(ctx context.Context, method string, path string, token string, request interface{}, response interface{}) error {
+	var body io.Reader
+	if request != nil {
+		payload, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewBuffer(payload)
+	}
+
+	httpRequest, err := http.NewRequest(method, r.config.Address+path, body)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		httpRequest.Header.Set("X-Vault-Token", token)
+	}
+	if r.config.Namespace != "" {
+		httpRequest.Header.Set("X-Vault-Namespace", r.config.Namespace)
+	}
+
+	httpResponse, err := r.httpClient.Do(httpRequest.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer httpResponse.Body.Close()
+
+	data, err := ioutil.ReadAll(httpResponse.Body)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case httpResponse.StatusCode == http.StatusForbidden:
+		return errForbidden
+	case httpResponse.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%s not found", path)
+	case httpResponse.StatusCode >= http.StatusBadRequest:
+		var vaultError errorResponse
+		if json.Unmarshal(data, &vaultError) == nil && len(vaultError.Errors) > 0 {
+			return fmt.Errorf("%s %s failed: %s", method, path, strings.Join(vaultError.Errors, ", "))
+		}
+		return fmt.Errorf("%s %s failed with status %d", method, path, httpResponse.StatusCode)
+	}
+
+	if err := json.Unmarshal(data, response); err != nil {
+		return errors.New("invalid response from vault: " + err.Error())
+	}
+	return nil
+}
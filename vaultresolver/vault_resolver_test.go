@@ -0,0 +1,161 @@
+package vaultresolver_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/goshims/timeshim/time_fake"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/vaultresolver"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeVault serves secrets to clients that logged in with the AppRole
+// some-role-id and some-secret-id.
+type fakeVault struct {
+	lock      sync.Mutex
+	secrets   map[string]string
+	tokens    map[string]bool
+	logins    int
+	namespace string
+}
+
+func (f *fakeVault) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.namespace = req.Header.Get("X-Vault-Namespace")
+
+	if req.Method == http.MethodPost && req.URL.Path == "/v1/auth/approle/login" {
+		var login map[string]string
+		data, _ := ioutil.ReadAll(req.Body)
+		Expect(json.Unmarshal(data, &login)).To(Succeed())
+		if login["role_id"] != "some-role-id" || login["secret_id"] != "some-secret-id" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"errors": ["invalid role or secret ID"]}`))
+			return
+		}
+
+		f.logins++
+		token := "some-token-" + strconv.Itoa(f.logins)
+		f.tokens[token] = true
+		w.Write([]byte(`{"auth": {"client_token": "` + token + `", "lease_duration": 3600}}`))
+		return
+	}
+
+	if !f.tokens[req.Header.Get("X-Vault-Token")] {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"errors": ["permission denied"]}`))
+		return
+	}
+
+	secret, ok := f.secrets[req.URL.Path]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"errors": []}`))
+		return
+	}
+	w.Write([]byte(secret))
+}
+
+var _ = Describe("VaultResolver", func() {
+	var (
+		env      dockerdriver.Env
+		vault    *fakeVault
+		server   *httptest.Server
+		fakeTime *time_fake.FakeTime
+		config   vaultresolver.Config
+		resolver volumedriver.SecretResolver
+		now      time.Time
+	)
+
+	BeforeEach(func() {
+		env = driverhttp.NewHttpDriverEnv(lagertest.NewTestLogger("vaultresolver"), context.TODO())
+		vault = &fakeVault{
+			secrets: map[string]string{
+				"/v1/secret/filer":      `{"data": {"password": "v1-password"}}`,
+				"/v1/secret/data/filer": `{"data": {"data": {"password": "v2-password", "port": 445}, "metadata": {"version": 3}}}`,
+			},
+			tokens: map[string]bool{},
+		}
+		server = httptest.NewServer(vault)
+
+		now = time.Unix(1000, 0)
+		fakeTime = &time_fake.FakeTime{}
+		fakeTime.NowStub = func() time.Time { return now }
+
+		config = vaultresolver.Config{
+			Address:   server.URL + "/",
+			Namespace: "cf",
+			RoleID:    "some-role-id",
+			SecretID:  "some-secret-id",
+		}
+	})
+
+	JustBeforeEach(func() {
+		resolver = vaultresolver.NewResolverWithClient(config, server.Client(), fakeTime)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("resolves secrets of the KV version 1 engine", func() {
+		Expect(resolver.Resolve(env, "secret/filer", "password")).To(Equal("v1-password"))
+		Expect(vault.namespace).To(Equal("cf"))
+	})
+
+	It("resolves secrets of the KV version 2 engine", func() {
+		Expect(resolver.Resolve(env, "secret/data/filer", "password")).To(Equal("v2-password"))
+	})
+
+	It("logs in once while the token lasts", func() {
+		Expect(resolver.Resolve(env, "secret/filer", "password")).To(Equal("v1-password"))
+		Expect(resolver.Resolve(env, "secret/data/filer", "password")).To(Equal("v2-password"))
+		Expect(vault.logins).To(Equal(1))
+
+		now = now.Add(time.Hour)
+		Expect(resolver.Resolve(env, "secret/filer", "password")).To(Equal("v1-password"))
+		Expect(vault.logins).To(Equal(2))
+	})
+
+	It("logs in again when the token is revoked", func() {
+		Expect(resolver.Resolve(env, "secret/filer", "password")).To(Equal("v1-password"))
+		vault.tokens = map[string]bool{}
+
+		Expect(resolver.Resolve(env, "secret/filer", "password")).To(Equal("v1-password"))
+		Expect(vault.logins).To(Equal(2))
+	})
+
+	It("fails for missing secrets and keys", func() {
+		_, err := resolver.Resolve(env, "secret/other", "password")
+		Expect(err).To(MatchError("/v1/secret/other not found"))
+
+		_, err = resolver.Resolve(env, "secret/filer", "username")
+		Expect(err).To(MatchError("secret 'secret/filer' has no key 'username'"))
+
+		_, err = resolver.Resolve(env, "secret/data/filer", "port")
+		Expect(err).To(MatchError("key 'port' of secret 'secret/data/filer' is not a string"))
+	})
+
+	Context("when the login fails", func() {
+		BeforeEach(func() {
+			config.SecretID = "wrong-secret-id"
+		})
+
+		It("returns the error of Vault", func() {
+			_, err := resolver.Resolve(env, "secret/filer", "password")
+			Expect(err).To(MatchError("approle login failed: POST /v1/auth/approle/login failed: invalid role or secret ID"))
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package vaultresolver_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestVaultResolver(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "VaultResolver Suite")
+}
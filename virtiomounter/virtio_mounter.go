@@ -0,0 +1,217 @@
+package virtiomounter
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/goshims/ioutilshim"
+	"code.cloudfoundry.org/goshims/osshim"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/invoker"
+	"code.cloudfoundry.org/volumedriver/mountchecker"
+	"code.cloudfoundry.org/volumedriver/mounthelper"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+)
+
+const (
+	// VirtioFS and NineP are the source schemes, and the names to register
+	// the mounter under in Options.Mounters.
+	VirtioFS = "virtiofs"
+	NineP    = "9p"
+
+	defaultNinePVersion = "9p2000.L"
+)
+
+// allowedOpts are the options each filesystem accepts. ro and readonly mount
+// read-only; everything else is passed to the kernel as is.
+var allowedOpts = map[string]map[string]bool{
+	VirtioFS: {"ro": true, "readonly": true, "dax": true},
+	NineP:    {"ro": true, "readonly": true, "msize": true, "cache": true, "access": true, "version": true, "posixacl": true},
+}
+
+// flagOpts take a boolean and are passed to the kernel without a value.
+var flagOpts = map[string]bool{"ro": true, "readonly": true, "dax": true, "posixacl": true}
+
+var validTag = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+type virtioMounter struct {
+	invoker      invoker.Invoker
+	os           osshim.Os
+	ioutil       ioutilshim.Ioutil
+	mountChecker mountchecker.MountChecker
+}
+
+// NewVirtioMounter returns a Mounter for filesystems that the hypervisor
+// shares into a guest VM, for runtimes that run containers in lightweight
+// VMs and cannot reach nfs servers themselves. Sources are virtiofs://tag or
+// 9p://tag, where tag is the mount tag of the shared device; a bare tag is
+// mounted as virtiofs.
+func NewVirtioMounter(invoker invoker.Invoker, os osshim.Os, ioutil ioutilshim.Ioutil, mountChecker mountchecker.MountChecker) volumedriver.Mounter {
+	return &virtioMounter{
+		invoker:      invoker,
+		os:           os,
+		ioutil:       ioutil,
+		mountChecker: mountChecker,
+	}
+}
+
+func (m *virtioMounter) Mount(env dockerdriver.Env, source string, target string, opts map[string]interface{}) error {
+	logger := env.Logger().Session("virtio-mount", lager.Data{"source": source, "target": target})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	fsType, tag, err := parseSource(source)
+	if err != nil {
+		return err
+	}
+
+	mountOpts, err := virtioMountOpts(fsType, opts)
+	if err != nil {
+		logger.Error("invalid-opts", err)
+		return err
+	}
+
+	args := []string{"-t", fsType}
+	if mountOpts != "" {
+		args = append(args, "-o", mountOpts)
+	}
+	args = append(args, tag, target)
+
+	result := m.invoker.Invoke(env, "mount", args)
+	if err := result.Wait(); err != nil {
+		logger.Error("mount-failed", err, lager.Data{"stderr": result.StdError()})
+		return safeerrors.FromMountHelper(err, result.StdError(), "%s mount failed", fsType)
+	}
+
+	return nil
+}
+
+func (m *virtioMounter) Unmount(env dockerdriver.Env, target string) error {
+	logger := env.Logger().Session("virtio-unmount", lager.Data{"target": target})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	result := m.invoker.Invoke(env, "umount", []string{target})
+	if err := result.Wait(); err != nil {
+		logger.Error("unmount-failed", err, lager.Data{"stderr": result.StdError()})
+		return safeerrors.FromMountHelper(err, result.StdError(), "unmount failed")
+	}
+
+	return nil
+}
+
+func (m *virtioMounter) Check(env dockerdriver.Env, name, mountPoint string, depth volumedriver.CheckDepth) bool {
+	logger := env.Logger().Session("virtio-check", lager.Data{"name": name, "mount-point": mountPoint, "depth": depth})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	mounted, err := m.mountChecker.Exists(mountPoint)
+	if err != nil {
+		logger.Error("check-mounts-failed", err)
+		return false
+	}
+	if !mounted {
+		logger.Info("not-mounted")
+		return false
+	}
+
+	if err := volumedriver.ProbeMountPoint(m.os, m.ioutil, mountPoint, depth); err != nil {
+		logger.Error("probe-failed", err)
+		return false
+	}
+
+	return true
+}
+
+// Purge lazily unmounts everything below path and removes the emptied
+// mountpoints. Directory contents are never removed.
+func (m *virtioMounter) Purge(env dockerdriver.Env, path string) {
+	logger := env.Logger().Session("virtio-purge", lager.Data{"path": path})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	mounthelper.Purge(logger, path, m.mountChecker, m.os, mounthelper.Unmount(env, m.invoker, "umount", "-l"))
+}
+
+func parseSource(source string) (string, string, error) {
+	fsType, tag := VirtioFS, source
+	if i := strings.Index(source, "://"); i >= 0 {
+		fsType, tag = strings.ToLower(source[:i]), source[i+3:]
+	}
+
+	if _, ok := allowedOpts[fsType]; !ok {
+		return "", "", safeerrors.New(safeerrors.InvalidSource, "unsupported filesystem '%s', expected virtiofs:// or 9p://", fsType)
+	}
+	if !validTag.MatchString(tag) {
+		return "", "", safeerrors.New(safeerrors.InvalidSource, "invalid mount tag '%s'", tag)
+	}
+
+	return fsType, tag, nil
+}
+
+// virtioMountOpts passes the hardening opts of the driver on as mount flags.
+func virtioMountOpts(fsType string, opts map[string]interface{}) (string, error) {
+	opts, flags := volumedriver.HardeningFlags(opts)
+	mountOpts := []string{}
+	if fsType == NineP {
+		mountOpts = append(mountOpts, "trans=virtio")
+		if _, ok := opts["version"]; !ok {
+			mountOpts = append(mountOpts, "version="+defaultNinePVersion)
+		}
+	}
+
+	keys := make([]string, 0, len(opts))
+	for key := range opts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !allowedOpts[fsType][key] {
+			return "", safeerrors.New(safeerrors.InvalidOption, "Not allowed options: %s", key)
+		}
+
+		value := opts[key]
+		if flagOpts[key] {
+			set, err := boolOpt(value)
+			if err != nil {
+				return "", safeerrors.New(safeerrors.InvalidOption, "Invalid value for option '%s': %v", key, value)
+			}
+			if set {
+				mountOpts = append(mountOpts, flagName(key))
+			}
+			continue
+		}
+
+		mountOpts = append(mountOpts, fmt.Sprintf("%s=%v", key, value))
+	}
+
+	return strings.Join(append(mountOpts, flags...), ","), nil
+}
+
+func flagName(key string) string {
+	if key == "readonly" {
+		return "ro"
+	}
+	return key
+}
+
+func boolOpt(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		switch strings.ToLower(v) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+
+	return false, fmt.Errorf("not a boolean: %v", value)
+}
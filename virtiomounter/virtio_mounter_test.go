@@ -0,0 +1,210 @@
+package virtiomounter_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/goshims/ioutilshim/ioutil_fake"
+	"code.cloudfoundry.org/goshims/osshim/os_fake"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/invokerfakes"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+	"code.cloudfoundry.org/volumedriver/virtiomounter"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("VirtioMounter", func() {
+	var (
+		logger           *lagertest.TestLogger
+		env              dockerdriver.Env
+		fakeInvoker      *invokerfakes.FakeInvoker
+		fakeInvokeResult *invokerfakes.FakeInvokeResult
+		fakeOs           *os_fake.FakeOs
+		fakeIoutil       *ioutil_fake.FakeIoutil
+		fakeMountChecker *volumedriverfakes.FakeMountChecker
+		mounter          volumedriver.Mounter
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("virtiomounter")
+		env = driverhttp.NewHttpDriverEnv(logger, context.TODO())
+		fakeInvoker = &invokerfakes.FakeInvoker{}
+		fakeInvokeResult = &invokerfakes.FakeInvokeResult{}
+		fakeInvoker.InvokeReturns(fakeInvokeResult)
+		fakeOs = &os_fake.FakeOs{}
+		fakeIoutil = &ioutil_fake.FakeIoutil{}
+		fakeMountChecker = &volumedriverfakes.FakeMountChecker{}
+
+		dirInfo := &ioutil_fake.FakeFileInfo{}
+		dirInfo.IsDirReturns(true)
+		fakeOs.StatReturns(dirInfo, nil)
+
+		mounter = virtiomounter.NewVirtioMounter(fakeInvoker, fakeOs, fakeIoutil, fakeMountChecker)
+	})
+
+	Describe("Mount", func() {
+		var (
+			source string
+			opts   map[string]interface{}
+			err    error
+		)
+
+		BeforeEach(func() {
+			source = "virtiofs://shared-data"
+			opts = map[string]interface{}{}
+		})
+
+		JustBeforeEach(func() {
+			err = mounter.Mount(env, source, "/mnt/target", opts)
+		})
+
+		It("mounts the tag with virtiofs", func() {
+			Expect(err).NotTo(HaveOccurred())
+			_, cmd, args, _ := fakeInvoker.InvokeArgsForCall(0)
+			Expect(cmd).To(Equal("mount"))
+			Expect(args).To(Equal([]string{"-t", "virtiofs", "shared-data", "/mnt/target"}))
+		})
+
+		Context("when the source is a bare tag", func() {
+			BeforeEach(func() {
+				source = "shared-data"
+				opts["readonly"] = "true"
+			})
+
+			It("mounts it with virtiofs", func() {
+				_, _, args, _ := fakeInvoker.InvokeArgsForCall(0)
+				Expect(args).To(Equal([]string{"-t", "virtiofs", "-o", "ro", "shared-data", "/mnt/target"}))
+			})
+		})
+
+		Context("when the source is a 9p share", func() {
+			BeforeEach(func() {
+				source = "9p://shared-data"
+				opts = map[string]interface{}{"msize": float64(262144), "cache": "loose", "ro": true}
+			})
+
+			It("mounts it over the virtio transport", func() {
+				_, _, args, _ := fakeInvoker.InvokeArgsForCall(0)
+				Expect(args).To(Equal([]string{"-t", "9p", "-o", "trans=virtio,version=9p2000.L,cache=loose,msize=262144,ro", "shared-data", "/mnt/target"}))
+			})
+
+			Context("when a protocol version is given", func() {
+				BeforeEach(func() {
+					opts = map[string]interface{}{"version": "9p2000.u"}
+				})
+
+				It("uses it", func() {
+					_, _, args, _ := fakeInvoker.InvokeArgsForCall(0)
+					Expect(args).To(ContainElement("trans=virtio,version=9p2000.u"))
+				})
+			})
+		})
+
+		Context("when the driver hardens the mount", func() {
+			BeforeEach(func() {
+				opts["nosuid"] = true
+				opts["nodev"] = true
+				opts["noexec"] = true
+			})
+
+			It("passes the hardening opts on as mount flags", func() {
+				Expect(err).NotTo(HaveOccurred())
+				_, _, args, _ := fakeInvoker.InvokeArgsForCall(0)
+				Expect(args).To(ContainElement(HaveSuffix("nosuid,nodev,noexec")))
+			})
+		})
+
+		Context("when an option is not supported by the filesystem", func() {
+			BeforeEach(func() {
+				opts["msize"] = "8192"
+			})
+
+			It("rejects the mount", func() {
+				Expect(err).To(Equal(safeerrors.New(safeerrors.InvalidOption, "Not allowed options: msize")))
+				Expect(fakeInvoker.InvokeCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when a flag is not a boolean", func() {
+			BeforeEach(func() {
+				opts["dax"] = "sometimes"
+			})
+
+			It("rejects the mount", func() {
+				Expect(err).To(Equal(safeerrors.New(safeerrors.InvalidOption, "Invalid value for option 'dax': sometimes")))
+			})
+		})
+
+		Context("when the filesystem is not supported", func() {
+			BeforeEach(func() {
+				source = "nfs://server/export"
+			})
+
+			It("rejects the mount", func() {
+				Expect(err).To(Equal(safeerrors.New(safeerrors.InvalidSource, "unsupported filesystem 'nfs', expected virtiofs:// or 9p://")))
+			})
+		})
+
+		Context("when the tag is not valid", func() {
+			BeforeEach(func() {
+				source = "virtiofs://shared data,rw"
+			})
+
+			It("rejects the mount", func() {
+				Expect(err).To(Equal(safeerrors.New(safeerrors.InvalidSource, "invalid mount tag 'shared data,rw'")))
+				Expect(fakeInvoker.InvokeCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when the mount command fails", func() {
+			BeforeEach(func() {
+				fakeInvokeResult.WaitReturns(errors.New("exit status 32"))
+				fakeInvokeResult.StdErrorReturns("mount: unknown filesystem type 'virtiofs'\n")
+			})
+
+			It("returns the command error", func() {
+				Expect(err).To(MatchError("virtiofs mount failed: the cell cannot mount this file system; check that its mount helper and kernel module are installed on the cells"))
+			})
+		})
+	})
+
+	Describe("Unmount", func() {
+		It("unmounts the target", func() {
+			Expect(mounter.Unmount(env, "/mnt/target")).To(Succeed())
+			_, cmd, args, _ := fakeInvoker.InvokeArgsForCall(0)
+			Expect(cmd).To(Equal("umount"))
+			Expect(args).To(Equal([]string{"/mnt/target"}))
+		})
+	})
+
+	Describe("Check", func() {
+		It("reports a healthy mount", func() {
+			fakeMountChecker.ExistsReturns(true, nil)
+			Expect(mounter.Check(env, "some-volume", "/mnt/target", volumedriver.CheckRead)).To(BeTrue())
+			Expect(fakeIoutil.ReadDirCallCount()).To(Equal(1))
+		})
+
+		It("reports a missing mount as unhealthy", func() {
+			fakeMountChecker.ExistsReturns(false, nil)
+			Expect(mounter.Check(env, "some-volume", "/mnt/target", volumedriver.CheckStat)).To(BeFalse())
+		})
+	})
+
+	Describe("Purge", func() {
+		It("unmounts and removes every mountpoint below the path", func() {
+			fakeMountChecker.ListReturns([]string{"/mnt/root/a"}, nil)
+			mounter.Purge(env, "/mnt/root")
+
+			Expect(fakeMountChecker.ListArgsForCall(0)).To(Equal(regexp.MustCompile("^/mnt/root/")))
+			_, _, args, _ := fakeInvoker.InvokeArgsForCall(0)
+			Expect(args).To(Equal([]string{"-l", "/mnt/root/a"}))
+			Expect(fakeOs.RemoveArgsForCall(0)).To(Equal("/mnt/root/a"))
+		})
+	})
+})
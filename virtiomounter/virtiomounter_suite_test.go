@@ -0,0 +1,13 @@
+package virtiomounter_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestVirtioMounter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "VirtioMounter Suite")
+}
@@ -2,12 +2,17 @@ package volumedriver
 
 import (
 	"context"
+	"crypto/cipher"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"code.cloudfoundry.org/dockerdriver"
@@ -17,14 +22,314 @@ import (
 	"code.cloudfoundry.org/goshims/osshim"
 	"code.cloudfoundry.org/goshims/timeshim"
 	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver/admission"
+	"code.cloudfoundry.org/volumedriver/metrics"
 	"code.cloudfoundry.org/volumedriver/mountchecker"
+	"code.cloudfoundry.org/volumedriver/rootlock"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
 )
 
 type NfsVolumeInfo struct {
-	Opts                    map[string]interface{} `json:"-"` // don't store opts
-	wg                      sync.WaitGroup
+	Opts                    map[string]interface{} `json:"-"`          // stored as SavedOpts
+	SavedOpts               map[string]interface{} `json:",omitempty"` // the opts without DroppedOpts, see savedOpts
+	DroppedOpts             []string               `json:",omitempty"` // secret opts not stored without a state key
+	mount                   mountState
 	mountError              string
-	dockerdriver.VolumeInfo // see dockerdriver.resources.go
+	mountErrorTime          time.Time
+	mountFailures           int                    // consecutive failed mounts
+	MountDirectory          string                 `json:",omitempty"` // directory below the mount path root
+	MountRoot               string                 `json:",omitempty"` // one of Options.MountPathRoots, empty for the mount path root
+	ExpiresAt               *time.Time             `json:",omitempty"` // set for volumes created with a ttl
+	CreatedAt               *time.Time             `json:",omitempty"` // unset for volumes created by older drivers
+	Labels                  map[string]string      `json:",omitempty"`
+	Driver                  string                 `json:",omitempty"` // key in Options.Mounters, empty for the default mounter
+	Subdir                  string                 `json:",omitempty"` // directory of the export bound as the volume
+	ExportMount             string                 `json:",omitempty"` // shared mount of the export of a subdir volume
+	Composite               map[string]string      `json:",omitempty"` // sources mounted below the mountpoint, by directory
+	LastMountedAt           *time.Time             `json:",omitempty"`
+	LastUnmountedAt         *time.Time             `json:",omitempty"` // set by every Unmount, also those that leave it mounted
+	LastIOAt                *time.Time             `json:",omitempty"` // seen in the mount stats, see Options.MountStats
+	persistedIOAt           *time.Time             // LastIOAt as last persisted
+	LastMountError          string                 `json:",omitempty"` // kept after the error is cleared
+	LastMountErrorAt        *time.Time             `json:",omitempty"`
+	IOLimits                *IOLimits              `json:",omitempty"`
+	Scratch                 bool                   `json:",omitempty"`            // mounted read-only under a local overlay
+	Relabel                 string                 `json:",omitempty"`            // SELinux label of the mountpoint, set after mounting
+	Frozen                  bool                   `json:",omitempty"`            // kept so that the volume can be thawed after a restart
+	RemountOpts             map[string]interface{} `json:"-"`                     // set by RemountVolume, applied over the opts of Create
+	SavedRemountOpts        map[string]interface{} `json:"RemountOpts,omitempty"` // RemountOpts as persisted, see savedOpts
+	References              map[string]int         `json:",omitempty"`            // mount references by caller, see WithCaller
+	Binds                   map[string]*VolumeBind `json:",omitempty"`            // bind mounts by target, see BindVolume
+	StateVersion            int                    // schema version of the persisted record, see StateVersion
+	dockerdriver.VolumeInfo                        // see dockerdriver.resources.go
+}
+
+// DefaultDrainTimeout is how long Drain waits for unmounts before it purges
+// the remaining mounts.
+const DefaultDrainTimeout = 2 * time.Minute
+
+// DefaultMountErrorTTL is how long a failed mount is reported back to callers
+// before the driver attempts to mount the volume again.
+const DefaultMountErrorTTL = 30 * time.Second
+
+// DefaultMaxMountErrorTTL is how long the mount failure of a volume is
+// remembered at most after the TTL doubled with each consecutive failure.
+const DefaultMaxMountErrorTTL = 10 * time.Minute
+
+// DefaultSlowMountThreshold and DefaultCriticalMountThreshold are the mount
+// durations above which a mount is reported as slow, well before container
+// creation times out.
+const (
+	DefaultSlowMountThreshold     = 8 * time.Second
+	DefaultCriticalMountThreshold = 20 * time.Second
+)
+
+// DefaultMountHeartbeatInterval is how often a mount in progress is
+// reported.
+const DefaultMountHeartbeatInterval = 5 * time.Second
+
+type Options struct {
+	// MountErrorTTL bounds how long a mount failure is remembered for a volume.
+	// Zero means the error is kept until the volume is healthy again or it is
+	// explicitly reset.
+	MountErrorTTL time.Duration
+	// MaxMountErrorTTL caps how far the MountErrorTTL of a volume doubles
+	// while its mounts keep failing, so that the driver backs off from a
+	// flapping mount instead of retrying it on every request. Zero keeps the
+	// MountErrorTTL fixed.
+	MaxMountErrorTTL time.Duration
+
+	// GlobalRateLimit and VolumeRateLimit throttle Mount and Unmount requests
+	// across all volumes and per volume, to protect the cell from mount storms.
+	GlobalRateLimit admission.RateLimit
+	VolumeRateLimit admission.RateLimit
+
+	// MaxPendingMounts and MaxPendingMountsPerServer bound the mounts in
+	// progress across all volumes and per NFS server. Mount requests beyond
+	// them fail at once with a Busy safe error that asks the caller to
+	// retry after BusyRetryAfter, instead of piling onto a saturated server
+	// until they time out. Zero means unlimited.
+	MaxPendingMounts          int
+	MaxPendingMountsPerServer int
+	BusyRetryAfter            time.Duration
+
+	// MetricsEmitter receives operational metrics. Metrics are dropped when it
+	// is nil, unless StatsdAddress is set, in which case they are sent to the
+	// statsd agent at that address with their names prefixed by StatsdPrefix.
+	MetricsEmitter metrics.Emitter
+	StatsdAddress  string
+	StatsdPrefix   string
+
+	// SlowMountThreshold and CriticalMountThreshold are the mount durations
+	// above which a mount is logged and counted as slow, with a warning or a
+	// critical severity. Zero disables the respective report.
+	SlowMountThreshold     time.Duration
+	CriticalMountThreshold time.Duration
+
+	// MountHeartbeatInterval is how often a mount that is still in progress
+	// is logged and published as an EventMounting, with the state of its
+	// helper processes, so that operators can tell a slow server from a
+	// stuck driver. Zero disables the heartbeat.
+	MountHeartbeatInterval time.Duration
+
+	// CheckDepth is how thoroughly mounts are probed before they are handed
+	// out again, unless a volume sets the check_depth opt.
+	CheckDepth CheckDepth
+
+	// SourceDefaults are merged under the opts of volumes created from
+	// matching sources.
+	SourceDefaults []SourceDefaults
+
+	// AllowedSources restricts the servers and exports volumes can be
+	// created from, so that tenants cannot mount arbitrary filers of the
+	// internal network. Every source is allowed when it is empty.
+	AllowedSources []SourceRule
+
+	// Mounters serve volumes of other filesystem types next to the default
+	// mounter. Volumes select one by name with the driver opt, or by the
+	// scheme of a scheme://... source.
+	Mounters map[string]Mounter
+
+	// BindMounter binds the directories of volumes created with the subdir
+	// opt from the shared mount of their export, and mounted volumes into
+	// the targets of BindVolume. The subdir opt and BindVolume are rejected
+	// when it is nil.
+	BindMounter Mounter
+
+	// IOThrottler enforces the IO limits of volumes created with the
+	// read_bps, write_bps, read_iops or write_iops opts. The opts are
+	// rejected when it is nil.
+	IOThrottler IOThrottler
+
+	// OverlayMounter layers the writable overlay of volumes created with
+	// the scratch opt over their read-only mount. It is called with the
+	// lower directory as the source and the upperdir and workdir opts,
+	// which are below ScratchDir on cell-local disk. The scratch opt is
+	// rejected when either is unset.
+	OverlayMounter Mounter
+	ScratchDir     string
+
+	// Rootless is for drivers that do not run as root. Volumes are refused
+	// unless their mounter is an UnprivilegedMounter, such as the fuse-nfs
+	// mounter or the mount helper of package mounthelper, and so are the
+	// opts that need root on the host, see checkRootless.
+	Rootless bool
+
+	// Labeler labels the mountpoints of volumes created with the relabel opt
+	// after they are mounted. The opt is rejected when it is nil.
+	Labeler Labeler
+
+	// Freezer suspends writes to volumes for FreezeVolume requests. Freezing
+	// is not supported when it is nil.
+	Freezer Freezer
+
+	// Remounter changes the options of mounted volumes for RemountVolume
+	// requests. Remounting is not supported when it is nil.
+	Remounter Remounter
+
+	// ExportDiscoverer lists the exports of servers for DiscoverExports
+	// requests. Discovery is not supported when it is nil.
+	ExportDiscoverer ExportDiscoverer
+
+	// Cloner copies data for Clone requests. Clone is not supported when it
+	// is nil.
+	Cloner Cloner
+
+	// Provisioner creates the exports of volumes created with the provision
+	// opt, see ontapprovisioner. The opt is rejected when it is nil.
+	Provisioner Provisioner
+
+	// SecretResolver resolves the opts of the form vault://path#key when
+	// a volume is mounted, see vaultresolver. Only the mounter sees the
+	// secrets, they are never persisted. Such opts are rejected when it is
+	// nil.
+	SecretResolver SecretResolver
+
+	// RefStore shares the references of the volumes mounted on every cell
+	// of a global-scope deployment, see consulrefs, so that an export is
+	// only released once no cell mounts it anymore. The driver then reports
+	// the global scope. CellID names this cell in the references, the host
+	// name when it is empty. ExportReleaser is called for every released
+	// export, for example to clean it up on the server.
+	RefStore       RefStore
+	CellID         string
+	ExportReleaser ExportReleaser
+
+	// StateKey encrypts the persisted volume state with AES-GCM, see
+	// ParseStateKey and StateKeyFromEnv. State written without a key is
+	// encrypted when it is restored. The state is kept in plaintext when it
+	// is nil: the records are only readable by the driver's user, but the
+	// opts in them, such as usernames, hosts and secret references, can be
+	// read by root and from backups of the disk. Secret opts such as
+	// password are then left out, see savedOpts.
+	StateKey []byte
+
+	// HardeningExemptions are the hardening opts, nosuid, nodev or noexec,
+	// that are not forced onto every mount. Volumes can only relax the
+	// exempted ones, for example with the exec opt.
+	HardeningExemptions []string
+
+	// Quotas limit the number of volumes and mounts. The zero value sets no
+	// limits.
+	Quotas Quotas
+
+	// StateLimits bound the state of volumes that are not mounted, see
+	// PruneState. The zero value sets no limits.
+	StateLimits StateLimits
+
+	// RemountOnStart mounts the volumes that were mounted when the driver
+	// stopped again once it restarts, unless they are still mounted, so
+	// that containers keep their volumes across a reboot of the cell.
+	// Volumes restored without their opts, because their record predates
+	// persisted opts or their secret opts were not persisted without a
+	// StateKey, are not remounted.
+	RemountOnStart bool
+
+	// DrainTimeout bounds how long Drain waits for volumes to unmount before
+	// it purges the remaining mounts. Zero waits for as long as the unmounts
+	// take.
+	DrainTimeout time.Duration
+
+	// SkipPurge keeps Drain from purging the mount path roots, leaving the
+	// mounts it could not unmount behind. Purges only ever touch roots that
+	// carry the OwnershipMarker.
+	SkipPurge bool
+
+	// LockRoot takes the rootlock of the mount path root when the driver is
+	// created, and exits when another driver instance holds it: two
+	// instances sharing a root overwrite each other's state. Drain releases
+	// the lock.
+	LockRoot bool
+
+	// PersistDebounce batches the state writes of changes that are safe to
+	// lose in a crash, such as unmounts that keep a volume mounted for
+	// others, into one write per volume every PersistDebounce. Creating,
+	// removing, mounting and finally unmounting a volume are always
+	// written at once. Zero writes every change at once. See FlushState.
+	PersistDebounce time.Duration
+
+	// PersistRetryInterval queues the state writes that fail in Create,
+	// Mount, Unmount and Remove for retry, starting after PersistRetryInterval
+	// and backing off up to MaxPersistRetryBackoff, instead of failing the
+	// request whose change already took effect. Zero fails the request. See
+	// PendingPersists.
+	PersistRetryInterval time.Duration
+
+	// ExpiryInterval is how often volumes created with a ttl are checked for
+	// expiry, and the state is pruned to its StateLimits. Zero disables the
+	// background check; ExpireVolumes and PruneState can still be called
+	// directly.
+	ExpiryInterval time.Duration
+
+	// SnapshotInterval is how often the volume records are copied to a
+	// state snapshot, see SnapshotState. Zero disables the scheduled
+	// snapshots. SnapshotRetention is how many snapshots are kept, the
+	// DefaultSnapshotRetention when it is zero.
+	SnapshotInterval  time.Duration
+	SnapshotRetention int
+
+	// OrphanInterval is how often directories below the mount path root that
+	// no volume owns are collected. Zero disables the background collection.
+	OrphanInterval time.Duration
+
+	// OrphanDryRun only logs the orphaned directories instead of removing
+	// them.
+	OrphanDryRun bool
+
+	// MountPathRoots are further roots next to the mount path root. New
+	// volumes are placed on one of them by RootPolicy, and keep their
+	// mountpoint and state records there, so that a full root does not
+	// break mounts and persistence on the others.
+	MountPathRoots []string
+	RootPolicy     RootPolicy
+
+	// DiskSpace measures the free space of the roots for RootMostFree. It
+	// defaults to NewDiskSpace.
+	DiskSpace DiskSpace
+
+	// MountStats reads the NFS client statistics that are emitted per
+	// volume every MountStatsInterval, see mountstats.NewReader. Nothing is
+	// emitted when it is nil or the interval is zero.
+	MountStats         MountStatsReader
+	MountStatsInterval time.Duration
+
+	// FSCache verifies that volumes created with the fsc opt are cached,
+	// see fscache.NewChecker. Without it, the fsc opt is passed on
+	// unchecked.
+	FSCache FSCache
+}
+
+func DefaultOptions() Options {
+	return Options{
+		MountErrorTTL:          DefaultMountErrorTTL,
+		MaxMountErrorTTL:       DefaultMaxMountErrorTTL,
+		SlowMountThreshold:     DefaultSlowMountThreshold,
+		CriticalMountThreshold: DefaultCriticalMountThreshold,
+		MountHeartbeatInterval: DefaultMountHeartbeatInterval,
+		CheckDepth:             CheckStat,
+		DrainTimeout:           DefaultDrainTimeout,
+		BusyRetryAfter:         DefaultBusyRetryAfter,
+	}
 }
 
 type OsHelper interface {
@@ -33,7 +338,8 @@ type OsHelper interface {
 
 type VolumeDriver struct {
 	volumes       map[string]*NfsVolumeInfo
-	volumesLock   sync.RWMutex
+	volumesLock   statsRWMutex
+	published     atomic.Value // volumeMap, see publishVolumes
 	os            osshim.Os
 	filepath      filepathshim.Filepath
 	ioutil        ioutilshim.Ioutil
@@ -42,9 +348,44 @@ type VolumeDriver struct {
 	mountPathRoot string
 	mounter       Mounter
 	osHelper      OsHelper
+	options       Options
+	globalLimiter *admission.RateLimiter
+	volumeLimiter *admission.RateLimiter
+	metrics       metrics.Emitter
+	stop          chan struct{}
+	stopOnce      sync.Once
+	inFlight      *inFlightTracker
+	exportsLock   sync.Mutex
+	exportUsers   map[string]map[string]bool
+	events        *eventBroker
+	stateCipher   cipher.AEAD
+	cellID        string
+	diskSpace     DiskSpace
+	policyLock    sync.RWMutex
+	policy        Policy
+
+	mountStatsLock sync.Mutex
+	mountStats     map[string]NFSStats
+
+	dirtyVolumes   map[string]bool          // guarded by volumesLock, see persistVolumeLater
+	persistRetries map[string]*persistRetry // guarded by volumesLock, see persistVolumeOrQueue
+	stateSizes     map[string]int           // bytes of the state record by volume, guarded by volumesLock
+	pendingMounts  map[string]int           // mounts in progress by server, guarded by volumesLock
+
+	releasesLock sync.Mutex
+	releases     map[string]chan struct{} // export releases in progress by volume, see releaseExport
+
+	snapshotsLock sync.Mutex // serializes snapshotState, which may run under a read lock of volumesLock
+
+	rootLock       *rootlock.Lock // set with Options.LockRoot, see lockRoot
+	rootUnlockOnce sync.Once
 }
 
 func NewVolumeDriver(logger lager.Logger, os osshim.Os, filepath filepathshim.Filepath, ioutil ioutilshim.Ioutil, time timeshim.Time, mountChecker mountchecker.MountChecker, mountPathRoot string, mounter Mounter, oshelper OsHelper) *VolumeDriver {
+	return NewVolumeDriverWithOptions(logger, os, filepath, ioutil, time, mountChecker, mountPathRoot, mounter, oshelper, DefaultOptions())
+}
+
+func NewVolumeDriverWithOptions(logger lager.Logger, os osshim.Os, filepath filepathshim.Filepath, ioutil ioutilshim.Ioutil, time timeshim.Time, mountChecker mountchecker.MountChecker, mountPathRoot string, mounter Mounter, oshelper OsHelper, options Options) *VolumeDriver {
 	d := &VolumeDriver{
 		volumes:       map[string]*NfsVolumeInfo{},
 		os:            os,
@@ -55,13 +396,94 @@ func NewVolumeDriver(logger lager.Logger, os osshim.Os, filepath filepathshim.Fi
 		mountPathRoot: mountPathRoot,
 		mounter:       mounter,
 		osHelper:      oshelper,
+		options:       options,
+		globalLimiter: admission.NewRateLimiter(time, options.GlobalRateLimit),
+		volumeLimiter: admission.NewRateLimiter(time, options.VolumeRateLimit),
+		metrics:       options.MetricsEmitter,
+		stop:          make(chan struct{}),
+		inFlight:      newInFlightTracker(),
+		exportUsers:   map[string]map[string]bool{},
+		events:        newEventBroker(),
+		diskSpace:     options.DiskSpace,
+		policy:        options.Policy(),
+		dirtyVolumes:  map[string]bool{},
+
+		persistRetries: map[string]*persistRetry{},
+		stateSizes:     map[string]int{},
+		pendingMounts:  map[string]int{},
+		releases:       map[string]chan struct{}{},
+	}
+	d.volumesLock.beforeUnlock = d.publishVolumes
+	d.publishVolumes()
+
+	if d.metrics == nil && options.StatsdAddress != "" {
+		emitter, err := metrics.NewStatsdEmitter(logger, options.StatsdAddress, options.StatsdPrefix)
+		if err != nil {
+			logger.Error("statsd-emitter-failed", err, lager.Data{"address": options.StatsdAddress})
+		} else {
+			d.metrics = emitter
+		}
+	}
+	if d.metrics == nil {
+		d.metrics = metrics.NewNoopEmitter()
+	}
+
+	if d.diskSpace == nil {
+		d.diskSpace = NewDiskSpace()
+	}
+
+	stateCipher, err := newStateCipher(options.StateKey)
+	if err != nil {
+		logger.Fatal("invalid-state-key", err)
+	}
+	d.stateCipher = stateCipher
+
+	d.cellID = options.CellID
+	if options.RefStore != nil && d.cellID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			logger.Fatal("cell-id-unknown", err)
+		}
+		d.cellID = hostname
 	}
 
 	ctx := context.TODO()
 	env := driverhttp.NewHttpDriverEnv(logger, ctx)
 
+	if options.LockRoot {
+		d.lockRoot(env)
+	}
+	d.claimRoots(env)
 	d.restoreState(env)
 
+	if options.RemountOnStart {
+		d.remountRestoredVolumes(env)
+	}
+
+	if options.ExpiryInterval > 0 {
+		go d.runExpiry(env, options.ExpiryInterval)
+	}
+
+	if options.SnapshotInterval > 0 {
+		go d.runSnapshots(env, options.SnapshotInterval)
+	}
+
+	if options.OrphanInterval > 0 {
+		go d.runOrphanCollection(env, options.OrphanInterval, options.OrphanDryRun)
+	}
+
+	if options.PersistDebounce > 0 {
+		go d.runStateFlush(env, options.PersistDebounce)
+	}
+
+	if options.PersistRetryInterval > 0 {
+		go d.runPersistRetries(env, options.PersistRetryInterval)
+	}
+
+	if options.MountStats != nil && options.MountStatsInterval > 0 {
+		go d.runMountStats(env, options.MountStatsInterval)
+	}
+
 	return d
 }
 
@@ -72,62 +494,170 @@ func (d *VolumeDriver) Activate(env dockerdriver.Env) dockerdriver.ActivateRespo
 }
 
 func (d *VolumeDriver) Create(env dockerdriver.Env, createRequest dockerdriver.CreateRequest) dockerdriver.ErrorResponse {
+	return d.create(env, createRequest, nil)
+}
+
+// create creates or updates a volume. A volume that is imported keeps the
+// mount directory and root it was exported with where they are free here.
+func (d *VolumeDriver) create(env dockerdriver.Env, createRequest dockerdriver.CreateRequest, imported *ExportedVolume) dockerdriver.ErrorResponse {
 	logger := env.Logger().Session("create")
 	logger.Info("start")
 	defer logger.Info("end")
+	defer d.inFlight.start(env, "create", createRequest.Name).done()
 
 	if createRequest.Name == "" {
 		return dockerdriver.ErrorResponse{Err: "Missing mandatory 'volume_name'"}
 	}
+	if err := ValidateVolumeName(createRequest.Name); err != nil {
+		logger.Info("invalid-volume-name", lager.Data{"err": err.Error()})
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
 
-	var ok bool
-	if _, ok = createRequest.Opts["source"].(string); !ok {
-		logger.Info("mount-config-missing-source", lager.Data{"volume_name": createRequest.Name})
-		return dockerdriver.ErrorResponse{Err: `Missing mandatory 'source' field in 'Opts'`}
+	if err := ValidateOpts(createRequest.Opts); err != nil {
+		logger.Info("invalid-opts", lager.Data{"volume_name": createRequest.Name, "err": err.Error()})
+		return dockerdriver.ErrorResponse{Err: err.Error()}
 	}
 
-	existing, err := d.getVolume(driverhttp.EnvWithLogger(logger, env), createRequest.Name)
+	opts, err := d.provision(driverhttp.EnvWithLogger(logger, env), createRequest.Name, createRequest.Opts)
+	if err != nil {
+		logger.Info("provision-failed", lager.Data{"volume_name": createRequest.Name, "err": err.Error()})
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+	createRequest.Opts = opts
 
+	if _, err := ParseSource(createRequest.Opts); err != nil {
+		logger.Info("mount-config-invalid-source", lager.Data{"volume_name": createRequest.Name, "err": err.Error()})
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+
+	opts, err = d.normalizeSource(createRequest.Opts)
 	if err != nil {
+		logger.Info("invalid-source", lager.Data{"volume_name": createRequest.Name, "err": err.Error()})
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+	createRequest.Opts = opts
+
+	source := sourceOpt(createRequest.Opts)
+	if err := d.checkSourceAllowed(source); err != nil {
+		logger.Info("source-not-allowed", lager.Data{"volume_name": createRequest.Name, "err": err.Error()})
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+
+	createRequest.Opts = d.applySourceDefaults(createRequest.Opts)
+
+	opts, err = normalizeCommonOpts(createRequest.Opts)
+	if err != nil {
+		logger.Info("invalid-opts", lager.Data{"volume_name": createRequest.Name, "err": err.Error()})
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+	createRequest.Opts = opts
+
+	if err := validateDriverOpts(createRequest.Opts); err != nil {
+		logger.Info("invalid-opts", lager.Data{"volume_name": createRequest.Name, "err": err.Error()})
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+
+	if err := d.checkCompositeSourcesAllowed(createRequest.Opts); err != nil {
+		logger.Info("source-not-allowed", lager.Data{"volume_name": createRequest.Name, "err": err.Error()})
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+
+	if err := d.validateSecretRefs(createRequest.Opts); err != nil {
+		logger.Info("invalid-secret-refs", lager.Data{"volume_name": createRequest.Name, "err": err.Error()})
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+
+	driver, err := d.volumeDriver(createRequest.Opts)
+	if err != nil {
+		logger.Info("unknown-driver", lager.Data{"volume_name": createRequest.Name, "err": err.Error()})
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+
+	if err := d.checkFeatures(createRequest.Opts); err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+
+	subdir, _ := parseSubdirOpts(createRequest.Opts)
+	exportMount := ""
+	if subdir.subdir != "" {
+		exportMount = d.exportMountPath(driverhttp.EnvWithLogger(logger, env), driver, createRequest.Opts)
+	}
+
+	ioLimits, _ := parseIOLimits(createRequest.Opts)
+	scratch, _ := parseScratchOpt(createRequest.Opts)
+	composite, _ := parseCompositeOpt(createRequest.Opts)
+	relabel := relabelOpt(createRequest.Opts)
+
+	d.volumesLock.Lock()
+	defer d.volumesLock.Unlock()
+
+	// look the volume up under the lock, so that the record is updated in
+	// place rather than replaced by a copy that misses concurrent changes
+	if existing, ok := d.volumes[createRequest.Name]; !ok {
 		logger.Info("creating-volume", lager.Data{"volume_name": createRequest.Name})
 		logger.Info("with-opts", lager.Data{"opts": createRequest.Opts})
 
 		volInfo := NfsVolumeInfo{
 			VolumeInfo: dockerdriver.VolumeInfo{Name: createRequest.Name},
 			Opts:       createRequest.Opts,
+			Driver:     driver,
+		}
+		volInfo.Subdir = subdir.subdir
+		volInfo.ExportMount = exportMount
+		volInfo.IOLimits = ioLimits
+		volInfo.Scratch = scratch
+		volInfo.Composite = composite
+		volInfo.Relabel = relabel
+
+		if err := d.checkVolumeQuota(); err != nil {
+			logger.Info("quota-exceeded", lager.Data{"volume_name": createRequest.Name, "err": err.Error()})
+			return dockerdriver.ErrorResponse{Err: err.Error()}
 		}
 
-		d.volumesLock.Lock()
-		defer d.volumesLock.Unlock()
-
+		volInfo.MountDirectory = d.assignMountDirectory(createRequest.Name)
+		volInfo.MountRoot = d.assignMountRoot(driverhttp.EnvWithLogger(logger, env))
+		if imported != nil {
+			d.keepImportedMountDirectory(&volInfo, imported)
+		}
+		volInfo.ExpiresAt = d.expiresAt(createRequest.Opts)
+		volInfo.Labels = volumeLabels(createRequest.Opts)
+		createdAt := d.time.Now()
+		volInfo.CreatedAt = &createdAt
 		d.volumes[createRequest.Name] = &volInfo
 	} else {
 		existing.Opts = createRequest.Opts
-
-		d.volumesLock.Lock()
-		defer d.volumesLock.Unlock()
-
-		d.volumes[createRequest.Name] = existing
+		if existing.RemountOpts != nil {
+			existing.Opts = withRemountOpts(createRequest.Opts, existing.RemountOpts)
+		}
+		existing.Driver = driver
+		existing.Subdir = subdir.subdir
+		existing.ExportMount = exportMount
+		existing.IOLimits = ioLimits
+		existing.Scratch = scratch
+		existing.Composite = composite
+		existing.Relabel = relabel
+		existing.ExpiresAt = d.expiresAt(createRequest.Opts)
+		existing.Labels = volumeLabels(createRequest.Opts)
 	}
 
-	err = d.persistState(driverhttp.EnvWithLogger(logger, env))
+	d.emitVolumeGauges()
+
+	err = d.persistVolumeOrQueue(driverhttp.EnvWithLogger(logger, env), createRequest.Name)
 	if err != nil {
 		logger.Error("persist-state-failed", err)
 		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("persist state failed when creating: %s", err.Error())}
 	}
 
+	d.publish(EventCreated, createRequest.Name, nil)
 	return dockerdriver.ErrorResponse{}
 }
 
 func (d *VolumeDriver) List(_ dockerdriver.Env) dockerdriver.ListResponse {
-	d.volumesLock.RLock()
-	defer d.volumesLock.RUnlock()
-
 	listResponse := dockerdriver.ListResponse{
 		Volumes: []dockerdriver.VolumeInfo{},
 	}
 
-	for _, volume := range d.volumes {
+	for _, volume := range d.loadVolumes() {
 		listResponse.Volumes = append(listResponse.Volumes, volume.VolumeInfo)
 	}
 	listResponse.Err = ""
@@ -138,34 +668,65 @@ func (d *VolumeDriver) Mount(env dockerdriver.Env, mountRequest dockerdriver.Mou
 	logger := env.Logger().Session("mount", lager.Data{"volume": mountRequest.Name})
 	logger.Info("start")
 	defer logger.Info("end")
+	op := d.inFlight.queue(env, "mount", mountRequest.Name)
+	defer op.done()
 
 	if mountRequest.Name == "" {
 		return dockerdriver.MountResponse{Err: "Missing mandatory 'volume_name'"}
 	}
 
+	if err := d.admit(driverhttp.EnvWithLogger(logger, env), mountRequest.Name); err != nil {
+		return dockerdriver.MountResponse{Err: errResponse(err)}
+	}
+
 	var doMount bool
 	var opts map[string]interface{}
+	var mounter Mounter
 	var mountPath string
-	var wg *sync.WaitGroup
+	var state *mountState
+	var pendingDone func()
 
 	ret := func() dockerdriver.MountResponse {
 
 		d.volumesLock.Lock()
 		defer d.volumesLock.Unlock()
+		op.running()
 
 		volume := d.volumes[mountRequest.Name]
 		if volume == nil {
 			return dockerdriver.MountResponse{Err: fmt.Sprintf("Volume '%s' must be created before being mounted", mountRequest.Name)}
 		}
 
-		mountPath = d.mountPath(driverhttp.EnvWithLogger(logger, env), volume.Name)
+		mountPath = d.volumeMountPath(driverhttp.EnvWithLogger(logger, env), volume)
 
 		logger.Info("mounting-volume", lager.Data{"id": volume.Name, "mountpoint": mountPath})
-		logger.Info("mount-source", lager.Data{"source": volume.Opts["source"].(string)})
+		// volumes restored after a restart have no opts
+		source := sourceOpt(volume.Opts)
+		logger.Info("mount-source", lager.Data{"source": source})
+
+		remount, recovered := false, false
+		if volume.mountError != "" {
+			remount = d.clearStaleMountError(driverhttp.EnvWithLogger(logger, env), volume, mountPath)
+			// a mount that is healthy again is handed out without mounting it
+			recovered = volume.mountError == "" && !remount
+		}
 
 		if volume.MountCount < 1 {
+			if err := d.checkMountQuota(source); err != nil {
+				logger.Info("quota-exceeded", lager.Data{"err": err.Error()})
+				return dockerdriver.MountResponse{Err: err.Error()}
+			}
+		}
+
+		// a mount in progress is waited for rather than started again, and a
+		// remembered mount error is returned until it expires
+		if (volume.MountCount < 1 && !recovered || remount) && volume.mountError == "" && !volume.mount.inProgress() {
+			if err := d.checkPendingMounts(source); err != nil {
+				logger.Info("busy", lager.Data{"err": err.Error()})
+				return dockerdriver.MountResponse{Err: errResponse(err)}
+			}
 			doMount = true
-			volume.wg.Add(1)
+			mounter = d.volumeMounter(volume)
 			opts = map[string]interface{}{}
 			for k, v := range volume.Opts {
 				opts[k] = v
@@ -174,15 +735,22 @@ func (d *VolumeDriver) Mount(env dockerdriver.Env, mountRequest dockerdriver.Mou
 
 		volume.Mountpoint = mountPath
 		volume.MountCount++
+		addReference(volume, CallerFrom(env.Context()))
 
 		logger.Info("volume-ref-count-incremented", lager.Data{"name": volume.Name, "count": volume.MountCount})
+		d.emitVolumeGauges()
 
-		if err := d.persistState(driverhttp.EnvWithLogger(logger, env)); err != nil {
+		if err := d.persistVolumeOrQueue(driverhttp.EnvWithLogger(logger, env), mountRequest.Name); err != nil {
 			logger.Error("persist-state-failed", err)
+			d.abortMount(driverhttp.EnvWithLogger(logger, env), volume, CallerFrom(env.Context()))
 			return dockerdriver.MountResponse{Err: fmt.Sprintf("persist state failed when mounting: %s", err.Error())}
 		}
 
-		wg = &volume.wg
+		if doMount {
+			volume.mount.begin(phaseMounting)
+			state = &volume.mount
+			pendingDone = d.startPendingMount(source)
+		}
 		return dockerdriver.MountResponse{Mountpoint: volume.Mountpoint}
 	}()
 
@@ -193,62 +761,229 @@ func (d *VolumeDriver) Mount(env dockerdriver.Env, mountRequest dockerdriver.Mou
 	if doMount {
 		mountStartTime := d.time.Now()
 
-		err := d.mount(driverhttp.EnvWithLogger(logger, env), opts, mountPath)
+		err := d.mount(driverhttp.EnvWithLogger(logger, env), mounter, mountRequest.Name, opts, mountPath)
 
 		mountEndTime := d.time.Now()
 		mountDuration := mountEndTime.Sub(mountStartTime)
-		if mountDuration > 8*time.Second {
-			logger.Error("mount-duration-too-high", nil, lager.Data{"mount-duration-in-second": mountDuration / time.Second, "warning": "This may result in container creation failure!"})
-		}
+		source := sourceOpt(opts)
+		d.metrics.Timing(metrics.MountDuration, mountDuration, metrics.SourceTag(source), metrics.OutcomeTag(err))
+		d.metrics.Count(metrics.Mounts, 1, metrics.SourceTag(source), metrics.OutcomeTag(err))
+		d.reportSlowMount(logger, source, mountDuration)
 
-		func() {
+		removed := func() bool {
 			d.volumesLock.Lock()
 			defer d.volumesLock.Unlock()
 
+			// the volume may have been removed meanwhile, its waiters are
+			// woken all the same
+			state.finishMount(err)
+			pendingDone()
+
 			volume := d.volumes[mountRequest.Name]
 			if volume == nil {
-				ret = dockerdriver.MountResponse{Err: fmt.Sprintf("Volume '%s' not found", mountRequest.Name)}
-			} else if err != nil {
-				if _, ok := err.(dockerdriver.SafeError); ok {
-					errBytes, m_err := json.Marshal(err)
-					if m_err != nil {
-						logger.Error("failed-to-marshal-safeerror", m_err)
-						volume.mountError = err.Error()
-					}
-					volume.mountError = string(errBytes)
-				} else {
-					volume.mountError = err.Error()
-				}
+				return true
 			}
+
+			d.recordMountOutcome(driverhttp.EnvWithLogger(logger, env), volume, err)
+			return false
 		}()
 
-		wg.Done()
+		if removed {
+			if err == nil {
+				d.abandonMount(driverhttp.EnvWithLogger(logger, env), mounter, mountRequest.Name, source, mountPath)
+			}
+			return dockerdriver.MountResponse{Err: fmt.Sprintf("Volume '%s' not found", mountRequest.Name)}
+		}
 	}
 
-	wg.Wait()
+	// the first request for the volume mounts it, the others wait for it
+	d.lockSettled(mountRequest.Name, op)
+	defer d.volumesLock.Unlock()
+	op.running()
+
+	volume := d.volumes[mountRequest.Name]
+	if volume == nil {
+		return dockerdriver.MountResponse{Err: fmt.Sprintf("Volume '%s' not found", mountRequest.Name)}
+	} else if volume.mountError != "" {
+		d.abortMount(driverhttp.EnvWithLogger(logger, env), volume, CallerFrom(env.Context()))
+		if doMount {
+			return dockerdriver.MountResponse{Err: volume.mountError}
+		}
+		// requests that did not try the mount themselves are told when
+		// the driver tries again
+		return dockerdriver.MountResponse{Err: withRetryAfter(volume.mountError, d.retryAfter(volume))}
+	}
 
-	return func() dockerdriver.MountResponse {
-		d.volumesLock.Lock()
-		defer d.volumesLock.Unlock()
+	// Check the volume to make sure it's still mounted before handing it out again.
+	mounter = d.volumeMounter(volume)
+	if !doMount && !mounter.Check(driverhttp.EnvWithLogger(logger, env), volume.Name, volume.Mountpoint, d.checkDepth(volume)) {
+		volume.mount.begin(phaseMounting)
+		err := d.mount(driverhttp.EnvWithLogger(logger, env), mounter, volume.Name, volume.Opts, mountPath)
+		volume.mount.finishMount(err)
+		d.recordMountOutcome(driverhttp.EnvWithLogger(logger, env), volume, err)
+		if err != nil {
+			logger.Error("remount-volume-failed", err, lager.Data{"failures": volume.mountFailures})
+			d.abortMount(driverhttp.EnvWithLogger(logger, env), volume, CallerFrom(env.Context()))
+			return dockerdriver.MountResponse{Err: withRetryAfter(fmt.Sprintf("Error remounting volume: %s", err.Error()), d.retryAfter(volume))}
+		}
+	}
+	return dockerdriver.MountResponse{Mountpoint: volume.Mountpoint}
+}
 
-		volume := d.volumes[mountRequest.Name]
-		if volume == nil {
-			return dockerdriver.MountResponse{Err: fmt.Sprintf("Volume '%s' not found", mountRequest.Name)}
-		} else if volume.mountError != "" {
-			return dockerdriver.MountResponse{Err: volume.mountError}
+// ResetMountError forgets a remembered mount failure so that the next Mount
+// request tries to mount the volume again.
+func (d *VolumeDriver) ResetMountError(env dockerdriver.Env, resetRequest ResetMountErrorRequest) dockerdriver.ErrorResponse {
+	logger := env.Logger().Session("reset-mount-error", lager.Data{"volume": resetRequest.Name})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	if resetRequest.Name == "" {
+		return dockerdriver.ErrorResponse{Err: "Missing mandatory 'volume_name'"}
+	}
+
+	d.volumesLock.Lock()
+	defer d.volumesLock.Unlock()
+
+	volume, ok := d.volumes[resetRequest.Name]
+	if !ok {
+		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("Volume '%s' not found", resetRequest.Name)}
+	}
+
+	if volume.mountError != "" {
+		logger.Info("mount-error-reset", lager.Data{"mount-error": volume.mountError})
+	}
+	volume.mountError = ""
+	volume.mountErrorTime = time.Time{}
+	volume.mountFailures = 0
+
+	return dockerdriver.ErrorResponse{}
+}
+
+func (d *VolumeDriver) ExportState(env dockerdriver.Env) ExportStateResponse {
+	logger := env.Logger().Session("export-state")
+	logger.Info("start")
+	defer logger.Info("end")
+
+	d.volumesLock.RLock()
+	defer d.volumesLock.RUnlock()
+
+	exportResponse := ExportStateResponse{
+		Volumes: []ExportedVolume{},
+	}
+
+	for _, volume := range d.volumes {
+		exported := ExportedVolume{
+			VolumeInfo:     volume.VolumeInfo,
+			MountDirectory: volume.MountDirectory,
+			MountRoot:      volume.MountRoot,
+		}
+		exported.Opts, exported.DroppedOpts = exportedOpts(volume)
+		exportResponse.Volumes = append(exportResponse.Volumes, exported)
+	}
+	sort.Slice(exportResponse.Volumes, func(i, j int) bool {
+		return exportResponse.Volumes[i].Name < exportResponse.Volumes[j].Name
+	})
+
+	return exportResponse
+}
+
+func (d *VolumeDriver) ImportState(env dockerdriver.Env, importRequest ImportStateRequest) dockerdriver.ErrorResponse {
+	logger := env.Logger().Session("import-state")
+	logger.Info("start")
+	defer logger.Info("end")
+
+	for _, volume := range importRequest.Volumes {
+		if volume.Name == "" {
+			return dockerdriver.ErrorResponse{Err: "Missing mandatory 'volume_name'"}
+		}
+		if err := ValidateVolumeName(volume.Name); err != nil {
+			return dockerdriver.ErrorResponse{Err: err.Error()}
+		}
+	}
+
+	for i := range importRequest.Volumes {
+		volume := &importRequest.Volumes[i]
+
+		d.volumesLock.RLock()
+		_, exists := d.volumes[volume.Name]
+		d.volumesLock.RUnlock()
+		if exists && !importRequest.Overwrite {
+			logger.Info("skipping-existing-volume", lager.Data{"volume": volume.Name})
+			continue
+		}
+
+		logger.Info("importing-volume", lager.Data{"volume": volume.Name})
+		var response dockerdriver.ErrorResponse
+		if volume.Opts == nil || len(volume.DroppedOpts) > 0 {
+			response = d.importWithoutOpts(driverhttp.EnvWithLogger(logger, env), volume)
 		} else {
-			// Check the volume to make sure it's still mounted before handing it out again.
-			if !doMount && !d.mounter.Check(driverhttp.EnvWithLogger(logger, env), volume.Name, volume.Mountpoint) {
-				wg.Add(1)
-				defer wg.Done()
-				if err := d.mount(driverhttp.EnvWithLogger(logger, env), volume.Opts, mountPath); err != nil {
-					logger.Error("remount-volume-failed", err)
-					return dockerdriver.MountResponse{Err: fmt.Sprintf("Error remounting volume: %s", err.Error())}
-				}
-			}
-			return dockerdriver.MountResponse{Mountpoint: volume.Mountpoint}
+			// the volume already has its source, it is not provisioned again
+			createRequest := dockerdriver.CreateRequest{Name: volume.Name, Opts: withoutProvisionOpts(volume.Opts)}
+			response = d.create(driverhttp.EnvWithLogger(logger, env), createRequest, volume)
 		}
-	}()
+		if response.Err != "" {
+			logger.Info("import-volume-failed", lager.Data{"volume": volume.Name, "err": response.Err})
+			return dockerdriver.ErrorResponse{Err: fmt.Sprintf("failed to import volume '%s': %s", volume.Name, response.Err)}
+		}
+	}
+
+	return dockerdriver.ErrorResponse{}
+}
+
+// importWithoutOpts adds a volume that was exported without usable opts. It
+// has no source, so like a volume restored without its secret opts it must
+// be created again before it mounts. A volume that exists is kept, since the
+// import has nothing to replace its opts with.
+func (d *VolumeDriver) importWithoutOpts(env dockerdriver.Env, imported *ExportedVolume) dockerdriver.ErrorResponse {
+	logger := env.Logger()
+
+	d.volumesLock.Lock()
+	defer d.volumesLock.Unlock()
+
+	if _, ok := d.volumes[imported.Name]; ok {
+		logger.Info("keeping-existing-volume", lager.Data{"volume": imported.Name})
+		return dockerdriver.ErrorResponse{}
+	}
+
+	if err := d.checkVolumeQuota(); err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
+	}
+
+	volume := &NfsVolumeInfo{
+		VolumeInfo:  dockerdriver.VolumeInfo{Name: imported.Name},
+		DroppedOpts: imported.DroppedOpts,
+	}
+	volume.MountDirectory = d.assignMountDirectory(imported.Name)
+	volume.MountRoot = d.assignMountRoot(env)
+	d.keepImportedMountDirectory(volume, imported)
+	createdAt := d.time.Now()
+	volume.CreatedAt = &createdAt
+	d.volumes[imported.Name] = volume
+	d.emitVolumeGauges()
+
+	if err := d.persistVolume(env, imported.Name); err != nil {
+		logger.Error("persist-state-failed", err)
+		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("persist state failed when importing: %s", err.Error())}
+	}
+	return dockerdriver.ErrorResponse{}
+}
+
+// keepImportedMountDirectory must be called with volumesLock held. It moves a
+// new volume to the mount directory and root it was exported with, where
+// this driver has that root and no other volume uses the directory.
+func (d *VolumeDriver) keepImportedMountDirectory(volume *NfsVolumeInfo, imported *ExportedVolume) {
+	if isSafeMountDirectory(imported.MountDirectory) && !d.mountDirectoryTaken(volume.Name, imported.MountDirectory) {
+		volume.MountDirectory = imported.MountDirectory
+	}
+	if imported.MountRoot == "" {
+		return
+	}
+	for _, root := range d.options.MountPathRoots {
+		if root == imported.MountRoot {
+			volume.MountRoot = root
+			return
+		}
+	}
 }
 
 func (d *VolumeDriver) Path(env dockerdriver.Env, pathRequest dockerdriver.PathRequest) dockerdriver.PathResponse {
@@ -276,42 +1011,24 @@ func (d *VolumeDriver) Path(env dockerdriver.Env, pathRequest dockerdriver.PathR
 
 func (d *VolumeDriver) Unmount(env dockerdriver.Env, unmountRequest dockerdriver.UnmountRequest) dockerdriver.ErrorResponse {
 	logger := env.Logger().Session("unmount", lager.Data{"volume": unmountRequest.Name})
+	op := d.inFlight.queue(env, "unmount", unmountRequest.Name)
+	defer op.done()
 
 	if unmountRequest.Name == "" {
 		return dockerdriver.ErrorResponse{Err: "Missing mandatory 'volume_name'"}
 	}
 
-	d.volumesLock.Lock()
-	defer d.volumesLock.Unlock()
-
-	volume, ok := d.volumes[unmountRequest.Name]
-	if !ok {
-		logger.Error("failed-no-such-volume-found", fmt.Errorf("could not find volume %s", unmountRequest.Name))
-
-		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("Volume '%s' not found", unmountRequest.Name)}
-	}
-
-	if volume.Mountpoint == "" {
-		errText := "Volume not previously mounted"
-		logger.Error("failed-mountpoint-not-assigned", errors.New(errText))
-		return dockerdriver.ErrorResponse{Err: errText}
-	}
-
-	if volume.MountCount == 1 {
-		if err := d.unmount(driverhttp.EnvWithLogger(logger, env), unmountRequest.Name, volume.Mountpoint); err != nil {
-			return dockerdriver.ErrorResponse{Err: err.Error()}
-		}
+	if err := d.admit(driverhttp.EnvWithLogger(logger, env), unmountRequest.Name); err != nil {
+		return dockerdriver.ErrorResponse{Err: errResponse(err)}
 	}
 
-	volume.MountCount--
-	logger.Info("volume-ref-count-decremented", lager.Data{"name": volume.Name, "count": volume.MountCount})
-
-	if volume.MountCount < 1 {
-		delete(d.volumes, unmountRequest.Name)
-	}
+	// an unmount during the mount of the volume releases the mounted volume
+	d.lockSettled(unmountRequest.Name, op)
+	defer d.volumesLock.Unlock()
+	op.running()
 
-	if err := d.persistState(driverhttp.EnvWithLogger(logger, env)); err != nil {
-		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("failed to persist state when unmounting: %s", err.Error())}
+	if err := d.releaseMount(driverhttp.EnvWithLogger(logger, env), unmountRequest.Name, CallerFrom(env.Context())); err != nil {
+		return dockerdriver.ErrorResponse{Err: err.Error()}
 	}
 
 	return dockerdriver.ErrorResponse{}
@@ -325,27 +1042,48 @@ func (d *VolumeDriver) Remove(env dockerdriver.Env, removeRequest dockerdriver.R
 	if removeRequest.Name == "" {
 		return dockerdriver.ErrorResponse{Err: "Missing mandatory 'volume_name'"}
 	}
+	op := d.inFlight.queue(env, "remove", removeRequest.Name)
+	defer op.done()
 
-	vol, err := d.getVolume(driverhttp.EnvWithLogger(logger, env), removeRequest.Name)
+	// a mount in progress finishes first, so that the volume is checked for
+	// use and removed as it is once it settled
+	d.lockSettled(removeRequest.Name, op)
+	defer d.volumesLock.Unlock()
+	op.running()
 
-	if err != nil {
+	vol, ok := d.volumes[removeRequest.Name]
+	if !ok {
 		logger.Error("warning-volume-removal", fmt.Errorf(fmt.Sprintf("Volume %s not found", removeRequest.Name)))
 		return dockerdriver.ErrorResponse{}
 	}
 
+	if vol.Mountpoint != "" && vol.Frozen {
+		return dockerdriver.ErrorResponse{Err: frozenError(removeRequest.Name)}
+	}
+
+	// containers may still use the volume, see RemoveVolume to remove it
+	// anyway
+	if vol.MountCount > 0 {
+		logger.Info("volume-in-use", lager.Data{"mount-count": vol.MountCount})
+		return dockerdriver.ErrorResponse{Err: inUseError(removeRequest.Name, vol.MountCount)}
+	}
+
 	if vol.Mountpoint != "" {
-		if err := d.unmount(driverhttp.EnvWithLogger(logger, env), removeRequest.Name, vol.Mountpoint); err != nil {
+		if err := d.unmount(driverhttp.EnvWithLogger(logger, env), d.volumeMounter(vol), removeRequest.Name, vol.Mountpoint); err != nil {
 			return dockerdriver.ErrorResponse{Err: err.Error()}
 		}
+		d.releaseExport(driverhttp.EnvWithLogger(logger, env), removeRequest.Name, sourceOpt(vol.Opts))
+		d.publish(EventUnmounted, removeRequest.Name, nil)
 	}
 
 	logger.Info("removing-volume", lager.Data{"name": removeRequest.Name})
 
-	d.volumesLock.Lock()
-	defer d.volumesLock.Unlock()
 	delete(d.volumes, removeRequest.Name)
+	d.volumeLimiter.Forget(removeRequest.Name)
+	d.publish(EventRemoved, removeRequest.Name, nil)
+	d.emitVolumeGauges()
 
-	if err := d.persistState(driverhttp.EnvWithLogger(logger, env)); err != nil {
+	if err := d.persistVolumeOrQueue(driverhttp.EnvWithLogger(logger, env), removeRequest.Name); err != nil {
 		return dockerdriver.ErrorResponse{Err: fmt.Sprintf("failed to persist state when removing: %s", err.Error())}
 	}
 
@@ -358,20 +1096,32 @@ func (d *VolumeDriver) Get(env dockerdriver.Env, getRequest dockerdriver.GetRequ
 		return dockerdriver.GetResponse{Err: err.Error()}
 	}
 
+	// report where the volume is, or will be, mounted. The snapshot must not
+	// be changed, so a directory from hand-written or imported state is
+	// left to Mount to replace under volumesLock.
+	mountpoint := volume.Mountpoint
+	if mountpoint == "" && isMountDirectoryElement(volume.MountDirectory) {
+		if path, err := d.mountPathBelow(d.volumeRoot(volume), volume.MountDirectory); err == nil {
+			mountpoint = path
+		}
+	}
+
 	return dockerdriver.GetResponse{
 		Volume: dockerdriver.VolumeInfo{
 			Name:       getRequest.Name,
-			Mountpoint: volume.Mountpoint,
+			Mountpoint: mountpoint,
+			MountCount: volume.MountCount,
 		},
 	}
 }
 
+// getVolume returns the published snapshot of the record of a volume, which
+// is read without volumesLock and must not be changed; change the record in
+// d.volumes with volumesLock held instead.
 func (d *VolumeDriver) getVolume(env dockerdriver.Env, volumeName string) (*NfsVolumeInfo, error) {
 	logger := env.Logger().Session("get-volume")
-	d.volumesLock.RLock()
-	defer d.volumesLock.RUnlock()
 
-	if vol, ok := d.volumes[volumeName]; ok {
+	if vol, ok := d.loadVolumes()[volumeName]; ok {
 		logger.Info("getting-volume", lager.Data{"name": volumeName})
 		return vol, nil
 	}
@@ -381,10 +1131,47 @@ func (d *VolumeDriver) getVolume(env dockerdriver.Env, volumeName string) (*NfsV
 
 func (d *VolumeDriver) Capabilities(env dockerdriver.Env) dockerdriver.CapabilitiesResponse {
 	return dockerdriver.CapabilitiesResponse{
-		Capabilities: dockerdriver.CapabilityInfo{Scope: "local"},
+		Capabilities: dockerdriver.CapabilityInfo{Scope: d.scope()},
 	}
 }
 
+// admit applies the configured rate limits to Mount and Unmount requests.
+// Only volumes that exist have a bucket of the volume rate limit, so that
+// requests for made up names cannot grow the buckets without bounds; removing
+// a volume forgets its bucket under the same lock.
+func (d *VolumeDriver) admit(env dockerdriver.Env, volumeName string) error {
+	logger := env.Logger().Session("admit")
+
+	if !d.globalLimiter.Allow("") {
+		logger.Info("global-rate-limit-exceeded")
+		return safeerrors.NewBusy(d.globalLimiter.RetryAfter(""), "Rate limit exceeded, try again later")
+	}
+
+	d.volumesLock.RLock()
+	defer d.volumesLock.RUnlock()
+
+	if _, ok := d.volumes[volumeName]; ok && !d.volumeLimiter.Allow(volumeName) {
+		logger.Info("volume-rate-limit-exceeded", lager.Data{"volume": volumeName})
+		return safeerrors.NewBusy(d.volumeLimiter.RetryAfter(volumeName), "Rate limit exceeded for volume '%s', try again later", volumeName)
+	}
+
+	return nil
+}
+
+// emitVolumeGauges must be called with volumesLock held.
+func (d *VolumeDriver) emitVolumeGauges() {
+	mounted := 0
+	for _, volume := range d.volumes {
+		if volume.MountCount > 0 {
+			mounted++
+		}
+	}
+
+	d.metrics.Gauge(metrics.VolumeCount, float64(len(d.volumes)))
+	d.metrics.Gauge(metrics.MountedCount, float64(mounted))
+	d.metrics.Gauge(metrics.UnmountedCount, float64(len(d.volumes)-mounted))
+}
+
 func (d *VolumeDriver) exists(path string) (bool, error) {
 	_, err := d.os.Stat(path)
 	if err == nil {
@@ -397,11 +1184,17 @@ func (d *VolumeDriver) exists(path string) (bool, error) {
 }
 
 func (d *VolumeDriver) mountPath(env dockerdriver.Env, volumeId string) string {
-	logger := env.Logger().Session("mount-path")
+	return d.mountPathOn(env, d.mountPathRoot, volumeId)
+}
+
+// mountPathOn creates root if needed and returns the absolute path of
+// volumeId below it.
+func (d *VolumeDriver) mountPathOn(env dockerdriver.Env, root string, volumeId string) string {
+	logger := env.Logger().Session("mount-path", lager.Data{"root": root})
 	orig := d.osHelper.Umask(000)
 	defer d.osHelper.Umask(orig)
 
-	dir, err := d.filepath.Abs(d.mountPathRoot)
+	dir, err := d.filepath.Abs(root)
 	if err != nil {
 		logger.Fatal("abs-failed", err)
 	}
@@ -413,18 +1206,35 @@ func (d *VolumeDriver) mountPath(env dockerdriver.Env, volumeId string) string {
 	return filepath.Join(dir, volumeId)
 }
 
-func (d *VolumeDriver) mount(env dockerdriver.Env, opts map[string]interface{}, mountPath string) error {
-	source, sourceOk := opts["source"].(string)
+// mountPathBelow returns the absolute path of volumeId below root like
+// mountPathOn, but neither creates root nor changes the umask, which is
+// shared by the whole process, so that requests that only report paths can
+// run without volumesLock.
+func (d *VolumeDriver) mountPathBelow(root string, volumeId string) (string, error) {
+	dir, err := d.filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, volumeId), nil
+}
+
+func (d *VolumeDriver) mount(env dockerdriver.Env, mounter Mounter, name string, opts map[string]interface{}, mountPath string) error {
+	source := sourceOpt(opts)
 	logger := env.Logger().Session("mount", lager.Data{"source": source, "target": mountPath})
 	logger.Info("start")
 	defer logger.Info("end")
 
-	if !sourceOk {
-		err := errors.New("no source information")
+	if source == "" {
+		err := fmt.Errorf("Volume '%s' has no source, it must be created again before being mounted", name)
 		logger.Error("unable-to-extract-source", err)
 		return err
 	}
 
+	if err := d.recordIntent(env, IntentMount, name, mountPath); err != nil {
+		return err
+	}
+	defer d.clearIntent(env, name, mountPath)
+
 	orig := d.osHelper.Umask(000)
 	defer d.osHelper.Umask(orig)
 
@@ -434,10 +1244,28 @@ func (d *VolumeDriver) mount(env dockerdriver.Env, opts map[string]interface{},
 		return err
 	}
 
-	err = d.mounter.Mount(env, source, mountPath, opts)
+	d.warnUncached(env, opts)
+
+	hardened, stripped := d.hardenOpts(mounterOpts(opts))
+	if len(stripped) > 0 {
+		logger.Info("stripped-privileged-opts", lager.Data{"opts": stripped})
+	}
+
+	resolved, secrets, err := d.resolveSecrets(env, hardened)
+	if err == nil {
+		err = d.acquireExport(env, name, source)
+	}
+	if err == nil {
+		heartbeatEnv, stopHeartbeat := d.startMountHeartbeat(env, name, source)
+		err = redactSecrets(mounter.Mount(heartbeatEnv, source, mountPath, resolved), secrets)
+		stopHeartbeat()
+		if err != nil {
+			d.releaseExport(env, name, source)
+		}
+	}
 	if err != nil {
 		logger.Error("mount-failed: ", err)
-		rm_err := d.os.Remove(mountPath)
+		rm_err := d.removeMountpoint(env, mountPath)
 		if rm_err != nil {
 			logger.Error("mountpoint-remove-failed", rm_err, lager.Data{"mount-path": mountPath})
 		}
@@ -445,62 +1273,91 @@ func (d *VolumeDriver) mount(env dockerdriver.Env, opts map[string]interface{},
 	return err
 }
 
-func (d *VolumeDriver) persistState(env dockerdriver.Env) error {
-	logger := env.Logger().Session("persist-state")
-	logger.Info("start")
-	defer logger.Info("end")
+// abandonMount undoes a mount that finished after its volume was removed,
+// which nothing else would unmount, and drops its export reference.
+func (d *VolumeDriver) abandonMount(env dockerdriver.Env, mounter Mounter, name string, source string, mountPath string) {
+	logger := env.Logger().Session("abandon-mount", lager.Data{"volume": name, "mountpoint": mountPath})
 
-	orig := d.osHelper.Umask(000)
-	defer d.osHelper.Umask(orig)
+	if err := d.unmount(env, mounter, name, mountPath); err != nil {
+		logger.Error("unmount-failed", err)
+		if err := d.removeMountpoint(env, mountPath); err != nil {
+			logger.Error("remove-mountpoint-failed", err)
+		}
+	}
+	d.releaseExport(env, name, source)
+}
 
-	stateFile := d.mountPath(env, "driver-state.json")
+// recordMountOutcome must be called with volumesLock held. It keeps the
+// time of the last successful mount, or the last mount error, with the
+// persisted state of the volume.
+func (d *VolumeDriver) recordMountOutcome(env dockerdriver.Env, volume *NfsVolumeInfo, err error) {
+	logger := env.Logger().Session("record-mount-outcome")
 
-	stateData, err := json.Marshal(d.volumes)
 	if err != nil {
-		logger.Error("failed-to-marshall-state", err)
-		return err
+		d.recordMountError(env, volume, err)
+		d.publish(EventMountFailed, volume.Name, err)
+	} else {
+		mountedAt := d.time.Now()
+		volume.LastMountedAt = &mountedAt
+		volume.mountFailures = 0
+		d.publish(EventMounted, volume.Name, nil)
 	}
 
-	err = d.ioutil.WriteFile(stateFile, stateData, os.ModePerm)
-	if err != nil {
-		logger.Error("failed-to-write-state-file", err, lager.Data{"stateFile": stateFile})
-		return err
+	if err := d.persistVolumeLater(env, volume.Name); err != nil {
+		logger.Error("persist-state-failed", err)
 	}
-
-	logger.Debug("state-saved", lager.Data{"state-file": stateFile})
-	return nil
 }
 
-func (d *VolumeDriver) restoreState(env dockerdriver.Env) {
-	logger := env.Logger().Session("restore-state")
-	logger.Info("start")
-	defer logger.Info("end")
-
-	stateFile := filepath.Join(d.mountPathRoot, "driver-state.json")
-
-	stateData, err := d.ioutil.ReadFile(stateFile)
-	if err != nil {
-		logger.Info("failed-to-read-state-file", lager.Data{"err": err, "stateFile": stateFile})
-		return
+// recordMountError must be called with volumesLock held.
+func (d *VolumeDriver) recordMountError(env dockerdriver.Env, volume *NfsVolumeInfo, err error) {
+	logger := env.Logger().Session("record-mount-error")
+
+	volume.mountError = err.Error()
+	volume.mountErrorTime = d.time.Now()
+	volume.mountFailures++
+	failedAt := volume.mountErrorTime
+	volume.LastMountError = volume.mountError
+	volume.LastMountErrorAt = &failedAt
+
+	if safe, ok := safeerrors.From(err); ok {
+		logger.Info("safe-error", lager.Data{"code": safe.Code, "detail": safe.Detail()})
+		errBytes, m_err := json.Marshal(safe)
+		if m_err != nil {
+			logger.Error("failed-to-marshal-safeerror", m_err)
+			return
+		}
+		volume.mountError = string(errBytes)
+		volume.LastMountError = volume.mountError
 	}
+}
 
-	state := map[string]*NfsVolumeInfo{}
-	err = json.Unmarshal(stateData, &state)
-
-	logger.Info("state", lager.Data{"state": state})
+// clearStaleMountError must be called with volumesLock held. It drops the
+// remembered mount error when the mount turns out to be healthy, or when the
+// error has outlived the configured TTL, in which case it returns true to
+// signal that the volume has to be mounted again.
+func (d *VolumeDriver) clearStaleMountError(env dockerdriver.Env, volume *NfsVolumeInfo, mountPath string) bool {
+	logger := env.Logger().Session("clear-stale-mount-error", lager.Data{"volume": volume.Name})
+
+	if d.volumeMounter(volume).Check(env, volume.Name, mountPath, d.checkDepth(volume)) {
+		logger.Info("mount-error-cleared", lager.Data{"mount-error": volume.mountError})
+		volume.mountError = ""
+		volume.mountFailures = 0
+		return false
+	}
 
-	if err != nil {
-		logger.Error("failed-to-unmarshall-state", err, lager.Data{"stateFile": stateFile})
-		return
+	// the TTL doubles with every consecutive failure, so that a flapping
+	// mount is not retried on every request
+	ttl := d.mountErrorTTL(volume)
+	if ttl > 0 && d.time.Now().Sub(volume.mountErrorTime) >= ttl {
+		logger.Info("mount-error-expired", lager.Data{"mount-error": volume.mountError, "ttl": ttl.String()})
+		volume.mountError = ""
+		return true
 	}
-	logger.Info("state-restored", lager.Data{"state-file": stateFile})
 
-	d.volumesLock.Lock()
-	defer d.volumesLock.Unlock()
-	d.volumes = state
+	return false
 }
 
-func (d *VolumeDriver) unmount(env dockerdriver.Env, name string, mountPath string) error {
+func (d *VolumeDriver) unmount(env dockerdriver.Env, mounter Mounter, name string, mountPath string) error {
 	logger := env.Logger().Session("unmount")
 	logger.Info("start")
 	defer logger.Info("end")
@@ -526,12 +1383,18 @@ func (d *VolumeDriver) unmount(env dockerdriver.Env, name string, mountPath stri
 
 	logger.Info("unmount-volume-folder", lager.Data{"mountpath": mountPath})
 
-	err = d.mounter.Unmount(env, mountPath)
+	if err := d.recordIntent(env, IntentUnmount, name, mountPath); err != nil {
+		return err
+	}
+	defer d.clearIntent(env, name, mountPath)
+
+	err = mounter.Unmount(env, mountPath)
+	d.metrics.Count(metrics.Unmounts, 1, metrics.OutcomeTag(err))
 	if err != nil {
 		logger.Error("unmount-failed", err)
 		return fmt.Errorf("Error unmounting volume: %s", err.Error())
 	}
-	err = d.os.Remove(mountPath)
+	err = d.removeMountpoint(env, mountPath)
 	if err != nil {
 		logger.Error("remove-mountpoint-failed", err)
 		return fmt.Errorf("Error removing mountpoint: %s", err.Error())
@@ -542,35 +1405,185 @@ func (d *VolumeDriver) unmount(env dockerdriver.Env, name string, mountPath stri
 	return nil
 }
 
+// removeMountpoint removes the directory a volume was mounted on, after
+// making sure that the kernel no longer has a mount there. Unmount helpers
+// can exit cleanly without unmounting, and the path must never be treated as
+// an empty directory while it still exposes the share.
+func (d *VolumeDriver) removeMountpoint(env dockerdriver.Env, mountPath string) error {
+	logger := env.Logger().Session("remove-mountpoint", lager.Data{"mount-path": mountPath})
+
+	mounted, err := d.mountChecker.Exists(mountPath)
+	if err != nil {
+		logger.Error("failed-proc-mounts-check", err)
+		return err
+	}
+	if mounted {
+		err := fmt.Errorf("%s is still mounted", mountPath)
+		logger.Error("refusing-to-remove-mountpoint", err)
+		d.metrics.Count(metrics.StillMounted, 1)
+		return err
+	}
+
+	return d.os.Remove(mountPath)
+}
+
 func (d *VolumeDriver) checkMounts(env dockerdriver.Env) {
 	logger := env.Logger().Session("check-mounts")
 	logger.Info("start")
 	defer logger.Info("end")
 
 	for key, mount := range d.volumes {
-		if !d.mounter.Check(driverhttp.EnvWithLogger(logger, env), key, mount.VolumeInfo.Mountpoint) {
+		if !d.volumeMounter(mount).Check(driverhttp.EnvWithLogger(logger, env), key, mount.VolumeInfo.Mountpoint, d.checkDepth(mount)) {
 			delete(d.volumes, key)
 		}
 	}
 }
 
-func (d *VolumeDriver) Drain(env dockerdriver.Env) error {
-	logger := env.Logger().Session("check-mounts")
+// drainPollInterval is how often Drain checks whether Options.DrainTimeout
+// has run out.
+const drainPollInterval = 10 * time.Millisecond
+
+// Drain unmounts every mounted volume in parallel and purges the mount path
+// roots marked as the driver's, unless Options.SkipPurge is set. Unmounts
+// that have not finished when Options.DrainTimeout runs out are left behind,
+// and the purge lazily unmounts what they were working on, so that a dead
+// server cannot block the drain of the cell. The response reports every
+// unmount and the kernel mounts left below the roots.
+func (d *VolumeDriver) Drain(env dockerdriver.Env) DrainResponse {
+	logger := env.Logger().Session("drain")
 	logger.Info("start")
 	defer logger.Info("end")
 
+	d.stopOnce.Do(func() { close(d.stop) })
+
+	type drainMount struct {
+		name       string
+		mountpoint string
+		mounter    Mounter
+		source     string
+	}
+
 	// flush any volumes that are still in our map
+	mounts := []drainMount{}
+	d.volumesLock.Lock()
 	for key, mount := range d.volumes {
+		d.unbindAll(env, mount)
 		if mount.Mountpoint != "" && mount.MountCount > 0 {
-			err := d.unmount(env, mount.Name, mount.Mountpoint)
+			mounts = append(mounts, drainMount{name: mount.Name, mountpoint: mount.Mountpoint, mounter: d.volumeMounter(mount), source: sourceOpt(mount.Opts)})
+		}
+		delete(d.volumes, key)
+	}
+	d.volumesLock.Unlock()
+
+	sort.Slice(mounts, func(i, j int) bool { return mounts[i].name < mounts[j].name })
+
+	// results are only read once every unmount finished, or under the lock
+	// when the deadline is reached while some are still running
+	var resultsLock sync.Mutex
+	results := make([]*DrainedVolume, len(mounts))
+
+	var wg sync.WaitGroup
+	for i, mount := range mounts {
+		wg.Add(1)
+		go func(i int, mount drainMount) {
+			defer wg.Done()
+			result := &DrainedVolume{Name: mount.name, Mountpoint: mount.mountpoint}
+			err := d.unmount(env, mount.mounter, mount.name, mount.mountpoint)
 			if err != nil {
-				logger.Error("drain-unmount-failed", err, lager.Data{"mount-name": mount.Name, "mount-point": mount.Mountpoint})
+				logger.Error("drain-unmount-failed", err, lager.Data{"mount-name": mount.name, "mount-point": mount.mountpoint})
+				result.Err = err.Error()
+			} else {
+				d.releaseExport(env, mount.name, mount.source)
+			}
+
+			resultsLock.Lock()
+			defer resultsLock.Unlock()
+			results[i] = result
+		}(i, mount)
+	}
+
+	unmounted := make(chan struct{})
+	go func() {
+		wg.Wait()
+		// the references of the unmounted volumes are dropped in time
+		d.awaitReleases()
+		close(unmounted)
+	}()
+
+	// the deadline follows the injected time, which only tells the time, so
+	// it is checked every drainPollInterval
+	deadline := d.time.Now().Add(d.options.DrainTimeout)
+	var ticks <-chan time.Time
+	if d.options.DrainTimeout > 0 {
+		ticker := time.NewTicker(drainPollInterval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	problems := []string{}
+wait:
+	for {
+		select {
+		case <-unmounted:
+			break wait
+		case <-ticks:
+			if d.time.Now().Before(deadline) {
+				continue
 			}
+			logger.Info("drain-deadline-reached", lager.Data{"timeout": d.options.DrainTimeout.String()})
+			problems = append(problems, fmt.Sprintf("drain did not finish unmounting within %s, purging the remaining mounts", d.options.DrainTimeout))
+			break wait
 		}
-		delete(d.volumes, key)
 	}
 
-	d.mounter.Purge(env, d.mountPathRoot)
+	response := DrainResponse{Volumes: []DrainedVolume{}}
+	failed := []string{}
+	resultsLock.Lock()
+	for i, mount := range mounts {
+		result := results[i]
+		if result == nil {
+			result = &DrainedVolume{Name: mount.name, Mountpoint: mount.mountpoint, Err: fmt.Sprintf("unmount did not finish within %s", d.options.DrainTimeout)}
+		} else if result.Err != "" {
+			failed = append(failed, mount.name)
+		}
+		response.Volumes = append(response.Volumes, *result)
+	}
+	resultsLock.Unlock()
+	if len(failed) > 0 {
+		problems = append(problems, fmt.Sprintf("drain failed to unmount %s", strings.Join(failed, ", ")))
+	}
 
-	return nil
+	d.purgeRoots(env)
+	d.unlockRoot(env)
+
+	response.RemainingMounts = d.remainingMounts(env)
+	if len(response.RemainingMounts) > 0 {
+		logger.Info("mounts-remaining", lager.Data{"mounts": response.RemainingMounts})
+		problems = append(problems, fmt.Sprintf("%d mounts remain below the mount path roots", len(response.RemainingMounts)))
+	}
+
+	response.Err = strings.Join(problems, "; ")
+	return response
+}
+
+// remainingMounts lists the kernel mounts below the mount path roots.
+func (d *VolumeDriver) remainingMounts(env dockerdriver.Env) []string {
+	logger := env.Logger().Session("remaining-mounts")
+
+	remaining := []string{}
+	for _, root := range d.roots() {
+		abs, err := d.filepath.Abs(root)
+		if err != nil {
+			logger.Error("abs-failed", err, lager.Data{"root": root})
+			continue
+		}
+		mounts, err := d.mountChecker.List(regexp.MustCompile("^" + regexp.QuoteMeta(filepath.Clean(abs)+string(filepath.Separator))))
+		if err != nil {
+			logger.Error("list-mounts-failed", err, lager.Data{"root": abs})
+			continue
+		}
+		remaining = append(remaining, mounts...)
+	}
+	sort.Strings(remaining)
+	return remaining
 }
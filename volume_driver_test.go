@@ -6,19 +6,32 @@ import (
 	"errors"
 	"fmt"
 	"github.com/onsi/gomega/gbytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
 	"code.cloudfoundry.org/dockerdriver"
 	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/goshims/filepathshim"
 	"code.cloudfoundry.org/goshims/filepathshim/filepath_fake"
+	"code.cloudfoundry.org/goshims/ioutilshim"
 	"code.cloudfoundry.org/goshims/ioutilshim/ioutil_fake"
+	"code.cloudfoundry.org/goshims/osshim"
 	"code.cloudfoundry.org/goshims/osshim/os_fake"
 	"code.cloudfoundry.org/goshims/timeshim/time_fake"
 	"code.cloudfoundry.org/lager/lagertest"
 	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/admission"
+	"code.cloudfoundry.org/volumedriver/bindmounter"
+	"code.cloudfoundry.org/volumedriver/invokerfakes"
+	"code.cloudfoundry.org/volumedriver/metrics"
 	"code.cloudfoundry.org/volumedriver/oshelper"
+	"code.cloudfoundry.org/volumedriver/rootlock"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
 	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -36,6 +49,8 @@ var _ = Describe("Nfs Driver", func() {
 	var fakeMountChecker *volumedriverfakes.FakeMountChecker
 	var volumeDriver *volumedriver.VolumeDriver
 	var mountDir string
+	var unmounted map[string]bool
+	var markUnmounted func(dockerdriver.Env, string) error
 
 	const volumeName = "test-volume-id"
 
@@ -56,7 +71,29 @@ var _ = Describe("Nfs Driver", func() {
 		fakeTime = &time_fake.FakeTime{}
 		fakeMounter = &volumedriverfakes.FakeMounter{}
 		fakeMountChecker = &volumedriverfakes.FakeMountChecker{}
-		fakeMountChecker.ExistsReturns(true, nil)
+
+		// paths are mounted until they are unmounted, so that the driver can
+		// tell that the kernel let go of them
+		unmounted = map[string]bool{}
+		var unmountedLock sync.Mutex
+		markUnmounted = func(_ dockerdriver.Env, target string) error {
+			unmountedLock.Lock()
+			defer unmountedLock.Unlock()
+			unmounted[target] = true
+			return nil
+		}
+		fakeMounter.MountStub = func(_ dockerdriver.Env, _ string, target string, _ map[string]interface{}) error {
+			unmountedLock.Lock()
+			defer unmountedLock.Unlock()
+			delete(unmounted, target)
+			return nil
+		}
+		fakeMounter.UnmountStub = markUnmounted
+		fakeMountChecker.ExistsStub = func(path string) (bool, error) {
+			unmountedLock.Lock()
+			defer unmountedLock.Unlock()
+			return !unmounted[path], nil
+		}
 	})
 
 	Context("created", func() {
@@ -99,19 +136,41 @@ var _ = Describe("Nfs Driver", func() {
 					Expect(strings.Replace(to, `\`, "/", -1)).To(Equal("/path/to/mount/" + volumeName))
 				})
 
-				It("should return 'source' in the mount Opts", func() {
-					expected := map[string]interface{}{
-						"source": ip,
-					}
+				It("passes the source to the mounter only as the source", func() {
 					Expect(fakeMounter.MountCallCount()).To(Equal(1))
 					_, _, _, opts := fakeMounter.MountArgsForCall(0)
-					Expect(opts).To(Equal(expected))
+					Expect(opts).To(Equal(hardened(map[string]interface{}{})))
+				})
+
+				It("records the mount intent until the mount returns", func() {
+					intentFile := "/path/to/mount/driver-state.d/" + volumeName + ".intent"
+					stateFile, data, _ := fakeIoutil.WriteFileArgsForCall(2)
+					Expect(stateFile).To(Equal(intentFile))
+					Expect(string(data)).To(ContainSubstring(`"Operation":"mount"`))
+					Expect(fakeOs.RemoveArgsForCall(fakeOs.RemoveCallCount() - 1)).To(Equal(intentFile))
+				})
+
+				Context("when the mount intent cannot be recorded", func() {
+					BeforeEach(func() {
+						fakeIoutil.WriteFileStub = func(path string, _ []byte, _ os.FileMode) error {
+							if strings.HasSuffix(path, ".intent") {
+								return errors.New("badness")
+							}
+							return nil
+						}
+					})
+
+					It("does not mount the volume", func() {
+						Expect(fakeMounter.MountCallCount()).To(BeZero())
+					})
 				})
 
 				It("should write state", func() {
 					// 1 - persist on create
 					// 2 - persist on mount
-					Expect(fakeIoutil.WriteFileCallCount()).To(Equal(2))
+					// 3 - mount intent
+					// 4 - mount outcome
+					Expect(fakeIoutil.WriteFileCallCount()).To(Equal(4))
 				})
 
 				Context("when the file system cant be written to", func() {
@@ -138,6 +197,107 @@ var _ = Describe("Nfs Driver", func() {
 						Expect(mountResponse.Err).To(Equal("unsafe-error"))
 						Expect(mountResponse.Mountpoint).To(Equal(""))
 					})
+
+					Context("when the volume is mounted again", func() {
+						var elapsed time.Duration
+
+						BeforeEach(func() {
+							elapsed = time.Second
+						})
+
+						JustBeforeEach(func() {
+							fakeTime.NowReturns(time.Time{}.Add(elapsed))
+							mountResponse = volumeDriver.Mount(env, dockerdriver.MountRequest{Name: volumeName})
+						})
+
+						It("returns the remembered error with a hint when it is tried again", func() {
+							Expect(mountResponse.Err).To(Equal("unsafe-error (retry after 29s)"))
+							Expect(fakeMounter.MountCallCount()).To(Equal(1))
+						})
+
+						Context("when the mount keeps failing", func() {
+							BeforeEach(func() {
+								elapsed = volumedriver.DefaultMountErrorTTL + time.Second
+							})
+
+							mountAt := func(at time.Duration) dockerdriver.MountResponse {
+								fakeTime.NowReturns(time.Time{}.Add(at))
+								return volumeDriver.Mount(env, dockerdriver.MountRequest{Name: volumeName})
+							}
+
+							It("doubles how long the error is remembered", func() {
+								Expect(mountResponse.Err).To(Equal("unsafe-error"))
+								Expect(fakeMounter.MountCallCount()).To(Equal(2))
+
+								Expect(mountAt(elapsed + 31*time.Second).Err).To(Equal("unsafe-error (retry after 29s)"))
+								Expect(fakeMounter.MountCallCount()).To(Equal(2))
+
+								Expect(mountAt(elapsed + 61*time.Second).Err).To(Equal("unsafe-error"))
+								Expect(fakeMounter.MountCallCount()).To(Equal(3))
+								Expect(mountAt(elapsed + 61*time.Second + time.Minute).Err).To(Equal("unsafe-error (retry after 1m0s)"))
+							})
+
+							It("remembers the error no longer than the MaxMountErrorTTL", func() {
+								policy := volumedriver.DefaultOptions().Policy()
+								policy.MaxMountErrorTTL = 45 * time.Second
+								Expect(volumeDriver.UpdatePolicy(env, policy)).To(Succeed())
+
+								Expect(mountAt(elapsed + 44*time.Second).Err).To(Equal("unsafe-error (retry after 1s)"))
+								Expect(mountAt(elapsed + 45*time.Second).Err).To(Equal("unsafe-error"))
+								Expect(fakeMounter.MountCallCount()).To(Equal(3))
+							})
+
+							It("starts over once the volume is mounted", func() {
+								fakeMounter.MountReturnsOnCall(2, nil)
+								Expect(mountAt(elapsed + 61*time.Second).Err).To(Equal(""))
+
+								fakeMounter.CheckReturns(false)
+								fakeMounter.MountReturnsOnCall(3, errors.New("unsafe-error"))
+								Expect(mountAt(elapsed + 62*time.Second).Err).To(Equal("Error remounting volume: unsafe-error (retry after 30s)"))
+							})
+						})
+
+						Context("when the mount error has expired", func() {
+							BeforeEach(func() {
+								elapsed = volumedriver.DefaultMountErrorTTL + time.Second
+								fakeMounter.MountReturnsOnCall(1, nil)
+							})
+
+							It("mounts the volume again", func() {
+								Expect(fakeMounter.MountCallCount()).To(Equal(2))
+								Expect(mountResponse.Err).To(Equal(""))
+								Expect(strings.Replace(mountResponse.Mountpoint, `\`, "/", -1)).To(Equal("/path/to/mount/" + volumeName))
+							})
+						})
+
+						Context("when the volume is healthy again", func() {
+							BeforeEach(func() {
+								fakeMounter.CheckReturns(true)
+							})
+
+							It("clears the error without mounting again", func() {
+								Expect(mountResponse.Err).To(Equal(""))
+								Expect(fakeMounter.MountCallCount()).To(Equal(1))
+							})
+						})
+
+						Context("when the mount error has been reset", func() {
+							BeforeEach(func() {
+								fakeMounter.MountReturnsOnCall(1, nil)
+							})
+
+							JustBeforeEach(func() {
+								resetResponse := volumeDriver.ResetMountError(env, volumedriver.ResetMountErrorRequest{Name: volumeName})
+								Expect(resetResponse.Err).To(Equal(""))
+								mountResponse = volumeDriver.Mount(env, dockerdriver.MountRequest{Name: volumeName})
+							})
+
+							It("mounts the volume again", func() {
+								Expect(fakeMounter.MountCallCount()).To(Equal(2))
+								Expect(mountResponse.Err).To(Equal(""))
+							})
+						})
+					})
 				})
 
 				Context("when mounter returns an safe error", func() {
@@ -150,13 +310,28 @@ var _ = Describe("Nfs Driver", func() {
 						Expect(mountResponse.Mountpoint).To(Equal(""))
 					})
 
+					It("adds the retry hint to the remembered error as a field", func() {
+						mountResponse = volumeDriver.Mount(env, dockerdriver.MountRequest{Name: volumeName})
+						Expect(mountResponse.Err).To(MatchJSON(`{"SafeDescription":"safe-error","RetryAfter":30}`))
+					})
+
+				})
+
+				Context("when mounter returns a safe error with a code", func() {
+					BeforeEach(func() {
+						fakeMounter.MountReturns(safeerrors.Wrap(errors.New("mount.nfs: access denied by server while mounting 10.0.0.1:/data"), safeerrors.PermissionDenied, "the server refused the mount"))
+					})
+
+					It("returns the code but not the internal detail", func() {
+						Expect(mountResponse.Err).To(Equal(`{"SafeDescription":"the server refused the mount","Code":"permission-denied"}`))
+					})
 				})
 
 				Context("when the mount operation takes more than 8 seconds", func() {
 					BeforeEach(func(){
 						startTime := time.Now()
-						fakeTime.NowReturnsOnCall(0, startTime)
-						fakeTime.NowReturnsOnCall(1, startTime.Add(time.Second * 9))
+						fakeTime.NowReturnsOnCall(1, startTime)
+						fakeTime.NowReturnsOnCall(2, startTime.Add(time.Second * 9))
 					})
 					It("logs a warning", func() {
 						Expect(logger.TestSink.Buffer()).Should(gbytes.Say("mount-duration-too-high"))
@@ -189,19 +364,19 @@ var _ = Describe("Nfs Driver", func() {
 				})
 
 				Context("when the driver is drained while there are still mounts", func() {
-					var drainResponse error
+					var drainResponse volumedriver.DrainResponse
 					JustBeforeEach(func() {
 						drainResponse = volumeDriver.Drain(env)
 					})
 
 					It("unmounts the volume", func() {
-						Expect(drainResponse).NotTo(HaveOccurred())
+						Expect(drainResponse.Err).To(BeEmpty())
 						Expect(fakeMounter.UnmountCallCount()).NotTo(BeZero())
 						_, name := fakeMounter.UnmountArgsForCall(0)
 						Expect(strings.Replace(name, `\`, "/", -1)).To(Equal("/path/to/mount/" + volumeName))
 					})
 					It("purges the directory", func() {
-						Expect(drainResponse).NotTo(HaveOccurred())
+						Expect(drainResponse.Err).To(BeEmpty())
 						Expect(fakeMounter.PurgeCallCount()).NotTo(BeZero())
 						_, path := fakeMounter.PurgeArgsForCall(0)
 						Expect(path).To(Equal("/path/to/mount"))
@@ -209,6 +384,68 @@ var _ = Describe("Nfs Driver", func() {
 				})
 			})
 
+			Context("when the volume name is not a safe directory name", func() {
+				const unsafeName = "some/volume/with/a/very/long/name/that/goes/on/and/on/and/on/and/on"
+				var mountResponse dockerdriver.MountResponse
+
+				BeforeEach(func() {
+					setupVolume(env, volumeDriver, unsafeName, ip)
+					fakeFilepath.AbsReturns("/path/to/mount/", nil)
+					mountResponse = volumeDriver.Mount(env, dockerdriver.MountRequest{Name: unsafeName})
+				})
+
+				It("mounts the volume below a hashed directory", func() {
+					Expect(mountResponse.Err).To(BeEmpty())
+					Expect(strings.Replace(mountResponse.Mountpoint, `\`, "/", -1)).To(MatchRegexp(`^/path/to/mount/vol-[0-9a-f]{16}$`))
+				})
+
+				It("persists the directory with the volume", func() {
+					// the last write records the mount outcome
+					stateFile, data, _ := fakeIoutil.WriteFileArgsForCall(fakeIoutil.WriteFileCallCount() - 1)
+					Expect(stateFile).To(HaveSuffix(".json.tmp"))
+					Expect(string(data)).To(ContainSubstring(`"MountDirectory":"` + filepath.Base(mountResponse.Mountpoint) + `"`))
+				})
+
+				It("writes the record only readable by the driver and renames it into place", func() {
+					stateFile, _, mode := fakeIoutil.WriteFileArgsForCall(fakeIoutil.WriteFileCallCount() - 1)
+					Expect(mode).To(Equal(os.FileMode(0600)))
+
+					from, to := fakeOs.RenameArgsForCall(fakeOs.RenameCallCount() - 1)
+					Expect(from).To(Equal(stateFile))
+					Expect(to).To(Equal(strings.TrimSuffix(stateFile, ".tmp")))
+				})
+
+				It("keeps using the same directory", func() {
+					Expect(volumeDriver.Unmount(env, dockerdriver.UnmountRequest{Name: unsafeName}).Err).To(BeEmpty())
+					setupVolume(env, volumeDriver, unsafeName, ip)
+					Expect(volumeDriver.Mount(env, dockerdriver.MountRequest{Name: unsafeName}).Mountpoint).To(Equal(mountResponse.Mountpoint))
+				})
+			})
+
+			Context("when the volume has a check depth", func() {
+				BeforeEach(func() {
+					createResponse := volumeDriver.Create(env, dockerdriver.CreateRequest{
+						Name: volumeName,
+						Opts: map[string]interface{}{"source": ip, "check_depth": "read"},
+					})
+					Expect(createResponse.Err).To(BeEmpty())
+					setupMount(env, volumeDriver, volumeName, fakeFilepath)
+					fakeMounter.CheckReturns(true)
+					setupMount(env, volumeDriver, volumeName, fakeFilepath)
+				})
+
+				It("does not pass the check depth to the mounter", func() {
+					_, _, _, opts := fakeMounter.MountArgsForCall(0)
+					Expect(opts).To(Equal(hardened(map[string]interface{}{})))
+				})
+
+				It("checks the mount with that depth", func() {
+					Expect(fakeMounter.CheckCallCount()).To(Equal(1))
+					_, _, _, depth := fakeMounter.CheckArgsForCall(0)
+					Expect(depth).To(Equal(volumedriver.CheckRead))
+				})
+			})
+
 			Context("when the volume has not been created", func() {
 				It("returns an error", func() {
 					mountResponse := volumeDriver.Mount(env, dockerdriver.MountRequest{Name: "bla"})
@@ -250,234 +487,3907 @@ var _ = Describe("Nfs Driver", func() {
 			})
 		})
 
-		Describe("Unmount", func() {
-			Context("when a volume has been created", func() {
+		Describe("Metrics", func() {
+			var fakeEmitter *volumedriverfakes.FakeEmitter
+
+			BeforeEach(func() {
+				fakeEmitter = &volumedriverfakes.FakeEmitter{}
+				options := volumedriver.DefaultOptions()
+				options.MetricsEmitter = fakeEmitter
+				volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+				setupVolume(env, volumeDriver, volumeName, ip)
+			})
+
+			It("emits volume gauges", func() {
+				name, value, _ := fakeEmitter.GaugeArgsForCall(0)
+				Expect(name).To(Equal(metrics.VolumeCount))
+				Expect(value).To(Equal(float64(1)))
+			})
+
+			Context("when a statsd agent is configured instead", func() {
+				var listener net.PacketConn
+
 				BeforeEach(func() {
-					setupVolume(env, volumeDriver, volumeName, ip)
+					var err error
+					listener, err = net.ListenPacket("udp", "127.0.0.1:0")
+					Expect(err).NotTo(HaveOccurred())
+
+					options := volumedriver.DefaultOptions()
+					options.StatsdAddress = listener.LocalAddr().String()
+					options.StatsdPrefix = "volumedriver"
+					volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+					setupVolume(env, volumeDriver, "other-volume", ip)
 				})
 
-				Context("when a volume has been mounted", func() {
-					var unmountResponse dockerdriver.ErrorResponse
+				AfterEach(func() {
+					listener.Close()
+				})
+
+				It("sends the metrics to the agent", func() {
+					buffer := make([]byte, 1024)
+					Expect(listener.SetReadDeadline(time.Now().Add(5 * time.Second))).To(Succeed())
+					n, _, err := listener.ReadFrom(buffer)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(buffer[:n])).To(HavePrefix("volumedriver."))
+				})
+			})
+
+			Context("when a volume is mounted", func() {
+				BeforeEach(func() {
+					startTime := time.Now()
+					fakeTime.NowReturnsOnCall(1, startTime)
+					fakeTime.NowReturnsOnCall(2, startTime.Add(2*time.Second))
+					setupMount(env, volumeDriver, volumeName, fakeFilepath)
+				})
+
+				It("emits the mount duration tagged with source and outcome", func() {
+					Expect(fakeEmitter.TimingCallCount()).To(Equal(1))
+					name, duration, tags := fakeEmitter.TimingArgsForCall(0)
+					Expect(name).To(Equal(metrics.MountDuration))
+					Expect(duration).To(Equal(2 * time.Second))
+					Expect(tags).To(ConsistOf(metrics.SourceTag(ip), metrics.Tag{Name: "outcome", Value: metrics.OutcomeSuccess}))
+				})
+
+				It("counts unmounts", func() {
+					Expect(volumeDriver.Unmount(env, dockerdriver.UnmountRequest{Name: volumeName}).Err).To(BeEmpty())
+
+					var names []string
+					for i := 0; i < fakeEmitter.CountCallCount(); i++ {
+						name, _, _ := fakeEmitter.CountArgsForCall(i)
+						names = append(names, name)
+					}
+					Expect(names).To(Equal([]string{metrics.Mounts, metrics.Unmounts}))
+				})
+			})
+
+			Context("when a mount is slow", func() {
+				var startTime time.Time
+
+				BeforeEach(func() {
+					startTime = time.Now()
+					fakeTime.NowReturnsOnCall(1, startTime)
+				})
+
+				slowMounts := func() []metrics.Tag {
+					for i := 0; i < fakeEmitter.CountCallCount(); i++ {
+						name, _, tags := fakeEmitter.CountArgsForCall(i)
+						if name == metrics.SlowMounts {
+							return tags
+						}
+					}
+					return nil
+				}
+
+				It("does not report mounts below the threshold", func() {
+					fakeTime.NowReturnsOnCall(2, startTime.Add(8*time.Second))
+					setupMount(env, volumeDriver, volumeName, fakeFilepath)
+					Expect(slowMounts()).To(BeNil())
+				})
+
+				It("reports mounts above the slow threshold as warnings", func() {
+					fakeTime.NowReturnsOnCall(2, startTime.Add(9*time.Second))
+					setupMount(env, volumeDriver, volumeName, fakeFilepath)
+					Expect(slowMounts()).To(ConsistOf(metrics.SourceTag(ip), metrics.SeverityTag(metrics.SeverityWarning)))
+					Expect(logger.Buffer()).To(gbytes.Say("mount-duration-too-high"))
+				})
+
+				It("reports mounts above the critical threshold as critical", func() {
+					fakeTime.NowReturnsOnCall(2, startTime.Add(21*time.Second))
+					setupMount(env, volumeDriver, volumeName, fakeFilepath)
+					Expect(slowMounts()).To(ConsistOf(metrics.SourceTag(ip), metrics.SeverityTag(metrics.SeverityCritical)))
+					Expect(logger.Buffer()).To(gbytes.Say("mount-duration-critical"))
+				})
 
+				Context("when the thresholds are configured", func() {
 					BeforeEach(func() {
-						setupMount(env, volumeDriver, volumeName, fakeFilepath)
+						options := volumedriver.DefaultOptions()
+						options.MetricsEmitter = fakeEmitter
+						options.SlowMountThreshold = 2 * time.Second
+						options.CriticalMountThreshold = 0
+						volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+						setupVolume(env, volumeDriver, volumeName, ip)
 					})
 
-					JustBeforeEach(func() {
-						unmountResponse = volumeDriver.Unmount(env, dockerdriver.UnmountRequest{
-							Name: volumeName,
-						})
+					It("uses them", func() {
+						// both drivers stamped the creation of the volume
+						fakeTime.NowReturnsOnCall(2, startTime)
+						fakeTime.NowReturnsOnCall(3, startTime.Add(time.Minute))
+						setupMount(env, volumeDriver, volumeName, fakeFilepath)
+						Expect(slowMounts()).To(ConsistOf(metrics.SourceTag(ip), metrics.SeverityTag(metrics.SeverityWarning)))
 					})
+				})
+			})
+		})
 
-					It("doesn't return an error", func() {
-						Expect(unmountResponse.Err).To(Equal(""))
-					})
+		Describe("Rate limiting", func() {
+			var options volumedriver.Options
 
-					It("After unmounting /VolumeDriver.Get returns no volume", func() {
-						getResponse := volumeDriver.Get(env, dockerdriver.GetRequest{
-							Name: volumeName,
-						})
+			BeforeEach(func() {
+				options = volumedriver.DefaultOptions()
+			})
 
-						Expect(getResponse.Err).To(Equal("Volume not found"))
-					})
+			JustBeforeEach(func() {
+				volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+				setupVolume(env, volumeDriver, volumeName, ip)
+				setupVolume(env, volumeDriver, volumeName+"2", ip)
+				fakeFilepath.AbsReturns("/path/to/mount/", nil)
+			})
 
-					It("/VolumeDriver.Unmount unmounts", func() {
-						Expect(fakeMounter.UnmountCallCount()).To(Equal(1))
-						_, removed := fakeMounter.UnmountArgsForCall(0)
-						Expect(strings.Replace(removed, `\`, "/", -1)).To(Equal("/path/to/mount/" + volumeName))
-					})
+			Context("when the volume rate limit is exceeded", func() {
+				BeforeEach(func() {
+					options.VolumeRateLimit = admission.RateLimit{Rate: 1, Burst: 1}
+				})
 
-					It("writes the driver state to disk", func() {
-						// 1 - create
-						// 2 - mount
-						// 3 - unmount
-						Expect(fakeIoutil.WriteFileCallCount()).To(Equal(3))
-					})
+				It("rejects further requests for that volume only", func() {
+					Expect(volumeDriver.Mount(env, dockerdriver.MountRequest{Name: volumeName}).Err).To(BeEmpty())
 
-					Context("when it fails to write the driver state to disk", func() {
-						BeforeEach(func() {
-							fakeIoutil.WriteFileReturns(errors.New("badness"))
-						})
+					mountResponse := volumeDriver.Mount(env, dockerdriver.MountRequest{Name: volumeName})
+					Expect(mountResponse.Err).To(MatchJSON(`{"SafeDescription":"Rate limit exceeded for volume 'test-volume-id', try again later","Code":"busy","RetryAfter":1}`))
 
-						It("returns an error response", func() {
-							Expect(unmountResponse.Err).To(Equal("failed to persist state when unmounting: badness"))
-						})
-					})
+					unmountResponse := volumeDriver.Unmount(env, dockerdriver.UnmountRequest{Name: volumeName})
+					Expect(unmountResponse.Err).To(MatchJSON(`{"SafeDescription":"Rate limit exceeded for volume 'test-volume-id', try again later","Code":"busy","RetryAfter":1}`))
 
-					Context("when the volume is mounted twice", func() {
-						BeforeEach(func() {
-							setupMount(env, volumeDriver, volumeName, fakeFilepath)
-							// JustBefore each does an unmount
-						})
+					Expect(volumeDriver.Mount(env, dockerdriver.MountRequest{Name: volumeName + "2"}).Err).To(BeEmpty())
+					Expect(fakeMounter.MountCallCount()).To(Equal(2))
+				})
 
-						It("returns no error when unmounting", func() {
-							Expect(unmountResponse.Err).To(Equal(""))
-						})
+				It("keeps no bucket for volumes that do not exist", func() {
+					for i := 0; i < 2; i++ {
+						Expect(volumeDriver.Mount(env, dockerdriver.MountRequest{Name: "unknown"}).Err).To(Equal("Volume 'unknown' must be created before being mounted"))
+					}
 
-						It("the volume should remain mounted (due to reference counting)", func() {
-							getResponse := ExpectVolumeExists(env, volumeDriver, volumeName)
-							Expect(strings.Replace(getResponse.Volume.Mountpoint, `\`, "/", -1)).To(Equal("/path/to/mount/" + volumeName))
-						})
+					setupVolume(env, volumeDriver, "unknown", ip)
+					Expect(volumeDriver.Mount(env, dockerdriver.MountRequest{Name: "unknown"}).Err).To(BeEmpty())
+				})
+			})
 
-						Context("when unmounting again", func() {
-							BeforeEach(func() {
-								unmountResponse = volumeDriver.Unmount(env, dockerdriver.UnmountRequest{
-									Name: volumeName,
-								})
-							})
+			Context("when the global rate limit is exceeded", func() {
+				BeforeEach(func() {
+					options.GlobalRateLimit = admission.RateLimit{Rate: 1, Burst: 1}
+				})
 
-							It("returns no error when unmounting", func() {
-								Expect(unmountResponse.Err).To(Equal(""))
-							})
+				It("rejects requests for all volumes", func() {
+					Expect(volumeDriver.Mount(env, dockerdriver.MountRequest{Name: volumeName}).Err).To(BeEmpty())
 
-							It("deleted the volume", func() {
-								getResponse := volumeDriver.Get(env, dockerdriver.GetRequest{
-									Name: volumeName,
-								})
+					mountResponse := volumeDriver.Mount(env, dockerdriver.MountRequest{Name: volumeName + "2"})
+					Expect(mountResponse.Err).To(MatchJSON(`{"SafeDescription":"Rate limit exceeded, try again later","Code":"busy","RetryAfter":1}`))
+					Expect(fakeMounter.MountCallCount()).To(Equal(1))
+				})
+			})
+		})
 
-								Expect(getResponse.Err).To(Equal("Volume not found"))
-							})
-						})
-					})
+		Describe("ExportState", func() {
+			BeforeEach(func() {
+				setupVolume(env, volumeDriver, "b-volume", ip)
+				setupVolume(env, volumeDriver, "a-volume", ip)
+				setupMount(env, volumeDriver, "a-volume", fakeFilepath)
+			})
 
-					Context("when the mountpath is not found", func() {
-						BeforeEach(func() {
-							fakeMountChecker.ExistsReturns(false, nil)
-						})
+			It("returns all volumes sorted by name", func() {
+				exportResponse := volumeDriver.ExportState(env)
+				Expect(exportResponse.Err).To(BeEmpty())
+				Expect(exportResponse.Volumes).To(HaveLen(2))
+				Expect(exportResponse.Volumes[0].Name).To(Equal("a-volume"))
+				Expect(exportResponse.Volumes[0].MountCount).To(Equal(1))
+				Expect(exportResponse.Volumes[1].Name).To(Equal("b-volume"))
+			})
 
-						It("returns an error", func() {
-							Expect(strings.Replace(unmountResponse.Err, `\`, "/", -1)).To(Equal("Volume " + volumeName + " does not exist (path: /path/to/mount/" + volumeName + ")"))
-						})
+			It("exports the opts and mount directory of the volumes", func() {
+				exportResponse := volumeDriver.ExportState(env)
+				Expect(exportResponse.Volumes[0].Opts).To(HaveKeyWithValue("source", ip))
+				Expect(exportResponse.Volumes[0].MountDirectory).To(Equal("a-volume"))
+			})
 
-						It("/VolumeDriver.Get still returns the mountpoint", func() {
-							getResponse := ExpectVolumeExists(env, volumeDriver, volumeName)
-							Expect(getResponse.Volume.Mountpoint).NotTo(Equal(""))
-						})
+			Context("when a volume has secret opts", func() {
+				BeforeEach(func() {
+					createResponse := volumeDriver.Create(env, dockerdriver.CreateRequest{
+						Name: "a-volume",
+						Opts: map[string]interface{}{"source": ip, "password": "hunter2"},
 					})
+					Expect(createResponse.Err).To(BeEmpty())
+				})
 
-					Context("when the mountpath cannot be accessed", func() {
-						BeforeEach(func() {
-							fakeOs.StatReturns(nil, errors.New("something weird"))
-						})
+				It("leaves them out", func() {
+					exportResponse := volumeDriver.ExportState(env)
+					Expect(exportResponse.Volumes[0].Opts).NotTo(HaveKey("password"))
+					Expect(exportResponse.Volumes[0].DroppedOpts).To(Equal([]string{"password"}))
+				})
+			})
+		})
 
-						It("unmounts anyway", func() {
-							Expect(unmountResponse.Err).To(Equal(""))
-						})
+		Describe("ListVolumes", func() {
+			var listRequest volumedriver.ListVolumesRequest
 
-						It("deleted the volume", func() {
-							getResponse := volumeDriver.Get(env, dockerdriver.GetRequest{
-								Name: volumeName,
-							})
+			names := func(listResponse volumedriver.ListVolumesResponse) []string {
+				result := []string{}
+				for _, volume := range listResponse.Volumes {
+					result = append(result, volume.Name)
+				}
+				return result
+			}
+
+			BeforeEach(func() {
+				for name, labels := range map[string]interface{}{
+					"app-a":   "team=red,tier=db",
+					"app-b":   map[string]interface{}{"team": "blue"},
+					"app-c":   "team=red",
+					"scratch": nil,
+				} {
+					opts := map[string]interface{}{"source": ip}
+					if labels != nil {
+						opts["labels"] = labels
+					}
+					createResponse := volumeDriver.Create(env, dockerdriver.CreateRequest{Name: name, Opts: opts})
+					Expect(createResponse.Err).To(BeEmpty())
+				}
+				fakeFilepath.AbsReturns("/path/to/mount/", nil)
+				Expect(volumeDriver.Mount(env, dockerdriver.MountRequest{Name: "app-c"}).Err).To(BeEmpty())
+
+				listRequest = volumedriver.ListVolumesRequest{}
+			})
+
+			It("lists all volumes in name order", func() {
+				Expect(names(volumeDriver.ListVolumes(env, listRequest))).To(Equal([]string{"app-a", "app-b", "app-c", "scratch"}))
+			})
+
+			It("filters by name prefix", func() {
+				listRequest.NamePrefix = "app-"
+				Expect(names(volumeDriver.ListVolumes(env, listRequest))).To(Equal([]string{"app-a", "app-b", "app-c"}))
+			})
+
+			It("filters by label selector", func() {
+				listRequest.Selector = "team=red"
+				Expect(names(volumeDriver.ListVolumes(env, listRequest))).To(Equal([]string{"app-a", "app-c"}))
+
+				listRequest.Selector = "team!=red,team"
+				Expect(names(volumeDriver.ListVolumes(env, listRequest))).To(Equal([]string{"app-b"}))
+
+				listRequest.Selector = "tier"
+				Expect(names(volumeDriver.ListVolumes(env, listRequest))).To(Equal([]string{"app-a"}))
+			})
+
+			It("filters mounted volumes", func() {
+				listRequest.MountedOnly = true
+				Expect(names(volumeDriver.ListVolumes(env, listRequest))).To(Equal([]string{"app-c"}))
+			})
+
+			It("pages through the volumes", func() {
+				listRequest.Limit = 3
+				page := volumeDriver.ListVolumes(env, listRequest)
+				Expect(names(page)).To(Equal([]string{"app-a", "app-b", "app-c"}))
+				Expect(page.NextToken).NotTo(BeEmpty())
+
+				listRequest.Token = page.NextToken
+				page = volumeDriver.ListVolumes(env, listRequest)
+				Expect(names(page)).To(Equal([]string{"scratch"}))
+				Expect(page.NextToken).To(BeEmpty())
+			})
+
+			It("persists the labels", func() {
+				Expect(fakeIoutil.WriteFileCallCount()).To(BeNumerically(">", 0))
+				found := false
+				for i := 0; i < fakeIoutil.WriteFileCallCount(); i++ {
+					_, data, _ := fakeIoutil.WriteFileArgsForCall(i)
+					if strings.Contains(string(data), `"Labels":{"team":"blue"}`) {
+						found = true
+					}
+				}
+				Expect(found).To(BeTrue())
+			})
+
+			Context("when the token is not valid", func() {
+				It("returns an error", func() {
+					listRequest.Token = "!!"
+					Expect(volumeDriver.ListVolumes(env, listRequest).Err).To(Equal("invalid token '!!'"))
+				})
+			})
+
+			Context("when the labels opt is not valid", func() {
+				It("rejects the volume", func() {
+					createResponse := volumeDriver.Create(env, dockerdriver.CreateRequest{
+						Name: "bad-labels",
+						Opts: map[string]interface{}{"source": ip, "labels": "team"},
+					})
+					Expect(createResponse.Err).To(Equal("invalid labels 'team', must be of the form key=value,..."))
+				})
+			})
+		})
+
+		Describe("DebugState", func() {
+			BeforeEach(func() {
+				setupVolume(env, volumeDriver, "b-volume", ip)
+				setupVolume(env, volumeDriver, "a-volume", ip)
+			})
+
+			It("dumps the volumes sorted by name", func() {
+				debugResponse := volumeDriver.DebugState(env)
+				Expect(debugResponse.Err).To(BeEmpty())
+				Expect(debugResponse.Volumes).To(HaveLen(2))
+				Expect(debugResponse.Volumes[0].Name).To(Equal("a-volume"))
+				Expect(debugResponse.Lock.Acquisition).To(BeNumerically(">=", 2))
+				Expect(debugResponse.Goroutines).To(BeNumerically(">", 0))
+			})
+
+			Context("when a mount is in progress", func() {
+				var release chan struct{}
+				var mounted chan struct{}
+
+				BeforeEach(func() {
+					release = make(chan struct{})
+					mounted = make(chan struct{})
+					fakeMounter.MountStub = func(dockerdriver.Env, string, string, map[string]interface{}) error {
+						<-release
+						return nil
+					}
+					fakeFilepath.AbsReturns("/path/to/mount/", nil)
+
+					go func() {
+						defer GinkgoRecover()
+						volumeDriver.Mount(env, dockerdriver.MountRequest{Name: "a-volume"})
+						close(mounted)
+					}()
+					Eventually(fakeMounter.MountCallCount).Should(Equal(1))
+				})
+
+				AfterEach(func() {
+					close(release)
+					Eventually(mounted).Should(BeClosed())
+				})
+
+				It("reports the in-flight operation", func() {
+					debugResponse := volumeDriver.DebugState(env)
+					Expect(debugResponse.InFlight).To(HaveLen(1))
+					Expect(debugResponse.InFlight[0].Operation).To(Equal("mount"))
+					Expect(debugResponse.InFlight[0].Volume).To(Equal("a-volume"))
+				})
+			})
+
+			Context("when the volume lock is held", func() {
+				var release chan struct{}
+				var mounted chan struct{}
+
+				BeforeEach(func() {
+					fakeFilepath.AbsReturns("/path/to/mount/", nil)
+					Expect(volumeDriver.Mount(env, dockerdriver.MountRequest{Name: "a-volume"}).Err).To(BeEmpty())
+
+					release = make(chan struct{})
+					mounted = make(chan struct{})
+					checking := make(chan struct{})
+					fakeMounter.CheckStub = func(dockerdriver.Env, string, string, volumedriver.CheckDepth) bool {
+						close(checking)
+						<-release
+						return true
+					}
+
+					go func() {
+						defer GinkgoRecover()
+						volumeDriver.Mount(env, dockerdriver.MountRequest{Name: "a-volume"})
+						close(mounted)
+					}()
+					Eventually(checking).Should(BeClosed())
+				})
+
+				AfterEach(func() {
+					close(release)
+					Eventually(mounted).Should(BeClosed())
+				})
+
+				It("still reports the lock and the in-flight operations", func() {
+					debugResponse := volumeDriver.DebugState(env)
+					Expect(debugResponse.Err).To(Equal("timed out waiting for the volume lock"))
+					Expect(debugResponse.Lock.Held).To(BeTrue())
+					Expect(debugResponse.InFlight).To(HaveLen(1))
+				})
+			})
+		})
+
+		Describe("InFlightOperations", func() {
+			var release chan struct{}
+			var mounted chan struct{}
+
+			stateOf := func(caller string) func() volumedriver.OperationState {
+				return func() volumedriver.OperationState {
+					for _, op := range volumeDriver.InFlightOperations(env).Operations {
+						if op.Caller == caller {
+							return op.State
+						}
+					}
+					return ""
+				}
+			}
+
+			BeforeEach(func() {
+				setupVolume(env, volumeDriver, "a-volume", ip)
+				Expect(volumeDriver.InFlightOperations(env).Operations).To(BeEmpty())
+
+				release = make(chan struct{})
+				mounted = make(chan struct{}, 2)
+				fakeMounter.MountStub = func(dockerdriver.Env, string, string, map[string]interface{}) error {
+					<-release
+					return nil
+				}
+				fakeFilepath.AbsReturns("/path/to/mount/", nil)
+
+				for _, caller := range []string{"first-app", "second-app"} {
+					callerEnv := driverhttp.NewHttpDriverEnv(logger, volumedriver.WithCaller(context.TODO(), caller))
+					go func() {
+						defer GinkgoRecover()
+						volumeDriver.Mount(callerEnv, dockerdriver.MountRequest{Name: "a-volume"})
+						mounted <- struct{}{}
+					}()
+					Eventually(fakeMounter.MountCallCount).Should(Equal(1))
+				}
+			})
+
+			It("lists the running mount and the mount waiting for it until they complete", func() {
+				Eventually(stateOf("second-app")).Should(Equal(volumedriver.OperationQueued))
+				Expect(stateOf("first-app")()).To(Equal(volumedriver.OperationRunning))
+
+				operations := volumeDriver.InFlightOperations(env).Operations
+				Expect(operations).To(HaveLen(2))
+				Expect(operations[0].Operation).To(Equal("mount"))
+				Expect(operations[0].Volume).To(Equal("a-volume"))
+				Expect(operations[0].Caller).To(Equal("first-app"))
+
+				close(release)
+				Eventually(mounted).Should(HaveLen(2))
+				Expect(volumeDriver.InFlightOperations(env).Operations).To(BeEmpty())
+			})
+		})
+
+		Describe("ImportState", func() {
+			var (
+				importRequest  volumedriver.ImportStateRequest
+				importResponse dockerdriver.ErrorResponse
+			)
+
+			BeforeEach(func() {
+				setupVolume(env, volumeDriver, "existing-volume", ip)
+				importRequest = volumedriver.ImportStateRequest{
+					Volumes: []volumedriver.ExportedVolume{
+						{
+							VolumeInfo: dockerdriver.VolumeInfo{Name: "existing-volume", Mountpoint: "/imported/path", MountCount: 3},
+							Opts:       map[string]interface{}{"source": "other-server:/export"},
+						},
+						{
+							VolumeInfo:     dockerdriver.VolumeInfo{Name: "new-volume", Mountpoint: "/new/path", MountCount: 1},
+							Opts:           map[string]interface{}{"source": ip},
+							MountDirectory: "exported-directory",
+						},
+					},
+				}
+			})
+
+			JustBeforeEach(func() {
+				importResponse = volumeDriver.ImportState(env, importRequest)
+			})
+
+			It("adds new volumes and keeps existing ones", func() {
+				Expect(importResponse.Err).To(BeEmpty())
+
+				Expect(volumeDriver.List(env).Volumes).To(ConsistOf(
+					dockerdriver.VolumeInfo{Name: "existing-volume"},
+					dockerdriver.VolumeInfo{Name: "new-volume"},
+				))
+			})
+
+			It("mounts the imported volumes with their opts and mount directory", func() {
+				fakeFilepath.AbsReturns("/path/to/mount/", nil)
+				mountResponse := volumeDriver.Mount(env, dockerdriver.MountRequest{Name: "new-volume"})
+				Expect(mountResponse.Err).To(BeEmpty())
+				Expect(mountResponse.Mountpoint).To(Equal("/path/to/mount/exported-directory"))
+
+				Expect(fakeMounter.MountCallCount()).To(Equal(1))
+				_, source, _, _ := fakeMounter.MountArgsForCall(0)
+				Expect(source).To(Equal(ip))
+				Expect(volumeDriver.List(env).Volumes).To(ContainElement(
+					dockerdriver.VolumeInfo{Name: "new-volume", Mountpoint: "/path/to/mount/exported-directory", MountCount: 1},
+				))
+			})
+
+			It("persists only the imported volumes", func() {
+				// 1 - create
+				// 2 - import
+				Expect(fakeIoutil.WriteFileCallCount()).To(Equal(2))
+				stateFile, _, _ := fakeIoutil.WriteFileArgsForCall(1)
+				Expect(stateFile).To(HaveSuffix("new-volume.json.tmp"))
+			})
+
+			Context("when overwriting", func() {
+				BeforeEach(func() {
+					importRequest.Overwrite = true
+				})
+
+				It("replaces the opts of existing volumes but not their mounts", func() {
+					Expect(importResponse.Err).To(BeEmpty())
+					Expect(volumeDriver.List(env).Volumes).To(ContainElement(dockerdriver.VolumeInfo{Name: "existing-volume"}))
+
+					fakeFilepath.AbsReturns("/path/to/mount/", nil)
+					Expect(volumeDriver.Mount(env, dockerdriver.MountRequest{Name: "existing-volume"}).Err).To(BeEmpty())
+					_, source, _, _ := fakeMounter.MountArgsForCall(0)
+					Expect(source).To(Equal("other-server:/export"))
+				})
+			})
+
+			Context("when a volume was exported without its secret opts", func() {
+				BeforeEach(func() {
+					importRequest.Volumes[1].Opts = map[string]interface{}{"source": ip}
+					importRequest.Volumes[1].DroppedOpts = []string{"password"}
+				})
+
+				It("imports it without opts until it is created again", func() {
+					Expect(importResponse.Err).To(BeEmpty())
+
+					mountResponse := volumeDriver.Mount(env, dockerdriver.MountRequest{Name: "new-volume"})
+					Expect(mountResponse.Err).To(ContainSubstring("must be created again"))
+					Expect(fakeMounter.MountCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when the opts of a volume are invalid", func() {
+				BeforeEach(func() {
+					importRequest.Volumes[1].Opts = map[string]interface{}{"source": ip, "ttl": "forever"}
+				})
+
+				It("refuses the volume as Create would", func() {
+					Expect(importResponse.Err).To(HavePrefix("failed to import volume 'new-volume': "))
+					Expect(volumeDriver.List(env).Volumes).To(HaveLen(1))
+				})
+			})
+
+			Context("when the volume quota is reached", func() {
+				BeforeEach(func() {
+					Expect(volumeDriver.UpdatePolicy(env, volumedriver.Policy{Quotas: volumedriver.Quotas{MaxVolumes: 1}})).To(Succeed())
+				})
+
+				It("refuses the new volumes", func() {
+					Expect(importResponse.Err).To(ContainSubstring("failed to import volume 'new-volume'"))
+					Expect(volumeDriver.List(env).Volumes).To(HaveLen(1))
+				})
+			})
+
+			Context("when a volume has no name", func() {
+				BeforeEach(func() {
+					importRequest.Volumes = append(importRequest.Volumes, volumedriver.ExportedVolume{})
+				})
+
+				It("rejects the whole import", func() {
+					Expect(importResponse.Err).To(Equal("Missing mandatory 'volume_name'"))
+					Expect(volumeDriver.List(env).Volumes).To(HaveLen(1))
+				})
+			})
+
+			Context("when the state cannot be written", func() {
+				BeforeEach(func() {
+					fakeIoutil.WriteFileReturns(errors.New("badness"))
+				})
+
+				It("returns an error", func() {
+					Expect(importResponse.Err).To(Equal("failed to import volume 'new-volume': persist state failed when creating: badness"))
+				})
+			})
+		})
+
+		Describe("ResetMountError", func() {
+			It("fails if no volume name provided", func() {
+				resetResponse := volumeDriver.ResetMountError(env, volumedriver.ResetMountErrorRequest{})
+				Expect(resetResponse.Err).To(Equal("Missing mandatory 'volume_name'"))
+			})
+
+			Context("when the volume has not been created", func() {
+				It("returns an error", func() {
+					resetResponse := volumeDriver.ResetMountError(env, volumedriver.ResetMountErrorRequest{Name: volumeName})
+					Expect(resetResponse.Err).To(Equal(fmt.Sprintf("Volume '%s' not found", volumeName)))
+				})
+			})
+		})
+
+		Describe("Unmount", func() {
+			Context("when a volume has been created", func() {
+				BeforeEach(func() {
+					setupVolume(env, volumeDriver, volumeName, ip)
+				})
+
+				Context("when a volume has been mounted", func() {
+					var unmountResponse dockerdriver.ErrorResponse
+
+					BeforeEach(func() {
+						setupMount(env, volumeDriver, volumeName, fakeFilepath)
+					})
+
+					JustBeforeEach(func() {
+						unmountResponse = volumeDriver.Unmount(env, dockerdriver.UnmountRequest{
+							Name: volumeName,
+						})
+					})
+
+					It("doesn't return an error", func() {
+						Expect(unmountResponse.Err).To(Equal(""))
+					})
+
+					It("After unmounting /VolumeDriver.Get returns no volume", func() {
+						getResponse := volumeDriver.Get(env, dockerdriver.GetRequest{
+							Name: volumeName,
+						})
+
+						Expect(getResponse.Err).To(Equal("Volume not found"))
+					})
+
+					It("/VolumeDriver.Unmount unmounts", func() {
+						Expect(fakeMounter.UnmountCallCount()).To(Equal(1))
+						_, removed := fakeMounter.UnmountArgsForCall(0)
+						Expect(strings.Replace(removed, `\`, "/", -1)).To(Equal("/path/to/mount/" + volumeName))
+					})
+
+					It("removes the volume state from disk", func() {
+						// 1 - create
+						// 2, 3, 4 - mount, mount intent and mount outcome
+						// 5 - unmount intent
+						Expect(fakeIoutil.WriteFileCallCount()).To(Equal(5))
+						Expect(fakeOs.RemoveCallCount()).To(Equal(4))
+						Expect(fakeOs.RemoveArgsForCall(3)).To(HaveSuffix("driver-state.d/" + volumeName + ".json"))
+					})
+
+					Context("when the path is still mounted after unmounting", func() {
+						var fakeMetrics *volumedriverfakes.FakeEmitter
+
+						BeforeEach(func() {
+							fakeMetrics = &volumedriverfakes.FakeEmitter{}
+							options := volumedriver.DefaultOptions()
+							options.MetricsEmitter = fakeMetrics
+							volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+							setupVolume(env, volumeDriver, volumeName, ip)
+							setupMount(env, volumeDriver, volumeName, fakeFilepath)
+
+							// the unmount helper exits cleanly without unmounting
+							fakeMounter.UnmountReturns(nil)
+						})
+
+						It("refuses to remove the mountpoint", func() {
+							Expect(unmountResponse.Err).To(ContainSubstring("is still mounted"))
+							for i := 0; i < fakeOs.RemoveCallCount(); i++ {
+								Expect(fakeOs.RemoveArgsForCall(i)).NotTo(HaveSuffix(volumeName))
+							}
+						})
+
+						It("keeps the volume", func() {
+							Expect(volumeDriver.Get(env, dockerdriver.GetRequest{Name: volumeName}).Err).To(BeEmpty())
+						})
+
+						It("counts the refusal", func() {
+							counted := []string{}
+							for i := 0; i < fakeMetrics.CountCallCount(); i++ {
+								name, _, _ := fakeMetrics.CountArgsForCall(i)
+								counted = append(counted, name)
+							}
+							Expect(counted).To(ContainElement(metrics.StillMounted))
+						})
+					})
+
+					Context("when it fails to remove the volume state from disk", func() {
+						BeforeEach(func() {
+							fakeOs.RemoveStub = func(path string) error {
+								if strings.HasSuffix(path, ".json") {
+									return errors.New("badness")
+								}
+								return nil
+							}
+						})
+
+						It("returns an error response", func() {
+							Expect(unmountResponse.Err).To(Equal("failed to persist state when unmounting: badness"))
+						})
+					})
+
+					Context("when the volume is mounted twice", func() {
+						BeforeEach(func() {
+							setupMount(env, volumeDriver, volumeName, fakeFilepath)
+							// JustBefore each does an unmount
+						})
+
+						It("returns no error when unmounting", func() {
+							Expect(unmountResponse.Err).To(Equal(""))
+						})
+
+						It("only rewrites the record of the volume", func() {
+							// 1 - create
+							// 2, 3, 4 - first mount, mount intent and mount outcome
+							// 5, 6, 7 - second mount, remount intent and remount outcome
+							// 8 - unmount
+							Expect(fakeIoutil.WriteFileCallCount()).To(Equal(8))
+							stateFile, _, _ := fakeIoutil.WriteFileArgsForCall(7)
+							Expect(stateFile).To(HaveSuffix("driver-state.d/" + volumeName + ".json.tmp"))
+						})
+
+						It("the volume should remain mounted (due to reference counting)", func() {
+							getResponse := ExpectVolumeExists(env, volumeDriver, volumeName)
+							Expect(strings.Replace(getResponse.Volume.Mountpoint, `\`, "/", -1)).To(Equal("/path/to/mount/" + volumeName))
+						})
+
+						Context("when unmounting again", func() {
+							BeforeEach(func() {
+								unmountResponse = volumeDriver.Unmount(env, dockerdriver.UnmountRequest{
+									Name: volumeName,
+								})
+							})
+
+							It("returns no error when unmounting", func() {
+								Expect(unmountResponse.Err).To(Equal(""))
+							})
+
+							It("deleted the volume", func() {
+								getResponse := volumeDriver.Get(env, dockerdriver.GetRequest{
+									Name: volumeName,
+								})
+
+								Expect(getResponse.Err).To(Equal("Volume not found"))
+							})
+						})
+					})
+
+					Context("when the mountpath is not found", func() {
+						BeforeEach(func() {
+							fakeMountChecker.ExistsReturns(false, nil)
+						})
+
+						It("returns an error", func() {
+							Expect(strings.Replace(unmountResponse.Err, `\`, "/", -1)).To(Equal("Volume " + volumeName + " does not exist (path: /path/to/mount/" + volumeName + ")"))
+						})
+
+						It("/VolumeDriver.Get still returns the mountpoint", func() {
+							getResponse := ExpectVolumeExists(env, volumeDriver, volumeName)
+							Expect(getResponse.Volume.Mountpoint).NotTo(Equal(""))
+						})
+					})
+
+					Context("when the mountpath cannot be accessed", func() {
+						BeforeEach(func() {
+							fakeOs.StatReturns(nil, errors.New("something weird"))
+						})
+
+						It("unmounts anyway", func() {
+							Expect(unmountResponse.Err).To(Equal(""))
+						})
+
+						It("deleted the volume", func() {
+							getResponse := volumeDriver.Get(env, dockerdriver.GetRequest{
+								Name: volumeName,
+							})
+
+							Expect(getResponse.Err).To(Equal("Volume not found"))
+						})
+					})
+
+					Context("when the volume ref count is 1 but the mount does not exist", func() {
+						BeforeEach(func() {
+							fakeMountChecker.ExistsReturns(false, nil)
+						})
+
+						It("deletes the mount directory", func() {
+							Expect(unmountResponse.Err).ToNot(BeEmpty())
+							// the first removal clears the mount intent
+							Expect(fakeOs.RemoveCallCount()).To(Equal(2))
+							expectedPathToRemove := fakeOs.RemoveArgsForCall(1)
+
+							Expect(expectedPathToRemove).To(Equal("/path/to/mount/" + volumeName))
+						})
+
+						Context("when unable to remove the mount directory", func() {
+							BeforeEach(func() {
+								fakeOs.RemoveReturns(errors.New("Unable to remove"))
+							})
+
+							It("returns an error", func() {
+								Expect(unmountResponse.Err).To(ContainSubstring("Volume test-volume-id does not exist (path: /path/to/mount/test-volume-id) and unable to remove mount directory"))
+							})
+						})
+					})
+				})
+
+				Context("when the volume has not been mounted", func() {
+					It("returns an error", func() {
+						unmountResponse := volumeDriver.Unmount(env, dockerdriver.UnmountRequest{
+							Name: volumeName,
+						})
+
+						Expect(unmountResponse.Err).To(Equal("Volume not previously mounted"))
+					})
+				})
+			})
+
+			Context("when the volume has not been created", func() {
+				It("returns an error", func() {
+					unmountResponse := volumeDriver.Unmount(env, dockerdriver.UnmountRequest{
+						Name: volumeName,
+					})
+
+					Expect(unmountResponse.Err).To(Equal(fmt.Sprintf("Volume '%s' not found", volumeName)))
+				})
+			})
+		})
+
+		Describe("Create", func() {
+			Context("when create is called with a volume ID", func() {
+
+				var createResponse dockerdriver.ErrorResponse
+
+				JustBeforeEach(func() {
+					opts := map[string]interface{}{"source": ip}
+					createResponse = volumeDriver.Create(env, dockerdriver.CreateRequest{
+						Name: volumeName,
+						Opts: opts,
+					})
+				})
+
+				It("should write state, but omit Opts for security", func() {
+					Expect(fakeIoutil.WriteFileCallCount()).To(Equal(1))
+
+					_, data, _ := fakeIoutil.WriteFileArgsForCall(0)
+					Expect(data).To(ContainSubstring("\"Name\":\"" + volumeName + "\""))
+					Expect(data).NotTo(ContainSubstring("\"Opts\""))
+				})
+
+				Context("when the file system cant be written to", func() {
+					BeforeEach(func() {
+						fakeIoutil.WriteFileReturns(errors.New("badness"))
+					})
+
+					It("returns an error in the response", func() {
+						Expect(createResponse.Err).To(Equal("persist state failed when creating: badness"))
+					})
+				})
+			})
+
+			Context("when create is called with an invalid check depth", func() {
+				It("returns an error", func() {
+					createResponse := volumeDriver.Create(env, dockerdriver.CreateRequest{
+						Name: volumeName,
+						Opts: map[string]interface{}{"source": ip, "check_depth": "deep"},
+					})
+					Expect(createResponse.Err).To(Equal("invalid check depth 'deep', must be one of stat, read or write"))
+					ExpectVolumeDoesNotExist(env, volumeDriver, volumeName)
+				})
+			})
+
+			Context("when create is called with an invalid ttl", func() {
+				It("returns an error", func() {
+					createResponse := volumeDriver.Create(env, dockerdriver.CreateRequest{
+						Name: volumeName,
+						Opts: map[string]interface{}{"source": ip, "ttl": "-1h"},
+					})
+					Expect(createResponse.Err).To(Equal("invalid ttl '-1h', must be a positive duration such as 24h"))
+					ExpectVolumeDoesNotExist(env, volumeDriver, volumeName)
+				})
+			})
+
+			Context("when create is called with a ttl", func() {
+				BeforeEach(func() {
+					fakeTime.NowReturns(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+					createResponse := volumeDriver.Create(env, dockerdriver.CreateRequest{
+						Name: volumeName,
+						Opts: map[string]interface{}{"source": ip, "ttl": "1h"},
+					})
+					Expect(createResponse.Err).To(BeEmpty())
+				})
+
+				It("persists the expiry time", func() {
+					_, data, _ := fakeIoutil.WriteFileArgsForCall(0)
+					Expect(string(data)).To(ContainSubstring(`"ExpiresAt":"2020-01-01T01:00:00Z"`))
+				})
+
+				It("does not pass the ttl to the mounter", func() {
+					setupMount(env, volumeDriver, volumeName, fakeFilepath)
+					_, _, _, opts := fakeMounter.MountArgsForCall(0)
+					Expect(opts).NotTo(HaveKey("ttl"))
+				})
+			})
+
+			Context("when create is called with common opts", func() {
+				It("passes them to the mounter in their canonical form", func() {
+					createResponse := volumeDriver.Create(env, dockerdriver.CreateRequest{
+						Name: volumeName,
+						Opts: map[string]interface{}{"source": ip, "readonly": "", "uid": 1000.0, "gid": "1001", "auto_cache": "false"},
+					})
+					Expect(createResponse.Err).To(BeEmpty())
+
+					setupMount(env, volumeDriver, volumeName, fakeFilepath)
+					_, _, _, opts := fakeMounter.MountArgsForCall(0)
+					Expect(opts).To(HaveKeyWithValue("readonly", true))
+					Expect(opts).To(HaveKeyWithValue("uid", "1000"))
+					Expect(opts).To(HaveKeyWithValue("gid", "1001"))
+					Expect(opts).To(HaveKeyWithValue("auto_cache", false))
+				})
+
+				It("returns an error naming the opt with an invalid value", func() {
+					for _, invalid := range []struct {
+						opts map[string]interface{}
+						err  string
+					}{
+						{map[string]interface{}{"readonly": "yes"}, "invalid readonly 'yes', must be true or false"},
+						{map[string]interface{}{"auto_cache": 1.0}, "invalid auto_cache '1', must be true or false"},
+						{map[string]interface{}{"uid": "root"}, "invalid uid 'root', must be a number from 0 to 4294967294"},
+						{map[string]interface{}{"gid": "4294967295"}, "invalid gid '4294967295', must be a number from 0 to 4294967294"},
+						{map[string]interface{}{"ro": true, "readonly": false}, "the readonly and ro opts contradict each other"},
+					} {
+						invalid.opts["source"] = ip
+						createResponse := volumeDriver.Create(env, dockerdriver.CreateRequest{Name: volumeName, Opts: invalid.opts})
+						Expect(createResponse.Err).To(Equal(invalid.err))
+						ExpectVolumeDoesNotExist(env, volumeDriver, volumeName)
+					}
+				})
+			})
+
+			Context("when source defaults are configured", func() {
+				BeforeEach(func() {
+					options := volumedriver.DefaultOptions()
+					options.SourceDefaults = []volumedriver.SourceDefaults{
+						{Pattern: "*.example.com", Opts: map[string]interface{}{"vers": "4.1", "timeo": "600", "nosuid": true}},
+						{Pattern: "nfs.example.com", Opts: map[string]interface{}{"timeo": "300"}},
+						{Pattern: "10.*", Opts: map[string]interface{}{"vers": "3"}},
+					}
+					volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+				})
+
+				mountOpts := func(source string, opts map[string]interface{}) map[string]interface{} {
+					opts["source"] = source
+					createResponse := volumeDriver.Create(env, dockerdriver.CreateRequest{Name: volumeName, Opts: opts})
+					Expect(createResponse.Err).To(BeEmpty())
+					setupMount(env, volumeDriver, volumeName, fakeFilepath)
+					_, _, _, mounted := fakeMounter.MountArgsForCall(0)
+					return mounted
+				}
+
+				It("merges the defaults of matching sources under the opts", func() {
+					Expect(mountOpts("nfs.example.com:/export", map[string]interface{}{"vers": "4.2"})).To(Equal(hardened(map[string]interface{}{
+						"vers":  "4.2",
+						"timeo": "300",
+					})))
+				})
+
+				It("matches hosts in other source formats", func() {
+					Expect(mountOpts("nfs://10.0.0.1/export", map[string]interface{}{})).To(HaveKeyWithValue("vers", "3"))
+				})
+
+				It("leaves other sources alone", func() {
+					Expect(mountOpts("other.host:/export", map[string]interface{}{})).To(Equal(hardened(map[string]interface{}{})))
+				})
+			})
+
+			Context("when mounters for other filesystems are registered", func() {
+				var fakeSmbMounter *volumedriverfakes.FakeMounter
+
+				BeforeEach(func() {
+					fakeSmbMounter = &volumedriverfakes.FakeMounter{}
+					fakeSmbMounter.UnmountStub = markUnmounted
+					fakeSmbMounter.CheckReturns(true)
+					options := volumedriver.DefaultOptions()
+					options.Mounters = map[string]volumedriver.Mounter{"smb": fakeSmbMounter}
+					volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+				})
+
+				create := func(opts map[string]interface{}) dockerdriver.ErrorResponse {
+					return volumeDriver.Create(env, dockerdriver.CreateRequest{Name: volumeName, Opts: opts})
+				}
+
+				It("mounts and unmounts volumes with the mounter named by the driver opt", func() {
+					Expect(create(map[string]interface{}{"source": "//server/share", "driver": "smb"}).Err).To(BeEmpty())
+					setupMount(env, volumeDriver, volumeName, fakeFilepath)
+
+					Expect(fakeMounter.MountCallCount()).To(BeZero())
+					Expect(fakeSmbMounter.MountCallCount()).To(Equal(1))
+					_, source, _, opts := fakeSmbMounter.MountArgsForCall(0)
+					Expect(source).To(Equal("//server/share"))
+					Expect(opts).NotTo(HaveKey("driver"))
+
+					unmountResponse := volumeDriver.Unmount(env, dockerdriver.UnmountRequest{Name: volumeName})
+					Expect(unmountResponse.Err).To(BeEmpty())
+					Expect(fakeSmbMounter.UnmountCallCount()).To(Equal(1))
+					Expect(fakeMounter.UnmountCallCount()).To(BeZero())
+				})
+
+				It("selects the mounter by the scheme of the source", func() {
+					Expect(create(map[string]interface{}{"source": "smb://server/share"}).Err).To(BeEmpty())
+					setupMount(env, volumeDriver, volumeName, fakeFilepath)
+					Expect(fakeSmbMounter.MountCallCount()).To(Equal(1))
+				})
+
+				It("persists the driver of the volume", func() {
+					Expect(create(map[string]interface{}{"source": "//server/share", "driver": "smb"}).Err).To(BeEmpty())
+					_, data, _ := fakeIoutil.WriteFileArgsForCall(fakeIoutil.WriteFileCallCount() - 1)
+					Expect(string(data)).To(ContainSubstring(`"Driver":"smb"`))
+				})
+
+				It("uses the default mounter for other volumes", func() {
+					Expect(create(map[string]interface{}{"source": "server:/export"}).Err).To(BeEmpty())
+					setupMount(env, volumeDriver, volumeName, fakeFilepath)
+					Expect(fakeMounter.MountCallCount()).To(Equal(1))
+					Expect(fakeSmbMounter.MountCallCount()).To(BeZero())
+				})
+
+				It("rejects unknown drivers", func() {
+					Expect(create(map[string]interface{}{"source": "server:/export", "driver": "glusterfs"}).Err).To(Equal("unknown driver 'glusterfs', must be one of [smb]"))
+					ExpectVolumeDoesNotExist(env, volumeDriver, volumeName)
+				})
+
+				It("purges every mounter on drain", func() {
+					Expect(volumeDriver.Drain(env).Err).To(BeEmpty())
+					Expect(fakeMounter.PurgeCallCount()).To(Equal(1))
+					Expect(fakeSmbMounter.PurgeCallCount()).To(Equal(1))
+				})
+			})
+
+			Context("when volumes are created with the subdir opt", func() {
+				var (
+					fakeBindMounter *volumedriverfakes.FakeMounter
+					exportMounted   bool
+				)
+
+				createSubdirVolume := func(name, subdir string) dockerdriver.ErrorResponse {
+					return volumeDriver.Create(env, dockerdriver.CreateRequest{
+						Name: name,
+						Opts: map[string]interface{}{"source": "server:/export", "vers": "4.1", "subdir": subdir, "subdir_mode": "0750", "subdir_uid": "1000"},
+					})
+				}
+
+				exportPath := func() string {
+					_, _, target, _ := fakeMounter.MountArgsForCall(0)
+					return target
+				}
+
+				BeforeEach(func() {
+					fakeBindMounter = &volumedriverfakes.FakeMounter{}
+					fakeBindMounter.UnmountStub = markUnmounted
+					options := volumedriver.DefaultOptions()
+					options.BindMounter = fakeBindMounter
+					volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+
+					exportMounted = false
+					fakeMounter.MountStub = func(dockerdriver.Env, string, string, map[string]interface{}) error {
+						exportMounted = true
+						return nil
+					}
+					fakeMounter.UnmountStub = func(dockerdriver.Env, string) error {
+						exportMounted = false
+						return nil
+					}
+					mounted := fakeMountChecker.ExistsStub
+					fakeMountChecker.ExistsStub = func(path string) (bool, error) {
+						if strings.Contains(filepath.ToSlash(path), "driver-exports.d") {
+							return exportMounted, nil
+						}
+						return mounted(path)
+					}
+					fakeOs.StatReturns(nil, os.ErrNotExist)
+					fakeFilepath.AbsReturns("/path/to/mount/", nil)
+
+					Expect(createSubdirVolume("app-a", "apps/a").Err).To(BeEmpty())
+					Expect(createSubdirVolume("app-b", "apps/b").Err).To(BeEmpty())
+				})
+
+				It("mounts the export once and binds the subdirectories", func() {
+					setupMount(env, volumeDriver, "app-a", fakeFilepath)
+					setupMount(env, volumeDriver, "app-b", fakeFilepath)
+
+					Expect(fakeMounter.MountCallCount()).To(Equal(1))
+					_, source, target, opts := fakeMounter.MountArgsForCall(0)
+					Expect(source).To(Equal("server:/export"))
+					Expect(filepath.ToSlash(target)).To(MatchRegexp(`^/path/to/mount/driver-exports.d/[0-9a-f]{16}$`))
+					Expect(opts).To(Equal(hardened(map[string]interface{}{"vers": "4.1"})))
+
+					Expect(fakeBindMounter.MountCallCount()).To(Equal(2))
+					_, dir, mountpoint, _ := fakeBindMounter.MountArgsForCall(1)
+					Expect(dir).To(Equal(filepath.Join(target, "apps", "b")))
+					Expect(strings.Replace(mountpoint, `\`, "/", -1)).To(Equal("/path/to/mount/app-b"))
+				})
+
+				It("creates missing subdirectories with the requested mode and owner", func() {
+					setupMount(env, volumeDriver, "app-a", fakeFilepath)
+
+					dir := filepath.Join(exportPath(), "apps", "a")
+					var mode os.FileMode
+					for i := 0; i < fakeOs.MkdirAllCallCount(); i++ {
+						if path, perm := fakeOs.MkdirAllArgsForCall(i); path == dir {
+							mode = perm
+						}
+					}
+					Expect(mode).To(Equal(os.FileMode(0750)))
+
+					Expect(fakeOs.ChownCallCount()).To(Equal(1))
+					path, uid, gid := fakeOs.ChownArgsForCall(0)
+					Expect(path).To(Equal(dir))
+					Expect(uid).To(Equal(1000))
+					Expect(gid).To(Equal(-1))
+				})
+
+				It("leaves existing subdirectories alone", func() {
+					fakeOs.StatReturns(&ioutil_fake.FakeFileInfo{}, nil)
+					setupMount(env, volumeDriver, "app-a", fakeFilepath)
+					Expect(fakeOs.ChownCallCount()).To(BeZero())
+				})
+
+				It("unmounts the export with its last volume", func() {
+					setupMount(env, volumeDriver, "app-a", fakeFilepath)
+					setupMount(env, volumeDriver, "app-b", fakeFilepath)
+
+					Expect(volumeDriver.Unmount(env, dockerdriver.UnmountRequest{Name: "app-a"}).Err).To(BeEmpty())
+					Expect(fakeBindMounter.UnmountCallCount()).To(Equal(1))
+					Expect(fakeMounter.UnmountCallCount()).To(BeZero())
+
+					Expect(volumeDriver.Unmount(env, dockerdriver.UnmountRequest{Name: "app-b"}).Err).To(BeEmpty())
+					Expect(fakeMounter.UnmountCallCount()).To(Equal(1))
+					_, target := fakeMounter.UnmountArgsForCall(0)
+					Expect(target).To(Equal(exportPath()))
+				})
+
+				Context("when the subdirectory cannot be created", func() {
+					BeforeEach(func() {
+						fakeOs.MkdirAllStub = func(path string, _ os.FileMode) error {
+							if strings.HasSuffix(filepath.ToSlash(path), "apps/a") {
+								return errors.New("permission denied")
+							}
+							return nil
+						}
+					})
+
+					It("fails the mount and releases the export", func() {
+						mountResponse := volumeDriver.Mount(env, dockerdriver.MountRequest{Name: "app-a"})
+						Expect(mountResponse.Err).To(ContainSubstring("unable to create subdir 'apps/a' on the export"))
+						Expect(fakeBindMounter.MountCallCount()).To(BeZero())
+						Expect(fakeMounter.UnmountCallCount()).To(Equal(1))
+					})
+				})
+
+				It("rejects subdirectories outside the export", func() {
+					Expect(createSubdirVolume("escape", "../other").Err).To(Equal("invalid subdir '../other', must be a relative path below the export"))
+				})
+
+				It("rejects invalid modes", func() {
+					createResponse := volumeDriver.Create(env, dockerdriver.CreateRequest{
+						Name: "bad-mode",
+						Opts: map[string]interface{}{"source": "server:/export", "subdir": "a", "subdir_mode": "rwx"},
+					})
+					Expect(createResponse.Err).To(Equal("invalid subdir_mode 'rwx', must be an octal mode such as 0750"))
+				})
+
+				Context("when no bind mounter is configured", func() {
+					BeforeEach(func() {
+						volumeDriver = volumedriver.NewVolumeDriver(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper())
+					})
+
+					It("rejects the subdir opt", func() {
+						Expect(createSubdirVolume("app-c", "apps/c").Err).To(Equal("the subdir opt is not supported by this driver"))
+					})
+				})
+			})
+
+			Context("when volumes are created with io limits", func() {
+				var fakeThrottler *volumedriverfakes.FakeIOThrottler
+
+				createLimitedVolume := func(opts map[string]interface{}) dockerdriver.ErrorResponse {
+					opts["source"] = "server:/export"
+					return volumeDriver.Create(env, dockerdriver.CreateRequest{Name: volumeName, Opts: opts})
+				}
+
+				BeforeEach(func() {
+					fakeThrottler = &volumedriverfakes.FakeIOThrottler{}
+					options := volumedriver.DefaultOptions()
+					options.IOThrottler = fakeThrottler
+					volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+				})
+
+				It("limits the io of the mount", func() {
+					Expect(createLimitedVolume(map[string]interface{}{"write_bps": "10485760", "read_iops": float64(500)}).Err).To(BeEmpty())
+					setupMount(env, volumeDriver, volumeName, fakeFilepath)
+
+					_, _, _, opts := fakeMounter.MountArgsForCall(0)
+					Expect(opts).NotTo(HaveKey("write_bps"))
+					Expect(opts).NotTo(HaveKey("read_iops"))
+
+					Expect(fakeThrottler.LimitCallCount()).To(Equal(1))
+					_, mountPoint, limits := fakeThrottler.LimitArgsForCall(0)
+					Expect(mountPoint).To(Equal(filepath.Join("/path/to/mount", volumeName)))
+					Expect(limits).To(Equal(volumedriver.IOLimits{WriteBPS: 10485760, ReadIOPS: 500}))
+				})
+
+				It("removes the limits before unmounting", func() {
+					Expect(createLimitedVolume(map[string]interface{}{"read_bps": "1048576"}).Err).To(BeEmpty())
+					setupMount(env, volumeDriver, volumeName, fakeFilepath)
+
+					fakeMounter.UnmountStub = func(env dockerdriver.Env, target string) error {
+						Expect(fakeThrottler.UnlimitCallCount()).To(Equal(1))
+						return markUnmounted(env, target)
+					}
+					Expect(volumeDriver.Unmount(env, dockerdriver.UnmountRequest{Name: volumeName}).Err).To(BeEmpty())
+					Expect(fakeMounter.UnmountCallCount()).To(Equal(1))
+				})
+
+				It("persists the limits of the volume", func() {
+					Expect(createLimitedVolume(map[string]interface{}{"read_bps": "1048576"}).Err).To(BeEmpty())
+					_, data, _ := fakeIoutil.WriteFileArgsForCall(fakeIoutil.WriteFileCallCount() - 1)
+					Expect(string(data)).To(ContainSubstring(`"IOLimits":{"ReadBPS":1048576}`))
+				})
+
+				Context("when the limits cannot be applied", func() {
+					BeforeEach(func() {
+						fakeThrottler.LimitReturns(errors.New("not a block device"))
+					})
+
+					It("fails the mount and unmounts again", func() {
+						Expect(createLimitedVolume(map[string]interface{}{"read_bps": "1048576"}).Err).To(BeEmpty())
+
+						mountResponse := volumeDriver.Mount(env, dockerdriver.MountRequest{Name: volumeName})
+						Expect(mountResponse.Err).To(Equal("not a block device"))
+						Expect(fakeMounter.UnmountCallCount()).To(Equal(1))
+					})
+				})
+
+				It("does not limit volumes without limits", func() {
+					Expect(createLimitedVolume(map[string]interface{}{}).Err).To(BeEmpty())
+					setupMount(env, volumeDriver, volumeName, fakeFilepath)
+					Expect(fakeThrottler.LimitCallCount()).To(BeZero())
+				})
+
+				It("rejects invalid limits", func() {
+					Expect(createLimitedVolume(map[string]interface{}{"write_iops": "-1"}).Err).To(Equal("invalid write_iops '-1', must be a positive integer"))
+					Expect(createLimitedVolume(map[string]interface{}{"read_bps": float64(1.5)}).Err).To(Equal("invalid read_bps '1.5', must be a positive integer"))
+					ExpectVolumeDoesNotExist(env, volumeDriver, volumeName)
+				})
+
+				Context("when no throttler is configured", func() {
+					BeforeEach(func() {
+						volumeDriver = volumedriver.NewVolumeDriver(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper())
+					})
+
+					It("rejects io limits", func() {
+						Expect(createLimitedVolume(map[string]interface{}{"read_bps": "1048576"}).Err).To(Equal("io limits are not supported by this driver"))
+					})
+				})
+			})
+
+			Context("when volumes are created with the scratch opt", func() {
+				var fakeOverlayMounter *volumedriverfakes.FakeMounter
+
+				createScratchVolume := func(opts map[string]interface{}) dockerdriver.ErrorResponse {
+					opts["source"] = "server:/export"
+					return volumeDriver.Create(env, dockerdriver.CreateRequest{Name: volumeName, Opts: opts})
+				}
+
+				BeforeEach(func() {
+					fakeOverlayMounter = &volumedriverfakes.FakeMounter{}
+					fakeOverlayMounter.UnmountStub = markUnmounted
+					options := volumedriver.DefaultOptions()
+					options.OverlayMounter = fakeOverlayMounter
+					options.ScratchDir = "/var/vcap/data/scratch"
+					volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+				})
+
+				It("layers an overlay over a read-only mount of the export", func() {
+					Expect(createScratchVolume(map[string]interface{}{"scratch": true, "vers": "4.1"}).Err).To(BeEmpty())
+					setupMount(env, volumeDriver, volumeName, fakeFilepath)
+
+					lower := filepath.Join("/path/to/mount", "driver-scratch.d", volumeName)
+					_, source, target, opts := fakeMounter.MountArgsForCall(0)
+					Expect(source).To(Equal("server:/export"))
+					Expect(target).To(Equal(lower))
+					Expect(opts).To(Equal(hardened(map[string]interface{}{"vers": "4.1", "ro": true})))
+
+					scratch := filepath.Join("/var/vcap/data/scratch", volumeName)
+					Expect(fakeOs.RemoveAllArgsForCall(0)).To(Equal(scratch))
+
+					Expect(fakeOverlayMounter.MountCallCount()).To(Equal(1))
+					_, source, target, opts = fakeOverlayMounter.MountArgsForCall(0)
+					Expect(source).To(Equal(lower))
+					Expect(target).To(Equal(filepath.Join("/path/to/mount", volumeName)))
+					Expect(opts).To(Equal(map[string]interface{}{"upperdir": filepath.Join(scratch, "upper"), "workdir": filepath.Join(scratch, "work")}))
+				})
+
+				It("throws the scratch data away on unmount", func() {
+					Expect(createScratchVolume(map[string]interface{}{"scratch": "true"}).Err).To(BeEmpty())
+					setupMount(env, volumeDriver, volumeName, fakeFilepath)
+
+					Expect(volumeDriver.Unmount(env, dockerdriver.UnmountRequest{Name: volumeName}).Err).To(BeEmpty())
+					Expect(fakeOverlayMounter.UnmountCallCount()).To(Equal(1))
+					Expect(fakeMounter.UnmountCallCount()).To(Equal(1))
+					_, target := fakeMounter.UnmountArgsForCall(0)
+					Expect(target).To(Equal(filepath.Join("/path/to/mount", "driver-scratch.d", volumeName)))
+					Expect(fakeOs.RemoveAllArgsForCall(fakeOs.RemoveAllCallCount() - 1)).To(Equal(filepath.Join("/var/vcap/data/scratch", volumeName)))
+				})
+
+				Context("when the overlay cannot be mounted", func() {
+					BeforeEach(func() {
+						fakeOverlayMounter.MountReturns(errors.New("overlay mount failed"))
+					})
+
+					It("unmounts the export again", func() {
+						Expect(createScratchVolume(map[string]interface{}{"scratch": true}).Err).To(BeEmpty())
+
+						mountResponse := volumeDriver.Mount(env, dockerdriver.MountRequest{Name: volumeName})
+						Expect(mountResponse.Err).To(Equal("overlay mount failed"))
+						Expect(fakeMounter.UnmountCallCount()).To(Equal(1))
+					})
+				})
+
+				It("persists the scratch flag of the volume", func() {
+					Expect(createScratchVolume(map[string]interface{}{"scratch": true}).Err).To(BeEmpty())
+					_, data, _ := fakeIoutil.WriteFileArgsForCall(fakeIoutil.WriteFileCallCount() - 1)
+					Expect(string(data)).To(ContainSubstring(`"Scratch":true`))
+				})
+
+				It("rejects invalid values", func() {
+					Expect(createScratchVolume(map[string]interface{}{"scratch": "sometimes"}).Err).To(Equal("invalid scratch 'sometimes', must be true or false"))
+					ExpectVolumeDoesNotExist(env, volumeDriver, volumeName)
+				})
+
+				Context("when no overlay mounter is configured", func() {
+					BeforeEach(func() {
+						volumeDriver = volumedriver.NewVolumeDriver(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper())
+					})
+
+					It("rejects the scratch opt", func() {
+						Expect(createScratchVolume(map[string]interface{}{"scratch": true}).Err).To(Equal("the scratch opt is not supported by this driver"))
+					})
+				})
+			})
+
+			Context("when volumes are created with the composite opt", func() {
+				createCompositeVolume := func(composite interface{}) dockerdriver.ErrorResponse {
+					opts := map[string]interface{}{"source": "server:/export", "vers": "4.1", "composite": composite}
+					return volumeDriver.Create(env, dockerdriver.CreateRequest{Name: volumeName, Opts: opts})
+				}
+
+				It("mounts the members into directories below the mountpoint", func() {
+					Expect(createCompositeVolume("data=other:/data, config=server:/config").Err).To(BeEmpty())
+					setupMount(env, volumeDriver, volumeName, fakeFilepath)
+
+					mountPath := filepath.Join("/path/to/mount", volumeName)
+					Expect(fakeMounter.MountCallCount()).To(Equal(3))
+					_, source, target, opts := fakeMounter.MountArgsForCall(0)
+					Expect(source).To(Equal("server:/export"))
+					Expect(target).To(Equal(mountPath))
+					Expect(opts).To(Equal(hardened(map[string]interface{}{"vers": "4.1"})))
+
+					_, source, target, opts = fakeMounter.MountArgsForCall(1)
+					Expect(source).To(Equal("server:/config"))
+					Expect(target).To(Equal(filepath.Join(mountPath, "config")))
+					Expect(opts).To(Equal(hardened(map[string]interface{}{"vers": "4.1"})))
+					dirs := []string{}
+					for i := 0; i < fakeOs.MkdirAllCallCount(); i++ {
+						dir, _ := fakeOs.MkdirAllArgsForCall(i)
+						dirs = append(dirs, dir)
+					}
+					Expect(dirs).To(ContainElement(filepath.Join(mountPath, "config")))
+
+					_, source, target, _ = fakeMounter.MountArgsForCall(2)
+					Expect(source).To(Equal("other:/data"))
+					Expect(target).To(Equal(filepath.Join(mountPath, "data")))
+				})
+
+				It("unmounts the members before the volume", func() {
+					Expect(createCompositeVolume(map[string]interface{}{"config": "server:/config", "data": "other:/data"}).Err).To(BeEmpty())
+					setupMount(env, volumeDriver, volumeName, fakeFilepath)
+
+					Expect(volumeDriver.Unmount(env, dockerdriver.UnmountRequest{Name: volumeName}).Err).To(BeEmpty())
+					targets := []string{}
+					for i := 0; i < fakeMounter.UnmountCallCount(); i++ {
+						_, target := fakeMounter.UnmountArgsForCall(i)
+						targets = append(targets, target)
+					}
+					mountPath := filepath.Join("/path/to/mount", volumeName)
+					Expect(targets).To(Equal([]string{filepath.Join(mountPath, "data"), filepath.Join(mountPath, "config"), mountPath}))
+				})
+
+				It("persists the members of the volume", func() {
+					Expect(createCompositeVolume("config=server:/config").Err).To(BeEmpty())
+					_, data, _ := fakeIoutil.WriteFileArgsForCall(fakeIoutil.WriteFileCallCount() - 1)
+					Expect(string(data)).To(ContainSubstring(`"Composite":{"config":"server:/config"}`))
+				})
+
+				Context("when a member cannot be mounted", func() {
+					BeforeEach(func() {
+						fakeMounter.MountReturnsOnCall(2, safeerrors.New(safeerrors.NotFound, "no such export"))
+					})
+
+					It("unmounts the members mounted so far and names the failed directory", func() {
+						Expect(createCompositeVolume("config=server:/config,data=other:/data").Err).To(BeEmpty())
+
+						fakeFilepath.AbsReturns("/path/to/mount/", nil)
+						mountResponse := volumeDriver.Mount(env, dockerdriver.MountRequest{Name: volumeName})
+						Expect(mountResponse.Err).To(ContainSubstring("composite directory 'data': no such export"))
+						Expect(fakeMounter.UnmountCallCount()).To(Equal(2))
+						_, target := fakeMounter.UnmountArgsForCall(0)
+						Expect(target).To(Equal(filepath.Join("/path/to/mount", volumeName, "config")))
+					})
+				})
+
+				It("rejects invalid values", func() {
+					for _, invalid := range []struct {
+						composite interface{}
+						err       string
+					}{
+						{"config", "invalid composite 'config', must be of the form dir=source,..."},
+						{"../etc=server:/etc", "invalid composite directory '../etc', must be a relative path below the mountpoint"},
+						{"config=", "invalid composite source for directory 'config', must not be empty"},
+						{"config=a:/x,config/=b:/y", "invalid composite 'config=a:/x,config/=b:/y', directory 'config' is listed twice"},
+						{42.0, "invalid composite '42', must be an object or dir=source,..."},
+					} {
+						Expect(createCompositeVolume(invalid.composite).Err).To(Equal(invalid.err))
+						ExpectVolumeDoesNotExist(env, volumeDriver, volumeName)
+					}
+				})
+
+				It("rejects the subdir opt", func() {
+					createResponse := volumeDriver.Create(env, dockerdriver.CreateRequest{Name: volumeName, Opts: map[string]interface{}{
+						"source": "server:/export", "subdir": "app", "composite": "config=server:/config",
+					}})
+					Expect(createResponse.Err).To(Equal("the composite opt cannot be combined with the subdir opt"))
+				})
+
+				Context("when sources are restricted", func() {
+					BeforeEach(func() {
+						options := volumedriver.DefaultOptions()
+						options.AllowedSources = []volumedriver.SourceRule{{Host: "server"}}
+						volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+					})
+
+					It("applies the policy to the members", func() {
+						Expect(createCompositeVolume("data=other:/data").Err).To(Equal("source 'other:/data' is not allowed on this cell"))
+						ExpectVolumeDoesNotExist(env, volumeDriver, volumeName)
+					})
+				})
+			})
+
+			Context("when a second create is called with the same volume ID", func() {
+				BeforeEach(func() {
+					setupVolume(env, volumeDriver, "volume", ip)
+				})
+
+				Context("with the same opts", func() {
+					It("does nothing", func() {
+						setupVolume(env, volumeDriver, "volume", ip)
+					})
+				})
+			})
+		})
+
+		Describe("ImportMount", func() {
+			var (
+				importRequest  volumedriver.ImportMountRequest
+				importResponse dockerdriver.ErrorResponse
+			)
+
+			BeforeEach(func() {
+				fakeFilepath.AbsReturns("/path/to/mount", nil)
+				fakeMounter.CheckReturns(true)
+
+				importRequest = volumedriver.ImportMountRequest{
+					Name:       volumeName,
+					Mountpoint: "/path/to/mount/legacy",
+					Opts:       map[string]interface{}{"source": "server:/export"},
+					MountCount: 2,
+				}
+			})
+
+			JustBeforeEach(func() {
+				importResponse = volumeDriver.ImportMount(env, importRequest)
+			})
+
+			It("adds the mount as a mounted volume", func() {
+				Expect(importResponse.Err).To(BeEmpty())
+				Expect(fakeMounter.MountCallCount()).To(Equal(0))
+
+				getResponse := volumeDriver.Get(env, dockerdriver.GetRequest{Name: volumeName})
+				Expect(getResponse.Err).To(BeEmpty())
+				Expect(getResponse.Volume.Mountpoint).To(Equal("/path/to/mount/legacy"))
+				Expect(getResponse.Volume.MountCount).To(Equal(2))
+			})
+
+			It("checks the health of the mount", func() {
+				Expect(fakeMounter.CheckCallCount()).To(Equal(1))
+				_, name, mountpoint, _ := fakeMounter.CheckArgsForCall(0)
+				Expect(name).To(Equal(volumeName))
+				Expect(mountpoint).To(Equal("/path/to/mount/legacy"))
+			})
+
+			It("persists the volume", func() {
+				Expect(fakeIoutil.WriteFileCallCount()).To(BeNumerically(">", 0))
+			})
+
+			It("unmounts the mount once every user has unmounted it", func() {
+				Expect(volumeDriver.Unmount(env, dockerdriver.UnmountRequest{Name: volumeName}).Err).To(BeEmpty())
+				Expect(fakeMounter.UnmountCallCount()).To(Equal(0))
+
+				Expect(volumeDriver.Unmount(env, dockerdriver.UnmountRequest{Name: volumeName}).Err).To(BeEmpty())
+				Expect(fakeMounter.UnmountCallCount()).To(Equal(1))
+				_, mountpoint := fakeMounter.UnmountArgsForCall(0)
+				Expect(mountpoint).To(Equal("/path/to/mount/legacy"))
+			})
+
+			Context("when no mount count is given", func() {
+				BeforeEach(func() {
+					importRequest.MountCount = 0
+				})
+
+				It("counts one mount", func() {
+					Expect(volumeDriver.Get(env, dockerdriver.GetRequest{Name: volumeName}).Volume.MountCount).To(Equal(1))
+				})
+			})
+
+			Context("when the path is not mounted", func() {
+				BeforeEach(func() {
+					unmounted["/path/to/mount/legacy"] = true
+				})
+
+				It("returns an error", func() {
+					Expect(importResponse.Err).To(Equal("'/path/to/mount/legacy' is not mounted"))
+					ExpectVolumeDoesNotExist(env, volumeDriver, volumeName)
+				})
+			})
+
+			Context("when the mount is not healthy", func() {
+				BeforeEach(func() {
+					fakeMounter.CheckReturns(false)
+				})
+
+				It("returns an error", func() {
+					Expect(importResponse.Err).To(Equal("the mount at '/path/to/mount/legacy' failed its health check"))
+					ExpectVolumeDoesNotExist(env, volumeDriver, volumeName)
+				})
+			})
+
+			Context("when the mountpoint is outside of the mount path root", func() {
+				BeforeEach(func() {
+					importRequest.Mountpoint = "/mnt/legacy"
+				})
+
+				It("returns an error", func() {
+					Expect(importResponse.Err).To(Equal("mountpoint '/mnt/legacy' must be directly below /path/to/mount"))
+				})
+			})
+
+			Context("when the mountpoint is reserved by the driver", func() {
+				BeforeEach(func() {
+					importRequest.Mountpoint = "/path/to/mount/driver-state.d"
+				})
+
+				It("returns an error", func() {
+					Expect(importResponse.Err).To(Equal("'/path/to/mount/driver-state.d' cannot be used as a mountpoint"))
+				})
+			})
+
+			Context("when the source is missing", func() {
+				BeforeEach(func() {
+					importRequest.Opts = map[string]interface{}{}
+				})
+
+				It("returns an error", func() {
+					Expect(importResponse.Err).To(Equal("Missing mandatory 'source' field in 'Opts'"))
+				})
+			})
+
+			Context("when the volume already exists", func() {
+				BeforeEach(func() {
+					setupVolume(env, volumeDriver, volumeName, ip)
+				})
+
+				It("returns an error", func() {
+					Expect(importResponse.Err).To(Equal(fmt.Sprintf("Volume '%s' already exists", volumeName)))
+				})
+			})
+
+			Context("when another volume is mounted there", func() {
+				BeforeEach(func() {
+					setupVolume(env, volumeDriver, "legacy", ip)
+					importRequest.Name = "other-volume"
+				})
+
+				It("returns an error", func() {
+					Expect(importResponse.Err).To(Equal("'/path/to/mount/legacy' is the mountpoint of another volume"))
+				})
+			})
+		})
+
+		Describe("Secrets", func() {
+			var (
+				fakeSecretResolver *volumedriverfakes.FakeSecretResolver
+				createOpts         map[string]interface{}
+				createResponse     dockerdriver.ErrorResponse
+			)
+
+			BeforeEach(func() {
+				fakeSecretResolver = &volumedriverfakes.FakeSecretResolver{}
+				fakeSecretResolver.ResolveReturns("s3cret", nil)
+				options := volumedriver.DefaultOptions()
+				options.SecretResolver = fakeSecretResolver
+				volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+
+				createOpts = map[string]interface{}{"source": "server:/export", "username": "app", "password": "vault://secret/data/filer#password"}
+			})
+
+			JustBeforeEach(func() {
+				createResponse = volumeDriver.Create(env, dockerdriver.CreateRequest{Name: "blue", Opts: createOpts})
+			})
+
+			It("resolves the secrets for the mounter", func() {
+				Expect(createResponse.Err).To(BeEmpty())
+				Expect(fakeSecretResolver.ResolveCallCount()).To(Equal(0))
+
+				setupMount(env, volumeDriver, "blue", fakeFilepath)
+				Expect(fakeSecretResolver.ResolveCallCount()).To(Equal(1))
+				_, path, key := fakeSecretResolver.ResolveArgsForCall(0)
+				Expect(path).To(Equal("secret/data/filer"))
+				Expect(key).To(Equal("password"))
+
+				_, _, _, opts := fakeMounter.MountArgsForCall(0)
+				Expect(opts).To(HaveKeyWithValue("password", "s3cret"))
+				Expect(opts).To(HaveKeyWithValue("username", "app"))
+			})
+
+			It("never persists the secrets", func() {
+				setupMount(env, volumeDriver, "blue", fakeFilepath)
+				Expect(fakeIoutil.WriteFileCallCount()).NotTo(BeZero())
+				for i := 0; i < fakeIoutil.WriteFileCallCount(); i++ {
+					_, data, _ := fakeIoutil.WriteFileArgsForCall(i)
+					Expect(string(data)).NotTo(ContainSubstring("s3cret"))
+				}
+			})
+
+			Context("when the mounter reports the secret in its error", func() {
+				BeforeEach(func() {
+					fakeMounter.MountReturns(errors.New("mount -o password=s3cret failed"))
+				})
+
+				It("redacts the secret", func() {
+					mountResponse := volumeDriver.Mount(env, dockerdriver.MountRequest{Name: "blue"})
+					Expect(mountResponse.Err).To(Equal("mount -o password=REDACTED failed"))
+
+					for i := 0; i < fakeIoutil.WriteFileCallCount(); i++ {
+						_, data, _ := fakeIoutil.WriteFileArgsForCall(i)
+						Expect(string(data)).NotTo(ContainSubstring("s3cret"))
+					}
+				})
+			})
+
+			Context("when the secret cannot be resolved", func() {
+				BeforeEach(func() {
+					fakeSecretResolver.ResolveReturns("", errors.New("permission denied"))
+				})
+
+				It("fails the mount with a safe error", func() {
+					mountResponse := volumeDriver.Mount(env, dockerdriver.MountRequest{Name: "blue"})
+					Expect(mountResponse.Err).To(MatchJSON(`{"SafeDescription": "unable to resolve the secret of the password opt", "Code": "permission-denied"}`))
+					Expect(fakeMounter.MountCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when the reference is malformed", func() {
+				BeforeEach(func() {
+					createOpts["password"] = "vault://secret/data/filer"
+				})
+
+				It("does not create the volume", func() {
+					Expect(createResponse.Err).To(Equal("invalid secret reference 'vault://secret/data/filer', must be of the form vault://path#key"))
+				})
+			})
+
+			Context("when the source references a secret", func() {
+				BeforeEach(func() {
+					createOpts["source"] = "vault://secret/data/filer#source"
+				})
+
+				It("does not create the volume", func() {
+					Expect(createResponse.Err).NotTo(BeEmpty())
+					ExpectVolumeDoesNotExist(env, volumeDriver, "blue")
+				})
+			})
+
+			Context("when the driver has no secret resolver", func() {
+				BeforeEach(func() {
+					volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), volumedriver.DefaultOptions())
+				})
+
+				It("does not create the volume", func() {
+					Expect(createResponse.Err).To(Equal("the password opt references a secret, which is not supported by this driver"))
+				})
+			})
+		})
+
+		Describe("BindVolume", func() {
+			var (
+				fakeBindMounter *volumedriverfakes.FakeMounter
+				bindRequest     volumedriver.BindVolumeRequest
+			)
+
+			BeforeEach(func() {
+				fakeBindMounter = &volumedriverfakes.FakeMounter{}
+				options := volumedriver.DefaultOptions()
+				options.BindMounter = fakeBindMounter
+				volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+
+				setupVolume(env, volumeDriver, volumeName, ip)
+				setupMount(env, volumeDriver, volumeName, fakeFilepath)
+				bindRequest = volumedriver.BindVolumeRequest{Name: volumeName, Target: "/containers/a/volume", ReadOnly: true}
+			})
+
+			It("binds the mountpoint into the target", func() {
+				Expect(volumeDriver.BindVolume(env, bindRequest).Err).To(BeEmpty())
+
+				Expect(fakeBindMounter.MountCallCount()).To(Equal(1))
+				_, source, target, opts := fakeBindMounter.MountArgsForCall(0)
+				Expect(strings.Replace(source, `\`, "/", -1)).To(Equal("/path/to/mount/" + volumeName))
+				Expect(target).To(Equal("/containers/a/volume"))
+				Expect(opts).To(Equal(map[string]interface{}{"readonly": true}))
+
+				described := volumeDriver.DescribeVolume(env, volumedriver.DescribeVolumeRequest{Name: volumeName}).Volume
+				Expect(described.MountCount).To(Equal(2))
+				Expect(described.References).To(Equal(map[string]int{"bind:/containers/a/volume": 1}))
+				Expect(described.Binds).To(Equal(map[string]volumedriver.VolumeBind{"/containers/a/volume": {Count: 1, ReadOnly: true}}))
+			})
+
+			It("keeps the volume mounted until the last bind is undone", func() {
+				Expect(volumeDriver.BindVolume(env, bindRequest).Err).To(BeEmpty())
+				Expect(volumeDriver.BindVolume(env, bindRequest).Err).To(BeEmpty())
+				Expect(fakeBindMounter.MountCallCount()).To(Equal(1))
+
+				Expect(volumeDriver.Unmount(env, dockerdriver.UnmountRequest{Name: volumeName}).Err).To(BeEmpty())
+				Expect(fakeMounter.UnmountCallCount()).To(Equal(0))
+
+				unbindRequest := volumedriver.UnbindVolumeRequest{Name: volumeName, Target: "/containers/a/volume/"}
+				Expect(volumeDriver.UnbindVolume(env, unbindRequest).Err).To(BeEmpty())
+				Expect(fakeBindMounter.UnmountCallCount()).To(Equal(0))
+
+				Expect(volumeDriver.UnbindVolume(env, unbindRequest).Err).To(BeEmpty())
+				Expect(fakeBindMounter.UnmountCallCount()).To(Equal(1))
+				Expect(fakeMounter.UnmountCallCount()).To(Equal(1))
+				ExpectVolumeDoesNotExist(env, volumeDriver, volumeName)
+			})
+
+			It("unbinds the targets when the volume is removed with force", func() {
+				Expect(volumeDriver.BindVolume(env, bindRequest).Err).To(BeEmpty())
+
+				Expect(volumeDriver.RemoveVolume(env, volumedriver.RemoveVolumeRequest{Name: volumeName, Force: true}).Err).To(BeEmpty())
+				Expect(fakeBindMounter.UnmountCallCount()).To(Equal(1))
+				Expect(fakeMounter.UnmountCallCount()).To(Equal(1))
+			})
+
+			It("refuses to revoke the reference of a bind", func() {
+				Expect(volumeDriver.BindVolume(env, bindRequest).Err).To(BeEmpty())
+
+				revokeResponse := volumeDriver.RevokeReferences(env, volumedriver.RevokeReferencesRequest{Caller: "bind:/containers/a/volume"})
+				Expect(revokeResponse.Err).To(Equal("'bind:/containers/a/volume' is a bind, unbind it with UnbindVolume"))
+			})
+
+			It("refuses targets that are relative or below the mount path root", func() {
+				bindRequest.Target = "containers/a/volume"
+				Expect(volumeDriver.BindVolume(env, bindRequest).Err).To(Equal("invalid bind target 'containers/a/volume', must be an absolute path"))
+
+				bindRequest.Target = "/path/to/mount/other"
+				Expect(volumeDriver.BindVolume(env, bindRequest).Err).To(ContainSubstring("must not be below the mount path root"))
+				Expect(fakeBindMounter.MountCallCount()).To(Equal(0))
+			})
+
+			It("refuses to bind a target again with other options", func() {
+				Expect(volumeDriver.BindVolume(env, bindRequest).Err).To(BeEmpty())
+
+				bindRequest.ReadOnly = false
+				Expect(volumeDriver.BindVolume(env, bindRequest).Err).To(Equal("target '/containers/a/volume' is bound with readonly true"))
+			})
+
+			It("refuses volumes that are not mounted", func() {
+				setupVolume(env, volumeDriver, "other-volume", ip)
+				bindRequest.Name = "other-volume"
+				Expect(volumeDriver.BindVolume(env, bindRequest).Err).To(Equal("Volume 'other-volume' must be mounted before it is bound"))
+			})
+
+			Context("when the bind mount fails", func() {
+				BeforeEach(func() {
+					fakeBindMounter.MountReturns(errors.New("permission denied"))
+				})
+
+				It("takes no reference", func() {
+					Expect(volumeDriver.BindVolume(env, bindRequest).Err).To(Equal("Error binding volume '" + volumeName + "': permission denied"))
+					described := volumeDriver.DescribeVolume(env, volumedriver.DescribeVolumeRequest{Name: volumeName}).Volume
+					Expect(described.MountCount).To(Equal(1))
+				})
+			})
+		})
+
+		Describe("Validate", func() {
+			var (
+				validateRequest  volumedriver.ValidateRequest
+				validateResponse volumedriver.ValidateResponse
+			)
+
+			checks := func() []string {
+				names := []string{}
+				for _, diagnostic := range validateResponse.Diagnostics {
+					names = append(names, diagnostic.Check)
+				}
+				return names
+			}
+
+			last := func() volumedriver.Diagnostic {
+				return validateResponse.Diagnostics[len(validateResponse.Diagnostics)-1]
+			}
+
+			BeforeEach(func() {
+				fakeFilepath.AbsReturns("/path/to/mount/", nil)
+				validateRequest = volumedriver.ValidateRequest{
+					Name: "blue",
+					Opts: map[string]interface{}{"source": "nfs://server/export", "ttl": "24h"},
+				}
+			})
+
+			JustBeforeEach(func() {
+				validateResponse = volumeDriver.Validate(env, validateRequest)
+			})
+
+			It("performs the checks of Create without creating the volume", func() {
+				Expect(validateResponse.Err).To(BeEmpty())
+				Expect(validateResponse.Valid).To(BeTrue())
+				Expect(checks()).To(Equal([]string{"name", "source", "opts", "secrets", "driver", "features", "quota"}))
+				Expect(fakeMounter.MountCallCount()).To(Equal(0))
+				ExpectVolumeDoesNotExist(env, volumeDriver, "blue")
+			})
+
+			Context("when an opt is malformed", func() {
+				BeforeEach(func() {
+					validateRequest.Opts["ttl"] = "soon"
+				})
+
+				It("reports the failed check and skips the rest", func() {
+					Expect(validateResponse.Valid).To(BeFalse())
+					Expect(checks()).To(Equal([]string{"name", "source", "opts"}))
+					Expect(last().Passed).To(BeFalse())
+					Expect(last().Message).To(ContainSubstring("soon"))
+				})
+			})
+
+			Context("when a common opt is malformed", func() {
+				BeforeEach(func() {
+					validateRequest.Opts["uid"] = "-1"
+				})
+
+				It("fails the opts check", func() {
+					Expect(validateResponse.Valid).To(BeFalse())
+					Expect(last()).To(Equal(volumedriver.Diagnostic{Check: "opts", Message: "invalid uid '-1', must be a number from 0 to 4294967294"}))
+				})
+			})
+
+			Context("when the source is missing", func() {
+				BeforeEach(func() {
+					delete(validateRequest.Opts, "source")
+				})
+
+				It("fails the source check", func() {
+					Expect(validateResponse.Valid).To(BeFalse())
+					Expect(last()).To(Equal(volumedriver.Diagnostic{Check: "source", Message: "Missing mandatory 'source' field in 'Opts'"}))
+				})
+			})
+
+			Context("when the volume asks for a feature the driver lacks", func() {
+				BeforeEach(func() {
+					validateRequest.Opts["scratch"] = true
+				})
+
+				It("fails the features check", func() {
+					Expect(validateResponse.Valid).To(BeFalse())
+					Expect(last()).To(Equal(volumedriver.Diagnostic{Check: "features", Message: "the scratch opt is not supported by this driver"}))
+				})
+			})
+
+			Context("when the volume asks for provisioning", func() {
+				BeforeEach(func() {
+					validateRequest.Opts = map[string]interface{}{"provision": "vol1"}
+				})
+
+				It("fails when the driver has no provisioner", func() {
+					Expect(validateResponse.Valid).To(BeFalse())
+					Expect(last()).To(Equal(volumedriver.Diagnostic{Check: "provision", Message: "the provision opt is not supported by this driver"}))
+				})
+			})
+
+			Context("when the volume exists", func() {
+				BeforeEach(func() {
+					setupVolume(env, volumeDriver, "blue", ip)
+				})
+
+				It("tells that Create would update it", func() {
+					Expect(validateResponse.Valid).To(BeTrue())
+					Expect(validateResponse.Diagnostics[0].Message).To(Equal("volume 'blue' exists, Create would update its opts"))
+					Expect(checks()).NotTo(ContainElement("quota"))
+				})
+			})
+
+			Context("when a test mount is asked for", func() {
+				BeforeEach(func() {
+					validateRequest.TestMount = true
+				})
+
+				It("mounts the source to a throwaway mountpoint and unmounts it", func() {
+					Expect(validateResponse.Valid).To(BeTrue())
+					Expect(last()).To(Equal(volumedriver.Diagnostic{Check: "test-mount", Passed: true}))
+
+					Expect(fakeMounter.MountCallCount()).To(Equal(1))
+					_, source, target, _ := fakeMounter.MountArgsForCall(0)
+					Expect(source).To(Equal("server:/export"))
+					Expect(strings.Replace(target, `\`, "/", -1)).To(HavePrefix("/path/to/mount/_validate-"))
+
+					Expect(fakeMounter.UnmountCallCount()).To(Equal(1))
+					_, unmounted := fakeMounter.UnmountArgsForCall(0)
+					Expect(unmounted).To(Equal(target))
+					ExpectVolumeDoesNotExist(env, volumeDriver, "blue")
+				})
+
+				Context("when the mount fails", func() {
+					BeforeEach(func() {
+						fakeMounter.MountReturns(errors.New("access denied by server"))
+						fakeMounter.MountStub = nil
+					})
+
+					It("fails the test mount check", func() {
+						Expect(validateResponse.Valid).To(BeFalse())
+						Expect(last()).To(Equal(volumedriver.Diagnostic{Check: "test-mount", Message: "mount failed: access denied by server"}))
+						Expect(fakeMounter.UnmountCallCount()).To(Equal(0))
+					})
+				})
+			})
+		})
+
+		Describe("Provision", func() {
+			var (
+				fakeProvisioner *volumedriverfakes.FakeProvisioner
+				createOpts      map[string]interface{}
+				createResponse  dockerdriver.ErrorResponse
+			)
+
+			BeforeEach(func() {
+				fakeProvisioner = &volumedriverfakes.FakeProvisioner{}
+				fakeProvisioner.ProvisionReturns("nfs.example.com:/vol1/cf_blue", nil)
+				options := volumedriver.DefaultOptions()
+				options.Provisioner = fakeProvisioner
+				volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+
+				createOpts = map[string]interface{}{"provision": "vol1", "provision_size": float64(1073741824), "provision_clients": "10.0.0.0/8, 10.1.0.1", "vers": "4.1"}
+			})
+
+			JustBeforeEach(func() {
+				createResponse = volumeDriver.Create(env, dockerdriver.CreateRequest{Name: "blue", Opts: createOpts})
+			})
+
+			It("creates the export of the volume", func() {
+				Expect(createResponse.Err).To(BeEmpty())
+				Expect(fakeProvisioner.ProvisionCallCount()).To(Equal(1))
+				_, request := fakeProvisioner.ProvisionArgsForCall(0)
+				Expect(request).To(Equal(volumedriver.ProvisionRequest{
+					Volume:  "blue",
+					Parent:  "vol1",
+					Size:    1073741824,
+					Clients: []string{"10.0.0.0/8", "10.1.0.1"},
+				}))
+			})
+
+			It("mounts the export without the provisioning opts", func() {
+				setupMount(env, volumeDriver, "blue", fakeFilepath)
+				_, source, _, opts := fakeMounter.MountArgsForCall(0)
+				Expect(source).To(Equal("nfs.example.com:/vol1/cf_blue"))
+				Expect(opts).To(Equal(hardened(map[string]interface{}{"vers": "4.1"})))
+			})
+
+			Context("when provisioning fails", func() {
+				BeforeEach(func() {
+					fakeProvisioner.ProvisionReturns("", errors.New("quota policy is full"))
+				})
+
+				It("does not create the volume", func() {
+					Expect(createResponse.Err).To(Equal("Error provisioning volume 'blue': quota policy is full"))
+					ExpectVolumeDoesNotExist(env, volumeDriver, "blue")
+				})
+			})
+
+			Context("when the volume also sets a source", func() {
+				BeforeEach(func() {
+					createOpts["source"] = "server:/export"
+				})
+
+				It("returns an error", func() {
+					Expect(createResponse.Err).To(Equal("the provision opt cannot be combined with a source"))
+					Expect(fakeProvisioner.ProvisionCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when the size is invalid", func() {
+				BeforeEach(func() {
+					createOpts["provision_size"] = "10G"
+				})
+
+				It("returns an error", func() {
+					Expect(createResponse.Err).To(Equal("invalid provision_size '10G', must be a positive number of bytes"))
+				})
+			})
+
+			Context("when provisioning opts are set without the provision opt", func() {
+				BeforeEach(func() {
+					createOpts = map[string]interface{}{"source": "server:/export", "provision_size": "1024"}
+				})
+
+				It("returns an error", func() {
+					Expect(createResponse.Err).To(Equal("the provision_size opt requires the provision opt"))
+				})
+			})
+
+			Context("when the driver has no provisioner", func() {
+				BeforeEach(func() {
+					volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), volumedriver.DefaultOptions())
+				})
+
+				It("returns an error", func() {
+					Expect(createResponse.Err).To(Equal("the provision opt is not supported by this driver"))
+				})
+			})
+		})
+
+		Describe("Clone", func() {
+			var (
+				fakeCloner    *volumedriverfakes.FakeCloner
+				cloneRequest  volumedriver.CloneRequest
+				cloneResponse dockerdriver.ErrorResponse
+			)
+
+			BeforeEach(func() {
+				fakeCloner = &volumedriverfakes.FakeCloner{}
+				options := volumedriver.DefaultOptions()
+				options.Cloner = fakeCloner
+				volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+
+				createResponse := volumeDriver.Create(env, dockerdriver.CreateRequest{
+					Name: "blue",
+					Opts: map[string]interface{}{"source": "server:/export/", "vers": "4.2", "labels": "app=web"},
+				})
+				Expect(createResponse.Err).To(BeEmpty())
+
+				cloneRequest = volumedriver.CloneRequest{Name: "green", From: "blue", Subdirectory: "app/blue", Target: "app/green"}
+			})
+
+			JustBeforeEach(func() {
+				cloneResponse = volumeDriver.Clone(env, cloneRequest)
+			})
+
+			It("copies the subdirectory within the source of the volume", func() {
+				Expect(cloneResponse.Err).To(BeEmpty())
+				Expect(fakeCloner.CloneCallCount()).To(Equal(1))
+				_, source, opts, from, to := fakeCloner.CloneArgsForCall(0)
+				Expect(source).To(Equal("server:/export/"))
+				Expect(opts).To(Equal(hardened(map[string]interface{}{"vers": "4.2"})))
+				Expect(from).To(Equal("app/blue"))
+				Expect(to).To(Equal("app/green"))
+			})
+
+			It("creates the clone on the copy with the opts of the original", func() {
+				ExpectVolumeExists(env, volumeDriver, "green")
+				setupMount(env, volumeDriver, "green", fakeFilepath)
+				_, source, _, opts := fakeMounter.MountArgsForCall(0)
+				Expect(source).To(Equal("server:/export/app/green"))
+				Expect(opts).To(HaveKeyWithValue("vers", "4.2"))
+
+				listResponse := volumeDriver.ListVolumes(env, volumedriver.ListVolumesRequest{Selector: "app=web"})
+				Expect(listResponse.Volumes).To(HaveLen(2))
+			})
+
+			Context("when the copy fails", func() {
+				BeforeEach(func() {
+					fakeCloner.CloneReturns(errors.New("no space left"))
+				})
+
+				It("does not create the clone", func() {
+					Expect(cloneResponse.Err).To(Equal("Error cloning volume 'blue': no space left"))
+					ExpectVolumeDoesNotExist(env, volumeDriver, "green")
+				})
+			})
+
+			Context("when the clone already exists", func() {
+				BeforeEach(func() {
+					setupVolume(env, volumeDriver, "green", ip)
+				})
+
+				It("does not copy anything", func() {
+					Expect(cloneResponse.Err).To(Equal("Volume 'green' already exists"))
+					Expect(fakeCloner.CloneCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when the original does not exist", func() {
+				BeforeEach(func() {
+					cloneRequest.From = "red"
+				})
+
+				It("returns an error", func() {
+					Expect(cloneResponse.Err).To(Equal("Volume 'red' not found"))
+				})
+			})
+
+			Context("when the directories escape the source", func() {
+				BeforeEach(func() {
+					cloneRequest.Target = "../other"
+				})
+
+				It("returns an error", func() {
+					Expect(cloneResponse.Err).To(Equal("invalid target '../other', must be a relative path below the volume source"))
+					Expect(fakeCloner.CloneCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when the target is inside the subdirectory", func() {
+				BeforeEach(func() {
+					cloneRequest.Target = "app/blue/green"
+				})
+
+				It("returns an error", func() {
+					Expect(cloneResponse.Err).To(Equal("target 'app/blue/green' and subdirectory 'app/blue' must not contain each other"))
+				})
+			})
+
+			Context("when no cloner is configured", func() {
+				BeforeEach(func() {
+					volumeDriver = volumedriver.NewVolumeDriver(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper())
+				})
+
+				It("returns an error", func() {
+					Expect(cloneResponse.Err).To(Equal("Clone is not supported by this driver"))
+				})
+			})
+		})
+
+		Describe("CollectOrphans", func() {
+			var (
+				now     time.Time
+				dryRun  bool
+				orphans []string
+			)
+
+			dir := func(name string, modTime time.Time) os.FileInfo {
+				info := &ioutil_fake.FakeFileInfo{}
+				info.NameReturns(name)
+				info.IsDirReturns(true)
+				info.ModTimeReturns(modTime)
+				return info
+			}
+
+			BeforeEach(func() {
+				now = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+				dryRun = false
+				setupVolume(env, volumeDriver, volumeName, ip)
+
+				file := &ioutil_fake.FakeFileInfo{}
+				file.NameReturns("some-file")
+				fakeIoutil.ReadDirReturns([]os.FileInfo{
+					dir(volumeName, now.Add(-time.Hour)),
+					dir("driver-state.d", now.Add(-time.Hour)),
+					dir("orphan", now.Add(-time.Hour)),
+					dir("recent", now.Add(-time.Minute)),
+					dir("still-mounted", now.Add(-time.Hour)),
+					file,
+				}, nil)
+				fakeFilepath.AbsReturns("/path/to/mount", nil)
+				fakeTime.NowReturns(now)
+				fakeMountChecker.ExistsStub = func(path string) (bool, error) {
+					return path == "/path/to/mount/still-mounted", nil
+				}
+				fakeOs.RemoveReturns(nil)
+			})
+
+			JustBeforeEach(func() {
+				orphans = volumeDriver.CollectOrphans(env, dryRun)
+			})
+
+			It("removes old directories that no volume owns", func() {
+				Expect(orphans).To(Equal([]string{"/path/to/mount/orphan"}))
+				Expect(fakeOs.RemoveCallCount()).To(Equal(1))
+				Expect(fakeOs.RemoveArgsForCall(0)).To(Equal("/path/to/mount/orphan"))
+			})
+
+			It("never removes directories recursively", func() {
+				Expect(fakeOs.RemoveAllCallCount()).To(BeZero())
+			})
+
+			Context("in dry-run mode", func() {
+				BeforeEach(func() {
+					dryRun = true
+				})
+
+				It("reports the orphans without removing them", func() {
+					Expect(orphans).To(Equal([]string{"/path/to/mount/orphan"}))
+					Expect(fakeOs.RemoveCallCount()).To(BeZero())
+					Expect(logger.Buffer()).To(gbytes.Say("orphan-found"))
+				})
+			})
+
+			Context("when the mount table cannot be read", func() {
+				BeforeEach(func() {
+					fakeMountChecker.ExistsStub = nil
+					fakeMountChecker.ExistsReturns(false, errors.New("badness"))
+				})
+
+				It("keeps the directories", func() {
+					Expect(orphans).To(BeEmpty())
+					Expect(fakeOs.RemoveCallCount()).To(BeZero())
+				})
+			})
+		})
+
+		Describe("ExpireVolumes", func() {
+			var fakeEmitter *volumedriverfakes.FakeEmitter
+			var createdAt time.Time
+
+			BeforeEach(func() {
+				fakeEmitter = &volumedriverfakes.FakeEmitter{}
+				options := volumedriver.DefaultOptions()
+				options.MetricsEmitter = fakeEmitter
+				volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+
+				createdAt = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+				fakeTime.NowReturns(createdAt)
+				for _, name := range []string{"short-lived", "mounted"} {
+					createResponse := volumeDriver.Create(env, dockerdriver.CreateRequest{
+						Name: name,
+						Opts: map[string]interface{}{"source": ip, "ttl": "1h"},
+					})
+					Expect(createResponse.Err).To(BeEmpty())
+				}
+				setupVolume(env, volumeDriver, "long-lived", ip)
+				setupMount(env, volumeDriver, "mounted", fakeFilepath)
+			})
+
+			Context("before the ttl has passed", func() {
+				It("keeps the volumes", func() {
+					fakeTime.NowReturns(createdAt.Add(59 * time.Minute))
+					volumeDriver.ExpireVolumes(env)
+					ExpectVolumeExists(env, volumeDriver, "short-lived")
+				})
+			})
+
+			Context("once the ttl has passed", func() {
+				BeforeEach(func() {
+					fakeTime.NowReturns(createdAt.Add(time.Hour))
+					volumeDriver.ExpireVolumes(env)
+				})
+
+				It("removes unmounted volumes that expired", func() {
+					ExpectVolumeDoesNotExist(env, volumeDriver, "short-lived")
+					Expect(filepath.Base(fakeOs.RemoveArgsForCall(fakeOs.RemoveCallCount() - 1))).To(Equal("short-lived.json"))
+				})
+
+				It("keeps mounted volumes and volumes without a ttl", func() {
+					ExpectVolumeExists(env, volumeDriver, "mounted")
+					ExpectVolumeExists(env, volumeDriver, "long-lived")
+				})
+
+				It("emits an expiry metric", func() {
+					Expect(fakeEmitter.CountCallCount()).To(BeNumerically(">", 0))
+					name, delta, _ := fakeEmitter.CountArgsForCall(fakeEmitter.CountCallCount() - 1)
+					Expect(name).To(Equal(metrics.Expirations))
+					Expect(delta).To(Equal(int64(1)))
+				})
+			})
+		})
+
+		Describe("EmitMountStats", func() {
+			var fakeEmitter *volumedriverfakes.FakeEmitter
+			var fakeMountStats *volumedriverfakes.FakeMountStatsReader
+
+			sample := func(read, written, ops, transmissions uint64, rtt time.Duration) map[string]volumedriver.NFSStats {
+				return map[string]volumedriver.NFSStats{
+					"/path/to/mount/" + volumeName: {
+						BytesRead:    read,
+						BytesWritten: written,
+						Ops:          map[string]volumedriver.NFSOpStats{"READ": {Ops: ops, Transmissions: transmissions, RTT: rtt}},
+					},
+				}
+			}
+
+			counted := func(name string) []int64 {
+				deltas := []int64{}
+				for i := 0; i < fakeEmitter.CountCallCount(); i++ {
+					countName, delta, tags := fakeEmitter.CountArgsForCall(i)
+					if countName == name {
+						Expect(tags).To(ConsistOf(metrics.VolumeTag(volumeName)))
+						deltas = append(deltas, delta)
+					}
+				}
+				return deltas
+			}
+
+			BeforeEach(func() {
+				fakeEmitter = &volumedriverfakes.FakeEmitter{}
+				fakeMountStats = &volumedriverfakes.FakeMountStatsReader{}
+				options := volumedriver.DefaultOptions()
+				options.MetricsEmitter = fakeEmitter
+				options.MountStats = fakeMountStats
+				volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+				setupVolume(env, volumeDriver, volumeName, ip)
+				setupMount(env, volumeDriver, volumeName, fakeFilepath)
+			})
+
+			It("emits the traffic of mounted volumes since the previous call", func() {
+				fakeMountStats.ReadReturnsOnCall(0, sample(100, 50, 4, 5, 40*time.Millisecond), nil)
+				fakeMountStats.ReadReturnsOnCall(1, sample(300, 50, 6, 8, 60*time.Millisecond), nil)
+
+				volumeDriver.EmitMountStats(env)
+				volumeDriver.EmitMountStats(env)
+
+				Expect(counted(metrics.NFSBytesRead)).To(Equal([]int64{100, 200}))
+				Expect(counted(metrics.NFSBytesWritten)).To(Equal([]int64{50, 0}))
+				Expect(counted(metrics.NFSRetransmits)).To(Equal([]int64{1, 1}))
+
+				Expect(fakeEmitter.TimingCallCount()).To(Equal(3))
+				name, latency, tags := fakeEmitter.TimingArgsForCall(2)
+				Expect(name).To(Equal(metrics.NFSRPCLatency))
+				Expect(latency).To(Equal(10 * time.Millisecond))
+				Expect(tags).To(ConsistOf(metrics.VolumeTag(volumeName), metrics.OpTag("READ")))
+			})
+
+			It("reports counters that went backwards in full", func() {
+				fakeMountStats.ReadReturnsOnCall(0, sample(300, 0, 0, 0, 0), nil)
+				fakeMountStats.ReadReturnsOnCall(1, sample(100, 0, 0, 0, 0), nil)
+
+				volumeDriver.EmitMountStats(env)
+				volumeDriver.EmitMountStats(env)
+
+				Expect(counted(metrics.NFSBytesRead)).To(Equal([]int64{300, 100}))
+			})
+
+			It("skips volumes without statistics", func() {
+				fakeMountStats.ReadReturns(map[string]volumedriver.NFSStats{}, nil)
+				volumeDriver.EmitMountStats(env)
+				Expect(counted(metrics.NFSBytesRead)).To(BeEmpty())
+			})
+
+			It("logs when the statistics cannot be read", func() {
+				fakeMountStats.ReadReturns(nil, errors.New("badness"))
+				volumeDriver.EmitMountStats(env)
+				Expect(logger.Buffer()).To(gbytes.Say("read-mount-stats-failed"))
+			})
+
+			It("emits the fscache hits and misses of cached mounts", func() {
+				cached := func(ok, failed uint64) map[string]volumedriver.NFSStats {
+					stats := sample(0, 0, 0, 0, 0)
+					volume := stats["/path/to/mount/"+volumeName]
+					volume.FSCache = &volumedriver.FSCacheStats{PagesReadOK: ok, PagesReadFailed: failed}
+					stats["/path/to/mount/"+volumeName] = volume
+					return stats
+				}
+				fakeMountStats.ReadReturnsOnCall(0, cached(80, 20), nil)
+				fakeMountStats.ReadReturnsOnCall(1, cached(200, 25), nil)
+				fakeMountStats.ReadReturnsOnCall(2, sample(0, 0, 0, 0, 0), nil)
+
+				volumeDriver.EmitMountStats(env)
+				volumeDriver.EmitMountStats(env)
+				volumeDriver.EmitMountStats(env)
+
+				Expect(counted(metrics.FSCacheHits)).To(Equal([]int64{80, 120}))
+				Expect(counted(metrics.FSCacheMisses)).To(Equal([]int64{20, 5}))
+			})
+		})
+
+		Describe("fscache", func() {
+			var fakeFSCache *volumedriverfakes.FakeFSCache
+
+			create := func(opts map[string]interface{}) dockerdriver.ErrorResponse {
+				opts["source"] = ip
+				return volumeDriver.Create(env, dockerdriver.CreateRequest{Name: volumeName, Opts: opts})
+			}
+
+			BeforeEach(func() {
+				fakeFSCache = &volumedriverfakes.FakeFSCache{}
+				options := volumedriver.DefaultOptions()
+				options.FSCache = fakeFSCache
+				volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+			})
+
+			It("passes the fsc opt on to the mounter", func() {
+				Expect(create(map[string]interface{}{"fsc": true}).Err).To(BeEmpty())
+				setupMount(env, volumeDriver, volumeName, fakeFilepath)
+
+				_, _, _, opts := fakeMounter.MountArgsForCall(0)
+				Expect(opts).To(HaveKeyWithValue("fsc", true))
+				Expect(fakeFSCache.AvailableCallCount()).To(Equal(2))
+			})
+
+			Context("when no cache is available", func() {
+				BeforeEach(func() {
+					fakeFSCache.AvailableReturns(errors.New("cachefilesd is not running"))
+				})
+
+				It("rejects volumes with the fsc opt", func() {
+					Expect(create(map[string]interface{}{"fsc": "default"}).Err).To(Equal("the fsc opt needs a running cachefilesd: cachefilesd is not running"))
+					ExpectVolumeDoesNotExist(env, volumeDriver, volumeName)
+				})
+
+				It("accepts volumes without it", func() {
+					Expect(create(map[string]interface{}{"fsc": "false"}).Err).To(BeEmpty())
+					Expect(fakeFSCache.AvailableCallCount()).To(Equal(0))
+				})
+
+				It("mounts volumes whose cache went away uncached", func() {
+					fakeFSCache.AvailableReturns(nil)
+					Expect(create(map[string]interface{}{"fsc": true}).Err).To(BeEmpty())
+
+					fakeFSCache.AvailableReturns(errors.New("cachefilesd is not running"))
+					setupMount(env, volumeDriver, volumeName, fakeFilepath)
+					Expect(logger.Buffer()).To(gbytes.Say("mounting-uncached"))
+				})
+			})
+		})
+
+		Describe("Get", func() {
+			Context("when the volume has been created", func() {
+				It("returns the volume name", func() {
+					volumeName := "test-volume"
+					setupVolume(env, volumeDriver, volumeName, ip)
+					ExpectVolumeExists(env, volumeDriver, volumeName)
+				})
+
+				It("returns the mountpoint assigned to the volume", func() {
+					fakeFilepath.AbsReturns("/path/to/mount/", nil)
+					setupVolume(env, volumeDriver, "some/volume", ip)
+					mkdirs := fakeOs.MkdirAllCallCount()
+					getResponse := ExpectVolumeExists(env, volumeDriver, "some/volume")
+					Expect(strings.Replace(getResponse.Volume.Mountpoint, `\`, "/", -1)).To(MatchRegexp(`^/path/to/mount/vol-[0-9a-f]{16}$`))
+
+					// Get runs without volumesLock, and must not touch the
+					// filesystem or the umask of the process
+					Expect(fakeOs.MkdirAllCallCount()).To(Equal(mkdirs))
+				})
+			})
+
+			Context("when the volume has not been created", func() {
+				It("returns an error", func() {
+					volumeName := "test-volume"
+					ExpectVolumeDoesNotExist(env, volumeDriver, volumeName)
+				})
+			})
+
+			Context("when the volume is changed concurrently", func() {
+				It("reads a consistent snapshot of it", func() {
+					setupVolume(env, volumeDriver, volumeName, ip)
+					// keep a reference, so that the volume stays mounted
+					setupMount(env, volumeDriver, volumeName, fakeFilepath)
+
+					done := make(chan struct{})
+					go func() {
+						defer GinkgoRecover()
+						defer close(done)
+						for i := 0; i < 50; i++ {
+							Expect(volumeDriver.Mount(env, dockerdriver.MountRequest{Name: volumeName}).Err).To(BeEmpty())
+							Expect(volumeDriver.Unmount(env, dockerdriver.UnmountRequest{Name: volumeName}).Err).To(BeEmpty())
+							setupVolume(env, volumeDriver, volumeName, ip)
+						}
+					}()
+
+					for i := 0; i < 50; i++ {
+						Expect(volumeDriver.Get(env, dockerdriver.GetRequest{Name: volumeName}).Err).To(BeEmpty())
+						volumeDriver.Path(env, dockerdriver.PathRequest{Name: volumeName})
+					}
+					Eventually(done).Should(BeClosed())
+				})
+			})
+		})
+
+		Describe("DescribeVolume", func() {
+			var mountedAt time.Time
+
+			BeforeEach(func() {
+				mountedAt = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+				fakeTime.NowReturns(mountedAt)
+				createResponse := volumeDriver.Create(env, dockerdriver.CreateRequest{
+					Name: volumeName,
+					Opts: map[string]interface{}{"source": ip, "vers": "4.1", "password": "secret", "access_key": "AKIA"},
+				})
+				Expect(createResponse.Err).To(BeEmpty())
+			})
+
+			It("reports the mount count in Get", func() {
+				setupMount(env, volumeDriver, volumeName, fakeFilepath)
+				getResponse := ExpectVolumeExists(env, volumeDriver, volumeName)
+				Expect(getResponse.Volume.MountCount).To(Equal(1))
+			})
+
+			It("reports the opts with secrets redacted", func() {
+				describeResponse := volumeDriver.DescribeVolume(env, volumedriver.DescribeVolumeRequest{Name: volumeName})
+				Expect(describeResponse.Err).To(BeEmpty())
+				Expect(describeResponse.Volume.Opts).To(Equal(map[string]interface{}{
+					"source":     ip,
+					"vers":       "4.1",
+					"password":   "REDACTED",
+					"access_key": "REDACTED",
+				}))
+			})
+
+			It("reports and persists the last successful mount", func() {
+				setupMount(env, volumeDriver, volumeName, fakeFilepath)
+				describeResponse := volumeDriver.DescribeVolume(env, volumedriver.DescribeVolumeRequest{Name: volumeName})
+				Expect(describeResponse.Volume.MountCount).To(Equal(1))
+				Expect(*describeResponse.Volume.LastMountedAt).To(Equal(mountedAt))
+
+				_, data, _ := fakeIoutil.WriteFileArgsForCall(fakeIoutil.WriteFileCallCount() - 1)
+				Expect(string(data)).To(ContainSubstring(`"LastMountedAt":"2020-01-01T00:00:00Z"`))
+			})
+
+			Context("when the mount fails", func() {
+				BeforeEach(func() {
+					fakeMounter.MountReturns(errors.New("connection refused"))
+					fakeFilepath.AbsReturns("/path/to/mount/", nil)
+					Expect(volumeDriver.Mount(env, dockerdriver.MountRequest{Name: volumeName}).Err).NotTo(BeEmpty())
+				})
+
+				It("keeps the last mount error after it is reset", func() {
+					Expect(volumeDriver.ResetMountError(env, volumedriver.ResetMountErrorRequest{Name: volumeName}).Err).To(BeEmpty())
+
+					describeResponse := volumeDriver.DescribeVolume(env, volumedriver.DescribeVolumeRequest{Name: volumeName})
+					Expect(describeResponse.Volume.MountError).To(BeEmpty())
+					Expect(describeResponse.Volume.LastMountError).To(Equal("connection refused"))
+					Expect(*describeResponse.Volume.LastMountErrorAt).To(Equal(mountedAt))
+				})
+
+				It("persists the error", func() {
+					_, data, _ := fakeIoutil.WriteFileArgsForCall(fakeIoutil.WriteFileCallCount() - 1)
+					Expect(string(data)).To(ContainSubstring(`"LastMountError":"connection refused"`))
+				})
+			})
+
+			It("returns an error for unknown volumes", func() {
+				describeResponse := volumeDriver.DescribeVolume(env, volumedriver.DescribeVolumeRequest{Name: "unknown"})
+				Expect(describeResponse.Err).To(Equal("Volume 'unknown' not found"))
+			})
+		})
+
+		Describe("Subscribe", func() {
+			var (
+				events      <-chan volumedriver.Event
+				unsubscribe func()
+			)
+
+			BeforeEach(func() {
+				events, unsubscribe = volumeDriver.Subscribe()
+			})
+
+			AfterEach(func() {
+				unsubscribe()
+			})
+
+			eventTypes := func(count int) []volumedriver.EventType {
+				types := []volumedriver.EventType{}
+				for i := 0; i < count; i++ {
+					var event volumedriver.Event
+					Eventually(events).Should(Receive(&event))
+					Expect(event.Volume).To(Equal(volumeName))
+					types = append(types, event.Type)
+				}
+				return types
+			}
+
+			It("publishes the lifecycle of a volume", func() {
+				fakeMounter.CheckReturns(true)
+				setupVolume(env, volumeDriver, volumeName, ip)
+				setupMount(env, volumeDriver, volumeName, fakeFilepath)
+				setupMount(env, volumeDriver, volumeName, fakeFilepath)
+				Expect(volumeDriver.Unmount(env, dockerdriver.UnmountRequest{Name: volumeName}).Err).To(BeEmpty())
+				Expect(volumeDriver.Unmount(env, dockerdriver.UnmountRequest{Name: volumeName}).Err).To(BeEmpty())
+
+				Expect(eventTypes(4)).To(Equal([]volumedriver.EventType{
+					volumedriver.EventCreated,
+					volumedriver.EventMounted,
+					volumedriver.EventUnmounted,
+					volumedriver.EventRemoved,
+				}))
+			})
+
+			It("publishes mount failures", func() {
+				setupVolume(env, volumeDriver, volumeName, ip)
+				fakeMounter.MountReturns(errors.New("connection refused"))
+				fakeFilepath.AbsReturns("/path/to/mount/", nil)
+				volumeDriver.Mount(env, dockerdriver.MountRequest{Name: volumeName})
+
+				Expect(eventTypes(2)).To(Equal([]volumedriver.EventType{volumedriver.EventCreated, volumedriver.EventMountFailed}))
+			})
+
+			It("publishes heartbeats while a mount is in progress", func() {
+				options := volumedriver.DefaultOptions()
+				options.MountHeartbeatInterval = 10 * time.Millisecond
+				volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+				unsubscribe()
+				events, unsubscribe = volumeDriver.Subscribe()
+
+				setupVolume(env, volumeDriver, volumeName, ip)
+				Expect(eventTypes(1)).To(Equal([]volumedriver.EventType{volumedriver.EventCreated}))
+
+				release := make(chan struct{})
+				fakeMounter.MountStub = func(dockerdriver.Env, string, string, map[string]interface{}) error {
+					<-release
+					return nil
+				}
+				mounted := make(chan dockerdriver.MountResponse)
+				go func() {
+					defer GinkgoRecover()
+					mounted <- volumeDriver.Mount(env, dockerdriver.MountRequest{Name: volumeName})
+				}()
+
+				var event volumedriver.Event
+				Eventually(events).Should(Receive(&event))
+				Expect(event.Type).To(Equal(volumedriver.EventMounting))
+				Expect(event.Volume).To(Equal(volumeName))
+				Expect(logger.Buffer()).To(gbytes.Say("still-mounting"))
+
+				close(release)
+				Expect((<-mounted).Err).To(BeEmpty())
+				for event.Type == volumedriver.EventMounting {
+					Eventually(events).Should(Receive(&event))
+				}
+				Expect(event.Type).To(Equal(volumedriver.EventMounted))
+				Consistently(events, 50*time.Millisecond).ShouldNot(Receive())
+			})
+
+			It("disconnects subscribers that fall behind", func() {
+				for i := 0; i < 100; i++ {
+					setupVolume(env, volumeDriver, volumeName, ip)
+				}
+
+				received := 0
+				for range events {
+					received++
+				}
+				Expect(received).To(BeNumerically("<", 100))
+			})
+
+			It("stops publishing to unsubscribed subscribers", func() {
+				unsubscribe()
+				setupVolume(env, volumeDriver, volumeName, ip)
+				Expect(events).To(BeClosed())
+			})
+		})
+
+		Describe("Drain", func() {
+			var (
+				release  chan struct{}
+				draining sync.WaitGroup
+				finished sync.WaitGroup
+			)
+
+			BeforeEach(func() {
+				options := volumedriver.DefaultOptions()
+				options.DrainTimeout = 100 * time.Millisecond
+				volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+
+				fakeFilepath.AbsReturns("/path/to/mount/", nil)
+				for _, name := range []string{"a", "b"} {
+					setupVolume(env, volumeDriver, name, ip)
+					Expect(volumeDriver.Mount(env, dockerdriver.MountRequest{Name: name}).Err).To(BeEmpty())
+				}
+
+				release = make(chan struct{})
+				draining.Add(2)
+				finished.Add(2)
+				markUnmounted := markUnmounted
+				fakeMounter.UnmountStub = func(env dockerdriver.Env, target string) error {
+					defer finished.Done()
+					draining.Done()
+					<-release
+					return markUnmounted(env, target)
+				}
+			})
+
+			It("unmounts the volumes in parallel", func() {
+				drained := make(chan volumedriver.DrainResponse)
+				go func() {
+					drained <- volumeDriver.Drain(env)
+				}()
+
+				// both unmounts are blocked until they have both started
+				draining.Wait()
+				close(release)
+
+				var response volumedriver.DrainResponse
+				Eventually(drained).Should(Receive(&response))
+				Expect(response.Err).To(BeEmpty())
+				Expect(fakeMounter.UnmountCallCount()).To(Equal(2))
+				Expect(fakeMounter.PurgeCallCount()).To(Equal(1))
+			})
+
+			It("reports the unmount of every volume", func() {
+				close(release)
+
+				response := volumeDriver.Drain(env)
+				Expect(response.Volumes).To(HaveLen(2))
+				Expect(response.Volumes[0].Name).To(Equal("a"))
+				Expect(response.Volumes[0].Err).To(BeEmpty())
+				Expect(response.Volumes[1].Name).To(Equal("b"))
+				Expect(response.RemainingMounts).To(BeEmpty())
+			})
+
+			Context("when an unmount fails", func() {
+				BeforeEach(func() {
+					close(release)
+					unmount := fakeMounter.UnmountStub
+					fakeMounter.UnmountStub = func(env dockerdriver.Env, target string) error {
+						if err := unmount(env, target); err != nil || filepath.Base(target) != "b" {
+							return err
+						}
+						return errors.New("device is busy")
+					}
+				})
+
+				It("reports the failure", func() {
+					response := volumeDriver.Drain(env)
+					Expect(response.Err).To(Equal("drain failed to unmount b"))
+					Expect(response.Volumes[0].Err).To(BeEmpty())
+					Expect(response.Volumes[1].Err).To(ContainSubstring("device is busy"))
+				})
+			})
+
+			Context("when kernel mounts remain below the mount path root", func() {
+				BeforeEach(func() {
+					close(release)
+					fakeMountChecker.ListReturns([]string{"/path/to/mount/b"}, nil)
+				})
+
+				It("reports them", func() {
+					response := volumeDriver.Drain(env)
+					Expect(response.RemainingMounts).To(Equal([]string{"/path/to/mount/b"}))
+					Expect(response.Err).To(Equal("1 mounts remain below the mount path roots"))
+					Expect(fakeMountChecker.ListArgsForCall(fakeMountChecker.ListCallCount() - 1).String()).To(Equal("^/path/to/mount/"))
+				})
+			})
+
+			Context("when the unmounts hang", func() {
+				var (
+					clockLock sync.Mutex
+					now       time.Time
+				)
+
+				BeforeEach(func() {
+					now = time.Now()
+					fakeTime.NowStub = func() time.Time {
+						clockLock.Lock()
+						defer clockLock.Unlock()
+						return now
+					}
+				})
+
+				AfterEach(func() {
+					close(release)
+					finished.Wait()
+				})
+
+				It("purges the remaining mounts once the deadline is reached", func() {
+					drained := make(chan volumedriver.DrainResponse)
+					go func() {
+						drained <- volumeDriver.Drain(env)
+					}()
+
+					// the deadline follows the driver's time, not the wall clock
+					draining.Wait()
+					Consistently(drained, 200*time.Millisecond).ShouldNot(Receive())
+					clockLock.Lock()
+					now = now.Add(100 * time.Millisecond)
+					clockLock.Unlock()
+
+					var response volumedriver.DrainResponse
+					Eventually(drained).Should(Receive(&response))
+					Expect(response.Err).To(Equal("drain did not finish unmounting within 100ms, purging the remaining mounts"))
+					Expect(response.Volumes).To(ConsistOf(
+						volumedriver.DrainedVolume{Name: "a", Mountpoint: "/path/to/mount/a", Err: "unmount did not finish within 100ms"},
+						volumedriver.DrainedVolume{Name: "b", Mountpoint: "/path/to/mount/b", Err: "unmount did not finish within 100ms"},
+					))
+					Expect(fakeMounter.PurgeCallCount()).To(Equal(1))
+					_, path := fakeMounter.PurgeArgsForCall(0)
+					Expect(path).To(Equal("/path/to/mount"))
+				})
+			})
+
+			Context("when purging is skipped", func() {
+				BeforeEach(func() {
+					close(release)
+					options := volumedriver.DefaultOptions()
+					options.SkipPurge = true
+					volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+				})
+
+				It("leaves the mount path root alone", func() {
+					Expect(volumeDriver.Drain(env).Err).To(BeEmpty())
+					Expect(fakeMounter.PurgeCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when the mount path root does not carry the ownership marker", func() {
+				BeforeEach(func() {
+					close(release)
+					fakeOs.StatStub = func(path string) (os.FileInfo, error) {
+						if filepath.Base(path) == volumedriver.OwnershipMarker {
+							return nil, os.ErrNotExist
+						}
+						return nil, nil
+					}
+				})
+
+				It("does not purge it", func() {
+					Expect(volumeDriver.Drain(env).Err).To(BeEmpty())
+					Expect(fakeMounter.UnmountCallCount()).To(Equal(2))
+					Expect(fakeMounter.PurgeCallCount()).To(Equal(0))
+				})
+			})
+		})
+
+		Describe("claiming the mount path root", func() {
+			var entries []os.FileInfo
+
+			BeforeEach(func() {
+				entries = nil
+				fakeFilepath.AbsReturns("/path/to/mount", nil)
+				fakeOs.StatReturns(nil, os.ErrNotExist)
+			})
+
+			JustBeforeEach(func() {
+				fakeIoutil.ReadDirStub = func(path string) ([]os.FileInfo, error) {
+					if path == "/path/to/mount" {
+						return entries, nil
+					}
+					return nil, nil
+				}
+				volumeDriver = volumedriver.NewVolumeDriver(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper())
+			})
+
+			markerWrites := func() int {
+				writes := 0
+				for i := 0; i < fakeIoutil.WriteFileCallCount(); i++ {
+					if path, _, _ := fakeIoutil.WriteFileArgsForCall(i); strings.Replace(path, `\`, "/", -1) == "/path/to/mount/"+volumedriver.OwnershipMarker {
+						writes++
+					}
+				}
+				return writes
+			}
+
+			It("marks an empty root as the driver's", func() {
+				Expect(markerWrites()).To(Equal(1))
+			})
+
+			Context("when the root holds the state of the driver", func() {
+				BeforeEach(func() {
+					stateDir := &ioutil_fake.FakeFileInfo{}
+					stateDir.NameReturns("driver-state.d")
+					entries = []os.FileInfo{stateDir}
+				})
+
+				It("marks it", func() {
+					Expect(markerWrites()).To(Equal(1))
+				})
+			})
+
+			Context("when the root holds the legacy state file and the lock file", func() {
+				BeforeEach(func() {
+					stateFile := &ioutil_fake.FakeFileInfo{}
+					stateFile.NameReturns("driver-state.json")
+					lockFile := &ioutil_fake.FakeFileInfo{}
+					lockFile.NameReturns(rootlock.FileName)
+					entries = []os.FileInfo{stateFile, lockFile}
+				})
+
+				It("marks it", func() {
+					Expect(markerWrites()).To(Equal(1))
+				})
+			})
+
+			Context("when the root was locked before the driver is constructed", func() {
+				var (
+					root string
+					lock *rootlock.Lock
+				)
+
+				BeforeEach(func() {
+					var err error
+					root, err = ioutil.TempDir("", "claim-roots")
+					Expect(err).NotTo(HaveOccurred())
+					lock, err = rootlock.Acquire(root)
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				AfterEach(func() {
+					Expect(lock.Release()).To(Succeed())
+					Expect(os.RemoveAll(root)).To(Succeed())
+				})
+
+				It("marks it", func() {
+					volumedriver.NewVolumeDriver(logger, &osshim.OsShim{}, &filepathshim.FilepathShim{}, &ioutilshim.IoutilShim{}, fakeTime, fakeMountChecker, root, fakeMounter, oshelper.NewOsHelper())
+					Expect(filepath.Join(root, volumedriver.OwnershipMarker)).To(BeAnExistingFile())
+				})
+			})
+
+			Context("when the root holds other data", func() {
+				BeforeEach(func() {
+					other := &ioutil_fake.FakeFileInfo{}
+					other.NameReturns("database")
+					entries = []os.FileInfo{other}
+				})
+
+				It("leaves it unmarked", func() {
+					Expect(markerWrites()).To(Equal(0))
+					Expect(logger.TestSink.LogMessages()).To(ContainElement("volumedriver-local.claim-roots.mount-path-root-not-owned"))
+				})
+			})
+		})
+
+		Describe("locking the mount path root", func() {
+			var root string
+
+			newDriver := func() *volumedriver.VolumeDriver {
+				options := volumedriver.DefaultOptions()
+				options.LockRoot = true
+				return volumedriver.NewVolumeDriverWithOptions(logger, &osshim.OsShim{}, &filepathshim.FilepathShim{}, &ioutilshim.IoutilShim{}, fakeTime, fakeMountChecker, root, fakeMounter, oshelper.NewOsHelper(), options)
+			}
+
+			BeforeEach(func() {
+				var err error
+				root, err = ioutil.TempDir("", "lock-root")
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			AfterEach(func() {
+				Expect(os.RemoveAll(root)).To(Succeed())
+			})
+
+			It("holds the lock until the driver is drained", func() {
+				volumeDriver = newDriver()
+				_, err := rootlock.Acquire(root)
+				Expect(err).To(BeAssignableToTypeOf(rootlock.HeldError{}))
+
+				Expect(volumeDriver.Drain(env).Err).To(BeEmpty())
+				lock, err := rootlock.Acquire(root)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(lock.Release()).To(Succeed())
+			})
+
+			It("refuses to start while another driver holds the lock", func() {
+				lock, err := rootlock.Acquire(root)
+				Expect(err).NotTo(HaveOccurred())
+				defer lock.Release()
+
+				Expect(func() { newDriver() }).To(Panic())
+				Expect(logger.TestSink.LogMessages()).To(ContainElement("volumedriver-local.lock-root.acquire-failed"))
+			})
+		})
+
+		Describe("Quotas", func() {
+			var quotas volumedriver.Quotas
+
+			JustBeforeEach(func() {
+				options := volumedriver.DefaultOptions()
+				options.Quotas = quotas
+				volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+				fakeFilepath.AbsReturns("/path/to/mount/", nil)
+			})
+
+			create := func(name string, source string) dockerdriver.ErrorResponse {
+				return volumeDriver.Create(env, dockerdriver.CreateRequest{Name: name, Opts: map[string]interface{}{"source": source}})
+			}
+
+			Context("when the volume quota is set", func() {
+				BeforeEach(func() {
+					quotas = volumedriver.Quotas{MaxVolumes: 2}
+				})
+
+				It("refuses to create more volumes", func() {
+					Expect(create("a", "server:/a").Err).To(BeEmpty())
+					Expect(create("b", "server:/b").Err).To(BeEmpty())
+					Expect(create("c", "server:/c").Err).To(Equal("quota exceeded: the volumes limit of 2 is reached"))
+					ExpectVolumeDoesNotExist(env, volumeDriver, "c")
+				})
+
+				It("still updates existing volumes", func() {
+					Expect(create("a", "server:/a").Err).To(BeEmpty())
+					Expect(create("b", "server:/b").Err).To(BeEmpty())
+					Expect(create("a", "server:/a2").Err).To(BeEmpty())
+				})
+			})
+
+			Context("when the mount quota is set", func() {
+				BeforeEach(func() {
+					quotas = volumedriver.Quotas{MaxMounts: 1}
+				})
+
+				It("refuses to mount more volumes", func() {
+					Expect(create("a", "server:/a").Err).To(BeEmpty())
+					Expect(create("b", "server:/b").Err).To(BeEmpty())
+					Expect(volumeDriver.Mount(env, dockerdriver.MountRequest{Name: "a"}).Err).To(BeEmpty())
+
+					mountResponse := volumeDriver.Mount(env, dockerdriver.MountRequest{Name: "b"})
+					Expect(mountResponse.Err).To(HavePrefix(volumedriver.QuotaExceededErrPrefix))
+					Expect(mountResponse.Err).To(Equal("quota exceeded: the mounts limit of 1 is reached"))
+					Expect(fakeMounter.MountCallCount()).To(Equal(1))
+				})
+
+				It("lets mounted volumes be mounted again", func() {
+					fakeMounter.CheckReturns(true)
+					Expect(create("a", "server:/a").Err).To(BeEmpty())
+					Expect(volumeDriver.Mount(env, dockerdriver.MountRequest{Name: "a"}).Err).To(BeEmpty())
+					Expect(volumeDriver.Mount(env, dockerdriver.MountRequest{Name: "a"}).Err).To(BeEmpty())
+				})
+
+				It("frees the quota when a volume is unmounted", func() {
+					Expect(create("a", "server:/a").Err).To(BeEmpty())
+					Expect(volumeDriver.Mount(env, dockerdriver.MountRequest{Name: "a"}).Err).To(BeEmpty())
+					Expect(volumeDriver.Unmount(env, dockerdriver.UnmountRequest{Name: "a"}).Err).To(BeEmpty())
+
+					Expect(create("b", "server:/b").Err).To(BeEmpty())
+					Expect(volumeDriver.Mount(env, dockerdriver.MountRequest{Name: "b"}).Err).To(BeEmpty())
+				})
+			})
+
+			Context("when the per source mount quota is set", func() {
+				BeforeEach(func() {
+					quotas = volumedriver.Quotas{MaxMountsPerSource: 1}
+				})
+
+				It("only refuses mounts of the same source", func() {
+					Expect(create("a", "server:/export").Err).To(BeEmpty())
+					Expect(create("b", "server:/export").Err).To(BeEmpty())
+					Expect(create("c", "server:/other").Err).To(BeEmpty())
+					Expect(volumeDriver.Mount(env, dockerdriver.MountRequest{Name: "a"}).Err).To(BeEmpty())
+
+					Expect(volumeDriver.Mount(env, dockerdriver.MountRequest{Name: "b"}).Err).To(Equal("quota exceeded: the mounts per source limit of 1 is reached"))
+					Expect(volumeDriver.Mount(env, dockerdriver.MountRequest{Name: "c"}).Err).To(BeEmpty())
+				})
+			})
+		})
+
+		Describe("nfs:// sources", func() {
+			create := func(opts map[string]interface{}) dockerdriver.ErrorResponse {
+				return volumeDriver.Create(env, dockerdriver.CreateRequest{Name: volumeName, Opts: opts})
+			}
+
+			mountedWith := func() (string, map[string]interface{}) {
+				fakeFilepath.AbsReturns("/path/to/mount/", nil)
+				Expect(volumeDriver.Mount(env, dockerdriver.MountRequest{Name: volumeName}).Err).To(BeEmpty())
+				_, from, _, opts := fakeMounter.MountArgsForCall(fakeMounter.MountCallCount() - 1)
+				return from, opts
+			}
+
+			It("mounts the export with the options of the query", func() {
+				Expect(create(map[string]interface{}{"source": "nfs://Filer.Example.com/export/a?vers=4.1&uid=1000&ro"}).Err).To(BeEmpty())
+
+				from, opts := mountedWith()
+				Expect(from).To(Equal("filer.example.com:/export/a"))
+				Expect(opts).To(Equal(hardened(map[string]interface{}{"vers": "4.1", "uid": "1000", "ro": true})))
+			})
+
+			It("prefers the opts over the query", func() {
+				Expect(create(map[string]interface{}{"source": "nfs://10.0.0.1:2050/?uid=1000", "uid": "2000"}).Err).To(BeEmpty())
+
+				from, opts := mountedWith()
+				Expect(from).To(Equal("10.0.0.1:/"))
+				Expect(opts).To(Equal(hardened(map[string]interface{}{"uid": "2000", "port": "2050"})))
+			})
+
+			It("brackets IPv6 servers", func() {
+				Expect(create(map[string]interface{}{"source": "nfs://[fd00::1]/export"}).Err).To(BeEmpty())
+
+				from, _ := mountedWith()
+				Expect(from).To(Equal("[fd00::1]:/export"))
+			})
+
+			It("rejects invalid sources", func() {
+				Expect(create(map[string]interface{}{"source": "nfs:///export"}).Err).To(Equal("invalid source 'nfs:///export', must be of the form nfs://host/export?opt=value"))
+				Expect(create(map[string]interface{}{"source": "nfs://host/export?uid=1&uid=2"}).Err).To(Equal("invalid option 'uid' in source 'nfs://host/export?uid=1&uid=2'"))
+				Expect(create(map[string]interface{}{"source": "nfs://host/export?source=other:/"}).Err).To(Equal("invalid option 'source' in source 'nfs://host/export?source=other:/'"))
+				ExpectVolumeDoesNotExist(env, volumeDriver, volumeName)
+			})
+
+			It("validates the driver opts of the query", func() {
+				Expect(create(map[string]interface{}{"source": "nfs://host/export?ttl=never"}).Err).To(ContainSubstring("invalid ttl 'never'"))
+			})
+
+			Context("when a mounter is registered for the scheme", func() {
+				var nfsMounter *volumedriverfakes.FakeMounter
+
+				BeforeEach(func() {
+					nfsMounter = &volumedriverfakes.FakeMounter{}
+					options := volumedriver.DefaultOptions()
+					options.Mounters = map[string]volumedriver.Mounter{"nfs": nfsMounter}
+					volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+				})
+
+				It("leaves the source to that mounter", func() {
+					Expect(create(map[string]interface{}{"source": "nfs://host/export?vers=4.1"}).Err).To(BeEmpty())
+
+					fakeFilepath.AbsReturns("/path/to/mount/", nil)
+					Expect(volumeDriver.Mount(env, dockerdriver.MountRequest{Name: volumeName}).Err).To(BeEmpty())
+					_, from, _, _ := nfsMounter.MountArgsForCall(0)
+					Expect(from).To(Equal("nfs://host/export?vers=4.1"))
+				})
+			})
+		})
+
+		Describe("AllowedSources", func() {
+			JustBeforeEach(func() {
+				options := volumedriver.DefaultOptions()
+				options.AllowedSources = []volumedriver.SourceRule{
+					{Host: "*.nfs.example.com", Export: "/tenants/*"},
+					{Host: "10.0.0.0/8"},
+					{Host: "fd00::/8"},
+				}
+				volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+			})
+
+			create := func(source string) dockerdriver.ErrorResponse {
+				return volumeDriver.Create(env, dockerdriver.CreateRequest{Name: "volume", Opts: map[string]interface{}{"source": source}})
+			}
+
+			It("creates volumes of allowed sources", func() {
+				Expect(create("filer.nfs.example.com:/tenants/a").Err).To(BeEmpty())
+				Expect(create("nfs://filer.nfs.example.com/tenants/b/").Err).To(BeEmpty())
+				Expect(create("10.1.2.3:/anything").Err).To(BeEmpty())
+				Expect(create("[fd00::1]:/export").Err).To(BeEmpty())
+				Expect(create("[fd00::1%eth0]:/export").Err).To(BeEmpty())
+			})
+
+			It("refuses sources that no rule allows", func() {
+				Expect(create("filer.internal:/tenants/a").Err).To(Equal("source 'filer.internal:/tenants/a' is not allowed on this cell"))
+				Expect(create("filer.nfs.example.com:/admin").Err).To(Equal("source 'filer.nfs.example.com:/admin' is not allowed on this cell"))
+				Expect(create("filer.nfs.example.com:/tenants/a/../../admin").Err).NotTo(BeEmpty())
+				Expect(create("192.168.0.1:/export").Err).NotTo(BeEmpty())
+				ExpectVolumeDoesNotExist(env, volumeDriver, "volume")
+			})
+
+			It("refuses to import mounts of sources that no rule allows", func() {
+				response := volumeDriver.ImportMount(env, volumedriver.ImportMountRequest{
+					Name:       "volume",
+					Mountpoint: filepath.Join(mountDir, "volume"),
+					Opts:       map[string]interface{}{"source": "filer.internal:/export"},
+				})
+				Expect(response.Err).To(Equal("source 'filer.internal:/export' is not allowed on this cell"))
+				Expect(fakeMountChecker.ExistsCallCount()).To(Equal(0))
+			})
+		})
+
+		Describe("DiscoverExports", func() {
+			var fakeDiscoverer *volumedriverfakes.FakeExportDiscoverer
+			var options volumedriver.Options
+
+			BeforeEach(func() {
+				fakeDiscoverer = &volumedriverfakes.FakeExportDiscoverer{}
+				fakeDiscoverer.ExportsReturns([]volumedriver.Export{{Path: "/tenants/a"}, {Path: "/admin"}}, nil)
+				options = volumedriver.DefaultOptions()
+				options.ExportDiscoverer = fakeDiscoverer
+			})
+
+			JustBeforeEach(func() {
+				volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+			})
+
+			It("returns the exports of the server with their sources", func() {
+				response := volumeDriver.DiscoverExports(env, volumedriver.DiscoverExportsRequest{Server: "[fd00::1]"})
+				Expect(response.Err).To(BeEmpty())
+				Expect(response.Exports).To(Equal([]volumedriver.Export{
+					{Path: "/tenants/a", Source: "[fd00::1]:/tenants/a"},
+					{Path: "/admin", Source: "[fd00::1]:/admin"},
+				}))
+				_, server := fakeDiscoverer.ExportsArgsForCall(0)
+				Expect(server).To(Equal("fd00::1"))
+			})
+
+			It("refuses servers that are not host names or addresses", func() {
+				response := volumeDriver.DiscoverExports(env, volumedriver.DiscoverExportsRequest{Server: "-o evil"})
+				Expect(response.Err).To(Equal("invalid server '-o evil', must be a host name or an address"))
+				Expect(fakeDiscoverer.ExportsCallCount()).To(BeZero())
+			})
+
+			It("returns the discovery error", func() {
+				fakeDiscoverer.ExportsReturns(nil, errors.New("showmount failed"))
+				response := volumeDriver.DiscoverExports(env, volumedriver.DiscoverExportsRequest{Server: "filer"})
+				Expect(response.Err).To(Equal("Error discovering the exports of 'filer': showmount failed"))
+			})
+
+			Context("with allowed sources", func() {
+				BeforeEach(func() {
+					options.AllowedSources = []volumedriver.SourceRule{{Host: "*.nfs.example.com", Export: "/tenants/*"}}
+				})
+
+				It("only lists allowed exports", func() {
+					response := volumeDriver.DiscoverExports(env, volumedriver.DiscoverExportsRequest{Server: "filer.nfs.example.com"})
+					Expect(response.Err).To(BeEmpty())
+					Expect(response.Exports).To(Equal([]volumedriver.Export{{Path: "/tenants/a", Source: "filer.nfs.example.com:/tenants/a"}}))
+				})
+
+				It("refuses servers that no rule allows", func() {
+					response := volumeDriver.DiscoverExports(env, volumedriver.DiscoverExportsRequest{Server: "filer.internal"})
+					Expect(response.Err).To(Equal("server 'filer.internal' is not allowed on this cell"))
+					Expect(fakeDiscoverer.ExportsCallCount()).To(BeZero())
+				})
+			})
+
+			Context("without a discoverer", func() {
+				BeforeEach(func() {
+					options.ExportDiscoverer = nil
+				})
+
+				It("returns an error", func() {
+					response := volumeDriver.DiscoverExports(env, volumedriver.DiscoverExportsRequest{Server: "filer"})
+					Expect(response.Err).To(Equal("Export discovery is not supported by this driver"))
+				})
+			})
+		})
+
+		Describe("PersistDebounce", func() {
+			var recordWrites func() int
+
+			BeforeEach(func() {
+				options := volumedriver.DefaultOptions()
+				options.PersistDebounce = time.Hour
+				volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+
+				recordWrites = func() int {
+					writes := 0
+					for i := 0; i < fakeIoutil.WriteFileCallCount(); i++ {
+						file, _, _ := fakeIoutil.WriteFileArgsForCall(i)
+						if strings.HasSuffix(filepath.ToSlash(file), "driver-state.d/"+volumeName+".json.tmp") {
+							writes++
+						}
+					}
+					return writes
+				}
+
+				setupVolume(env, volumeDriver, volumeName, ip)
+				setupMount(env, volumeDriver, volumeName, fakeFilepath)
+				setupMount(env, volumeDriver, volumeName, fakeFilepath)
+			})
+
+			AfterEach(func() {
+				volumeDriver.Drain(env)
+			})
+
+			It("writes mounts at once", func() {
+				// create and two mounts; the outcome of the first is debounced
+				Expect(recordWrites()).To(Equal(3))
+			})
+
+			It("debounces unmounts that keep the volume mounted until the state is flushed", func() {
+				writes := recordWrites()
+				Expect(volumeDriver.Unmount(env, dockerdriver.UnmountRequest{Name: volumeName}).Err).To(BeEmpty())
+				Expect(recordWrites()).To(Equal(writes))
+
+				volumeDriver.FlushState(env)
+				Expect(recordWrites()).To(Equal(writes + 1))
+
+				volumeDriver.FlushState(env)
+				Expect(recordWrites()).To(Equal(writes + 1))
+			})
+
+			It("removes the record of the last unmount at once", func() {
+				Expect(volumeDriver.Unmount(env, dockerdriver.UnmountRequest{Name: volumeName}).Err).To(BeEmpty())
+				Expect(volumeDriver.Unmount(env, dockerdriver.UnmountRequest{Name: volumeName}).Err).To(BeEmpty())
+
+				Expect(fakeOs.RemoveArgsForCall(fakeOs.RemoveCallCount() - 1)).To(HaveSuffix(volumeName + ".json"))
+
+				writes := recordWrites()
+				volumeDriver.FlushState(env)
+				Expect(recordWrites()).To(Equal(writes))
+			})
+		})
+
+		Describe("PersistRetryInterval", func() {
+			var (
+				fakeEmitter *volumedriverfakes.FakeEmitter
+				now         time.Time
+				failWrites  bool
+			)
+
+			gauge := func(name string) float64 {
+				value := -1.0
+				for i := 0; i < fakeEmitter.GaugeCallCount(); i++ {
+					gaugeName, gaugeValue, _ := fakeEmitter.GaugeArgsForCall(i)
+					if gaugeName == name {
+						value = gaugeValue
+					}
+				}
+				return value
+			}
+
+			BeforeEach(func() {
+				now = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+				fakeTime.NowReturns(now)
+				fakeEmitter = &volumedriverfakes.FakeEmitter{}
+
+				options := volumedriver.DefaultOptions()
+				options.PersistRetryInterval = time.Minute
+				options.MetricsEmitter = fakeEmitter
+				volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+
+				setupVolume(env, volumeDriver, volumeName, ip)
+
+				// fail the writes of the volume record, not those of the
+				// mount intents
+				failWrites = true
+				fakeIoutil.WriteFileStub = func(file string, data []byte, perm os.FileMode) error {
+					if failWrites && strings.HasSuffix(filepath.ToSlash(file), "driver-state.d/"+volumeName+".json.tmp") {
+						return errors.New("no space left on device")
+					}
+					return nil
+				}
+			})
+
+			AfterEach(func() {
+				volumeDriver.Drain(env)
+			})
+
+			It("mounts and queues the failed state write", func() {
+				setupMount(env, volumeDriver, volumeName, fakeFilepath)
+
+				Expect(volumeDriver.PendingPersists()).To(Equal([]volumedriver.PendingPersist{{
+					Volume:       volumeName,
+					Attempts:     1,
+					FailingSince: now,
+					NextRetry:    now.Add(time.Minute),
+					LastError:    "no space left on device",
+				}}))
+				Expect(gauge(metrics.PersistPending)).To(Equal(1.0))
+			})
+
+			It("retries with backoff until the write succeeds", func() {
+				setupMount(env, volumeDriver, volumeName, fakeFilepath)
+				writes := fakeIoutil.WriteFileCallCount()
+
+				volumeDriver.RetryStateWrites(env)
+				Expect(fakeIoutil.WriteFileCallCount()).To(Equal(writes))
+
+				fakeTime.NowReturns(now.Add(time.Minute))
+				volumeDriver.RetryStateWrites(env)
+				Expect(fakeIoutil.WriteFileCallCount()).To(Equal(writes + 1))
+				Expect(volumeDriver.PendingPersists()[0].NextRetry).To(Equal(now.Add(3 * time.Minute)))
+				Expect(gauge(metrics.PersistFailingSeconds)).To(Equal(time.Minute.Seconds()))
+
+				failWrites = false
+				fakeTime.NowReturns(now.Add(3 * time.Minute))
+				volumeDriver.RetryStateWrites(env)
+				Expect(volumeDriver.PendingPersists()).To(BeEmpty())
+				Expect(gauge(metrics.PersistPending)).To(Equal(0.0))
+
+				name, _, tags := fakeEmitter.CountArgsForCall(fakeEmitter.CountCallCount() - 1)
+				Expect(name).To(Equal(metrics.PersistRetries))
+				Expect(tags).To(ConsistOf(metrics.OutcomeTag(nil)))
+			})
+
+			It("reports the pending writes in the debug state", func() {
+				setupMount(env, volumeDriver, volumeName, fakeFilepath)
+				Expect(volumeDriver.DebugState(env).PendingPersists).To(HaveLen(1))
+			})
+
+			It("drops the queued write once the volume is removed", func() {
+				setupMount(env, volumeDriver, volumeName, fakeFilepath)
+				Expect(volumeDriver.Unmount(env, dockerdriver.UnmountRequest{Name: volumeName}).Err).To(BeEmpty())
+				Expect(volumeDriver.PendingPersists()).To(BeEmpty())
+			})
+		})
+
+		Describe("mount hardening", func() {
+			var opts map[string]interface{}
+
+			BeforeEach(func() {
+				opts = map[string]interface{}{"source": ip, "suid": true, "dev": true, "exec": true, "ro": true}
+			})
+
+			JustBeforeEach(func() {
+				createResponse := volumeDriver.Create(env, dockerdriver.CreateRequest{Name: volumeName, Opts: opts})
+				Expect(createResponse.Err).To(Equal(""))
+				setupMount(env, volumeDriver, volumeName, fakeFilepath)
+			})
+
+			It("strips the opts that relax the hardening and adds nosuid, nodev and noexec", func() {
+				_, _, _, mountOpts := fakeMounter.MountArgsForCall(0)
+				Expect(mountOpts).To(Equal(hardened(map[string]interface{}{"ro": true})))
+			})
+
+			Context("when the policy exempts a hardening opt", func() {
+				BeforeEach(func() {
+					Expect(volumeDriver.UpdatePolicy(env, volumedriver.Policy{HardeningExemptions: []string{"noexec"}})).To(Succeed())
+				})
+
+				It("leaves it to the opts of the volume", func() {
+					_, _, _, mountOpts := fakeMounter.MountArgsForCall(0)
+					Expect(mountOpts).To(Equal(map[string]interface{}{"ro": true, "exec": true, "nosuid": true, "nodev": true}))
+				})
+			})
+		})
+
+		Describe("mount hardening with a mounter that refuses unknown opts", func() {
+			var fakeInvoker *invokerfakes.FakeInvoker
+
+			BeforeEach(func() {
+				fakeInvoker = &invokerfakes.FakeInvoker{}
+				fakeInvoker.InvokeReturns(&invokerfakes.FakeInvokeResult{})
+
+				mounterOs := &os_fake.FakeOs{}
+				dirInfo := &ioutil_fake.FakeFileInfo{}
+				dirInfo.IsDirReturns(true)
+				mounterOs.StatReturns(dirInfo, nil)
+
+				mounter := bindmounter.NewBindMounter(fakeInvoker, mounterOs, &ioutil_fake.FakeIoutil{}, fakeMountChecker)
+				volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, mounter, oshelper.NewOsHelper(), volumedriver.DefaultOptions())
+
+				setupVolume(env, volumeDriver, volumeName, "/var/vcap/data/scratch")
+			})
+
+			It("mounts with the hardening opts translated by the mounter", func() {
+				setupMount(env, volumeDriver, volumeName, fakeFilepath)
+
+				Expect(fakeInvoker.InvokeCallCount()).To(Equal(1))
+				_, cmd, args, _ := fakeInvoker.InvokeArgsForCall(0)
+				Expect(cmd).To(Equal("mount"))
+				Expect(args[:2]).To(Equal([]string{"-o", "bind,nosuid,nodev,noexec"}))
+			})
+		})
+
+		Describe("UpdatePolicy", func() {
+			It("applies the policy to later requests", func() {
+				setupVolume(env, volumeDriver, "first", ip)
+
+				Expect(volumeDriver.UpdatePolicy(env, volumedriver.Policy{Quotas: volumedriver.Quotas{MaxVolumes: 1}})).To(Succeed())
+
+				createResponse := volumeDriver.Create(env, dockerdriver.CreateRequest{Name: "second", Opts: map[string]interface{}{"source": ip}})
+				Expect(createResponse.Err).To(Equal("quota exceeded: the volumes limit of 1 is reached"))
+			})
+
+			It("refuses an invalid policy and keeps the current one", func() {
+				err := volumeDriver.UpdatePolicy(env, volumedriver.Policy{
+					Quotas:     volumedriver.Quotas{MaxVolumes: 1},
+					CheckDepth: "deep",
+				})
+				Expect(err).To(HaveOccurred())
+
+				setupVolume(env, volumeDriver, "first", ip)
+				setupVolume(env, volumeDriver, "second", ip)
+			})
+
+			It("refuses exemptions of unknown hardening opts", func() {
+				err := volumeDriver.UpdatePolicy(env, volumedriver.Policy{HardeningExemptions: []string{"nosymfollow"}})
+				Expect(err).To(MatchError("invalid hardening exemption 'nosymfollow', must be one of nosuid, nodev or noexec"))
+			})
+		})
+
+		Describe("MountPathRoots", func() {
+			var (
+				fakeDiskSpace *volumedriverfakes.FakeDiskSpace
+				options       volumedriver.Options
+			)
+
+			BeforeEach(func() {
+				fakeFilepath.AbsStub = func(path string) (string, error) {
+					return path, nil
+				}
+				fakeDiskSpace = &volumedriverfakes.FakeDiskSpace{}
+				fakeDiskSpace.FreeStub = func(root string) (uint64, error) {
+					return map[string]uint64{mountDir: 10, "/data/b": 30, "/data/c": 20}[root], nil
+				}
+				options = volumedriver.DefaultOptions()
+				options.MountPathRoots = []string{"/data/b", "/data/c"}
+				options.DiskSpace = fakeDiskSpace
+			})
+
+			JustBeforeEach(func() {
+				volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+			})
+
+			It("places new volumes on the root with the most free space", func() {
+				setupVolume(env, volumeDriver, volumeName, ip)
+
+				getResponse := volumeDriver.Get(env, dockerdriver.GetRequest{Name: volumeName})
+				Expect(getResponse.Volume.Mountpoint).To(Equal("/data/b/" + volumeName))
+
+				stateFile, _, _ := fakeIoutil.WriteFileArgsForCall(fakeIoutil.WriteFileCallCount() - 1)
+				Expect(stateFile).To(HavePrefix("/data/b/driver-state.d/"))
+			})
+
+			It("mounts the volume and records its intent on its root", func() {
+				setupVolume(env, volumeDriver, volumeName, ip)
+				mountResponse := volumeDriver.Mount(env, dockerdriver.MountRequest{Name: volumeName})
+				Expect(mountResponse.Err).To(BeEmpty())
+				Expect(mountResponse.Mountpoint).To(Equal("/data/b/" + volumeName))
+
+				intentFile := "/data/b/driver-state.d/" + volumeName + ".intent"
+				written := []string{}
+				for i := 0; i < fakeIoutil.WriteFileCallCount(); i++ {
+					file, _, _ := fakeIoutil.WriteFileArgsForCall(i)
+					written = append(written, file)
+				}
+				Expect(written).To(ContainElement(intentFile))
+				Expect(fakeOs.RemoveArgsForCall(fakeOs.RemoveCallCount() - 1)).To(Equal(intentFile))
+			})
+
+			It("skips roots whose free space cannot be measured", func() {
+				fakeDiskSpace.FreeStub = func(root string) (uint64, error) {
+					if root == "/data/b" {
+						return 0, errors.New("no such device")
+					}
+					return map[string]uint64{mountDir: 10, "/data/c": 20}[root], nil
+				}
+				setupVolume(env, volumeDriver, volumeName, ip)
+
+				getResponse := volumeDriver.Get(env, dockerdriver.GetRequest{Name: volumeName})
+				Expect(getResponse.Volume.Mountpoint).To(Equal("/data/c/" + volumeName))
+			})
+
+			It("removes the state of a volume from its root", func() {
+				setupVolume(env, volumeDriver, volumeName, ip)
+				Expect(volumeDriver.Remove(env, dockerdriver.RemoveRequest{Name: volumeName}).Err).To(BeEmpty())
+
+				removed := []string{}
+				for i := 0; i < fakeOs.RemoveCallCount(); i++ {
+					removed = append(removed, fakeOs.RemoveArgsForCall(i))
+				}
+				Expect(removed).To(ContainElement(HavePrefix("/data/b/driver-state.d/")))
+			})
+
+			Context("with the fewest-volumes policy", func() {
+				BeforeEach(func() {
+					options.RootPolicy = volumedriver.RootFewestVolumes
+				})
+
+				It("spreads the volumes over the roots", func() {
+					for _, name := range []string{"a", "b", "c", "d"} {
+						setupVolume(env, volumeDriver, name, ip)
+					}
+
+					mountpoints := []string{}
+					for _, name := range []string{"a", "b", "c", "d"} {
+						mountpoints = append(mountpoints, volumeDriver.Get(env, dockerdriver.GetRequest{Name: name}).Volume.Mountpoint)
+					}
+					Expect(mountpoints).To(Equal([]string{mountDir + "/a", "/data/b/b", "/data/c/c", mountDir + "/d"}))
+					Expect(fakeDiskSpace.FreeCallCount()).To(BeZero())
+				})
+			})
+
+			Context("when the driver restarts", func() {
+				It("restores the volumes of every root", func() {
+					volumeFile := &ioutil_fake.FakeFileInfo{}
+					volumeFile.NameReturns("volume.json")
+					fakeIoutil.ReadDirStub = func(dir string) ([]os.FileInfo, error) {
+						if dir == "/data/c/driver-state.d" {
+							return []os.FileInfo{volumeFile}, nil
+						}
+						return nil, os.ErrNotExist
+					}
+					fakeIoutil.ReadFileStub = func(file string) ([]byte, error) {
+						if file == "/data/c/driver-state.d/volume.json" {
+							return []byte(`{"Name":"volume","MountDirectory":"volume","MountRoot":"/data/c"}`), nil
+						}
+						return nil, os.ErrNotExist
+					}
+					volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+
+					getResponse := volumeDriver.Get(env, dockerdriver.GetRequest{Name: "volume"})
+					Expect(getResponse.Err).To(BeEmpty())
+					Expect(getResponse.Volume.Mountpoint).To(Equal("/data/c/volume"))
+				})
+			})
+
+			It("imports mounts below any of the roots", func() {
+				fakeMounter.CheckReturns(true)
+				response := volumeDriver.ImportMount(env, volumedriver.ImportMountRequest{
+					Name:       "volume",
+					Mountpoint: "/data/c/volume",
+					Opts:       map[string]interface{}{"source": "server:/export"},
+				})
+				Expect(response.Err).To(BeEmpty())
+
+				stateFile, _, _ := fakeIoutil.WriteFileArgsForCall(fakeIoutil.WriteFileCallCount() - 1)
+				Expect(stateFile).To(HavePrefix("/data/c/driver-state.d/"))
+			})
+		})
+
+		Describe("IdleSince", func() {
+			It("is idle when nothing is mounted", func() {
+				setupVolume(env, volumeDriver, volumeName, ip)
+
+				_, idle := volumeDriver.IdleSince()
+				Expect(idle).To(BeTrue())
+			})
+
+			It("is not idle while a volume is mounted", func() {
+				setupVolume(env, volumeDriver, volumeName, ip)
+				setupMount(env, volumeDriver, volumeName, fakeFilepath)
+
+				_, idle := volumeDriver.IdleSince()
+				Expect(idle).To(BeFalse())
+			})
+
+			It("is idle from the last request once volumes are unmounted", func() {
+				setupVolume(env, volumeDriver, volumeName, ip)
+				setupMount(env, volumeDriver, volumeName, fakeFilepath)
+				before := time.Now()
+				Expect(volumeDriver.Unmount(env, dockerdriver.UnmountRequest{Name: volumeName}).Err).To(BeEmpty())
+
+				since, idle := volumeDriver.IdleSince()
+				Expect(idle).To(BeTrue())
+				Expect(since).To(BeTemporally(">=", before))
+			})
+		})
+
+		Describe("FreezeVolume", func() {
+			var fakeFreezer *volumedriverfakes.FakeFreezer
+
+			BeforeEach(func() {
+				fakeFreezer = &volumedriverfakes.FakeFreezer{}
+				options := volumedriver.DefaultOptions()
+				options.Freezer = fakeFreezer
+				volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+				setupVolume(env, volumeDriver, volumeName, ip)
+			})
+
+			Context("when the volume is mounted", func() {
+				BeforeEach(func() {
+					setupMount(env, volumeDriver, volumeName, fakeFilepath)
+				})
+
+				It("freezes and thaws the mountpoint", func() {
+					Expect(volumeDriver.FreezeVolume(env, volumedriver.FreezeVolumeRequest{Name: volumeName}).Err).To(BeEmpty())
+					Expect(fakeFreezer.FreezeCallCount()).To(Equal(1))
+					_, mountPoint := fakeFreezer.FreezeArgsForCall(0)
+					Expect(mountPoint).To(Equal(filepath.Join("/path/to/mount", volumeName)))
+					Expect(volumeDriver.DescribeVolume(env, volumedriver.DescribeVolumeRequest{Name: volumeName}).Volume.Frozen).To(BeTrue())
+
+					Expect(volumeDriver.ThawVolume(env, volumedriver.ThawVolumeRequest{Name: volumeName}).Err).To(BeEmpty())
+					Expect(fakeFreezer.ThawCallCount()).To(Equal(1))
+					_, mountPoint = fakeFreezer.ThawArgsForCall(0)
+					Expect(mountPoint).To(Equal(filepath.Join("/path/to/mount", volumeName)))
+					Expect(volumeDriver.DescribeVolume(env, volumedriver.DescribeVolumeRequest{Name: volumeName}).Volume.Frozen).To(BeFalse())
+				})
+
+				It("persists the frozen volume", func() {
+					Expect(volumeDriver.FreezeVolume(env, volumedriver.FreezeVolumeRequest{Name: volumeName}).Err).To(BeEmpty())
+					_, data, _ := fakeIoutil.WriteFileArgsForCall(fakeIoutil.WriteFileCallCount() - 1)
+					Expect(string(data)).To(ContainSubstring(`"Frozen":true`))
+				})
+
+				It("refuses to freeze a frozen volume again", func() {
+					Expect(volumeDriver.FreezeVolume(env, volumedriver.FreezeVolumeRequest{Name: volumeName}).Err).To(BeEmpty())
+					Expect(volumeDriver.FreezeVolume(env, volumedriver.FreezeVolumeRequest{Name: volumeName}).Err).To(Equal("Volume 'test-volume-id' is already frozen"))
+					Expect(fakeFreezer.FreezeCallCount()).To(Equal(1))
+				})
+
+				It("refuses to unmount or remove a frozen volume", func() {
+					Expect(volumeDriver.FreezeVolume(env, volumedriver.FreezeVolumeRequest{Name: volumeName}).Err).To(BeEmpty())
+
+					Expect(volumeDriver.Unmount(env, dockerdriver.UnmountRequest{Name: volumeName}).Err).To(Equal("Volume 'test-volume-id' is frozen, thaw it before unmounting"))
+					Expect(volumeDriver.Remove(env, dockerdriver.RemoveRequest{Name: volumeName}).Err).To(Equal("Volume 'test-volume-id' is frozen, thaw it before unmounting"))
+					Expect(fakeMounter.UnmountCallCount()).To(BeZero())
+				})
+
+				Context("when freezing fails", func() {
+					BeforeEach(func() {
+						fakeFreezer.FreezeReturns(errors.New("Operation not supported"))
+					})
+
+					It("returns the error and leaves the volume unfrozen", func() {
+						Expect(volumeDriver.FreezeVolume(env, volumedriver.FreezeVolumeRequest{Name: volumeName}).Err).To(Equal("Error freezing volume 'test-volume-id': Operation not supported"))
+						Expect(volumeDriver.DescribeVolume(env, volumedriver.DescribeVolumeRequest{Name: volumeName}).Volume.Frozen).To(BeFalse())
+						Expect(volumeDriver.Unmount(env, dockerdriver.UnmountRequest{Name: volumeName}).Err).To(BeEmpty())
+					})
+				})
+
+				Context("when thawing fails", func() {
+					BeforeEach(func() {
+						fakeFreezer.ThawReturns(errors.New("Invalid argument"))
+					})
+
+					It("returns the error and keeps the volume frozen", func() {
+						Expect(volumeDriver.FreezeVolume(env, volumedriver.FreezeVolumeRequest{Name: volumeName}).Err).To(BeEmpty())
+						Expect(volumeDriver.ThawVolume(env, volumedriver.ThawVolumeRequest{Name: volumeName}).Err).To(Equal("Error thawing volume 'test-volume-id': Invalid argument"))
+						Expect(volumeDriver.DescribeVolume(env, volumedriver.DescribeVolumeRequest{Name: volumeName}).Volume.Frozen).To(BeTrue())
+					})
+				})
+			})
+
+			It("refuses to freeze a volume that is not mounted", func() {
+				Expect(volumeDriver.FreezeVolume(env, volumedriver.FreezeVolumeRequest{Name: volumeName}).Err).To(Equal("Volume 'test-volume-id' is not mounted"))
+				Expect(fakeFreezer.FreezeCallCount()).To(BeZero())
+			})
+
+			It("refuses to thaw a volume that is not frozen", func() {
+				Expect(volumeDriver.ThawVolume(env, volumedriver.ThawVolumeRequest{Name: volumeName}).Err).To(Equal("Volume 'test-volume-id' is not frozen"))
+			})
+
+			It("returns an error for unknown volumes", func() {
+				Expect(volumeDriver.FreezeVolume(env, volumedriver.FreezeVolumeRequest{Name: "unknown"}).Err).To(Equal("Volume 'unknown' not found"))
+				Expect(volumeDriver.ThawVolume(env, volumedriver.ThawVolumeRequest{Name: "unknown"}).Err).To(Equal("Volume 'unknown' not found"))
+			})
+
+			Context("when no freezer is configured", func() {
+				BeforeEach(func() {
+					volumeDriver = volumedriver.NewVolumeDriver(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper())
+				})
+
+				It("rejects freeze requests", func() {
+					Expect(volumeDriver.FreezeVolume(env, volumedriver.FreezeVolumeRequest{Name: volumeName}).Err).To(Equal("Freeze is not supported by this driver"))
+				})
+			})
+		})
+
+		Describe("References", func() {
+			envOf := func(caller string) dockerdriver.Env {
+				return driverhttp.NewHttpDriverEnv(logger, volumedriver.WithCaller(ctx, caller))
+			}
+
+			references := func() map[string]int {
+				response := volumeDriver.DescribeVolume(env, volumedriver.DescribeVolumeRequest{Name: volumeName})
+				Expect(response.Err).To(BeEmpty())
+				return response.Volume.References
+			}
+
+			BeforeEach(func() {
+				setupVolume(env, volumeDriver, volumeName, ip)
+				fakeFilepath.AbsReturns("/path/to/mount/", nil)
+				for _, caller := range []string{"app-a", "app-a", "app-b", ""} {
+					Expect(volumeDriver.Mount(envOf(caller), dockerdriver.MountRequest{Name: volumeName}).Err).To(BeEmpty())
+				}
+			})
+
+			It("counts the mount references of every caller", func() {
+				Expect(references()).To(Equal(map[string]int{"app-a": 2, "app-b": 1}))
+			})
+
+			It("releases the reference of the caller on unmount", func() {
+				Expect(volumeDriver.Unmount(envOf("app-b"), dockerdriver.UnmountRequest{Name: volumeName}).Err).To(BeEmpty())
+				Expect(references()).To(Equal(map[string]int{"app-a": 2}))
+			})
+
+			It("releases unattributed references for unknown callers", func() {
+				Expect(volumeDriver.Unmount(env, dockerdriver.UnmountRequest{Name: volumeName}).Err).To(BeEmpty())
+				Expect(references()).To(Equal(map[string]int{"app-a": 2, "app-b": 1}))
+
+				Expect(volumeDriver.Unmount(env, dockerdriver.UnmountRequest{Name: volumeName}).Err).To(BeEmpty())
+				Expect(references()).To(Equal(map[string]int{"app-a": 1, "app-b": 1}))
+			})
+
+			It("persists the references", func() {
+				_, data, _ := fakeIoutil.WriteFileArgsForCall(fakeIoutil.WriteFileCallCount() - 1)
+				Expect(string(data)).To(ContainSubstring(`"References":{"app-a":2,"app-b":1}`))
+			})
+
+			Describe("RevokeReferences", func() {
+				It("releases every reference of the caller", func() {
+					response := volumeDriver.RevokeReferences(env, volumedriver.RevokeReferencesRequest{Caller: "app-a"})
+					Expect(response.Err).To(BeEmpty())
+					Expect(response.Revoked).To(Equal(2))
+					Expect(references()).To(Equal(map[string]int{"app-b": 1}))
+					Expect(fakeMounter.UnmountCallCount()).To(BeZero())
+				})
+
+				It("unmounts volumes whose last reference is revoked", func() {
+					Expect(volumeDriver.Unmount(env, dockerdriver.UnmountRequest{Name: volumeName}).Err).To(BeEmpty())
+					Expect(volumeDriver.RevokeReferences(env, volumedriver.RevokeReferencesRequest{Caller: "app-b"}).Revoked).To(Equal(1))
+
+					response := volumeDriver.RevokeReferences(env, volumedriver.RevokeReferencesRequest{Caller: "app-a", Name: volumeName})
+					Expect(response.Err).To(BeEmpty())
+					Expect(response.Revoked).To(Equal(2))
+					Expect(fakeMounter.UnmountCallCount()).To(Equal(1))
+					ExpectVolumeDoesNotExist(env, volumeDriver, volumeName)
+				})
 
-							Expect(getResponse.Err).To(Equal("Volume not found"))
-						})
-					})
+				It("requires a caller", func() {
+					Expect(volumeDriver.RevokeReferences(env, volumedriver.RevokeReferencesRequest{}).Err).To(Equal("Missing mandatory 'Caller'"))
+				})
+			})
+		})
 
-					Context("when the volume ref count is 1 but the mount does not exist", func() {
-						BeforeEach(func() {
-							fakeMountChecker.ExistsReturns(false, nil)
-						})
+		Describe("RemountVolume", func() {
+			var fakeRemounter *volumedriverfakes.FakeRemounter
 
-						It("deletes the mount directory", func() {
-							Expect(unmountResponse.Err).ToNot(BeEmpty())
-							Expect(fakeOs.RemoveCallCount()).To(Equal(1))
-							expectedPathToRemove := fakeOs.RemoveArgsForCall(0)
+			remount := func(opts map[string]interface{}) string {
+				return volumeDriver.RemountVolume(env, volumedriver.RemountVolumeRequest{Name: volumeName, Opts: opts}).Err
+			}
 
-							Expect(expectedPathToRemove).To(Equal("/path/to/mount/" + volumeName))
-						})
+			BeforeEach(func() {
+				fakeRemounter = &volumedriverfakes.FakeRemounter{}
+				options := volumedriver.DefaultOptions()
+				options.Remounter = fakeRemounter
+				volumeDriver = volumedriver.NewVolumeDriverWithOptions(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper(), options)
+				Expect(volumeDriver.Create(env, dockerdriver.CreateRequest{Name: volumeName, Opts: map[string]interface{}{"source": ip, "rw": true}}).Err).To(BeEmpty())
+			})
 
-						Context("when unable to remove the mount directory", func() {
-							BeforeEach(func() {
-								fakeOs.RemoveReturns(errors.New("Unable to remove"))
-							})
+			Context("when the volume is mounted", func() {
+				BeforeEach(func() {
+					setupMount(env, volumeDriver, volumeName, fakeFilepath)
+				})
 
-							It("returns an error", func() {
-								Expect(unmountResponse.Err).To(ContainSubstring("Volume test-volume-id does not exist (path: /path/to/mount/test-volume-id) and unable to remove mount directory"))
-							})
-						})
-					})
+				It("remounts the mountpoint with the opts", func() {
+					Expect(remount(map[string]interface{}{"ro": true})).To(BeEmpty())
+					Expect(fakeRemounter.RemountCallCount()).To(Equal(1))
+					_, mountPoint, opts := fakeRemounter.RemountArgsForCall(0)
+					Expect(mountPoint).To(Equal(filepath.Join("/path/to/mount", volumeName)))
+					Expect(opts).To(Equal(hardened(map[string]interface{}{"ro": true})))
+					Expect(fakeMounter.UnmountCallCount()).To(BeZero())
 				})
 
-				Context("when the volume has not been mounted", func() {
-					It("returns an error", func() {
-						unmountResponse := volumeDriver.Unmount(env, dockerdriver.UnmountRequest{
-							Name: volumeName,
-						})
+				It("keeps the opts for later mounts", func() {
+					Expect(remount(map[string]interface{}{"ro": true})).To(BeEmpty())
+					Expect(volumeDriver.DescribeVolume(env, volumedriver.DescribeVolumeRequest{Name: volumeName}).Volume.Opts).To(Equal(map[string]interface{}{"source": ip, "ro": true}))
 
-						Expect(unmountResponse.Err).To(Equal("Volume not previously mounted"))
-					})
+					_, data, _ := fakeIoutil.WriteFileArgsForCall(fakeIoutil.WriteFileCallCount() - 1)
+					Expect(string(data)).To(ContainSubstring(`"RemountOpts":{"ro":true}`))
 				})
-			})
-
-			Context("when the volume has not been created", func() {
-				It("returns an error", func() {
-					unmountResponse := volumeDriver.Unmount(env, dockerdriver.UnmountRequest{
-						Name: volumeName,
-					})
 
-					Expect(unmountResponse.Err).To(Equal(fmt.Sprintf("Volume '%s' not found", volumeName)))
+				It("applies the opts over the opts of a later create", func() {
+					Expect(remount(map[string]interface{}{"ro": true})).To(BeEmpty())
+					Expect(volumeDriver.Create(env, dockerdriver.CreateRequest{Name: volumeName, Opts: map[string]interface{}{"source": ip, "rw": true}}).Err).To(BeEmpty())
+					Expect(volumeDriver.DescribeVolume(env, volumedriver.DescribeVolumeRequest{Name: volumeName}).Volume.Opts).To(Equal(map[string]interface{}{"source": ip, "ro": true}))
 				})
-			})
-		})
 
-		Describe("Create", func() {
-			Context("when create is called with a volume ID", func() {
+				It("leaves secret opts out of the persisted state", func() {
+					Expect(remount(map[string]interface{}{"password": "s3cr3t"})).To(BeEmpty())
 
-				var createResponse dockerdriver.ErrorResponse
+					_, data, _ := fakeIoutil.WriteFileArgsForCall(fakeIoutil.WriteFileCallCount() - 1)
+					Expect(string(data)).NotTo(ContainSubstring("s3cr3t"))
+					Expect(string(data)).To(ContainSubstring(`"DroppedOpts":["password"]`))
+				})
 
-				JustBeforeEach(func() {
-					opts := map[string]interface{}{"source": ip}
-					createResponse = volumeDriver.Create(env, dockerdriver.CreateRequest{
-						Name: volumeName,
-						Opts: opts,
-					})
+				It("normalizes the common opts", func() {
+					Expect(remount(map[string]interface{}{"ro": "true"})).To(BeEmpty())
+					_, _, opts := fakeRemounter.RemountArgsForCall(0)
+					Expect(opts).To(Equal(hardened(map[string]interface{}{"ro": true})))
 				})
 
-				It("should write state, but omit Opts for security", func() {
-					Expect(fakeIoutil.WriteFileCallCount()).To(Equal(1))
+				It("refuses invalid opts", func() {
+					Expect(remount(map[string]interface{}{"vers": map[string]interface{}{"a": "b"}})).To(Equal("invalid opt 'vers', must be a string, number or boolean"))
+					Expect(remount(map[string]interface{}{"ro": "maybe"})).To(Equal("invalid ro 'maybe', must be true or false"))
+					Expect(fakeRemounter.RemountCallCount()).To(BeZero())
+				})
 
-					_, data, _ := fakeIoutil.WriteFileArgsForCall(0)
-					Expect(data).To(ContainSubstring("\"Name\":\"" + volumeName + "\""))
-					Expect(data).NotTo(ContainSubstring("\"Opts\""))
+				It("refuses to change the source or driver opts", func() {
+					Expect(remount(map[string]interface{}{"source": "other:/export"})).To(Equal("the source opt cannot be changed by a remount"))
+					Expect(remount(map[string]interface{}{"subdir": "a"})).To(Equal("the subdir opt cannot be changed by a remount"))
+					Expect(fakeRemounter.RemountCallCount()).To(BeZero())
 				})
 
-				Context("when the file system cant be written to", func() {
+				Context("when the remount fails", func() {
 					BeforeEach(func() {
-						fakeIoutil.WriteFileReturns(errors.New("badness"))
+						fakeRemounter.RemountReturns(errors.New("an incorrect mount option was specified"))
 					})
 
-					It("returns an error in the response", func() {
-						Expect(createResponse.Err).To(Equal("persist state failed when creating: badness"))
+					It("returns the error and keeps the opts", func() {
+						Expect(remount(map[string]interface{}{"vers": "3"})).To(Equal("Error remounting volume 'test-volume-id': an incorrect mount option was specified"))
+						Expect(volumeDriver.DescribeVolume(env, volumedriver.DescribeVolumeRequest{Name: volumeName}).Volume.Opts).NotTo(HaveKey("vers"))
 					})
 				})
 			})
 
-			Context("when a second create is called with the same volume ID", func() {
-				BeforeEach(func() {
-					setupVolume(env, volumeDriver, "volume", ip)
-				})
-
-				Context("with the same opts", func() {
-					It("does nothing", func() {
-						setupVolume(env, volumeDriver, "volume", ip)
-					})
-				})
+			It("refuses to remount a volume that is not mounted", func() {
+				Expect(remount(map[string]interface{}{"ro": true})).To(Equal("Volume 'test-volume-id' is not mounted"))
+				Expect(fakeRemounter.RemountCallCount()).To(BeZero())
 			})
-		})
 
-		Describe("Get", func() {
-			Context("when the volume has been created", func() {
-				It("returns the volume name", func() {
-					volumeName := "test-volume"
-					setupVolume(env, volumeDriver, volumeName, ip)
-					ExpectVolumeExists(env, volumeDriver, volumeName)
+			Context("when no remounter is configured", func() {
+				BeforeEach(func() {
+					volumeDriver = volumedriver.NewVolumeDriver(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper())
 				})
-			})
 
-			Context("when the volume has not been created", func() {
-				It("returns an error", func() {
-					volumeName := "test-volume"
-					ExpectVolumeDoesNotExist(env, volumeDriver, volumeName)
+				It("rejects remount requests", func() {
+					Expect(remount(map[string]interface{}{"ro": true})).To(Equal("Remount is not supported by this driver"))
 				})
 			})
 		})
@@ -554,6 +4464,65 @@ var _ = Describe("Nfs Driver", func() {
 					ExpectVolumeDoesNotExist(env, volumeDriver, volumeName)
 				})
 			})
+
+			Context("while an unmount holds the volume lock", func() {
+				var (
+					volumeName string
+					release    chan struct{}
+					unmounted  chan dockerdriver.ErrorResponse
+				)
+
+				BeforeEach(func() {
+					volumeName = "test-volume-id"
+					setupVolume(env, volumeDriver, volumeName, ip)
+					setupMount(env, volumeDriver, volumeName, fakeFilepath)
+
+					release = make(chan struct{})
+					unmount := fakeMounter.UnmountStub
+					fakeMounter.UnmountStub = func(env dockerdriver.Env, target string) error {
+						<-release
+						return unmount(env, target)
+					}
+
+					unmounted = make(chan dockerdriver.ErrorResponse)
+					go func() {
+						unmounted <- volumeDriver.Unmount(env, dockerdriver.UnmountRequest{Name: volumeName})
+					}()
+					Eventually(fakeMounter.UnmountCallCount).Should(Equal(1))
+				})
+
+				AfterEach(func() {
+					if release != nil {
+						close(release)
+						Eventually(unmounted).Should(Receive(Equal(dockerdriver.ErrorResponse{})))
+					}
+				})
+
+				It("serves List, Get and Path from the last published state without waiting", func() {
+					listResponse := volumeDriver.List(env)
+					Expect(listResponse.Volumes).To(HaveLen(1))
+					Expect(listResponse.Volumes[0].MountCount).To(Equal(1))
+
+					getResponse := volumeDriver.Get(env, dockerdriver.GetRequest{Name: volumeName})
+					Expect(getResponse.Err).To(BeEmpty())
+					Expect(getResponse.Volume.MountCount).To(Equal(1))
+
+					pathResponse := volumeDriver.Path(env, dockerdriver.PathRequest{Name: volumeName})
+					Expect(pathResponse.Err).To(BeEmpty())
+					Expect(pathResponse.Mountpoint).NotTo(BeEmpty())
+				})
+
+				It("publishes the unmount once the lock is released", func() {
+					close(release)
+					Eventually(unmounted).Should(Receive(Equal(dockerdriver.ErrorResponse{})))
+					release = nil
+
+					// the last unmount removes the volume
+					Expect(volumeDriver.List(env).Volumes).To(BeEmpty())
+					getResponse := volumeDriver.Get(env, dockerdriver.GetRequest{Name: volumeName})
+					Expect(getResponse.Err).To(Equal("Volume not found"))
+				})
+			})
 		})
 
 		Describe("Remove", func() {
@@ -591,15 +4560,15 @@ var _ = Describe("Nfs Driver", func() {
 					Expect(fakeMounter.UnmountCallCount()).To(Equal(0))
 				})
 
-				It("should write state to disk", func() {
-					// 1 create
-					// 2 remove
-					Expect(fakeIoutil.WriteFileCallCount()).To(Equal(2))
+				It("should remove the volume state from disk", func() {
+					Expect(fakeIoutil.WriteFileCallCount()).To(Equal(1))
+					Expect(fakeOs.RemoveCallCount()).To(Equal(1))
+					Expect(fakeOs.RemoveArgsForCall(0)).To(HaveSuffix("driver-state.d/" + volumeName + ".json"))
 				})
 
-				Context("when writing state to disk fails", func() {
+				Context("when removing the volume state from disk fails", func() {
 					BeforeEach(func() {
-						fakeIoutil.WriteFileReturns(errors.New("badness"))
+						fakeOs.RemoveReturns(errors.New("badness"))
 					})
 
 					It("should return an error response", func() {
@@ -607,17 +4576,123 @@ var _ = Describe("Nfs Driver", func() {
 					})
 				})
 
+				Context("when its mount failed", func() {
+					BeforeEach(func() {
+						fakeMounter.MountReturns(errors.New("access denied"))
+						fakeMountChecker.ExistsReturns(false, nil)
+						fakeFilepath.AbsReturns("/path/to/mount/", nil)
+						Expect(volumeDriver.Mount(env, dockerdriver.MountRequest{Name: volumeName}).Err).To(Equal("access denied"))
+					})
+
+					It("removes the volume, since docker sends no unmount for the failed mount", func() {
+						Expect(removeResponse.Err).To(BeEmpty())
+						Expect(fakeMounter.UnmountCallCount()).To(BeZero())
+						ExpectVolumeDoesNotExist(env, volumeDriver, volumeName)
+					})
+				})
+
+				Context("when a mount is in progress", func() {
+					var (
+						released chan struct{}
+						mounted  chan dockerdriver.MountResponse
+					)
+
+					BeforeEach(func() {
+						mounting := make(chan struct{})
+						release := make(chan struct{})
+						fakeMounter.MountStub = func(dockerdriver.Env, string, string, map[string]interface{}) error {
+							close(mounting)
+							<-release
+							return nil
+						}
+						fakeFilepath.AbsReturns("/path/to/mount/", nil)
+
+						mounted = make(chan dockerdriver.MountResponse, 1)
+						go func() {
+							mounted <- volumeDriver.Mount(env, dockerdriver.MountRequest{Name: volumeName})
+						}()
+						<-mounting
+
+						released = make(chan struct{})
+						go func() {
+							time.Sleep(100 * time.Millisecond)
+							close(released)
+							close(release)
+						}()
+					})
+
+					It("waits for it and refuses to remove the mounted volume", func() {
+						Expect(released).To(BeClosed())
+						Expect(removeResponse.Err).To(ContainSubstring("is in use by 1 mounts"))
+						Expect(fakeMounter.UnmountCallCount()).To(BeZero())
+						Eventually(mounted).Should(Receive())
+						ExpectVolumeExists(env, volumeDriver, volumeName)
+					})
+				})
+
 				Context("when volume has been mounted", func() {
 					BeforeEach(func() {
 						setupMount(env, volumeDriver, volumeName, fakeFilepath)
-						fakeMounter.UnmountReturns(nil)
 					})
 
-					It("/VolumePlugin.Remove unmounts volume", func() {
-						Expect(removeResponse.Err).To(Equal(""))
-						Expect(fakeMounter.UnmountCallCount()).To(Equal(1))
+					It("refuses to remove the volume while it is in use", func() {
+						Expect(removeResponse.Err).To(MatchJSON(`{"SafeDescription": "Volume '` + volumeName + `' is in use by 1 mounts, unmount it first or remove it with force", "Code": "in-use"}`))
+						Expect(fakeMounter.UnmountCallCount()).To(BeZero())
 
-						ExpectVolumeDoesNotExist(env, volumeDriver, volumeName)
+						ExpectVolumeExists(env, volumeDriver, volumeName)
+					})
+
+					Context("when it is removed with force", func() {
+						var lazyMounter *lazyFakeMounter
+
+						BeforeEach(func() {
+							setupMount(env, volumeDriver, volumeName, fakeFilepath)
+							fakeMountChecker.ExistsReturns(true, nil)
+							fakeMounter.UnmountReturns(errors.New("device or resource busy"))
+						})
+
+						JustBeforeEach(func() {
+							removeResponse = volumeDriver.RemoveVolume(env, volumedriver.RemoveVolumeRequest{Name: volumeName, Force: true})
+						})
+
+						It("fails when the mounter cannot detach the busy mount", func() {
+							Expect(removeResponse.Err).To(ContainSubstring("and the mounter cannot detach busy mounts"))
+							ExpectVolumeExists(env, volumeDriver, volumeName)
+						})
+
+						Context("when the mounter can detach busy mounts", func() {
+							BeforeEach(func() {
+								lazyMounter = &lazyFakeMounter{FakeMounter: fakeMounter}
+								volumeDriver = volumedriver.NewVolumeDriver(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, lazyMounter, oshelper.NewOsHelper())
+								setupVolume(env, volumeDriver, volumeName, ip)
+								setupMount(env, volumeDriver, volumeName, fakeFilepath)
+								setupMount(env, volumeDriver, volumeName, fakeFilepath)
+								fakeMountChecker.ExistsStub = func(string) (bool, error) {
+									return len(lazyMounter.lazyUnmounted) == 0, nil
+								}
+							})
+
+							It("detaches the mount and drops the references", func() {
+								Expect(removeResponse.Err).To(BeEmpty())
+								Expect(lazyMounter.lazyUnmounted).To(ConsistOf("/path/to/mount/" + volumeName))
+								ExpectVolumeDoesNotExist(env, volumeDriver, volumeName)
+							})
+						})
+
+						Context("when the unmount succeeds", func() {
+							BeforeEach(func() {
+								fakeMounter.UnmountReturns(nil)
+								fakeMountChecker.ExistsStub = func(string) (bool, error) {
+									return fakeMounter.UnmountCallCount() == 0, nil
+								}
+							})
+
+							It("removes the volume", func() {
+								Expect(removeResponse.Err).To(BeEmpty())
+								Expect(fakeMounter.UnmountCallCount()).To(Equal(1))
+								ExpectVolumeDoesNotExist(env, volumeDriver, volumeName)
+							})
+						})
 					})
 				})
 			})
@@ -678,9 +4753,19 @@ var _ = Describe("Nfs Driver", func() {
 					}))
 				})
 
+				It("migrates the legacy state to one record per volume", func() {
+					Expect(fakeIoutil.WriteFileCallCount()).To(Equal(1))
+					stateFile, data, _ := fakeIoutil.WriteFileArgsForCall(0)
+					Expect(stateFile).To(HaveSuffix("driver-state.d/some-volume-name.json.tmp"))
+					Expect(string(data)).To(ContainSubstring(`"StateVersion":1`))
+
+					Expect(fakeOs.RemoveCallCount()).To(Equal(1))
+					Expect(fakeOs.RemoveArgsForCall(0)).To(Equal("/path/to/mount/driver-state.json"))
+				})
+
 				Context("when the mounts are not present", func() {
 					It("only returns the volumes that are present on disk", func() {
-						removeResult := volumeDriver.Remove(env, dockerdriver.RemoveRequest{Name: "some-volume-name"})
+						removeResult := volumeDriver.RemoveVolume(env, volumedriver.RemoveVolumeRequest{Name: "some-volume-name", Force: true})
 						Expect(removeResult.Err).To(BeEmpty())
 
 						Expect(volumeDriver.List(env)).To(Equal(dockerdriver.ListResponse{
@@ -689,6 +4774,18 @@ var _ = Describe("Nfs Driver", func() {
 					})
 				})
 
+				Context("when a legacy record only has its name as key", func() {
+					BeforeEach(func() {
+						fakeIoutil.ReadFileReturns([]byte(`{"old-volume": {"Mountpoint": "/some/mount/point"}}`), nil)
+					})
+
+					It("restores the volume under its key", func() {
+						Expect(volumeDriver.List(env).Volumes).To(Equal([]dockerdriver.VolumeInfo{
+							{Name: "old-volume", Mountpoint: "/some/mount/point"},
+						}))
+					})
+				})
+
 				Context("when the state is corrupted", func() {
 					BeforeEach(func() {
 						fakeIoutil.ReadFileReturns([]byte("I have eleven toes."), nil)
@@ -700,6 +4797,177 @@ var _ = Describe("Nfs Driver", func() {
 					})
 				})
 			})
+
+			Context("when per-volume state is persisted", func() {
+				var volumeData []byte
+
+				BeforeEach(func() {
+					var err error
+					volumeData, err = json.Marshal(volumedriver.NfsVolumeInfo{
+						VolumeInfo: dockerdriver.VolumeInfo{
+							Name:       "some/volume",
+							Mountpoint: "/some/mount/point",
+							MountCount: 2,
+						},
+						StateVersion: volumedriver.StateVersion,
+					})
+					Expect(err).ToNot(HaveOccurred())
+
+					volumeFile := &ioutil_fake.FakeFileInfo{}
+					volumeFile.NameReturns("some%2Fvolume.json")
+					otherFile := &ioutil_fake.FakeFileInfo{}
+					otherFile.NameReturns("some-other-file")
+					fakeIoutil.ReadDirReturns([]os.FileInfo{volumeFile, otherFile}, nil)
+
+					fakeIoutil.ReadFileStub = func(path string) ([]byte, error) {
+						if path == "/path/to/mount/driver-state.d/some%2Fvolume.json" {
+							return volumeData, nil
+						}
+						return nil, errors.New("file not found")
+					}
+				})
+
+				It("returns the persisted volumes when listing", func() {
+					Expect(volumeDriver.List(env)).To(Equal(dockerdriver.ListResponse{
+						Volumes: []dockerdriver.VolumeInfo{
+							{Name: "some/volume", Mountpoint: "/some/mount/point", MountCount: 2},
+						},
+					}))
+				})
+
+				It("does not rewrite the state", func() {
+					Expect(fakeIoutil.WriteFileCallCount()).To(Equal(0))
+				})
+
+				Context("when a volume record has no state version", func() {
+					BeforeEach(func() {
+						volumeData = []byte(`{"Name": "some/volume", "Mountpoint": "/some/mount/point", "MountCount": 2}`)
+					})
+
+					It("restores the volume", func() {
+						Expect(volumeDriver.List(env).Volumes).To(Equal([]dockerdriver.VolumeInfo{
+							{Name: "some/volume", Mountpoint: "/some/mount/point", MountCount: 2},
+						}))
+					})
+
+					It("rewrites the record with the current state version", func() {
+						Expect(fakeIoutil.WriteFileCallCount()).To(Equal(1))
+						stateFile, data, _ := fakeIoutil.WriteFileArgsForCall(0)
+						Expect(stateFile).To(HaveSuffix("driver-state.d/some%2Fvolume.json.tmp"))
+
+						record := volumedriver.NfsVolumeInfo{}
+						Expect(json.Unmarshal(data, &record)).To(Succeed())
+						Expect(record.StateVersion).To(Equal(volumedriver.StateVersion))
+						Expect(record.MountCount).To(Equal(2))
+					})
+				})
+
+				Context("when a volume record was written by a newer driver", func() {
+					BeforeEach(func() {
+						volumeData = []byte(`{"Name": "some/volume", "Mountpoint": "/some/mount/point", "StateVersion": 99}`)
+					})
+
+					It("skips the volume without touching its record", func() {
+						Expect(volumeDriver.List(env).Volumes).To(BeEmpty())
+						Expect(fakeIoutil.WriteFileCallCount()).To(Equal(0))
+						Expect(fakeOs.RemoveCallCount()).To(Equal(0))
+						Expect(logger.TestSink.Buffer()).To(gbytes.Say("state-written-by-newer-driver"))
+					})
+				})
+
+				Context("when a volume record is corrupted", func() {
+					BeforeEach(func() {
+						volumeData = []byte("I have eleven toes.")
+					})
+
+					It("skips the volume", func() {
+						Expect(volumeDriver.List(env)).To(Equal(dockerdriver.ListResponse{
+							Volumes: []dockerdriver.VolumeInfo{},
+						}))
+					})
+				})
+			})
+
+			Context("when an operation was interrupted", func() {
+				var (
+					mountCount int
+					intent     volumedriver.Intent
+				)
+
+				BeforeEach(func() {
+					mountCount = 1
+					intent = volumedriver.Intent{Operation: volumedriver.IntentMount, Volume: "some-volume", MountPath: "/path/to/mount/some-volume"}
+					fakeMountChecker.ExistsReturns(true, nil)
+				})
+
+				JustBeforeEach(func() {
+					volumeData, err := json.Marshal(volumedriver.NfsVolumeInfo{
+						VolumeInfo: dockerdriver.VolumeInfo{
+							Name:       "some-volume",
+							Mountpoint: "/path/to/mount/some-volume",
+							MountCount: mountCount,
+						},
+					})
+					Expect(err).ToNot(HaveOccurred())
+					intentData, err := json.Marshal(intent)
+					Expect(err).ToNot(HaveOccurred())
+
+					volumeFile := &ioutil_fake.FakeFileInfo{}
+					volumeFile.NameReturns("some-volume.json")
+					intentFile := &ioutil_fake.FakeFileInfo{}
+					intentFile.NameReturns("some-volume.intent")
+					fakeIoutil.ReadDirReturns([]os.FileInfo{volumeFile, intentFile}, nil)
+
+					fakeIoutil.ReadFileStub = func(path string) ([]byte, error) {
+						switch path {
+						case "/path/to/mount/driver-state.d/some-volume.json":
+							return volumeData, nil
+						case "/path/to/mount/driver-state.d/some-volume.intent":
+							return intentData, nil
+						}
+						return nil, errors.New("file not found")
+					}
+
+					volumeDriver = volumedriver.NewVolumeDriver(logger, fakeOs, fakeFilepath, fakeIoutil, fakeTime, fakeMountChecker, mountDir, fakeMounter, oshelper.NewOsHelper())
+				})
+
+				Context("while mounting", func() {
+					It("rolls the mount back", func() {
+						Expect(fakeMounter.UnmountCallCount()).To(Equal(1))
+						_, target := fakeMounter.UnmountArgsForCall(0)
+						Expect(target).To(Equal("/path/to/mount/some-volume"))
+
+						Expect(volumeDriver.List(env).Volumes).To(Equal([]dockerdriver.VolumeInfo{{Name: "some-volume"}}))
+					})
+
+					It("clears the intent", func() {
+						Expect(fakeOs.RemoveArgsForCall(fakeOs.RemoveCallCount() - 1)).To(Equal("/path/to/mount/driver-state.d/some-volume.intent"))
+					})
+
+					Context("when the volume is also mounted by others", func() {
+						BeforeEach(func() {
+							mountCount = 2
+						})
+
+						It("keeps the kernel mount", func() {
+							Expect(fakeMounter.UnmountCallCount()).To(BeZero())
+							Expect(volumeDriver.List(env).Volumes[0].MountCount).To(Equal(1))
+						})
+					})
+				})
+
+				Context("while unmounting", func() {
+					BeforeEach(func() {
+						intent.Operation = volumedriver.IntentUnmount
+					})
+
+					It("completes the unmount and removes the volume", func() {
+						Expect(fakeMounter.UnmountCallCount()).To(Equal(1))
+						Expect(volumeDriver.List(env).Volumes).To(BeEmpty())
+						Expect(fakeOs.RemoveArgsForCall(fakeOs.RemoveCallCount() - 1)).To(Equal("/path/to/mount/driver-state.d/some-volume.intent"))
+					})
+				})
+			})
 		})
 	})
 })
@@ -738,3 +5006,23 @@ func setupMount(env dockerdriver.Env, volumeDriver dockerdriver.Driver, volumeNa
 	Expect(mountResponse.Err).To(Equal(""))
 	Expect(strings.Replace(mountResponse.Mountpoint, `\`, "/", -1)).To(Equal("/path/to/mount/" + volumeName))
 }
+
+// lazyFakeMounter is a FakeMounter that can detach busy mounts.
+type lazyFakeMounter struct {
+	*volumedriverfakes.FakeMounter
+	lazyUnmounted []string
+}
+
+func (m *lazyFakeMounter) LazyUnmount(env dockerdriver.Env, target string) error {
+	m.lazyUnmounted = append(m.lazyUnmounted, strings.Replace(target, `\`, "/", -1))
+	return nil
+}
+
+// hardened returns opts with the hardening opts the driver adds to every
+// mount by default.
+func hardened(opts map[string]interface{}) map[string]interface{} {
+	opts[volumedriver.NosuidOpt] = true
+	opts[volumedriver.NodevOpt] = true
+	opts[volumedriver.NoexecOpt] = true
+	return opts
+}
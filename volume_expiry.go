@@ -0,0 +1,78 @@
+package volumedriver
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver/metrics"
+)
+
+// expiresAt returns when a volume created with opts expires, or nil if it was
+// created without a ttl. Opts have already been validated.
+func (d *VolumeDriver) expiresAt(opts map[string]interface{}) *time.Time {
+	value, ok := opts[TTLOpt]
+	if !ok {
+		return nil
+	}
+
+	ttl, err := parseTTL(value)
+	if err != nil {
+		return nil
+	}
+
+	expiresAt := d.time.Now().Add(ttl)
+	return &expiresAt
+}
+
+// ExpireVolumes removes every unmounted volume whose ttl has passed. Mounted
+// volumes are left alone; they are removed on their last unmount anyway.
+func (d *VolumeDriver) ExpireVolumes(env dockerdriver.Env) {
+	logger := env.Logger().Session("expire-volumes")
+	logger.Info("start")
+	defer logger.Info("end")
+
+	d.volumesLock.Lock()
+	defer d.volumesLock.Unlock()
+
+	now := d.time.Now()
+	expired := 0
+
+	for name, volume := range d.volumes {
+		if volume.ExpiresAt == nil || volume.MountCount > 0 || now.Before(*volume.ExpiresAt) {
+			continue
+		}
+
+		logger.Info("volume-expired", lager.Data{"volume": name, "expires-at": volume.ExpiresAt.String()})
+
+		delete(d.volumes, name)
+		d.volumeLimiter.Forget(name)
+		d.metrics.Count(metrics.Expirations, 1)
+		d.publish(EventExpired, name, nil)
+		expired++
+
+		if err := d.removeVolumeState(driverhttp.EnvWithLogger(logger, env), name); err != nil {
+			logger.Error("remove-volume-state-failed", err, lager.Data{"volume": name})
+		}
+	}
+
+	if expired > 0 {
+		d.emitVolumeGauges()
+	}
+}
+
+func (d *VolumeDriver) runExpiry(env dockerdriver.Env, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.ExpireVolumes(env)
+			d.PruneState(env)
+		case <-d.stop:
+			return
+		}
+	}
+}
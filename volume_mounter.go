@@ -4,10 +4,31 @@ import (
 	"code.cloudfoundry.org/dockerdriver"
 )
 
+// CheckDepth selects how thoroughly Mounter.Check probes a mount.
+type CheckDepth string
+
+const (
+	// CheckStat only verifies that the mountpoint is present.
+	CheckStat CheckDepth = "stat"
+	// CheckRead also lists the mountpoint, which requires a round trip to the
+	// server.
+	CheckRead CheckDepth = "read"
+	// CheckWrite also creates and deletes a probe file. It cannot be used for
+	// read-only exports.
+	CheckWrite CheckDepth = "write"
+)
+
 //go:generate counterfeiter -o volumedriverfakes/fake_mounter.go . Mounter
 type Mounter interface {
 	Mount(env dockerdriver.Env, source string, target string, opts map[string]interface{}) error
 	Unmount(env dockerdriver.Env, target string) error
-	Check(env dockerdriver.Env, name, mountPoint string) bool
+	Check(env dockerdriver.Env, name, mountPoint string, depth CheckDepth) bool
 	Purge(env dockerdriver.Env, path string)
-}
\ No newline at end of file
+}
+
+// LazyUnmounter is implemented by Mounters that can detach a mount that is
+// still busy, like umount -l, for RemoveVolume with Force. The mount goes
+// away once its last user closes it.
+type LazyUnmounter interface {
+	LazyUnmount(env dockerdriver.Env, target string) error
+}
@@ -0,0 +1,43 @@
+package volumedriver
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxFileNameLength is the longest file name of common filesystems. The
+// state and intent files of a volume are named after its escaped name.
+const maxFileNameLength = 255
+
+// ValidateVolumeName fails for names that could reach outside the mount path
+// roots if a path were built from them: names with '.' or '..' segments,
+// control characters or invalid UTF-8, and names too long for their state
+// and intent files. The driver never joins names into paths as they are,
+// mount directories and state files encode them, but refusing such names
+// when a volume is created keeps them out of logs and tools that might.
+// Names may contain '/', which tenant namespaces use.
+func ValidateVolumeName(name string) error {
+	if !utf8.ValidString(name) {
+		return fmt.Errorf("invalid volume name %q, must be valid UTF-8", name)
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("invalid volume name %q, must not contain control characters", name)
+		}
+	}
+	for _, segment := range strings.FieldsFunc(name, isPathSeparator) {
+		if segment == "." || segment == ".." {
+			return fmt.Errorf("invalid volume name '%s', must not contain '.' or '..' path segments", name)
+		}
+	}
+	if len(intentFileName(name)) > maxFileNameLength {
+		return fmt.Errorf("invalid volume name '%s', must not be longer than %d bytes once escaped", name, maxFileNameLength-len(intentFileSuffix))
+	}
+	return nil
+}
+
+func isPathSeparator(r rune) bool {
+	return r == '/' || r == '\\'
+}
@@ -0,0 +1,94 @@
+package volumedriver_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/testhelpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Volume names", func() {
+	var (
+		logger *lagertest.TestLogger
+		env    dockerdriver.Env
+		driver *testhelpers.MemoryDriver
+		opts   map[string]interface{}
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("volume-names")
+		env = driverhttp.NewHttpDriverEnv(logger, context.TODO())
+		driver = testhelpers.NewMemoryDriver(logger)
+		opts = map[string]interface{}{"source": "server:/export"}
+	})
+
+	It("refuses names that could traverse out of the mount path root", func() {
+		for _, name := range []string{"..", "../../etc", "tenant/..", `..\etc`, "volume/./x", "volume\x00", "volume\n", "\xff", strings.Repeat("a", 249)} {
+			Expect(driver.Create(env, dockerdriver.CreateRequest{Name: name, Opts: opts}).Err).To(HavePrefix("invalid volume name"), name)
+			Expect(driver.Get(env, dockerdriver.GetRequest{Name: name}).Err).To(Equal("Volume not found"), name)
+		}
+		Expect(driver.FS.Exists(testhelpers.MountPathRoot + "/driver-state.d")).To(BeFalse())
+	})
+
+	It("accepts names with separators and dots that are not segments", func() {
+		for _, name := range []string{"tenant/volume", "/volume", "a..b", ".volume", strings.Repeat("a", 248)} {
+			Expect(driver.Create(env, dockerdriver.CreateRequest{Name: name, Opts: opts}).Err).To(BeEmpty(), name)
+
+			mountResponse := driver.Mount(env, dockerdriver.MountRequest{Name: name})
+			Expect(mountResponse.Err).To(BeEmpty(), name)
+			Expect(filepath.Dir(mountResponse.Mountpoint)).To(Equal(testhelpers.MountPathRoot), name)
+		}
+	})
+
+	It("refuses them on import and in validation", func() {
+		Expect(driver.ImportState(env, volumedriver.ImportStateRequest{
+			Volumes: []volumedriver.ExportedVolume{{VolumeInfo: dockerdriver.VolumeInfo{Name: "../volume"}}},
+		}).Err).To(HavePrefix("invalid volume name '../volume'"))
+		Expect(driver.ImportMount(env, volumedriver.ImportMountRequest{
+			Name: "../volume", Opts: opts, Mountpoint: testhelpers.MountPathRoot + "/volume",
+		}).Err).To(HavePrefix("invalid volume name '../volume'"))
+
+		response := driver.Validate(env, volumedriver.ValidateRequest{Name: "../volume", Opts: opts})
+		Expect(response.Valid).To(BeFalse())
+		Expect(response.Diagnostics).To(ConsistOf(volumedriver.Diagnostic{
+			Check:   volumedriver.CheckName,
+			Message: "invalid volume name '../volume', must not contain '.' or '..' path segments",
+		}))
+	})
+
+	Context("when the restored state places the mountpoint outside the root", func() {
+		const stateFile = testhelpers.MountPathRoot + "/driver-state.d/volume.json"
+
+		BeforeEach(func() {
+			Expect(driver.Create(env, dockerdriver.CreateRequest{Name: "volume", Opts: opts}).Err).To(BeEmpty())
+
+			data, err := driver.FS.ReadFile(stateFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(ContainSubstring(`"MountDirectory":"volume"`))
+			data = []byte(strings.Replace(string(data), `"MountDirectory":"volume"`, `"MountDirectory":"../../etc"`, 1))
+			Expect(driver.FS.Ioutil().WriteFile(stateFile, data, 0600)).To(Succeed())
+
+			driver = driver.Restart(logger)
+		})
+
+		It("reports no mountpoint in Get until the volume is mounted", func() {
+			getResponse := driver.Get(env, dockerdriver.GetRequest{Name: "volume"})
+			Expect(getResponse.Err).To(BeEmpty())
+			Expect(getResponse.Volume.Mountpoint).To(BeEmpty())
+		})
+
+		It("mounts the volume below the root", func() {
+			Expect(driver.Create(env, dockerdriver.CreateRequest{Name: "volume", Opts: opts}).Err).To(BeEmpty())
+			mountResponse := driver.Mount(env, dockerdriver.MountRequest{Name: "volume"})
+			Expect(mountResponse.Err).To(BeEmpty())
+			Expect(mountResponse.Mountpoint).To(Equal(testhelpers.MountPathRoot + "/volume"))
+		})
+	})
+})
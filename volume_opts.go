@@ -0,0 +1,187 @@
+package volumedriver
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Opts handled by the driver itself. They are not passed on to the Mounter.
+const (
+	CheckDepthOpt = "check_depth"
+	// TTLOpt removes the volume once it has been unmounted for this long.
+	TTLOpt = "ttl"
+	// LabelsOpt attaches labels to the volume for ListVolumes selectors,
+	// either as an object or as a k1=v1,k2=v2 string.
+	LabelsOpt = "labels"
+	// DriverOpt selects the Mounter registered under its value in
+	// Options.Mounters.
+	DriverOpt = "driver"
+	// SubdirOpt binds this directory of the export as the volume, creating
+	// it with SubdirModeOpt and owned by SubdirUIDOpt and SubdirGIDOpt if
+	// it is missing.
+	SubdirOpt     = "subdir"
+	SubdirModeOpt = "subdir_mode"
+	SubdirUIDOpt  = "subdir_uid"
+	SubdirGIDOpt  = "subdir_gid"
+	// ReadBPSOpt, WriteBPSOpt, ReadIOPSOpt and WriteIOPSOpt limit the IO of
+	// the volume through Options.IOThrottler.
+	ReadBPSOpt   = "read_bps"
+	WriteBPSOpt  = "write_bps"
+	ReadIOPSOpt  = "read_iops"
+	WriteIOPSOpt = "write_iops"
+	// ScratchOpt mounts the volume read-only and layers a writable overlay
+	// on cell-local disk over it, through Options.OverlayMounter.
+	ScratchOpt = "scratch"
+	// ProvisionOpt creates the export of the volume inside its value through
+	// Options.Provisioner, instead of mounting a pre-created source. The
+	// export gets a quota of ProvisionSizeOpt bytes and can be mounted by
+	// the comma separated ProvisionClientsOpt.
+	ProvisionOpt        = "provision"
+	ProvisionSizeOpt    = "provision_size"
+	ProvisionClientsOpt = "provision_clients"
+	// CompositeOpt mounts further sources into directories below the
+	// mountpoint of the volume, either as an object or as a
+	// dir=source,... string.
+	CompositeOpt = "composite"
+)
+
+var driverOpts = []string{CheckDepthOpt, TTLOpt, LabelsOpt, DriverOpt, SubdirOpt, SubdirModeOpt, SubdirUIDOpt, SubdirGIDOpt, ReadBPSOpt, WriteBPSOpt, ReadIOPSOpt, WriteIOPSOpt, ScratchOpt, ProvisionOpt, ProvisionSizeOpt, ProvisionClientsOpt, CompositeOpt, RelabelOpt}
+
+// mounterOpts returns a copy of opts without the driver's own options. The
+// source is left out as well, since mounters are given it on its own.
+func mounterOpts(opts map[string]interface{}) map[string]interface{} {
+	filtered := map[string]interface{}{}
+	for k, v := range opts {
+		filtered[k] = v
+	}
+
+	for _, k := range driverOpts {
+		delete(filtered, k)
+	}
+	delete(filtered, "source")
+
+	return filtered
+}
+
+// sourceOpt returns the source of opts, or "" when they have none. Volumes
+// restored from records that predate persisted opts, or whose secret opts
+// were left out, have none; read the source of a volume through sourceOpt
+// rather than with a type assertion.
+func sourceOpt(opts map[string]interface{}) string {
+	source, _ := opts["source"].(string)
+	return source
+}
+
+func validateDriverOpts(opts map[string]interface{}) error {
+	if _, ok := opts[CheckDepthOpt]; ok {
+		depth, _ := opts[CheckDepthOpt].(string)
+		if _, err := ParseCheckDepth(depth); err != nil {
+			return err
+		}
+	}
+
+	if _, ok := opts[TTLOpt]; ok {
+		if _, err := parseTTL(opts[TTLOpt]); err != nil {
+			return err
+		}
+	}
+
+	if _, ok := opts[LabelsOpt]; ok {
+		if _, err := parseLabels(opts[LabelsOpt]); err != nil {
+			return err
+		}
+	}
+
+	if _, err := parseSubdirOpts(opts); err != nil {
+		return err
+	}
+
+	if _, err := parseIOLimits(opts); err != nil {
+		return err
+	}
+
+	if _, err := parseScratchOpt(opts); err != nil {
+		return err
+	}
+
+	if _, err := parseCompositeOpt(opts); err != nil {
+		return err
+	}
+
+	if err := validateSELinuxOpts(opts); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// parseTTL accepts durations such as "24h". Numbers are taken as seconds.
+func parseTTL(value interface{}) (time.Duration, error) {
+	var ttl time.Duration
+	var err error
+
+	switch v := value.(type) {
+	case string:
+		ttl, err = time.ParseDuration(v)
+	case float64:
+		ttl = time.Duration(v * float64(time.Second))
+	default:
+		err = errors.New("unsupported type")
+	}
+
+	if err != nil || ttl <= 0 {
+		return 0, fmt.Errorf("invalid ttl '%v', must be a positive duration such as 24h", value)
+	}
+	return ttl, nil
+}
+
+// volumeLabels returns the labels of a volume created with opts, which have
+// already been validated.
+func volumeLabels(opts map[string]interface{}) map[string]string {
+	if value, ok := opts[LabelsOpt]; ok {
+		labels, _ := parseLabels(value)
+		return labels
+	}
+	return nil
+}
+
+func parseLabels(value interface{}) (map[string]string, error) {
+	labels := map[string]string{}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, labelValue := range v {
+			labels[key] = fmt.Sprintf("%v", labelValue)
+		}
+	case string:
+		for _, pair := range strings.Split(v, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+				return nil, fmt.Errorf("invalid labels '%s', must be of the form key=value,...", v)
+			}
+			labels[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	default:
+		return nil, fmt.Errorf("invalid labels '%v', must be an object or key=value,...", value)
+	}
+
+	return labels, nil
+}
+
+func (d *VolumeDriver) checkDepth(volume *NfsVolumeInfo) CheckDepth {
+	if depth, ok := volume.Opts[CheckDepthOpt].(string); ok {
+		if parsed, err := ParseCheckDepth(depth); err == nil {
+			return parsed
+		}
+	}
+
+	if depth := d.currentPolicy().CheckDepth; depth != "" {
+		return depth
+	}
+	return CheckStat
+}
@@ -0,0 +1,64 @@
+package volumedriver_test
+
+import (
+	"context"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver/testhelpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Volumes restored without opts", func() {
+	var (
+		env        dockerdriver.Env
+		driver     *testhelpers.MemoryDriver
+		mountpoint string
+	)
+
+	BeforeEach(func() {
+		logger := lagertest.NewTestLogger("restored-opts")
+		env = driverhttp.NewHttpDriverEnv(logger, context.TODO())
+		driver = testhelpers.NewMemoryDriver(logger)
+
+		Expect(driver.Create(env, dockerdriver.CreateRequest{
+			Name: "volume",
+			Opts: map[string]interface{}{"source": "server:/export", "password": "secret"},
+		}).Err).To(BeEmpty())
+		mountResponse := driver.Mount(env, dockerdriver.MountRequest{Name: "volume"})
+		Expect(mountResponse.Err).To(BeEmpty())
+		mountpoint = mountResponse.Mountpoint
+
+		// secret opts are not persisted without a state key, the restarted
+		// driver has no opts for the volume
+		driver = driver.Restart(lagertest.NewTestLogger("restored-opts"))
+	})
+
+	It("mounts the volume again from its existing mount", func() {
+		mountResponse := driver.Mount(env, dockerdriver.MountRequest{Name: "volume"})
+		Expect(mountResponse.Err).To(BeEmpty())
+		Expect(mountResponse.Mountpoint).To(Equal(mountpoint))
+		Expect(driver.Mounter.MountCalls()).To(HaveLen(1))
+	})
+
+	It("fails the mount when the volume must be mounted again", func() {
+		driver.Mounter.Break(mountpoint)
+
+		mountResponse := driver.Mount(env, dockerdriver.MountRequest{Name: "volume"})
+		Expect(mountResponse.Err).To(ContainSubstring("Volume 'volume' has no source, it must be created again before being mounted"))
+		Expect(driver.Mounter.MountCalls()).To(HaveLen(1))
+	})
+
+	It("serves the volume once it is created again", func() {
+		Expect(driver.Create(env, dockerdriver.CreateRequest{
+			Name: "volume",
+			Opts: map[string]interface{}{"source": "server:/export"},
+		}).Err).To(BeEmpty())
+		driver.Mounter.Break(mountpoint)
+
+		Expect(driver.Mount(env, dockerdriver.MountRequest{Name: "volume"}).Err).To(BeEmpty())
+		Expect(driver.Mounter.MountCalls()).To(HaveLen(2))
+	})
+})
@@ -0,0 +1,120 @@
+package volumedriver
+
+import (
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver/metrics"
+)
+
+// orphanGracePeriod keeps directories that were touched recently, so that
+// a mountpoint that is still being set up is never mistaken for an orphan.
+const orphanGracePeriod = 10 * time.Minute
+
+// CollectOrphans removes directories below the mount path roots that are not
+// the mountpoint of any known volume, such as leftovers of failed mounts or
+// of volumes lost in a crash. Directories that still have a kernel mount, are
+// not empty, or were modified in the last ten minutes are kept. With dryRun
+// nothing is removed. It returns the orphaned directories it found.
+func (d *VolumeDriver) CollectOrphans(env dockerdriver.Env, dryRun bool) []string {
+	logger := env.Logger().Session("collect-orphans", lager.Data{"dry-run": dryRun})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	d.volumesLock.Lock()
+	defer d.volumesLock.Unlock()
+
+	owned := map[string]bool{stateDirName: true, exportsDirName: true, scratchDirName: true}
+	for _, volume := range d.volumes {
+		if volume.MountDirectory != "" {
+			owned[volume.MountDirectory] = true
+		}
+		if volume.Mountpoint != "" {
+			owned[filepath.Base(volume.Mountpoint)] = true
+		}
+	}
+
+	now := d.time.Now()
+	orphans := []string{}
+
+	for _, root := range d.roots() {
+		orphans = append(orphans, d.collectOrphansOn(env, root, owned, now, dryRun)...)
+	}
+
+	if len(orphans) > 0 {
+		d.metrics.Count(metrics.Orphans, int64(len(orphans)))
+	}
+
+	return orphans
+}
+
+func (d *VolumeDriver) collectOrphansOn(env dockerdriver.Env, root string, owned map[string]bool, now time.Time, dryRun bool) []string {
+	logger := env.Logger().Session("collect-orphans-on", lager.Data{"root": root})
+
+	root, err := d.filepath.Abs(root)
+	if err != nil {
+		logger.Error("abs-failed", err)
+		return nil
+	}
+
+	entries, err := d.ioutil.ReadDir(root)
+	if err != nil {
+		logger.Error("read-mount-path-root-failed", err)
+		return nil
+	}
+
+	orphans := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() || owned[entry.Name()] {
+			continue
+		}
+
+		path := filepath.Join(root, entry.Name())
+		if now.Sub(entry.ModTime()) < orphanGracePeriod {
+			logger.Debug("orphan-too-recent", lager.Data{"path": path})
+			continue
+		}
+
+		mounted, err := d.mountChecker.Exists(path)
+		if err != nil {
+			logger.Error("check-mount-failed", err, lager.Data{"path": path})
+			continue
+		}
+		if mounted {
+			logger.Info("orphan-still-mounted", lager.Data{"path": path})
+			continue
+		}
+
+		orphans = append(orphans, path)
+		if dryRun {
+			logger.Info("orphan-found", lager.Data{"path": path})
+			continue
+		}
+
+		// Remove rather than RemoveAll, a directory with contents is not
+		// an empty mountpoint and is left for an operator to look at
+		if err := d.os.Remove(path); err != nil {
+			logger.Error("remove-orphan-failed", err, lager.Data{"path": path})
+			continue
+		}
+		logger.Info("orphan-removed", lager.Data{"path": path})
+	}
+
+	return orphans
+}
+
+func (d *VolumeDriver) runOrphanCollection(env dockerdriver.Env, interval time.Duration, dryRun bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.CollectOrphans(env, dryRun)
+		case <-d.stop:
+			return
+		}
+	}
+}
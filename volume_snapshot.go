@@ -0,0 +1,120 @@
+package volumedriver
+
+import "time"
+
+// snapshot returns a copy of the record of a volume that can be read after
+// volumesLock is released, while requests for the volume go on changing the
+// record. It must be called with volumesLock held.
+func (v *NfsVolumeInfo) snapshot() *NfsVolumeInfo {
+	return &NfsVolumeInfo{
+		Opts:             copyOpts(v.Opts),
+		SavedOpts:        copyOpts(v.SavedOpts),
+		DroppedOpts:      copyStrings(v.DroppedOpts),
+		mountError:       v.mountError,
+		mountErrorTime:   v.mountErrorTime,
+		mountFailures:    v.mountFailures,
+		MountDirectory:   v.MountDirectory,
+		MountRoot:        v.MountRoot,
+		ExpiresAt:        copyTime(v.ExpiresAt),
+		CreatedAt:        copyTime(v.CreatedAt),
+		Labels:           copyLabels(v.Labels),
+		Driver:           v.Driver,
+		Subdir:           v.Subdir,
+		ExportMount:      v.ExportMount,
+		Composite:        copyLabels(v.Composite),
+		LastMountedAt:    copyTime(v.LastMountedAt),
+		LastUnmountedAt:  copyTime(v.LastUnmountedAt),
+		LastIOAt:         copyTime(v.LastIOAt),
+		persistedIOAt:    copyTime(v.persistedIOAt),
+		LastMountError:   v.LastMountError,
+		LastMountErrorAt: copyTime(v.LastMountErrorAt),
+		IOLimits:         copyIOLimits(v.IOLimits),
+		Scratch:          v.Scratch,
+		Relabel:          v.Relabel,
+		Frozen:           v.Frozen,
+		RemountOpts:      copyOpts(v.RemountOpts),
+		SavedRemountOpts: copyOpts(v.SavedRemountOpts),
+		References:       copyReferences(v.References),
+		Binds:            copyBindRecords(v.Binds),
+		StateVersion:     v.StateVersion,
+		VolumeInfo:       v.VolumeInfo,
+	}
+}
+
+// volumeMap is a published snapshot of d.volumes. It is never changed;
+// writers publish a new one copy-on-write when they release volumesLock, so
+// that List, Get and Path never wait behind the bookkeeping of mounts.
+type volumeMap map[string]*NfsVolumeInfo
+
+// publishVolumes must be called with volumesLock held. It is run on every
+// release of the write lock.
+func (d *VolumeDriver) publishVolumes() {
+	published := make(volumeMap, len(d.volumes))
+	for name, volume := range d.volumes {
+		published[name] = volume.snapshot()
+	}
+	d.published.Store(published)
+}
+
+// loadVolumes returns the snapshot of d.volumes as of the last release of
+// the write lock. The records in it must not be changed.
+func (d *VolumeDriver) loadVolumes() volumeMap {
+	return d.published.Load().(volumeMap)
+}
+
+func copyOpts(opts map[string]interface{}) map[string]interface{} {
+	if opts == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(opts))
+	for k, v := range opts {
+		copied[k] = v
+	}
+	return copied
+}
+
+func copyLabels(labels map[string]string) map[string]string {
+	if labels == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(labels))
+	for k, v := range labels {
+		copied[k] = v
+	}
+	return copied
+}
+
+func copyStrings(strings []string) []string {
+	if strings == nil {
+		return nil
+	}
+	return append([]string{}, strings...)
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	copied := *t
+	return &copied
+}
+
+func copyIOLimits(limits *IOLimits) *IOLimits {
+	if limits == nil {
+		return nil
+	}
+	copied := *limits
+	return &copied
+}
+
+func copyBindRecords(binds map[string]*VolumeBind) map[string]*VolumeBind {
+	if binds == nil {
+		return nil
+	}
+	copied := make(map[string]*VolumeBind, len(binds))
+	for target, bind := range binds {
+		bind := *bind
+		copied[target] = &bind
+	}
+	return copied
+}
@@ -0,0 +1,306 @@
+package volumedriver
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+)
+
+const (
+	// legacyStateFile held the whole volume map before state was split into
+	// one record per volume. It is migrated on restore.
+	legacyStateFile = "driver-state.json"
+	stateDirName    = "driver-state.d"
+	stateFileSuffix = ".json"
+	// stateTempSuffix marks records that are being written
+	stateTempSuffix = ".tmp"
+
+	// stateFileMode keeps the records, which hold the opts of the volumes,
+	// from other users
+	stateFileMode os.FileMode = 0600
+)
+
+// persistVolume must be called with volumesLock held. Only the record of the
+// named volume is rewritten.
+func (d *VolumeDriver) persistVolume(env dockerdriver.Env, volumeName string) error {
+	logger := env.Logger().Session("persist-volume", lager.Data{"volume": volumeName})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	volume, ok := d.volumes[volumeName]
+	if !ok {
+		return d.removeVolumeState(env, volumeName)
+	}
+
+	orig := d.osHelper.Umask(000)
+	defer d.osHelper.Umask(orig)
+
+	stateDir, err := d.stateDir(env, d.volumeRoot(volume))
+	if err != nil {
+		return err
+	}
+	stateFile := filepath.Join(stateDir, stateFileName(volumeName))
+
+	volume.StateVersion = StateVersion
+	volume.SavedOpts, volume.DroppedOpts = d.savedOpts(volume.Opts)
+	volume.SavedRemountOpts, _ = d.savedOpts(volume.RemountOpts)
+	stateData, err := json.Marshal(volume)
+	if err != nil {
+		logger.Error("failed-to-marshall-state", err)
+		return err
+	}
+
+	stateData, err = d.sealState(stateFileName(volumeName), stateData)
+	if err != nil {
+		logger.Error("failed-to-encrypt-state", err)
+		return err
+	}
+
+	err = d.writeStateFile(stateFile, stateData)
+	if err != nil {
+		logger.Error("failed-to-write-state-file", err, lager.Data{"stateFile": stateFile})
+		return err
+	}
+
+	d.stateSizes[volumeName] = len(stateData)
+	delete(d.dirtyVolumes, volumeName)
+	delete(d.persistRetries, volumeName)
+	logger.Debug("state-saved", lager.Data{"state-file": stateFile})
+	return nil
+}
+
+// writeStateFile replaces the record at path with data. The record is
+// written next to it first and renamed over it, so that a crash leaves
+// either the old or the new record rather than a truncated one.
+func (d *VolumeDriver) writeStateFile(path string, data []byte) error {
+	tmp := path + stateTempSuffix
+	if err := d.ioutil.WriteFile(tmp, data, stateFileMode); err != nil {
+		return err
+	}
+	if err := d.os.Rename(tmp, path); err != nil {
+		d.os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func (d *VolumeDriver) removeVolumeState(env dockerdriver.Env, volumeName string) error {
+	logger := env.Logger().Session("remove-volume-state", lager.Data{"volume": volumeName})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	// the volume may already be gone from d.volumes, so its record is
+	// removed from every root
+	for _, root := range d.roots() {
+		stateDir, err := d.stateDir(env, root)
+		if err != nil {
+			return err
+		}
+		stateFile := filepath.Join(stateDir, stateFileName(volumeName))
+
+		err = d.os.Remove(stateFile)
+		if err != nil && !os.IsNotExist(err) {
+			logger.Error("failed-to-remove-state-file", err, lager.Data{"stateFile": stateFile})
+			return err
+		}
+	}
+
+	delete(d.dirtyVolumes, volumeName)
+	delete(d.persistRetries, volumeName)
+	delete(d.stateSizes, volumeName)
+	return nil
+}
+
+func (d *VolumeDriver) stateDir(env dockerdriver.Env, root string) (string, error) {
+	logger := env.Logger().Session("state-dir")
+
+	stateDir := d.mountPathOn(env, root, stateDirName)
+	if err := d.os.MkdirAll(stateDir, os.ModePerm); err != nil {
+		logger.Error("mkdir-state-dir-failed", err, lager.Data{"state-dir": stateDir})
+		return "", err
+	}
+
+	return stateDir, nil
+}
+
+func (d *VolumeDriver) restoreState(env dockerdriver.Env) {
+	logger := env.Logger().Session("restore-state")
+	logger.Info("start")
+	defer logger.Info("end")
+
+	state := d.restoreLegacyState(env)
+	migrate := len(state) > 0
+
+	intents := []Intent{}
+	sizes := map[string]int{}
+	unencrypted := []string{}
+	outdated := []string{}
+	for _, root := range d.roots() {
+		stateDir := filepath.Join(root, stateDirName)
+		entries, err := d.ioutil.ReadDir(stateDir)
+		if err != nil {
+			logger.Info("failed-to-read-state-dir", lager.Data{"err": err, "stateDir": stateDir})
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.HasSuffix(entry.Name(), intentFileSuffix) {
+				if intent, ok := d.restoreIntent(env, filepath.Join(stateDir, entry.Name())); ok {
+					intents = append(intents, intent)
+				}
+				continue
+			}
+
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), stateFileSuffix) {
+				continue
+			}
+
+			stateFile := filepath.Join(stateDir, entry.Name())
+			stateData, err := d.ioutil.ReadFile(stateFile)
+			if err != nil {
+				logger.Error("failed-to-read-state-file", err, lager.Data{"stateFile": stateFile})
+				continue
+			}
+
+			size := len(stateData)
+			stateData, encrypted, err := d.openState(entry.Name(), stateData)
+			if err != nil {
+				logger.Error("failed-to-decrypt-state", err, lager.Data{"stateFile": stateFile})
+				continue
+			}
+
+			volume, migrated, err := decodeVolumeState(stateData, "")
+			if _, tooNew := err.(stateTooNewError); tooNew {
+				logger.Error("state-written-by-newer-driver", err, lager.Data{"stateFile": stateFile})
+				continue
+			}
+			if err != nil {
+				logger.Error("failed-to-unmarshall-state", err, lager.Data{"stateFile": stateFile})
+				continue
+			}
+			volume.Opts = restoredOpts(volume)
+			volume.RemountOpts = volume.SavedRemountOpts
+			if len(volume.DroppedOpts) > 0 {
+				logger.Info("restored-without-secret-opts", lager.Data{"volume": volume.Name, "opts": volume.DroppedOpts})
+			}
+			state[volume.Name] = volume
+			sizes[volume.Name] = size
+
+			if migrated {
+				outdated = append(outdated, volume.Name)
+			} else if d.stateCipher != nil && !encrypted {
+				unencrypted = append(unencrypted, volume.Name)
+			}
+		}
+	}
+
+	// the opts of the volumes may hold secrets, only their names are logged
+	names := make([]string, 0, len(state))
+	for name := range state {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	logger.Info("state", lager.Data{"volumes": names})
+
+	d.volumesLock.Lock()
+	defer d.volumesLock.Unlock()
+	d.volumes = state
+	d.stateSizes = sizes
+
+	if migrate {
+		d.migrateLegacyState(env)
+	}
+
+	if len(outdated) > 0 {
+		d.migrateState(env, outdated)
+	}
+
+	if len(unencrypted) > 0 {
+		d.encryptState(env, unencrypted)
+	}
+
+	d.restoreExports()
+
+	if len(intents) > 0 {
+		d.resolveIntents(env, intents)
+	}
+}
+
+func (d *VolumeDriver) restoreLegacyState(env dockerdriver.Env) map[string]*NfsVolumeInfo {
+	logger := env.Logger().Session("restore-legacy-state")
+
+	state := map[string]*NfsVolumeInfo{}
+	stateFile := filepath.Join(d.mountPathRoot, legacyStateFile)
+
+	stateData, err := d.ioutil.ReadFile(stateFile)
+	if err != nil {
+		logger.Info("failed-to-read-state-file", lager.Data{"err": err, "stateFile": stateFile})
+		return state
+	}
+
+	records := map[string]json.RawMessage{}
+	if err := json.Unmarshal(stateData, &records); err != nil {
+		logger.Error("failed-to-unmarshall-state", err, lager.Data{"stateFile": stateFile})
+		return map[string]*NfsVolumeInfo{}
+	}
+
+	for name, record := range records {
+		volume, _, err := decodeVolumeState(record, name)
+		if err != nil {
+			logger.Error("failed-to-migrate-volume", err, lager.Data{"volume": name})
+			continue
+		}
+		state[volume.Name] = volume
+	}
+	logger.Info("state-restored", lager.Data{"state-file": stateFile})
+
+	return state
+}
+
+// migrateLegacyState must be called with volumesLock held. The legacy file is
+// only removed once every volume has its own record.
+func (d *VolumeDriver) migrateLegacyState(env dockerdriver.Env) {
+	logger := env.Logger().Session("migrate-legacy-state")
+	logger.Info("start")
+	defer logger.Info("end")
+
+	for name := range d.volumes {
+		if err := d.persistVolume(env, name); err != nil {
+			logger.Error("failed-to-migrate-volume", err, lager.Data{"volume": name})
+			return
+		}
+	}
+
+	stateFile := filepath.Join(d.mountPathRoot, legacyStateFile)
+	if err := d.os.Remove(stateFile); err != nil && !os.IsNotExist(err) {
+		logger.Error("failed-to-remove-legacy-state-file", err, lager.Data{"stateFile": stateFile})
+	}
+}
+
+// encryptState must be called with volumesLock held. It rewrites the records
+// of volumes that were persisted before a state key was configured.
+func (d *VolumeDriver) encryptState(env dockerdriver.Env, volumeNames []string) {
+	logger := env.Logger().Session("encrypt-state")
+	logger.Info("start", lager.Data{"volumes": len(volumeNames)})
+	defer logger.Info("end")
+
+	for _, name := range volumeNames {
+		if _, ok := d.volumes[name]; !ok {
+			continue
+		}
+		if err := d.persistVolume(env, name); err != nil {
+			logger.Error("failed-to-encrypt-volume", err, lager.Data{"volume": name})
+		}
+	}
+}
+
+// stateFileName escapes the volume name so that every volume maps to a single
+// file inside the state directory.
+func stateFileName(volumeName string) string {
+	return url.PathEscape(volumeName) + stateFileSuffix
+}
@@ -0,0 +1,94 @@
+package volumedriver
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/lager"
+)
+
+// lastIOPersistInterval is how far the persisted LastIOAt of a volume may lag
+// behind, so that busy volumes are not written on every mount stats
+// interval.
+const lastIOPersistInterval = time.Hour
+
+// leaseOps are the RPCs an NFS client sends on its own to keep its state on
+// the server, which do not count as I/O of the volume.
+var leaseOps = map[string]bool{
+	"NULL":         true,
+	"RENEW":        true,
+	"SEQUENCE":     true,
+	"TEST_STATEID": true,
+}
+
+// VolumeUsage tells when a volume was last used, so that operators can find
+// abandoned volumes. LastIOAt is only tracked with Options.MountStats.
+type VolumeUsage struct {
+	CreatedAt       *time.Time `json:",omitempty"`
+	LastMountedAt   *time.Time `json:",omitempty"`
+	LastUnmountedAt *time.Time `json:",omitempty"`
+	LastIOAt        *time.Time `json:",omitempty"`
+	// LastUsedAt is the latest of the others, unset when none is known.
+	LastUsedAt *time.Time `json:",omitempty"`
+}
+
+func volumeUsage(volume *NfsVolumeInfo) VolumeUsage {
+	usage := VolumeUsage{
+		CreatedAt:       copyTime(volume.CreatedAt),
+		LastMountedAt:   copyTime(volume.LastMountedAt),
+		LastUnmountedAt: copyTime(volume.LastUnmountedAt),
+		LastIOAt:        copyTime(volume.LastIOAt),
+	}
+
+	for _, at := range []*time.Time{usage.CreatedAt, usage.LastMountedAt, usage.LastUnmountedAt, usage.LastIOAt} {
+		if at != nil && (usage.LastUsedAt == nil || at.After(*usage.LastUsedAt)) {
+			usage.LastUsedAt = copyTime(at)
+		}
+	}
+	return usage
+}
+
+// unusedSince tells whether volume has not been used since the time given.
+// Mounted volumes are in use unless the driver tracks their I/O, and volumes
+// without any usage record are left to their expiry.
+func (d *VolumeDriver) unusedSince(volume *NfsVolumeInfo, since time.Time) bool {
+	if volume.MountCount > 0 && d.options.MountStats == nil {
+		return false
+	}
+
+	lastUsed := volumeUsage(volume).LastUsedAt
+	return lastUsed != nil && lastUsed.Before(since)
+}
+
+// recordIO must be called with volumesLock held. It only writes the state
+// once the persisted LastIOAt lags more than lastIOPersistInterval.
+func (d *VolumeDriver) recordIO(env dockerdriver.Env, volume *NfsVolumeInfo) {
+	logger := env.Logger().Session("record-io", lager.Data{"volume": volume.Name})
+
+	now := d.time.Now()
+	persist := volume.persistedIOAt == nil || now.Sub(*volume.persistedIOAt) >= lastIOPersistInterval
+	volume.LastIOAt = &now
+	if !persist {
+		return
+	}
+
+	volume.persistedIOAt = &now
+	if err := d.persistVolumeLater(env, volume.Name); err != nil {
+		logger.Error("persist-state-failed", err)
+	}
+}
+
+// hadIO tells whether a mount did I/O between two samples of its statistics,
+// other than the RPCs that keep its lease.
+func hadIO(previous, sample NFSStats) bool {
+	if counterDelta(previous.BytesRead, sample.BytesRead) > 0 || counterDelta(previous.BytesWritten, sample.BytesWritten) > 0 {
+		return true
+	}
+
+	for op, opStats := range sample.Ops {
+		if !leaseOps[op] && counterDelta(previous.Ops[op].Ops, opStats.Ops) > 0 {
+			return true
+		}
+	}
+	return false
+}
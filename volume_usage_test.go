@@ -0,0 +1,160 @@
+package volumedriver_test
+
+import (
+	"context"
+	"time"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/dockerdriver/driverhttp"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/testhelpers"
+	"code.cloudfoundry.org/volumedriver/volumedriverfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Volume usage", func() {
+	var (
+		env            dockerdriver.Env
+		options        volumedriver.Options
+		fakeMountStats *volumedriverfakes.FakeMountStatsReader
+		driver         *testhelpers.MemoryDriver
+		mountpoint     string
+	)
+
+	describe := func() volumedriver.VolumeDescription {
+		describeResponse := driver.DescribeVolume(env, volumedriver.DescribeVolumeRequest{Name: "volume"})
+		Expect(describeResponse.Err).To(BeEmpty())
+		return describeResponse.Volume
+	}
+
+	unused := func(unusedFor time.Duration) []string {
+		listResponse := driver.ListVolumes(env, volumedriver.ListVolumesRequest{UnusedFor: unusedFor})
+		Expect(listResponse.Err).To(BeEmpty())
+		names := []string{}
+		for _, volume := range listResponse.Volumes {
+			names = append(names, volume.Name)
+		}
+		return names
+	}
+
+	stats := func(bytesRead uint64, ops map[string]uint64) map[string]volumedriver.NFSStats {
+		sample := volumedriver.NFSStats{BytesRead: bytesRead, Ops: map[string]volumedriver.NFSOpStats{}}
+		for op, count := range ops {
+			sample.Ops[op] = volumedriver.NFSOpStats{Ops: count, Transmissions: count}
+		}
+		return map[string]volumedriver.NFSStats{mountpoint: sample}
+	}
+
+	BeforeEach(func() {
+		logger := lagertest.NewTestLogger("volume-usage")
+		env = driverhttp.NewHttpDriverEnv(logger, context.TODO())
+
+		fakeMountStats = &volumedriverfakes.FakeMountStatsReader{}
+		options = volumedriver.DefaultOptions()
+		options.MountStats = fakeMountStats
+		driver = testhelpers.NewMemoryDriverWithOptions(logger, options)
+
+		for _, name := range []string{"volume", "other"} {
+			Expect(driver.Create(env, dockerdriver.CreateRequest{
+				Name: name,
+				Opts: map[string]interface{}{"source": "server:/export"},
+			}).Err).To(BeEmpty())
+		}
+		mountResponse := driver.Mount(env, dockerdriver.MountRequest{Name: "volume"})
+		Expect(mountResponse.Err).To(BeEmpty())
+		mountpoint = mountResponse.Mountpoint
+	})
+
+	It("reports the usage of the listed volumes", func() {
+		listResponse := driver.ListVolumes(env, volumedriver.ListVolumesRequest{})
+		Expect(listResponse.Usage).To(HaveLen(2))
+
+		usage := listResponse.Usage["volume"]
+		Expect(usage.CreatedAt).NotTo(BeNil())
+		Expect(usage.LastMountedAt).NotTo(BeNil())
+		Expect(usage.LastUnmountedAt).To(BeNil())
+		Expect(usage.LastUsedAt).To(Equal(usage.LastMountedAt))
+		Expect(listResponse.Usage["other"].LastUsedAt).To(Equal(listResponse.Usage["other"].CreatedAt))
+	})
+
+	It("records unmounts that leave the volume mounted", func() {
+		Expect(driver.Mount(env, dockerdriver.MountRequest{Name: "volume"}).Err).To(BeEmpty())
+		Expect(driver.Unmount(env, dockerdriver.UnmountRequest{Name: "volume"}).Err).To(BeEmpty())
+
+		volume := describe()
+		Expect(volume.LastUnmountedAt).NotTo(BeNil())
+		Expect(volume.LastUnmountedAt.Before(*volume.LastMountedAt)).To(BeFalse())
+
+		restarted := driver.Restart(lagertest.NewTestLogger("volume-usage"))
+		Expect(restarted.DescribeVolume(env, volumedriver.DescribeVolumeRequest{Name: "volume"}).Volume.LastUnmountedAt).NotTo(BeNil())
+	})
+
+	Describe("I/O", func() {
+		It("records the I/O seen between mount stats samples", func() {
+			fakeMountStats.ReadReturnsOnCall(0, stats(100, map[string]uint64{"READ": 1}), nil)
+			fakeMountStats.ReadReturnsOnCall(1, stats(300, map[string]uint64{"READ": 2}), nil)
+
+			driver.EmitMountStats(env)
+			Expect(describe().LastIOAt).To(BeNil())
+
+			driver.EmitMountStats(env)
+			Expect(describe().LastIOAt).NotTo(BeNil())
+		})
+
+		It("does not count lease renewals as I/O", func() {
+			fakeMountStats.ReadReturnsOnCall(0, stats(100, map[string]uint64{"SEQUENCE": 10, "GETATTR": 3}), nil)
+			fakeMountStats.ReadReturnsOnCall(1, stats(100, map[string]uint64{"SEQUENCE": 20, "GETATTR": 3}), nil)
+
+			driver.EmitMountStats(env)
+			driver.EmitMountStats(env)
+			Expect(describe().LastIOAt).To(BeNil())
+		})
+
+		It("persists the time of the I/O", func() {
+			fakeMountStats.ReadReturnsOnCall(0, stats(100, nil), nil)
+			fakeMountStats.ReadReturnsOnCall(1, stats(300, nil), nil)
+			driver.EmitMountStats(env)
+			driver.EmitMountStats(env)
+
+			restarted := driver.Restart(lagertest.NewTestLogger("volume-usage"))
+			Expect(restarted.DescribeVolume(env, volumedriver.DescribeVolumeRequest{Name: "volume"}).Volume.LastIOAt).NotTo(BeNil())
+		})
+	})
+
+	Describe("listing unused volumes", func() {
+		BeforeEach(func() {
+			time.Sleep(5 * time.Millisecond)
+		})
+
+		It("lists the volumes not used for the duration", func() {
+			Expect(unused(time.Millisecond)).To(Equal([]string{"other", "volume"}))
+			Expect(unused(time.Hour)).To(BeEmpty())
+		})
+
+		It("does not list volumes with recent I/O", func() {
+			fakeMountStats.ReadReturnsOnCall(0, stats(100, nil), nil)
+			fakeMountStats.ReadReturnsOnCall(1, stats(300, nil), nil)
+			driver.EmitMountStats(env)
+			driver.EmitMountStats(env)
+
+			Expect(unused(3 * time.Millisecond)).To(Equal([]string{"other"}))
+		})
+
+		It("rejects negative durations", func() {
+			Expect(driver.ListVolumes(env, volumedriver.ListVolumesRequest{UnusedFor: -time.Second}).Err).To(Equal("invalid unused duration -1s"))
+		})
+
+		Context("when the I/O of volumes is not tracked", func() {
+			BeforeEach(func() {
+				options.MountStats = nil
+				driver = driver.RestartWithOptions(lagertest.NewTestLogger("volume-usage"), options)
+			})
+
+			It("never lists mounted volumes", func() {
+				Expect(unused(time.Millisecond)).To(Equal([]string{"other"}))
+			})
+		})
+	})
+})
@@ -9,12 +9,13 @@ import (
 )
 
 type FakeMounter struct {
-	CheckStub        func(dockerdriver.Env, string, string) bool
+	CheckStub        func(dockerdriver.Env, string, string, volumedriver.CheckDepth) bool
 	checkMutex       sync.RWMutex
 	checkArgsForCall []struct {
 		arg1 dockerdriver.Env
 		arg2 string
 		arg3 string
+		arg4 volumedriver.CheckDepth
 	}
 	checkReturns struct {
 		result1 bool
@@ -58,18 +59,19 @@ type FakeMounter struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeMounter) Check(arg1 dockerdriver.Env, arg2 string, arg3 string) bool {
+func (fake *FakeMounter) Check(arg1 dockerdriver.Env, arg2 string, arg3 string, arg4 volumedriver.CheckDepth) bool {
 	fake.checkMutex.Lock()
 	ret, specificReturn := fake.checkReturnsOnCall[len(fake.checkArgsForCall)]
 	fake.checkArgsForCall = append(fake.checkArgsForCall, struct {
 		arg1 dockerdriver.Env
 		arg2 string
 		arg3 string
-	}{arg1, arg2, arg3})
-	fake.recordInvocation("Check", []interface{}{arg1, arg2, arg3})
+		arg4 volumedriver.CheckDepth
+	}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("Check", []interface{}{arg1, arg2, arg3, arg4})
 	fake.checkMutex.Unlock()
 	if fake.CheckStub != nil {
-		return fake.CheckStub(arg1, arg2, arg3)
+		return fake.CheckStub(arg1, arg2, arg3, arg4)
 	}
 	if specificReturn {
 		return ret.result1
@@ -84,17 +86,17 @@ func (fake *FakeMounter) CheckCallCount() int {
 	return len(fake.checkArgsForCall)
 }
 
-func (fake *FakeMounter) CheckCalls(stub func(dockerdriver.Env, string, string) bool) {
+func (fake *FakeMounter) CheckCalls(stub func(dockerdriver.Env, string, string, volumedriver.CheckDepth) bool) {
 	fake.checkMutex.Lock()
 	defer fake.checkMutex.Unlock()
 	fake.CheckStub = stub
 }
 
-func (fake *FakeMounter) CheckArgsForCall(i int) (dockerdriver.Env, string, string) {
+func (fake *FakeMounter) CheckArgsForCall(i int) (dockerdriver.Env, string, string, volumedriver.CheckDepth) {
 	fake.checkMutex.RLock()
 	defer fake.checkMutex.RUnlock()
 	argsForCall := fake.checkArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
 }
 
 func (fake *FakeMounter) CheckReturns(result1 bool) {
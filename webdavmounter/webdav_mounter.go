@@ -0,0 +1,289 @@
+package webdavmounter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/dockerdriver"
+	"code.cloudfoundry.org/goshims/ioutilshim"
+	"code.cloudfoundry.org/goshims/osshim"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/volumedriver"
+	"code.cloudfoundry.org/volumedriver/invoker"
+	"code.cloudfoundry.org/volumedriver/mountchecker"
+	"code.cloudfoundry.org/volumedriver/mounthelper"
+	"code.cloudfoundry.org/volumedriver/safeerrors"
+)
+
+const (
+	// Dav and Davs are the source schemes for WebDAV over http and https,
+	// and the names to register the mounter under in Options.Mounters.
+	Dav  = "dav"
+	Davs = "davs"
+
+	UsernameOpt = "username"
+	PasswordOpt = "password"
+)
+
+// allowedOpts are passed to mount.davfs as they are, apart from the
+// credentials, which go to a secrets file, and ro and readonly, which mount
+// read-only.
+var allowedOpts = map[string]bool{
+	UsernameOpt: true, PasswordOpt: true,
+	"uid": true, "gid": true, "file_mode": true, "dir_mode": true,
+	"ro": true, "readonly": true,
+}
+
+var validValue = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+type webdavMounter struct {
+	invoker      invoker.Invoker
+	os           osshim.Os
+	ioutil       ioutilshim.Ioutil
+	mountChecker mountchecker.MountChecker
+	secretsDir   string
+}
+
+// NewWebdavMounter returns a Mounter for WebDAV shares, mounted with davfs2.
+// Sources are dav://host/path or davs://host/path, or http:// and https://
+// URLs for volumes that select the mounter with the driver opt. Credentials
+// are written to a davfs2 secrets file in secretsDir, readable by root only,
+// rather than passed on the mount command line.
+func NewWebdavMounter(invoker invoker.Invoker, os osshim.Os, ioutil ioutilshim.Ioutil, mountChecker mountchecker.MountChecker, secretsDir string) volumedriver.Mounter {
+	return &webdavMounter{
+		invoker:      invoker,
+		os:           os,
+		ioutil:       ioutil,
+		mountChecker: mountChecker,
+		secretsDir:   secretsDir,
+	}
+}
+
+func (m *webdavMounter) Mount(env dockerdriver.Env, source string, target string, opts map[string]interface{}) error {
+	logger := env.Logger().Session("webdav-mount", lager.Data{"source": source, "target": target})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	davURL, err := parseSource(source)
+	if err != nil {
+		return err
+	}
+
+	mountOpts, err := davfsMountOpts(opts)
+	if err != nil {
+		logger.Error("invalid-opts", err)
+		return err
+	}
+
+	if username, ok := opts[UsernameOpt].(string); ok {
+		password, _ := opts[PasswordOpt].(string)
+		conf, err := m.writeSecrets(target, davURL, username, password)
+		if err != nil {
+			logger.Error("write-secrets-failed", err)
+			return fmt.Errorf("failed to store the webdav credentials: %s", err)
+		}
+		mountOpts = append([]string{"conf=" + conf}, mountOpts...)
+	} else if _, ok := opts[PasswordOpt]; ok {
+		return safeerrors.New(safeerrors.InvalidOption, "'username' is required when passing credentials")
+	}
+
+	args := []string{"-t", "davfs"}
+	if len(mountOpts) > 0 {
+		args = append(args, "-o", strings.Join(mountOpts, ","))
+	}
+	args = append(args, davURL, target)
+
+	result := m.invoker.Invoke(env, "mount", args)
+	if err := result.Wait(); err != nil {
+		logger.Error("mount-failed", err, lager.Data{"stderr": result.StdError()})
+		m.removeSecrets(logger, target)
+		return safeerrors.FromMountHelper(err, result.StdError(), "webdav mount failed")
+	}
+
+	return nil
+}
+
+func (m *webdavMounter) Unmount(env dockerdriver.Env, target string) error {
+	logger := env.Logger().Session("webdav-unmount", lager.Data{"target": target})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	result := m.invoker.Invoke(env, "umount", []string{target})
+	if err := result.Wait(); err != nil {
+		logger.Error("unmount-failed", err, lager.Data{"stderr": result.StdError()})
+		return safeerrors.FromMountHelper(err, result.StdError(), "unmount failed")
+	}
+
+	m.removeSecrets(logger, target)
+	return nil
+}
+
+func (m *webdavMounter) Check(env dockerdriver.Env, name, mountPoint string, depth volumedriver.CheckDepth) bool {
+	logger := env.Logger().Session("webdav-check", lager.Data{"name": name, "mount-point": mountPoint, "depth": depth})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	mounted, err := m.mountChecker.Exists(mountPoint)
+	if err != nil {
+		logger.Error("check-mounts-failed", err)
+		return false
+	}
+	if !mounted {
+		logger.Info("not-mounted")
+		return false
+	}
+
+	if err := volumedriver.ProbeMountPoint(m.os, m.ioutil, mountPoint, depth); err != nil {
+		logger.Error("probe-failed", err)
+		return false
+	}
+
+	return true
+}
+
+// Purge lazily unmounts everything below path, along with the credentials
+// of the mounts. Directory contents are never removed.
+func (m *webdavMounter) Purge(env dockerdriver.Env, path string) {
+	logger := env.Logger().Session("webdav-purge", lager.Data{"path": path})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	unmount := mounthelper.Unmount(env, m.invoker, "umount", "-l")
+	mounthelper.Purge(logger, path, m.mountChecker, m.os, func(mountPoint string) error {
+		if err := unmount(mountPoint); err != nil {
+			return err
+		}
+		m.removeSecrets(logger, mountPoint)
+		return nil
+	})
+}
+
+// writeSecrets writes a davfs2 configuration for the mount at target that
+// points at a secrets file holding the credentials, and returns its path.
+func (m *webdavMounter) writeSecrets(target, davURL, username, password string) (string, error) {
+	if err := m.os.MkdirAll(m.secretsDir, 0700); err != nil {
+		return "", err
+	}
+
+	conf, secrets := m.secretsPaths(target)
+	line := fmt.Sprintf("%s %s %s\n", quote(davURL), quote(username), quote(password))
+	if err := m.ioutil.WriteFile(secrets, []byte(line), 0600); err != nil {
+		return "", err
+	}
+	if err := m.ioutil.WriteFile(conf, []byte(fmt.Sprintf("secrets %s\n", quote(secrets))), 0600); err != nil {
+		return "", err
+	}
+
+	return conf, nil
+}
+
+func (m *webdavMounter) removeSecrets(logger lager.Logger, target string) {
+	conf, secrets := m.secretsPaths(target)
+	for _, path := range []string{conf, secrets} {
+		if err := m.os.Remove(path); err != nil && !os.IsNotExist(err) {
+			logger.Error("remove-secrets-failed", err, lager.Data{"path": path})
+		}
+	}
+}
+
+func (m *webdavMounter) secretsPaths(target string) (string, string) {
+	sum := sha256.Sum256([]byte(filepath.Clean(target)))
+	name := hex.EncodeToString(sum[:])[:16]
+	return filepath.Join(m.secretsDir, name+".conf"), filepath.Join(m.secretsDir, name+".secrets")
+}
+
+// parseSource turns dav:// and davs:// sources into the http and https URLs
+// that davfs2 expects.
+func parseSource(source string) (string, error) {
+	parsed, err := url.Parse(source)
+	if err != nil || parsed.Host == "" {
+		return "", safeerrors.New(safeerrors.InvalidSource, "invalid webdav source '%s', expected davs://host/path", source)
+	}
+
+	switch strings.ToLower(parsed.Scheme) {
+	case Dav, "http":
+		parsed.Scheme = "http"
+	case Davs, "https":
+		parsed.Scheme = "https"
+	default:
+		return "", safeerrors.New(safeerrors.InvalidSource, "invalid webdav source '%s', expected davs://host/path", source)
+	}
+
+	if parsed.User != nil {
+		return "", safeerrors.New(safeerrors.InvalidSource, "credentials must be passed in the username and password opts, not in the source")
+	}
+
+	return parsed.String(), nil
+}
+
+// davfsMountOpts passes the hardening opts of the driver on as mount flags.
+func davfsMountOpts(opts map[string]interface{}) ([]string, error) {
+	opts, flags := volumedriver.HardeningFlags(opts)
+	keys := make([]string, 0, len(opts))
+	for key := range opts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	mountOpts := []string{}
+	for _, key := range keys {
+		if !allowedOpts[key] {
+			return nil, safeerrors.New(safeerrors.InvalidOption, "Not allowed options: %s", key)
+		}
+
+		value := opts[key]
+		switch key {
+		case UsernameOpt, PasswordOpt:
+			if _, ok := value.(string); !ok {
+				return nil, safeerrors.New(safeerrors.InvalidOption, "Invalid value for option '%s'", key)
+			}
+		case "ro", "readonly":
+			set, err := boolOpt(value)
+			if err != nil {
+				return nil, safeerrors.New(safeerrors.InvalidOption, "Invalid value for option '%s': %v", key, value)
+			}
+			if set {
+				mountOpts = append(mountOpts, "ro")
+			}
+		default:
+			formatted := fmt.Sprintf("%v", value)
+			if !validValue.MatchString(formatted) {
+				return nil, safeerrors.New(safeerrors.InvalidOption, "Invalid value for option '%s': %v", key, value)
+			}
+			mountOpts = append(mountOpts, key+"="+formatted)
+		}
+	}
+
+	return append(mountOpts, flags...), nil
+}
+
+func boolOpt(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		switch strings.ToLower(v) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+
+	return false, fmt.Errorf("not a boolean: %v", value)
+}
+
+// quote returns a field of a davfs2 configuration or secrets file, which
+// are separated by spaces and may be double quoted.
+func quote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	return `"` + s + `"`
+}
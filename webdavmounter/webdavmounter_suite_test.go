@@ -0,0 +1,13 @@
+package webdavmounter_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestWebdavMounter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "WebdavMounter Suite")
+}